@@ -8,7 +8,8 @@
 //
 //	slinit-check [options] [service-name ...]
 //
-// If no service names are given, "boot" is checked by default.
+// If no service names are given, "boot" is checked by default; --all
+// checks every service file in the service directories.
 package main
 
 import (
@@ -32,6 +33,7 @@ func main() {
 	var socketPath string
 	onlineMode := false
 	userMode := false
+	checkAll := false
 
 	args := os.Args[1:]
 	for i := 0; i < len(args); i++ {
@@ -56,6 +58,8 @@ func main() {
 			envFile = args[i]
 		case "-n", "--online":
 			onlineMode = true
+		case "-a", "--all":
+			checkAll = true
 		case "-p", "--socket-path":
 			if i+1 >= len(args) {
 				fatal("missing argument for %s", args[i])
@@ -94,10 +98,6 @@ func main() {
 		dirs = defaultSystemDirs()
 	}
 
-	if len(services) == 0 {
-		services = []string{"boot"}
-	}
-
 	// Load env file if specified (offline mode or override)
 	if envFile != "" {
 		envVars, err := readEnvFile(envFile)
@@ -118,6 +118,15 @@ func main() {
 	loader := config.NewDirLoader(set, dirs)
 	set.SetLoader(loader)
 
+	if checkAll {
+		if len(services) > 0 {
+			fatal("--all does not take service names")
+		}
+		services = loader.DescribedNames()
+	} else if len(services) == 0 {
+		services = []string{"boot"}
+	}
+
 	var errors int
 	var warnings int
 
@@ -164,12 +173,20 @@ func main() {
 		if len(desc.Command) > 0 {
 			w := checkExecutable(desc.Command[0], name, "command", path)
 			warnings += w
-		} else if desc.Type != service.TypeInternal && desc.Type != service.TypeTriggered {
+		} else if desc.Type != service.TypeInternal && desc.Type != service.TypeTriggered &&
+			desc.Type != service.TypeBarrier && desc.Type != service.TypeFanout &&
+			desc.Type != service.TypeTimer {
 			fmt.Fprintf(os.Stderr, "  WARNING [%s]: no command specified for %s service\n",
 				name, desc.Type)
 			warnings++
 		}
 
+		// A socket-activated service has nothing to start without a socket
+		if desc.Type == service.TypeSocketActivated && desc.SocketPath == "" {
+			fmt.Fprintf(os.Stderr, "  ERROR [%s]: socket-activated service has no socket-listen\n", name)
+			errors++
+		}
+
 		// Check stop-command executable
 		if len(desc.StopCommand) > 0 {
 			w := checkExecutable(desc.StopCommand[0], name, "stop-command", path)
@@ -198,6 +215,15 @@ func main() {
 			}
 		}
 
+		// Check encrypted-env-file
+		if desc.EncryptedEnvFile != "" {
+			if _, err := os.Stat(desc.EncryptedEnvFile); err != nil {
+				fmt.Fprintf(os.Stderr, "  WARNING [%s]: encrypted-env-file %q: %v\n",
+					name, desc.EncryptedEnvFile, err)
+				warnings++
+			}
+		}
+
 		// Check PID file directory
 		if desc.PIDFile != "" {
 			dir := filepath.Dir(desc.PIDFile)
@@ -375,22 +401,15 @@ func checkExecutable(path, svcName, setting, svcFile string) int {
 
 func findServiceDesc(dirs []string, name string) (*config.ServiceDescription, string) {
 	// Support name@argument templates: try the full name first, then fall
-	// back to the base name (before '@'), and thread the argument through
-	// so $1/${1} gets substituted during the re-parse.
-	baseName := name
+	// back to the template files ("base@", "base"), and thread the
+	// argument through so $1/%i get substituted during the re-parse.
 	var serviceArg *string
-	if idx := strings.IndexByte(name, '@'); idx >= 0 {
-		baseName = name[:idx]
-		arg := name[idx+1:]
+	if _, arg, ok := config.SplitInstance(name); ok {
 		serviceArg = &arg
 	}
-	searchNames := []string{name}
-	if baseName != name {
-		searchNames = append(searchNames, baseName)
-	}
 
 	for _, dir := range dirs {
-		for _, sn := range searchNames {
+		for _, sn := range config.TemplateFileNames(name) {
 			path := filepath.Join(dir, sn)
 			f, err := os.Open(path)
 			if err != nil {
@@ -461,6 +480,7 @@ Configuration linter for slinit service files.
 If no service names are given, "boot" is checked by default.
 
 Options:
+  -a, --all                  Check every service file in the service directories
   -d, --services-dir <dir>   Service directory to search (can be repeated)
   -s, --system               Use system service directories
   -u, --user                 Use user service directories
@@ -751,8 +771,10 @@ func checkNamespaceConfig(desc *config.ServiceDescription, name string) (int, in
 		}
 	}
 
-	// Namespace flags on internal/triggered services make no sense
-	if hasAnyNS && (desc.Type == service.TypeInternal || desc.Type == service.TypeTriggered) {
+	// Namespace flags on internal/triggered/barrier/fanout/timer services make no sense
+	if hasAnyNS && (desc.Type == service.TypeInternal || desc.Type == service.TypeTriggered ||
+		desc.Type == service.TypeBarrier || desc.Type == service.TypeFanout ||
+		desc.Type == service.TypeTimer) {
 		fmt.Fprintf(os.Stderr, "  WARNING [%s]: namespace settings on %s service have no effect (no process is forked)\n",
 			name, desc.Type)
 		warnings++
@@ -256,7 +256,7 @@ func readMonitorReply(conn net.Conn) (uint8, []byte, error) {
 			return 0, nil, err
 		}
 		switch rply {
-		case control.InfoServiceEvent, control.InfoServiceEvent5, control.InfoEnvEvent:
+		case control.InfoServiceEvent, control.InfoServiceEvent5, control.InfoEnvEvent, control.InfoGlobalEvent:
 			continue
 		default:
 			return rply, payload, nil
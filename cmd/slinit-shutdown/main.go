@@ -2,17 +2,24 @@
 //
 // Can be invoked as:
 //
-//	slinit-shutdown [-r|-h|-p|-s|-k] [--system] [--use-passed-cfd]
+//	slinit-shutdown [-r|-h|-p|-s|-k] [--system] [--use-passed-cfd] [TIME [MESSAGE...]]
 //	slinit-reboot      (symlink — defaults to reboot)
 //	slinit-halt        (symlink — defaults to halt)
+//	slinit-poweroff    (symlink — defaults to poweroff)
 //	slinit-soft-reboot (symlink — defaults to soft-reboot)
 //
 // When invoked without --system, it connects to the slinit daemon via
-// the control socket and issues a shutdown command. With --system, it
-// performs the shutdown sequence directly (kill all, umount, sync, reboot).
+// the control socket and issues a shutdown command; a TIME ("now",
+// "+N" minutes or "HH:MM") schedules it instead, and any MESSAGE is
+// broadcast to logged-in users. If slinit is not running (a rescue
+// shell), an immediate shutdown falls back to the --system path. With
+// --system, it performs the shutdown sequence directly (kill all,
+// umount, sync, reboot).
 package main
 
 import (
+	"encoding/binary"
+	"errors"
 	"fmt"
 	"net"
 	"os"
@@ -44,6 +51,7 @@ func main() {
 		noSync      bool
 		noWall      bool
 		interactive bool
+		positional  []string
 	)
 
 	shutdownType := defaultShutdownType()
@@ -121,6 +129,8 @@ func main() {
 				fmt.Fprintf(os.Stderr, "Invalid --grace value: %s\n", arg[len("--grace="):])
 				os.Exit(1)
 			}
+		case !strings.HasPrefix(arg, "-"):
+			positional = append(positional, arg)
 		default:
 			fmt.Fprintf(os.Stderr, "Unrecognized option: %s\n", arg)
 			os.Exit(1)
@@ -132,6 +142,19 @@ func main() {
 		os.Exit(0)
 	}
 
+	// shutdown(8) positional form: TIME, then the wall message.
+	var delay time.Duration
+	var message string
+	if len(positional) > 0 {
+		d, err := shutdown.ParseDelay(positional[0])
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "slinit-shutdown: %v\n", err)
+			os.Exit(1)
+		}
+		delay = d
+		message = strings.Join(positional[1:], " ")
+	}
+
 	// -w / --wtmp-only writes the shutdown record and exits without
 	// touching the init system or the reboot syscall. Matches systemd's
 	// contract exactly.
@@ -178,6 +201,14 @@ func main() {
 	// Connect to daemon and issue shutdown command
 	conn, err := connectToDaemon(useCFD)
 	if err != nil {
+		// No daemon to ask (rescue shell, init=/bin/sh): do the job
+		// ourselves, as root only, and only for an immediate shutdown
+		// since nothing would be left to carry out a scheduled one.
+		if !useCFD && daemonNotRunning(err) && delay == 0 && os.Geteuid() == 0 {
+			fmt.Fprintf(os.Stderr, "slinit is not running; shutting down directly\n")
+			doSystemShutdown(shutdownType)
+			os.Exit(1)
+		}
 		fmt.Fprintf(os.Stderr, "Failed to connect to slinit daemon: %v\n", err)
 		os.Exit(1)
 	}
@@ -188,27 +219,54 @@ func main() {
 		os.Exit(1)
 	}
 
+	if delay > 0 {
+		// The daemon walls the notice and the 5/2/1 minute reminders.
+		payload := control.EncodeScheduleShutdown(shutdownType, delay, message)
+		if err := sendCommand(conn, control.CmdScheduleShutdown, payload); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to schedule shutdown: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("Shutdown (%s) scheduled in %v.\n", shutdownVerb(shutdownType), delay)
+		os.Exit(0)
+	}
+
+	if message != "" && !noWall {
+		// Best effort: the daemon's own shutdown notice follows anyway.
+		payload := binary.LittleEndian.AppendUint16(nil, uint16(len(message)))
+		sendCommand(conn, control.CmdWallNotice, append(payload, message...)) //nolint: errcheck
+	}
+
 	fmt.Println("Issuing shutdown command...")
 
 	payload := []byte{uint8(shutdownType)}
-	if err := control.WritePacket(conn, control.CmdShutdown, payload); err != nil {
-		fmt.Fprintf(os.Stderr, "Failed to send shutdown command: %v\n", err)
+	if err := sendCommand(conn, control.CmdShutdown, payload); err != nil {
+		fmt.Fprintf(os.Stderr, "Shutdown command failed: %v\n", err)
 		os.Exit(1)
 	}
 
+	// Wait indefinitely — the system should shut down around us
+	select {}
+}
+
+// sendCommand writes one command and expects RplyACK in return.
+func sendCommand(conn net.Conn, cmd uint8, payload []byte) error {
+	if err := control.WritePacket(conn, cmd, payload); err != nil {
+		return err
+	}
 	rply, _, err := control.ReadPacket(conn)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Failed to read reply: %v\n", err)
-		os.Exit(1)
+		return err
 	}
-
 	if rply != control.RplyACK {
-		fmt.Fprintf(os.Stderr, "Shutdown command failed (reply: %d)\n", rply)
-		os.Exit(1)
+		return fmt.Errorf("reply: %d", rply)
 	}
+	return nil
+}
 
-	// Wait indefinitely — the system should shut down around us
-	select {}
+// daemonNotRunning reports whether a failed connect means no slinit is
+// listening, as opposed to, say, a permission problem.
+func daemonNotRunning(err error) bool {
+	return errors.Is(err, syscall.ENOENT) || errors.Is(err, syscall.ECONNREFUSED)
 }
 
 func defaultShutdownType() service.ShutdownType {
@@ -236,7 +294,12 @@ func shutdownVerb(st service.ShutdownType) string {
 }
 
 func printUsage(execName string) {
-	fmt.Fprintf(os.Stderr, `%s: shut down the system
+	fmt.Fprintf(os.Stderr, `Usage: %s [options] [TIME [MESSAGE...]]
+Shut down the system. TIME is "now" (default), "+N" minutes or "HH:MM";
+a later TIME schedules the shutdown, and MESSAGE is broadcast to
+logged-in users. Without a running slinit, an immediate shutdown is
+carried out directly (as with --system).
+
   --help             show this help
   -r, --reboot       reboot the machine
   -h, --halt         halt the machine
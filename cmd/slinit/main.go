@@ -7,10 +7,12 @@ import (
 	"flag"
 	"fmt"
 	"io"
+	"log/syslog"
 	"net"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
 	"syscall"
@@ -57,6 +59,35 @@ func (s *stringSlice) Set(v string) error {
 	return nil
 }
 
+// durationMap implements flag.Value for repeated NAME=DURATION flags
+// (--service-stop-timeout).
+type durationMap map[string]time.Duration
+
+func (m durationMap) String() string {
+	parts := make([]string, 0, len(m))
+	for name, d := range m {
+		parts = append(parts, name+"="+d.String())
+	}
+	sort.Strings(parts)
+	return strings.Join(parts, ",")
+}
+
+func (m durationMap) Set(v string) error {
+	name, val, ok := strings.Cut(v, "=")
+	if !ok || name == "" {
+		return fmt.Errorf("expected SERVICE=DURATION, got %q", v)
+	}
+	d, err := time.ParseDuration(val)
+	if err != nil {
+		return err
+	}
+	if d <= 0 {
+		return fmt.Errorf("duration for %s must be positive", name)
+	}
+	m[name] = d
+	return nil
+}
+
 func main() {
 	bootStartTime := time.Now()
 
@@ -158,6 +189,12 @@ func main() {
 	flag.StringVar(&initUmask, "umask", "0022", "initial umask (octal)")
 	flag.BoolVar(&consoleDup, "1", false, "duplicate log output to /dev/console (when using --log-file)")
 	flag.BoolVar(&consoleDup, "console-dup", false, "duplicate log output to /dev/console (when using --log-file)")
+	var logSyslog bool
+	var logSyslogFacility string
+	flag.BoolVar(&logSyslog, "log-syslog", false, "also send log output to syslog (falls back to console if syslog is unavailable)")
+	flag.StringVar(&logSyslogFacility, "log-syslog-facility", "daemon", "syslog facility: daemon, local0..local7")
+	var logAsync bool
+	flag.BoolVar(&logAsync, "log-async", false, "write log output from a background goroutine (drops entries when the buffer is full)")
 	flag.StringVar(&devtmpfsPath, "devtmpfs-path", "/dev", "mount devtmpfs at this path (empty disables the mount)")
 	flag.StringVar(&runMode, "run-mode", "mount", "how to stage /run at boot (mount|remount|keep)")
 	flag.StringVar(&kcmdlineDest, "kcmdline-dest", "/run/slinit/kcmdline", "snapshot /proc/cmdline to this path (empty disables)")
@@ -195,11 +232,30 @@ func main() {
 	flag.BoolVar(&noWatchdog, "no-watchdog", false, "disable hardware watchdog feeder even when running as PID 1")
 	flag.StringVar(&sysOverride, "sys", "", "override platform detection (docker, lxc, podman, wsl, xen0, xenu, none)")
 	flag.StringVar(&sysOverride, "S", "", "override platform detection (short for --sys)")
+	var noContainerDetect bool
+	flag.BoolVar(&noContainerDetect, "no-container-detect", false,
+		"as PID 1 inside a container, keep PID 1 behaviour instead of switching to container mode")
 	flag.StringVar(&confDir, "conf-dir", "", "override conf.d overlay directories (comma-separated; 'none' disables overlays)")
+	var noEmbedded bool
+	flag.BoolVar(&noEmbedded, "no-embedded", false, "disable fallback to the service descriptions built into the binary")
+	var validateDeps, requireAllDeps bool
+	flag.BoolVar(&validateDeps, "validate-deps", false,
+		"before starting boot services, warn about dependencies that name no service file")
+	flag.BoolVar(&requireAllDeps, "require-all-deps", false,
+		"refuse to start if any boot service dependency, even an optional one, names no service file")
+	var noFileLocking bool
+	flag.BoolVar(&noFileLocking, "no-file-locking", false,
+		"don't flock service files while loading them or for slinitctl edit (single-instance deployments)")
+	var checksumFile string
+	flag.StringVar(&checksumFile, "checksum-file", "",
+		"refuse to load service files not listed with a matching SHA-256 in this sha256sum-format file (see slinitctl generate-checksums)")
 
 	var watchServiceDirs bool
 	flag.BoolVar(&watchServiceDirs, "watch-services-dir", false,
 		"auto-load/unload services when files appear or disappear in services-dir (inotify-based, opt-in)")
+	var preloadServices bool
+	flag.BoolVar(&preloadServices, "preload-services", false,
+		"load every service in services-dir at startup, reading files in parallel (opt-in; default loads on demand)")
 
 	var sentinelDir string
 	flag.StringVar(&sentinelDir, "sentinel-dir", "",
@@ -231,8 +287,77 @@ func main() {
 	flag.DurationVar(&emergencyTimeout, "emergency-timeout", 0,
 		"maximum time to wait for services to stop during shutdown before force-exit (default 90s; workloads with heavy docker/systemd-style teardown may need 3-5m)")
 
+	serviceStopTimeouts := durationMap{}
+	flag.Var(serviceStopTimeouts, "service-stop-timeout",
+		"SERVICE=DURATION: SIGKILL SERVICE if it has not stopped within DURATION of shutdown starting (can be specified multiple times)")
+
+	var restartJitterFactor float64
+	flag.Float64Var(&restartJitterFactor, "restart-jitter-factor", 0,
+		"scale every restart delay by a random factor in [1, 1+F] so services failing together don't restart together (0 = off)")
+
+	var skipPreExecChecks bool
+	flag.BoolVar(&skipPreExecChecks, "skip-preexec-checks", false,
+		"don't validate command, directories, env-file and run-as user before starting a service")
+
+	var debugVerifyRefcounts bool
+	flag.BoolVar(&debugVerifyRefcounts, "debug-verify-refcounts", false,
+		"check service reference counts against the dependency graph every minute and log discrepancies (debugging aid)")
+
+	var rateLimitCapacity int
+	flag.IntVar(&rateLimitCapacity, "rate-limit-capacity", control.DefaultRateLimitCapacity,
+		"burst of control commands a single connection may send before being rate limited (0 = no limit)")
+	var rateLimitRefill float64
+	flag.Float64Var(&rateLimitRefill, "rate-limit-refill", control.DefaultRateLimitRefill,
+		"sustained control commands per second allowed per connection")
+
+	var controlAllow, controlReadOnly string
+	flag.StringVar(&controlAllow, "control-allow", "",
+		"comma-separated users and @groups (names or IDs) given full use of the control socket besides root")
+	flag.StringVar(&controlReadOnly, "control-read-only", "",
+		"comma-separated users and @groups (names or IDs) allowed read-only queries (list, status, ...) on the control socket")
+
+	var listenTCP, tlsCert, tlsKey, tlsCA string
+	flag.StringVar(&listenTCP, "listen-tcp", "",
+		"also accept control connections on this TCP host:port, TLS with client certificates required (needs --tls-cert, --tls-key, --tls-ca)")
+	flag.StringVar(&tlsCert, "tls-cert", "", "server certificate for --listen-tcp (PEM)")
+	flag.StringVar(&tlsKey, "tls-key", "", "server private key for --listen-tcp (PEM)")
+	flag.StringVar(&tlsCA, "tls-ca", "", "CA bundle that client certificates must chain to for --listen-tcp (PEM)")
+
+	var powerStatusFile string
+	flag.StringVar(&powerStatusFile, "power-status-file", eventloop.DefaultPowerStatusFile,
+		"file read on SIGPWR for the UPS line state (OK, FAIL or LOW)")
+	var powerFailGrace time.Duration
+	flag.DurationVar(&powerFailGrace, "power-fail-grace", eventloop.DefaultPowerFailGrace,
+		"how long services keep running after a SIGPWR power failure before poweroff (0 = immediately)")
+
+	signalMapping := map[syscall.Signal]service.ShutdownType{}
+	for _, s := range []struct {
+		sig  syscall.Signal
+		name string
+	}{{syscall.SIGTERM, "sigterm"}, {syscall.SIGINT, "sigint"}, {syscall.SIGQUIT, "sigquit"}} {
+		sig := s.sig
+		flag.Func(s.name+"-action",
+			"shutdown type "+strings.ToUpper(s.name)+" initiates: halt, poweroff or reboot (default depends on the signal and mode)",
+			func(v string) error {
+				st, err := eventloop.ParseSignalAction(v)
+				if err != nil {
+					return err
+				}
+				signalMapping[sig] = st
+				return nil
+			})
+	}
+	var sighupAction string
+	flag.StringVar(&sighupAction, "sighup-action", "ignore",
+		"what SIGHUP does besides reopening the syslog connection and service logfiles: reload (re-read every service description) or ignore")
+
 	flag.Parse()
 
+	if sighupAction != "reload" && sighupAction != "ignore" {
+		fmt.Fprintf(os.Stderr, "slinit: --sighup-action: unknown action %q (use reload or ignore)\n", sighupAction)
+		os.Exit(2)
+	}
+
 	if showVersion {
 		detected := platform.Detect()
 		if detected == platform.None {
@@ -259,6 +384,14 @@ func main() {
 	// Determine mode
 	isPID1 := os.Getpid() == 1
 
+	// PID 1 of a container's PID namespace: there is no machine to
+	// reboot and no console to own, so behave as with --container.
+	var autoContainer platform.Type
+	if isPID1 && !containerMode && !noContainerDetect {
+		autoContainer = detectContainerPlatform(sysOverride)
+		containerMode = autoContainer != platform.None
+	}
+
 	// Safety net: if slinit panics, catch it and perform emergency cleanup.
 	// PID 1: kill all processes + force reboot. Container: exit(111).
 	defer shutdown.CrashRecovery(isPID1, containerMode)
@@ -428,14 +561,44 @@ func main() {
 			defer lf.Close()
 			logger.SetOutput(lf)
 		}
-	} else if systemMode {
-		// In system mode without --log-file, use syslog as the main log
-		// facility (like dinit's /dev/log connection).
-		if err := logger.SetSyslog(); err != nil {
-			// Syslog may not be available yet (e.g. read-only rootfs);
-			// this is not fatal — we'll keep logging to console.
-			logger.Debug("syslog not available: %v", err)
+	}
+
+	// Syslog as the main log: always with --log-syslog, and by default in
+	// system mode without --log-file (like dinit's /dev/log connection).
+	// Console (or --log-file) output continues alongside it. As PID 1,
+	// syslogd is normally not running yet: main log messages then go to
+	// the kernel log and are held until a starts-log service is up,
+	// when they are replayed to syslog.
+	var syslogFacility syslog.Priority
+	if logSyslog || (systemMode && logFile == "") {
+		facility, err := logging.ParseSyslogFacility(logSyslogFacility)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "slinit: %v (using daemon)\n", err)
+		}
+		syslogFacility = facility
+		if sb, err := logging.NewSyslogBackend(facility); err != nil {
+			if isPID1 {
+				kmsg, kerr := logging.OpenKmsg(facility)
+				if kerr != nil {
+					fmt.Fprintf(os.Stderr, "slinit: /dev/kmsg: %v\n", kerr)
+					kmsg = nil
+				}
+				logger.HoldMainLog(kmsg)
+				defer logger.CloseSyslog()
+			} else if logSyslog {
+				fmt.Fprintf(os.Stderr, "slinit: --log-syslog: %v (falling back to stderr)\n", err)
+				// Everything meant for the main log now goes to the
+				// console instead, so drop the quiet boot console.
+				bootConsole = false
+				logger.SetBootConsole(false, false)
+				logger.SetLevel(mainLogLevel)
+			} else {
+				// Syslog may not be available yet (e.g. read-only rootfs);
+				// this is not fatal — we'll keep logging to console.
+				logger.Debug("syslog not available: %v", err)
+			}
 		} else {
+			logger.SetSyslogBackend(sb)
 			defer logger.CloseSyslog()
 		}
 	}
@@ -453,6 +616,14 @@ func main() {
 		}
 	}
 
+	// Async logging: queue log writes for a background goroutine so a
+	// slow console or syslog never stalls the event loop. Deferred
+	// after the writers above so it flushes before they close.
+	if logAsync {
+		logger.EnableAsync()
+		defer logger.Close()
+	}
+
 	// Stderr ring buffer (runsvdir rolling-buffer analogue). Captures
 	// the last N bytes of the daemon's own log output and re-emits
 	// them on a periodic ticker so transient warnings stay visible
@@ -545,6 +716,9 @@ func main() {
 	}
 
 	if containerMode {
+		if autoContainer != platform.None {
+			logger.Notice("Running as PID 1 in a %s container: container mode enabled", autoContainer)
+		}
 		logger.Notice("slinit starting in container mode (PID %d)", os.Getpid())
 		if err := shutdown.InitContainer(logger); err != nil {
 			logger.Error("Container initialization warning: %v", err)
@@ -625,6 +799,13 @@ func main() {
 
 	// Create service set
 	serviceSet := service.NewServiceSet(logger)
+
+	// As init we inherit every orphan and must reap it. One goroutine
+	// owns waitpid from here on, before the first service is forked,
+	// so no child's exit status can be stolen by another waiter.
+	if isPID1 || containerMode {
+		eventloop.StartReaper(serviceSet, logger)
+	}
 	if activeProfile != "" {
 		// Record the intended profile before any services load so
 		// the loader / boot flow can filter accordingly.
@@ -646,6 +827,16 @@ func main() {
 			heartbeatInterval, heartbeatWindow)
 	}
 
+	// Reference-count invariant checker. Opt-in via
+	// --debug-verify-refcounts; meant for chasing Require/Release
+	// imbalances, not for production.
+	if debugVerifyRefcounts {
+		stopVerify := make(chan struct{})
+		go serviceSet.VerifyRefCountsEvery(time.Minute, stopVerify)
+		defer close(stopVerify)
+		logger.Info("reference-count verifier active (interval=1m)")
+	}
+
 	// Wire UTMP callbacks (keeps service pkg cgo-free)
 	serviceSet.OnUtmpCreate = func(id, line, mode string, pid int) {
 		utmp.CreateEntryMode(id, line, pid, mode)
@@ -658,6 +849,11 @@ func main() {
 			logger.Info("Boot time logged to utmp/wtmp")
 		}
 	}
+	serviceSet.OnLogReady = func() {
+		if logger.HoldingMainLog() {
+			go connectHeldSyslog(logger, syslogFacility)
+		}
+	}
 
 	// Load daemon-level environment file (--env-file/-e).
 	//
@@ -675,6 +871,7 @@ func main() {
 				logger.Error("Failed to read env-file '%s': %v (continuing)", envFile, err)
 			} else {
 				logger.Error("Failed to read env-file '%s': %v", envFile, err)
+				logger.Close()
 				os.Exit(1)
 			}
 		} else {
@@ -762,8 +959,16 @@ func main() {
 		logger.Info("Parallel start limit: %d (slow threshold: %v)", parallelStartLimit, slowThresh)
 	}
 
+	if restartJitterFactor < 0 {
+		logger.Error("Invalid --restart-jitter-factor %v, ignoring", restartJitterFactor)
+		restartJitterFactor = 0
+	}
+	serviceSet.SetRestartJitterFactor(restartJitterFactor)
+	serviceSet.SetSkipPreExecChecks(skipPreExecChecks)
+
 	// Record boot timing (use first service as the boot timing target)
 	serviceSet.SetBootStartTime(bootStartTime)
+	serviceSet.SetVersion(version)
 	serviceSet.SetBootServiceName(bootServices[0])
 	if uptime, err := readKernelUptime(); err == nil {
 		serviceSet.SetKernelUptime(uptime)
@@ -779,6 +984,7 @@ func main() {
 			logger.Info("Platform override: %s", detectedPlatform)
 		} else {
 			logger.Error("Invalid --sys value %q (valid: docker, lxc, podman, wsl, xen0, xenu, openvz, vserver, systemd-nspawn, uml, rkt, none)", sysOverride)
+			logger.Close()
 			os.Exit(1)
 		}
 	} else {
@@ -789,8 +995,22 @@ func main() {
 	}
 
 	// Create and configure the loader
+	config.SetFileLocking(!noFileLocking)
 	loader := config.NewDirLoader(serviceSet, dirs)
 	loader.SetPlatform(detectedPlatform)
+	if checksumFile != "" {
+		loader.SetChecksumFile(checksumFile)
+		logger.Info("Verifying service files against %s", checksumFile)
+	}
+	if len(dirs) > 1 {
+		for _, w := range loader.CheckShadowing() {
+			if w.Masked {
+				logger.Warn("Service '%s' is masked by %s", w.ServiceName, w.ShadowedPath)
+			} else {
+				logger.Warn("Service file %s is shadowed by %s", w.ShadowedPath, w.ActivePath)
+			}
+		}
+	}
 
 	// Configure conf.d overlay directories.
 	// Default (--conf-dir not passed) keeps built-in /etc/slinit.conf.d.
@@ -821,14 +1041,23 @@ func main() {
 		loader.SetInitDDirs(initDDirs)
 	}
 
-	serviceSet.SetLoader(loader)
+	// Service directories take priority; the descriptions compiled into
+	// the binary are consulted only for names no directory provides.
+	if noEmbedded {
+		serviceSet.SetLoader(loader)
+	} else {
+		embedded := config.NewEmbeddedLoader(serviceSet, nil)
+		embedded.SetPlatform(detectedPlatform)
+		serviceSet.SetLoader(config.NewCompositeLoader(loader, embedded))
+	}
 
 	// Path-based activation: wire an inotify watcher for services that
 	// declare a start-on-path-* stanza. Hooked via OnServiceLoaded so
 	// both the initial boot load and dynamic `slinitctl load` produce
-	// armed watches. Re-arm on EventStopped via a per-service listener
-	// gives the systemd path-unit one-shot semantics (fires once per
-	// service-stopped cycle).
+	// armed watches, and via OnServiceReloaded so a reload that changes
+	// the stanza moves the watch. Re-arm on EventStopped via a
+	// per-service listener gives the systemd path-unit one-shot
+	// semantics (fires once per service-stopped cycle).
 	pathWatcher, pwErr := pathwatch.New(logger)
 	if pwErr != nil {
 		logger.Warn("Path activation disabled: %v", pwErr)
@@ -836,36 +1065,10 @@ func main() {
 		go pathWatcher.Run()
 		defer pathWatcher.Close()
 
-		serviceSet.OnServiceLoaded = func(svc service.Service) {
-			path, trig := svc.Record().StartOnPath()
-			if path == "" || trig == 0 {
-				return
-			}
-			kind := pathwatch.Trigger(trig)
-			s := svc
-			if err := pathWatcher.Add(path, kind, func() {
-				logger.Info("Service '%s': path activation fired (%s on %s)",
-					s.Name(), kind, path)
-				// Goroutine: pathwatch.arm() may fire this callback
-				// synchronously from pathRearmListener.ServiceEvent,
-				// which runs under queueMu inside the process-exit
-				// handler. StartService also takes queueMu, so a
-				// direct call self-deadlocks the moment the trigger
-				// path still exists when the service stops.
-				go serviceSet.StartService(s)
-			}); err != nil {
-				logger.Warn("Service '%s': %s disabled: %v", s.Name(), kind, err)
-				return
-			}
-			s.Record().AddListener(&pathRearmListener{
-				watcher: pathWatcher, path: path, logger: logger,
-			})
-		}
-		serviceSet.OnServiceUnloaded = func(svc service.Service) {
-			if path, _ := svc.Record().StartOnPath(); path != "" {
-				pathWatcher.Remove(path)
-			}
-		}
+		pa := newPathActivation(pathWatcher, serviceSet, logger)
+		serviceSet.OnServiceLoaded = pa.watch
+		serviceSet.OnServiceReloaded = pa.reloaded
+		serviceSet.OnServiceUnloaded = pa.unwatch
 	}
 
 	// Services-dir auto-watch (opt-in via --watch-services-dir): drop a
@@ -931,6 +1134,41 @@ func main() {
 		return
 	}
 
+	// Check the boot graph for dependencies that name no service file
+	// before anything starts; with --require-all-deps even optional
+	// ordering hints must resolve.
+	if validateDeps || requireAllDeps {
+		problems := loader.ValidateDependencyTree(bootServices, requireAllDeps)
+		for _, p := range problems {
+			logger.Warn("Missing dependency: %s", p)
+		}
+		if requireAllDeps && len(problems) > 0 {
+			logger.Error("%d boot dependencies cannot be loaded (--require-all-deps)", len(problems))
+			if isPID1 {
+				logger.Error("Rebooting in 10 seconds...")
+				time.Sleep(10 * time.Second)
+				closeWatchdog(wd, logger)
+				shutdown.Execute(service.ShutdownReboot, logger)
+			}
+			closeWatchdog(wd, logger)
+			logger.Close()
+			os.Exit(1)
+		}
+	}
+
+	// Preload (opt-in via --preload-services): load every described
+	// service up front, parsing files in parallel, so a large services
+	// dir is read once at boot rather than piecemeal. It stays opt-in
+	// because by default only the boot graph is loaded and everything
+	// else on demand; loading all of it also surfaces errors in
+	// services nobody asked for. Failures are logged and do not stop
+	// the boot services below.
+	if preloadServices {
+		if err := loader.PreloadConcurrent(0); err != nil {
+			logger.Warn("Preloading services: %v", err)
+		}
+	}
+
 	// Load and start boot services (-t svc1 -t svc2 ... or positional args)
 	startedAny := false
 	for _, svcName := range bootServices {
@@ -963,6 +1201,7 @@ func main() {
 		if containerMode {
 			logger.Error("No boot services could be loaded, exiting (container mode)")
 			closeWatchdog(wd, logger)
+			logger.Close()
 			os.Exit(1)
 		}
 		if isPID1 {
@@ -974,6 +1213,7 @@ func main() {
 			shutdown.Execute(service.ShutdownReboot, logger)
 		}
 		closeWatchdog(wd, logger)
+		logger.Close()
 		os.Exit(1)
 	}
 
@@ -995,6 +1235,14 @@ func main() {
 	// in use.
 	pinStore := persist.NewPinStore(persistIntentDir)
 	ctrlServer.Pins = pinStore
+	ctrlServer.RateLimitCapacity = rateLimitCapacity
+	ctrlServer.RateLimitRefill = rateLimitRefill
+	if access, err := controlAccessPolicy(controlAllow, controlReadOnly); err != nil {
+		// Fail closed: only root and our own user get in.
+		logger.Error("Control socket access policy: %v", err)
+	} else {
+		ctrlServer.Access = access
+	}
 
 	if err := ctrlServer.Start(ctx); err != nil {
 		logger.Error("Failed to start control socket: %v", err)
@@ -1003,6 +1251,18 @@ func main() {
 		defer ctrlServer.Stop()
 	}
 
+	// Optional remote control over mutually authenticated TLS. A
+	// misconfiguration is logged rather than fatal, like the Unix
+	// socket: the machine must still boot.
+	if listenTCP != "" {
+		tlsConfig, err := control.ServerTLSConfig(tlsCert, tlsKey, tlsCA)
+		if err != nil {
+			logger.Error("--listen-tcp: %v", err)
+		} else if err := ctrlServer.StartTCP(ctx, listenTCP, tlsConfig); err != nil {
+			logger.Error("Failed to listen on %s: %v", listenTCP, err)
+		}
+	}
+
 	// Replay any persisted pins BEFORE the boot cascade runs so a
 	// service marked pinned-stopped never briefly comes up first.
 	// Errors from the store are logged; a broken file for one service
@@ -1037,6 +1297,32 @@ func main() {
 	// Boot loop: runs the event loop, handles boot failures with recovery
 	for {
 		loop := eventloop.New(serviceSet, logger)
+		loop.OnReopenLog = func() {
+			if logger.HoldingMainLog() && serviceSet.LogReady() {
+				if sb, err := logging.NewSyslogBackend(syslogFacility); err == nil {
+					logger.ConnectSyslog(sb)
+				}
+			} else if err := logger.ReopenSyslog(); err != nil {
+				logger.Error("Failed to reconnect to syslog: %v", err)
+			}
+			serviceSet.ReopenLogFiles()
+		}
+		if sighupAction == "reload" {
+			loop.OnReloadConfig = func() {
+				results := serviceSet.ReloadAll(nil)
+				for _, r := range results {
+					switch r.Outcome {
+					case service.ReloadRestartNeeded:
+						logger.Info("SIGHUP reload: service '%s' changed; restart it to apply", r.Name)
+					case service.ReloadFailed:
+						logger.Error("SIGHUP reload: service '%s': %s", r.Name, r.Detail)
+					}
+				}
+				reloaded, failed := service.ReloadCounts(results)
+				logger.Info("SIGHUP reload: %d service(s) reloaded, %d failed", reloaded, failed)
+			}
+		}
+		loop.SignalMapping = signalMapping
 
 		if containerMode {
 			loop.SetContainerMode(true)
@@ -1049,6 +1335,9 @@ func main() {
 		// event loop's built-in default (90s); the setter handles the
 		// fallback so we don't hard-code the default twice.
 		loop.SetEmergencyTimeout(emergencyTimeout)
+		loop.SetServiceStopDeadlines(serviceStopTimeouts)
+		loop.SetPowerStatusFile(powerStatusFile)
+		loop.SetPowerFailGrace(powerFailGrace)
 
 		ctrlServer.ShutdownFunc = func(st service.ShutdownType) {
 			loop.InitiateShutdown(st)
@@ -1090,6 +1379,22 @@ func main() {
 		ctrlServer.WallNoticeFunc = func(msg string) {
 			shutdown.Wall(msg, logger)
 		}
+		loop.OnDumpDiagnostics = func() {
+			f, err := os.CreateTemp("", "slinit-diagnostics-*.txt")
+			if err != nil {
+				logger.Error("Failed to create diagnostics file: %v", err)
+				return
+			}
+			err = serviceSet.PrintDiagnostics(f)
+			if cerr := f.Close(); err == nil {
+				err = cerr
+			}
+			if err != nil {
+				logger.Error("Failed to write diagnostics to %s: %v", f.Name(), err)
+				return
+			}
+			logger.Notice("Diagnostics written to %s", f.Name())
+		}
 		loop.OnReopenSocket = func() {
 			if err := ctrlServer.Reopen(); err != nil {
 				logger.Error("Failed to reopen control socket: %v", err)
@@ -1204,6 +1509,7 @@ func main() {
 				logger.Debug("Failed to write container results: %v", err)
 			}
 			closeWatchdog(wd, logger)
+			logger.Close()
 			os.Exit(exitCode)
 		}
 
@@ -1272,25 +1578,6 @@ func main() {
 	logger.Info("slinit shutdown complete")
 }
 
-// pathRearmListener re-arms a path-activation watch each time the
-// service becomes STOPPED. Registered per-service by the
-// OnServiceLoaded hook above; gives the watcher one-shot semantics
-// (fires once per service-stopped cycle), matching systemd path units.
-type pathRearmListener struct {
-	watcher *pathwatch.Watcher
-	path    string
-	logger  *logging.Logger
-}
-
-func (l *pathRearmListener) ServiceEvent(svc service.Service, event service.ServiceEvent) {
-	if event != service.EventStopped {
-		return
-	}
-	if err := l.watcher.Rearm(l.path); err != nil {
-		l.logger.Warn("Service '%s': path watch re-arm failed: %v", svc.Name(), err)
-	}
-}
-
 // handlePID1Shutdown performs the appropriate system action after all services
 // have stopped when running as PID 1. Called only for explicit shutdowns
 // (shutdownType != ShutdownNone). This function does not return.
@@ -1346,7 +1633,7 @@ func runRescueShell(logger *logging.Logger) {
 	}
 	// setsid so the shell owns its own controlling tty.
 	cmd.SysProcAttr = &syscall.SysProcAttr{Setsid: true, Setctty: true, Ctty: 0}
-	if err := cmd.Run(); err != nil {
+	if err := process.Run(cmd); err != nil {
 		logger.Error("slinit.rescue: shell exited with error: %v", err)
 	}
 }
@@ -1375,6 +1662,24 @@ func noColor() bool {
 	return set
 }
 
+// connectHeldSyslog connects the main log to syslog once a starts-log
+// service is up, replaying the messages held since boot. The logger
+// may take a moment after being started to create /dev/log, so the
+// connection is retried for a few seconds before giving up; a later
+// SIGHUP tries again.
+func connectHeldSyslog(logger *logging.Logger, facility syslog.Priority) {
+	var err error
+	for i := 0; i < 25; i++ {
+		var sb *logging.SyslogBackend
+		if sb, err = logging.NewSyslogBackend(facility); err == nil {
+			logger.ConnectSyslog(sb)
+			return
+		}
+		time.Sleep(200 * time.Millisecond)
+	}
+	logger.Warn("System logger is ready but syslog is unavailable: %v", err)
+}
+
 func parseLogLevel(s string) logging.Level {
 	switch strings.ToLower(s) {
 	case "debug":
@@ -1820,6 +2125,19 @@ func waitForFDClose(n int) error {
 	}
 }
 
+// detectContainerPlatform returns the container platform slinit runs
+// in, or platform.None. A --sys override takes the place of detection.
+func detectContainerPlatform(sysOverride string) platform.Type {
+	if sysOverride != "" {
+		t := platform.Type(strings.ToLower(sysOverride))
+		if platform.IsContainer(t) {
+			return t
+		}
+		return platform.None
+	}
+	return platform.DetectContainer()
+}
+
 // containerExitCode extracts the exit code from the first boot service
 // that has a non-zero exit status. Returns 0 if all services exited cleanly.
 func containerExitCode(ss *service.ServiceSet, bootNames []string) int {
@@ -1839,3 +2157,20 @@ func containerExitCode(ss *service.ServiceSet, bootNames []string) int {
 	}
 	return 0
 }
+
+// controlAccessPolicy builds the control socket access policy from the
+// --control-allow and --control-read-only lists; nil if both are empty.
+func controlAccessPolicy(allow, readOnly string) (*control.AccessPolicy, error) {
+	if allow == "" && readOnly == "" {
+		return nil, nil
+	}
+	var p control.AccessPolicy
+	var err error
+	if p.FullUIDs, p.FullGIDs, err = control.ParseAccessList(allow); err != nil {
+		return nil, fmt.Errorf("--control-allow: %w", err)
+	}
+	if p.ReadUIDs, p.ReadGIDs, err = control.ParseAccessList(readOnly); err != nil {
+		return nil, fmt.Errorf("--control-read-only: %w", err)
+	}
+	return &p, nil
+}
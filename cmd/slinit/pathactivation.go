@@ -0,0 +1,120 @@
+package main
+
+import (
+	"sync"
+
+	"github.com/sunlightlinux/slinit/pkg/logging"
+	"github.com/sunlightlinux/slinit/pkg/pathwatch"
+	"github.com/sunlightlinux/slinit/pkg/service"
+)
+
+// pathActivation keeps the path watcher in step with the loaded
+// services: a watch per service with a start-on-path-* stanza,
+// registered on load, dropped on unload, and replaced when a reload
+// changes the stanza or swaps the service record for a new one.
+type pathActivation struct {
+	watcher *pathwatch.Watcher
+	set     *service.ServiceSet
+	logger  *logging.Logger
+
+	mu   sync.Mutex
+	regs map[string]*pathReg // service name → its watch
+}
+
+// pathReg is one service's registered watch.
+type pathReg struct {
+	svc      service.Service
+	path     string
+	trigger  int
+	listener *pathRearmListener
+}
+
+func newPathActivation(w *pathwatch.Watcher, set *service.ServiceSet, logger *logging.Logger) *pathActivation {
+	return &pathActivation{watcher: w, set: set, logger: logger, regs: make(map[string]*pathReg)}
+}
+
+// watch registers svc's path trigger, if it has one, replacing any
+// earlier registration under the same name.
+func (pa *pathActivation) watch(svc service.Service) {
+	pa.mu.Lock()
+	defer pa.mu.Unlock()
+	pa.unwatchLocked(svc.Name())
+	pa.watchLocked(svc)
+}
+
+// unwatch drops svc's watch.
+func (pa *pathActivation) unwatch(svc service.Service) {
+	pa.mu.Lock()
+	defer pa.mu.Unlock()
+	pa.unwatchLocked(svc.Name())
+}
+
+// reloaded re-registers the watch after a reload of oldSvc produced
+// newSvc (the same record unless the type changed). An unchanged
+// stanza on the same record keeps its watch, so a reload does not
+// re-fire a trigger that has already fired.
+func (pa *pathActivation) reloaded(oldSvc, newSvc service.Service) {
+	pa.mu.Lock()
+	defer pa.mu.Unlock()
+	path, trig := newSvc.Record().StartOnPath()
+	if reg := pa.regs[oldSvc.Name()]; reg != nil && reg.svc == newSvc &&
+		reg.path == path && reg.trigger == trig {
+		return
+	}
+	pa.unwatchLocked(oldSvc.Name())
+	pa.watchLocked(newSvc)
+}
+
+func (pa *pathActivation) watchLocked(svc service.Service) {
+	path, trig := svc.Record().StartOnPath()
+	if path == "" || trig == 0 {
+		return
+	}
+	kind := pathwatch.Trigger(trig)
+	if err := pa.watcher.Add(path, kind, func() {
+		pa.logger.Info("Service '%s': path activation fired (%s on %s)",
+			svc.Name(), kind, path)
+		// Goroutine: pathwatch.arm() may fire this callback
+		// synchronously from pathRearmListener.ServiceEvent,
+		// which runs under queueMu inside the process-exit
+		// handler. StartService also takes queueMu, so a
+		// direct call self-deadlocks the moment the trigger
+		// path still exists when the service stops.
+		go pa.set.StartService(svc)
+	}); err != nil {
+		pa.logger.Warn("Service '%s': %s disabled: %v", svc.Name(), kind, err)
+		return
+	}
+	l := &pathRearmListener{watcher: pa.watcher, path: path, logger: pa.logger}
+	svc.Record().AddListener(l)
+	pa.regs[svc.Name()] = &pathReg{svc: svc, path: path, trigger: trig, listener: l}
+}
+
+func (pa *pathActivation) unwatchLocked(name string) {
+	reg := pa.regs[name]
+	if reg == nil {
+		return
+	}
+	delete(pa.regs, name)
+	reg.svc.Record().RemoveListener(reg.listener)
+	pa.watcher.Remove(reg.path)
+}
+
+// pathRearmListener re-arms a path-activation watch each time the
+// service becomes STOPPED. Registered per-service by pathActivation;
+// gives the watcher one-shot semantics (fires once per service-stopped
+// cycle), matching systemd path units.
+type pathRearmListener struct {
+	watcher *pathwatch.Watcher
+	path    string
+	logger  *logging.Logger
+}
+
+func (l *pathRearmListener) ServiceEvent(svc service.Service, event service.ServiceEvent) {
+	if event != service.EventStopped {
+		return
+	}
+	if err := l.watcher.Rearm(l.path); err != nil {
+		l.logger.Warn("Service '%s': path watch re-arm failed: %v", svc.Name(), err)
+	}
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/sunlightlinux/slinit/pkg/config"
+	"github.com/sunlightlinux/slinit/pkg/logging"
+	"github.com/sunlightlinux/slinit/pkg/pathwatch"
+	"github.com/sunlightlinux/slinit/pkg/service"
+)
+
+// TestPathActivationFollowsReload checks that a reload which changes
+// start-on-path-exists moves the watch to the new path.
+func TestPathActivationFollowsReload(t *testing.T) {
+	svcDir := t.TempDir()
+	watchDir := t.TempDir()
+	logger := logging.New(logging.LevelError)
+
+	w, err := pathwatch.New(logger)
+	if err != nil {
+		t.Skipf("inotify unavailable: %v", err)
+	}
+	go w.Run()
+	defer w.Close()
+
+	set := service.NewServiceSet(logger)
+	loader := config.NewDirLoader(set, []string{svcDir})
+	set.SetLoader(loader)
+	pa := newPathActivation(w, set, logger)
+	set.OnServiceLoaded = pa.watch
+	set.OnServiceReloaded = pa.reloaded
+	set.OnServiceUnloaded = pa.unwatch
+
+	writeSvc := func(path string) {
+		content := "type = internal\nstart-on-path-exists = " + path + "\n"
+		if err := os.WriteFile(filepath.Join(svcDir, "spooler"), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	oldPath := filepath.Join(watchDir, "lp0")
+	newPath := filepath.Join(watchDir, "lp1")
+
+	writeSvc(oldPath)
+	svc, err := loader.LoadService("spooler")
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	writeSvc(newPath)
+	if svc, err = loader.ReloadService(svc); err != nil {
+		t.Fatalf("reload: %v", err)
+	}
+
+	if err := os.WriteFile(oldPath, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(100 * time.Millisecond)
+	if svc.State() != service.StateStopped {
+		t.Fatalf("old path still watched: state %v", svc.State())
+	}
+
+	if err := os.WriteFile(newPath, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for svc.State() != service.StateStarted && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if svc.State() != service.StateStarted {
+		t.Fatalf("new path did not activate the service: state %v", svc.State())
+	}
+
+	// Dropping the stanza drops the watch. The path goes first so the
+	// re-arm on stop does not start the service again.
+	if err := os.Remove(newPath); err != nil {
+		t.Fatal(err)
+	}
+	set.StopService(svc)
+	if err := os.WriteFile(filepath.Join(svcDir, "spooler"), []byte("type = internal\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := loader.ReloadService(svc); err != nil {
+		t.Fatalf("reload: %v", err)
+	}
+	if len(pa.regs) != 0 {
+		t.Errorf("watch kept after the stanza was removed: %v", pa.regs)
+	}
+}
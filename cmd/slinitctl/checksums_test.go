@@ -0,0 +1,58 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestGenerateChecksums(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"b", "a", ".hidden"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("type = internal\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Patch files in <service>.d are hashed too; anything else there is not.
+	if err := os.Mkdir(filepath.Join(dir, "a.d"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"10-cmd.patch", "README"} {
+		if err := os.WriteFile(filepath.Join(dir, "a.d", name), []byte("=command = /bin/a\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var out bytes.Buffer
+	if code := cmdGenerateChecksums(&out, dir, nil); code != 0 {
+		t.Fatalf("exit code %d", code)
+	}
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 3 || !strings.HasSuffix(lines[0], "  "+filepath.Join(dir, "a")) ||
+		!strings.HasSuffix(lines[1], "  "+filepath.Join(dir, "a.d", "10-cmd.patch")) ||
+		!strings.HasSuffix(lines[2], "  "+filepath.Join(dir, "b")) {
+		t.Fatalf("unexpected output:\n%s", out.String())
+	}
+
+	// The output file is left out of its own sums.
+	output := filepath.Join(dir, "checksums")
+	if code := cmdGenerateChecksums(&out, dir, []string{"--output", output}); code != 0 {
+		t.Fatalf("exit code %d", code)
+	}
+	if code := cmdGenerateChecksums(&out, dir, []string{"--output=" + output}); code != 0 {
+		t.Fatalf("exit code %d", code)
+	}
+	data, err := os.ReadFile(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Count(string(data), "\n"); got != 3 || strings.Contains(string(data), output) {
+		t.Errorf("unexpected checksum file:\n%s", data)
+	}
+
+	if code := cmdGenerateChecksums(&out, dir, []string{"extra"}); code == 0 {
+		t.Error("unexpected argument should fail")
+	}
+}
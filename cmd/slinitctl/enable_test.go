@@ -0,0 +1,94 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sunlightlinux/slinit/pkg/config"
+	"github.com/sunlightlinux/slinit/pkg/logging"
+	"github.com/sunlightlinux/slinit/pkg/service"
+)
+
+// TestOfflineEnableRoundTrip checks that an offline enable writes the
+// link into the waits-for.d directory the source service declares, so
+// the loader picks the dependency up on the next boot, and that disable
+// removes it again.
+func TestOfflineEnableRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("boot", "type = internal\nwaits-for.d: boot.d\n")
+	write("sshd", "type = internal\n")
+
+	if err := offlineEnable(dir, "", "sshd"); err != nil {
+		t.Fatalf("enable: %v", err)
+	}
+	link := filepath.Join(dir, "boot.d", "sshd")
+	if dest, err := os.Readlink(link); err != nil || dest != filepath.Join("..", "sshd") {
+		t.Fatalf("link %s -> %q, %v; want ../sshd", link, dest, err)
+	}
+	// Enabling twice is harmless.
+	if err := offlineEnable(dir, "", "sshd"); err != nil {
+		t.Fatalf("second enable: %v", err)
+	}
+
+	set := service.NewServiceSet(logging.New(logging.LevelError))
+	loader := config.NewDirLoader(set, []string{dir})
+	set.SetLoader(loader)
+	boot, err := loader.LoadService("boot")
+	if err != nil {
+		t.Fatalf("load boot: %v", err)
+	}
+	found := false
+	for _, dep := range boot.Record().Dependencies() {
+		if dep.To.Name() == "sshd" && dep.DepType == service.DepWaitsFor {
+			found = true
+		}
+	}
+	if !found {
+		t.Error("loader did not pick up the enabled waits-for dependency")
+	}
+
+	if err := offlineDisable(dir, "", "sshd"); err != nil {
+		t.Fatalf("disable: %v", err)
+	}
+	if _, err := os.Lstat(link); !os.IsNotExist(err) {
+		t.Errorf("expected link removed, lstat err = %v", err)
+	}
+}
+
+func TestOfflineEnableErrors(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "boot"), []byte("type = internal\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "sshd"), []byte("type = internal\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := offlineEnable(dir, "", "missing"); err == nil {
+		t.Error("enable of a missing service: expected error")
+	}
+	if err := offlineEnable(dir, "", "sshd"); err == nil {
+		t.Error("enable from a service without waits-for.d: expected error")
+	}
+}
+
+func TestOfflineEnableVia(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "net"), []byte("type = internal\nwaits-for.d: net.d\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "dhcp"), []byte("type = internal\n@meta enable-via net\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := offlineEnable(dir, "", "dhcp"); err != nil {
+		t.Fatalf("enable: %v", err)
+	}
+	if _, err := os.Lstat(filepath.Join(dir, "net.d", "dhcp")); err != nil {
+		t.Errorf("expected link in net.d: %v", err)
+	}
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/sunlightlinux/slinit/pkg/process"
+)
+
+func TestEncryptEnvFile(t *testing.T) {
+	t.Setenv("SLINIT_TEST_ENV_KEY", strings.Repeat("0f", 32))
+	var out bytes.Buffer
+	if code := cmdEncryptEnvFile(strings.NewReader("TOKEN=s3cret\n"), &out, []string{"--key", "env:SLINIT_TEST_ENV_KEY"}); code != 0 {
+		t.Fatalf("exit code %d", code)
+	}
+	path := filepath.Join(t.TempDir(), "secrets.enc")
+	if err := os.WriteFile(path, out.Bytes(), 0600); err != nil {
+		t.Fatal(err)
+	}
+	env, err := process.LoadEncryptedEnvFile(path, "env:SLINIT_TEST_ENV_KEY")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(env) != 1 || env[0] != "TOKEN=s3cret" {
+		t.Errorf("decrypted %q", env)
+	}
+
+	for _, args := range [][]string{nil, {"--key"}, {"--key=vault:x"}, {"extra"}} {
+		if code := cmdEncryptEnvFile(strings.NewReader(""), &out, args); code == 0 {
+			t.Errorf("args %q should fail", args)
+		}
+	}
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"bytes"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sunlightlinux/slinit/pkg/control"
+	"github.com/sunlightlinux/slinit/pkg/service"
+)
+
+func TestParseEventsArgs(t *testing.T) {
+	since, follow, names, err := parseEventsArgs([]string{"--since", "5m", "--follow"})
+	if err != nil || since != 5*time.Minute || !follow || names != nil {
+		t.Errorf("got %v, %v, %v, %v", since, follow, names, err)
+	}
+	since, follow, names, err = parseEventsArgs([]string{"--since=1h"})
+	if err != nil || since != time.Hour || follow {
+		t.Errorf("got %v, %v, %v", since, follow, err)
+	}
+	_, follow, names, err = parseEventsArgs([]string{"sshd", "-f", "nginx"})
+	if err != nil || !follow || len(names) != 2 || names[0] != "sshd" || names[1] != "nginx" {
+		t.Errorf("got %v, %v, %v", follow, names, err)
+	}
+	for _, bad := range [][]string{{"--since"}, {"--since", "soon"}, {"--tail"}} {
+		if _, _, _, err := parseEventsArgs(bad); err == nil {
+			t.Errorf("parseEventsArgs(%q) should fail", bad)
+		}
+	}
+}
+
+func TestFormatGlobalEvent(t *testing.T) {
+	ts := time.Date(2026, 1, 2, 15, 4, 5, 0, time.Local)
+	got := formatGlobalEvent(service.GlobalEvent{
+		Time: ts, Service: "sshd", Event: service.EventStopped, Details: "exit code 1",
+	})
+	want := "2026-01-02 15:04:05.000  STOPPED         sshd  (exit code 1)"
+	if got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	got = formatGlobalEvent(service.GlobalEvent{Time: ts, Service: "sshd", Event: service.EventStarted})
+	if want := "2026-01-02 15:04:05.000  STARTED         sshd"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestCmdMonitor(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+	ts := time.Date(2026, 1, 2, 15, 4, 5, 0, time.Local)
+
+	go func() {
+		defer server.Close()
+		cmd, payload, err := control.ReadPacket(server)
+		if err != nil {
+			return
+		}
+		if names, _ := control.DecodeSubscribeEvents(payload); cmd != control.CmdSubscribeEvents ||
+			len(names) != 1 || names[0] != "sshd" {
+			t.Errorf("got cmd %d names %q", cmd, names)
+		}
+		// Only the InfoGlobalEvent push is printed.
+		ev := control.EncodeGlobalEvent(service.GlobalEvent{Time: ts, Service: "sshd", Event: service.EventStarted})
+		for _, p := range []struct {
+			kind    uint8
+			payload []byte
+		}{{control.RplyACK, nil}, {control.InfoServiceEvent, []byte{0, 0, 0}}, {control.InfoGlobalEvent, ev}} {
+			if err := control.WritePacket(server, p.kind, p.payload); err != nil {
+				return
+			}
+		}
+	}()
+
+	var out bytes.Buffer
+	if err := cmdMonitor(&out, client, []string{"sshd"}); err != nil {
+		t.Fatalf("cmdMonitor: %v", err)
+	}
+	if want := "2026-01-02 15:04:05.000  STARTED         sshd\n"; out.String() != want {
+		t.Errorf("output %q, want %q", out.String(), want)
+	}
+}
+
+func TestFormatBootTimeline(t *testing.T) {
+	base := time.Unix(1000, 0)
+	got := formatBootTimeline([]service.BootEvent{
+		{Time: base, Type: service.BootEventKernel},
+		{Time: base.Add(1500 * time.Millisecond), Type: service.BootEventServiceStarted, Service: "sshd"},
+	})
+	want := []string{"+0us       kernel", "+1.500s    started  sshd"}
+	if len(got) != len(want) {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("line %d = %q, want %q", i, got[i], want[i])
+		}
+	}
+}
+
+func TestWriteCriticalChain(t *testing.T) {
+	base := time.Unix(1000, 0)
+	var out bytes.Buffer
+	writeCriticalChain(&out, []service.ChainLink{
+		{Name: "boot", Started: base.Add(2 * time.Second)},
+		{Name: "network", Started: base.Add(1200 * time.Millisecond), Took: 1100 * time.Millisecond},
+		{Name: "udev", Started: base.Add(100 * time.Millisecond), Took: 100 * time.Millisecond},
+	}, base)
+	lines := strings.Split(strings.TrimRight(out.String(), "\n"), "\n")
+	want := []string{"boot @2.000s", "└─network @1.200s +1.100s", "  └─udev @100ms +100ms"}
+	if len(lines) != 3+len(want) {
+		t.Fatalf("output:\n%s", out.String())
+	}
+	for i, w := range want {
+		if lines[3+i] != w {
+			t.Errorf("line %d = %q, want %q", i, lines[3+i], w)
+		}
+	}
+}
+
+func TestParseWatchArgs(t *testing.T) {
+	incremental, interval, err := parseWatchArgs(nil)
+	if err != nil || incremental || interval != 2*time.Second {
+		t.Errorf("defaults: got %v, %v, %v", incremental, interval, err)
+	}
+	incremental, interval, err = parseWatchArgs([]string{"--incremental", "--interval=500ms"})
+	if err != nil || !incremental || interval != 500*time.Millisecond {
+		t.Errorf("got %v, %v, %v", incremental, interval, err)
+	}
+	for _, bad := range [][]string{{"--interval"}, {"--interval", "0"}, {"--interval=soon"}, {"sshd"}} {
+		if _, _, err := parseWatchArgs(bad); err == nil {
+			t.Errorf("parseWatchArgs(%q) should fail", bad)
+		}
+	}
+}
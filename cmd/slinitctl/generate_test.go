@@ -0,0 +1,25 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestGenerateUnitArgs(t *testing.T) {
+	var out bytes.Buffer
+	if code := cmdGenerateUnit(&out, nil); code != 1 {
+		t.Errorf("no args: exit %d, want 1", code)
+	}
+	if code := cmdGenerateUnit(&out, []string{"--from-pid", "abc"}); code != 1 {
+		t.Errorf("bad pid: exit %d, want 1", code)
+	}
+	if code := cmdGenerateUnit(&out, []string{fmt.Sprintf("--from-pid=%d", os.Getpid())}); code != 0 {
+		t.Fatalf("own pid: exit %d, want 0", code)
+	}
+	if !strings.Contains(out.String(), "type = process") {
+		t.Errorf("unexpected output:\n%s", out.String())
+	}
+}
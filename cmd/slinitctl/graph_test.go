@@ -1,6 +1,9 @@
 package main
 
 import (
+	"bytes"
+	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/sunlightlinux/slinit/pkg/service"
@@ -66,3 +69,70 @@ func TestGraphEdgeStyle(t *testing.T) {
 		}
 	}
 }
+
+func TestPrintGraphTiers(t *testing.T) {
+	var buf bytes.Buffer
+	printGraphTiers(&buf, [][]string{{"d"}, {"b", "c"}, {"a"}})
+	want := "TIER 0  TIER 1  TIER 2\n" +
+		"d       b       a\n" +
+		"        c\n"
+	if buf.String() != want {
+		t.Errorf("got:\n%s\nwant:\n%s", buf.String(), want)
+	}
+}
+
+func TestGraphTiersBeforeAfter(t *testing.T) {
+	nodes := []service.GraphNode{{Name: "root"}, {Name: "a"}, {Name: "b"}, {Name: "c"}}
+	edges := []service.GraphEdge{
+		{From: "root", To: "a", Type: service.DepRegular},
+		{From: "root", To: "b", Type: service.DepRegular},
+		{From: "root", To: "c", Type: service.DepRegular},
+		{From: "a", To: "b", Type: service.DepBefore},
+		{From: "c", To: "a", Type: service.DepAfter},
+	}
+	layers, err := graphTiers(nodes, edges)
+	if err != nil {
+		t.Fatalf("graphTiers: %v", err)
+	}
+	want := [][]string{{"a"}, {"b", "c"}, {"root"}}
+	if !reflect.DeepEqual(layers, want) {
+		t.Errorf("got %v, want %v", layers, want)
+	}
+}
+
+func TestGraphFrom(t *testing.T) {
+	nodes := []service.GraphNode{{Name: "app"}, {Name: "db"}, {Name: "net"}, {Name: "other"}}
+	edges := []service.GraphEdge{
+		{From: "app", To: "db", Type: service.DepRegular},
+		{From: "db", To: "net", Type: service.DepWaitsFor},
+		{From: "other", To: "net", Type: service.DepRegular},
+		{From: "db", To: "other", Type: service.DepAfter},
+	}
+	subNodes, subEdges := graphFrom(nodes, edges, "db")
+	if len(subNodes) != 2 || subNodes[0].Name != "db" || subNodes[1].Name != "net" {
+		t.Errorf("nodes = %+v", subNodes)
+	}
+	if len(subEdges) != 1 || subEdges[0] != edges[1] {
+		t.Errorf("edges = %+v", subEdges)
+	}
+	if n, e := graphFrom(nodes, edges, "missing"); n != nil || e != nil {
+		t.Errorf("missing root: %+v %+v", n, e)
+	}
+}
+
+func TestWriteGraphDOT(t *testing.T) {
+	var b strings.Builder
+	writeGraphDOT(&b,
+		[]service.GraphNode{{Name: "app", Type: service.TypeProcess, State: service.StateStarted}},
+		[]service.GraphEdge{{From: "app", To: "db", Type: service.DepSoft}})
+	out := b.String()
+	for _, want := range []string{
+		"digraph services {",
+		`"app" [shape=ellipse style=filled fillcolor="#c8e6c9" color="#2e7d32"];`,
+		`"app" -> "db" [style=dashed color="#7b1fa2" label="soft"];`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output missing %q:\n%s", want, out)
+		}
+	}
+}
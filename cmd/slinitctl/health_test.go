@@ -0,0 +1,35 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/sunlightlinux/slinit/pkg/control"
+)
+
+func TestFormatHealth(t *testing.T) {
+	now := time.Unix(1000, 0)
+	cases := []struct {
+		h    control.HealthInfo
+		want string
+	}{
+		{control.HealthInfo{Flags: control.HealthFlagConfigured}, "PENDING (no check yet)"},
+		{control.HealthInfo{
+			Flags:     control.HealthFlagConfigured | control.HealthFlagHealthy,
+			LastCheck: now.Add(-5500 * time.Millisecond),
+		}, "OK (last check: 5s ago)"},
+		{control.HealthInfo{
+			Flags: control.HealthFlagConfigured, Failures: 3, MaxFailures: 3,
+			LastCheck: now,
+		}, "FAILING (3/3 retries)"},
+		{control.HealthInfo{
+			Flags: control.HealthFlagConfigured, Failures: 7,
+			LastCheck: now,
+		}, "FAILING (7 failures)"},
+	}
+	for _, c := range cases {
+		if got := formatHealth(c.h, now); got != c.want {
+			t.Errorf("formatHealth(%+v) = %q, want %q", c.h, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sunlightlinux/slinit/pkg/service"
+)
+
+func TestWriteJobs(t *testing.T) {
+	now := time.Date(2026, 5, 4, 12, 0, 0, 0, time.Local)
+	var b strings.Builder
+	writeJobs(&b, []service.Job{
+		{ID: 3, Kind: service.JobStart, Service: "network", State: service.JobDone,
+			Created: now.Add(-time.Minute), Finished: now.Add(-50 * time.Second)},
+		{ID: 4, Kind: service.JobStop, Service: "database", State: service.JobRunning,
+			Created: now.Add(-2 * time.Minute)},
+	}, now)
+
+	lines := strings.Split(strings.TrimRight(b.String(), "\n"), "\n")
+	if len(lines) != 3 || !strings.HasPrefix(lines[0], "JOB") {
+		t.Fatalf("unexpected table:\n%s", b.String())
+	}
+	if got := strings.Join(strings.Fields(lines[1]), " "); got != "3 start done 10s network" {
+		t.Errorf("row = %q", lines[1])
+	}
+	if got := strings.Join(strings.Fields(lines[2]), " "); got != "4 stop running 2m database" {
+		t.Errorf("running row = %q", lines[2])
+	}
+}
+
+func TestParseJobID(t *testing.T) {
+	if id, err := parseJobID("12"); err != nil || id != 12 {
+		t.Errorf("parseJobID(12) = %d, %v", id, err)
+	}
+	for _, bad := range []string{"0", "-1", "x", "4294967296"} {
+		if _, err := parseJobID(bad); err == nil {
+			t.Errorf("parseJobID(%q) succeeded", bad)
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCmdLintExitCodes(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		p := filepath.Join(dir, name)
+		if err := os.WriteFile(p, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return p
+	}
+	warnOnly := write("warn", "type = process\ncommand = /bin/d\nrestart = yes\n")
+	withErr := write("err", "type = process\ncommand = /bin/d\noptions = signal-process-only kill-all-on-stop\n")
+
+	var out bytes.Buffer
+	if code := cmdLint(&out, []string{warnOnly}); code != 0 {
+		t.Errorf("warnings only: exit %d, want 0", code)
+	}
+	if !strings.Contains(out.String(), warnOnly+": W003 warning:") ||
+		!strings.Contains(out.String(), "    suggestion: ") {
+		t.Errorf("unexpected output:\n%s", out.String())
+	}
+
+	out.Reset()
+	if code := cmdLint(&out, []string{warnOnly, withErr}); code != 1 {
+		t.Errorf("with error: exit %d, want 1", code)
+	}
+	if !strings.Contains(out.String(), withErr+": E001 error:") {
+		t.Errorf("unexpected output:\n%s", out.String())
+	}
+
+	if code := cmdLint(&out, []string{filepath.Join(dir, "missing")}); code != 1 {
+		t.Errorf("missing file: exit %d, want 1", code)
+	}
+}
+
+func TestCmdValidate(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		p := filepath.Join(dir, name)
+		if err := os.WriteFile(p, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return p
+	}
+	write("db", "type = internal\n")
+	good := write("good", "type = internal\ndepends-on: db\n")
+	bad := write("bad", "type = internal\ndepends-on: db\nwaits-for: cache\n")
+
+	var out bytes.Buffer
+	if code := cmdValidate(&out, "", []string{good}); code != 0 || out.Len() != 0 {
+		t.Errorf("good: exit %d, output %q", code, out.String())
+	}
+	if code := cmdValidate(&out, "", []string{bad}); code != 1 {
+		t.Errorf("bad: exit %d, want 1", code)
+	}
+	if want := bad + ": missing dependency 'cache'\n"; out.String() != want {
+		t.Errorf("output = %q, want %q", out.String(), want)
+	}
+}
+
+func TestCmdValidateReportsValidation(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		p := filepath.Join(dir, name)
+		if err := os.WriteFile(p, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return p
+	}
+	warn := write("warn", "type = process\ncommand = /bin/d\nterm-signal = KILL\n")
+	bad := write("bad", "type = bgprocess\ncommand = /bin/d\n")
+
+	var out bytes.Buffer
+	if code := cmdValidate(&out, "", []string{warn}); code != 0 {
+		t.Errorf("warning only: exit %d, want 0", code)
+	}
+	if want := warn + ": term-signal warning: "; !strings.HasPrefix(out.String(), want) {
+		t.Errorf("output = %q, want prefix %q", out.String(), want)
+	}
+
+	out.Reset()
+	if code := cmdValidate(&out, "", []string{bad}); code != 1 {
+		t.Errorf("error: exit %d, want 1", code)
+	}
+	if want := bad + ": pid-file error: bgprocess service requires a pid-file\n"; out.String() != want {
+		t.Errorf("output = %q, want %q", out.String(), want)
+	}
+}
@@ -0,0 +1,25 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/sunlightlinux/slinit/pkg/control"
+)
+
+func TestFormatSuffix(t *testing.T) {
+	cases := []struct {
+		e    control.SvcInfoEntry
+		want string
+	}{
+		{control.SvcInfoEntry{}, ""},
+		{control.SvcInfoEntry{PID: 42}, " (pid: 42)"},
+		{control.SvcInfoEntry{PID: 42, Flags: control.StatusFlagHasConsole}, " (pid: 42, has console)"},
+		{control.SvcInfoEntry{Pin: control.PinStopped}, " (pinned stopped)"},
+		{control.SvcInfoEntry{PID: 7, Pin: control.PinStarted}, " (pid: 7, pinned started)"},
+	}
+	for _, c := range cases {
+		if got := formatSuffix(c.e); got != c.want {
+			t.Errorf("formatSuffix(%+v) = %q, want %q", c.e, got, c.want)
+		}
+	}
+}
@@ -3,11 +3,17 @@
 package main
 
 import (
+	"bytes"
+	"context"
 	"crypto/rand"
+	"crypto/tls"
 	"encoding/binary"
+	"errors"
 	"fmt"
+	"io"
 	"net"
 	"os"
+	"os/exec"
 	"path/filepath"
 	"sort"
 	"strconv"
@@ -16,8 +22,12 @@ import (
 	"time"
 	"unsafe"
 
+	"github.com/sunlightlinux/slinit/pkg/config"
 	"github.com/sunlightlinux/slinit/pkg/control"
+	"github.com/sunlightlinux/slinit/pkg/logging"
+	"github.com/sunlightlinux/slinit/pkg/migrate"
 	"github.com/sunlightlinux/slinit/pkg/platform"
+	"github.com/sunlightlinux/slinit/pkg/process"
 	"github.com/sunlightlinux/slinit/pkg/service"
 	"github.com/sunlightlinux/slinit/pkg/shutdown"
 )
@@ -44,18 +54,36 @@ var quiet bool
 // on the same round-trip and avoids a follow-up SERVICESTATUS query.
 var peerCPVersion uint16
 
+// compressionEnabled is set once the server agreed to compress replies
+// (see negotiateCompression); readReply then reads the flagged framing
+// and inflates them.
+var compressionEnabled bool
+
 // waitTimeout is the reply timeout in seconds set by -w / --wait. 0
 // disables the CLI-side cap (server-side timeouts still apply). This
 // is a package-level so command functions don't have to plumb it
 // through their signatures — matching the existing pattern for quiet.
 var waitTimeout time.Duration
 
+// opTimeout bounds the whole operation — connecting, sending the
+// command and reading every reply — when set by --timeout or the
+// timeout command. 0 means no bound.
+var opTimeout time.Duration
+
+// timeoutExitCode is the exit status when opTimeout expires, the same
+// as GNU coreutils timeout(1).
+const timeoutExitCode = 124
+
 func main() {
 	args := os.Args[1:]
 
 	// Parse global flags
 	var (
 		socketPath  string
+		socketTCP   string
+		tlsCert     string
+		tlsKey      string
+		tlsCA       string
 		systemMode  bool
 		userMode    bool
 		noWait      bool
@@ -80,6 +108,34 @@ func main() {
 		case strings.HasPrefix(args[0], "--socket-path="):
 			socketPath = strings.TrimPrefix(args[0], "--socket-path=")
 			args = args[1:]
+		case args[0] == "--socket-tcp" || args[0] == "--tls-cert" ||
+			args[0] == "--tls-key" || args[0] == "--tls-ca":
+			if len(args) < 2 {
+				fatal("%s requires an argument", args[0])
+			}
+			switch args[0] {
+			case "--socket-tcp":
+				socketTCP = args[1]
+			case "--tls-cert":
+				tlsCert = args[1]
+			case "--tls-key":
+				tlsKey = args[1]
+			case "--tls-ca":
+				tlsCA = args[1]
+			}
+			args = args[2:]
+		case strings.HasPrefix(args[0], "--socket-tcp="):
+			socketTCP = strings.TrimPrefix(args[0], "--socket-tcp=")
+			args = args[1:]
+		case strings.HasPrefix(args[0], "--tls-cert="):
+			tlsCert = strings.TrimPrefix(args[0], "--tls-cert=")
+			args = args[1:]
+		case strings.HasPrefix(args[0], "--tls-key="):
+			tlsKey = strings.TrimPrefix(args[0], "--tls-key=")
+			args = args[1:]
+		case strings.HasPrefix(args[0], "--tls-ca="):
+			tlsCA = strings.TrimPrefix(args[0], "--tls-ca=")
+			args = args[1:]
 		case args[0] == "--system" || args[0] == "-s":
 			systemMode = true
 			args = args[1:]
@@ -107,6 +163,23 @@ func main() {
 			}
 			waitSecs = n
 			args = args[1:]
+		case args[0] == "--timeout":
+			if len(args) < 2 {
+				fatal("--timeout requires an argument (duration)")
+			}
+			d, err := parseOpTimeout(args[1])
+			if err != nil {
+				fatal("--timeout: %v", err)
+			}
+			opTimeout = d
+			args = args[2:]
+		case strings.HasPrefix(args[0], "--timeout="):
+			d, err := parseOpTimeout(strings.TrimPrefix(args[0], "--timeout="))
+			if err != nil {
+				fatal("--timeout: %v", err)
+			}
+			opTimeout = d
+			args = args[1:]
 		case args[0] == "--pin":
 			pinFlag = true
 			args = args[1:]
@@ -149,6 +222,9 @@ func main() {
 		case args[0] == "--version":
 			fmt.Printf("slinitctl version %s\n", version)
 			os.Exit(0)
+		case args[0] == "--schema":
+			fmt.Print(config.GenerateSchema())
+			os.Exit(0)
 		default:
 			goto doneFlags
 		}
@@ -160,6 +236,34 @@ doneFlags:
 		os.Exit(1)
 	}
 
+	// `timeout <duration> <command> [args...]` is --timeout as a prefix
+	// command, for scripts that already build a command line.
+	if args[0] == "timeout" {
+		if len(args) < 3 {
+			fatal("Usage: slinitctl timeout <duration> <command> [args...]")
+		}
+		d, err := parseOpTimeout(args[1])
+		if err != nil {
+			fatal("timeout: %v", err)
+		}
+		opTimeout = d
+		args = args[2:]
+	}
+
+	ctx := context.Background()
+	if opTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, opTimeout)
+		defer cancel()
+		// Covers time spent outside socket I/O; reads and writes that
+		// hit the deadline are handled by timeoutConn.
+		context.AfterFunc(ctx, func() {
+			if ctx.Err() == context.DeadlineExceeded {
+				exitTimedOut()
+			}
+		})
+	}
+
 	command := args[0]
 	cmdArgs := args[1:]
 
@@ -176,6 +280,32 @@ doneFlags:
 		cmdCompletion(shell)
 		return
 	}
+	if command == "help-settings" {
+		if len(cmdArgs) > 0 && cmdArgs[0] == "--man" {
+			fmt.Print(config.GenerateManPage())
+		} else {
+			fmt.Print(config.GenerateSchema())
+		}
+		return
+	}
+	if command == "migrate" {
+		os.Exit(cmdMigrate(cmdArgs))
+	}
+	if command == "lint" {
+		os.Exit(cmdLint(os.Stdout, cmdArgs))
+	}
+	if command == "validate" {
+		os.Exit(cmdValidate(os.Stdout, servicesDir, cmdArgs))
+	}
+	if command == "generate-unit" {
+		os.Exit(cmdGenerateUnit(os.Stdout, cmdArgs))
+	}
+	if command == "encrypt-env-file" {
+		os.Exit(cmdEncryptEnvFile(os.Stdin, os.Stdout, cmdArgs))
+	}
+	if command == "generate-checksums" {
+		os.Exit(cmdGenerateChecksums(os.Stdout, servicesDir, cmdArgs))
+	}
 	if command == "is-newer-than" || command == "is-older-than" {
 		if len(cmdArgs) != 2 {
 			fatal("Usage: slinitctl %s <file-a> <file-b>", command)
@@ -236,23 +366,39 @@ doneFlags:
 
 	var conn net.Conn
 	var err error
-	if useCFD {
+	switch {
+	case useCFD:
 		conn, err = connectPassedFD()
-	} else {
-		conn, err = connectSocket(sockPath)
+	case socketTCP != "":
+		sockPath = socketTCP
+		conn, err = connectTCP(ctx, socketTCP, tlsCert, tlsKey, tlsCA)
+	default:
+		conn, err = connectSocketWithContext(ctx, sockPath)
 	}
 	if err != nil {
+		if ctx.Err() != nil {
+			exitTimedOut()
+		}
 		if useCFD {
 			fatal("Failed to connect via passed fd: %v", err)
 		}
 		fatal("Failed to connect to slinit at %s: %v", sockPath, err)
 	}
+	if opTimeout > 0 {
+		conn = newTimeoutConn(ctx, conn)
+	}
+	conn = &replayConn{Conn: conn}
 	defer conn.Close()
 
 	// Protocol version handshake
 	if err := versionHandshake(conn); err != nil {
 		fatal("%v", err)
 	}
+	if peerCPVersion >= 8 {
+		if err := negotiateCompression(conn); err != nil {
+			fatal("%v", err)
+		}
+	}
 
 	// Set package-level quiet flag
 	quiet = quietMode || noWait
@@ -261,6 +407,16 @@ doneFlags:
 	switch command {
 	case "list", "ls":
 		err = cmdList(conn)
+	case "aliases":
+		err = cmdAliases(conn)
+	case "list-timers":
+		err = cmdListTimers(conn)
+	case "watch":
+		incremental, interval, perr := parseWatchArgs(cmdArgs)
+		if perr != nil {
+			fatal("Usage: slinitctl watch [--incremental] [--interval DURATION]: %v", perr)
+		}
+		err = cmdWatch(conn, incremental, interval)
 	case "run":
 		err = cmdRun(conn, cmdArgs)
 	case "start":
@@ -269,7 +425,7 @@ doneFlags:
 		})
 	case "wake":
 		err = requireServiceArg(cmdArgs, func(name string) error {
-			return cmdWake(conn, name)
+			return cmdWake(conn, name, noWait)
 		})
 	case "stop":
 		err = requireServiceArg(cmdArgs, func(name string) error {
@@ -277,8 +433,24 @@ doneFlags:
 		})
 	case "release":
 		err = requireServiceArg(cmdArgs, func(name string) error {
-			return cmdRelease(conn, name)
+			return cmdRelease(conn, name, noWait)
+		})
+	case "switch-to":
+		err = requireServiceArg(cmdArgs, func(name string) error {
+			return cmdSwitchTarget(conn, name)
 		})
+	case "jobs":
+		err = cmdJobs(conn)
+	case "job":
+		if len(cmdArgs) < 1 {
+			fatal("Usage: slinitctl job <id>")
+		}
+		err = cmdJob(conn, cmdArgs[0])
+	case "cancel":
+		if len(cmdArgs) < 1 {
+			fatal("Usage: slinitctl cancel <job>")
+		}
+		err = cmdCancelJob(conn, cmdArgs[0])
 	case "restart":
 		err = requireServiceArg(cmdArgs, func(name string) error {
 			return cmdRestart(conn, name, pinFlag, forceFlag, ignoreUnst, noWait)
@@ -299,14 +471,32 @@ doneFlags:
 		err = cmdResetFailedDispatch(conn, cmdArgs)
 	case "shutdown":
 		err = cmdShutdownDispatch(conn, cmdArgs)
-	case "trigger":
+	case "trigger", "untrigger":
+		if len(cmdArgs) < 1 {
+			fatal("Service name required")
+		}
+		event := ""
+		if len(cmdArgs) > 1 {
+			event = cmdArgs[1]
+		}
+		if command == "trigger" {
+			err = cmdTrigger(conn, cmdArgs[0], event)
+		} else {
+			err = cmdUntrigger(conn, cmdArgs[0], event)
+		}
+	case "edit":
 		err = requireServiceArg(cmdArgs, func(name string) error {
-			return cmdTrigger(conn, name)
+			return cmdEdit(conn, name)
 		})
-	case "untrigger":
+	case "no-restart", "enable-restart":
 		err = requireServiceArg(cmdArgs, func(name string) error {
-			return cmdUntrigger(conn, name)
+			return cmdSetRestartEnabled(conn, name, command == "enable-restart")
 		})
+	case "set-priority":
+		if len(cmdArgs) < 2 {
+			fatal("Usage: slinitctl set-priority <service> <N>")
+		}
+		err = cmdSetPriority(conn, cmdArgs[0], cmdArgs[1])
 	case "signal":
 		if len(cmdArgs) >= 1 && (cmdArgs[0] == "--list" || cmdArgs[0] == "-l") {
 			printSignalList()
@@ -336,8 +526,53 @@ doneFlags:
 		err = requireServiceArg(cmdArgs, func(name string) error {
 			return cmdOnce(conn, name)
 		})
+	case "kill":
+		err = requireServiceArg(cmdArgs, func(name string) error {
+			return cmdKill(conn, name)
+		})
+	case "annotate":
+		if len(cmdArgs) < 2 {
+			fatal("Usage: slinitctl annotate <service> <message>")
+		}
+		err = cmdAnnotate(conn, cmdArgs[0], strings.Join(cmdArgs[1:], " "))
+	case "set-stop-reason":
+		if len(cmdArgs) < 2 {
+			fatal("Usage: slinitctl set-stop-reason <service> <message>")
+		}
+		err = cmdSetStopReason(conn, cmdArgs[0], strings.Join(cmdArgs[1:], " "))
 	case "boot-time", "analyze":
-		err = cmdBootTime(conn)
+		if len(cmdArgs) > 0 && cmdArgs[0] == "critical-chain" {
+			if len(cmdArgs) > 2 {
+				fatal("Usage: slinitctl analyze critical-chain [service]")
+			}
+			root := ""
+			if len(cmdArgs) == 2 {
+				root = cmdArgs[1]
+			}
+			err = cmdCriticalChain(conn, root)
+			break
+		}
+		showEvents := false
+		for _, a := range cmdArgs {
+			if a != "--events" {
+				fatal("Usage: slinitctl boot-time [--events]")
+			}
+			showEvents = true
+		}
+		err = cmdBootTime(conn, showEvents)
+	case "verify-internal":
+		err = cmdVerifyInternal(conn)
+	case "diagnostics":
+		err = cmdDiagnostics(conn, os.Stdout)
+	case "check-shadowing":
+		err = cmdCheckShadowing(conn)
+	case "info":
+		err = cmdInfo(conn)
+	case "patch-apply":
+		if len(cmdArgs) != 2 {
+			fatal("Usage: slinitctl patch-apply <service> <patch-file>")
+		}
+		err = cmdPatchApply(conn, cmdArgs[0], cmdArgs[1])
 	case "reload":
 		err = requireServiceArg(cmdArgs, func(name string) error {
 			return cmdReload(conn, name)
@@ -426,6 +661,10 @@ doneFlags:
 		err = requireServiceArg(cmdArgs, func(name string) error {
 			return cmdUnpin(conn, name)
 		})
+	case "pin-start", "pin-stop":
+		err = requireServiceArg(cmdArgs, func(name string) error {
+			return cmdPin(conn, name, command == "pin-stop")
+		})
 	case "enable":
 		err = requireServiceArg(cmdArgs, func(name string) error {
 			return cmdEnable(conn, name, fromSvc)
@@ -440,6 +679,20 @@ doneFlags:
 		})
 	case "service-dirs":
 		err = cmdQueryServiceDscDir(conn)
+	case "set-service-dirs":
+		if len(cmdArgs) < 1 {
+			fatal("Usage: slinitctl set-service-dirs <dir>...")
+		}
+		err = cmdSetServiceDirs(conn, control.ServiceDirsSet, cmdArgs)
+	case "add-service-dir", "rm-service-dir":
+		if len(cmdArgs) != 1 {
+			fatal("Usage: slinitctl %s <dir>", command)
+		}
+		op := control.ServiceDirsAdd
+		if command == "rm-service-dir" {
+			op = control.ServiceDirsRemove
+		}
+		err = cmdSetServiceDirs(conn, op, cmdArgs)
 	case "query-load-mech", "load-mech":
 		err = cmdQueryLoadMech(conn)
 	case "dependents":
@@ -448,6 +701,10 @@ doneFlags:
 			os.Exit(1)
 		}
 		err = cmdDependents(conn, cmdArgs[0])
+	case "deps":
+		err = requireServiceArg(cmdArgs, func(name string) error {
+			return cmdDeps(conn, name)
+		})
 	case "list5":
 		err = cmdListServices5(conn)
 	case "status5":
@@ -455,7 +712,53 @@ doneFlags:
 			return cmdServiceStatus5(conn, name)
 		})
 	case "graph":
-		err = cmdGraph(conn)
+		tiers := false
+		format := "dot"
+		root := ""
+		for i := 0; i < len(cmdArgs); i++ {
+			a := cmdArgs[i]
+			switch {
+			case a == "--tiers":
+				tiers = true
+			case a == "--dot":
+				format = "dot"
+			case a == "--json":
+				format = "json"
+			case a == "--format":
+				if i+1 >= len(cmdArgs) {
+					fatal("--format requires an argument (dot|mermaid|json)")
+				}
+				i++
+				format = cmdArgs[i]
+			case strings.HasPrefix(a, "--format="):
+				format = strings.TrimPrefix(a, "--format=")
+			case !strings.HasPrefix(a, "-"):
+				root = a
+			}
+		}
+		if format != "dot" && format != "mermaid" && format != "json" {
+			fatal("Unknown graph format %q (use dot|mermaid|json)", format)
+		}
+		err = cmdGraph(conn, root, tiers, format)
+	case "export-deps":
+		format := "csv"
+		for i := 0; i < len(cmdArgs); i++ {
+			a := cmdArgs[i]
+			switch {
+			case a == "--format":
+				if i+1 >= len(cmdArgs) {
+					fatal("--format requires an argument (csv|json|tsv)")
+				}
+				i++
+				format = cmdArgs[i]
+			case strings.HasPrefix(a, "--format="):
+				format = strings.TrimPrefix(a, "--format=")
+			}
+		}
+		if format != "csv" && format != "json" && format != "tsv" {
+			fatal("Unknown export format %q (use csv|json|tsv)", format)
+		}
+		err = cmdExportDeps(conn, format)
 	case "attach":
 		if len(cmdArgs) < 1 {
 			fatal("Usage: slinitctl attach <service>")
@@ -474,6 +777,19 @@ doneFlags:
 		err = requireServiceArg(cmdArgs, func(name string) error {
 			return cmdListActions(conn, name)
 		})
+	case "events":
+		since, follow, names, perr := parseEventsArgs(cmdArgs)
+		if perr != nil {
+			fatal("Usage: slinitctl events [--since DURATION] [--follow] [service...]: %v", perr)
+		}
+		err = cmdEvents(conn, since, follow, names)
+	case "monitor":
+		for _, arg := range cmdArgs {
+			if strings.HasPrefix(arg, "-") {
+				fatal("Usage: slinitctl monitor [service...]")
+			}
+		}
+		err = cmdMonitor(os.Stdout, conn, cmdArgs)
 	default:
 		fatal("Unknown command: %s", command)
 	}
@@ -488,12 +804,19 @@ func printUsage() {
 
 Options:
   --socket-path, -p PATH   Control socket path
+  --socket-tcp HOST:PORT   Connect to a remote slinit over TLS instead
+  --tls-cert FILE          Client certificate for --socket-tcp
+  --tls-key FILE           Client private key for --socket-tcp
+  --tls-ca FILE            CA that signed the server certificate
   --system, -s             Connect to system service manager
   --user, -u               Connect to user service manager
   --no-wait                Do not wait for command completion
   -w, --wait SEC           Fail after SEC seconds if the daemon does not
                            reply (0 = no cap; server-side timeouts still
                            apply). Mirrors sv -w SEC.
+  --timeout DURATION       Give up on the whole operation (connect, command
+                           and replies) after DURATION, e.g. 10s, and exit
+                           with status 124
   --pin                    Pin service in started/stopped state (start/stop)
   --force, -f              Force stop even with dependents (stop/restart)
   --ignore-unstarted       Exit 0 if service already stopped (stop/restart)
@@ -504,13 +827,22 @@ Options:
   --quiet, -q              Suppress informational output
   --help, -h               Show this help
   --version                Show version
+  --schema                 Print the service settings reference (Markdown)
 
 Commands:
   list                     List all loaded services
   start <service>          Start a service (marks active)
   wake <service>           Start without marking active
   stop <service>           Stop a service
+  kill <service>           SIGKILL a service and keep it down (no restart)
+  annotate <svc> <message> Attach an operator note to a service's history
+  set-stop-reason <svc> <message>
+                           Record why a service stopped (custom stop reason)
   release <service>        Remove active mark (stop if unrequired)
+  switch-to <service>      Start a target and release the services it does not need
+  jobs                     List running and recently finished start/stop jobs
+  job <id>                 Show a job; exit 1 if it failed or was cancelled
+  cancel <job>             Cancel a running start or stop job
   restart <service>        Restart a service (stop + start)
   status <service>         Show detailed service status
   is-started <service>     Exit 0 if started, 1 otherwise
@@ -519,8 +851,14 @@ Commands:
                            time=now|+N (min)|HH:MM (default: poweroff now)
   shutdown -c              Cancel scheduled shutdown
   shutdown --status        Show pending shutdown info
-  trigger <service>        Trigger a triggered service
-  untrigger <service>      Reset trigger state
+  shutdown --dry-run       Show shutdown stop order and estimated duration
+  trigger <svc> [event]    Trigger a triggered service, or set one named trigger
+  untrigger <svc> [event]  Reset trigger state, or clear one named trigger
+  edit <service>           Edit the service file with $EDITOR, locked against reloads
+  patch-apply <svc> <file> Check a .patch file and install it in <service>.d/
+  no-restart <service>     Suspend auto-restart until reload (for debugging)
+  enable-restart <service> Force auto-restart on until reload
+  set-priority <svc> <N>   Set propagation priority (higher first) until reload
   signal [-l] <sig> <svc>  Send signal to service process (-l to list)
   pause <service>          Pause (SIGSTOP) a running service
   continue <service>       Continue (SIGCONT) a paused service
@@ -528,10 +866,26 @@ Commands:
   action <svc> <action>    Run a custom extra-command action
   list-actions <service>   List available extra-command actions
   reload <service>         Reload service configuration from disk
-  reload-all               Reload every loaded service from disk (skips transitional)
+  reload-all               Reload every loaded service from disk; list changed ones
   reload-signal <service>  Send service's configured reload-signal to its process
   unload <service>         Unload a stopped service from memory
-  boot-time                Show boot timing analysis
+  boot-time [--events]     Show boot timing analysis (--events: full timeline)
+  analyze critical-chain [service]
+                           Show the chain of services that held up the boot
+                           service (or service) reaching STARTED
+  verify-internal          Check service reference counts (debugging)
+  diagnostics              Dump the daemon's full service graph state (debugging)
+  check-shadowing          List service files hidden by another or masked
+  info                     Show daemon and connection info (socket mode, ...)
+  aliases                  List service aliases and the services they name
+  list-timers              List timer services with their next and last firing
+  watch [--incremental] [--interval 2s]
+                           Keep the service list on screen, redrawn as it changes
+  events [--since 5m] [--follow] [service...]
+                           Show the timeline of recent service events
+  monitor [service...]     Print service events live as they happen
+  timeout <duration> <command> [args...]
+                           Run <command> like --timeout <duration>
   catlog [--clear] <svc>   Show buffered service output
   setenv <svc> KEY=VALUE   Set environment variable for service
   unsetenv <svc> KEY       Remove environment variable
@@ -542,19 +896,45 @@ Commands:
   getallenv-global         List all global environment variables
   add-dep <from> <type> <to>  Add runtime dependency
   rm-dep <from> <type> <to>   Remove runtime dependency
+  pin-start <service>      Pin a service started (starting it if needed)
+  pin-stop <service>       Pin a service stopped (stopping it if needed)
   unpin <service>          Remove start/stop pins from a service
   enable <service>         Enable service (add waits-for to boot + start)
   disable <service>        Disable service (remove waits-for from boot + stop)
-  graph                    Export dependency graph in DOT format (Graphviz)
+  graph [--tiers] [--dot|--json|--format dot|mermaid|json] [service]
+                           Export dependency graph in DOT format (Graphviz),
+                           as JSON or as a Mermaid flowchart, or with --tiers
+                           as columns of start tiers
+  export-deps [--format csv|json|tsv]
+                           Export every dependency edge with service state,
+                           PID and restart count as a table
   dependents <service>     List services that depend on a service
+  deps <service>           Show dependency state (acquisitions, waits, targets)
   query-name <service>     Query the canonical name of a service handle
   service-dirs             List configured service directories
+  set-service-dirs <dir>...
+                           Replace the daemon's service directories
+  add-service-dir <dir>    Append a directory to the service directories
+  rm-service-dir <dir>     Remove a directory from the service directories
   load-mech                Query loader mechanism info
   list5                    List services (protocol v5, detailed)
   status5 <service>        Show service status (protocol v5, detailed)
   attach <service>         Attach to service virtual terminal
   platform                 Detect and display virtualization/container platform
   completion [shell]       Output shell completion script (bash|zsh|fish)
+  help-settings [--man]    Print the service settings reference (Markdown or groff)
+  migrate --from systemd [--output-dir DIR] UNIT...
+                           Convert systemd unit files to service descriptions
+  lint FILE...             Check service files for common misconfigurations
+  validate FILE...         Report dependencies of service files that name
+                           no service file (see --services-dir)
+  generate-unit --from-pid PID [--format slinit|toml]
+                           Draft a service description from a running process
+  encrypt-env-file --key SPEC < FILE.env > FILE.enc
+                           Encrypt an env-file for encrypted-env-file
+  generate-checksums [--output FILE]
+                           Write SHA-256 sums of the service files for
+                           slinit --checksum-file (see --services-dir)
 `)
 }
 
@@ -600,7 +980,112 @@ func resolveSocketPath(flagValue string, systemMode, userMode bool) string {
 }
 
 func connectSocket(path string) (net.Conn, error) {
-	return net.Dial("unix", path)
+	return connectSocketWithContext(context.Background(), path)
+}
+
+// connectSocketWithContext dials the control socket, giving up when ctx
+// is done.
+func connectSocketWithContext(ctx context.Context, path string) (net.Conn, error) {
+	var d net.Dialer
+	return d.DialContext(ctx, "unix", path)
+}
+
+// connectTCP dials a remote slinit's TCP control listener. The server
+// only authorizes clients with a certificate it trusts, so TLS is
+// mandatory here.
+func connectTCP(ctx context.Context, addr, certFile, keyFile, caFile string) (net.Conn, error) {
+	cfg, err := control.ClientTLSConfig(addr, certFile, keyFile, caFile)
+	if err != nil {
+		return nil, err
+	}
+	dialer := &tls.Dialer{
+		NetDialer: &net.Dialer{Timeout: 10 * time.Second},
+		Config:    cfg,
+	}
+	return dialer.DialContext(ctx, "tcp", addr)
+}
+
+// parseOpTimeout parses the --timeout argument: a Go duration such as
+// 10s or 1m30s, or a bare number of seconds.
+func parseOpTimeout(s string) (time.Duration, error) {
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		n, aerr := strconv.Atoi(s)
+		if aerr != nil {
+			return 0, fmt.Errorf("invalid duration %q", s)
+		}
+		d = time.Duration(n) * time.Second
+	}
+	if d <= 0 {
+		return 0, fmt.Errorf("duration must be positive (got %q)", s)
+	}
+	return d, nil
+}
+
+// exitTimedOut reports that opTimeout expired and exits with
+// timeoutExitCode.
+func exitTimedOut() {
+	fmt.Fprintf(os.Stderr, "slinitctl: operation timed out after %s\n", opTimeout)
+	os.Exit(timeoutExitCode)
+}
+
+// timeoutConn holds every read and write on the control connection to
+// the operation deadline of ctx. Deadlines set by callers (readReply's
+// -w cap) are clamped to it, clearing one restores it, and an I/O error
+// once ctx has expired ends slinitctl with the timeout exit instead of
+// the command's own error.
+type timeoutConn struct {
+	net.Conn
+	ctx      context.Context
+	deadline time.Time
+}
+
+func newTimeoutConn(ctx context.Context, conn net.Conn) net.Conn {
+	deadline, _ := ctx.Deadline()
+	_ = conn.SetDeadline(deadline)
+	return &timeoutConn{Conn: conn, ctx: ctx, deadline: deadline}
+}
+
+func (c *timeoutConn) clamp(t time.Time) time.Time {
+	if t.IsZero() || t.After(c.deadline) {
+		return c.deadline
+	}
+	return t
+}
+
+func (c *timeoutConn) SetDeadline(t time.Time) error {
+	return c.Conn.SetDeadline(c.clamp(t))
+}
+
+func (c *timeoutConn) SetReadDeadline(t time.Time) error {
+	return c.Conn.SetReadDeadline(c.clamp(t))
+}
+
+func (c *timeoutConn) SetWriteDeadline(t time.Time) error {
+	return c.Conn.SetWriteDeadline(c.clamp(t))
+}
+
+// expired reports whether the operation deadline has passed. The
+// connection deadline can fire a moment before ctx's own timer does, so
+// the clock is checked too.
+func (c *timeoutConn) expired() bool {
+	return c.ctx.Err() != nil || !time.Now().Before(c.deadline)
+}
+
+func (c *timeoutConn) Read(b []byte) (int, error) {
+	n, err := c.Conn.Read(b)
+	if err != nil && c.expired() {
+		exitTimedOut()
+	}
+	return n, err
+}
+
+func (c *timeoutConn) Write(b []byte) (int, error) {
+	n, err := c.Conn.Write(b)
+	if err != nil && c.expired() {
+		exitTimedOut()
+	}
+	return n, err
 }
 
 // readReply reads packets from the connection, skipping any unsolicited
@@ -619,12 +1104,12 @@ func readReply(conn net.Conn) (uint8, []byte, error) {
 		defer conn.SetReadDeadline(time.Time{})
 	}
 	for {
-		rply, payload, err := control.ReadPacket(conn)
+		rply, payload, err := readPacket(conn)
 		if err != nil {
 			return 0, nil, err
 		}
 		switch rply {
-		case control.InfoServiceEvent, control.InfoServiceEvent5, control.InfoEnvEvent:
+		case control.InfoServiceEvent, control.InfoServiceEvent5, control.InfoEnvEvent, control.InfoGlobalEvent:
 			// Skip unsolicited push notifications
 			continue
 		default:
@@ -633,11 +1118,115 @@ func readReply(conn net.Conn) (uint8, []byte, error) {
 	}
 }
 
+// replayConn remembers the bytes of the request most recently written
+// (everything written since the last read) so that a command rejected
+// with RplyRateLimited can be sent again unchanged. It also remembers
+// which service each handle from loadServiceHandle names, so a command
+// rejected with RplyStaleHandle can be re-sent with a fresh handle.
+type replayConn struct {
+	net.Conn
+	req       []byte
+	readSince bool
+	names     map[uint32]string
+}
+
+func (c *replayConn) Write(p []byte) (int, error) {
+	if c.readSince {
+		c.req = c.req[:0]
+		c.readSince = false
+	}
+	c.req = append(c.req, p...)
+	return c.Conn.Write(p)
+}
+
+func (c *replayConn) Read(p []byte) (int, error) {
+	c.readSince = true
+	return c.Conn.Read(p)
+}
+
+// errAccessDenied reports a command refused because the caller only has
+// read-only access to the control socket.
+var errAccessDenied = errors.New("access denied: read-only access to the control socket")
+
+// maxStaleRetries bounds how often one request is re-sent after
+// RplyStaleHandle, in case reloads keep replacing the service.
+const maxStaleRetries = 3
+
+// readPacket reads one packet from the daemon. A RplyRateLimited reply
+// is handled here: after the advertised retry-after the last request is
+// re-sent and the read repeats, so callers only ever see the real reply.
+// So is RplyStaleHandle for a request starting with a handle from
+// loadServiceHandle: the service is loaded again and the request re-sent
+// with the new handle. RplyAccessDenied becomes errAccessDenied.
+func readPacket(conn net.Conn) (uint8, []byte, error) {
+	staleRetries := 0
+	for {
+		rply, payload, err := control.ReadPacketWith(conn, compressionEnabled)
+		if err == nil && rply == control.RplyAccessDenied {
+			return 0, nil, errAccessDenied
+		}
+		if err != nil || (rply != control.RplyRateLimited && rply != control.RplyStaleHandle) {
+			return rply, payload, err
+		}
+		rc, ok := conn.(*replayConn)
+		if !ok {
+			return rply, payload, nil
+		}
+		if rply == control.RplyStaleHandle {
+			if staleRetries == maxStaleRetries || !rc.refreshHandle() {
+				return rply, payload, nil
+			}
+			staleRetries++
+			continue
+		}
+		retry, err := control.DecodeRateLimited(payload)
+		if err != nil {
+			return 0, nil, err
+		}
+		time.Sleep(retry)
+		if _, err := rc.Conn.Write(rc.req); err != nil {
+			return 0, nil, err
+		}
+	}
+}
+
+// refreshHandle re-sends the last request, which was rejected with
+// RplyStaleHandle, with its leading handle replaced by a fresh one for
+// the same service. Reports false, sending nothing, if the request does
+// not start with a handle from loadServiceHandle or the service can no
+// longer be loaded.
+func (c *replayConn) refreshHandle() bool {
+	req := append([]byte(nil), c.req...)
+	if len(req) < 7 {
+		return false
+	}
+	name, ok := c.names[binary.LittleEndian.Uint32(req[3:7])]
+	if !ok {
+		return false
+	}
+	handle, err := loadServiceHandle(c, name)
+	if err != nil {
+		return false
+	}
+	binary.LittleEndian.PutUint32(req[3:7], handle)
+	_, err = c.Write(req)
+	return err == nil
+}
+
 // isStderrTTY reports whether stderr is attached to a terminal.
 // Uses TCGETS ioctl — succeeds only on real terminals.
 func isStderrTTY() bool {
+	return isTTY(os.Stderr)
+}
+
+// isStdoutTTY reports whether stdout is attached to a terminal.
+func isStdoutTTY() bool {
+	return isTTY(os.Stdout)
+}
+
+func isTTY(f *os.File) bool {
 	var t syscall.Termios
-	_, _, errno := syscall.Syscall6(syscall.SYS_IOCTL, uintptr(os.Stderr.Fd()),
+	_, _, errno := syscall.Syscall6(syscall.SYS_IOCTL, uintptr(f.Fd()),
 		uintptr(syscall.TCGETS), uintptr(unsafe.Pointer(&t)), 0, 0, 0)
 	return errno == 0
 }
@@ -653,8 +1242,16 @@ var progressTick = 500 * time.Millisecond
 // --quiet is not set, prints a "waiting" line followed by dots while the
 // server is still computing a reply. Silent in non-TTY (scripts, logs).
 func readReplyWithProgress(conn net.Conn, action string) (uint8, []byte, error) {
-	if quiet || !isStderrTTY() {
+	return withProgress(action, func() (uint8, []byte, error) {
 		return readReply(conn)
+	})
+}
+
+// withProgress runs read, printing progress dots for action while it
+// takes longer than progressGrace, as readReplyWithProgress does.
+func withProgress(action string, read func() (uint8, []byte, error)) (uint8, []byte, error) {
+	if quiet || !isStderrTTY() {
+		return read()
 	}
 
 	type result struct {
@@ -664,7 +1261,7 @@ func readReplyWithProgress(conn net.Conn, action string) (uint8, []byte, error)
 	}
 	done := make(chan result, 1)
 	go func() {
-		rply, payload, err := readReply(conn)
+		rply, payload, err := read()
 		done <- result{rply, payload, err}
 	}()
 
@@ -701,7 +1298,7 @@ func versionHandshake(conn net.Conn) error {
 		return fmt.Errorf("version handshake write: %w", err)
 	}
 
-	rply, payload, err := control.ReadPacket(conn)
+	rply, payload, err := readPacket(conn)
 	if err != nil {
 		return fmt.Errorf("version handshake read: %w", err)
 	}
@@ -736,6 +1333,27 @@ func versionHandshake(conn net.Conn) error {
 	return nil
 }
 
+// negotiateCompression offers zlib to a v8+ server. Only replies are
+// compressed; requests from this side always go out as-is.
+func negotiateCompression(conn net.Conn) error {
+	offer := control.CompressMask(control.CompressNone) | control.CompressMask(control.CompressZlib)
+	if err := control.WritePacket(conn, control.CmdNegotiateCompression, []byte{offer}); err != nil {
+		return fmt.Errorf("compression negotiation write: %w", err)
+	}
+	rply, payload, err := readPacket(conn)
+	if err != nil {
+		return fmt.Errorf("compression negotiation read: %w", err)
+	}
+	if rply != control.RplyCompression || len(payload) < 1 {
+		return fmt.Errorf("unexpected compression reply: %d", rply)
+	}
+	if offer&control.CompressMask(payload[0]) == 0 {
+		return fmt.Errorf("server chose unoffered compression algorithm %d", payload[0])
+	}
+	compressionEnabled = payload[0] != control.CompressNone
+	return nil
+}
+
 // connectPassedFD creates a net.Conn from a file descriptor passed via
 // the SLINIT_CS_FD environment variable.
 func connectPassedFD() (net.Conn, error) {
@@ -760,8 +1378,10 @@ func connectPassedFD() (net.Conn, error) {
 }
 
 // encodeStartStopFlags encodes handle + optional flags byte.
-// Bit 0 = pin, Bit 1 = force (relevant only for stop).
-func encodeStartStopFlags(handle uint32, pin bool, force bool) []byte {
+// Bit 0 = pin, Bit 1 = force (relevant only for stop), Bit 7 = pre-ack:
+// the daemon sends RplyPreACK before acting, so service events that
+// follow it are known to result from this command (see awaitTransition).
+func encodeStartStopFlags(handle uint32, pin bool, force bool, wait bool) []byte {
 	var flags uint8
 	if pin {
 		flags |= 0x01
@@ -769,6 +1389,9 @@ func encodeStartStopFlags(handle uint32, pin bool, force bool) []byte {
 	if force {
 		flags |= 0x02
 	}
+	if wait {
+		flags |= 0x80
+	}
 	if flags == 0 {
 		return control.EncodeHandle(handle)
 	}
@@ -778,34 +1401,170 @@ func encodeStartStopFlags(handle uint32, pin bool, force bool) []byte {
 	return buf
 }
 
-// stripServiceArg returns the base name without the @argument part.
-// For "svc@arg" returns "svc"; for "svc" returns "svc".
-func stripServiceArg(name string) string {
-	if idx := strings.IndexByte(name, '@'); idx >= 0 {
-		return name[:idx]
+// awaitTransition reads the replies to a start (start = true) or stop of
+// handle. Without the pre-ack flag, or when the daemon refuses before
+// acting, that is just the final reply. After an RplyPreACK it also
+// waits, past the final reply, for the service event that settles the
+// command: STARTED or STOPPED succeed; a failed start or a cancelled
+// start or stop is returned as an error, so a script can trust the exit
+// status. -w SEC caps the whole wait.
+func awaitTransition(conn net.Conn, handle uint32, name string, start bool) (uint8, []byte, error) {
+	rply, payload, err := readReply(conn)
+	if err != nil || rply != control.RplyPreACK {
+		return rply, payload, err
+	}
+	if waitTimeout > 0 {
+		_ = conn.SetReadDeadline(time.Now().Add(waitTimeout))
+		defer conn.SetReadDeadline(time.Time{})
+	}
+
+	// Events may arrive before the final reply: the daemon acts on the
+	// command before replying to it.
+	var final uint8
+	var finalPayload []byte
+	var result error
+	settled := false
+	for !settled || final == 0 {
+		rply, payload, err := readPacket(conn)
+		if err != nil {
+			return 0, nil, err
+		}
+		switch rply {
+		case control.InfoServiceEvent:
+			h, event, _, err := control.DecodeServiceEvent(payload)
+			if err != nil || h != handle || settled {
+				continue
+			}
+			settled, result = transitionResult(service.ServiceEvent(event), name, start)
+		case control.InfoServiceEvent5, control.InfoEnvEvent, control.InfoGlobalEvent:
+		default:
+			if rply != control.RplyACK {
+				return rply, payload, nil
+			}
+			final, finalPayload = rply, payload
+		}
+	}
+	return final, finalPayload, result
+}
+
+// transitionResult reports whether event settles a start or stop of
+// service name, and the error to return if it did not succeed.
+func transitionResult(event service.ServiceEvent, name string, start bool) (bool, error) {
+	switch event {
+	case service.EventStarted:
+		if start {
+			return true, nil
+		}
+	case service.EventStopped:
+		if !start {
+			return true, nil
+		}
+	case service.EventFailedStart:
+		if start {
+			return true, fmt.Errorf("service '%s' failed to start", name)
+		}
+	case service.EventStartCancelled:
+		if start {
+			return true, fmt.Errorf("start of service '%s' was cancelled", name)
+		}
+	case service.EventStopCancelled:
+		if !start {
+			return true, fmt.Errorf("stop of service '%s' was cancelled", name)
+		}
+	}
+	return false, nil
+}
+
+// offlineServiceFile returns the description file for name in svcDir;
+// a "name@arg" instance without its own file uses the template.
+func offlineServiceFile(svcDir, name string) (string, error) {
+	for _, n := range config.TemplateFileNames(name) {
+		path := filepath.Join(svcDir, n)
+		if fi, err := os.Stat(path); err == nil && fi.Mode().IsRegular() {
+			return path, nil
+		}
+	}
+	return "", fmt.Errorf("service '%s' not found in %s", name, svcDir)
+}
+
+// offlineParse parses the description of service name in svcDir.
+func offlineParse(svcDir, name string) (*config.ServiceDescription, string, error) {
+	path, err := offlineServiceFile(svcDir, name)
+	if err != nil {
+		return nil, "", err
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, "", err
+	}
+	defer f.Close()
+	var desc *config.ServiceDescription
+	if _, arg, ok := config.SplitInstance(name); ok {
+		desc, err = config.ParseWithArg(f, name, path, arg)
+	} else {
+		desc, err = config.Parse(f, name, path)
+	}
+	if err != nil {
+		return nil, "", err
+	}
+	return desc, path, nil
+}
+
+// offlineWaitsForDirs returns the directories named by the waits-for.d
+// setting of service from in svcDir.
+func offlineWaitsForDirs(svcDir, from string) ([]string, error) {
+	desc, path, err := offlineParse(svcDir, from)
+	if err != nil {
+		return nil, err
+	}
+	return config.WaitsForDirs(desc.WaitsForD, filepath.Dir(path)), nil
+}
+
+// offlineEnableFrom resolves the source service like the daemon does:
+// an explicit --from, else the target's @meta enable-via, else boot.
+// A target that no longer parses falls back to boot, so a stale link
+// can still be disabled.
+func offlineEnableFrom(svcDir, from, to string) string {
+	if from != "" {
+		return from
+	}
+	if desc, _, err := offlineParse(svcDir, to); err == nil && desc.EnableVia != "" {
+		return desc.EnableVia
 	}
-	return name
+	return "boot"
 }
 
-// offlineEnable creates a waits-for.d symlink (offline mode).
+// offlineEnable creates a symlink for to in the first waits-for.d
+// directory of from (offline mode), the same link an online enable
+// persists.
 func offlineEnable(svcDir, from, to string) error {
-	if from == "" {
-		from = "boot"
+	from = offlineEnableFrom(svcDir, from, to)
+	toFile, err := offlineServiceFile(svcDir, to)
+	if err != nil {
+		return err
 	}
-	// Strip @arg from "from" for directory lookup
-	fromBase := stripServiceArg(from)
-	waitsDir := svcDir + "/" + fromBase + "/waits-for.d"
+	dirs, err := offlineWaitsForDirs(svcDir, from)
+	if err != nil {
+		return err
+	}
+	if len(dirs) == 0 {
+		return fmt.Errorf("service '%s' has no waits-for.d directory", from)
+	}
+	waitsDir := dirs[0]
 	if err := os.MkdirAll(waitsDir, 0755); err != nil {
 		return fmt.Errorf("creating waits-for.d: %w", err)
 	}
-	link := waitsDir + "/" + to
+	link := filepath.Join(waitsDir, to)
 	// Check if link already exists
 	if _, err := os.Lstat(link); err == nil {
 		info("Service '%s' is already enabled (from '%s').\n", to, from)
 		return nil
 	}
-	// Create relative symlink pointing to the service file (use base name for target)
-	target := "../../" + stripServiceArg(to)
+	// Relative symlink to the target's description file
+	target, err := filepath.Rel(waitsDir, toFile)
+	if err != nil {
+		target = toFile
+	}
 	if err := os.Symlink(target, link); err != nil {
 		return fmt.Errorf("creating symlink: %w", err)
 	}
@@ -813,20 +1572,27 @@ func offlineEnable(svcDir, from, to string) error {
 	return nil
 }
 
-// offlineDisable removes a waits-for.d symlink (offline mode).
+// offlineDisable removes the symlink for to from every waits-for.d
+// directory of from (offline mode).
 func offlineDisable(svcDir, from, to string) error {
-	if from == "" {
-		from = "boot"
-	}
-	// Strip @arg from "from" for directory lookup
-	fromBase := stripServiceArg(from)
-	link := svcDir + "/" + fromBase + "/waits-for.d/" + to
-	if err := os.Remove(link); err != nil {
-		if os.IsNotExist(err) {
-			info("Service '%s' is not enabled (from '%s').\n", to, from)
-			return nil
+	from = offlineEnableFrom(svcDir, from, to)
+	dirs, err := offlineWaitsForDirs(svcDir, from)
+	if err != nil {
+		return err
+	}
+	removed := false
+	for _, dir := range dirs {
+		if err := os.Remove(filepath.Join(dir, to)); err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return fmt.Errorf("removing symlink: %w", err)
 		}
-		return fmt.Errorf("removing symlink: %w", err)
+		removed = true
+	}
+	if !removed {
+		info("Service '%s' is not enabled (from '%s').\n", to, from)
+		return nil
 	}
 	info("Service '%s' disabled (from '%s').\n", to, from)
 	return nil
@@ -860,15 +1626,7 @@ func warnIfDescriptionChanged(conn net.Conn, handle uint32, name string) {
 	}
 	count := int(binary.LittleEndian.Uint16(payload))
 	off := 2
-	// Strip @arg for template lookup
-	baseName := name
-	if idx := strings.IndexByte(name, '@'); idx >= 0 {
-		baseName = name[:idx]
-	}
-	searchNames := []string{name}
-	if baseName != name {
-		searchNames = append(searchNames, baseName)
-	}
+	searchNames := config.TemplateFileNames(name)
 	for i := 0; i < count; i++ {
 		if len(payload) < off+2 {
 			return
@@ -927,14 +1685,7 @@ func resolveServiceDescFile(conn net.Conn, name string, loadModTime int64) (path
 	}
 	count := int(binary.LittleEndian.Uint16(payload))
 	off := 2
-	baseName := name
-	if idx := strings.IndexByte(name, '@'); idx >= 0 {
-		baseName = name[:idx]
-	}
-	searchNames := []string{name}
-	if baseName != name {
-		searchNames = append(searchNames, baseName)
-	}
+	searchNames := config.TemplateFileNames(name)
 	for i := 0; i < count; i++ {
 		if len(payload) < off+2 {
 			return "", false, false
@@ -965,7 +1716,7 @@ func loadServiceHandle(conn net.Conn, name string) (uint32, error) {
 		return 0, fmt.Errorf("write error: %w", err)
 	}
 
-	rply, payload, err := control.ReadPacket(conn)
+	rply, payload, err := readPacket(conn)
 	if err != nil {
 		return 0, fmt.Errorf("read error: %w", err)
 	}
@@ -976,6 +1727,12 @@ func loadServiceHandle(conn net.Conn, name string) (uint32, error) {
 			return 0, fmt.Errorf("invalid service record reply")
 		}
 		handle := binary.LittleEndian.Uint32(payload[1:5])
+		if rc, ok := conn.(*replayConn); ok {
+			if rc.names == nil {
+				rc.names = make(map[uint32]string)
+			}
+			rc.names[handle] = name
+		}
 		return handle, nil
 	case control.RplyNoService:
 		return 0, fmt.Errorf("service '%s' not found", name)
@@ -994,34 +1751,250 @@ func cmdList(conn net.Conn) error {
 	if err := control.WritePacket(conn, control.CmdListServices, nil); err != nil {
 		return err
 	}
+	entries, err := readSvcInfoList(conn)
+	if err != nil {
+		return err
+	}
 
-	for {
-		rply, payload, err := control.ReadPacket(conn)
-		if err != nil {
-			return err
-		}
+	// Older daemons don't know CmdListAliases; list without aliases then.
+	aliases, err := queryAliases(conn)
+	if err != nil {
+		aliases = nil
+	}
+	byService := make(map[string][]string)
+	for _, kv := range aliases {
+		byService[kv[1]] = append(byService[kv[1]], kv[0])
+	}
 
-		if rply == control.RplyListDone {
-			break
+	for _, entry := range entries {
+		indicator := formatIndicator(entry)
+		suffix := formatSuffix(entry) + formatAliases(byService[entry.Name])
+
+		fmt.Printf("[%s] %s%s\n", indicator, entry.Name, suffix)
+	}
+	return nil
+}
+
+// readSvcInfoList reads the RplySvcInfo entries of a service list up to
+// RplyListDone.
+func readSvcInfoList(conn net.Conn) ([]control.SvcInfoEntry, error) {
+	var entries []control.SvcInfoEntry
+	for {
+		rply, payload, err := readPacket(conn)
+		if err != nil {
+			return nil, err
+		}
+
+		if rply == control.RplyListDone {
+			return entries, nil
 		}
 
 		if rply != control.RplySvcInfo {
-			return fmt.Errorf("unexpected reply: %d", rply)
+			return nil, fmt.Errorf("unexpected reply: %d", rply)
+		}
+
+		entry, _, err := control.DecodeSvcInfo(payload)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+}
+
+// cmdWatch keeps a live service list on screen. By default the list is
+// fetched again every interval; with incremental the daemon pushes the
+// changes (CmdSubscribeList) and the list is redrawn as they arrive.
+func cmdWatch(conn net.Conn, incremental bool, interval time.Duration) error {
+	if !incremental {
+		for {
+			if err := control.WritePacket(conn, control.CmdListServices, nil); err != nil {
+				return err
+			}
+			entries, err := readSvcInfoList(conn)
+			if err != nil {
+				return err
+			}
+			drawWatch(entries)
+			time.Sleep(interval)
 		}
+	}
 
+	if err := control.WritePacket(conn, control.CmdSubscribeList, nil); err != nil {
+		return err
+	}
+	initial, err := readSvcInfoList(conn)
+	if err != nil {
+		return err
+	}
+	byName := make(map[string]control.SvcInfoEntry, len(initial))
+	for _, e := range initial {
+		byName[e.Name] = e
+	}
+	redraw := func() {
+		entries := make([]control.SvcInfoEntry, 0, len(byName))
+		for _, e := range byName {
+			entries = append(entries, e)
+		}
+		drawWatch(entries)
+	}
+	redraw()
+	for {
+		rply, payload, err := readPacket(conn)
+		if err != nil {
+			return err
+		}
+		switch rply {
+		case control.RplySvcAdded, control.RplySvcChanged, control.RplySvcRemoved:
+		default:
+			continue // pushes for other subscriptions
+		}
 		entry, _, err := control.DecodeSvcInfo(payload)
 		if err != nil {
 			return err
 		}
+		if rply == control.RplySvcRemoved {
+			delete(byName, entry.Name)
+		} else {
+			byName[entry.Name] = entry
+		}
+		redraw()
+	}
+}
 
-		indicator := formatIndicator(entry)
-		suffix := formatSuffix(entry)
+// drawWatch prints one screen of `watch`, sorted by name. On a terminal
+// the screen is cleared first; otherwise screens are separated by a
+// blank line.
+func drawWatch(entries []control.SvcInfoEntry) {
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name < entries[j].Name })
+	if isStdoutTTY() {
+		fmt.Print("\033[H\033[2J")
+	} else {
+		fmt.Println()
+	}
+	fmt.Printf("%s  (%d services)\n", time.Now().Format("15:04:05"), len(entries))
+	for _, entry := range entries {
+		fmt.Printf("[%s] %s%s\n", formatIndicator(entry), entry.Name, formatSuffix(entry))
+	}
+}
 
-		fmt.Printf("[%s] %s%s\n", indicator, entry.Name, suffix)
+// parseWatchArgs parses `watch [--incremental] [--interval DURATION]`.
+func parseWatchArgs(args []string) (incremental bool, interval time.Duration, err error) {
+	interval = 2 * time.Second
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		val := ""
+		switch {
+		case arg == "--incremental":
+			incremental = true
+			continue
+		case arg == "--interval":
+			if i+1 >= len(args) {
+				return false, 0, fmt.Errorf("--interval requires a duration")
+			}
+			i++
+			val = args[i]
+		case strings.HasPrefix(arg, "--interval="):
+			val = strings.TrimPrefix(arg, "--interval=")
+		default:
+			return false, 0, fmt.Errorf("unknown argument %q", arg)
+		}
+		if interval, err = time.ParseDuration(val); err != nil {
+			return false, 0, fmt.Errorf("--interval: %v", err)
+		}
+		if interval <= 0 {
+			return false, 0, fmt.Errorf("--interval must be positive")
+		}
 	}
+	return incremental, interval, nil
+}
+
+// formatAliases returns " (aliases: a, b)" for a service's aliases, or
+// "" if it has none.
+func formatAliases(aliases []string) string {
+	if len(aliases) == 0 {
+		return ""
+	}
+	return " (aliases: " + strings.Join(aliases, ", ") + ")"
+}
+
+// queryAliases fetches every alias → service mapping, sorted by alias.
+func queryAliases(conn net.Conn) ([][2]string, error) {
+	if err := control.WritePacket(conn, control.CmdListAliases, nil); err != nil {
+		return nil, err
+	}
+	rply, payload, err := readReply(conn)
+	if err != nil {
+		return nil, err
+	}
+	if rply != control.RplyAliases {
+		return nil, fmt.Errorf("unexpected reply: %d", rply)
+	}
+	return control.DecodeAliases(payload)
+}
+
+// cmdAliases prints each alias and the service it resolves to.
+func cmdAliases(conn net.Conn) error {
+	aliases, err := queryAliases(conn)
+	if err != nil {
+		return err
+	}
+	if len(aliases) == 0 {
+		info("No aliases.\n")
+		return nil
+	}
+	for _, kv := range aliases {
+		fmt.Printf("%s -> %s\n", kv[0], kv[1])
+	}
+	return nil
+}
+
+// cmdListTimers prints every timer service with its schedule.
+func cmdListTimers(conn net.Conn) error {
+	if err := control.WritePacket(conn, control.CmdListTimers, nil); err != nil {
+		return err
+	}
+	rply, payload, err := readReply(conn)
+	if err != nil {
+		return err
+	}
+	if rply != control.RplyTimers {
+		return fmt.Errorf("unexpected reply: %d", rply)
+	}
+	timers, err := control.DecodeTimers(payload)
+	if err != nil {
+		return err
+	}
+	if len(timers) == 0 {
+		info("No timers.\n")
+		return nil
+	}
+	writeTimers(os.Stdout, timers, time.Now())
 	return nil
 }
 
+// writeTimers renders the list-timers table: when each timer fires
+// next and how long that is from now, when it last fired, and the
+// service it activates. A stopped timer shows "-" for its next firing.
+func writeTimers(w io.Writer, timers []service.TimerInfo, now time.Time) {
+	const stamp = "2006-01-02 15:04:05"
+	fmt.Fprintf(w, "%-19s %-8s %-19s %-20s %s\n", "NEXT", "LEFT", "LAST", "TIMER", "TARGET")
+	for _, t := range timers {
+		next, left, last := "-", "-", "-"
+		if !t.NextFire.IsZero() {
+			next = t.NextFire.Format(stamp)
+			left = formatHumanDuration(max(t.NextFire.Sub(now), 0))
+		}
+		if !t.LastFire.IsZero() {
+			last = t.LastFire.Format(stamp)
+		}
+		target := t.Target
+		if target == "" {
+			target = "-"
+		}
+		fmt.Fprintf(w, "%-19s %-8s %-19s %-20s %s\n", next, left, last, t.Name, target)
+	}
+}
+
 // formatIndicator renders the dinit-style 8-char service state indicator.
 //
 // Layout: 3 chars (started zone) + 2 chars (arrow zone) + 3 chars (stopped zone)
@@ -1097,27 +2070,26 @@ func formatIndicator(e control.SvcInfoEntry) string {
 	return string(buf[:])
 }
 
-// formatSuffix returns extra info like (pid: N) or (has console).
+// formatSuffix returns extra info like (pid: N), (has console) or
+// (pinned started).
 func formatSuffix(e control.SvcInfoEntry) string {
-	hasPID := e.PID > 0
-	hasCon := e.Flags&control.StatusFlagHasConsole != 0
-	if !hasPID && !hasCon {
-		return ""
+	var parts []string
+	if e.PID > 0 {
+		parts = append(parts, "pid: "+strconv.FormatInt(int64(e.PID), 10))
 	}
-	var b strings.Builder
-	b.WriteString(" (")
-	if hasPID {
-		b.WriteString("pid: ")
-		b.WriteString(strconv.FormatInt(int64(e.PID), 10))
-		if hasCon {
-			b.WriteString(", ")
-		}
+	if e.Flags&control.StatusFlagHasConsole != 0 {
+		parts = append(parts, "has console")
 	}
-	if hasCon {
-		b.WriteString("has console")
+	switch e.Pin {
+	case control.PinStarted:
+		parts = append(parts, "pinned started")
+	case control.PinStopped:
+		parts = append(parts, "pinned stopped")
+	}
+	if len(parts) == 0 {
+		return ""
 	}
-	b.WriteByte(')')
-	return b.String()
+	return " (" + strings.Join(parts, ", ") + ")"
 }
 
 func cmdStart(conn net.Conn, name string, pin bool, noWait bool) error {
@@ -1128,12 +2100,14 @@ func cmdStart(conn net.Conn, name string, pin bool, noWait bool) error {
 
 	warnIfDescriptionChanged(conn, handle, name)
 
-	payload := encodeStartStopFlags(handle, pin, false)
+	payload := encodeStartStopFlags(handle, pin, false, !noWait)
 	if err := control.WritePacket(conn, control.CmdStartService, payload); err != nil {
 		return err
 	}
 
-	rply, _, err := readReplyWithProgress(conn, fmt.Sprintf("starting %s", name))
+	rply, payload, err := withProgress(fmt.Sprintf("starting %s", name), func() (uint8, []byte, error) {
+		return awaitTransition(conn, handle, name, true)
+	})
 	if err != nil {
 		return err
 	}
@@ -1141,6 +2115,7 @@ func cmdStart(conn net.Conn, name string, pin bool, noWait bool) error {
 	switch rply {
 	case control.RplyACK:
 		info("Service '%s' started.\n", name)
+		printQueuedJob(payload, noWait)
 	case control.RplyAlreadySS:
 		info("Service '%s' is already started.\n", name)
 	case control.RplyPinnedStopped:
@@ -1155,17 +2130,20 @@ func cmdStart(conn net.Conn, name string, pin bool, noWait bool) error {
 	return nil
 }
 
-func cmdWake(conn net.Conn, name string) error {
+func cmdWake(conn net.Conn, name string, noWait bool) error {
 	handle, err := loadServiceHandle(conn, name)
 	if err != nil {
 		return err
 	}
 
-	if err := control.WritePacket(conn, control.CmdWakeService, control.EncodeHandle(handle)); err != nil {
+	payload := encodeStartStopFlags(handle, false, false, !noWait)
+	if err := control.WritePacket(conn, control.CmdWakeService, payload); err != nil {
 		return err
 	}
 
-	rply, _, err := readReply(conn)
+	rply, _, err := withProgress(fmt.Sprintf("waking %s", name), func() (uint8, []byte, error) {
+		return awaitTransition(conn, handle, name, true)
+	})
 	if err != nil {
 		return err
 	}
@@ -1175,6 +2153,8 @@ func cmdWake(conn net.Conn, name string) error {
 		info("Service '%s' woken.\n", name)
 	case control.RplyAlreadySS:
 		info("Service '%s' is already started.\n", name)
+	case control.RplyPinnedStopped:
+		return fmt.Errorf("service '%s' is pinned stopped", name)
 	case control.RplyNAK:
 		return fmt.Errorf("service '%s' has no active dependents, cannot wake", name)
 	case control.RplyShuttingDown:
@@ -1185,17 +2165,22 @@ func cmdWake(conn net.Conn, name string) error {
 	return nil
 }
 
-func cmdRelease(conn net.Conn, name string) error {
+func cmdRelease(conn net.Conn, name string, noWait bool) error {
 	handle, err := loadServiceHandle(conn, name)
 	if err != nil {
 		return err
 	}
 
-	if err := control.WritePacket(conn, control.CmdReleaseService, control.EncodeHandle(handle)); err != nil {
+	// The daemon only pre-acks, and so makes us wait, when the release
+	// stops the service.
+	payload := encodeStartStopFlags(handle, false, false, !noWait)
+	if err := control.WritePacket(conn, control.CmdReleaseService, payload); err != nil {
 		return err
 	}
 
-	rply, _, err := readReply(conn)
+	rply, _, err := withProgress(fmt.Sprintf("releasing %s", name), func() (uint8, []byte, error) {
+		return awaitTransition(conn, handle, name, false)
+	})
 	if err != nil {
 		return err
 	}
@@ -1211,18 +2196,195 @@ func cmdRelease(conn net.Conn, name string) error {
 	return nil
 }
 
+// cmdSwitchTarget makes name the active target, runlevel-style, and
+// reports which services were released and which are stopping.
+func cmdSwitchTarget(conn net.Conn, name string) error {
+	handle, err := loadServiceHandle(conn, name)
+	if err != nil {
+		return err
+	}
+
+	if err := control.WritePacket(conn, control.CmdSwitchTarget, control.EncodeHandle(handle)); err != nil {
+		return err
+	}
+
+	rply, payload, err := readReply(conn)
+	if err != nil {
+		return err
+	}
+
+	switch rply {
+	case control.RplyTargetSwitched:
+		target, released, stopping, derr := control.DecodeTargetSwitched(payload)
+		if derr != nil {
+			return fmt.Errorf("switch-to: bad reply: %w", derr)
+		}
+		info("Switched to target '%s'.\n", target)
+		if len(released) > 0 {
+			info("Released (%d): %s\n", len(released), strings.Join(released, ", "))
+		}
+		if len(stopping) > 0 {
+			info("Stopping (%d): %s\n", len(stopping), strings.Join(stopping, ", "))
+		}
+	case control.RplyPinnedStopped:
+		return fmt.Errorf("service '%s' is pinned stopped", name)
+	case control.RplyManualRefused:
+		return fmt.Errorf("service '%s' refuses manual start (refuse-manual-start = yes)", name)
+	case control.RplyShuttingDown:
+		return fmt.Errorf("system is shutting down")
+	case control.RplyNAK:
+		return fmt.Errorf("switch-to: %s", string(payload))
+	case control.RplyBadReq:
+		return fmt.Errorf("daemon does not support switch-to")
+	default:
+		return fmt.Errorf("unexpected reply: %d", rply)
+	}
+	return nil
+}
+
+// printQueuedJob prints the job ID a start or stop ACK carries when the
+// command did not wait, so a script can follow it with `slinitctl job`
+// or withdraw it with `slinitctl cancel`. It prints even though
+// --no-wait otherwise silences the success message.
+func printQueuedJob(ack []byte, noWait bool) {
+	if id := control.DecodeJobACK(ack); noWait && id != 0 {
+		fmt.Printf("Job %d queued.\n", id)
+	}
+}
+
+// parseJobID parses a job ID argument.
+func parseJobID(arg string) (uint32, error) {
+	id, err := strconv.ParseUint(arg, 10, 32)
+	if err != nil || id == 0 {
+		return 0, fmt.Errorf("invalid job ID %q", arg)
+	}
+	return uint32(id), nil
+}
+
+func cmdJobs(conn net.Conn) error {
+	if err := control.WritePacket(conn, control.CmdListJobs, nil); err != nil {
+		return err
+	}
+	var jobs []service.Job
+	for {
+		rply, payload, err := readReply(conn)
+		if err != nil {
+			return err
+		}
+		if rply == control.RplyListDone {
+			break
+		}
+		if rply == control.RplyBadReq {
+			return fmt.Errorf("daemon does not support jobs")
+		}
+		if rply != control.RplyJobInfo {
+			return fmt.Errorf("unexpected reply: %d", rply)
+		}
+		j, err := control.DecodeJobInfo(payload)
+		if err != nil {
+			return err
+		}
+		jobs = append(jobs, j)
+	}
+	if len(jobs) == 0 {
+		info("No jobs.\n")
+		return nil
+	}
+	writeJobs(os.Stdout, jobs, time.Now())
+	return nil
+}
+
+// writeJobs renders the jobs table. TIME is how long a job has been
+// running, or how long it took once settled.
+func writeJobs(w io.Writer, jobs []service.Job, now time.Time) {
+	fmt.Fprintf(w, "%-6s %-5s %-9s %-8s %s\n", "JOB", "KIND", "STATE", "TIME", "SERVICE")
+	for _, j := range jobs {
+		end := j.Finished
+		if end.IsZero() {
+			end = now
+		}
+		took := formatHumanDuration(max(end.Sub(j.Created), 0))
+		fmt.Fprintf(w, "%-6d %-5s %-9s %-8s %s\n", j.ID, j.Kind, j.State, took, j.Service)
+	}
+}
+
+// cmdJob shows one job. A failed or cancelled job is an error, so a
+// script polling `slinitctl job N` learns the outcome from the exit
+// status.
+func cmdJob(conn net.Conn, arg string) error {
+	id, err := parseJobID(arg)
+	if err != nil {
+		return err
+	}
+	if err := control.WritePacket(conn, control.CmdQueryJob, control.EncodeHandle(id)); err != nil {
+		return err
+	}
+	rply, payload, err := readReply(conn)
+	if err != nil {
+		return err
+	}
+	switch rply {
+	case control.RplyJobInfo:
+	case control.RplyNAK:
+		return fmt.Errorf("%s", string(payload))
+	case control.RplyBadReq:
+		return fmt.Errorf("daemon does not support jobs")
+	default:
+		return fmt.Errorf("unexpected reply: %d", rply)
+	}
+	j, err := control.DecodeJobInfo(payload)
+	if err != nil {
+		return err
+	}
+	info("Job %d: %s of '%s' %s\n", j.ID, j.Kind, j.Service, j.State)
+	switch j.State {
+	case service.JobFailed:
+		return fmt.Errorf("job %d failed", j.ID)
+	case service.JobCancelled:
+		return fmt.Errorf("job %d was cancelled", j.ID)
+	}
+	return nil
+}
+
+func cmdCancelJob(conn net.Conn, arg string) error {
+	id, err := parseJobID(arg)
+	if err != nil {
+		return err
+	}
+	if err := control.WritePacket(conn, control.CmdCancelJob, control.EncodeHandle(id)); err != nil {
+		return err
+	}
+	rply, payload, err := readReply(conn)
+	if err != nil {
+		return err
+	}
+	switch rply {
+	case control.RplyACK:
+		info("Job %d cancelled.\n", id)
+	case control.RplyNAK:
+		return fmt.Errorf("cancel: %s", string(payload))
+	case control.RplyBadReq:
+		return fmt.Errorf("daemon does not support jobs")
+	default:
+		return fmt.Errorf("unexpected reply: %d", rply)
+	}
+	return nil
+}
+
 func cmdStop(conn net.Conn, name string, pin bool, force bool, ignoreUnstarted bool, noWait bool) error {
 	handle, err := loadServiceHandle(conn, name)
 	if err != nil {
 		return err
 	}
 
-	payload := encodeStartStopFlags(handle, pin, force)
+	payload := encodeStartStopFlags(handle, pin, force, !noWait)
 	if err := control.WritePacket(conn, control.CmdStopService, payload); err != nil {
 		return err
 	}
 
-	rply, _, err := readReplyWithProgress(conn, fmt.Sprintf("stopping %s", name))
+	rply, payload, err := withProgress(fmt.Sprintf("stopping %s", name), func() (uint8, []byte, error) {
+		return awaitTransition(conn, handle, name, false)
+	})
 	if err != nil {
 		return err
 	}
@@ -1230,6 +2392,7 @@ func cmdStop(conn net.Conn, name string, pin bool, force bool, ignoreUnstarted b
 	switch rply {
 	case control.RplyACK:
 		info("Service '%s' stopped.\n", name)
+		printQueuedJob(payload, noWait)
 	case control.RplyAlreadySS:
 		info("Service '%s' is already stopped.\n", name)
 	case control.RplyPinnedStarted:
@@ -1251,11 +2414,13 @@ func cmdRestart(conn net.Conn, name string, pin bool, force bool, ignoreUnstarte
 	warnIfDescriptionChanged(conn, handle, name)
 
 	// Stop first
-	stopPayload := encodeStartStopFlags(handle, false, force)
+	stopPayload := encodeStartStopFlags(handle, false, force, !noWait)
 	if err := control.WritePacket(conn, control.CmdStopService, stopPayload); err != nil {
 		return err
 	}
-	rply, _, err := readReplyWithProgress(conn, fmt.Sprintf("stopping %s", name))
+	rply, _, err := withProgress(fmt.Sprintf("stopping %s", name), func() (uint8, []byte, error) {
+		return awaitTransition(conn, handle, name, false)
+	})
 	if err != nil {
 		return err
 	}
@@ -1268,11 +2433,13 @@ func cmdRestart(conn net.Conn, name string, pin bool, force bool, ignoreUnstarte
 	}
 
 	// Then start
-	startPayload := encodeStartStopFlags(handle, pin, false)
+	startPayload := encodeStartStopFlags(handle, pin, false, !noWait)
 	if err := control.WritePacket(conn, control.CmdStartService, startPayload); err != nil {
 		return err
 	}
-	rply, _, err = readReplyWithProgress(conn, fmt.Sprintf("starting %s", name))
+	rply, payload, err := withProgress(fmt.Sprintf("starting %s", name), func() (uint8, []byte, error) {
+		return awaitTransition(conn, handle, name, true)
+	})
 	if err != nil {
 		return err
 	}
@@ -1280,6 +2447,7 @@ func cmdRestart(conn net.Conn, name string, pin bool, force bool, ignoreUnstarte
 	switch rply {
 	case control.RplyACK:
 		info("Service '%s' restarted.\n", name)
+		printQueuedJob(payload, noWait)
 	case control.RplyShuttingDown:
 		return fmt.Errorf("system is shutting down")
 	default:
@@ -1288,6 +2456,9 @@ func cmdRestart(conn net.Conn, name string, pin bool, force bool, ignoreUnstarte
 	return nil
 }
 
+// maxStatusAnnotations is how many of a service's notes `status` shows.
+const maxStatusAnnotations = 5
+
 func cmdStatus(conn net.Conn, name string) error {
 	handle, err := loadServiceHandle(conn, name)
 	if err != nil {
@@ -1321,12 +2492,15 @@ func cmdStatus(conn net.Conn, name string) error {
 	// Show the description file path + modification marker, dinit-parity
 	// e099aa4 + a94ef73. Skip on error so init.d/synthesized services
 	// don't print a bogus "File:" line.
-	if sdfPath, modified, ok := resolveServiceDescFile(conn, name, loadModTime); ok {
+	if status.Flags&control.StatusFlagEmbedded != 0 {
+		fmt.Printf("  Source:  embedded\n")
+	} else if sdfPath, modified, ok := resolveServiceDescFile(conn, name, loadModTime); ok {
 		if modified {
 			fmt.Printf("  File:    %s (modified since loaded)\n", sdfPath)
 		} else {
 			fmt.Printf("  File:    %s\n", sdfPath)
 		}
+		fmt.Printf("  Source:  filesystem\n")
 	}
 	if desc, err := fetchDescription(conn, handle); err == nil && desc != "" {
 		fmt.Printf("  Description: %s\n", desc)
@@ -1351,6 +2525,50 @@ func cmdStatus(conn net.Conn, name string) error {
 	if status.ExitStatus != 0 {
 		fmt.Printf("  Exit:    %d\n", status.ExitStatus)
 	}
+	if h, err := fetchHealth(conn, handle); err == nil && h.Flags&control.HealthFlagConfigured != 0 {
+		fmt.Printf("  Health:  %s\n", formatHealth(h, time.Now()))
+	}
+	if status.SvcType == service.TypeTriggered {
+		if ti, err := fetchTriggers(conn, handle); err == nil && len(ti.Named) > 0 {
+			fmt.Printf("  Triggers: %s\n", formatTriggers(ti))
+		}
+	}
+	writeStatusLifecycle(os.Stdout, status, time.Now())
+	if status.LastErrorMessage != "" {
+		fmt.Printf("  Last error: %q\n", status.LastErrorMessage)
+	}
+	if status.HasFailureStats && status.FailureStats.TotalStarts > 0 {
+		fmt.Printf("  Reliability: %s\n", formatReliability(status.FailureStats, status.RestartSuppressed))
+	}
+	if status.HasEffectiveRestart {
+		fmt.Printf("  Effective restart: %s (%s)\n", status.EffectiveRestart, status.RestartSource)
+	} else if o := status.RestartOverride; o != nil {
+		if *o {
+			fmt.Println("  Auto-restart: enabled (override)")
+		} else {
+			fmt.Println("  Auto-restart: disabled (override)")
+		}
+	}
+
+	if deps, dependents, err := fetchDependencyInfo(conn, handle); err == nil {
+		writeStatusDeps(os.Stdout, deps, dependents, func(name string) string {
+			if s, err := getServiceStatus(conn, name); err == nil {
+				return formatState(s.State)
+			}
+			return "?"
+		})
+	}
+
+	// Only the newest few notes, to keep status short.
+	if notes, err := fetchAnnotations(conn, handle); err == nil && len(notes) > 0 {
+		if len(notes) > maxStatusAnnotations {
+			notes = notes[len(notes)-maxStatusAnnotations:]
+		}
+		fmt.Println("  Annotations:")
+		for _, a := range notes {
+			fmt.Printf("    %s\n", formatAnnotation(a))
+		}
+	}
 
 	// Bundle rendering: when the service is an s6-rc-style bundle the
 	// members list is non-empty, so we fetch each member's state and
@@ -1387,38 +2605,174 @@ func fetchDescription(conn net.Conn, handle uint32) (string, error) {
 	return desc, err
 }
 
-// fetchBundleMembers queries the s6-rc-style member list for a bundle
-// service handle. Empty list is a legitimate reply for non-bundle
-// services, so callers use it as a "should I render a Members section?"
-// gate rather than as an error signal.
-func fetchBundleMembers(conn net.Conn, handle uint32) ([]string, error) {
-	if err := control.WritePacket(conn, control.CmdQueryBundleMembers, control.EncodeHandle(handle)); err != nil {
-		return nil, err
+// fetchHealth queries the healthcheck-command state for a service handle.
+// Services without a health check come back with no flags set.
+func fetchHealth(conn net.Conn, handle uint32) (control.HealthInfo, error) {
+	if err := control.WritePacket(conn, control.CmdQueryHealth, control.EncodeHandle(handle)); err != nil {
+		return control.HealthInfo{}, err
 	}
 	rply, payload, err := readReply(conn)
 	if err != nil {
-		return nil, err
+		return control.HealthInfo{}, err
 	}
-	if rply != control.RplyBundleMembers {
-		return nil, fmt.Errorf("unexpected reply: %d", rply)
+	if rply != control.RplyHealth {
+		return control.HealthInfo{}, fmt.Errorf("unexpected reply: %d", rply)
 	}
-	members, _, err := control.DecodeStringList(payload)
-	return members, err
+	return control.DecodeHealth(payload)
 }
 
-// fetchMetadata queries author/version/usage strings for a service handle.
-// Returns empty strings (no error) when the server does not support the
-// command, or when the service has no metadata set.
-func fetchMetadata(conn net.Conn, handle uint32) (author, version, usage string, err error) {
-	if err := control.WritePacket(conn, control.CmdQueryMetadata, control.EncodeHandle(handle)); err != nil {
-		return "", "", "", err
+// fetchTriggers queries the trigger state of a triggered service.
+func fetchTriggers(conn net.Conn, handle uint32) (control.TriggerInfo, error) {
+	if err := control.WritePacket(conn, control.CmdQueryTriggers, control.EncodeHandle(handle)); err != nil {
+		return control.TriggerInfo{}, err
 	}
 	rply, payload, err := readReply(conn)
 	if err != nil {
-		return "", "", "", err
+		return control.TriggerInfo{}, err
 	}
-	if rply != control.RplyMetadata {
-		return "", "", "", fmt.Errorf("unexpected reply: %d", rply)
+	if rply != control.RplyTriggers {
+		return control.TriggerInfo{}, fmt.Errorf("unexpected reply: %d", rply)
+	}
+	return control.DecodeTriggers(payload)
+}
+
+// formatTriggers renders the "Triggers:" status line, e.g.
+// "network (set), database (waiting) [all required]".
+func formatTriggers(ti control.TriggerInfo) string {
+	parts := make([]string, len(ti.Named))
+	for i, nt := range ti.Named {
+		state := "waiting"
+		if nt.Set {
+			state = "set"
+		}
+		parts[i] = fmt.Sprintf("%s (%s)", nt.Name, state)
+	}
+	mode := "any"
+	if ti.All {
+		mode = "all required"
+	}
+	return fmt.Sprintf("%s [%s]", strings.Join(parts, ", "), mode)
+}
+
+// writeStatusLifecycle writes the status lines about the service's
+// current run and how the last one ended: uptime while started, whether
+// the last start failed or was skipped, the restart count and, once
+// stopped, the stop reason.
+func writeStatusLifecycle(w io.Writer, status control.ServiceStatusInfo, now time.Time) {
+	if status.State == service.StateStarted && !status.StartedTime.IsZero() {
+		fmt.Fprintf(w, "  Uptime:  %s (since %s)\n", formatHumanDuration(now.Sub(status.StartedTime)),
+			status.StartedTime.Format("2006-01-02 15:04:05"))
+	}
+	if status.Flags&control.StatusFlagStartFailed != 0 {
+		fmt.Fprintln(w, "  Last start: failed")
+	} else if status.Flags&control.StatusFlagStartSkipped != 0 {
+		fmt.Fprintln(w, "  Last start: skipped")
+	}
+	if status.HasFailureStats && status.FailureStats.TotalRestarts > 0 {
+		fmt.Fprintf(w, "  Restarts: %d\n", status.FailureStats.TotalRestarts)
+	}
+	if !status.HasStopReason {
+		return
+	}
+	if status.StopReason >= service.ReasonCustom {
+		fmt.Fprintf(w, "  Stop reason: %s - %q\n", status.StopReason, status.CustomStopReason)
+	} else if status.State == service.StateStopped && status.StopReason != service.ReasonNormal {
+		fmt.Fprintf(w, "  Stop reason: %s\n", status.StopReason)
+	}
+}
+
+// writeStatusDeps writes the "Dependencies:" and "Dependents:" sections
+// of status, one indented line per edge with its type and the other
+// service's state; stateOf looks up a dependent's state, which the
+// dependency info does not carry.
+func writeStatusDeps(w io.Writer, deps, dependents []service.DependencyInfo, stateOf func(name string) string) {
+	if len(deps) > 0 {
+		fmt.Fprintln(w, "  Dependencies:")
+		for _, d := range deps {
+			attrs := d.DepType.String()
+			if d.WaitingOn {
+				attrs += ", waiting"
+			}
+			fmt.Fprintf(w, "    → %s (%s): %s\n", d.Name, attrs, formatState(d.State))
+		}
+	}
+	if len(dependents) > 0 {
+		fmt.Fprintln(w, "  Dependents:")
+		for _, d := range dependents {
+			fmt.Fprintf(w, "    ← %s (%s): %s\n", d.Name, d.DepType, stateOf(d.Name))
+		}
+	}
+}
+
+// formatHealth renders the "Health:" status line, e.g.
+// "OK (last check: 5s ago)" or "FAILING (3/3 retries)".
+func formatHealth(h control.HealthInfo, now time.Time) string {
+	if h.LastCheck.IsZero() {
+		return "PENDING (no check yet)"
+	}
+	if h.Flags&control.HealthFlagHealthy != 0 {
+		ago := now.Sub(h.LastCheck).Truncate(time.Second)
+		return fmt.Sprintf("OK (last check: %s ago)", ago)
+	}
+	if h.MaxFailures == 0 {
+		return fmt.Sprintf("FAILING (%d failures)", h.Failures)
+	}
+	return fmt.Sprintf("FAILING (%d/%d retries)", h.Failures, h.MaxFailures)
+}
+
+// formatReliability renders the "Reliability:" status line, e.g.
+// "42/45 starts succeeded (93.3%), 3 consecutive failures".
+func formatReliability(fs service.FailureStats, suppressed bool) string {
+	ok := fs.TotalStarts - fs.TotalFailures
+	if ok < 0 {
+		ok = 0
+	}
+	s := fmt.Sprintf("%d/%d starts succeeded (%.1f%%)", ok, fs.TotalStarts, fs.SuccessRate()*100)
+	switch fs.ConsecutiveFailures {
+	case 0:
+	case 1:
+		s += ", 1 consecutive failure"
+	default:
+		s += fmt.Sprintf(", %d consecutive failures", fs.ConsecutiveFailures)
+	}
+	if suppressed {
+		s += ", auto-restart disabled (reset-failed to re-enable)"
+	}
+	return s
+}
+
+// fetchBundleMembers queries the s6-rc-style member list for a bundle
+// service handle. Empty list is a legitimate reply for non-bundle
+// services, so callers use it as a "should I render a Members section?"
+// gate rather than as an error signal.
+func fetchBundleMembers(conn net.Conn, handle uint32) ([]string, error) {
+	if err := control.WritePacket(conn, control.CmdQueryBundleMembers, control.EncodeHandle(handle)); err != nil {
+		return nil, err
+	}
+	rply, payload, err := readReply(conn)
+	if err != nil {
+		return nil, err
+	}
+	if rply != control.RplyBundleMembers {
+		return nil, fmt.Errorf("unexpected reply: %d", rply)
+	}
+	members, _, err := control.DecodeStringList(payload)
+	return members, err
+}
+
+// fetchMetadata queries author/version/usage strings for a service handle.
+// Returns empty strings (no error) when the server does not support the
+// command, or when the service has no metadata set.
+func fetchMetadata(conn net.Conn, handle uint32) (author, version, usage string, err error) {
+	if err := control.WritePacket(conn, control.CmdQueryMetadata, control.EncodeHandle(handle)); err != nil {
+		return "", "", "", err
+	}
+	rply, payload, err := readReply(conn)
+	if err != nil {
+		return "", "", "", err
+	}
+	if rply != control.RplyMetadata {
+		return "", "", "", fmt.Errorf("unexpected reply: %d", rply)
 	}
 	return control.DecodeMetadata(payload)
 }
@@ -1934,6 +3288,9 @@ func cmdShutdownDispatch(conn net.Conn, args []string) error {
 		if a == "--status" {
 			return cmdQueryShutdown(conn)
 		}
+		if a == "--dry-run" {
+			return cmdShutdownPlan(conn)
+		}
 	}
 
 	shutType := "poweroff"
@@ -1982,7 +3339,7 @@ func cmdShutdownDispatch(conn net.Conn, args []string) error {
 			shutType = a
 			positionalIdx++
 		default:
-			if _, err := parseShutdownTime(a); err == nil {
+			if _, err := shutdown.ParseDelay(a); err == nil {
 				timeArg = a
 				positionalIdx++
 				continue
@@ -2040,7 +3397,7 @@ func cmdShutdownDispatch(conn net.Conn, args []string) error {
 				"to actually kexec.\n")
 	}
 
-	delay, err := parseShutdownTime(timeArg)
+	delay, err := shutdown.ParseDelay(timeArg)
 	if err != nil {
 		return err
 	}
@@ -2051,7 +3408,7 @@ func cmdShutdownDispatch(conn net.Conn, args []string) error {
 		if err := control.WritePacket(conn, control.CmdShutdown, payload); err != nil {
 			return err
 		}
-		rply, _, err := control.ReadPacket(conn)
+		rply, _, err := readPacket(conn)
 		if err != nil {
 			return err
 		}
@@ -2063,30 +3420,11 @@ func cmdShutdownDispatch(conn net.Conn, args []string) error {
 		return nil
 	}
 
-	// Scheduled shutdown. Wire layout:
-	//   [type(1)] [delay_secs(4, big-endian)]
-	//   [msg_len(2, LE)] [msg_bytes...]
-	// The message tail is only appended when non-empty so pre-message
-	// slinitd instances still accept the packet (they treat the extra
-	// bytes as ignored padding).
-	secs := uint32(delay.Seconds())
-	payload := []byte{
-		uint8(st),
-		byte(secs >> 24), byte(secs >> 16), byte(secs >> 8), byte(secs),
-	}
-	if message != "" {
-		msgBytes := []byte(message)
-		if len(msgBytes) > 0xFFFF {
-			msgBytes = msgBytes[:0xFFFF]
-		}
-		payload = append(payload,
-			byte(len(msgBytes)&0xFF), byte((len(msgBytes)>>8)&0xFF))
-		payload = append(payload, msgBytes...)
-	}
+	payload := control.EncodeScheduleShutdown(st, delay, message)
 	if err := control.WritePacket(conn, control.CmdScheduleShutdown, payload); err != nil {
 		return err
 	}
-	rply, _, err := control.ReadPacket(conn)
+	rply, _, err := readPacket(conn)
 	if err != nil {
 		return err
 	}
@@ -2114,7 +3452,7 @@ func sendWallNotice(conn net.Conn, msg string) error {
 	if err := control.WritePacket(conn, control.CmdWallNotice, payload); err != nil {
 		return err
 	}
-	rply, _, err := control.ReadPacket(conn)
+	rply, _, err := readPacket(conn)
 	if err != nil {
 		return err
 	}
@@ -2129,7 +3467,7 @@ func cmdCancelShutdown(conn net.Conn) error {
 	if err := control.WritePacket(conn, control.CmdCancelShutdown, nil); err != nil {
 		return err
 	}
-	rply, _, err := control.ReadPacket(conn)
+	rply, _, err := readPacket(conn)
 	if err != nil {
 		return err
 	}
@@ -2145,7 +3483,7 @@ func cmdQueryShutdown(conn net.Conn) error {
 	if err := control.WritePacket(conn, control.CmdQueryShutdown, nil); err != nil {
 		return err
 	}
-	rply, payload, err := control.ReadPacket(conn)
+	rply, payload, err := readPacket(conn)
 	if err != nil {
 		return err
 	}
@@ -2165,6 +3503,298 @@ func cmdQueryShutdown(conn net.Conn) error {
 	return nil
 }
 
+// cmdShutdownPlan prints which services a shutdown would stop, in which
+// order, and how long it is expected to take. Nothing is stopped.
+func cmdShutdownPlan(conn net.Conn) error {
+	if err := control.WritePacket(conn, control.CmdShutdownPlan, nil); err != nil {
+		return err
+	}
+	rply, payload, err := readReply(conn)
+	if err != nil {
+		return err
+	}
+	if rply != control.RplyShutdownPlan {
+		return fmt.Errorf("unexpected reply: %d", rply)
+	}
+	plan, err := control.DecodeShutdownPlan(payload)
+	if err != nil {
+		return err
+	}
+	printShutdownPlan(os.Stdout, plan)
+	return nil
+}
+
+// cmdEdit opens a service's description file in $VISUAL / $EDITOR
+// (default vi). The daemon holds an exclusive lock on the file for the
+// whole session, so a reload waits for the edit instead of reading a
+// half-written file; the lock goes when the session ends, even if
+// slinitctl dies. Run "slinitctl reload" afterwards to apply changes.
+func cmdEdit(conn net.Conn, name string) error {
+	path, err := lockServiceFile(conn, name)
+	if err != nil {
+		return err
+	}
+
+	editor := os.Getenv("VISUAL")
+	if editor == "" {
+		editor = os.Getenv("EDITOR")
+	}
+	argv := strings.Fields(editor)
+	if len(argv) == 0 {
+		argv = []string{"vi"}
+	}
+	cmd := exec.Command(argv[0], append(argv[1:], path)...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	editErr := cmd.Run()
+
+	if err := unlockServiceFile(conn, name); err != nil {
+		return err
+	}
+	if editErr != nil {
+		return fmt.Errorf("editor: %w", editErr)
+	}
+	info("Edited %s; run 'slinitctl reload %s' to apply.\n", path, name)
+	return nil
+}
+
+// lockServiceFile asks the daemon to lock a service's description file
+// for this connection and returns its path.
+func lockServiceFile(conn net.Conn, name string) (string, error) {
+	if err := control.WritePacket(conn, control.CmdLockServiceFile, control.EncodeServiceName(name)); err != nil {
+		return "", err
+	}
+	rply, payload, err := readReply(conn)
+	if err != nil {
+		return "", err
+	}
+	switch rply {
+	case control.RplyServiceFile:
+	case control.RplyNoService:
+		return "", fmt.Errorf("no service file for '%s' in the daemon's service directories", name)
+	case control.RplyNAK:
+		return "", fmt.Errorf("service file for '%s' is being edited elsewhere", name)
+	default:
+		return "", fmt.Errorf("unexpected reply: %d", rply)
+	}
+	path, _, err := control.DecodeServiceName(payload)
+	return path, err
+}
+
+// unlockServiceFile releases a lockServiceFile lock.
+func unlockServiceFile(conn net.Conn, name string) error {
+	if err := control.WritePacket(conn, control.CmdUnlockServiceFile, control.EncodeServiceName(name)); err != nil {
+		return err
+	}
+	_, _, err := readReply(conn)
+	return err
+}
+
+// cmdPatchApply installs a patch file into <service>.d/ next to the
+// service file, after checking that it parses and applies cleanly on
+// top of the service file and the patches already in that directory.
+// The service file stays locked meanwhile so a reload cannot see a
+// half-installed patch. Run "slinitctl reload" afterwards to apply.
+func cmdPatchApply(conn net.Conn, name, patchFile string) error {
+	patchName := filepath.Base(patchFile)
+	if !strings.HasSuffix(patchName, config.PatchSuffix) {
+		return fmt.Errorf("%s: patch file name must end in %s", patchFile, config.PatchSuffix)
+	}
+	content, err := os.ReadFile(patchFile)
+	if err != nil {
+		return err
+	}
+
+	path, err := lockServiceFile(conn, name)
+	if err != nil {
+		return err
+	}
+	defer unlockServiceFile(conn, name)
+
+	var serviceArg *string
+	if _, arg, ok := config.SplitInstance(name); ok {
+		serviceArg = &arg
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	var desc *config.ServiceDescription
+	if serviceArg != nil {
+		desc, err = config.ParseWithArg(f, name, path, *serviceArg)
+	} else {
+		desc, err = config.Parse(f, name, path)
+	}
+	f.Close()
+	if err != nil {
+		return err
+	}
+
+	dir := filepath.Dir(path)
+	existing, err := config.PatchFiles([]string{dir}, name)
+	if err != nil {
+		return err
+	}
+	for _, p := range existing {
+		if filepath.Base(p) == patchName {
+			continue // being replaced
+		}
+		if err := config.ApplyPatchFile(desc, p, serviceArg); err != nil {
+			return err
+		}
+	}
+	if err := config.ApplyPatchFile(desc, patchFile, serviceArg); err != nil {
+		return err
+	}
+
+	patchDir := filepath.Join(dir, name+".d")
+	if err := os.MkdirAll(patchDir, 0755); err != nil {
+		return err
+	}
+	dest := filepath.Join(patchDir, patchName)
+	tmp := dest + ".tmp"
+	if err := os.WriteFile(tmp, content, 0644); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, dest); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	info("Installed %s; run 'slinitctl reload %s' to apply.\n", dest, name)
+	return nil
+}
+
+// cmdVerifyInternal asks the daemon to check its service reference
+// counts and prints any discrepancy. Exits 1 when there are some.
+func cmdVerifyInternal(conn net.Conn) error {
+	if err := control.WritePacket(conn, control.CmdVerifyInternal, nil); err != nil {
+		return err
+	}
+	rply, payload, err := readReply(conn)
+	if err != nil {
+		return err
+	}
+	if rply != control.RplyRefCountErrors {
+		return fmt.Errorf("unexpected reply: %d", rply)
+	}
+	errs, err := control.DecodeRefCountErrors(payload)
+	if err != nil {
+		return err
+	}
+	if len(errs) == 0 {
+		fmt.Println("Reference counts consistent.")
+		return nil
+	}
+	for _, e := range errs {
+		fmt.Println(e.Error())
+	}
+	os.Exit(1)
+	return nil
+}
+
+// cmdDiagnostics prints the daemon's diagnostic dump of the whole
+// service graph, which arrives as a run of text chunks.
+func cmdDiagnostics(conn net.Conn, w io.Writer) error {
+	if err := control.WritePacket(conn, control.CmdDumpDiagnostics, nil); err != nil {
+		return err
+	}
+	for {
+		rply, payload, err := readReply(conn)
+		if err != nil {
+			return err
+		}
+		switch rply {
+		case control.RplyDiagnostics:
+			if _, err := w.Write(payload); err != nil {
+				return err
+			}
+		case control.RplyListDone:
+			return nil
+		default:
+			return fmt.Errorf("unexpected reply: %d", rply)
+		}
+	}
+}
+
+// cmdInfo prints what the daemon reports about itself and this
+// connection, one "key: value" per line.
+// queryInfo fetches the daemon's CmdQueryInfo key/value pairs.
+func queryInfo(conn net.Conn) ([][2]string, error) {
+	if err := control.WritePacket(conn, control.CmdQueryInfo, nil); err != nil {
+		return nil, err
+	}
+	rply, payload, err := readReply(conn)
+	if err != nil {
+		return nil, err
+	}
+	if rply != control.RplyInfo {
+		return nil, fmt.Errorf("unexpected reply: %d", rply)
+	}
+	return control.DecodeInfo(payload)
+}
+
+// cmdCheckShadowing lists service files that are never loaded: those
+// hidden by a same-named file in an earlier service directory, and
+// masked <service>.disabled files. The daemon only supplies its service
+// directories; the scan runs here.
+func cmdCheckShadowing(conn net.Conn) error {
+	pairs, err := queryInfo(conn)
+	if err != nil {
+		return err
+	}
+	var dirs []string
+	for _, kv := range pairs {
+		if kv[0] == "service-dir" {
+			dirs = append(dirs, kv[1])
+		}
+	}
+	warnings := config.CheckShadowing(dirs)
+	if len(warnings) == 0 {
+		info("No shadowed or masked services.\n")
+		return nil
+	}
+	fmt.Printf("%-24s %-9s %-40s %s\n", "SERVICE", "STATUS", "ACTIVE", "IGNORED")
+	for _, w := range warnings {
+		status, active := "shadowed", w.ActivePath
+		if w.Masked {
+			status = "masked"
+		}
+		if active == "" {
+			active = "-"
+		}
+		fmt.Printf("%-24s %-9s %-40s %s\n", w.ServiceName, status, active, w.ShadowedPath)
+	}
+	return nil
+}
+
+func cmdInfo(conn net.Conn) error {
+	pairs, err := queryInfo(conn)
+	if err != nil {
+		return err
+	}
+	for _, kv := range pairs {
+		fmt.Printf("%s: %s\n", kv[0], kv[1])
+	}
+	return nil
+}
+
+// printShutdownPlan renders a shutdown plan as a table of steps followed
+// by the estimated total, which is the sum of the per-step estimates
+// since each step waits for the previous one.
+func printShutdownPlan(w io.Writer, plan []service.ShutdownStep) {
+	if len(plan) == 0 {
+		fmt.Fprintln(w, "No active services; shutdown would stop nothing.")
+		return
+	}
+	fmt.Fprintf(w, "%-5s %-10s %s\n", "STEP", "ESTIMATE", "SERVICES")
+	var total time.Duration
+	for i, step := range plan {
+		est := time.Duration(step.EstimatedDurationMs) * time.Millisecond
+		total += est
+		fmt.Fprintf(w, "%-5d %-10s %s\n", i+1, formatDuration(est), strings.Join(step.Services, ", "))
+	}
+	fmt.Fprintf(w, "Estimated total shutdown time: %s\n", formatDuration(total))
+}
+
 func parseShutdownType(s string) (service.ShutdownType, error) {
 	switch s {
 	case "halt":
@@ -2195,49 +3825,6 @@ func kexecKernelLoaded() bool {
 	return strings.TrimSpace(string(data)) == "1"
 }
 
-// parseShutdownTime parses a time argument into a duration.
-//
-//	"now"      → 0 (immediate)
-//	"+N"       → N minutes
-//	"HH:MM"    → delay until that time today (or tomorrow if past)
-func parseShutdownTime(s string) (time.Duration, error) {
-	if s == "now" || s == "" {
-		return 0, nil
-	}
-
-	// +N minutes
-	if strings.HasPrefix(s, "+") {
-		mins, err := strconv.Atoi(s[1:])
-		if err != nil || mins < 0 {
-			return 0, fmt.Errorf("invalid time: %s (use +N for minutes)", s)
-		}
-		return time.Duration(mins) * time.Minute, nil
-	}
-
-	// Plain number = minutes
-	if mins, err := strconv.Atoi(s); err == nil && mins >= 0 {
-		return time.Duration(mins) * time.Minute, nil
-	}
-
-	// HH:MM absolute time
-	parts := strings.SplitN(s, ":", 2)
-	if len(parts) == 2 {
-		h, err1 := strconv.Atoi(parts[0])
-		m, err2 := strconv.Atoi(parts[1])
-		if err1 == nil && err2 == nil && h >= 0 && h <= 23 && m >= 0 && m <= 59 {
-			now := time.Now()
-			target := time.Date(now.Year(), now.Month(), now.Day(), h, m, 0, 0, now.Location())
-			if target.Before(now) {
-				// Target time already passed today — schedule for tomorrow.
-				target = target.Add(24 * time.Hour)
-			}
-			return target.Sub(now), nil
-		}
-	}
-
-	return 0, fmt.Errorf("invalid time: %s (use 'now', '+N' for minutes, or 'HH:MM')", s)
-}
-
 func shutdownTypeToString(st service.ShutdownType) string {
 	switch st {
 	case service.ShutdownHalt:
@@ -2270,17 +3857,42 @@ func formatHumanDuration(d time.Duration) string {
 	return fmt.Sprintf("%dh%dm", h, m)
 }
 
-func cmdTrigger(conn net.Conn, name string) error {
+// cmdTrigger sets the trigger of a triggered service, or with event
+// the named trigger of that name.
+func cmdTrigger(conn net.Conn, name, event string) error {
+	if err := sendTrigger(conn, name, event, true); err != nil {
+		return err
+	}
+	if event != "" {
+		info("Service '%s' triggered by '%s'.\n", name, event)
+	} else {
+		info("Service '%s' triggered.\n", name)
+	}
+	return nil
+}
+
+// sendTrigger sends CmdSetTrigger, or CmdSetNamedTrigger when event is
+// set.
+func sendTrigger(conn net.Conn, name, event string, value bool) error {
 	handle, err := loadServiceHandle(conn, name)
 	if err != nil {
 		return err
 	}
 
-	payload := make([]byte, 5)
-	binary.LittleEndian.PutUint32(payload, handle)
-	payload[4] = 1 // trigger = true
+	cmd := control.CmdSetTrigger
+	var payload []byte
+	if event != "" {
+		cmd = control.CmdSetNamedTrigger
+		payload = control.EncodeSetNamedTrigger(handle, event, value)
+	} else {
+		payload = make([]byte, 5)
+		binary.LittleEndian.PutUint32(payload, handle)
+		if value {
+			payload[4] = 1
+		}
+	}
 
-	if err := control.WritePacket(conn, control.CmdSetTrigger, payload); err != nil {
+	if err := control.WritePacket(conn, cmd, payload); err != nil {
 		return err
 	}
 
@@ -2291,16 +3903,20 @@ func cmdTrigger(conn net.Conn, name string) error {
 
 	switch rply {
 	case control.RplyACK:
-		info("Service '%s' triggered.\n", name)
+		return nil
 	case control.RplyNAK:
+		if event != "" {
+			return fmt.Errorf("service '%s' is not a triggered service or has no trigger '%s'", name, event)
+		}
 		return fmt.Errorf("service '%s' is not a triggered service", name)
 	default:
 		return fmt.Errorf("unexpected reply: %d", rply)
 	}
-	return nil
 }
 
-func cmdUntrigger(conn net.Conn, name string) error {
+// cmdSetRestartEnabled sends the runtime auto-restart override for a
+// service ("no-restart" / "enable-restart").
+func cmdSetRestartEnabled(conn net.Conn, name string, enabled bool) error {
 	handle, err := loadServiceHandle(conn, name)
 	if err != nil {
 		return err
@@ -2308,9 +3924,11 @@ func cmdUntrigger(conn net.Conn, name string) error {
 
 	payload := make([]byte, 5)
 	binary.LittleEndian.PutUint32(payload, handle)
-	payload[4] = 0 // trigger = false
+	if enabled {
+		payload[4] = 1
+	}
 
-	if err := control.WritePacket(conn, control.CmdSetTrigger, payload); err != nil {
+	if err := control.WritePacket(conn, control.CmdSetRestartEnabled, payload); err != nil {
 		return err
 	}
 
@@ -2318,14 +3936,49 @@ func cmdUntrigger(conn net.Conn, name string) error {
 	if err != nil {
 		return err
 	}
+	if rply != control.RplyACK {
+		return fmt.Errorf("unexpected reply: %d", rply)
+	}
+	if enabled {
+		info("Auto-restart of '%s' enabled until reload.\n", name)
+	} else {
+		info("Auto-restart of '%s' disabled until reload.\n", name)
+	}
+	return nil
+}
 
-	switch rply {
-	case control.RplyACK:
+// cmdSetPriority changes the propagation queue priority of a service.
+func cmdSetPriority(conn net.Conn, name, value string) error {
+	priority, err := strconv.ParseInt(value, 10, 32)
+	if err != nil {
+		return fmt.Errorf("invalid priority %q", value)
+	}
+	handle, err := loadServiceHandle(conn, name)
+	if err != nil {
+		return err
+	}
+	if err := control.WritePacket(conn, control.CmdSetPriority, control.EncodeSetPriority(handle, int32(priority))); err != nil {
+		return err
+	}
+	rply, _, err := readReply(conn)
+	if err != nil {
+		return err
+	}
+	if rply != control.RplyACK {
+		return fmt.Errorf("failed to set priority of '%s' (reply %d)", name, rply)
+	}
+	info("Priority of '%s' set to %d.\n", name, priority)
+	return nil
+}
+
+func cmdUntrigger(conn net.Conn, name, event string) error {
+	if err := sendTrigger(conn, name, event, false); err != nil {
+		return err
+	}
+	if event != "" {
+		info("Service '%s' trigger '%s' cleared.\n", name, event)
+	} else {
 		info("Service '%s' untriggered.\n", name)
-	case control.RplyNAK:
-		return fmt.Errorf("service '%s' is not a triggered service", name)
-	default:
-		return fmt.Errorf("unexpected reply: %d", rply)
 	}
 	return nil
 }
@@ -2460,12 +4113,111 @@ func cmdOnce(conn net.Conn, svcName string) error {
 		return err
 	}
 	if rply != control.RplyACK {
-		return fmt.Errorf("failed to start service '%s' once", svcName)
+		return fmt.Errorf("failed to start service '%s' once", svcName)
+	}
+	info("Service '%s' started once (no restart).\n", svcName)
+	return nil
+}
+
+// cmdKill sends SIGKILL to a service and keeps it stopped: it is no
+// longer wanted and does not auto-restart until started again.
+func cmdKill(conn net.Conn, svcName string) error {
+	handle, err := loadServiceHandle(conn, svcName)
+	if err != nil {
+		return err
+	}
+	if err := control.WritePacket(conn, control.CmdKillService, control.EncodeHandle(handle)); err != nil {
+		return err
+	}
+	rply, _, err := readReply(conn)
+	if err != nil {
+		return err
+	}
+	switch rply {
+	case control.RplyACK:
+		info("Service '%s' killed.\n", svcName)
+	case control.RplyAlreadySS:
+		info("Service '%s' is already stopped.\n", svcName)
+	case control.RplyPinnedStarted:
+		return fmt.Errorf("service '%s' is pinned started; unpin it first", svcName)
+	default:
+		return fmt.Errorf("failed to kill service '%s' (reply %d)", svcName, rply)
+	}
+	return nil
+}
+
+// cmdAnnotate attaches an operator note to a service. The daemon stamps
+// it with the time and the calling user.
+func cmdAnnotate(conn net.Conn, svcName, msg string) error {
+	if len(msg) > control.MaxAnnotationLen {
+		return fmt.Errorf("annotation too long (max %d bytes)", control.MaxAnnotationLen)
+	}
+	handle, err := loadServiceHandle(conn, svcName)
+	if err != nil {
+		return err
+	}
+	if err := control.WritePacket(conn, control.CmdAnnotate, control.EncodeAnnotate(handle, msg)); err != nil {
+		return err
+	}
+	rply, _, err := readReply(conn)
+	if err != nil {
+		return err
+	}
+	if rply != control.RplyACK {
+		return fmt.Errorf("failed to annotate service '%s' (reply %d)", svcName, rply)
+	}
+	info("Annotation added to '%s'.\n", svcName)
+	return nil
+}
+
+func cmdSetStopReason(conn net.Conn, svcName, msg string) error {
+	if len(msg) > control.MaxAnnotationLen {
+		return fmt.Errorf("stop reason too long (max %d bytes)", control.MaxAnnotationLen)
+	}
+	handle, err := loadServiceHandle(conn, svcName)
+	if err != nil {
+		return err
+	}
+	payload := control.EncodeSetStopReason(handle, service.ReasonCustom, msg)
+	if err := control.WritePacket(conn, control.CmdSetServiceStopReason, payload); err != nil {
+		return err
+	}
+	rply, _, err := readReply(conn)
+	if err != nil {
+		return err
+	}
+	if rply != control.RplyACK {
+		return fmt.Errorf("failed to set stop reason of '%s' (reply %d)", svcName, rply)
 	}
-	info("Service '%s' started once (no restart).\n", svcName)
+	info("Stop reason of '%s' set.\n", svcName)
 	return nil
 }
 
+// fetchAnnotations queries the operator notes on a service handle.
+func fetchAnnotations(conn net.Conn, handle uint32) ([]service.Annotation, error) {
+	if err := control.WritePacket(conn, control.CmdListAnnotations, control.EncodeHandle(handle)); err != nil {
+		return nil, err
+	}
+	rply, payload, err := readReply(conn)
+	if err != nil {
+		return nil, err
+	}
+	if rply != control.RplyAnnotations {
+		return nil, fmt.Errorf("unexpected reply: %d", rply)
+	}
+	return control.DecodeAnnotations(payload)
+}
+
+// formatAnnotation renders one note, e.g.
+// "2024-01-15 10:00:00 [alice] Starting planned maintenance window".
+func formatAnnotation(a service.Annotation) string {
+	s := a.Time.Format("2006-01-02 15:04:05")
+	if a.User != "" {
+		s += " [" + a.User + "]"
+	}
+	return s + " " + a.Message
+}
+
 func cmdRunAction(conn net.Conn, svcName, actionName string) error {
 	handle, err := loadServiceHandle(conn, svcName)
 	if err != nil {
@@ -2480,7 +4232,7 @@ func cmdRunAction(conn net.Conn, svcName, actionName string) error {
 	if err := control.WritePacket(conn, control.CmdRunAction, payload); err != nil {
 		return err
 	}
-	rply, data, err := control.ReadPacket(conn)
+	rply, data, err := readPacket(conn)
 	if err != nil {
 		return err
 	}
@@ -2507,7 +4259,7 @@ func cmdListActions(conn net.Conn, svcName string) error {
 	if err := control.WritePacket(conn, control.CmdListActions, payload); err != nil {
 		return err
 	}
-	rply, data, err := control.ReadPacket(conn)
+	rply, data, err := readPacket(conn)
 	if err != nil {
 		return err
 	}
@@ -2524,12 +4276,12 @@ func cmdListActions(conn net.Conn, svcName string) error {
 	}
 }
 
-func cmdBootTime(conn net.Conn) error {
+func cmdBootTime(conn net.Conn, showEvents bool) error {
 	if err := control.WritePacket(conn, control.CmdBootTime, nil); err != nil {
 		return err
 	}
 
-	rply, payload, err := control.ReadPacket(conn)
+	rply, payload, err := readPacket(conn)
 	if err != nil {
 		return err
 	}
@@ -2589,9 +4341,274 @@ func cmdBootTime(conn net.Conn) error {
 		}
 	}
 
+	if showEvents {
+		fmt.Println()
+		if info.Events == nil {
+			fmt.Println("Boot timeline not available from this daemon.")
+		} else {
+			fmt.Println("Boot timeline:")
+			for _, line := range formatBootTimeline(info.Events) {
+				fmt.Println("  " + line)
+			}
+		}
+	}
+
+	return nil
+}
+
+// cmdCriticalChain prints the chain of services that held up root (by
+// default the boot service) reaching STARTED, as offsets from the
+// start of userspace.
+func cmdCriticalChain(conn net.Conn, root string) error {
+	if err := control.WritePacket(conn, control.CmdBootTime, nil); err != nil {
+		return err
+	}
+	rply, payload, err := readPacket(conn)
+	if err != nil {
+		return err
+	}
+	if rply != control.RplyBootTime {
+		return fmt.Errorf("unexpected reply: %d", rply)
+	}
+	info, err := control.DecodeBootTime(payload)
+	if err != nil {
+		return err
+	}
+	_, edges, err := fetchGraph(conn)
+	if err != nil {
+		return err
+	}
+	if root == "" {
+		root = info.BootSvcName
+	}
+
+	entries := make(map[string]control.BootTimeEntry, len(info.Services))
+	for _, e := range info.Services {
+		entries[e.Name] = e
+	}
+	deps := make(map[string][]string)
+	for _, e := range edges {
+		deps[e.From] = append(deps[e.From], e.To)
+	}
+	chain := service.CriticalChain(root,
+		func(name string) (time.Time, time.Time, bool) {
+			e, ok := entries[name]
+			if !ok || e.StartedNs == 0 {
+				return time.Time{}, time.Time{}, false
+			}
+			return time.Unix(0, e.StartRequestNs), time.Unix(0, e.StartedNs), true
+		},
+		func(name string) []string { return deps[name] })
+	if chain == nil {
+		if e, ok := entries[root]; ok && e.StartRequestNs == 0 && e.StartupNs > 0 {
+			return fmt.Errorf("critical-chain: daemon does not report start times")
+		}
+		return fmt.Errorf("critical-chain: service '%s' has not started", root)
+	}
+	writeCriticalChain(os.Stdout, chain, time.Unix(0, info.BootStartNs))
+	return nil
+}
+
+// writeCriticalChain prints chain as an indented tree, systemd style:
+// "@" gives when each service reached STARTED (relative to origin) and
+// "+" how long its own start took.
+func writeCriticalChain(w io.Writer, chain []service.ChainLink, origin time.Time) {
+	fmt.Fprintln(w, "The time when a service reached STARTED is printed after the \"@\" character.")
+	fmt.Fprintln(w, "The time the service took to start is printed after the \"+\" character.")
+	fmt.Fprintln(w)
+	for i, l := range chain {
+		prefix := ""
+		if i > 0 {
+			prefix = strings.Repeat("  ", i-1) + "└─"
+		}
+		line := fmt.Sprintf("%s%s @%s", prefix, l.Name, formatDuration(max(l.Started.Sub(origin), 0)))
+		if l.Took > 0 {
+			line += " +" + formatDuration(l.Took)
+		}
+		fmt.Fprintln(w, line)
+	}
+}
+
+// formatBootTimeline renders boot events as offsets from the first one
+// (the kernel start when known), e.g. "+1.234s  started  sshd".
+func formatBootTimeline(events []service.BootEvent) []string {
+	if len(events) == 0 {
+		return nil
+	}
+	origin := events[0].Time
+	lines := make([]string, 0, len(events))
+	for _, ev := range events {
+		line := fmt.Sprintf("+%-9s %-8s", formatDuration(ev.Time.Sub(origin)), ev.Type)
+		if ev.Service != "" {
+			line += " " + ev.Service
+		}
+		lines = append(lines, strings.TrimRight(line, " "))
+	}
+	return lines
+}
+
+// cmdEvents prints the daemon-wide service event timeline, optionally
+// limited to the last `since` and to the named services and, with
+// follow, keeps printing live events until the connection closes.
+func cmdEvents(conn net.Conn, since time.Duration, follow bool, names []string) error {
+	// Subscribe before fetching the history so nothing falls in the
+	// gap; pushes that race the reply are de-duplicated by timestamp.
+	// The daemon filters live events; the history is filtered here.
+	if follow {
+		if err := control.WritePacket(conn, control.CmdSubscribeEvents, control.EncodeSubscribeEvents(names)); err != nil {
+			return err
+		}
+		rply, _, err := readReply(conn)
+		if err != nil {
+			return err
+		}
+		if rply != control.RplyACK {
+			return fmt.Errorf("unexpected reply: %d", rply)
+		}
+	}
+
+	req := make([]byte, 2) // max 0: everything the daemon kept
+	if err := control.WritePacket(conn, control.CmdListRecentEvents, req); err != nil {
+		return err
+	}
+	var pending []service.GlobalEvent
+	var history []service.GlobalEvent
+	for {
+		rply, payload, err := readPacket(conn)
+		if err != nil {
+			return err
+		}
+		if rply == control.InfoGlobalEvent {
+			if ev, err := control.DecodeGlobalEvent(payload); err == nil {
+				pending = append(pending, ev)
+			}
+			continue
+		}
+		if rply == control.InfoServiceEvent || rply == control.InfoServiceEvent5 ||
+			rply == control.InfoEnvEvent {
+			continue
+		}
+		if rply != control.RplyRecentEvents {
+			return fmt.Errorf("unexpected reply: %d", rply)
+		}
+		if history, err = control.DecodeRecentEvents(payload); err != nil {
+			return err
+		}
+		break
+	}
+
+	var cutoff time.Time
+	if since > 0 {
+		cutoff = time.Now().Add(-since)
+	}
+	wanted := func(ev service.GlobalEvent) bool {
+		if len(names) == 0 {
+			return true
+		}
+		for _, n := range names {
+			if ev.Service == n {
+				return true
+			}
+		}
+		return false
+	}
+	var last time.Time
+	for _, ev := range history {
+		if !ev.Time.Before(cutoff) && wanted(ev) {
+			fmt.Println(formatGlobalEvent(ev))
+		}
+		last = ev.Time
+	}
+	if !follow {
+		return nil
+	}
+	for _, ev := range pending {
+		if ev.Time.After(last) {
+			fmt.Println(formatGlobalEvent(ev))
+		}
+	}
+	return followEvents(os.Stdout, conn)
+}
+
+// cmdMonitor subscribes to service events, for all services or only the
+// named ones, and prints each to w as it happens, without the history
+// `events` starts with. It returns nil once the daemon closes the
+// connection.
+func cmdMonitor(w io.Writer, conn net.Conn, names []string) error {
+	if err := control.WritePacket(conn, control.CmdSubscribeEvents, control.EncodeSubscribeEvents(names)); err != nil {
+		return err
+	}
+	rply, _, err := readReply(conn)
+	if err != nil {
+		return err
+	}
+	if rply != control.RplyACK {
+		return fmt.Errorf("unexpected reply: %d", rply)
+	}
+	if err := followEvents(w, conn); err != io.EOF {
+		return err
+	}
 	return nil
 }
 
+// followEvents prints the InfoGlobalEvent pushes of an event subscription
+// to w until reading from conn fails.
+func followEvents(w io.Writer, conn net.Conn) error {
+	for {
+		rply, payload, err := readPacket(conn)
+		if err != nil {
+			return err
+		}
+		if rply != control.InfoGlobalEvent {
+			continue
+		}
+		ev, err := control.DecodeGlobalEvent(payload)
+		if err != nil {
+			continue
+		}
+		fmt.Fprintln(w, formatGlobalEvent(ev))
+	}
+}
+
+// formatGlobalEvent renders one timeline line, e.g.
+// "2026-01-02 15:04:05.000  STOPPED         sshd  (exit code 1)".
+func formatGlobalEvent(ev service.GlobalEvent) string {
+	s := fmt.Sprintf("%s  %-15s %s", ev.Time.Format("2006-01-02 15:04:05.000"), ev.Event, ev.Service)
+	if ev.Details != "" {
+		s += "  (" + ev.Details + ")"
+	}
+	return s
+}
+
+// parseEventsArgs parses `events [--since DURATION] [--follow|-f]
+// [service...]`.
+func parseEventsArgs(args []string) (since time.Duration, follow bool, names []string, err error) {
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		switch {
+		case arg == "--follow" || arg == "-f":
+			follow = true
+		case arg == "--since":
+			if i+1 >= len(args) {
+				return 0, false, nil, fmt.Errorf("--since requires a duration")
+			}
+			i++
+			if since, err = time.ParseDuration(args[i]); err != nil {
+				return 0, false, nil, fmt.Errorf("--since: %v", err)
+			}
+		case strings.HasPrefix(arg, "--since="):
+			if since, err = time.ParseDuration(strings.TrimPrefix(arg, "--since=")); err != nil {
+				return 0, false, nil, fmt.Errorf("--since: %v", err)
+			}
+		case strings.HasPrefix(arg, "-"):
+			return 0, false, nil, fmt.Errorf("unknown argument %q", arg)
+		default:
+			names = append(names, arg)
+		}
+	}
+	return since, follow, names, nil
+}
+
 func formatDuration(d time.Duration) string {
 	if d < time.Millisecond {
 		return strconv.FormatInt(d.Microseconds(), 10) + "us"
@@ -2675,16 +4692,17 @@ func cmdReloadAll(conn net.Conn) error {
 
 	switch rply {
 	case control.RplyReloadAllResult:
-		if len(payload) < 4 {
-			return fmt.Errorf("reload-all: short reply (%d bytes)", len(payload))
+		ok, failed, results, err := control.DecodeReloadAll(payload)
+		if err != nil {
+			return err
+		}
+		if len(results) > 0 {
+			writeReloadResults(os.Stdout, results)
 		}
-		ok := binary.LittleEndian.Uint16(payload[0:2])
-		failed := binary.LittleEndian.Uint16(payload[2:4])
+		info("%s\n", reloadSummary(ok, failed, results))
 		if failed > 0 {
-			info("Reloaded %d service(s); %d failed (see daemon log).\n", ok, failed)
 			return fmt.Errorf("reload-all: %d service(s) failed", failed)
 		}
-		info("Reloaded %d service(s).\n", ok)
 		return nil
 	case control.RplyNAK:
 		return fmt.Errorf("reload-all: daemon has no loader configured")
@@ -2693,6 +4711,49 @@ func cmdReloadAll(conn net.Conn) error {
 	}
 }
 
+// writeReloadResults prints the services reload-all did something
+// with, one row each. Unchanged services are not listed.
+func writeReloadResults(w io.Writer, results []service.ReloadResult) {
+	fmt.Fprintf(w, "%-24s %-15s %s\n", "SERVICE", "RESULT", "DETAIL")
+	for _, r := range results {
+		detail := r.Detail
+		if r.Outcome == service.ReloadRestartNeeded {
+			detail = "restart to apply process settings"
+		}
+		fmt.Fprintf(w, "%-24s %-15s %s\n", r.Name, r.Outcome, detail)
+	}
+}
+
+// reloadSummary returns the one-line totals for reload-all.
+func reloadSummary(ok, failed int, results []service.ReloadResult) string {
+	var changed, restart, skipped int
+	for _, r := range results {
+		switch r.Outcome {
+		case service.ReloadApplied:
+			changed++
+		case service.ReloadRestartNeeded:
+			changed++
+			restart++
+		case service.ReloadSkipped:
+			skipped++
+		}
+	}
+	msg := fmt.Sprintf("Reloaded %d service(s)", ok)
+	if changed > 0 {
+		msg += fmt.Sprintf(", %d changed", changed)
+	}
+	if restart > 0 {
+		msg += fmt.Sprintf(", %d need a restart", restart)
+	}
+	if skipped > 0 {
+		msg += fmt.Sprintf(", %d skipped", skipped)
+	}
+	if failed > 0 {
+		msg += fmt.Sprintf("; %d failed", failed)
+	}
+	return msg + "."
+}
+
 // cmdActivateProfile swaps the daemon's active profile. Reports the
 // stopped/started/kept service lists so the operator can see the
 // diff and reconcile any surprises.
@@ -3053,7 +5114,7 @@ func cmdSetEnvGlobal(conn net.Conn, kvPair string) error {
 		return err
 	}
 
-	rply, _, err := control.ReadPacket(conn)
+	rply, _, err := readPacket(conn)
 	if err != nil {
 		return err
 	}
@@ -3070,7 +5131,7 @@ func cmdUnsetEnvGlobal(conn net.Conn, key string) error {
 		return err
 	}
 
-	rply, _, err := control.ReadPacket(conn)
+	rply, _, err := readPacket(conn)
 	if err != nil {
 		return err
 	}
@@ -3086,7 +5147,7 @@ func cmdGetAllEnvGlobal(conn net.Conn) error {
 		return err
 	}
 
-	rply, payload, err := control.ReadPacket(conn)
+	rply, payload, err := readPacket(conn)
 	if err != nil {
 		return err
 	}
@@ -3287,6 +5348,50 @@ func cmdUnpin(conn net.Conn, name string) error {
 	return nil
 }
 
+// cmdPin pins a service started, or stopped if stop is set, bringing
+// it to that state. Unlike start/stop --pin it also pins a service that
+// is already there.
+func cmdPin(conn net.Conn, name string, stop bool) error {
+	handle, err := loadServiceHandle(conn, name)
+	if err != nil {
+		return err
+	}
+
+	pin, what := control.PinStarted, "started"
+	if stop {
+		pin, what = control.PinStopped, "stopped"
+	}
+	if err := control.WritePacket(conn, control.CmdPinService, control.EncodePinService(handle, pin)); err != nil {
+		return err
+	}
+
+	rply, _, err := readReply(conn)
+	if err != nil {
+		return err
+	}
+
+	switch rply {
+	case control.RplyACK:
+		info("Service '%s' pinned %s.\n", name, what)
+	case control.RplyPinnedStarted:
+		return fmt.Errorf("service '%s' is pinned started; unpin it first", name)
+	case control.RplyPinnedStopped:
+		return fmt.Errorf("service '%s' is pinned stopped; unpin it first", name)
+	case control.RplyManualRefused:
+		if stop {
+			return fmt.Errorf("service '%s' refuses manual stop (refuse-manual-stop = yes)", name)
+		}
+		return fmt.Errorf("service '%s' refuses manual start (refuse-manual-start = yes)", name)
+	case control.RplyShuttingDown:
+		return fmt.Errorf("system is shutting down")
+	case control.RplyBadReq:
+		return fmt.Errorf("daemon does not support pin-start/pin-stop")
+	default:
+		return fmt.Errorf("unexpected reply: %d", rply)
+	}
+	return nil
+}
+
 func cmdDisable(conn net.Conn, name string, from string) error {
 	handle, err := loadServiceHandle(conn, name)
 	if err != nil {
@@ -3352,37 +5457,198 @@ func cmdQueryServiceName(conn net.Conn, svcName string) error {
 	return nil
 }
 
-func cmdQueryServiceDscDir(conn net.Conn) error {
-	if err := control.WritePacket(conn, control.CmdQueryServiceDscDir, nil); err != nil {
-		return err
+// cmdSetServiceDirs changes the daemon's service directories. Relative
+// paths are taken against slinitctl's working directory, since the
+// daemon's is unrelated.
+func cmdSetServiceDirs(conn net.Conn, op uint8, dirs []string) error {
+	abs := make([]string, len(dirs))
+	for i, d := range dirs {
+		a, err := filepath.Abs(d)
+		if err != nil {
+			return err
+		}
+		abs[i] = a
+	}
+	if err := control.WritePacket(conn, control.CmdSetServiceDirs, control.EncodeSetServiceDirs(op, abs)); err != nil {
+		return err
+	}
+	rply, payload, err := readReply(conn)
+	if err != nil {
+		return err
+	}
+	switch rply {
+	case control.RplyACK:
+		info("Service directories updated.\n")
+		return nil
+	case control.RplyNAK:
+		return fmt.Errorf("cannot change service directories: %s", string(payload))
+	default:
+		return fmt.Errorf("unexpected reply: %d", rply)
+	}
+}
+
+func cmdQueryServiceDscDir(conn net.Conn) error {
+	if err := control.WritePacket(conn, control.CmdQueryServiceDscDir, nil); err != nil {
+		return err
+	}
+
+	rply, payload, err := readPacket(conn)
+	if err != nil {
+		return err
+	}
+	if rply != control.RplyServiceDscDir {
+		return fmt.Errorf("service-dirs failed: reply %d", rply)
+	}
+
+	if len(payload) < 2 {
+		return fmt.Errorf("response too short")
+	}
+	count := int(binary.LittleEndian.Uint16(payload))
+	off := 2
+	for i := 0; i < count; i++ {
+		if len(payload) < off+2 {
+			return fmt.Errorf("truncated response at dir %d", i)
+		}
+		dirLen := int(binary.LittleEndian.Uint16(payload[off:]))
+		off += 2
+		if len(payload) < off+dirLen {
+			return fmt.Errorf("truncated response at dir %d", i)
+		}
+		fmt.Println(string(payload[off : off+dirLen]))
+		off += dirLen
+	}
+	return nil
+}
+
+// cmdDeps prints a service's dependencies with their live state, e.g.
+// "  → network (regular, holding acquisition, state: STARTED)", then its
+// dependents.
+func cmdDeps(conn net.Conn, name string) error {
+	handle, err := loadServiceHandle(conn, name)
+	if err != nil {
+		return err
+	}
+	deps, dependents, err := fetchDependencyInfo(conn, handle)
+	if err != nil {
+		return err
+	}
+
+	if len(deps) == 0 {
+		fmt.Printf("Service '%s' has no dependencies.\n", name)
+	} else {
+		fmt.Printf("Dependencies of '%s':\n", name)
+		for _, d := range deps {
+			attrs := []string{d.DepType.String()}
+			if d.HoldingAcq {
+				attrs = append(attrs, "holding acquisition")
+			}
+			if d.WaitingOn {
+				attrs = append(attrs, "waiting")
+			}
+			attrs = append(attrs, "state: "+formatState(d.State))
+			fmt.Printf("  → %s (%s)\n", d.Name, strings.Join(attrs, ", "))
+		}
+	}
+	if len(dependents) > 0 {
+		fmt.Printf("Dependents of '%s':\n", name)
+		for _, d := range dependents {
+			fmt.Printf("  ← %s (%s)\n", d.Name, d.DepType)
+		}
+	}
+	return nil
+}
+
+// fetchDependencyInfo queries the dependency edges of a service handle
+// in both directions.
+func fetchDependencyInfo(conn net.Conn, handle uint32) (deps, dependents []service.DependencyInfo, err error) {
+	if err := control.WritePacket(conn, control.CmdGetDependencyInfo, control.EncodeHandle(handle)); err != nil {
+		return nil, nil, err
 	}
-
-	rply, payload, err := control.ReadPacket(conn)
+	rply, payload, err := readReply(conn)
 	if err != nil {
-		return err
+		return nil, nil, err
 	}
-	if rply != control.RplyServiceDscDir {
-		return fmt.Errorf("service-dirs failed: reply %d", rply)
+	if rply != control.RplyDependencyInfo {
+		return nil, nil, fmt.Errorf("unexpected reply: %d", rply)
 	}
+	return control.DecodeDependencyInfo(payload)
+}
 
-	if len(payload) < 2 {
-		return fmt.Errorf("response too short")
+// cmdExportDeps writes one row per dependency edge of every loaded
+// service, with the service's state, PID and restart count, as CSV, TSV
+// or JSON for spreadsheets and ad-hoc analysis.
+func cmdExportDeps(conn net.Conn, format string) error {
+	if err := control.WritePacket(conn, control.CmdListServices, nil); err != nil {
+		return err
 	}
-	count := int(binary.LittleEndian.Uint16(payload))
-	off := 2
-	for i := 0; i < count; i++ {
-		if len(payload) < off+2 {
-			return fmt.Errorf("truncated response at dir %d", i)
+	var entries []control.SvcInfoEntry
+	for {
+		rply, payload, err := readPacket(conn)
+		if err != nil {
+			return err
 		}
-		dirLen := int(binary.LittleEndian.Uint16(payload[off:]))
-		off += 2
-		if len(payload) < off+dirLen {
-			return fmt.Errorf("truncated response at dir %d", i)
+		if rply == control.RplyListDone {
+			break
 		}
-		fmt.Println(string(payload[off : off+dirLen]))
-		off += dirLen
+		if rply != control.RplySvcInfo {
+			return fmt.Errorf("unexpected reply: %d", rply)
+		}
+		entry, _, err := control.DecodeSvcInfo(payload)
+		if err != nil {
+			return err
+		}
+		entries = append(entries, entry)
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name < entries[j].Name })
+
+	var rows []service.DepExportRow
+	for _, e := range entries {
+		handle, err := loadServiceHandle(conn, e.Name)
+		if err != nil {
+			return err
+		}
+		row := service.DepExportRow{Service: e.Name, Type: e.SvcType, State: e.State}
+
+		if err := control.WritePacket(conn, control.CmdServiceStatus, control.EncodeHandle(handle)); err != nil {
+			return err
+		}
+		rply, payload, err := readReply(conn)
+		if err != nil {
+			return err
+		}
+		if rply != control.RplyServiceStatus {
+			return fmt.Errorf("unexpected reply: %d", rply)
+		}
+		status, err := control.DecodeServiceStatus(payload)
+		if err != nil {
+			return err
+		}
+		if status.Flags&control.StatusFlagHasPID != 0 {
+			row.PID = int(status.PID)
+		}
+		row.Restarts = status.FailureStats.TotalRestarts
+
+		deps, _, err := fetchDependencyInfo(conn, handle)
+		if err != nil {
+			return err
+		}
+		if len(deps) == 0 {
+			rows = append(rows, row)
+		}
+		for i := range deps {
+			row.Dep = &deps[i]
+			rows = append(rows, row)
+		}
+	}
+
+	switch format {
+	case "json":
+		return service.WriteDepsJSON(os.Stdout, rows)
+	case "tsv":
+		return service.WriteDepsCSV(os.Stdout, rows, '\t')
+	default:
+		return service.WriteDepsCSV(os.Stdout, rows, ',')
 	}
-	return nil
 }
 
 func cmdDependents(conn net.Conn, name string) error {
@@ -3395,7 +5661,7 @@ func cmdDependents(conn net.Conn, name string) error {
 		return err
 	}
 
-	rply, payload, err := control.ReadPacket(conn)
+	rply, payload, err := readPacket(conn)
 	if err != nil {
 		return err
 	}
@@ -3427,7 +5693,7 @@ func cmdDependents(conn net.Conn, name string) error {
 			fmt.Printf("  handle=%d (name query failed)\n", depHandle)
 			continue
 		}
-		rply2, payload2, err := control.ReadPacket(conn)
+		rply2, payload2, err := readPacket(conn)
 		if err != nil || rply2 != control.RplyServiceName {
 			fmt.Printf("  handle=%d\n", depHandle)
 			continue
@@ -3438,57 +5704,139 @@ func cmdDependents(conn net.Conn, name string) error {
 	return nil
 }
 
-// cmdGraph queries all services and their forward dependencies, then outputs
-// a DOT-format directed graph suitable for Graphviz visualization.
-//
-// Usage: slinitctl graph | dot -Tpng -o services.png
+// cmdGraph fetches the dependency graph and prints it as DOT for
+// Graphviz, a Mermaid flowchart, JSON, or (with tiers) the start
+// tiers. A non-empty root limits the output to root and everything it
+// depends on.
 //
-//	slinitctl graph | dot -Tsvg -o services.svg
-func cmdGraph(conn net.Conn) error {
+// Usage: slinitctl graph --dot | dot -Tsvg -o services.svg
+func cmdGraph(conn net.Conn, root string, tiers bool, format string) error {
+	nodes, edges, err := fetchGraph(conn)
+	if err != nil {
+		return err
+	}
+	if root != "" {
+		if nodes, edges = graphFrom(nodes, edges, root); len(nodes) == 0 {
+			return fmt.Errorf("service '%s' is not loaded", root)
+		}
+	}
+
+	if tiers {
+		layers, err := graphTiers(nodes, edges)
+		if err != nil {
+			return err
+		}
+		printGraphTiers(os.Stdout, layers)
+		return nil
+	}
+
+	switch format {
+	case "mermaid":
+		return service.WriteMermaid(os.Stdout, nodes, edges)
+	case "json":
+		return service.WriteGraphJSON(os.Stdout, nodes, edges)
+	default:
+		writeGraphDOT(os.Stdout, nodes, edges)
+		return nil
+	}
+}
+
+// graphTiers groups the nodes into start tiers. A before edge is read in
+// reverse, since "From before To" makes To wait for From.
+func graphTiers(nodes []service.GraphNode, edges []service.GraphEdge) ([][]string, error) {
+	names := make([]string, len(nodes))
+	deps := make(map[string][]string)
+	for i, n := range nodes {
+		names[i] = n.Name
+	}
+	for _, edge := range edges {
+		if edge.Type == service.DepBefore {
+			deps[edge.To] = append(deps[edge.To], edge.From)
+		} else {
+			deps[edge.From] = append(deps[edge.From], edge.To)
+		}
+	}
+	return service.TierNames(names, func(n string) []string { return deps[n] })
+}
+
+// fetchGraph reads the dependency graph with CmdDumpGraph. A daemon
+// that predates it answers RplyBadReq; the graph is then assembled from
+// the service list and per-service dependency queries.
+func fetchGraph(conn net.Conn) ([]service.GraphNode, []service.GraphEdge, error) {
+	if err := control.WritePacket(conn, control.CmdDumpGraph, nil); err != nil {
+		return nil, nil, err
+	}
+	var nodes []service.GraphNode
+	var edges []service.GraphEdge
+	for {
+		rply, payload, err := readPacket(conn)
+		if err != nil {
+			return nil, nil, err
+		}
+		switch rply {
+		case control.RplyListDone:
+			return nodes, edges, nil
+		case control.RplyBadReq:
+			if len(nodes) == 0 {
+				return fetchGraphLegacy(conn)
+			}
+			return nil, nil, fmt.Errorf("unexpected reply: %d", rply)
+		case control.RplyGraphNode:
+			node, nodeEdges, err := control.DecodeGraphNode(payload)
+			if err != nil {
+				return nil, nil, err
+			}
+			nodes = append(nodes, node)
+			edges = append(edges, nodeEdges...)
+		default:
+			return nil, nil, fmt.Errorf("unexpected reply: %d", rply)
+		}
+	}
+}
+
+// fetchGraphLegacy builds the graph one query at a time: the service
+// list, a handle per service, then each service's dependencies.
+func fetchGraphLegacy(conn net.Conn) ([]service.GraphNode, []service.GraphEdge, error) {
 	// Phase 1: list all services (collect names + handles)
 	type svcEntry struct {
-		name   string
-		state  service.ServiceState
-		stype  service.ServiceType
+		node   service.GraphNode
 		handle uint32
 	}
 
 	if err := control.WritePacket(conn, control.CmdListServices, nil); err != nil {
-		return err
+		return nil, nil, err
 	}
 
 	var entries []svcEntry
 	for {
-		rply, payload, err := control.ReadPacket(conn)
+		rply, payload, err := readPacket(conn)
 		if err != nil {
-			return err
+			return nil, nil, err
 		}
 		if rply == control.RplyListDone {
 			break
 		}
 		if rply != control.RplySvcInfo {
-			return fmt.Errorf("unexpected reply: %d", rply)
+			return nil, nil, fmt.Errorf("unexpected reply: %d", rply)
 		}
 		entry, _, err := control.DecodeSvcInfo(payload)
 		if err != nil {
-			return err
+			return nil, nil, err
 		}
 		entries = append(entries, svcEntry{
-			name:  entry.Name,
-			state: entry.State,
-			stype: entry.SvcType,
+			node: service.GraphNode{Name: entry.Name, Type: entry.SvcType, State: entry.State},
 		})
 	}
 
 	// Phase 2: get handle for each service
 	for i := range entries {
-		namePayload := control.EncodeServiceName(entries[i].name)
+		namePayload := control.EncodeServiceName(entries[i].node.Name)
 		if err := control.WritePacket(conn, control.CmdFindService, namePayload); err != nil {
-			return err
+			return nil, nil, err
 		}
-		rply, payload, err := control.ReadPacket(conn)
+		rply, payload, err := readPacket(conn)
 		if err != nil {
-			return err
+			return nil, nil, err
 		}
 		if rply != control.RplyServiceRecord || len(payload) < 5 {
 			continue
@@ -3497,28 +5845,22 @@ func cmdGraph(conn net.Conn) error {
 	}
 
 	// Phase 3: query forward dependencies for each service
-	type depEdge struct {
-		from    string
-		to      string
-		depType service.DependencyType
-	}
-
 	handleNames := make(map[uint32]string)
 	for _, e := range entries {
-		handleNames[e.handle] = e.name
+		handleNames[e.handle] = e.node.Name
 	}
 
-	var edges []depEdge
+	var edges []service.GraphEdge
 	for _, e := range entries {
 		if e.handle == 0 {
 			continue
 		}
 		if err := control.WritePacket(conn, control.CmdQueryDependencies, control.EncodeHandle(e.handle)); err != nil {
-			return err
+			return nil, nil, err
 		}
-		rply, payload, err := control.ReadPacket(conn)
+		rply, payload, err := readPacket(conn)
 		if err != nil {
-			return err
+			return nil, nil, err
 		}
 		if rply != control.RplyDependencies || len(payload) < 4 {
 			continue
@@ -3540,7 +5882,7 @@ func cmdGraph(conn net.Conn) error {
 				if err := control.WritePacket(conn, control.CmdQueryServiceName, control.EncodeHandle(depHandle)); err != nil {
 					continue
 				}
-				rply2, payload2, err := control.ReadPacket(conn)
+				rply2, payload2, err := readPacket(conn)
 				if err != nil || rply2 != control.RplyServiceName {
 					depName = fmt.Sprintf("handle_%d", depHandle)
 				} else {
@@ -3549,38 +5891,130 @@ func cmdGraph(conn net.Conn) error {
 				handleNames[depHandle] = depName
 			}
 
-			edges = append(edges, depEdge{from: e.name, to: depName, depType: dt})
+			edges = append(edges, service.GraphEdge{From: e.node.Name, To: depName, Type: dt})
 		}
 	}
 
-	// Phase 4: emit DOT output
-	fmt.Println("digraph services {")
-	fmt.Println("  rankdir=LR;")
-	fmt.Println("  node [fontname=\"sans-serif\" fontsize=10];")
-	fmt.Println("  edge [fontname=\"sans-serif\" fontsize=8];")
-	fmt.Println()
+	nodes := make([]service.GraphNode, len(entries))
+	for i, e := range entries {
+		nodes[i] = e.node
+	}
+	return nodes, edges, nil
+}
 
-	// Node styling by state and type
-	for _, e := range entries {
-		shape := graphNodeShape(e.stype)
-		color, fillcolor := graphNodeColor(e.state)
-		fmt.Printf("  %q [shape=%s style=filled fillcolor=%q color=%q];\n",
-			e.name, shape, fillcolor, color)
+// graphFrom returns the part of the graph reachable from root by
+// following dependencies: root, its transitive dependencies, and the
+// edges among them. Before/after edges only order services and do not
+// pull their targets in. Both are empty if root is not a node.
+func graphFrom(nodes []service.GraphNode, edges []service.GraphEdge, root string) ([]service.GraphNode, []service.GraphEdge) {
+	deps := make(map[string][]string)
+	for _, e := range edges {
+		if e.Type != service.DepBefore && e.Type != service.DepAfter {
+			deps[e.From] = append(deps[e.From], e.To)
+		}
+	}
+	found := false
+	for _, n := range nodes {
+		found = found || n.Name == root
+	}
+	if !found {
+		return nil, nil
+	}
+	keep := map[string]bool{root: true}
+	queue := []string{root}
+	for len(queue) > 0 {
+		name := queue[0]
+		queue = queue[1:]
+		for _, d := range deps[name] {
+			if !keep[d] {
+				keep[d] = true
+				queue = append(queue, d)
+			}
+		}
 	}
-	fmt.Println()
+	var subNodes []service.GraphNode
+	for _, n := range nodes {
+		if keep[n.Name] {
+			subNodes = append(subNodes, n)
+		}
+	}
+	var subEdges []service.GraphEdge
+	for _, e := range edges {
+		if keep[e.From] && keep[e.To] {
+			subEdges = append(subEdges, e)
+		}
+	}
+	return subNodes, subEdges
+}
+
+// writeGraphDOT renders the graph as a Graphviz digraph, nodes shaped
+// by type and filled by state, edges styled by dependency type.
+func writeGraphDOT(w io.Writer, nodes []service.GraphNode, edges []service.GraphEdge) {
+	fmt.Fprintln(w, "digraph services {")
+	fmt.Fprintln(w, "  rankdir=LR;")
+	fmt.Fprintln(w, "  node [fontname=\"sans-serif\" fontsize=10];")
+	fmt.Fprintln(w, "  edge [fontname=\"sans-serif\" fontsize=8];")
+	fmt.Fprintln(w)
+
+	for _, n := range nodes {
+		shape := graphNodeShape(n.Type)
+		color, fillcolor := graphNodeColor(n.State)
+		fmt.Fprintf(w, "  %q [shape=%s style=filled fillcolor=%q color=%q];\n",
+			n.Name, shape, fillcolor, color)
+	}
+	fmt.Fprintln(w)
 
-	// Edges with dep type styling
 	for _, edge := range edges {
-		style, color, label := graphEdgeStyle(edge.depType)
+		style, color, label := graphEdgeStyle(edge.Type)
 		attrs := fmt.Sprintf("style=%s color=%q", style, color)
 		if label != "" {
 			attrs += fmt.Sprintf(" label=%q", label)
 		}
-		fmt.Printf("  %q -> %q [%s];\n", edge.from, edge.to, attrs)
+		fmt.Fprintf(w, "  %q -> %q [%s];\n", edge.From, edge.To, attrs)
 	}
 
-	fmt.Println("}")
-	return nil
+	fmt.Fprintln(w, "}")
+}
+
+// printGraphTiers renders start tiers side by side, one column per tier:
+// every service in a column can start once the columns to its left have.
+func printGraphTiers(w io.Writer, tiers [][]string) {
+	if len(tiers) == 0 {
+		return
+	}
+	widths := make([]int, len(tiers))
+	rows := 0
+	for i, tier := range tiers {
+		widths[i] = len(fmt.Sprintf("TIER %d", i))
+		for _, name := range tier {
+			if len(name) > widths[i] {
+				widths[i] = len(name)
+			}
+		}
+		if len(tier) > rows {
+			rows = len(tier)
+		}
+	}
+	line := func(cell func(i int) string) {
+		var sb strings.Builder
+		for i := range tiers {
+			if i < len(tiers)-1 {
+				fmt.Fprintf(&sb, "%-*s  ", widths[i], cell(i))
+			} else {
+				sb.WriteString(cell(i))
+			}
+		}
+		fmt.Fprintln(w, strings.TrimRight(sb.String(), " "))
+	}
+	line(func(i int) string { return fmt.Sprintf("TIER %d", i) })
+	for r := 0; r < rows; r++ {
+		line(func(i int) string {
+			if r < len(tiers[i]) {
+				return tiers[i][r]
+			}
+			return ""
+		})
+	}
 }
 
 // graphNodeShape returns the DOT shape for a service type.
@@ -3594,6 +6028,14 @@ func graphNodeShape(t service.ServiceType) string {
 		return "box"
 	case service.TypeBGProcess:
 		return "doubleoctagon"
+	case service.TypeSocketActivated:
+		return "cds"
+	case service.TypeBarrier:
+		return "octagon"
+	case service.TypeFanout:
+		return "invtriangle"
+	case service.TypeTimer:
+		return "house"
 	default: // TypeProcess
 		return "ellipse"
 	}
@@ -3642,7 +6084,7 @@ func cmdQueryLoadMech(conn net.Conn) error {
 		return err
 	}
 
-	rply, payload, err := control.ReadPacket(conn)
+	rply, payload, err := readPacket(conn)
 	if err != nil {
 		return err
 	}
@@ -3715,7 +6157,7 @@ func cmdListServices5(conn net.Conn) error {
 	}
 
 	for {
-		rply, payload, err := control.ReadPacket(conn)
+		rply, payload, err := readPacket(conn)
 		if err != nil {
 			return err
 		}
@@ -3794,6 +6236,9 @@ func cmdServiceStatus5(conn net.Conn, svcName string) error {
 	if status.Flags&control.StatusFlagStartFailed != 0 {
 		fmt.Printf(" [start-failed]")
 	}
+	if status.Flags&control.StatusFlagEmbedded != 0 {
+		fmt.Printf(" [embedded]")
+	}
 	fmt.Println()
 	if status.ExecStage != 0 {
 		fmt.Printf("  Exec-stage:  %d\n", status.ExecStage)
@@ -3863,6 +6308,311 @@ func cmdFileCompare(op, a, b string) {
 	os.Exit(code)
 }
 
+// cmdMigrate implements "migrate --from systemd [--output-dir DIR] UNIT...".
+// Each unit is converted and written to stdout, or to DIR/<name> when
+// --output-dir is given (existing files are never overwritten).
+// Conversion warnings go to stderr. Returns the process exit code.
+func cmdMigrate(args []string) int {
+	from, outDir := "", ""
+	var units []string
+	for len(args) > 0 {
+		switch {
+		case args[0] == "--from" && len(args) > 1:
+			from, args = args[1], args[2:]
+		case strings.HasPrefix(args[0], "--from="):
+			from, args = strings.TrimPrefix(args[0], "--from="), args[1:]
+		case args[0] == "--output-dir" && len(args) > 1:
+			outDir, args = args[1], args[2:]
+		case strings.HasPrefix(args[0], "--output-dir="):
+			outDir, args = strings.TrimPrefix(args[0], "--output-dir="), args[1:]
+		default:
+			units, args = append(units, args[0]), args[1:]
+		}
+	}
+	if from != "systemd" || len(units) == 0 {
+		fmt.Fprintln(os.Stderr, "Usage: slinitctl migrate --from systemd [--output-dir DIR] UNIT...")
+		return 1
+	}
+
+	code := 0
+	for i, unit := range units {
+		if err := migrateUnit(unit, outDir, i > 0); err != nil {
+			fmt.Fprintf(os.Stderr, "slinitctl migrate: %s: %v\n", unit, err)
+			code = 1
+		}
+	}
+	return code
+}
+
+// migrateUnit converts one systemd unit file. sep requests a blank line
+// before the output when several units are printed to stdout.
+func migrateUnit(unit, outDir string, sep bool) error {
+	f, err := os.Open(unit)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	desc, warns, err := migrate.ParseSystemdUnit(f)
+	if err != nil {
+		return err
+	}
+	base := filepath.Base(unit)
+	name := migrate.UnitServiceName(base)
+	for _, w := range warns {
+		fmt.Fprintf(os.Stderr, "slinitctl migrate: %s: %s\n", base, w)
+	}
+
+	if outDir == "" {
+		if sep {
+			fmt.Println()
+		}
+		return migrate.WriteServiceDescription(os.Stdout, desc, base, warns)
+	}
+
+	path := filepath.Join(outDir, name)
+	out, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return err
+	}
+	if err := migrate.WriteServiceDescription(out, desc, base, warns); err != nil {
+		out.Close()
+		return err
+	}
+	if err := out.Close(); err != nil {
+		return err
+	}
+	info("Wrote %s\n", path)
+	return nil
+}
+
+// cmdLint implements "lint FILE...": parse each service file offline
+// and report config.Lint issues. Returns 1 if any file failed to parse
+// or had an error-level issue; warnings alone exit 0.
+func cmdLint(w io.Writer, files []string) int {
+	if len(files) == 0 {
+		fmt.Fprintln(os.Stderr, "Usage: slinitctl lint FILE...")
+		return 1
+	}
+	code := 0
+	for _, path := range files {
+		f, err := os.Open(path)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "slinitctl lint: %v\n", err)
+			code = 1
+			continue
+		}
+		desc, err := config.Parse(f, filepath.Base(path), path)
+		f.Close()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "slinitctl lint: %v\n", err)
+			code = 1
+			continue
+		}
+		for _, issue := range config.Lint(desc) {
+			fmt.Fprintf(w, "%s: %s\n", path, issue)
+			if issue.Suggestion != "" {
+				fmt.Fprintf(w, "    suggestion: %s\n", issue.Suggestion)
+			}
+			if issue.Severity == config.LintError {
+				code = 1
+			}
+		}
+	}
+	return code
+}
+
+// cmdValidate implements "validate FILE...": parse each service file
+// offline and report the problems ServiceDescription.Validate found,
+// then dependencies that name no service file or fail to load.
+// Dependencies are looked up in servicesDir, or next to the file when
+// it is empty. Returns 1 if any file failed to parse, had an
+// error-level validation problem or a missing dependency.
+func cmdValidate(w io.Writer, servicesDir string, files []string) int {
+	if len(files) == 0 {
+		fmt.Fprintln(os.Stderr, "Usage: slinitctl validate FILE...")
+		return 1
+	}
+	code := 0
+	for _, path := range files {
+		f, err := os.Open(path)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "slinitctl validate: %v\n", err)
+			code = 1
+			continue
+		}
+		desc, err := config.Parse(f, filepath.Base(path), path)
+		f.Close()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "slinitctl validate: %v\n", err)
+			code = 1
+			continue
+		}
+		for _, v := range desc.Warnings {
+			fmt.Fprintf(w, "%s: %v\n", path, v)
+			if v.Severity == config.LintError {
+				code = 1
+			}
+		}
+		dirs := []string{filepath.Dir(path)}
+		if servicesDir != "" {
+			dirs = strings.Split(servicesDir, ",")
+		}
+		set := service.NewServiceSet(logging.New(logging.LevelError))
+		loader := config.NewDirLoader(set, dirs)
+		set.SetLoader(loader)
+		for _, p := range loader.ValidateDependencies(desc) {
+			if !strings.Contains(p, ": ") {
+				p = "missing dependency '" + p + "'"
+			} else {
+				p = "dependency " + p
+			}
+			fmt.Fprintf(w, "%s: %s\n", path, p)
+			code = 1
+		}
+	}
+	return code
+}
+
+// cmdEncryptEnvFile implements "encrypt-env-file --key SPEC": encrypt
+// the env-file on stdin for use as an encrypted-env-file and write it to
+// stdout. Returns the process exit code.
+func cmdEncryptEnvFile(r io.Reader, w io.Writer, args []string) int {
+	keySpec := ""
+	for len(args) > 0 {
+		switch {
+		case args[0] == "--key" && len(args) > 1:
+			keySpec, args = args[1], args[2:]
+		case strings.HasPrefix(args[0], "--key="):
+			keySpec, args = strings.TrimPrefix(args[0], "--key="), args[1:]
+		default:
+			fmt.Fprintf(os.Stderr, "slinitctl encrypt-env-file: unexpected argument %q\n", args[0])
+			return 1
+		}
+	}
+	if keySpec == "" {
+		fmt.Fprintln(os.Stderr, "Usage: slinitctl encrypt-env-file --key SPEC < FILE.env > FILE.enc")
+		return 1
+	}
+	plain, err := io.ReadAll(r)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "slinitctl encrypt-env-file: %v\n", err)
+		return 1
+	}
+	out, err := process.EncryptEnvFile(plain, keySpec)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "slinitctl encrypt-env-file: %v\n", err)
+		return 1
+	}
+	if _, err := w.Write(out); err != nil {
+		fmt.Fprintf(os.Stderr, "slinitctl encrypt-env-file: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// defaultChecksumDirs are the system service directories, and the
+// default conf.d overlay directory, hashed by generate-checksums when
+// --services-dir is not given.
+var defaultChecksumDirs = []string{"/etc/slinit.d", "/run/slinit.d", "/usr/local/lib/slinit.d", "/lib/slinit.d", "/etc/slinit.conf.d"}
+
+// cmdGenerateChecksums implements "generate-checksums [--output FILE]":
+// hash every service file in servicesDir (or the default system
+// directories) in the format slinit --checksum-file reads. The sums go
+// to FILE, replaced atomically, or to w. Returns the process exit code.
+func cmdGenerateChecksums(w io.Writer, servicesDir string, args []string) int {
+	output := ""
+	for len(args) > 0 {
+		switch {
+		case args[0] == "--output" && len(args) > 1:
+			output, args = args[1], args[2:]
+		case strings.HasPrefix(args[0], "--output="):
+			output, args = strings.TrimPrefix(args[0], "--output="), args[1:]
+		default:
+			fmt.Fprintf(os.Stderr, "slinitctl generate-checksums: unexpected argument %q\n", args[0])
+			return 1
+		}
+	}
+	dirs := defaultChecksumDirs
+	if servicesDir != "" {
+		dirs = strings.Split(servicesDir, ",")
+	}
+
+	var buf bytes.Buffer
+	if err := config.GenerateChecksums(&buf, dirs, output); err != nil {
+		fmt.Fprintf(os.Stderr, "slinitctl generate-checksums: %v\n", err)
+		return 1
+	}
+	if output == "" {
+		if _, err := w.Write(buf.Bytes()); err != nil {
+			fmt.Fprintf(os.Stderr, "slinitctl generate-checksums: %v\n", err)
+			return 1
+		}
+		return 0
+	}
+	// Write beside the target and rename so a daemon loading services
+	// never sees a half-written file. The dot keeps the temporary file
+	// out of the sums if it shares a service directory.
+	tmp := filepath.Join(filepath.Dir(output), "."+filepath.Base(output)+".tmp")
+	if err := os.WriteFile(tmp, buf.Bytes(), 0o644); err != nil {
+		fmt.Fprintf(os.Stderr, "slinitctl generate-checksums: %v\n", err)
+		return 1
+	}
+	if err := os.Rename(tmp, output); err != nil {
+		os.Remove(tmp)
+		fmt.Fprintf(os.Stderr, "slinitctl generate-checksums: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// cmdGenerateUnit implements "generate-unit --from-pid PID [--format
+// slinit|toml]": read the process from /proc and print a draft service
+// description. Returns the process exit code.
+func cmdGenerateUnit(w io.Writer, args []string) int {
+	pidStr, format := "", "slinit"
+	for len(args) > 0 {
+		switch {
+		case args[0] == "--from-pid" && len(args) > 1:
+			pidStr, args = args[1], args[2:]
+		case strings.HasPrefix(args[0], "--from-pid="):
+			pidStr, args = strings.TrimPrefix(args[0], "--from-pid="), args[1:]
+		case args[0] == "--format" && len(args) > 1:
+			format, args = args[1], args[2:]
+		case strings.HasPrefix(args[0], "--format="):
+			format, args = strings.TrimPrefix(args[0], "--format="), args[1:]
+		default:
+			fmt.Fprintf(os.Stderr, "slinitctl generate-unit: unexpected argument %q\n", args[0])
+			return 1
+		}
+	}
+	if pidStr == "" {
+		fmt.Fprintln(os.Stderr, "Usage: slinitctl generate-unit --from-pid PID [--format slinit|toml]")
+		return 1
+	}
+	pid, err := strconv.Atoi(pidStr)
+	if err != nil || pid <= 0 {
+		fmt.Fprintf(os.Stderr, "slinitctl generate-unit: invalid pid %q\n", pidStr)
+		return 1
+	}
+	return generateFromPID(w, "/proc", pid, format)
+}
+
+// generateFromPID writes the draft service description for pid, reading
+// from procRoot.
+func generateFromPID(w io.Writer, procRoot string, pid int, format string) int {
+	snap, err := config.ReadProcessSnapshot(procRoot, pid)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "slinitctl generate-unit: %v\n", err)
+		return 1
+	}
+	if err := config.WriteGeneratedService(w, snap, format); err != nil {
+		fmt.Fprintf(os.Stderr, "slinitctl generate-unit: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
 func cmdCompletion(shell string) {
 	switch shell {
 	case "bash":
@@ -3880,7 +6630,7 @@ const bashCompletion = `# Bash completion for slinitctl
 # Usage: eval "$(slinitctl completion bash)"
 
 _slinitctl_commands() {
-    echo "list ls start wake stop release restart status is-started is-failed is-newer-than is-older-than shutdown trigger untrigger signal pause continue cont once reload reload-all reload-signal unload boot-time analyze catlog setenv unsetenv getallenv reset-env setenv-global unsetenv-global getallenv-global add-dep rm-dep unpin enable disable graph dependents query-name service-dirs load-mech list5 status5 attach platform completion"
+    echo "list ls start wake stop kill release switch-to jobs job cancel restart status is-started is-failed is-newer-than is-older-than shutdown trigger untrigger edit patch-apply annotate set-stop-reason no-restart enable-restart set-priority signal pause continue cont once reload reload-all reload-signal unload boot-time analyze verify-internal diagnostics check-shadowing info aliases list-timers watch events timeout catlog setenv unsetenv getallenv reset-env setenv-global unsetenv-global getallenv-global add-dep rm-dep pin-start pin-stop unpin enable disable graph export-deps dependents deps query-name service-dirs set-service-dirs add-service-dir rm-service-dir load-mech list5 status5 attach platform completion"
 }
 
 _slinitctl_services() {
@@ -3897,21 +6647,22 @@ _slinitctl() {
     local i
     for ((i=1; i < COMP_CWORD; i++)); do
         case "${COMP_WORDS[i]}" in
-            --socket-path|-p|--services-dir|-d|--from) ((i++)) ;;
+            --socket-path|-p|--socket-tcp|--tls-cert|--tls-key|--tls-ca|--services-dir|-d|--from) ((i++)) ;;
             -*) ;;
             *) cmd="${COMP_WORDS[i]}"; break ;;
         esac
     done
 
     case "$prev" in
-        --socket-path|-p) COMPREPLY=( $(compgen -f -- "$cur") ); return 0 ;;
+        --socket-path|-p|--tls-cert|--tls-key|--tls-ca) COMPREPLY=( $(compgen -f -- "$cur") ); return 0 ;;
+        --socket-tcp) return 0 ;;
         --services-dir|-d) COMPREPLY=( $(compgen -d -- "$cur") ); return 0 ;;
         --from) COMPREPLY=( $(compgen -W "$(_slinitctl_services)" -- "$cur") ); return 0 ;;
     esac
 
     if [ -z "$cmd" ]; then
         if [[ "$cur" == -* ]]; then
-            COMPREPLY=( $(compgen -W "--socket-path -p --system -s --user -u --no-wait -w --wait --pin --force -f --ignore-unstarted --offline -o --services-dir -d --from --use-passed-cfd --quiet -q --help -h --version" -- "$cur") )
+            COMPREPLY=( $(compgen -W "--socket-path -p --socket-tcp --tls-cert --tls-key --tls-ca --system -s --user -u --no-wait -w --wait --timeout --pin --force -f --ignore-unstarted --offline -o --services-dir -d --from --use-passed-cfd --quiet -q --help -h --version" -- "$cur") )
         else
             COMPREPLY=( $(compgen -W "$(_slinitctl_commands)" -- "$cur") )
         fi
@@ -3919,10 +6670,20 @@ _slinitctl() {
     fi
 
     case "$cmd" in
-        start|stop|wake|release|restart|status|is-started|is-failed|trigger|untrigger|pause|continue|cont|once|reload|reload-signal|unload|unpin|enable|disable|query-name|getallenv|catlog|dependents|setenv|unsetenv|status5|attach)
+        start|stop|kill|wake|release|switch-to|restart|status|is-started|is-failed|trigger|untrigger|edit|no-restart|enable-restart|pause|continue|cont|once|reload|reload-signal|unload|pin-start|pin-stop|unpin|enable|disable|query-name|getallenv|catlog|dependents|deps|setenv|unsetenv|status5|attach)
             COMPREPLY=( $(compgen -W "$(_slinitctl_services)" -- "$cur") ) ;;
         shutdown)
             COMPREPLY=( $(compgen -W "halt poweroff reboot kexec softreboot" -- "$cur") ) ;;
+        annotate|set-stop-reason|set-priority)
+            if [ "$prev" = "$cmd" ]; then
+                COMPREPLY=( $(compgen -W "$(_slinitctl_services)" -- "$cur") )
+            fi ;;
+        patch-apply)
+            if [ "$prev" = "patch-apply" ]; then
+                COMPREPLY=( $(compgen -W "$(_slinitctl_services)" -- "$cur") )
+            else
+                COMPREPLY=( $(compgen -f -- "$cur") )
+            fi ;;
         signal)
             local args_after=0
             for ((i=i+1; i < COMP_CWORD; i++)); do
@@ -3946,7 +6707,15 @@ _slinitctl() {
             COMPREPLY=( $(compgen -W "bash zsh fish" -- "$cur") ) ;;
         is-newer-than|is-older-than)
             COMPREPLY=( $(compgen -f -- "$cur") ) ;;
-        graph|list5|getallenv-global|boot-time|analyze|service-dirs|load-mech)
+        set-service-dirs|add-service-dir|rm-service-dir)
+            COMPREPLY=( $(compgen -d -- "$cur") ) ;;
+        boot-time|analyze)
+            if [ "$prev" = "$cmd" ]; then
+                COMPREPLY=( $(compgen -W "critical-chain --events" -- "$cur") )
+            elif [ "$prev" = "critical-chain" ]; then
+                COMPREPLY=( $(compgen -W "$(_slinitctl_services)" -- "$cur") )
+            fi ;;
+        graph|export-deps|list5|getallenv-global|verify-internal|diagnostics|check-shadowing|info|list-timers|service-dirs|load-mech)
             ;;
     esac
     return 0
@@ -3974,7 +6743,12 @@ _slinitctl() {
         'start:Start a service'
         'wake:Start without marking active'
         'stop:Stop a service'
+        'kill:Kill a service and keep it down'
         'release:Remove active mark'
+        'switch-to:Switch to another target'
+        'jobs:List start/stop jobs'
+        'job:Show a job'
+        'cancel:Cancel a job'
         'restart:Restart a service'
         'status:Show service status'
         'is-started:Check if started'
@@ -3984,6 +6758,13 @@ _slinitctl() {
         'shutdown:Initiate shutdown'
         'trigger:Trigger a service'
         'untrigger:Reset trigger'
+        'patch-apply:Check and install a service patch file'
+        'annotate:Attach a note to a service'
+        'set-stop-reason:Record why a service stopped'
+        'edit:Edit a service file'
+        'no-restart:Suspend auto-restart until reload'
+        'enable-restart:Force auto-restart on until reload'
+        'set-priority:Set a service propagation priority'
         'signal:Send signal to service'
         'pause:Pause (SIGSTOP) a service'
         'continue:Continue (SIGCONT) a paused service'
@@ -3995,6 +6776,15 @@ _slinitctl() {
         'unload:Unload stopped service'
         'boot-time:Boot timing analysis'
         'analyze:Boot timing analysis'
+        'verify-internal:Check service reference counts'
+        'diagnostics:Dump the service graph state'
+        'check-shadowing:List shadowed and masked service files'
+        'info:Show daemon and connection info'
+        'aliases:List service aliases'
+        'list-timers:List timer services'
+        'watch:Live service list'
+        'events:Timeline of recent service events'
+        'timeout:Run a command with a time limit'
         'catlog:Show service log buffer'
         'setenv:Set service env var'
         'unsetenv:Remove service env var'
@@ -4004,13 +6794,20 @@ _slinitctl() {
         'getallenv-global:List global env vars'
         'add-dep:Add runtime dependency'
         'rm-dep:Remove runtime dependency'
+        'pin-start:Pin service started'
+        'pin-stop:Pin service stopped'
         'unpin:Remove pins'
         'enable:Enable service'
         'disable:Disable service'
         'graph:Export dependency graph (DOT format)'
+        'export-deps:Export dependency edges as CSV, TSV or JSON'
         'dependents:List dependents'
+        'deps:Show dependency state'
         'query-name:Query service name'
         'service-dirs:List service dirs'
+        'set-service-dirs:Replace service dirs'
+        'add-service-dir:Add a service dir'
+        'rm-service-dir:Remove a service dir'
         'load-mech:Query loader mechanism'
         'list5:List services (protocol v5)'
         'status5:Show status (protocol v5)'
@@ -4019,9 +6816,14 @@ _slinitctl() {
     )
     global_opts=(
         '(-p --socket-path)'{-p,--socket-path}'[Control socket path]:path:_files'
+        '--socket-tcp[Remote slinit over TLS]:address: '
+        '--tls-cert[Client certificate]:file:_files'
+        '--tls-key[Client private key]:file:_files'
+        '--tls-ca[Server CA certificate]:file:_files'
         '(-s --system)'{-s,--system}'[System service manager]'
         '(-u --user)'{-u,--user}'[User service manager]'
         '--no-wait[Do not wait]'
+        '--timeout[Time limit for the whole operation]:duration:'
         '--pin[Pin service state]'
         '(-f --force)'{-f,--force}'[Force stop]'
         '--ignore-unstarted[Exit 0 if already stopped]'
@@ -4038,12 +6840,16 @@ _slinitctl() {
         command) _describe 'command' commands ;;
         args)
             case ${words[1]} in
-                start|stop|wake|release|restart|status|is-started|is-failed|trigger|untrigger|pause|continue|cont|once|reload|reload-signal|unload|unpin|enable|disable|query-name|getallenv|catlog|dependents|setenv|unsetenv|status5|attach)
+                start|stop|kill|wake|release|switch-to|restart|status|is-started|is-failed|trigger|untrigger|edit|no-restart|enable-restart|pause|continue|cont|once|reload|reload-signal|unload|pin-start|pin-stop|unpin|enable|disable|query-name|getallenv|catlog|dependents|deps|setenv|unsetenv|status5|attach)
                     _slinitctl_services ;;
                 shutdown) _describe 'type' '(halt poweroff reboot kexec softreboot)' ;;
                 signal) case $CURRENT in 2) _describe 'signal' '(SIGHUP SIGINT SIGQUIT SIGKILL SIGUSR1 SIGUSR2 SIGTERM)' ;; 3) _slinitctl_services ;; esac ;;
                 add-dep|rm-dep) case $CURRENT in 2|4) _slinitctl_services ;; 3) _describe 'dep type' '(regular waits-for milestone soft before after)' ;; esac ;;
                 is-newer-than|is-older-than) _files ;;
+                set-service-dirs|add-service-dir|rm-service-dir) _directories ;;
+                patch-apply) case $CURRENT in 2) _slinitctl_services ;; 3) _files -g '*.patch' ;; esac ;;
+                annotate|set-stop-reason|set-priority) case $CURRENT in 2) _slinitctl_services ;; esac ;;
+                boot-time|analyze) case $CURRENT in 2) _describe 'analysis' '(critical-chain --events)' ;; 3) _slinitctl_services ;; esac ;;
                 completion) _describe 'shell' '(bash zsh fish)' ;;
             esac ;;
     esac
@@ -4059,24 +6865,29 @@ function __slinitctl_services
     slinitctl --system list 2>/dev/null | string replace -r '^\[.*\] ' '' | string replace -r ' \(.*' ''
 end
 
-set -l cmds list ls start wake stop release restart status is-started is-failed is-newer-than is-older-than shutdown trigger untrigger signal pause continue cont once reload reload-all reload-signal unload boot-time analyze catlog setenv unsetenv getallenv reset-env setenv-global unsetenv-global getallenv-global add-dep rm-dep unpin enable disable graph dependents query-name service-dirs load-mech list5 status5 attach completion
+set -l cmds list ls start wake stop kill release switch-to jobs job cancel restart status is-started is-failed is-newer-than is-older-than shutdown trigger untrigger edit patch-apply annotate set-stop-reason no-restart enable-restart set-priority signal pause continue cont once reload reload-all reload-signal unload boot-time analyze verify-internal diagnostics check-shadowing info aliases list-timers watch events timeout catlog setenv unsetenv getallenv reset-env setenv-global unsetenv-global getallenv-global add-dep rm-dep pin-start pin-stop unpin enable disable graph export-deps dependents deps query-name service-dirs set-service-dirs add-service-dir rm-service-dir load-mech list5 status5 attach completion
 
 complete -c slinitctl -f
 complete -c slinitctl -n "not __fish_seen_subcommand_from $cmds" -s p -l socket-path -rF -d 'Socket path'
+complete -c slinitctl -n "not __fish_seen_subcommand_from $cmds" -l socket-tcp -r -d 'Remote host:port (TLS)'
+complete -c slinitctl -n "not __fish_seen_subcommand_from $cmds" -l tls-cert -rF -d 'Client certificate'
+complete -c slinitctl -n "not __fish_seen_subcommand_from $cmds" -l tls-key -rF -d 'Client private key'
+complete -c slinitctl -n "not __fish_seen_subcommand_from $cmds" -l tls-ca -rF -d 'Server CA certificate'
 complete -c slinitctl -n "not __fish_seen_subcommand_from $cmds" -s s -l system -d 'System mode'
 complete -c slinitctl -n "not __fish_seen_subcommand_from $cmds" -s u -l user -d 'User mode'
 complete -c slinitctl -n "not __fish_seen_subcommand_from $cmds" -l no-wait -d 'No wait'
+complete -c slinitctl -n "not __fish_seen_subcommand_from $cmds" -l timeout -r -d 'Time limit for the whole operation'
 complete -c slinitctl -n "not __fish_seen_subcommand_from $cmds" -l pin -d 'Pin state'
 complete -c slinitctl -n "not __fish_seen_subcommand_from $cmds" -s f -l force -d 'Force'
 complete -c slinitctl -n "not __fish_seen_subcommand_from $cmds" -s q -l quiet -d 'Quiet'
 complete -c slinitctl -n "not __fish_seen_subcommand_from $cmds" -s h -l help -d 'Help'
 complete -c slinitctl -n "not __fish_seen_subcommand_from $cmds" -l version -d 'Version'
 
-for cmd in list ls start wake stop release restart status is-started is-failed is-newer-than is-older-than shutdown trigger untrigger signal pause continue cont once reload reload-all reload-signal unload boot-time analyze catlog setenv unsetenv getallenv reset-env setenv-global unsetenv-global getallenv-global add-dep rm-dep unpin enable disable graph dependents query-name service-dirs load-mech list5 status5 attach completion
+for cmd in list ls start wake stop kill release switch-to jobs job cancel restart status is-started is-failed is-newer-than is-older-than shutdown trigger untrigger edit patch-apply annotate set-stop-reason no-restart enable-restart set-priority signal pause continue cont once reload reload-all reload-signal unload boot-time analyze verify-internal diagnostics check-shadowing info aliases list-timers watch events timeout catlog setenv unsetenv getallenv reset-env setenv-global unsetenv-global getallenv-global add-dep rm-dep pin-start pin-stop unpin enable disable graph export-deps dependents deps query-name service-dirs set-service-dirs add-service-dir rm-service-dir load-mech list5 status5 attach completion
     complete -c slinitctl -n "not __fish_seen_subcommand_from $cmds" -a $cmd
 end
 
-for cmd in start stop wake release restart status is-started is-failed trigger untrigger pause continue cont once reload reload-signal unload unpin enable disable query-name getallenv reset-env catlog dependents setenv unsetenv status5 attach
+for cmd in start stop kill wake release switch-to restart status is-started is-failed trigger untrigger edit patch-apply annotate set-stop-reason no-restart enable-restart set-priority pause continue cont once reload reload-signal unload pin-start pin-stop unpin enable disable query-name getallenv reset-env catlog dependents deps setenv unsetenv status5 attach
     complete -c slinitctl -n "__fish_seen_subcommand_from $cmd" -a '(__slinitctl_services)'
 end
 
@@ -4084,6 +6895,9 @@ complete -c slinitctl -n "__fish_seen_subcommand_from shutdown" -a 'halt powerof
 complete -c slinitctl -n "__fish_seen_subcommand_from signal" -a 'SIGHUP SIGINT SIGQUIT SIGKILL SIGUSR1 SIGUSR2 SIGTERM SIGCONT SIGSTOP'
 complete -c slinitctl -n "__fish_seen_subcommand_from add-dep rm-dep" -a 'regular waits-for milestone soft before after'
 complete -c slinitctl -n "__fish_seen_subcommand_from is-newer-than is-older-than" -F
+complete -c slinitctl -n "__fish_seen_subcommand_from patch-apply" -F
+complete -c slinitctl -n "__fish_seen_subcommand_from set-service-dirs add-service-dir rm-service-dir" -a '(__fish_complete_directories)'
+complete -c slinitctl -n "__fish_seen_subcommand_from boot-time analyze" -a 'critical-chain --events'
 complete -c slinitctl -n "__fish_seen_subcommand_from completion" -a 'bash zsh fish'`)
 }
 
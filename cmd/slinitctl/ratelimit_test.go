@@ -0,0 +1,121 @@
+package main
+
+import (
+	"encoding/binary"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/sunlightlinux/slinit/pkg/control"
+)
+
+func TestReadPacketRetriesRateLimited(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+	defer server.Close()
+	conn := &replayConn{Conn: client}
+
+	done := make(chan error, 1)
+	go func() {
+		// First attempt is rejected, the replayed request succeeds.
+		for i, reply := range []uint8{control.RplyRateLimited, control.RplyACK} {
+			cmd, payload, err := control.ReadPacket(server)
+			if err != nil {
+				done <- err
+				return
+			}
+			if cmd != control.CmdQueryVersion || string(payload) != "x" {
+				t.Errorf("attempt %d: got cmd %d payload %q", i, cmd, payload)
+			}
+			var out []byte
+			if reply == control.RplyRateLimited {
+				out = control.EncodeRateLimited(time.Millisecond)
+			}
+			if err := control.WritePacket(server, reply, out); err != nil {
+				done <- err
+				return
+			}
+		}
+		done <- nil
+	}()
+
+	if err := control.WritePacket(conn, control.CmdQueryVersion, []byte("x")); err != nil {
+		t.Fatal(err)
+	}
+	rply, _, err := readReply(conn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rply != control.RplyACK {
+		t.Errorf("readReply = %d, want RplyACK after retry", rply)
+	}
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestReadPacketRefreshesStaleHandle(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+	defer server.Close()
+	conn := &replayConn{Conn: client}
+
+	// serviceRecord answers CmdLoadService with the given handle.
+	serviceRecord := func(handle uint32) []byte {
+		out := make([]byte, 6)
+		binary.LittleEndian.PutUint32(out[1:], handle)
+		return out
+	}
+	done := make(chan error, 1)
+	go func() {
+		steps := []struct {
+			cmd    uint8
+			handle uint32 // expected leading handle, for CmdServiceStatus
+			reply  uint8
+			out    []byte
+		}{
+			{control.CmdLoadService, 0, control.RplyServiceRecord, serviceRecord(1)},
+			{control.CmdServiceStatus, 1, control.RplyStaleHandle, nil},
+			{control.CmdLoadService, 0, control.RplyServiceRecord, serviceRecord(2)},
+			{control.CmdServiceStatus, 2, control.RplyACK, nil},
+		}
+		for i, st := range steps {
+			cmd, payload, err := control.ReadPacket(server)
+			if err != nil {
+				done <- err
+				return
+			}
+			if cmd != st.cmd {
+				t.Errorf("step %d: got cmd %d, want %d", i, cmd, st.cmd)
+			}
+			if st.cmd == control.CmdServiceStatus {
+				if h, _ := control.DecodeHandle(payload); h != st.handle {
+					t.Errorf("step %d: handle %d, want %d", i, h, st.handle)
+				}
+			}
+			if err := control.WritePacket(server, st.reply, st.out); err != nil {
+				done <- err
+				return
+			}
+		}
+		done <- nil
+	}()
+
+	h, err := loadServiceHandle(conn, "svc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := control.WritePacket(conn, control.CmdServiceStatus, control.EncodeHandle(h)); err != nil {
+		t.Fatal(err)
+	}
+	rply, _, err := readReply(conn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rply != control.RplyACK {
+		t.Errorf("readReply = %d, want RplyACK after refreshing the handle", rply)
+	}
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+}
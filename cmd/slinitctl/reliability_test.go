@@ -0,0 +1,29 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/sunlightlinux/slinit/pkg/service"
+)
+
+func TestFormatReliability(t *testing.T) {
+	cases := []struct {
+		fs         service.FailureStats
+		suppressed bool
+		want       string
+	}{
+		{service.FailureStats{TotalStarts: 45, TotalFailures: 3, ConsecutiveFailures: 3}, false,
+			"42/45 starts succeeded (93.3%), 3 consecutive failures"},
+		{service.FailureStats{TotalStarts: 10}, false,
+			"10/10 starts succeeded (100.0%)"},
+		{service.FailureStats{TotalStarts: 4, TotalFailures: 1, ConsecutiveFailures: 1}, false,
+			"3/4 starts succeeded (75.0%), 1 consecutive failure"},
+		{service.FailureStats{TotalStarts: 5, TotalFailures: 5, ConsecutiveFailures: 5}, true,
+			"0/5 starts succeeded (0.0%), 5 consecutive failures, auto-restart disabled (reset-failed to re-enable)"},
+	}
+	for _, c := range cases {
+		if got := formatReliability(c.fs, c.suppressed); got != c.want {
+			t.Errorf("formatReliability(%+v) = %q, want %q", c.fs, got, c.want)
+		}
+	}
+}
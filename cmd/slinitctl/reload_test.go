@@ -0,0 +1,51 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/sunlightlinux/slinit/pkg/service"
+)
+
+func TestWriteReloadResults(t *testing.T) {
+	var b strings.Builder
+	writeReloadResults(&b, []service.ReloadResult{
+		{Name: "db", Outcome: service.ReloadFailed, Detail: "bad setting"},
+		{Name: "web", Outcome: service.ReloadRestartNeeded},
+		{Name: "worker", Outcome: service.ReloadSkipped, Detail: "STARTING"},
+	})
+	lines := strings.Split(strings.TrimRight(b.String(), "\n"), "\n")
+	if len(lines) != 4 || !strings.HasPrefix(lines[0], "SERVICE") {
+		t.Fatalf("unexpected table:\n%s", b.String())
+	}
+	for i, want := range []string{
+		"db failed bad setting",
+		"web restart needed restart to apply process settings",
+		"worker skipped STARTING",
+	} {
+		if got := strings.Join(strings.Fields(lines[i+1]), " "); got != want {
+			t.Errorf("row %d = %q, want %q", i+1, got, want)
+		}
+	}
+}
+
+func TestReloadSummary(t *testing.T) {
+	results := []service.ReloadResult{
+		{Name: "a", Outcome: service.ReloadApplied},
+		{Name: "b", Outcome: service.ReloadRestartNeeded},
+		{Name: "c", Outcome: service.ReloadFailed},
+		{Name: "d", Outcome: service.ReloadSkipped},
+	}
+	for _, tc := range []struct {
+		ok, failed int
+		results    []service.ReloadResult
+		want       string
+	}{
+		{12, 0, nil, "Reloaded 12 service(s)."},
+		{10, 1, results, "Reloaded 10 service(s), 2 changed, 1 need a restart, 1 skipped; 1 failed."},
+	} {
+		if got := reloadSummary(tc.ok, tc.failed, tc.results); got != tc.want {
+			t.Errorf("reloadSummary = %q, want %q", got, tc.want)
+		}
+	}
+}
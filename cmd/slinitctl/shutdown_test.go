@@ -1,91 +1,13 @@
 package main
 
 import (
+	"bytes"
+	"strings"
 	"testing"
 	"time"
-)
-
-func TestParseShutdownTimeNow(t *testing.T) {
-	d, err := parseShutdownTime("now")
-	if err != nil {
-		t.Fatalf("parseShutdownTime(now): %v", err)
-	}
-	if d != 0 {
-		t.Errorf("d = %v, want 0", d)
-	}
-}
-
-func TestParseShutdownTimeEmpty(t *testing.T) {
-	d, err := parseShutdownTime("")
-	if err != nil {
-		t.Fatalf("parseShutdownTime(): %v", err)
-	}
-	if d != 0 {
-		t.Errorf("d = %v, want 0", d)
-	}
-}
-
-func TestParseShutdownTimePlusMinutes(t *testing.T) {
-	d, err := parseShutdownTime("+5")
-	if err != nil {
-		t.Fatalf("parseShutdownTime(+5): %v", err)
-	}
-	if d != 5*time.Minute {
-		t.Errorf("d = %v, want 5m", d)
-	}
-}
-
-func TestParseShutdownTimePlainMinutes(t *testing.T) {
-	d, err := parseShutdownTime("10")
-	if err != nil {
-		t.Fatalf("parseShutdownTime(10): %v", err)
-	}
-	if d != 10*time.Minute {
-		t.Errorf("d = %v, want 10m", d)
-	}
-}
-
-func TestParseShutdownTimeAbsolute(t *testing.T) {
-	// Use a time in the future.
-	now := time.Now()
-	target := now.Add(30 * time.Minute)
-	timeStr := target.Format("15:04")
 
-	d, err := parseShutdownTime(timeStr)
-	if err != nil {
-		t.Fatalf("parseShutdownTime(%s): %v", timeStr, err)
-	}
-	// Should be approximately 30 minutes (allow 2 minute tolerance).
-	if d < 28*time.Minute || d > 32*time.Minute {
-		t.Errorf("d = %v, want ~30m for %s", d, timeStr)
-	}
-}
-
-func TestParseShutdownTimeAbsolutePast(t *testing.T) {
-	// Use a time in the past — should wrap to tomorrow.
-	now := time.Now()
-	target := now.Add(-30 * time.Minute)
-	timeStr := target.Format("15:04")
-
-	d, err := parseShutdownTime(timeStr)
-	if err != nil {
-		t.Fatalf("parseShutdownTime(%s): %v", timeStr, err)
-	}
-	// Should be approximately 23h30m.
-	if d < 23*time.Hour || d > 24*time.Hour {
-		t.Errorf("d = %v, want ~23h30m for past time %s", d, timeStr)
-	}
-}
-
-func TestParseShutdownTimeInvalid(t *testing.T) {
-	bad := []string{"foo", "+abc", "25:00", "12:61", "-5"}
-	for _, s := range bad {
-		_, err := parseShutdownTime(s)
-		if err == nil {
-			t.Errorf("parseShutdownTime(%q) should fail", s)
-		}
-	}
-}
+	"github.com/sunlightlinux/slinit/pkg/service"
+)
 
 func TestParseShutdownType(t *testing.T) {
 	valid := map[string]bool{
@@ -122,3 +44,24 @@ func TestFormatHumanDuration(t *testing.T) {
 		}
 	}
 }
+
+func TestPrintShutdownPlan(t *testing.T) {
+	var buf bytes.Buffer
+	printShutdownPlan(&buf, []service.ShutdownStep{
+		{Services: []string{"nginx", "sshd"}, EstimatedDurationMs: 1500},
+		{Services: []string{"network"}, EstimatedDurationMs: 200},
+	})
+	want := "STEP  ESTIMATE   SERVICES\n" +
+		"1     1.500s     nginx, sshd\n" +
+		"2     200ms      network\n" +
+		"Estimated total shutdown time: 1.700s\n"
+	if got := buf.String(); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+
+	buf.Reset()
+	printShutdownPlan(&buf, nil)
+	if !strings.Contains(buf.String(), "No active services") {
+		t.Errorf("empty plan output = %q", buf.String())
+	}
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"encoding/binary"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/sunlightlinux/slinit/pkg/control"
+	"github.com/sunlightlinux/slinit/pkg/service"
+)
+
+// packet is one reply or push a fake daemon sends.
+type packet struct {
+	kind    uint8
+	payload []byte
+}
+
+// serviceEvent builds an InfoServiceEvent push for handle.
+func serviceEvent(handle uint32, event service.ServiceEvent) packet {
+	buf := make([]byte, 17)
+	binary.LittleEndian.PutUint32(buf, handle)
+	buf[4] = uint8(event)
+	return packet{control.InfoServiceEvent, buf}
+}
+
+// runAwait feeds packets to awaitTransition for a start (or stop) of
+// handle 7.
+func runAwait(t *testing.T, start bool, packets ...packet) (uint8, error) {
+	t.Helper()
+	client, server := net.Pipe()
+	defer client.Close()
+	go func() {
+		defer server.Close()
+		for _, p := range packets {
+			if err := control.WritePacket(server, p.kind, p.payload); err != nil {
+				return
+			}
+		}
+	}()
+	rply, _, err := awaitTransition(client, 7, "svc", start)
+	return rply, err
+}
+
+func TestAwaitTransition(t *testing.T) {
+	preack := packet{control.RplyPreACK, nil}
+	ack := packet{control.RplyACK, nil}
+
+	// The settling event may come before or after the final reply;
+	// events for other handles and unrelated events are skipped.
+	rply, err := runAwait(t, true, preack, serviceEvent(3, service.EventFailedStart), ack,
+		serviceEvent(7, service.EventStopCancelled), serviceEvent(7, service.EventStarted))
+	if err != nil || rply != control.RplyACK {
+		t.Errorf("start: got %d, %v", rply, err)
+	}
+	rply, err = runAwait(t, false, preack, serviceEvent(7, service.EventStartCancelled),
+		serviceEvent(7, service.EventStopped), ack)
+	if err != nil || rply != control.RplyACK {
+		t.Errorf("stop: got %d, %v", rply, err)
+	}
+
+	rply, err = runAwait(t, true, preack, ack, serviceEvent(7, service.EventFailedStart))
+	if err == nil || !strings.Contains(err.Error(), "failed to start") || rply != control.RplyACK {
+		t.Errorf("failed start: got %d, %v", rply, err)
+	}
+	if _, err = runAwait(t, false, preack, serviceEvent(7, service.EventStopCancelled), ack); err == nil {
+		t.Error("cancelled stop: expected error")
+	}
+
+	// A refusal before the pre-ack, or without the wait flag the bare
+	// reply, is returned as is.
+	if rply, err = runAwait(t, true, packet{control.RplyPinnedStopped, nil}); err != nil || rply != control.RplyPinnedStopped {
+		t.Errorf("refusal: got %d, %v", rply, err)
+	}
+	if rply, err = runAwait(t, true, ack); err != nil || rply != control.RplyACK {
+		t.Errorf("no wait: got %d, %v", rply, err)
+	}
+}
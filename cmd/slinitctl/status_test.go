@@ -0,0 +1,67 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/sunlightlinux/slinit/pkg/control"
+	"github.com/sunlightlinux/slinit/pkg/service"
+)
+
+func TestWriteStatusLifecycle(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.Local)
+	cases := []struct {
+		status control.ServiceStatusInfo
+		want   string
+	}{
+		{control.ServiceStatusInfo{
+			State: service.StateStarted, StartedTime: now.Add(-90 * time.Minute),
+			HasFailureStats: true, FailureStats: service.FailureStats{TotalRestarts: 2},
+			HasStopReason: true, StopReason: service.ReasonFailed,
+		}, "  Uptime:  1h30m (since 2026-03-01 10:30:00)\n  Restarts: 2\n"},
+		{control.ServiceStatusInfo{
+			State: service.StateStopped, Flags: control.StatusFlagStartFailed,
+			HasStopReason: true, StopReason: service.ReasonDepFailed,
+		}, "  Last start: failed\n  Stop reason: dependency-failed\n"},
+		{control.ServiceStatusInfo{
+			State: service.StateStopped, Flags: control.StatusFlagStartSkipped,
+			HasStopReason: true, StopReason: service.ReasonNormal,
+		}, "  Last start: skipped\n"},
+		{control.ServiceStatusInfo{
+			State:         service.StateStopped,
+			HasStopReason: true, StopReason: service.ReasonCustom, CustomStopReason: "no disk",
+		}, "  Stop reason: custom - \"no disk\"\n"},
+	}
+	for i, c := range cases {
+		var buf bytes.Buffer
+		writeStatusLifecycle(&buf, c.status, now)
+		if buf.String() != c.want {
+			t.Errorf("case %d:\n got %q\nwant %q", i, buf.String(), c.want)
+		}
+	}
+}
+
+func TestWriteStatusDeps(t *testing.T) {
+	deps := []service.DependencyInfo{
+		{Name: "network", DepType: service.DepRegular, State: service.StateStarted},
+		{Name: "udev", DepType: service.DepWaitsFor, WaitingOn: true, State: service.StateStarting},
+	}
+	dependents := []service.DependencyInfo{{Name: "web", DepType: service.DepRegular}}
+	var buf bytes.Buffer
+	writeStatusDeps(&buf, deps, dependents, func(string) string { return "STARTED" })
+	want := "  Dependencies:\n" +
+		"    → network (regular): " + formatState(service.StateStarted) + "\n" +
+		"    → udev (waits-for, waiting): " + formatState(service.StateStarting) + "\n" +
+		"  Dependents:\n" +
+		"    ← web (regular): STARTED\n"
+	if buf.String() != want {
+		t.Errorf("got:\n%s\nwant:\n%s", buf.String(), want)
+	}
+
+	buf.Reset()
+	writeStatusDeps(&buf, nil, nil, nil)
+	if buf.Len() != 0 {
+		t.Errorf("no edges: got %q", buf.String())
+	}
+}
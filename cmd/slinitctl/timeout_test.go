@@ -0,0 +1,103 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseOpTimeout(t *testing.T) {
+	cases := []struct {
+		in      string
+		want    time.Duration
+		wantErr bool
+	}{
+		{"10s", 10 * time.Second, false},
+		{"1m30s", 90 * time.Second, false},
+		{"250ms", 250 * time.Millisecond, false},
+		{"5", 5 * time.Second, false},
+		{"0", 0, true},
+		{"-3s", 0, true},
+		{"soon", 0, true},
+	}
+	for _, c := range cases {
+		got, err := parseOpTimeout(c.in)
+		if (err != nil) != c.wantErr {
+			t.Errorf("parseOpTimeout(%q) error = %v, wantErr %v", c.in, err, c.wantErr)
+			continue
+		}
+		if got != c.want {
+			t.Errorf("parseOpTimeout(%q) = %v, want %v", c.in, got, c.want)
+		}
+	}
+}
+
+// Deadlines set on the connection (readReply's -w cap, or clearing it)
+// must never extend past the operation deadline.
+func TestTimeoutConnClampsDeadline(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Hour)
+	defer cancel()
+	tc := newTimeoutConn(ctx, client).(*timeoutConn)
+
+	if got := tc.clamp(time.Time{}); !got.Equal(tc.deadline) {
+		t.Errorf("clamp(zero) = %v, want the operation deadline %v", got, tc.deadline)
+	}
+	if got := tc.clamp(tc.deadline.Add(time.Minute)); !got.Equal(tc.deadline) {
+		t.Errorf("clamp(later) = %v, want the operation deadline %v", got, tc.deadline)
+	}
+	earlier := time.Now().Add(time.Second)
+	if got := tc.clamp(earlier); !got.Equal(earlier) {
+		t.Errorf("clamp(earlier) = %v, want %v", got, earlier)
+	}
+}
+
+// TestTimeoutExitStatus runs slinitctl against a daemon that accepts the
+// connection but never replies: `timeout 200ms list` must give up with
+// the timeout message and exit status 124.
+func TestTimeoutExitStatus(t *testing.T) {
+	if sock := os.Getenv("SLINITCTL_TEST_TIMEOUT_SOCKET"); sock != "" {
+		os.Args = []string{"slinitctl", "-p", sock, "timeout", "200ms", "list"}
+		main()
+		os.Exit(0)
+	}
+
+	sock := filepath.Join(t.TempDir(), "ctl.sock")
+	ln, err := net.Listen("unix", sock)
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	defer ln.Close()
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+		}
+	}()
+
+	cmd := exec.Command(os.Args[0], "-test.run=^TestTimeoutExitStatus$")
+	cmd.Env = append(os.Environ(), "SLINITCTL_TEST_TIMEOUT_SOCKET="+sock)
+	var stderr strings.Builder
+	cmd.Stderr = &stderr
+	err = cmd.Run()
+
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) || exitErr.ExitCode() != timeoutExitCode {
+		t.Fatalf("exit = %v, want status %d (stderr: %q)", err, timeoutExitCode, stderr.String())
+	}
+	if !strings.Contains(stderr.String(), "slinitctl: operation timed out after 200ms") {
+		t.Errorf("stderr = %q, want the timeout message", stderr.String())
+	}
+}
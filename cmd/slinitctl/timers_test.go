@@ -0,0 +1,30 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sunlightlinux/slinit/pkg/service"
+)
+
+func TestWriteTimers(t *testing.T) {
+	now := time.Date(2026, 5, 4, 12, 0, 0, 0, time.Local)
+	var b strings.Builder
+	writeTimers(&b, []service.TimerInfo{
+		{Name: "backup-timer", Target: "backup", State: service.StateStarted,
+			NextFire: now.Add(90 * time.Minute), LastFire: now.Add(-30 * time.Minute)},
+		{Name: "idle-timer", Target: "", State: service.StateStopped},
+	}, now)
+
+	lines := strings.Split(strings.TrimRight(b.String(), "\n"), "\n")
+	if len(lines) != 3 || !strings.HasPrefix(lines[0], "NEXT") {
+		t.Fatalf("unexpected table:\n%s", b.String())
+	}
+	if got := strings.Join(strings.Fields(lines[1]), " "); got != "2026-05-04 13:30:00 1h30m 2026-05-04 11:30:00 backup-timer backup" {
+		t.Errorf("row = %q", lines[1])
+	}
+	if got := strings.Fields(lines[2]); strings.Join(got, " ") != "- - - idle-timer -" {
+		t.Errorf("idle row = %q", lines[2])
+	}
+}
@@ -2,10 +2,11 @@ module github.com/sunlightlinux/slinit
 
 go 1.25.0
 
+require golang.org/x/sys v0.41.0
+
 require (
 	github.com/cpuguy83/go-md2man/v2 v2.0.7 // indirect
 	github.com/russross/blackfriday/v2 v2.1.0 // indirect
-	golang.org/x/sys v0.41.0 // indirect
 )
 
 tool github.com/cpuguy83/go-md2man/v2
@@ -0,0 +1,228 @@
+package config
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// A checksum file lists the SHA-256 of each trusted service file in
+// sha256sum(1) format, one "<hash>  <filename>" entry per line. With
+// one configured, the loader refuses any service file that is not
+// listed or whose contents no longer match, so a description changed
+// behind the administrator's back is never run. Relative filenames are
+// taken relative to the directory holding the checksum file; slinitctl
+// generate-checksums writes absolute ones.
+
+// SetChecksumFile makes the loader verify every service file against
+// the given checksum file before parsing it. An empty path disables
+// verification.
+func (dl *DirLoader) SetChecksumFile(path string) {
+	dl.checksumFile = path
+}
+
+// ChecksumFile returns the configured checksum file, if any.
+func (dl *DirLoader) ChecksumFile() string {
+	return dl.checksumFile
+}
+
+// VerifyChecksum checks the file the loader would read for service name
+// against its entry in checksumFile. It returns an error if the service
+// file is missing, has no entry, or its SHA-256 does not match.
+func (dl *DirLoader) VerifyChecksum(name string, checksumFile string) error {
+	for _, dir := range dl.ServiceDirs() {
+		path := filepath.Join(dir, name)
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			continue
+		}
+		return verifyPathChecksum(path, checksumFile)
+	}
+	return ErrServiceNotFound
+}
+
+// checkOpenFile verifies f, the open file at path contributing to
+// service name, against the checksum file when one is configured, and
+// rewinds it for parsing. Hashing the open file rather than the path
+// means the contents checked are the contents parsed.
+func (dl *DirLoader) checkOpenFile(f *os.File, path, name string) error {
+	if dl.checksumFile == "" {
+		return nil
+	}
+	err := verifyFileChecksum(f, path, dl.checksumFile)
+	if err == nil {
+		_, err = f.Seek(0, io.SeekStart)
+	}
+	if err != nil {
+		return &ServiceLoadError{ServiceName: name, Message: err.Error(), Err: err}
+	}
+	return nil
+}
+
+// verifyPathChecksum opens path and checks it against checksumFile.
+func verifyPathChecksum(path, checksumFile string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return verifyFileChecksum(f, path, checksumFile)
+}
+
+// verifyFileChecksum hashes r, the contents of path, and compares the
+// result with path's entry in checksumFile.
+func verifyFileChecksum(r io.Reader, path, checksumFile string) error {
+	sums, err := readChecksumFile(checksumFile)
+	if err != nil {
+		return err
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return err
+	}
+	want, ok := sums[abs]
+	if !ok {
+		return fmt.Errorf("integrity violation: %s is not listed in %s", path, checksumFile)
+	}
+	h := sha256.New()
+	if _, err := io.Copy(h, r); err != nil {
+		return fmt.Errorf("error reading %s: %w", path, err)
+	}
+	if got := hex.EncodeToString(h.Sum(nil)); got != want {
+		return fmt.Errorf("integrity violation: %s does not match its checksum in %s (have %s, want %s)",
+			path, checksumFile, got, want)
+	}
+	return nil
+}
+
+// readChecksumFile parses a sha256sum(1) style file into a map from
+// absolute filename to lower-case hex digest. Blank lines and lines
+// starting with '#' are skipped.
+func readChecksumFile(path string) (map[string]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("checksum file: %w", err)
+	}
+	defer f.Close()
+
+	base := filepath.Dir(path)
+	sums := make(map[string]string)
+	sc := bufio.NewScanner(f)
+	lineNum := 0
+	for sc.Scan() {
+		lineNum++
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || line[0] == '#' {
+			continue
+		}
+		hash, file, ok := strings.Cut(line, " ")
+		// "  name" is text mode, " *name" binary mode; both hash the
+		// same bytes.
+		file = strings.TrimPrefix(strings.TrimLeft(file, " "), "*")
+		if !ok || file == "" || len(hash) != sha256.Size*2 {
+			return nil, fmt.Errorf("%s:%d: malformed checksum line", path, lineNum)
+		}
+		if _, err := hex.DecodeString(hash); err != nil {
+			return nil, fmt.Errorf("%s:%d: malformed checksum line", path, lineNum)
+		}
+		if !filepath.IsAbs(file) {
+			file = filepath.Join(base, file)
+		}
+		sums[filepath.Clean(file)] = strings.ToLower(hash)
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("checksum file %s: %w", path, err)
+	}
+	return sums, nil
+}
+
+// GenerateChecksums writes a checksum file for every regular file
+// directly inside dirs, and every patch file in their <service>.d
+// subdirectories, sorted by path. Overlay directories are hashed the
+// same way when listed in dirs. Hidden files are skipped, as are
+// directories that do not exist, and skip when non-empty: the checksum
+// file itself when it lives in a service directory.
+func GenerateChecksums(w io.Writer, dirs []string, skip string) error {
+	if skip != "" {
+		abs, err := filepath.Abs(skip)
+		if err != nil {
+			return err
+		}
+		skip = abs
+	}
+	var paths []string
+	for _, dir := range dirs {
+		abs, err := filepath.Abs(dir)
+		if err != nil {
+			return err
+		}
+		entries, err := os.ReadDir(abs)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return err
+		}
+		for _, e := range entries {
+			if strings.HasPrefix(e.Name(), ".") {
+				continue
+			}
+			path := filepath.Join(abs, e.Name())
+			if path == skip {
+				continue
+			}
+			fi, err := os.Stat(path)
+			if err != nil {
+				continue
+			}
+			if fi.IsDir() && strings.HasSuffix(e.Name(), ".d") {
+				patches, err := patchFilesIn(path)
+				if err != nil {
+					return err
+				}
+				paths = append(paths, patches...)
+				continue
+			}
+			if fi.Mode().IsRegular() {
+				paths = append(paths, path)
+			}
+		}
+	}
+	sort.Strings(paths)
+
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		sum := sha256.Sum256(data)
+		if _, err := fmt.Fprintf(w, "%s  %s\n", hex.EncodeToString(sum[:]), path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// patchFilesIn returns the regular, non-hidden patch files in dir.
+func patchFilesIn(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var paths []string
+	for _, e := range entries {
+		if strings.HasPrefix(e.Name(), ".") || !strings.HasSuffix(e.Name(), PatchSuffix) {
+			continue
+		}
+		path := filepath.Join(dir, e.Name())
+		if fi, err := os.Stat(path); err == nil && fi.Mode().IsRegular() {
+			paths = append(paths, path)
+		}
+	}
+	return paths, nil
+}
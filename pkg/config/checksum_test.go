@@ -0,0 +1,140 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/sunlightlinux/slinit/pkg/service"
+)
+
+func TestChecksumFileVerifiesServiceFiles(t *testing.T) {
+	servicesDir := t.TempDir()
+	writeServiceFile(t, servicesDir, "trusted", "type = process\ncommand = /bin/true\n")
+	writeServiceFile(t, servicesDir, "tampered", "type = process\ncommand = /bin/true\n")
+
+	sumsPath := filepath.Join(t.TempDir(), "checksums")
+	var sums bytes.Buffer
+	if err := GenerateChecksums(&sums, []string{servicesDir}, ""); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(sumsPath, sums.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+	// Added and changed after the sums were taken.
+	writeServiceFile(t, servicesDir, "unlisted", "type = process\ncommand = /bin/true\n")
+	writeServiceFile(t, servicesDir, "tampered", "type = process\ncommand = /bin/evil\n")
+
+	ss := service.NewServiceSet(&testReloadLogger{})
+	loader := NewDirLoader(ss, []string{servicesDir})
+	loader.SetChecksumFile(sumsPath)
+	ss.SetLoader(loader)
+
+	if _, err := loader.LoadService("trusted"); err != nil {
+		t.Fatalf("trusted service should load: %v", err)
+	}
+	for _, name := range []string{"tampered", "unlisted"} {
+		_, err := loader.LoadService(name)
+		var le *ServiceLoadError
+		if !errors.As(err, &le) || !strings.Contains(err.Error(), "integrity violation") {
+			t.Errorf("%s: expected integrity violation, got %v", name, err)
+		}
+	}
+
+	if err := loader.VerifyChecksum("trusted", sumsPath); err != nil {
+		t.Errorf("VerifyChecksum(trusted): %v", err)
+	}
+	if err := loader.VerifyChecksum("tampered", sumsPath); err == nil {
+		t.Error("VerifyChecksum(tampered) should fail")
+	}
+	if err := loader.VerifyChecksum("missing", sumsPath); !errors.Is(err, ErrServiceNotFound) {
+		t.Errorf("VerifyChecksum(missing) = %v, want ErrServiceNotFound", err)
+	}
+}
+
+func TestChecksumFileCoversDropIns(t *testing.T) {
+	servicesDir := t.TempDir()
+	overlayDir := t.TempDir()
+	writeServiceFile(t, servicesDir, "web", "type = process\ncommand = /bin/true\n")
+	writeServiceFile(t, servicesDir, "web.override", "restart = yes\n")
+	if err := os.Mkdir(filepath.Join(servicesDir, "web.d"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	writeServiceFile(t, filepath.Join(servicesDir, "web.d"), "10-cmd.patch", "=command = /bin/web\n")
+	writeServiceFile(t, overlayDir, "web", "stop-timeout = 5\n")
+
+	sumsPath := filepath.Join(t.TempDir(), "checksums")
+	var sums bytes.Buffer
+	if err := GenerateChecksums(&sums, []string{servicesDir, overlayDir}, ""); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(sumsPath, sums.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	load := func() error {
+		ss := service.NewServiceSet(&testReloadLogger{})
+		loader := NewDirLoader(ss, []string{servicesDir})
+		loader.SetOverlayDirs([]string{overlayDir})
+		loader.SetChecksumFile(sumsPath)
+		ss.SetLoader(loader)
+		_, err := loader.LoadService("web")
+		return err
+	}
+	if err := load(); err != nil {
+		t.Fatalf("listed drop-ins should load: %v", err)
+	}
+
+	// Each file that feeds into the description is checked: changing
+	// any one of them, or adding an unlisted patch, fails the load.
+	for _, tc := range []struct{ dir, name, content string }{
+		{servicesDir, "web.override", "restart = no\n"},
+		{filepath.Join(servicesDir, "web.d"), "10-cmd.patch", "=command = /bin/evil\n"},
+		{filepath.Join(servicesDir, "web.d"), "20-new.patch", "=command = /bin/evil\n"},
+		{overlayDir, "web", "stop-timeout = 1\n"},
+	} {
+		path := filepath.Join(tc.dir, tc.name)
+		old, _ := os.ReadFile(path)
+		writeServiceFile(t, tc.dir, tc.name, tc.content)
+		if err := load(); err == nil || !strings.Contains(err.Error(), "integrity violation") {
+			t.Errorf("%s: expected integrity violation, got %v", path, err)
+		}
+		if old == nil {
+			os.Remove(path)
+		} else {
+			os.WriteFile(path, old, 0644)
+		}
+	}
+	if err := load(); err != nil {
+		t.Fatalf("restored drop-ins should load: %v", err)
+	}
+}
+
+func TestReadChecksumFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "sums")
+	hash := strings.Repeat("ab", 32)
+	content := "# comment\n\n" + hash + "  /etc/slinit.d/a\n" + strings.ToUpper(hash) + " *b\n"
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	sums, err := readChecksumFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sums["/etc/slinit.d/a"] != hash || sums[filepath.Join(dir, "b")] != hash {
+		t.Errorf("sums = %v", sums)
+	}
+
+	for _, bad := range []string{"nothash  /x\n", hash + "\n", strings.Repeat("zz", 32) + "  /x\n"} {
+		if err := os.WriteFile(path, []byte(bad), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := readChecksumFile(path); err == nil {
+			t.Errorf("%q should be rejected", bad)
+		}
+	}
+}
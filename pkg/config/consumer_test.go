@@ -214,3 +214,81 @@ func writeConsumerServiceFile(t *testing.T, dir, name, content string) {
 		t.Fatalf("failed to write service file: %v", err)
 	}
 }
+
+// Reloading a consumer as a different type carries its producer link
+// over; re-linking must not trip the "already has consumer" check on
+// the consumer's own old link.
+func TestConsumerOfReloadTypeChange(t *testing.T) {
+	dir := t.TempDir()
+	ss := service.NewServiceSet(&testConsumerLogger{})
+	loader := NewDirLoader(ss, []string{dir})
+	ss.SetLoader(loader)
+
+	writeConsumerServiceFile(t, dir, "producer", "type = process\ncommand = /bin/produce\nlog-type = pipe\n")
+	writeConsumerServiceFile(t, dir, "consumer", "type = process\ncommand = /bin/consume\nconsumer-of: producer\n")
+	consumer, err := loader.LoadService("consumer")
+	if err != nil {
+		t.Fatalf("load consumer failed: %v", err)
+	}
+
+	writeConsumerServiceFile(t, dir, "consumer", "type = scripted\ncommand = /bin/consume\nconsumer-of: producer\n")
+	newConsumer, err := loader.ReloadService(consumer)
+	if err != nil {
+		t.Fatalf("reload failed: %v", err)
+	}
+	producer := ss.FindService("producer", false)
+	if newConsumer.Record().ConsumerFor() != producer || producer.Record().LogConsumer() != newConsumer {
+		t.Error("producer and reloaded consumer should be linked")
+	}
+}
+
+// Dropping or changing consumer-of on a stopped service's reload must
+// release the old producer so another service can consume it.
+func TestConsumerOfReloadUnlinks(t *testing.T) {
+	dir := t.TempDir()
+	ss := service.NewServiceSet(&testConsumerLogger{})
+	loader := NewDirLoader(ss, []string{dir})
+	ss.SetLoader(loader)
+
+	writeConsumerServiceFile(t, dir, "producer", "type = process\ncommand = /bin/produce\nlog-type = pipe\n")
+	writeConsumerServiceFile(t, dir, "producer2", "type = process\ncommand = /bin/produce\nlog-type = pipe\n")
+	writeConsumerServiceFile(t, dir, "consumer", "type = process\ncommand = /bin/consume\nconsumer-of: producer\n")
+	consumer, err := loader.LoadService("consumer")
+	if err != nil {
+		t.Fatalf("load consumer failed: %v", err)
+	}
+	producer := ss.FindService("producer", false)
+
+	writeConsumerServiceFile(t, dir, "consumer", "type = process\ncommand = /bin/consume\nconsumer-of: producer2\n")
+	if _, err := loader.ReloadService(consumer); err != nil {
+		t.Fatalf("reload failed: %v", err)
+	}
+	producer2 := ss.FindService("producer2", false)
+	if producer.Record().LogConsumer() != nil {
+		t.Error("old producer should have no consumer after consumer-of changed")
+	}
+	if consumer.Record().ConsumerFor() != producer2 || producer2.Record().LogConsumer() != consumer {
+		t.Error("consumer should be linked to producer2")
+	}
+
+	writeConsumerServiceFile(t, dir, "consumer", "type = process\ncommand = /bin/consume\n")
+	if _, err := loader.ReloadService(consumer); err != nil {
+		t.Fatalf("reload failed: %v", err)
+	}
+	if consumer.Record().ConsumerFor() != nil || producer2.Record().LogConsumer() != nil {
+		t.Error("consumer-of link should be gone after it was removed from the description")
+	}
+
+	// A failed reload puts the previous link back.
+	writeConsumerServiceFile(t, dir, "consumer", "type = process\ncommand = /bin/consume\nconsumer-of: producer\n")
+	if _, err := loader.ReloadService(consumer); err != nil {
+		t.Fatalf("reload failed: %v", err)
+	}
+	writeConsumerServiceFile(t, dir, "consumer", "type = process\ncommand = /bin/consume\nconsumer-of: no-such-producer\n")
+	if _, err := loader.ReloadService(consumer); err == nil {
+		t.Fatal("expected reload to fail on unknown producer")
+	}
+	if consumer.Record().ConsumerFor() != producer || producer.Record().LogConsumer() != consumer {
+		t.Error("failed reload should restore the link to producer")
+	}
+}
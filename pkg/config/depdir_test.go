@@ -0,0 +1,112 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/sunlightlinux/slinit/pkg/service"
+)
+
+// depNames returns the sorted names of svc's dependencies.
+func depNames(svc service.Service) string {
+	var names []string
+	for _, d := range svc.Record().Dependencies() {
+		names = append(names, d.To.Name())
+	}
+	sort.Strings(names)
+	return strings.Join(names, " ")
+}
+
+// touchDeps creates empty marker files in dir, as a package manager
+// would drop into a wants directory.
+func touchDeps(t *testing.T, dir string, names ...string) {
+	t.Helper()
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	for _, n := range names {
+		if err := os.WriteFile(filepath.Join(dir, n), nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func newDepDirLoader(t *testing.T, services ...string) (string, *DirLoader) {
+	t.Helper()
+	dir := t.TempDir()
+	ss := service.NewServiceSet(&testReloadLogger{})
+	loader := NewDirLoader(ss, []string{dir})
+	ss.SetLoader(loader)
+	for _, name := range services {
+		writeServiceFile(t, dir, name, "type = internal\n")
+	}
+	return dir, loader
+}
+
+func TestDependsOnDGlob(t *testing.T) {
+	dir, loader := newDepDirLoader(t, "net.service", "dns.service", "notes")
+	wants := filepath.Join(dir, "network.wants")
+	touchDeps(t, wants, "net.service", "dns.service", "notes", ".hidden.service")
+	writeServiceFile(t, dir, "main", "type = internal\ndepends-on.d: "+wants+"/*.service\n")
+
+	svc, err := loader.LoadService("main")
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if got := depNames(svc); got != "dns.service net.service" {
+		t.Errorf("deps = %q, want the .service matches only", got)
+	}
+}
+
+func TestDependsOnDExclusion(t *testing.T) {
+	dir, loader := newDepDirLoader(t, "sshd", "legacy-inetd", "legacy-portmap", "cron")
+	touchDeps(t, filepath.Join(dir, "wanted.d"), "sshd", "legacy-inetd", "legacy-portmap", "cron")
+
+	// A lone exclusion stands for the rest of its directory; the
+	// relative entry is taken from the service file's directory.
+	writeServiceFile(t, dir, "main", "type = internal\nwaits-for.d: wanted.d/!legacy-*\n")
+	svc, err := loader.LoadService("main")
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if got := depNames(svc); got != "cron sshd" {
+		t.Errorf("deps = %q, want cron sshd", got)
+	}
+	for _, d := range svc.Record().Dependencies() {
+		if d.DepType != service.DepWaitsFor {
+			t.Errorf("dep %s has type %v, want waits-for", d.To.Name(), d.DepType)
+		}
+	}
+}
+
+func TestDependsOnDExclusionWithGlob(t *testing.T) {
+	dir, loader := newDepDirLoader(t, "a.service", "b.service", "c.service")
+	wants := filepath.Join(dir, "wants")
+	touchDeps(t, wants, "a.service", "b.service", "c.service")
+	writeServiceFile(t, dir, "main",
+		"type = internal\ndepends-on.d: "+wants+"/*.service\ndepends-on.d: "+wants+"/!b.*\n")
+
+	svc, err := loader.LoadService("main")
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if got := depNames(svc); got != "a.service c.service" {
+		t.Errorf("deps = %q, want a.service c.service", got)
+	}
+}
+
+func TestDependsOnDGlobNoMatch(t *testing.T) {
+	dir, loader := newDepDirLoader(t)
+	writeServiceFile(t, dir, "main", "type = internal\ndepends-on.d: "+dir+"/missing/*.service\n")
+
+	svc, err := loader.LoadService("main")
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if got := depNames(svc); got != "" {
+		t.Errorf("deps = %q, want none", got)
+	}
+}
@@ -0,0 +1,127 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// ValidateDependencies reports the named dependencies of desc that
+// cannot be loaded: the bare name of a dependency no service directory
+// has a description for, or "name: error" for one whose description
+// fails to load. Each dependency is loaded to find out, so on success it
+// ends up in the service set just as it would on a normal load. Optional
+// ordering hints (before-optional / after-optional) are not checked,
+// since the loader drops them when their target is missing.
+func (dl *DirLoader) ValidateDependencies(desc *ServiceDescription) []string {
+	return dl.validateDeps(desc, false, false)
+}
+
+// ValidateDependencyTree checks the dependencies of every service
+// reachable from roots and returns one "service: problem" entry for each
+// dependency ValidateDependencies would report. With includeOptional
+// set, optional ordering hints that name a missing service are reported
+// too (slinit --require-all-deps). Roots that cannot be described are
+// skipped; loading them reports the error.
+func (dl *DirLoader) ValidateDependencyTree(roots []string, includeOptional bool) []string {
+	var problems []string
+	seen := make(map[string]bool)
+	queue := append([]string(nil), roots...)
+	for len(queue) > 0 {
+		name := queue[0]
+		queue = queue[1:]
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+
+		desc, err := dl.describe(name)
+		if err != nil {
+			continue
+		}
+		for _, p := range dl.validateDeps(desc, includeOptional, true) {
+			problems = append(problems, name+": "+p)
+		}
+		queue = append(queue, namedDeps(desc, includeOptional)...)
+	}
+	sort.Strings(problems)
+	return problems
+}
+
+// validateDeps loads each named dependency of desc and describes the
+// ones that fail. With inTree set, a dependency that only fails because
+// something further down is missing is not reported: the tree walk
+// reports the missing service where it is named.
+func (dl *DirLoader) validateDeps(desc *ServiceDescription, includeOptional, inTree bool) []string {
+	var problems []string
+	seen := make(map[string]bool)
+	for _, name := range namedDeps(desc, includeOptional) {
+		if seen[name] || dl.set.FindService(name, false) != nil {
+			continue
+		}
+		seen[name] = true
+		if _, err := dl.loadDep(name); err != nil {
+			switch {
+			case isNotFound(err, name):
+				problems = append(problems, name)
+			case inTree && errors.Is(err, ErrServiceNotFound):
+			default:
+				problems = append(problems, fmt.Sprintf("%s: %v", name, err))
+			}
+		}
+	}
+	return problems
+}
+
+// namedDeps lists the services desc names as dependencies, in setting
+// order. Directory dependencies are left out: their entries are files
+// that exist by construction.
+func namedDeps(desc *ServiceDescription, includeOptional bool) []string {
+	lists := [][]string{
+		desc.DependsOn, desc.DependsMS, desc.WaitsFor, desc.PreparedBy,
+		desc.Before, desc.After,
+	}
+	if includeOptional {
+		lists = append(lists, desc.BeforeOptional, desc.AfterOptional)
+	}
+	var names []string
+	for _, l := range lists {
+		names = append(names, l...)
+	}
+	return names
+}
+
+// isNotFound reports whether err says that name itself has no
+// description, as opposed to name failing to load because one of its
+// own dependencies is missing.
+func isNotFound(err error, name string) bool {
+	var le *ServiceLoadError
+	return errors.Is(err, ErrServiceNotFound) && errors.As(err, &le) && le.ServiceName == name
+}
+
+// describe finds and parses the description of name without loading
+// it, through the composite loader when dl is part of one so embedded
+// descriptions are found too.
+func (dl *DirLoader) describe(name string) (*ServiceDescription, error) {
+	cl, ok := dl.deps.(*CompositeLoader)
+	if !ok {
+		desc, _, err := dl.findDesc(name)
+		return desc, err
+	}
+	for _, l := range cl.loaders {
+		var member *DirLoader
+		switch l := l.(type) {
+		case *DirLoader:
+			member = l
+		case *EmbeddedLoader:
+			member = l.DirLoader
+		default:
+			continue
+		}
+		desc, _, err := member.findDesc(name)
+		if err == nil || !errors.Is(err, ErrServiceNotFound) {
+			return desc, err
+		}
+	}
+	return nil, fmt.Errorf("%s: %w", name, ErrServiceNotFound)
+}
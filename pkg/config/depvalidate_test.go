@@ -0,0 +1,45 @@
+package config
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestValidateDependencies(t *testing.T) {
+	dir, loader := newDepDirLoader(t, "present")
+	writeServiceFile(t, dir, "broken", "type = internal\nbogus-setting = 1\n")
+	writeServiceFile(t, dir, "main", "type = internal\n"+
+		"depends-on: present\ndepends-on: missing\nwaits-for: broken\nafter: gone\n")
+
+	desc, _, err := loader.findDesc("main")
+	if err != nil {
+		t.Fatal(err)
+	}
+	desc.BeforeOptional = []string{"not-installed"}
+
+	got := loader.ValidateDependencies(desc)
+	if len(got) != 3 || got[0] != "missing" || got[2] != "gone" {
+		t.Fatalf("ValidateDependencies = %q, want missing, broken, gone", got)
+	}
+	if !strings.HasPrefix(got[1], "broken: ") || !strings.Contains(got[1], "bogus-setting") {
+		t.Errorf("broken dependency reported as %q, want its parse error", got[1])
+	}
+	if loader.set.FindService("present", false) == nil {
+		t.Error("existing dependency was not loaded")
+	}
+}
+
+func TestValidateDependencyTree(t *testing.T) {
+	dir, loader := newDepDirLoader(t, "leaf")
+	writeServiceFile(t, dir, "boot", "type = internal\nwaits-for: mid\n")
+	writeServiceFile(t, dir, "mid", "type = internal\ndepends-on: leaf\nafter: nowhere\n")
+
+	want := []string{"mid: nowhere"}
+	if got := loader.ValidateDependencyTree([]string{"boot"}, false); !reflect.DeepEqual(got, want) {
+		t.Errorf("tree = %q, want %q", got, want)
+	}
+	if got := loader.ValidateDependencyTree([]string{"leaf"}, true); len(got) != 0 {
+		t.Errorf("leaf has no dependencies, got %q", got)
+	}
+}
@@ -0,0 +1,183 @@
+package config
+
+import (
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"strings"
+
+	"github.com/sunlightlinux/slinit/pkg/service"
+)
+
+// embeddedServices holds the default service descriptions compiled into
+// the binary. They are the lowest-priority source: any file of the same
+// name in a service directory takes precedence.
+//
+//go:embed services/*.slinit
+var embeddedServices embed.FS
+
+// embeddedDir is the directory inside the embedded FS holding descriptions.
+const embeddedDir = "services"
+
+// embeddedExt is the file extension used by embedded descriptions.
+const embeddedExt = ".slinit"
+
+// EmbeddedLoader loads service descriptions from an fs.FS (by default the
+// set compiled into the binary). Descriptions are looked up as
+// services/<name>.slinit, falling back to services/<base>.slinit for
+// template instances (name@arg). Loaded services are tagged as embedded.
+type EmbeddedLoader struct {
+	*DirLoader
+	fsys fs.FS
+}
+
+// NewEmbeddedLoader creates a loader over fsys. A nil fsys selects the
+// descriptions compiled into the binary.
+func NewEmbeddedLoader(set *service.ServiceSet, fsys fs.FS) *EmbeddedLoader {
+	if fsys == nil {
+		fsys = embeddedServices
+	}
+	el := &EmbeddedLoader{
+		DirLoader: &DirLoader{
+			set:      set,
+			loading:  make(map[string]bool),
+			embedded: true,
+		},
+		fsys: fsys,
+	}
+	el.DirLoader.find = el.findAndParse
+	return el
+}
+
+// ServiceDirs returns nil: embedded descriptions live in no directory.
+func (el *EmbeddedLoader) ServiceDirs() []string {
+	return nil
+}
+
+func (el *EmbeddedLoader) findAndParse(name string) (*ServiceDescription, string, error) {
+	var serviceArg *string
+	if _, arg, ok := SplitInstance(name); ok {
+		serviceArg = &arg
+	}
+
+	for _, sn := range TemplateFileNames(name) {
+		// Reject anything that could escape the services directory.
+		if strings.ContainsAny(sn, "/\\") || sn == "." || sn == ".." {
+			break
+		}
+		p := path.Join(embeddedDir, sn+embeddedExt)
+		f, err := el.fsys.Open(p)
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				continue
+			}
+			return nil, "", &ServiceLoadError{
+				ServiceName: name,
+				Message:     fmt.Sprintf("error reading embedded %s: %v", p, err),
+			}
+		}
+
+		filePath := "embedded:" + p
+		var desc *ServiceDescription
+		if serviceArg != nil {
+			desc, err = ParseWithArg(f, name, filePath, *serviceArg)
+		} else {
+			desc, err = Parse(f, name, filePath)
+		}
+		f.Close()
+		if err != nil {
+			return nil, "", err
+		}
+		return desc, filePath, nil
+	}
+
+	return nil, "", &ServiceLoadError{
+		ServiceName: name,
+		Message:     "service description not found",
+		Err:         ErrServiceNotFound,
+	}
+}
+
+// CompositeLoader chains several loaders in priority order. A name is
+// resolved by the first loader that has a description for it; only a
+// "not found" result moves on to the next loader, so parse errors in a
+// higher-priority source are never masked by a lower one.
+type CompositeLoader struct {
+	loaders []service.ServiceLoader
+}
+
+// NewCompositeLoader creates a loader trying each of loaders in order.
+// Member DirLoaders (and EmbeddedLoaders) resolve their dependencies
+// through the composite, so a directory service may depend on an
+// embedded one and vice versa.
+func NewCompositeLoader(loaders ...service.ServiceLoader) *CompositeLoader {
+	cl := &CompositeLoader{loaders: loaders}
+	for _, l := range loaders {
+		switch dl := l.(type) {
+		case *DirLoader:
+			dl.deps = cl
+		case *EmbeddedLoader:
+			dl.deps = cl
+		}
+	}
+	return cl
+}
+
+// LoadService loads name from the first loader that has it.
+func (cl *CompositeLoader) LoadService(name string) (service.Service, error) {
+	var lastErr error
+	for _, l := range cl.loaders {
+		svc, err := l.LoadService(name)
+		if err == nil {
+			return svc, nil
+		}
+		if !errors.Is(err, ErrServiceNotFound) {
+			return nil, err
+		}
+		lastErr = err
+	}
+	if lastErr == nil {
+		lastErr = &ServiceLoadError{
+			ServiceName: name,
+			Message:     "service description not found",
+			Err:         ErrServiceNotFound,
+		}
+	}
+	return nil, lastErr
+}
+
+// ReloadService reloads svc from the first loader that has a description
+// for it. A service originally loaded from the embedded set is picked up
+// from a service directory if a file for it has appeared since.
+func (cl *CompositeLoader) ReloadService(svc service.Service) (service.Service, error) {
+	var lastErr error
+	for _, l := range cl.loaders {
+		nsvc, err := l.ReloadService(svc)
+		if err == nil {
+			return nsvc, nil
+		}
+		if !errors.Is(err, ErrServiceNotFound) {
+			return nil, err
+		}
+		lastErr = err
+	}
+	if lastErr == nil {
+		lastErr = &ServiceLoadError{
+			ServiceName: svc.Name(),
+			Message:     "service description not found",
+			Err:         ErrServiceNotFound,
+		}
+	}
+	return nil, lastErr
+}
+
+// ServiceDirs returns the service directories of all member loaders.
+func (cl *CompositeLoader) ServiceDirs() []string {
+	var dirs []string
+	for _, l := range cl.loaders {
+		dirs = append(dirs, l.ServiceDirs()...)
+	}
+	return dirs
+}
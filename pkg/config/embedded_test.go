@@ -0,0 +1,121 @@
+package config
+
+import (
+	"errors"
+	"testing"
+	"testing/fstest"
+
+	"github.com/sunlightlinux/slinit/pkg/service"
+)
+
+func newCompositeForTest(t *testing.T, fsys fstest.MapFS) (*service.ServiceSet, string, *CompositeLoader) {
+	t.Helper()
+	servicesDir := t.TempDir()
+	ss := service.NewServiceSet(&testReloadLogger{})
+	dl := NewDirLoader(ss, []string{servicesDir})
+	dl.SetOverlayDirs(nil)
+	cl := NewCompositeLoader(dl, NewEmbeddedLoader(ss, fsys))
+	ss.SetLoader(cl)
+	return ss, servicesDir, cl
+}
+
+// TestCompositeLoaderFallsBackToEmbedded verifies that a name missing from
+// every service directory is served from the embedded set and tagged.
+func TestCompositeLoaderFallsBackToEmbedded(t *testing.T) {
+	_, _, cl := newCompositeForTest(t, fstest.MapFS{
+		"services/base.slinit": {Data: []byte("type = internal\ndescription = from embed\n")},
+	})
+
+	svc, err := cl.LoadService("base")
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if !svc.Record().Embedded() {
+		t.Error("expected service to be marked embedded")
+	}
+	if svc.Record().Description() != "from embed" {
+		t.Errorf("description: got %q", svc.Record().Description())
+	}
+	if svc.Record().ServiceDir() != "" {
+		t.Errorf("embedded service should have no dir, got %q", svc.Record().ServiceDir())
+	}
+}
+
+// TestCompositeLoaderDirOverridesEmbedded verifies embedded descriptions
+// have the lowest priority.
+func TestCompositeLoaderDirOverridesEmbedded(t *testing.T) {
+	_, dir, cl := newCompositeForTest(t, fstest.MapFS{
+		"services/base.slinit": {Data: []byte("type = internal\ndescription = from embed\n")},
+	})
+	writeServiceFile(t, dir, "base", "type = internal\ndescription = from dir\n")
+
+	svc, err := cl.LoadService("base")
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if svc.Record().Embedded() {
+		t.Error("directory service must not be marked embedded")
+	}
+	if svc.Record().Description() != "from dir" {
+		t.Errorf("description: got %q", svc.Record().Description())
+	}
+}
+
+// TestCompositeLoaderCrossSourceDependency verifies that a directory
+// service can depend on a service only present in the embedded set.
+func TestCompositeLoaderCrossSourceDependency(t *testing.T) {
+	ss, dir, cl := newCompositeForTest(t, fstest.MapFS{
+		"services/base.slinit": {Data: []byte("type = internal\n")},
+	})
+	writeServiceFile(t, dir, "app", "type = internal\ndepends-on: base\n")
+
+	if _, err := cl.LoadService("app"); err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	base := ss.FindService("base", false)
+	if base == nil {
+		t.Fatal("dependency was not loaded")
+	}
+	if !base.Record().Embedded() {
+		t.Error("dependency should come from the embedded set")
+	}
+}
+
+// TestCompositeLoaderTemplate verifies template instances resolve against
+// the base name in the embedded set.
+func TestCompositeLoaderTemplate(t *testing.T) {
+	_, _, cl := newCompositeForTest(t, fstest.MapFS{
+		"services/getty.slinit": {Data: []byte("type = internal\ndescription = getty\n")},
+	})
+
+	svc, err := cl.LoadService("getty@tty1")
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if svc.Name() != "getty@tty1" || !svc.Record().Embedded() {
+		t.Errorf("got %q (embedded=%v)", svc.Name(), svc.Record().Embedded())
+	}
+}
+
+// TestCompositeLoaderNotFound verifies a missing name reports
+// ErrServiceNotFound once every member has been tried.
+func TestCompositeLoaderNotFound(t *testing.T) {
+	_, _, cl := newCompositeForTest(t, fstest.MapFS{})
+
+	_, err := cl.LoadService("missing")
+	if !errors.Is(err, ErrServiceNotFound) {
+		t.Fatalf("expected ErrServiceNotFound, got %v", err)
+	}
+}
+
+// TestEmbeddedDefaultSet verifies the descriptions compiled into the
+// binary parse cleanly.
+func TestEmbeddedDefaultSet(t *testing.T) {
+	ss := service.NewServiceSet(&testReloadLogger{})
+	el := NewEmbeddedLoader(ss, nil)
+	ss.SetLoader(el)
+
+	if _, err := el.LoadService("null"); err != nil {
+		t.Fatalf("load embedded null: %v", err)
+	}
+}
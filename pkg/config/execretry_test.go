@@ -0,0 +1,41 @@
+package config
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseExecRetry(t *testing.T) {
+	input := "type = process\ncommand = /bin/true\nexec-retry-count = 3\nexec-retry-interval = 100ms\n"
+	desc, err := Parse(strings.NewReader(input), "svc", "test")
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if desc.ExecRetryCount != 3 {
+		t.Errorf("ExecRetryCount: got %d want 3", desc.ExecRetryCount)
+	}
+	if desc.ExecRetryInterval != 100*time.Millisecond {
+		t.Errorf("ExecRetryInterval: got %v want 100ms", desc.ExecRetryInterval)
+	}
+}
+
+func TestParseExecRetryIntervalSeconds(t *testing.T) {
+	input := "type = process\ncommand = /bin/true\nexec-retry-interval = 0.5\n"
+	desc, err := Parse(strings.NewReader(input), "svc", "test")
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if desc.ExecRetryInterval != 500*time.Millisecond {
+		t.Errorf("ExecRetryInterval: got %v want 500ms", desc.ExecRetryInterval)
+	}
+}
+
+func TestParseExecRetryCountBadValue(t *testing.T) {
+	for _, v := range []string{"0", "-1", "many"} {
+		input := "type = process\ncommand = /bin/true\nexec-retry-count = " + v + "\n"
+		if _, err := Parse(strings.NewReader(input), "svc", "test"); err == nil {
+			t.Errorf("expected error for exec-retry-count = %s", v)
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/sunlightlinux/slinit/pkg/service"
+)
+
+func TestLoaderWaitsForExclusiveLock(t *testing.T) {
+	defer func(d time.Duration) { sharedLockWait = d }(sharedLockWait)
+	sharedLockWait = 100 * time.Millisecond
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app")
+	if err := os.WriteFile(path, []byte("type = internal\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	set := service.NewServiceSet(&testReloadLogger{})
+	dl := NewDirLoader(set, []string{dir})
+
+	held, err := LockServiceFileExclusive(path)
+	if err != nil {
+		t.Fatalf("LockServiceFileExclusive: %v", err)
+	}
+	if _, err := LockServiceFileExclusive(path); !errors.Is(err, ErrServiceFileLocked) {
+		t.Errorf("second exclusive lock: err = %v, want ErrServiceFileLocked", err)
+	}
+	if _, _, err := dl.findAndParse("app"); !errors.Is(err, ErrServiceFileLocked) {
+		t.Errorf("load while locked: err = %v, want ErrServiceFileLocked", err)
+	}
+
+	// A holder that lets go within the wait is simply waited for.
+	go func() {
+		time.Sleep(30 * time.Millisecond)
+		held.Close()
+	}()
+	if _, _, err := dl.findAndParse("app"); err != nil {
+		t.Errorf("load after unlock: %v", err)
+	}
+}
+
+func TestLoaderIgnoresLocksWhenDisabled(t *testing.T) {
+	defer SetFileLocking(true)
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app")
+	if err := os.WriteFile(path, []byte("type = internal\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	held, err := LockServiceFileExclusive(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer held.Close()
+
+	SetFileLocking(false)
+	dl := NewDirLoader(service.NewServiceSet(&testReloadLogger{}), []string{dir})
+	if _, _, err := dl.findAndParse("app"); err != nil {
+		t.Errorf("load with locking disabled: %v", err)
+	}
+}
+
+func TestFindServiceFile(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"getty", "db"} {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"db", filepath.Join(dir, "db")},
+		{"getty@tty1", filepath.Join(dir, "getty")},
+		{"missing", ""},
+		{"sub/../../etc/passwd", ""},
+	}
+	for _, tt := range tests {
+		got, err := FindServiceFile([]string{dir}, tt.name)
+		if got != tt.want || (tt.want == "") != (err != nil) {
+			t.Errorf("FindServiceFile(%q) = %q, %v; want %q", tt.name, got, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,340 @@
+package config
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// ProcessSnapshot is what ReadProcessSnapshot learned about a running
+// process from /proc. Fields that could not be read are left empty.
+type ProcessSnapshot struct {
+	PID        int
+	Name       string   // comm, e.g. "nginx"
+	Command    []string // argv; argv[0] replaced by /proc/<pid>/exe when relative
+	ExeUsed    bool     // argv[0] came from /proc/<pid>/exe
+	WorkingDir string
+	Env        []string
+	UID        uint32 // effective
+	GID        uint32 // effective
+	EnvFile    string // env-file the process still has open, if any
+}
+
+// envFileDirs are directories whose files are conventionally env files.
+var envFileDirs = []string{"/etc/default", "/etc/sysconfig", "/etc/conf.d"}
+
+// ReadProcessSnapshot reads cmdline, environ, cwd, status and open fds
+// of pid under procRoot (normally "/proc"). Only a missing or empty
+// cmdline is an error: the rest depends on permissions and is
+// best-effort.
+func ReadProcessSnapshot(procRoot string, pid int) (*ProcessSnapshot, error) {
+	dir := filepath.Join(procRoot, strconv.Itoa(pid))
+	cmdline, err := os.ReadFile(filepath.Join(dir, "cmdline"))
+	if err != nil {
+		return nil, err
+	}
+	argv := splitNUL(cmdline)
+	if len(argv) == 0 {
+		return nil, fmt.Errorf("pid %d has no command line (kernel thread or zombie?)", pid)
+	}
+
+	snap := &ProcessSnapshot{PID: pid, Command: argv}
+	if comm, err := os.ReadFile(filepath.Join(dir, "comm")); err == nil {
+		snap.Name = strings.TrimSpace(string(comm))
+	}
+	if !filepath.IsAbs(argv[0]) {
+		if exe, err := os.Readlink(filepath.Join(dir, "exe")); err == nil {
+			snap.Command[0] = strings.TrimSuffix(exe, " (deleted)")
+			snap.ExeUsed = true
+		}
+	}
+	if cwd, err := os.Readlink(filepath.Join(dir, "cwd")); err == nil {
+		snap.WorkingDir = cwd
+	}
+	if environ, err := os.ReadFile(filepath.Join(dir, "environ")); err == nil {
+		snap.Env = splitNUL(environ)
+	}
+	snap.UID, snap.GID = readStatusIDs(filepath.Join(dir, "status"))
+	snap.EnvFile = findOpenEnvFile(filepath.Join(dir, "fd"))
+	return snap, nil
+}
+
+// splitNUL splits a NUL-separated /proc record, dropping the trailing
+// empty element.
+func splitNUL(b []byte) []string {
+	s := strings.TrimRight(string(b), "\x00")
+	if s == "" {
+		return nil
+	}
+	return strings.Split(s, "\x00")
+}
+
+// readStatusIDs returns the effective UID and GID from a status file.
+func readStatusIDs(path string) (uid, gid uint32) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, 0
+	}
+	defer f.Close()
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		key, rest, ok := strings.Cut(sc.Text(), ":")
+		if !ok || (key != "Uid" && key != "Gid") {
+			continue
+		}
+		// real, effective, saved, filesystem
+		ids := strings.Fields(rest)
+		if len(ids) < 2 {
+			continue
+		}
+		n, err := strconv.ParseUint(ids[1], 10, 32)
+		if err != nil {
+			continue
+		}
+		if key == "Uid" {
+			uid = uint32(n)
+		} else {
+			gid = uint32(n)
+		}
+	}
+	return uid, gid
+}
+
+// findOpenEnvFile looks through a process's open fds for a file that
+// looks like an env file: "*.env", ".env", or anything under
+// /etc/default, /etc/sysconfig or /etc/conf.d. Most programs close their
+// env file after reading it, so an empty result is common.
+func findOpenEnvFile(fdDir string) string {
+	entries, err := os.ReadDir(fdDir)
+	if err != nil {
+		return ""
+	}
+	for _, e := range entries {
+		target, err := os.Readlink(filepath.Join(fdDir, e.Name()))
+		if err != nil || !filepath.IsAbs(target) {
+			continue // pipes, sockets, anon inodes
+		}
+		if isEnvFilePath(target) {
+			return target
+		}
+	}
+	return ""
+}
+
+func isEnvFilePath(path string) bool {
+	if strings.HasSuffix(path, ".env") {
+		return true
+	}
+	for _, d := range envFileDirs {
+		if filepath.Dir(path) == d {
+			return true
+		}
+	}
+	return false
+}
+
+// noisyEnvVars are set by shells and session managers rather than the
+// service, so they are left out of the captured environment listing.
+var noisyEnvVars = map[string]bool{
+	"_": true, "PWD": true, "OLDPWD": true, "SHLVL": true,
+	"TERM": true, "LS_COLORS": true, "SSH_TTY": true, "SSH_CONNECTION": true,
+	"SSH_CLIENT": true, "MAIL": true, "LOGNAME": true, "HOME": true,
+	"USER": true, "SHELL": true,
+}
+
+// generatedSetting is one setting of a generated service file.
+type generatedSetting struct {
+	comment []string
+	key     string
+	value   string   // scalar value
+	list    []string // command-style value (TOML array)
+}
+
+// WriteGeneratedService renders snap as a type = process service
+// description with comments that say where each value came from.
+// format is "" or "slinit" for the native format, or "toml".
+func WriteGeneratedService(w io.Writer, snap *ProcessSnapshot, format string) error {
+	if format != "" && format != "slinit" && format != "toml" {
+		return fmt.Errorf("unknown format %q (use slinit|toml)", format)
+	}
+	toml := format == "toml"
+	proc := fmt.Sprintf("/proc/%d", snap.PID)
+
+	settings := []generatedSetting{{
+		comment: []string{"Run the command in the foreground and supervise it (default; adjust if it daemonizes)."},
+		key:     "type",
+		value:   "process",
+	}}
+
+	cmdComment := fmt.Sprintf("Command line, inferred from %s/cmdline.", proc)
+	if snap.ExeUsed {
+		cmdComment = fmt.Sprintf("Command line, inferred from %s/cmdline; the program path from %s/exe.", proc, proc)
+	}
+	settings = append(settings, generatedSetting{
+		comment: []string{cmdComment},
+		key:     "command",
+		value:   joinCommandLine(snap.Command),
+		list:    snap.Command,
+	})
+
+	if snap.WorkingDir != "" {
+		settings = append(settings, generatedSetting{
+			comment: []string{fmt.Sprintf("Working directory, inferred from %s/cwd.", proc)},
+			key:     "working-dir",
+			value:   snap.WorkingDir,
+		})
+	}
+
+	if snap.UID != 0 {
+		settings = append(settings, generatedSetting{
+			comment: []string{fmt.Sprintf("User (and group) to run as, inferred from the effective IDs in %s/status.", proc)},
+			key:     "run-as",
+			value:   runAsSpec(snap.UID, snap.GID),
+		})
+	}
+
+	if snap.EnvFile != "" {
+		settings = append(settings, generatedSetting{
+			comment: []string{fmt.Sprintf("Environment file, inferred from a file the process has open (%s/fd).", proc)},
+			key:     "env-file",
+			value:   snap.EnvFile,
+		})
+	}
+
+	var b strings.Builder
+	name := snap.Name
+	if name == "" {
+		name = filepath.Base(snap.Command[0])
+	}
+	fmt.Fprintf(&b, "# Generated by slinitctl generate-unit from PID %d (%s).\n", snap.PID, name)
+	b.WriteString("# Review before use: \"inferred\" values were read from the running\n")
+	b.WriteString("# process, everything else is slinit's default.\n")
+	if toml {
+		b.WriteString("# TOML rendering for external tooling; slinit itself reads the\n")
+		b.WriteString("# native key = value format.\n")
+	}
+	for _, s := range settings {
+		b.WriteString("\n")
+		for _, c := range s.comment {
+			fmt.Fprintf(&b, "# %s\n", c)
+		}
+		switch {
+		case toml && s.list != nil:
+			quoted := make([]string, len(s.list))
+			for i, a := range s.list {
+				quoted[i] = tomlString(a)
+			}
+			fmt.Fprintf(&b, "%s = [%s]\n", s.key, strings.Join(quoted, ", "))
+		case toml:
+			fmt.Fprintf(&b, "%s = %s\n", s.key, tomlString(s.value))
+		default:
+			fmt.Fprintf(&b, "%s = %s\n", s.key, s.value)
+		}
+	}
+
+	b.WriteString("\n# Not observable from a running process; defaults apply:\n")
+	b.WriteString("#   restart = no, stop-timeout = 10, no dependencies.\n")
+	if snap.UID == 0 {
+		b.WriteString("#   run-as is unset: the process ran as root.\n")
+	}
+
+	if env := serviceEnv(snap.Env); len(env) > 0 && snap.EnvFile == "" {
+		b.WriteString("\n# Environment at capture time (no env-file detected). Move what\n")
+		b.WriteString("# the service needs into an env-file and set env-file above.\n")
+		for _, kv := range env {
+			fmt.Fprintf(&b, "#   %s\n", kv)
+		}
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// secretEnvMarkers flag variables whose values must not be copied into
+// a generated file.
+var secretEnvMarkers = []string{"KEY", "TOKEN", "SECRET", "PASSWORD", "PASSWD", "CREDENTIAL"}
+
+// serviceEnv drops shell/session variables from a captured environment
+// and redacts the values of ones that look like credentials.
+func serviceEnv(env []string) []string {
+	var out []string
+	for _, kv := range env {
+		k, _, _ := strings.Cut(kv, "=")
+		if noisyEnvVars[k] {
+			continue
+		}
+		upper := strings.ToUpper(k)
+		for _, m := range secretEnvMarkers {
+			if strings.Contains(upper, m) {
+				kv = k + "=<redacted>"
+				break
+			}
+		}
+		out = append(out, kv)
+	}
+	return out
+}
+
+// runAsSpec renders uid/gid as a run-as value, preferring names. The
+// group is only given when it is not the user's primary group.
+func runAsSpec(uid, gid uint32) string {
+	uidStr := strconv.FormatUint(uint64(uid), 10)
+	gidStr := strconv.FormatUint(uint64(gid), 10)
+	spec := uidStr
+	primary := ""
+	if u, err := user.LookupId(uidStr); err == nil {
+		spec = u.Username
+		primary = u.Gid
+	}
+	if primary == gidStr {
+		return spec
+	}
+	group := gidStr
+	if g, err := user.LookupGroupId(gidStr); err == nil {
+		group = g.Name
+	}
+	return spec + ":" + group
+}
+
+// joinCommandLine quotes each argument that the service file parser
+// would otherwise split or unescape.
+func joinCommandLine(args []string) string {
+	quoted := make([]string, len(args))
+	r := strings.NewReplacer(`\`, `\\`, `"`, `\"`)
+	for i, a := range args {
+		if a != "" && !strings.ContainsAny(a, " \t\"'\\") {
+			quoted[i] = a
+			continue
+		}
+		quoted[i] = `"` + r.Replace(a) + `"`
+	}
+	return strings.Join(quoted, " ")
+}
+
+// tomlString quotes s as a TOML basic string.
+func tomlString(s string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for _, r := range s {
+		switch {
+		case r == '"' || r == '\\':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r == '\n':
+			b.WriteString(`\n`)
+		case r == '\t':
+			b.WriteString(`\t`)
+		case r < 0x20 || r == 0x7f:
+			fmt.Fprintf(&b, `\u%04X`, r)
+		default:
+			b.WriteRune(r)
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
@@ -0,0 +1,138 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// fakeProc builds a minimal /proc/<pid> tree under a temp dir.
+func fakeProc(t *testing.T, pid string, files map[string]string, links map[string]string) string {
+	t.Helper()
+	root := t.TempDir()
+	dir := filepath.Join(root, pid)
+	if err := os.MkdirAll(filepath.Join(dir, "fd"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for name, target := range links {
+		if err := os.Symlink(target, filepath.Join(dir, name)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return root
+}
+
+func TestReadProcessSnapshot(t *testing.T) {
+	root := fakeProc(t, "42", map[string]string{
+		"cmdline": "myd\x00--listen\x00:8080\x00--name\x00a b\x00",
+		"comm":    "myd\n",
+		"environ": "PORT=8080\x00HOME=/root\x00MODE=prod\x00",
+		"status":  "Name:\tmyd\nUid:\t0\t1000\t1000\t1000\nGid:\t0\t1001\t1001\t1001\n",
+	}, map[string]string{
+		"exe":  "/usr/local/bin/myd",
+		"cwd":  "/srv/myd",
+		"fd/0": "/dev/null",
+		"fd/3": "socket:[1234]",
+		"fd/4": "/etc/default/myd",
+	})
+
+	snap, err := ReadProcessSnapshot(root, 42)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"/usr/local/bin/myd", "--listen", ":8080", "--name", "a b"}
+	if strings.Join(snap.Command, "|") != strings.Join(want, "|") || !snap.ExeUsed {
+		t.Errorf("Command = %q (exe used %v), want %q", snap.Command, snap.ExeUsed, want)
+	}
+	if snap.Name != "myd" || snap.WorkingDir != "/srv/myd" {
+		t.Errorf("Name/WorkingDir = %q/%q", snap.Name, snap.WorkingDir)
+	}
+	if snap.UID != 1000 || snap.GID != 1001 {
+		t.Errorf("UID/GID = %d/%d, want effective 1000/1001", snap.UID, snap.GID)
+	}
+	if snap.EnvFile != "/etc/default/myd" {
+		t.Errorf("EnvFile = %q", snap.EnvFile)
+	}
+	if len(snap.Env) != 3 {
+		t.Errorf("Env = %q", snap.Env)
+	}
+}
+
+func TestReadProcessSnapshotKernelThread(t *testing.T) {
+	root := fakeProc(t, "2", map[string]string{"cmdline": ""}, nil)
+	if _, err := ReadProcessSnapshot(root, 2); err == nil {
+		t.Error("expected error for empty cmdline")
+	}
+	if _, err := ReadProcessSnapshot(root, 3); err == nil {
+		t.Error("expected error for missing pid")
+	}
+}
+
+func TestWriteGeneratedServiceParses(t *testing.T) {
+	snap := &ProcessSnapshot{
+		PID:        42,
+		Name:       "myd",
+		Command:    []string{"/usr/bin/myd", "--name", `a "b"`},
+		WorkingDir: "/srv",
+		Env:        []string{"MODE=prod", "SHLVL=1", "API_TOKEN=hunter2"},
+	}
+	var b strings.Builder
+	if err := WriteGeneratedService(&b, snap, ""); err != nil {
+		t.Fatal(err)
+	}
+	out := b.String()
+	if !strings.Contains(out, "#   MODE=prod") || strings.Contains(out, "SHLVL") {
+		t.Errorf("environment listing wrong:\n%s", out)
+	}
+	if strings.Contains(out, "hunter2") || !strings.Contains(out, "#   API_TOKEN=<redacted>") {
+		t.Errorf("credential not redacted:\n%s", out)
+	}
+	if strings.Contains(out, "run-as") && !strings.Contains(out, "run-as is unset") {
+		t.Errorf("root process should not get run-as:\n%s", out)
+	}
+
+	desc, err := Parse(strings.NewReader(out), "myd", "test")
+	if err != nil {
+		t.Fatalf("generated file does not parse: %v\n%s", err, out)
+	}
+	if strings.Join(desc.Command, "|") != strings.Join(snap.Command, "|") {
+		t.Errorf("Command round-trip = %q, want %q", desc.Command, snap.Command)
+	}
+	if desc.WorkingDir != "/srv" {
+		t.Errorf("WorkingDir = %q", desc.WorkingDir)
+	}
+}
+
+func TestWriteGeneratedServiceTOML(t *testing.T) {
+	snap := &ProcessSnapshot{
+		PID:     7,
+		Command: []string{"/bin/app", "x\ty"},
+		UID:     4242,
+		GID:     4242,
+		EnvFile: "/srv/app/.env",
+	}
+	var b strings.Builder
+	if err := WriteGeneratedService(&b, snap, "toml"); err != nil {
+		t.Fatal(err)
+	}
+	out := b.String()
+	for _, want := range []string{
+		`type = "process"`,
+		`command = ["/bin/app", "x\ty"]`,
+		`run-as = "`,
+		`env-file = "/srv/app/.env"`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("missing %q in:\n%s", want, out)
+		}
+	}
+	if err := WriteGeneratedService(&b, snap, "yaml"); err == nil {
+		t.Error("expected error for unknown format")
+	}
+}
@@ -185,8 +185,9 @@ func InitDToServiceDescription(scriptPath string) (*ServiceDescription, error) {
 	if len(lsb.Provides) > 0 {
 		desc.Name = lsb.Provides[0]
 		if len(lsb.Provides) > 1 {
-			// Use first alias as 'provides' (slinit supports one alias)
+			// First alias as 'provides', any further ones as 'aliases'
 			desc.Provides = lsb.Provides[1]
+			desc.Aliases = append(desc.Aliases, lsb.Provides[2:]...)
 		}
 	}
 
@@ -251,7 +252,7 @@ func readShebang(path string) (string, error) {
 //	want    → waits-for  (modern spelling of use)
 //	after   → after      (order-only)
 //	before  → before     (order-only)
-//	provide → provides   (first only; slinit takes a single alias)
+//	provide → provides   (first; the rest become aliases)
 //	keyword → captured but not enforced yet
 //
 // Virtual facility names ($network etc.) are re-mapped via
@@ -286,10 +287,10 @@ func applyOpenRCDepend(desc *ServiceDescription, dep *OpenRCDepend) {
 		}
 	}
 	if desc.Provides == "" && len(dep.Provide) > 0 {
-		// slinit supports a single provides alias; take the first
-		// and drop the rest silently. Rare in practice — most scripts
-		// list at most one.
+		// The first becomes the provides alias, the rest plain
+		// aliases. Rare in practice — most scripts list at most one.
 		desc.Provides = dep.Provide[0]
+		desc.Aliases = append(desc.Aliases, dep.Provide[1:]...)
 	}
 }
 
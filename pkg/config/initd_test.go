@@ -236,7 +236,7 @@ func TestMapFacility(t *testing.T) {
 func TestMultipleProvides(t *testing.T) {
 	script := `#!/bin/sh
 ### BEGIN INIT INFO
-# Provides:          main-name alias-name
+# Provides:          main-name alias-name other-alias
 # Required-Start:
 # Default-Start:     2 3 4 5
 # Short-Description: Multi-provides test
@@ -255,6 +255,9 @@ func TestMultipleProvides(t *testing.T) {
 	if desc.Provides != "alias-name" {
 		t.Errorf("Provides = %q, want %q", desc.Provides, "alias-name")
 	}
+	if len(desc.Aliases) != 1 || desc.Aliases[0] != "other-alias" {
+		t.Errorf("Aliases = %q, want [other-alias]", desc.Aliases)
+	}
 }
 
 // TestInitDToServiceDescription_OpenRCDepend covers the compat path:
@@ -0,0 +1,118 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/sunlightlinux/slinit/pkg/service"
+)
+
+// LintSeverity classifies a LintIssue.
+type LintSeverity uint8
+
+const (
+	// LintWarning marks a setting combination that is legal but
+	// probably not what the author meant.
+	LintWarning LintSeverity = iota
+	// LintError marks settings that contradict each other.
+	LintError
+)
+
+func (s LintSeverity) String() string {
+	if s == LintError {
+		return "error"
+	}
+	return "warning"
+}
+
+// LintIssue is a likely misconfiguration found by Lint.
+type LintIssue struct {
+	Code       string // e.g. "W003"; W = warning, E = error
+	Message    string
+	Severity   LintSeverity
+	Suggestion string
+}
+
+func (i LintIssue) String() string {
+	return fmt.Sprintf("%s %s: %s", i.Code, i.Severity, i.Message)
+}
+
+// minSaneStopTimeout is the stop-timeout below which W004 fires; real
+// daemons rarely flush state and exit that fast.
+const minSaneStopTimeout = 2 * time.Second
+
+// Lint checks a parsed service description for common misconfiguration
+// patterns that parse cleanly but misbehave at runtime. Issues are
+// returned in rule order.
+func Lint(desc *ServiceDescription) []LintIssue {
+	var issues []LintIssue
+	add := func(sev LintSeverity, code, suggestion, format string, args ...interface{}) {
+		issues = append(issues, LintIssue{
+			Code:       code,
+			Message:    fmt.Sprintf(format, args...),
+			Severity:   sev,
+			Suggestion: suggestion,
+		})
+	}
+
+	if desc.Type == service.TypeProcess && len(desc.Command) == 0 {
+		add(LintWarning, "W001", "set command, or use type = internal for a service with no process",
+			"process service has no command")
+	}
+
+	if desc.Type == service.TypeBGProcess && desc.PIDFile == "" {
+		add(LintWarning, "W002", "set pid-file to the path the daemon writes its PID to",
+			"bgprocess service has no pid-file; slinit cannot track the daemon after it forks")
+	}
+
+	if desc.AutoRestart == service.RestartAlways && desc.RestartDelay == 0 {
+		add(LintWarning, "W003", "set restart-delay (e.g. restart-delay = 1) to space out restarts",
+			"restart = yes without restart-delay may restart in a tight loop")
+	}
+
+	if desc.StopTimeout > 0 && desc.StopTimeout < minSaneStopTimeout {
+		add(LintWarning, "W004", "raise stop-timeout to at least 2 seconds",
+			"stop-timeout of %v is unlikely to let a real process shut down cleanly", desc.StopTimeout)
+	}
+
+	for _, dep := range desc.DependsOn {
+		for _, w := range desc.WaitsFor {
+			if dep == w {
+				add(LintWarning, "W005", "drop the waits-for entry; depends-on already implies it",
+					"%q is listed in both depends-on and waits-for", dep)
+			}
+		}
+	}
+
+	if desc.ChainTo != "" && !desc.Flags.AlwaysChain &&
+		len(desc.NormalExitCodes) == 0 && len(desc.NormalExitSignals) == 0 {
+		add(LintWarning, "W006", "add options = always-chain, or list the exit codes that count as success in normal-exit",
+			"chain-to %q only runs after a clean exit (status 0)", desc.ChainTo)
+	}
+
+	if desc.Flags.SignalProcessOnly {
+		switch {
+		case desc.Flags.KillAllOnStop:
+			add(LintError, "E001", "drop either signal-process-only or kill-all-on-stop",
+				"options signal-process-only and kill-all-on-stop contradict each other")
+		case desc.KillMode == service.KillModeControlGroup || desc.KillMode == service.KillModeMixed:
+			add(LintError, "E001", "drop signal-process-only, or set kill-mode = process",
+				"signal-process-only contradicts kill-mode = %s", desc.KillMode)
+		}
+	}
+
+	if len(desc.LDPreload) > 0 && !desc.PreloadSecurityCheck && runsUnprivileged(desc.RunAs) {
+		add(LintWarning, "W007", "set preload-security-check = yes so only root-owned, non-world-writable libraries are preloaded",
+			"unprivileged service preloads %s without preload-security-check", strings.Join(desc.LDPreload, ", "))
+	}
+
+	return issues
+}
+
+// runsUnprivileged reports whether a run-as value names a user other
+// than root.
+func runsUnprivileged(runAs string) bool {
+	user, _, _ := strings.Cut(runAs, ":")
+	return user != "" && user != "root" && user != "0"
+}
@@ -0,0 +1,88 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+// lintCodes parses content and returns the codes Lint reports.
+func lintCodes(t *testing.T, content string) []string {
+	t.Helper()
+	desc, err := Parse(strings.NewReader(content), "lint-svc", "test")
+	if err != nil {
+		t.Fatalf("parse failed: %v", err)
+	}
+	var codes []string
+	for _, issue := range Lint(desc) {
+		if issue.Suggestion == "" {
+			t.Errorf("%s has no suggestion", issue.Code)
+		}
+		codes = append(codes, issue.Code)
+	}
+	return codes
+}
+
+func TestLintClean(t *testing.T) {
+	codes := lintCodes(t, `type = process
+command = /usr/bin/daemon
+restart = yes
+restart-delay = 1
+depends-on: network
+waits-for: syslog
+`)
+	if len(codes) != 0 {
+		t.Errorf("expected no issues, got %v", codes)
+	}
+}
+
+func TestLintRules(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{"W001", "type = process\n", "W001"},
+		{"W002", "type = bgprocess\ncommand = /usr/sbin/forker\n", "W002"},
+		{"W003", "type = process\ncommand = /bin/d\nrestart = yes\n", "W003"},
+		{"W004", "type = process\ncommand = /bin/d\nstop-timeout = 0.5\n", "W004"},
+		{"W005", "type = process\ncommand = /bin/d\ndepends-on: db\nwaits-for: db\n", "W005"},
+		{"W006", "type = process\ncommand = /bin/d\nchain-to = next\n", "W006"},
+		{"W007", "type = process\ncommand = /bin/d\nrun-as = nobody\nld-preload = /usr/lib/libjemalloc.so.2\n", "W007"},
+		{"E001 kill-all-on-stop", "type = process\ncommand = /bin/d\noptions = signal-process-only kill-all-on-stop\n", "E001"},
+		{"E001 kill-mode", "type = process\ncommand = /bin/d\noptions = signal-process-only\nkill-mode = control-group\n", "E001"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			codes := lintCodes(t, tt.content)
+			if len(codes) != 1 || codes[0] != tt.want {
+				t.Errorf("got %v, want [%s]", codes, tt.want)
+			}
+		})
+	}
+}
+
+func TestLintChainToQuietWithAlwaysChainOrNormalExit(t *testing.T) {
+	for _, extra := range []string{"options = always-chain\n", "normal-exit = 0 3\n"} {
+		codes := lintCodes(t, "type = process\ncommand = /bin/d\nchain-to = next\n"+extra)
+		if len(codes) != 0 {
+			t.Errorf("with %q: expected no issues, got %v", extra, codes)
+		}
+	}
+}
+
+func TestLintSeverity(t *testing.T) {
+	desc, err := Parse(strings.NewReader("type = process\noptions = signal-process-only kill-all-on-stop\n"), "x", "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	issues := Lint(desc)
+	if len(issues) != 2 {
+		t.Fatalf("expected W001 and E001, got %v", issues)
+	}
+	if issues[0].Severity != LintWarning || issues[1].Severity != LintError {
+		t.Errorf("unexpected severities: %v", issues)
+	}
+	if got := issues[1].String(); !strings.HasPrefix(got, "E001 error: ") {
+		t.Errorf("String() = %q", got)
+	}
+}
@@ -30,6 +30,7 @@ var DefaultInitDDirs = []string{"/etc/init.d", "/etc/rc.d"}
 
 // DirLoader loads service descriptions from one or more directories.
 type DirLoader struct {
+	dirsMu      sync.RWMutex // guards dirs, which is replaced, never modified
 	dirs        []string
 	initDirs    []string // init.d directories for fallback (empty = disabled)
 	overlayDirs []string // conf.d overlay directories (default: /etc/slinit.conf.d)
@@ -37,6 +38,25 @@ type DirLoader struct {
 	loading     map[string]bool // tracks loading state for circular dependency detection
 	curDepth    int             // current recursion depth during loading
 	platformSys platform.Type   // detected (or overridden) platform for keyword filtering
+
+	// checksumFile, when set, lists the SHA-256 every service file
+	// must match before it is parsed (see checksum.go).
+	checksumFile string
+
+	// find locates and parses a description; nil means findAndParse
+	// over the service directories. EmbeddedLoader swaps in a lookup
+	// against its fs.FS and sets embedded so records are tagged.
+	find     func(name string) (*ServiceDescription, string, error)
+	embedded bool
+
+	// deps resolves dependency names; nil means dl itself. A
+	// CompositeLoader points each member here so that a dependency
+	// can be satisfied by any member.
+	deps service.ServiceLoader
+
+	// prefetch holds descriptions parsed ahead by PreloadConcurrent;
+	// nil outside of it.
+	prefetch *descPrefetch
 }
 
 // defaultOverlayDir is the default conf.d overlay location.
@@ -81,8 +101,11 @@ func (dl *DirLoader) OverlayDirs() []string {
 	return dl.overlayDirs
 }
 
-// ServiceDirs returns the configured service directories.
+// ServiceDirs returns the configured service directories. The slice
+// must not be modified.
 func (dl *DirLoader) ServiceDirs() []string {
+	dl.dirsMu.RLock()
+	defer dl.dirsMu.RUnlock()
 	return dl.dirs
 }
 
@@ -104,23 +127,59 @@ func (dl *DirLoader) ReloadService(svc service.Service) (service.Service, error)
 	name := svc.Name()
 
 	// Re-parse the config file
-	desc, filePath, err := dl.findAndParse(name)
+	desc, filePath, err := dl.findDesc(name)
 	if err != nil {
 		return nil, err
 	}
 
+	var nsvc service.Service
 	state := svc.State()
 	switch state {
 	case service.StateStopped:
-		return dl.reloadStopped(svc, desc, filePath)
+		nsvc, err = dl.reloadStopped(svc, desc, filePath)
 	case service.StateStarted:
-		return dl.reloadStarted(svc, desc, filePath)
+		nsvc, err = dl.reloadStarted(svc, desc, filePath)
 	default:
 		return nil, &ServiceLoadError{
 			ServiceName: name,
 			Message:     fmt.Sprintf("cannot reload service in state %d", state),
 		}
 	}
+	if err != nil {
+		return nil, err
+	}
+	// The description may now come from a different source than before
+	// (e.g. a directory file shadowing a previously embedded default).
+	nsvc.Record().SetEmbedded(dl.embedded)
+	dl.recordSource(nsvc, filePath)
+	// A reload applies the configured restart mode afresh; drop any
+	// `slinitctl no-restart` / `enable-restart` override.
+	nsvc.Record().ClearAutoRestartOverride()
+	// A stopped on-demand service re-binds its activation socket so a
+	// changed socket-listen or socket-activation takes effect.
+	if ps, ok := nsvc.(*service.ProcessService); ok && state == service.StateStopped {
+		ps.DisarmSocketActivation()
+		ps.ArmSocketActivation()
+	}
+	if dl.set.OnServiceReloaded != nil {
+		dl.set.OnServiceReloaded(svc, nsvc)
+	}
+	return nsvc, nil
+}
+
+// recordSource records the directory and modification time of the
+// description svc was (re)loaded from. Embedded descriptions have
+// neither.
+func (dl *DirLoader) recordSource(svc service.Service, filePath string) {
+	if dl.embedded {
+		svc.Record().SetServiceDir("")
+		svc.Record().SetLoadModTime(time.Time{})
+		return
+	}
+	svc.Record().SetServiceDir(filepath.Dir(filePath))
+	if fi, err := os.Stat(filePath); err == nil {
+		svc.Record().SetLoadModTime(fi.ModTime())
+	}
 }
 
 // reloadStopped handles reload of a stopped service. Can change type.
@@ -138,12 +197,21 @@ func (dl *DirLoader) reloadStopped(svc service.Service, desc *ServiceDescription
 		if err := dl.loadDependencies(newSvc, desc, filePath); err != nil {
 			return nil, err
 		}
+		if err := dl.loadFanoutMembers(newSvc, desc); err != nil {
+			return nil, err
+		}
+		if err := dl.loadTimerTarget(newSvc, desc); err != nil {
+			return nil, err
+		}
 
 		// Apply common settings
 		applyToService(newSvc, desc)
 
 		// Transfer pipe fds and consumer links from old to new
 		dl.transferConsumerOf(svc, newSvc)
+		if p := newSvc.Record().ConsumerFor(); p != nil && p.Name() != desc.ConsumerOf {
+			unlinkConsumerOf(newSvc)
+		}
 
 		// Transfer dependents from old to new
 		dl.transferDependents(svc, newSvc)
@@ -152,7 +220,7 @@ func (dl *DirLoader) reloadStopped(svc service.Service, desc *ServiceDescription
 		dl.removeDependencies(svc)
 
 		// Set up consumer-of for new service
-		if desc.ConsumerOf != "" {
+		if desc.ConsumerOf != "" && newSvc.Record().ConsumerFor() == nil {
 			if err := dl.setupConsumerOf(newSvc, desc); err != nil {
 				return nil, err
 			}
@@ -199,6 +267,12 @@ func (dl *DirLoader) reloadStarted(svc service.Service, desc *ServiceDescription
 		return nil, err
 	}
 
+	// Cannot change consumer-of: stdin is bound to the old producer's
+	// pipe until the consumer restarts
+	if err := dl.validateConsumerOfUnchanged(svc, desc); err != nil {
+		return nil, err
+	}
+
 	// New regular deps must already be STARTED
 	if err := dl.validateNewRegularDeps(svc, desc); err != nil {
 		return nil, err
@@ -208,9 +282,11 @@ func (dl *DirLoader) reloadStarted(svc service.Service, desc *ServiceDescription
 }
 
 // updateInPlace updates a service's configuration without replacing the record.
-// Dependencies are updated first (may fail), then type-specific fields and
-// common settings (cannot fail), so a dependency error does not leave the
-// service in a partially-updated state.
+// The update is all-or-nothing. Dependencies are updated first, then the
+// type-specific fields, saved beforehand with SaveSnapshot. The consumer-of
+// and shared-logger links can still fail after that; if they do, the
+// snapshot and the old dependencies are restored. Common settings, which
+// cannot fail, are applied last.
 func (dl *DirLoader) updateInPlace(svc service.Service, desc *ServiceDescription, filePath string) (service.Service, error) {
 	// Check for cycles before modifying
 	if err := dl.checkCycle(svc, desc); err != nil {
@@ -218,19 +294,53 @@ func (dl *DirLoader) updateInPlace(svc service.Service, desc *ServiceDescription
 	}
 
 	// Update dependencies first — this can fail (e.g. missing dep) and has rollback
-	if err := dl.updateDependencies(svc, desc, filePath); err != nil {
+	restoreDeps, err := dl.updateDependencies(svc, desc, filePath)
+	if err != nil {
 		return nil, err
 	}
 
 	// Update type-specific fields (command, timeouts, etc.)
+	snapper, _ := svc.(service.Snapshotter)
+	var snap service.ServiceSnapshot
+	if snapper != nil {
+		snap = snapper.SaveSnapshot()
+	}
+	rollback := func() {
+		if snapper != nil {
+			snapper.RestoreSnapshot(snap)
+		}
+		restoreDeps()
+	}
+	if err := dl.loadFanoutMembers(svc, desc); err != nil {
+		rollback()
+		return nil, err
+	}
+	if err := dl.loadTimerTarget(svc, desc); err != nil {
+		rollback()
+		return nil, err
+	}
 	dl.updateTypeSpecificFields(svc, desc)
 
-	// Update common settings
-	applyToService(svc, desc)
-
-	// Update consumer-of relationship
+	// Update consumer-of relationship. A link to a producer the new
+	// description no longer names is dropped first; restoreConsumer
+	// puts it back if the reload fails.
+	oldProducer := svc.Record().ConsumerFor()
+	if oldProducer != nil && oldProducer.Name() != desc.ConsumerOf {
+		unlinkConsumerOf(svc)
+	}
+	restoreConsumer := func() {
+		if svc.Record().ConsumerFor() != oldProducer {
+			unlinkConsumerOf(svc)
+			if oldProducer != nil {
+				oldProducer.Record().SetLogConsumer(svc)
+				svc.Record().SetConsumerFor(oldProducer)
+			}
+		}
+	}
 	if desc.ConsumerOf != "" && svc.Record().ConsumerFor() == nil {
 		if err := dl.setupConsumerOf(svc, desc); err != nil {
+			restoreConsumer()
+			rollback()
 			return nil, err
 		}
 	}
@@ -238,10 +348,18 @@ func (dl *DirLoader) updateInPlace(svc service.Service, desc *ServiceDescription
 	// Update shared-logger relationship
 	if desc.SharedLogger != "" && svc.Record().SharedLoggerName() == "" {
 		if err := dl.setupSharedLogger(svc, desc); err != nil {
+			restoreConsumer()
+			rollback()
 			return nil, err
 		}
 	}
 
+	// Update common settings
+	applyToService(svc, desc)
+
+	// Pick up added aliases and drop removed ones
+	dl.set.SyncAliases(svc)
+
 	// A service that IS a shared-logger sink records its lossy /
 	// queue-size on its own record; the loader reads them back when a
 	// producer registers via setupSharedLogger.
@@ -261,6 +379,7 @@ func (dl *DirLoader) updateTypeSpecificFields(svc service.Service, desc *Service
 	case *service.ProcessService:
 		s.SetCommand(desc.Command)
 		s.SetArgv0(desc.Argv0)
+		s.SetMemfdExec(desc.MemfdExec)
 		s.SetStopCommand(desc.StopCommand)
 		s.SetFinishCommand(desc.FinishCommand)
 		s.SetPreStartCommand(desc.PreStartCommand)
@@ -286,15 +405,16 @@ func (dl *DirLoader) updateTypeSpecificFields(svc service.Service, desc *Service
 		s.SetWorkingDir(desc.WorkingDir)
 		s.SetEnvFile(desc.EnvFile)
 		s.SetEnvDir(desc.EnvDir)
+		s.SetEncryptedEnvFile(desc.EncryptedEnvFile, desc.EnvEncryptionKey)
 		s.SetEnvGenerator(desc.EnvGenerator)
 		s.SetChroot(desc.Chroot)
 		s.SetLockFile(desc.LockFile)
 		s.SetNewSession(desc.NewSession)
 		s.SetCloseFDs(desc.CloseStdin, desc.CloseStdout, desc.CloseStderr)
-		if desc.StartTimeout > 0 {
+		if desc.StartTimeout > 0 || desc.StartTimeoutSet {
 			s.SetStartTimeout(desc.StartTimeout)
 		}
-		if desc.StopTimeout > 0 {
+		if desc.StopTimeout > 0 || desc.StopTimeoutSet {
 			s.SetStopTimeout(desc.StopTimeout)
 		}
 		if desc.TimeoutAbortSec > 0 {
@@ -350,6 +470,7 @@ func (dl *DirLoader) updateTypeSpecificFields(svc service.Service, desc *Service
 			s.SetReadyNotification(-1, "")
 		} else {
 			s.SetReadyNotification(desc.ReadyNotifyFD, desc.ReadyNotifyVar)
+			s.SetReadyNotifySocket(desc.ReadyNotifySocket)
 		}
 		if desc.WatchdogTimeout > 0 {
 			s.SetWatchdogTimeout(desc.WatchdogTimeout)
@@ -379,24 +500,29 @@ func (dl *DirLoader) updateTypeSpecificFields(svc service.Service, desc *Service
 		s.SetStartCommand(desc.Command)
 		s.SetStopCommand(desc.StopCommand)
 		s.SetWorkingDir(desc.WorkingDir)
-		if desc.StartTimeout > 0 {
+		s.SetCaptureStartError(desc.CaptureStartError)
+		if desc.StartTimeout > 0 || desc.StartTimeoutSet {
 			s.SetStartTimeout(desc.StartTimeout)
 		}
-		if desc.StopTimeout > 0 {
+		if desc.StopTimeout > 0 || desc.StopTimeoutSet {
 			s.SetStopTimeout(desc.StopTimeout)
 		}
 		applyLogSettings(s, desc)
+		s.SetHealthCheck(desc.HealthCheckCommand, desc.HealthCheckInterval,
+			desc.HealthCheckDelay, desc.HealthCheckMaxFail, desc.UnhealthyCommand)
 	case *service.BGProcessService:
 		s.SetCommand(desc.Command)
 		s.SetArgv0(desc.Argv0)
+		s.SetMemfdExec(desc.MemfdExec)
 		s.SetStopCommand(desc.StopCommand)
 		s.SetWorkingDir(desc.WorkingDir)
 		s.SetEnvFile(desc.EnvFile)
 		s.SetPIDFile(desc.PIDFile)
-		if desc.StartTimeout > 0 {
+		s.SetPIDFileLocking(desc.PIDFileLocking)
+		if desc.StartTimeout > 0 || desc.StartTimeoutSet {
 			s.SetStartTimeout(desc.StartTimeout)
 		}
-		if desc.StopTimeout > 0 {
+		if desc.StopTimeout > 0 || desc.StopTimeoutSet {
 			s.SetStopTimeout(desc.StopTimeout)
 		}
 		if desc.TimeoutAbortSec > 0 {
@@ -420,11 +546,22 @@ func (dl *DirLoader) updateTypeSpecificFields(svc service.Service, desc *Service
 			s.SetRestartLimits(desc.RestartInterval, desc.RestartLimitCount)
 		}
 		applyLogSettings(s, desc)
+	case *service.SocketActivatedService:
+		s.SetCommand(desc.Command)
+		s.SetWorkingDir(desc.WorkingDir)
+		s.SetEnvFile(desc.EnvFile)
+		s.SetSocketActivationMode(desc.SocketActivationMode)
+	case *service.TriggeredService:
+		s.SetTriggerNames(desc.TriggerNames, desc.TriggerAll)
+	case *service.TimerService:
+		s.SetSchedule(desc.TimerInterval, desc.TimerBootDelay, desc.TimerCalendar)
 	}
 }
 
-// updateDependencies atomically replaces dependencies on a service.
-func (dl *DirLoader) updateDependencies(svc service.Service, desc *ServiceDescription, filePath string) error {
+// updateDependencies atomically replaces dependencies on a service. On
+// success it returns a func that puts the old dependencies back, for a
+// reload that fails at a later step.
+func (dl *DirLoader) updateDependencies(svc service.Service, desc *ServiceDescription, filePath string) (func(), error) {
 	rec := svc.Record()
 
 	// Fast-path: if the description's declared deps match the
@@ -445,7 +582,7 @@ func (dl *DirLoader) updateDependencies(svc service.Service, desc *ServiceDescri
 	// The skip is safe because "no change" means we would end up with
 	// the same dep set anyway; the round-trip was pure churn.
 	if descDepsMatchCurrent(rec, desc) {
-		return nil
+		return func() {}, nil
 	}
 
 	// Save old deps for rollback
@@ -453,22 +590,13 @@ func (dl *DirLoader) updateDependencies(svc service.Service, desc *ServiceDescri
 	copy(oldDeps, rec.Dependencies())
 
 	// Remove all deps except BEFORE deps from other services
-	for i := len(rec.Dependencies()) - 1; i >= 0; i-- {
-		dep := rec.Dependencies()[i]
-		if dep.DepType != service.DepBefore {
-			rec.RmDep(dep.To, dep.DepType)
-		}
-	}
+	removeNonBeforeDeps(rec)
 
 	// Load and add new deps
 	if err := dl.loadDependencies(svc, desc, filePath); err != nil {
 		// Rollback: re-add old deps
-		for _, dep := range oldDeps {
-			if dep.DepType != service.DepBefore {
-				rec.AddDep(dep.To, dep.DepType)
-			}
-		}
-		return err
+		addNonBeforeDeps(rec, oldDeps)
+		return nil, err
 	}
 
 	// Recalculate dependency depth after dep changes
@@ -476,22 +604,103 @@ func (dl *DirLoader) updateDependencies(svc service.Service, desc *ServiceDescri
 	updater.AddPotentialUpdate(svc)
 	if err := updater.ProcessUpdates(); err != nil {
 		// Rollback deps on depth overflow
-		for i := len(rec.Dependencies()) - 1; i >= 0; i-- {
-			dep := rec.Dependencies()[i]
-			if dep.DepType != service.DepBefore {
-				rec.RmDep(dep.To, dep.DepType)
+		removeNonBeforeDeps(rec)
+		addNonBeforeDeps(rec, oldDeps)
+		updater.Rollback()
+		return nil, &ServiceLoadError{ServiceName: svc.Name(), Message: err.Error()}
+	}
+	updater.Commit()
+
+	restore := func() {
+		removeNonBeforeDeps(rec)
+		addNonBeforeDeps(rec, oldDeps)
+		var updater service.DepDepthUpdater
+		updater.AddPotentialUpdate(svc)
+		if err := updater.ProcessUpdates(); err != nil {
+			// The old deps had a valid depth before; keep it.
+			updater.Rollback()
+			return
+		}
+		updater.Commit()
+	}
+	return restore, nil
+}
+
+// removeNonBeforeDeps removes the dependencies of rec other than BEFORE
+// deps from other services.
+func removeNonBeforeDeps(rec *service.ServiceRecord) {
+	for i := len(rec.Dependencies()) - 1; i >= 0; i-- {
+		dep := rec.Dependencies()[i]
+		if dep.DepType != service.DepBefore {
+			rec.RmDep(dep.To, dep.DepType)
+		}
+	}
+}
+
+// addNonBeforeDeps re-adds deps saved from rec, except BEFORE deps.
+func addNonBeforeDeps(rec *service.ServiceRecord, deps []*service.ServiceDep) {
+	for _, dep := range deps {
+		if dep.DepType != service.DepBefore {
+			rec.AddDep(dep.To, dep.DepType)
+		}
+	}
+}
+
+// loadFanoutMembers loads the members of a fanout service and hands them
+// to it. Members are not dependencies, so no dependency edge is added.
+// Other service types are left alone.
+func (dl *DirLoader) loadFanoutMembers(svc service.Service, desc *ServiceDescription) error {
+	fs, ok := svc.(*service.FanoutService)
+	if !ok {
+		return nil
+	}
+	members := make([]service.Service, 0, len(desc.Members))
+	for _, name := range desc.Members {
+		m, err := dl.loadDep(name)
+		if err != nil {
+			return &ServiceLoadError{
+				ServiceName: svc.Name(),
+				Message:     fmt.Sprintf("member '%s': %v", name, err),
 			}
 		}
-		for _, dep := range oldDeps {
-			if dep.DepType != service.DepBefore {
-				rec.AddDep(dep.To, dep.DepType)
+		if m == svc {
+			return &ServiceLoadError{
+				ServiceName: svc.Name(),
+				Message:     "a fanout service cannot be its own member",
 			}
 		}
-		updater.Rollback()
-		return &ServiceLoadError{ServiceName: svc.Name(), Message: err.Error()}
+		members = append(members, m)
 	}
-	updater.Commit()
+	fs.SetMembers(members)
+	return nil
+}
 
+// loadTimerTarget loads the service a timer activates and hands it to
+// the timer. Like fanout members, the target is not a dependency.
+// Other service types are left alone.
+func (dl *DirLoader) loadTimerTarget(svc service.Service, desc *ServiceDescription) error {
+	ts, ok := svc.(*service.TimerService)
+	if !ok {
+		return nil
+	}
+	if desc.TimerTarget == "" {
+		ts.SetTarget(nil)
+		return nil
+	}
+	target, err := dl.loadDep(desc.TimerTarget)
+	if err != nil {
+		return &ServiceLoadError{
+			ServiceName: svc.Name(),
+			Message:     fmt.Sprintf("activates '%s': %v", desc.TimerTarget, err),
+		}
+	}
+	if target == svc {
+		return &ServiceLoadError{
+			ServiceName: svc.Name(),
+			Message:     "a timer service cannot activate itself",
+		}
+	}
+	ts.SetTarget(target)
 	return nil
 }
 
@@ -520,6 +729,15 @@ func (dl *DirLoader) transferConsumerOf(oldSvc, newSvc service.Service) {
 	}
 }
 
+// unlinkConsumerOf drops the consumer-of link between svc and the
+// producer it consumes, if any.
+func unlinkConsumerOf(svc service.Service) {
+	if producer := svc.Record().ConsumerFor(); producer != nil {
+		producer.Record().SetLogConsumer(nil)
+		svc.Record().SetConsumerFor(nil)
+	}
+}
+
 // transferDependents moves dependents from old service to new service.
 func (dl *DirLoader) transferDependents(oldSvc, newSvc service.Service) {
 	oldRec := oldSvc.Record()
@@ -600,6 +818,19 @@ func (dl *DirLoader) validateLogTypeUnchanged(svc service.Service, desc *Service
 	return nil
 }
 
+// validateConsumerOfUnchanged checks that consumer-of is not changed
+// for a running service.
+func (dl *DirLoader) validateConsumerOfUnchanged(svc service.Service, desc *ServiceDescription) error {
+	current := ""
+	if p := svc.Record().ConsumerFor(); p != nil {
+		current = p.Name()
+	}
+	if current != desc.ConsumerOf {
+		return &ServiceLoadError{ServiceName: svc.Name(), Message: "cannot change consumer-of for running service"}
+	}
+	return nil
+}
+
 // validatePidFileUnchanged checks that pid-file is not changed for a running BGProcess.
 func (dl *DirLoader) validatePidFileUnchanged(svc service.Service, desc *ServiceDescription) error {
 	if bgp, ok := svc.(*service.BGProcessService); ok {
@@ -683,7 +914,7 @@ func (dl *DirLoader) loadServiceImpl(name string, depth int) (service.Service, e
 	defer func() { dl.curDepth = prevDepth }()
 
 	// Find and parse the service description file
-	desc, filePath, err := dl.findAndParse(name)
+	desc, filePath, err := dl.findDesc(name)
 	if err != nil {
 		return nil, err
 	}
@@ -758,7 +989,7 @@ func (dl *DirLoader) loadServiceImpl(name string, depth int) (service.Service, e
 	}
 
 	// Validate: ready-notification not supported for bgprocess
-	if desc.Type == service.TypeBGProcess && (desc.ReadyNotifyFD >= 0 || desc.ReadyNotifyVar != "") {
+	if desc.Type == service.TypeBGProcess && (desc.ReadyNotifyFD >= 0 || desc.ReadyNotifyVar != "" || desc.ReadyNotifySocket) {
 		return nil, &ServiceLoadError{
 			ServiceName: name,
 			Message:     "ready-notification is not supported for bgprocess services",
@@ -861,11 +1092,10 @@ func (dl *DirLoader) loadServiceImpl(name string, depth int) (service.Service, e
 	// Create the service based on type
 	svc := dl.createService(name, desc)
 
-	// Record the directory and modification time of the service description
-	svc.Record().SetServiceDir(filepath.Dir(filePath))
-	if fi, err := os.Stat(filePath); err == nil {
-		svc.Record().SetLoadModTime(fi.ModTime())
+	if dl.embedded {
+		svc.Record().SetEmbedded(true)
 	}
+	dl.recordSource(svc, filePath)
 
 	// Add to set before loading dependencies (allows circular detection)
 	dl.set.AddService(svc)
@@ -875,6 +1105,14 @@ func (dl *DirLoader) loadServiceImpl(name string, depth int) (service.Service, e
 		dl.set.RemoveService(svc)
 		return nil, err
 	}
+	if err := dl.loadFanoutMembers(svc, desc); err != nil {
+		dl.set.RemoveService(svc)
+		return nil, err
+	}
+	if err := dl.loadTimerTarget(svc, desc); err != nil {
+		dl.set.RemoveService(svc)
+		return nil, err
+	}
 
 	// Calculate dependency depth
 	svc.Record().SetDepDepth(calcServiceDepth(svc))
@@ -890,11 +1128,9 @@ func (dl *DirLoader) loadServiceImpl(name string, depth int) (service.Service, e
 		svc.Record().SetMarkedDown(true)
 	}
 
-	// Re-register alias now that provides is set (AddService was called
-	// before applyToService, so the alias wasn't registered yet)
-	if alias := svc.Record().Provides(); alias != "" {
-		dl.set.RegisterAlias(alias, svc)
-	}
+	// Re-register aliases now that provides/aliases are set (AddService
+	// was called before applyToService, so they weren't registered yet)
+	dl.set.SyncAliases(svc)
 
 	// Apply load-options
 	applyLoadOptions(svc, desc)
@@ -932,10 +1168,41 @@ func (dl *DirLoader) loadServiceImpl(name string, depth int) (service.Service, e
 	if dl.set.OnServiceLoaded != nil {
 		dl.set.OnServiceLoaded(svc)
 	}
+	if ps, ok := svc.(*service.ProcessService); ok {
+		ps.ArmSocketActivation()
+	}
 
 	return svc, nil
 }
 
+// findDesc locates and parses name's description through dl.find, or
+// the service directories when no custom lookup is set. During
+// PreloadConcurrent it hands out the prefetched parse instead.
+func (dl *DirLoader) findDesc(name string) (*ServiceDescription, string, error) {
+	if dl.prefetch != nil {
+		if e := dl.prefetch.take(name); e != nil {
+			return e.desc, e.path, e.err
+		}
+	}
+	return dl.parseDesc(name)
+}
+
+// parseDesc is findDesc without the prefetch.
+func (dl *DirLoader) parseDesc(name string) (*ServiceDescription, string, error) {
+	if dl.find != nil {
+		return dl.find(name)
+	}
+	return dl.findAndParse(name)
+}
+
+// loadDep loads a dependency through dl.deps when set, else through dl.
+func (dl *DirLoader) loadDep(name string) (service.Service, error) {
+	if dl.deps != nil {
+		return dl.deps.LoadService(name)
+	}
+	return dl.LoadService(name)
+}
+
 func (dl *DirLoader) findAndParse(name string) (*ServiceDescription, string, error) {
 	// Extract service argument from name@argument pattern
 	baseName := name
@@ -946,14 +1213,9 @@ func (dl *DirLoader) findAndParse(name string) (*ServiceDescription, string, err
 		serviceArg = &arg
 	}
 
-	// Try full name first, then base name (for templates)
-	searchNames := []string{name}
-	if baseName != name {
-		searchNames = append(searchNames, baseName)
-	}
-
-	for _, dir := range dl.dirs {
-		for _, sn := range searchNames {
+	// Try full name first, then the template files
+	for _, dir := range dl.ServiceDirs() {
+		for _, sn := range TemplateFileNames(name) {
 			path := filepath.Join(dir, sn)
 			f, err := os.Open(path)
 			if err != nil {
@@ -965,6 +1227,19 @@ func (dl *DirLoader) findAndParse(name string) (*ServiceDescription, string, err
 					Message:     fmt.Sprintf("error reading %s: %v", path, err),
 				}
 			}
+			// Released by f.Close below.
+			if err := LoaderLock(f); err != nil {
+				f.Close()
+				return nil, "", &ServiceLoadError{
+					ServiceName: name,
+					Message:     fmt.Sprintf("error reading %s: %v", path, err),
+					Err:         err,
+				}
+			}
+			if err := dl.checkOpenFile(f, path, name); err != nil {
+				f.Close()
+				return nil, "", err
+			}
 
 			var desc *ServiceDescription
 			if serviceArg != nil {
@@ -986,6 +1261,11 @@ func (dl *DirLoader) findAndParse(name string) (*ServiceDescription, string, err
 			if err := dl.applySiblingOverride(desc, name, path, serviceArg); err != nil {
 				return nil, "", err
 			}
+			// Patch files (<service>.d/*.patch) adjust single settings
+			// on top of everything else.
+			if err := dl.applyPatches(desc, name, serviceArg); err != nil {
+				return nil, "", err
+			}
 			return desc, path, nil
 		}
 	}
@@ -995,6 +1275,15 @@ func (dl *DirLoader) findAndParse(name string) (*ServiceDescription, string, err
 		for _, dir := range dl.initDirs {
 			path := filepath.Join(dir, name)
 			if IsInitDScript(path) {
+				if dl.checksumFile != "" {
+					if err := verifyPathChecksum(path, dl.checksumFile); err != nil {
+						return nil, "", &ServiceLoadError{
+							ServiceName: name,
+							Message:     err.Error(),
+							Err:         err,
+						}
+					}
+				}
 				desc, err := InitDToServiceDescription(path)
 				if err != nil {
 					return nil, "", &ServiceLoadError{
@@ -1052,7 +1341,10 @@ func (dl *DirLoader) applyOverlays(desc *ServiceDescription, name, baseName stri
 					Message:     fmt.Sprintf("error reading overlay %s: %v", path, err),
 				}
 			}
-			parseErr := ParseOverlay(f, name, path, desc, serviceArg)
+			parseErr := dl.checkOpenFile(f, path, name)
+			if parseErr == nil {
+				parseErr = ParseOverlay(f, name, path, desc, serviceArg)
+			}
 			f.Close()
 			if parseErr != nil {
 				return parseErr
@@ -1085,6 +1377,9 @@ func (dl *DirLoader) applySiblingOverride(desc *ServiceDescription, name, basePa
 		}
 	}
 	defer f.Close()
+	if err := dl.checkOpenFile(f, overridePath, name); err != nil {
+		return err
+	}
 	return ParseOverlay(f, name, overridePath, desc, serviceArg)
 }
 
@@ -1096,6 +1391,7 @@ func (dl *DirLoader) createService(name string, desc *ServiceDescription) servic
 		svc := service.NewProcessService(dl.set, name)
 		svc.SetCommand(desc.Command)
 		svc.SetArgv0(desc.Argv0)
+		svc.SetMemfdExec(desc.MemfdExec)
 		svc.SetStopCommand(desc.StopCommand)
 		svc.SetFinishCommand(desc.FinishCommand)
 		svc.SetPreStartCommand(desc.PreStartCommand)
@@ -1112,6 +1408,7 @@ func (dl *DirLoader) createService(name string, desc *ServiceDescription) servic
 		svc.SetPreStopHook(desc.PreStopHook)
 		svc.SetControlCommands(desc.ControlCommands)
 		svc.SetEnvDir(desc.EnvDir)
+		svc.SetEncryptedEnvFile(desc.EncryptedEnvFile, desc.EnvEncryptionKey)
 		svc.SetWorkingDir(desc.WorkingDir)
 		svc.SetEnvFile(desc.EnvFile)
 		svc.SetEnvGenerator(desc.EnvGenerator)
@@ -1119,10 +1416,10 @@ func (dl *DirLoader) createService(name string, desc *ServiceDescription) servic
 		svc.SetLockFile(desc.LockFile)
 		svc.SetNewSession(desc.NewSession)
 		svc.SetCloseFDs(desc.CloseStdin, desc.CloseStdout, desc.CloseStderr)
-		if desc.StartTimeout > 0 {
+		if desc.StartTimeout > 0 || desc.StartTimeoutSet {
 			svc.SetStartTimeout(desc.StartTimeout)
 		}
-		if desc.StopTimeout > 0 {
+		if desc.StopTimeout > 0 || desc.StopTimeoutSet {
 			svc.SetStopTimeout(desc.StopTimeout)
 		}
 		if desc.RestartDelay > 0 {
@@ -1159,6 +1456,8 @@ func (dl *DirLoader) createService(name string, desc *ServiceDescription) servic
 		if desc.ReadyNotifyFD >= 0 || desc.ReadyNotifyVar != "" {
 			svc.SetReadyNotification(desc.ReadyNotifyFD, desc.ReadyNotifyVar)
 		}
+		svc.SetReadyNotifySocket(desc.ReadyNotifySocket &&
+			!(desc.NotifyAccessSet && desc.NotifyAccess == service.NotifyAccessNone))
 		if desc.WatchdogTimeout > 0 {
 			svc.SetWatchdogTimeout(desc.WatchdogTimeout)
 		}
@@ -1177,9 +1476,7 @@ func (dl *DirLoader) createService(name string, desc *ServiceDescription) servic
 			svc.SetHealthCheck(desc.HealthCheckCommand, desc.HealthCheckInterval,
 				desc.HealthCheckDelay, desc.HealthCheckMaxFail, desc.UnhealthyCommand)
 		}
-		if desc.SocketActivation == "on-demand" {
-			svc.SetSocketOnDemand(true)
-		}
+		svc.SetSocketOnDemand(desc.SocketActivation == "on-demand")
 		if desc.VTTYEnabled {
 			svc.SetVTTY(true, desc.VTTYScrollback, "/run/slinit")
 		}
@@ -1191,13 +1488,18 @@ func (dl *DirLoader) createService(name string, desc *ServiceDescription) servic
 		svc.SetStartCommand(desc.Command)
 		svc.SetStopCommand(desc.StopCommand)
 		svc.SetWorkingDir(desc.WorkingDir)
-		if desc.StartTimeout > 0 {
+		svc.SetCaptureStartError(desc.CaptureStartError)
+		if desc.StartTimeout > 0 || desc.StartTimeoutSet {
 			svc.SetStartTimeout(desc.StartTimeout)
 		}
-		if desc.StopTimeout > 0 {
+		if desc.StopTimeout > 0 || desc.StopTimeoutSet {
 			svc.SetStopTimeout(desc.StopTimeout)
 		}
 		applyLogSettings(svc, desc)
+		if len(desc.HealthCheckCommand) > 0 {
+			svc.SetHealthCheck(desc.HealthCheckCommand, desc.HealthCheckInterval,
+				desc.HealthCheckDelay, desc.HealthCheckMaxFail, desc.UnhealthyCommand)
+		}
 		dl.applyRunAs(svc, desc)
 		dl.applySupplementaryGroups(svc, desc)
 		return svc
@@ -1205,14 +1507,16 @@ func (dl *DirLoader) createService(name string, desc *ServiceDescription) servic
 		svc := service.NewBGProcessService(dl.set, name)
 		svc.SetCommand(desc.Command)
 		svc.SetArgv0(desc.Argv0)
+		svc.SetMemfdExec(desc.MemfdExec)
 		svc.SetStopCommand(desc.StopCommand)
 		svc.SetWorkingDir(desc.WorkingDir)
 		svc.SetEnvFile(desc.EnvFile)
 		svc.SetPIDFile(desc.PIDFile)
-		if desc.StartTimeout > 0 {
+		svc.SetPIDFileLocking(desc.PIDFileLocking)
+		if desc.StartTimeout > 0 || desc.StartTimeoutSet {
 			svc.SetStartTimeout(desc.StartTimeout)
 		}
-		if desc.StopTimeout > 0 {
+		if desc.StopTimeout > 0 || desc.StopTimeoutSet {
 			svc.SetStopTimeout(desc.StopTimeout)
 		}
 		if desc.RestartDelay > 0 {
@@ -1229,7 +1533,25 @@ func (dl *DirLoader) createService(name string, desc *ServiceDescription) servic
 		dl.applySupplementaryGroups(svc, desc)
 		return svc
 	case service.TypeTriggered:
-		return service.NewTriggeredService(dl.set, name)
+		svc := service.NewTriggeredService(dl.set, name)
+		svc.SetTriggerNames(desc.TriggerNames, desc.TriggerAll)
+		return svc
+	case service.TypeBarrier:
+		return service.NewBarrierService(dl.set, name)
+	case service.TypeFanout:
+		return service.NewFanoutService(dl.set, name)
+	case service.TypeTimer:
+		svc := service.NewTimerService(dl.set, name)
+		svc.SetSchedule(desc.TimerInterval, desc.TimerBootDelay, desc.TimerCalendar)
+		return svc
+	case service.TypeSocketActivated:
+		svc := service.NewSocketActivatedService(dl.set, name)
+		svc.SetCommand(desc.Command)
+		svc.SetWorkingDir(desc.WorkingDir)
+		svc.SetEnvFile(desc.EnvFile)
+		svc.SetSocketActivationMode(desc.SocketActivationMode)
+		dl.applyRunAs(svc, desc)
+		return svc
 	default:
 		return service.NewInternalService(dl.set, name)
 	}
@@ -1315,7 +1637,7 @@ func (dl *DirLoader) loadDependencies(svc service.Service, desc *ServiceDescript
 
 	for _, spec := range depSpecs {
 		for _, depName := range spec.names {
-			depSvc, err := dl.LoadService(depName)
+			depSvc, err := dl.loadDep(depName)
 			if err != nil {
 				if spec.optional && errors.Is(err, ErrServiceNotFound) {
 					continue
@@ -1339,21 +1661,84 @@ func (dl *DirLoader) loadDependencies(svc service.Service, desc *ServiceDescript
 	}
 
 	for _, spec := range dirDepSpecs {
-		for _, dir := range spec.dirs {
-			depDir := dir
-			if !filepath.IsAbs(depDir) {
-				depDir = filepath.Join(filepath.Dir(filePath), dir)
-			}
-			if err := dl.loadDepsFromDir(svc, depDir, spec.depType); err != nil {
-				return err
+		if err := dl.loadDirDeps(svc, spec.dirs, filepath.Dir(filePath), spec.depType); err != nil {
+			return err
+		}
+	}
+	svc.Record().SetWaitsForDirs(WaitsForDirs(desc.WaitsForD, filepath.Dir(filePath)))
+
+	return nil
+}
+
+// WaitsForDirs returns the plain directories among waits-for.d
+// entries, relative ones taken from baseDir. Glob patterns and '!'
+// exclusions are skipped: slinitctl enable needs a directory it can add
+// a link to.
+func WaitsForDirs(entries []string, baseDir string) []string {
+	var dirs []string
+	for _, entry := range entries {
+		if strings.HasPrefix(filepath.Base(entry), "!") || hasGlobMeta(entry) {
+			continue
+		}
+		if !filepath.IsAbs(entry) {
+			entry = filepath.Join(baseDir, entry)
+		}
+		dirs = append(dirs, filepath.Clean(entry))
+	}
+	return dirs
+}
+
+// loadDirDeps loads the entries of one directory dependency setting
+// (depends-on.d etc.). An entry is a directory, whose files all become
+// dependencies, or a glob pattern such as wants/*.service. An entry
+// whose last element starts with '!' (wanted.d/!legacy-*) excludes the
+// matching files from every other entry of the setting, and on its own
+// stands for the rest of that directory. Relative entries are taken
+// from baseDir.
+func (dl *DirLoader) loadDirDeps(svc service.Service, entries []string, baseDir string, depType service.DependencyType) error {
+	var includes, exclude []string
+	for _, entry := range entries {
+		if !filepath.IsAbs(entry) {
+			entry = filepath.Join(baseDir, entry)
+		}
+		dir, base := filepath.Split(entry)
+		if pat, ok := strings.CutPrefix(base, "!"); ok {
+			exclude = append(exclude, filepath.Join(dir, pat))
+			continue
+		}
+		includes = append(includes, entry)
+	}
+	// An exclusion for a directory nothing else names implies the
+	// directory itself.
+	for _, pat := range exclude {
+		dir := filepath.Dir(pat)
+		named := false
+		for _, inc := range includes {
+			if filepath.Clean(inc) == dir || filepath.Dir(inc) == dir {
+				named = true
+				break
 			}
 		}
+		if !named {
+			includes = append(includes, dir)
+		}
 	}
 
+	for _, inc := range includes {
+		var err error
+		if hasGlobMeta(inc) {
+			err = dl.loadDepsFromGlob(svc, inc, depType, exclude)
+		} else {
+			err = dl.loadDepsFromDir(svc, inc, depType, exclude)
+		}
+		if err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
-func (dl *DirLoader) loadDepsFromDir(svc service.Service, dir string, depType service.DependencyType) error {
+func (dl *DirLoader) loadDepsFromDir(svc service.Service, dir string, depType service.DependencyType, exclude []string) error {
 	entries, err := os.ReadDir(dir)
 	if err != nil {
 		if os.IsNotExist(err) {
@@ -1366,9 +1751,12 @@ func (dl *DirLoader) loadDepsFromDir(svc service.Service, dir string, depType se
 		if entry.IsDir() || entry.Name()[0] == '.' {
 			continue
 		}
+		if depExcluded(filepath.Join(dir, entry.Name()), exclude) {
+			continue
+		}
 
 		depName := entry.Name()
-		depSvc, err := dl.LoadService(depName)
+		depSvc, err := dl.loadDep(depName)
 		if err != nil {
 			return fmt.Errorf("loading dependency '%s' from directory '%s': %w",
 				depName, dir, err)
@@ -1379,6 +1767,48 @@ func (dl *DirLoader) loadDepsFromDir(svc service.Service, dir string, depType se
 	return nil
 }
 
+// loadDepsFromGlob adds a dependency on each file matching pattern,
+// named by its base name, skipping directories, hidden files and
+// anything matching an exclude pattern. No match is not an error.
+func (dl *DirLoader) loadDepsFromGlob(svc service.Service, pattern string, depType service.DependencyType, exclude []string) error {
+	matches, err := filepath.Glob(pattern)
+	if err != nil {
+		return fmt.Errorf("dependency pattern %s: %w", pattern, err)
+	}
+	for _, path := range matches {
+		depName := filepath.Base(path)
+		if depName[0] == '.' || depExcluded(path, exclude) {
+			continue
+		}
+		if fi, err := os.Stat(path); err != nil || fi.IsDir() {
+			continue
+		}
+		depSvc, err := dl.loadDep(depName)
+		if err != nil {
+			return fmt.Errorf("loading dependency '%s' matching '%s': %w",
+				depName, pattern, err)
+		}
+		svc.Record().AddDep(depSvc, depType)
+	}
+	return nil
+}
+
+// depExcluded reports whether path matches one of the exclusion
+// patterns of a directory dependency setting.
+func depExcluded(path string, exclude []string) bool {
+	for _, pat := range exclude {
+		if ok, _ := filepath.Match(pat, path); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// hasGlobMeta reports whether s contains a filepath.Match metacharacter.
+func hasGlobMeta(s string) bool {
+	return strings.ContainsAny(s, "*?[")
+}
+
 // logSettable is implemented by process-based services that support log configuration.
 type logSettable interface {
 	SetLogType(service.LogType)
@@ -1408,6 +1838,8 @@ func applyLogSettings(svc logSettable, desc *ServiceDescription) {
 	case service.LogToBuffer:
 		svc.SetLogType(desc.LogType)
 		svc.SetLogBufMax(desc.LogBufMax)
+		// A logfile alongside the buffer tees output into both.
+		svc.SetLogFileDetails(desc.LogFile, desc.LogFilePerms, desc.LogFileUID, desc.LogFileGID)
 	case service.LogToPipe:
 		svc.SetLogType(desc.LogType)
 	case service.LogToFile:
@@ -1459,11 +1891,14 @@ func applyToService(svc service.Service, desc *ServiceDescription) {
 	rec.SetFailureAction(desc.FailureAction)
 	rec.SetSuccessAction(desc.SuccessAction)
 	rec.SetStartLimitAction(desc.StartLimitAction)
+	rec.SetConsecutiveFailureThreshold(desc.ConsecutiveFailureThreshold)
+	rec.SetBackoffPolicy(desc.RestartBackoff, desc.RestartBackoffMax, desc.RestartBackoffReset)
 	rec.SetRebootArgument(desc.RebootArgument)
 	rec.SetRuntimeMax(desc.RuntimeMaxSec)
 	rec.SetRuntimeMaxExtra(desc.RuntimeRandomizedExtra)
 	rec.SetJobTimeout(desc.JobTimeoutSec)
 	rec.SetOOMPolicy(desc.OOMPolicy)
+	rec.SetWatchFile(desc.WatchFile)
 	rec.SetPSIMemoryWatch(desc.MemoryPressureWatch, desc.MemoryPressureThreshold)
 	rec.SetPSICPUWatch(desc.CPUPressureWatch, desc.CPUPressureThreshold)
 	rec.SetPSIIOWatch(desc.IOPressureWatch, desc.IOPressureThreshold)
@@ -1514,8 +1949,12 @@ func applyToService(svc service.Service, desc *ServiceDescription) {
 	rec.SetRestartMode(desc.RestartMode)
 	rec.SetNormalExitSignals(desc.NormalExitSignals)
 	rec.SetFlags(desc.Flags)
+	rec.SetHighConsolePriority(desc.ConsolePriorityHigh)
+	rec.SetPriority(desc.Priority)
 	rec.SetTermSignal(desc.TermSignal)
+	rec.SetStopSignalSequence(desc.StopSignalSequence)
 	rec.SetReloadSignal(desc.ReloadSignal)
+	rec.SetNotifyShutdown(desc.NotifyShutdownSignal, desc.NotifyShutdownDelay)
 	if desc.ChainTo != "" {
 		rec.SetChainTo(desc.ChainTo)
 	}
@@ -1528,6 +1967,7 @@ func applyToService(svc service.Service, desc *ServiceDescription) {
 	if desc.Provides != "" {
 		rec.SetProvides(desc.Provides)
 	}
+	rec.SetAliases(desc.Aliases)
 	if desc.EnableVia != "" {
 		rec.SetEnableVia(desc.EnableVia)
 	}
@@ -1535,19 +1975,14 @@ func applyToService(svc service.Service, desc *ServiceDescription) {
 		rec.SetUtmpDetails(desc.InittabID, desc.InittabLine)
 	}
 
-	// Process attributes
-	if desc.Nice != nil {
-		rec.SetNice(desc.Nice)
-	}
-	if desc.OOMScoreAdj != nil {
-		rec.SetOOMScoreAdj(desc.OOMScoreAdj)
-	}
+	// Process attributes. Set unconditionally so a reload that drops
+	// the setting also drops the value.
+	rec.SetNice(desc.Nice)
+	rec.SetOOMScoreAdj(desc.OOMScoreAdj)
 	if desc.Umask != nil {
 		rec.SetUmask(desc.Umask)
 	}
-	if desc.StartOnPathTrigger != 0 {
-		rec.SetStartOnPath(desc.StartOnPath, desc.StartOnPathTrigger)
-	}
+	rec.SetStartOnPath(desc.StartOnPath, desc.StartOnPathTrigger)
 	if desc.AppArmorLoad != "" || desc.AppArmorSwitch != "" {
 		rec.SetAppArmor(desc.AppArmorLoad, desc.AppArmorSwitch)
 	}
@@ -1606,11 +2041,10 @@ func applyToService(svc service.Service, desc *ServiceDescription) {
 	if len(cgSettings) > 0 {
 		rec.SetCgroupSettings(cgSettings)
 	}
-	if desc.IOPrio != "" {
-		class, level := parseIOPrio(desc.IOPrio)
-		if class >= 0 {
-			rec.SetIOPrio(class, level)
-		}
+	if class, level := parseIOPrio(desc.IOPrio); class >= 0 {
+		rec.SetIOPrio(class, level)
+	} else {
+		rec.SetIOPrio(0, 0)
 	}
 
 	// Resource limits
@@ -1736,6 +2170,7 @@ func applyToService(svc service.Service, desc *ServiceDescription) {
 	}
 	// Bucket B — legacy-safe niches. Threaded as individual fields on
 	// the record because they don't share a cluster gate.
+	rec.SetExecRetry(desc.ExecRetryCount, desc.ExecRetryInterval)
 	rec.SetCoredumpFilter(desc.CoredumpFilter)
 	rec.SetTimerSlackNsec(desc.TimerSlackNsec)
 	rec.SetMemoryKSM(desc.MemoryKSM)
@@ -1766,10 +2201,15 @@ func applyToService(svc service.Service, desc *ServiceDescription) {
 	rec.SetKillMode(desc.KillMode)
 
 	// Bucket D — env + credential pipeline.
+	rec.SetConfigEnv(desc.Env)
 	rec.SetPassEnvironment(desc.PassEnvironment, desc.PassEnvSet)
 	rec.SetUnsetEnvironment(desc.UnsetEnvironment)
 	rec.SetExecSearchPath(desc.ExecSearchPath)
+	rec.SetLDPreload(desc.LDPreload)
+	rec.SetLDLibraryPath(desc.LDLibraryPath)
+	rec.SetPreloadSecurityCheck(desc.PreloadSecurityCheck)
 	rec.SetStandardInput(desc.StandardInput, desc.StandardInputSet)
+	rec.SetStdioRedirects(desc.StdinRedirect, desc.StdinPath, desc.StderrRedirect, desc.StderrPath)
 	if len(desc.OpenFiles) > 0 {
 		ofs := make([]service.OpenFileRecord, len(desc.OpenFiles))
 		for i, f := range desc.OpenFiles {
@@ -1959,10 +2399,13 @@ func applyLoadOptions(svc service.Service, desc *ServiceDescription) {
 		var u *user.User
 		var err error
 		if desc.RunAs != "" {
-			// Try as username first, then as UID
-			u, err = user.Lookup(desc.RunAs)
+			// Try as username first, then as UID. Only the user half
+			// of a "user:group" spec names the passwd entry.
+			name, _, _ := strings.Cut(desc.RunAs, ":")
+			name = strings.TrimSpace(name)
+			u, err = user.Lookup(name)
 			if err != nil {
-				u, err = user.LookupId(desc.RunAs)
+				u, err = user.LookupId(name)
 			}
 		} else {
 			u, err = user.LookupId(fmt.Sprintf("%d", os.Getuid()))
@@ -1972,7 +2415,12 @@ func applyLoadOptions(svc service.Service, desc *ServiceDescription) {
 			rec.SetEnvVar("LOGNAME", u.Username)
 			rec.SetEnvVar("HOME", u.HomeDir)
 			rec.SetEnvVar("UID", u.Uid)
-			rec.SetEnvVar("GID", u.Gid)
+			gid := u.Gid
+			if _, g, ok := resolveRunAs(desc.RunAs); ok {
+				// Report the group the process actually runs as.
+				gid = strconv.FormatUint(uint64(g), 10)
+			}
+			rec.SetEnvVar("GID", gid)
 			// Shell: os/user doesn't expose shell, read from /etc/passwd
 			if shell := lookupShell(u.Uid); shell != "" {
 				rec.SetEnvVar("SHELL", shell)
@@ -1984,7 +2432,9 @@ func applyLoadOptions(svc service.Service, desc *ServiceDescription) {
 // resolveRunAs decodes a `run-as = <user>[:<group>]` value into the
 // numeric UID/GID pair slinit hands to SysProcAttr.Credential. Each
 // component accepts a name or a numeric id (matching most other init
-// systems). Returns (0, 0, false) when the user can't be resolved —
+// systems); a numeric group need not have an /etc/group entry. A group
+// that is neither falls back to the user's primary group. Returns
+// (0, 0, false) when the user can't be resolved —
 // the caller logs and skips, rather than refusing to load the
 // service, because dropping the description for a typoed user would
 // surprise admins more than logging would.
@@ -2021,6 +2471,8 @@ func resolveRunAs(spec string) (uid uint32, gid uint32, ok bool) {
 			if ggid, perr := strconv.ParseUint(g.Gid, 10, 32); perr == nil {
 				gid64 = ggid
 			}
+		} else if ggid, perr := strconv.ParseUint(groupPart, 10, 32); perr == nil {
+			gid64 = ggid
 		}
 	}
 
@@ -2049,6 +2501,8 @@ func (dl *DirLoader) applyRunAs(svc service.Service, desc *ServiceDescription) {
 		s.SetRunAs(uid, gid)
 	case *service.BGProcessService:
 		s.SetRunAs(uid, gid)
+	case *service.SocketActivatedService:
+		s.SetRunAs(uid, gid)
 	}
 }
 
@@ -2209,20 +2663,24 @@ const (
 	rlimitAs     = syscall.RLIMIT_AS     // 9
 )
 
-// applyRlimits adds parsed resource limits to the service record.
+// applyRlimits installs the parsed resource limits on the service
+// record, replacing any from a previous load so a reload neither
+// duplicates a limit nor keeps one that was removed.
 func applyRlimits(rec *service.ServiceRecord, desc *ServiceDescription) {
+	var limits []process.Rlimit
 	if desc.RlimitNofile != nil {
-		rec.AddRlimit(process.Rlimit{Resource: rlimitNofile, Soft: desc.RlimitNofile[0], Hard: desc.RlimitNofile[1]})
+		limits = append(limits, process.Rlimit{Resource: rlimitNofile, Soft: desc.RlimitNofile[0], Hard: desc.RlimitNofile[1]})
 	}
 	if desc.RlimitCore != nil {
-		rec.AddRlimit(process.Rlimit{Resource: rlimitCore, Soft: desc.RlimitCore[0], Hard: desc.RlimitCore[1]})
+		limits = append(limits, process.Rlimit{Resource: rlimitCore, Soft: desc.RlimitCore[0], Hard: desc.RlimitCore[1]})
 	}
 	if desc.RlimitData != nil {
-		rec.AddRlimit(process.Rlimit{Resource: rlimitData, Soft: desc.RlimitData[0], Hard: desc.RlimitData[1]})
+		limits = append(limits, process.Rlimit{Resource: rlimitData, Soft: desc.RlimitData[0], Hard: desc.RlimitData[1]})
 	}
 	if desc.RlimitAs != nil {
-		rec.AddRlimit(process.Rlimit{Resource: rlimitAs, Soft: desc.RlimitAs[0], Hard: desc.RlimitAs[1]})
+		limits = append(limits, process.Rlimit{Resource: rlimitAs, Soft: desc.RlimitAs[0], Hard: desc.RlimitAs[1]})
 	}
+	rec.SetRlimits(limits)
 }
 
 // calcServiceDepth computes a service's depth as max(dep.depth + 1) over all deps.
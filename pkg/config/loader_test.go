@@ -1,6 +1,13 @@
 package config
 
-import "testing"
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sunlightlinux/slinit/pkg/process"
+	"github.com/sunlightlinux/slinit/pkg/service"
+)
 
 func TestParseIOPrio(t *testing.T) {
 	tests := []struct {
@@ -37,6 +44,9 @@ func TestParseRlimitHelper(t *testing.T) {
 		{"1024", 1024, 1024, false},
 		{"1024:4096", 1024, 4096, false},
 		{"unlimited", ^uint64(0), ^uint64(0), false},
+		{"1024:unlimited", 1024, ^uint64(0), false},
+		{"4096:1024", 0, 0, true},
+		{"unlimited:1024", 0, 0, true},
 		{"abc", 0, 0, true},
 	}
 
@@ -56,6 +66,51 @@ func TestParseRlimitHelper(t *testing.T) {
 	}
 }
 
+// A reload re-applies the description; the limits must be replaced,
+// not appended, and a limit dropped from the file must go away.
+func TestApplyRlimitsReplaces(t *testing.T) {
+	ss := service.NewServiceSet(&testReloadLogger{})
+	svc := service.NewProcessService(ss, "svc")
+	rec := svc.Record()
+
+	desc := &ServiceDescription{RlimitNofile: &[2]uint64{1024, 4096}, RlimitCore: &[2]uint64{0, 0}}
+	applyRlimits(rec, desc)
+	applyRlimits(rec, desc)
+	var params process.ExecParams
+	rec.ApplyProcessAttrs(&params)
+	if len(params.Rlimits) != 2 {
+		t.Fatalf("after two loads: %d rlimits, want 2: %v", len(params.Rlimits), params.Rlimits)
+	}
+
+	applyRlimits(rec, &ServiceDescription{RlimitCore: &[2]uint64{0, 0}})
+	params = process.ExecParams{}
+	rec.ApplyProcessAttrs(&params)
+	if len(params.Rlimits) != 1 || params.Rlimits[0].Resource != rlimitCore {
+		t.Errorf("after dropping rlimit-nofile: %v, want only core", params.Rlimits)
+	}
+}
+
+// Dropping nice / oom-score-adj / ioprio from a description must
+// clear them on reload, not leave the previous values in place.
+func TestApplyProcessAttrsCleared(t *testing.T) {
+	ss := service.NewServiceSet(&testReloadLogger{})
+	svc := service.NewProcessService(ss, "svc")
+	nice, adj := 5, -100
+	applyToService(svc, &ServiceDescription{Nice: &nice, OOMScoreAdj: &adj, IOPrio: "idle"})
+	var params process.ExecParams
+	svc.Record().ApplyProcessAttrs(&params)
+	if params.Nice == nil || *params.Nice != 5 || params.OOMScoreAdj == nil || params.IOPrioClass != 3 {
+		t.Fatalf("attrs not applied: nice=%v oom=%v ioprio=%d", params.Nice, params.OOMScoreAdj, params.IOPrioClass)
+	}
+
+	applyToService(svc, &ServiceDescription{})
+	params = process.ExecParams{}
+	svc.Record().ApplyProcessAttrs(&params)
+	if params.Nice != nil || params.OOMScoreAdj != nil || params.IOPrioClass != 0 {
+		t.Errorf("attrs kept after reload: nice=%v oom=%v ioprio=%d", params.Nice, params.OOMScoreAdj, params.IOPrioClass)
+	}
+}
+
 // TestResolveAlertLevel pins the fallback matrix: file empty →
 // disabled (-1), file set + level parsed → level unchanged, file set +
 // level default (-1) → warn (4) so operators declaring only the file
@@ -81,3 +136,23 @@ func TestResolveAlertLevel(t *testing.T) {
 		}
 	}
 }
+
+func TestZeroStopTimeoutApplied(t *testing.T) {
+	dir := t.TempDir()
+	ss := service.NewServiceSet(&testReloadLogger{})
+	loader := NewDirLoader(ss, []string{dir})
+	ss.SetLoader(loader)
+
+	// An explicit 0 means no timeout, not "use the default".
+	if err := os.WriteFile(filepath.Join(dir, "forever"),
+		[]byte("type = process\ncommand = /bin/true\nstop-timeout = 0\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	svc, err := loader.LoadService("forever")
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if got := svc.(*service.ProcessService).StopTimeout(); got != 0 {
+		t.Errorf("StopTimeout = %v, want 0", got)
+	}
+}
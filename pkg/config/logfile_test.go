@@ -125,3 +125,18 @@ func (l *testLogfileLogger) ServiceStopped(name string)               {}
 func (l *testLogfileLogger) ServiceFailed(name string, dep bool)      {}
 func (l *testLogfileLogger) Error(format string, args ...interface{}) {}
 func (l *testLogfileLogger) Info(format string, args ...interface{})  {}
+
+func TestLogTypeBufferWithLogfile(t *testing.T) {
+	for _, input := range []string{
+		"type = process\ncommand = /bin/app\nlog-type = both-buffer-and-file\nlogfile = /var/log/app.log\n",
+		"type = process\ncommand = /bin/app\nlogfile = /var/log/app.log\nlog-type = buffer\n",
+	} {
+		desc, err := Parse(strings.NewReader(input), "app", "test-file")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if desc.LogType != service.LogToBuffer || desc.LogFile != "/var/log/app.log" {
+			t.Errorf("got log-type %v, logfile %q; want buffer teed to /var/log/app.log", desc.LogType, desc.LogFile)
+		}
+	}
+}
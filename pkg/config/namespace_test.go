@@ -145,6 +145,40 @@ namespace-gid-map = 0:1000:65536
 	}
 }
 
+func TestLoaderUserNamespaceAliases(t *testing.T) {
+	dir := t.TempDir()
+	writeNSServiceFile(t, dir, "test-userns", `type = process
+command = /bin/true
+user-namespace = true
+uid-map: 0 1000 1
+uid-map: 1 100000 65536
+gid-map = 0 1000 1
+`)
+
+	ss := service.NewServiceSet(&testServiceLogger{})
+	loader := NewDirLoader(ss, []string{dir})
+	ss.SetLoader(loader)
+
+	svc, err := loader.LoadService("test-userns")
+	if err != nil {
+		t.Fatalf("LoadService: %v", err)
+	}
+
+	var params process.ExecParams
+	svc.Record().ApplyProcessAttrs(&params)
+
+	if params.Cloneflags&syscall.CLONE_NEWUSER == 0 {
+		t.Errorf("Cloneflags = %#x, want CLONE_NEWUSER", params.Cloneflags)
+	}
+	wantUID := []syscall.SysProcIDMap{{ContainerID: 0, HostID: 1000, Size: 1}, {ContainerID: 1, HostID: 100000, Size: 65536}}
+	if len(params.UidMappings) != 2 || params.UidMappings[0] != wantUID[0] || params.UidMappings[1] != wantUID[1] {
+		t.Errorf("UidMappings = %+v, want %+v", params.UidMappings, wantUID)
+	}
+	if len(params.GidMappings) != 1 || params.GidMappings[0] != (syscall.SysProcIDMap{ContainerID: 0, HostID: 1000, Size: 1}) {
+		t.Errorf("GidMappings = %+v", params.GidMappings)
+	}
+}
+
 func TestLoaderMultipleUidMappings(t *testing.T) {
 	dir := t.TempDir()
 	writeNSServiceFile(t, dir, "test-multi", `type = process
@@ -5,6 +5,7 @@ import (
 	"encoding/base64"
 	"fmt"
 	"io"
+	"math"
 	"net"
 	"os"
 	"path/filepath"
@@ -31,9 +32,14 @@ type IDMapping struct {
 	Size        int
 }
 
-// ParseIDMapping parses a "container:host:size" string into an IDMapping.
+// ParseIDMapping parses a "container:host:size" string, or the same
+// three numbers separated by whitespace as in /proc/PID/uid_map, into
+// an IDMapping.
 func ParseIDMapping(s string) (IDMapping, error) {
 	parts := strings.SplitN(s, ":", 3)
+	if len(parts) == 1 {
+		parts = strings.Fields(s)
+	}
 	if len(parts) != 3 {
 		return IDMapping{}, fmt.Errorf("invalid id mapping %q: expected container:host:size", s)
 	}
@@ -67,9 +73,13 @@ type ServiceDescription struct {
 	Name string
 	Type service.ServiceType
 
+	// Warnings holds what Validate found when the file was parsed.
+	Warnings []ValidationError
+
 	// Commands
 	Command              []string
 	Argv0                string // override argv[0] presented to the target binary (runit chpst -b)
+	MemfdExec            bool   // exec-memfd: run an in-memory copy of the binary
 	ScriptBlock          bool   // command came from a script...end script block
 	StopCommand          []string
 	FinishCommand        []string            // runs after process exits (before restart)
@@ -84,6 +94,13 @@ type ServiceDescription struct {
 	WorkingDir           string
 	EnvFile              string
 	EnvDir               string // runit-style: directory with one file per env var
+	// Env holds the KEY=VALUE pairs of `env` lines, in order. They
+	// override env-file.
+	Env []string
+	// EncryptedEnvFile: env-file encrypted with the key named by
+	// EnvEncryptionKey (keyring:NAME, file:PATH or env:VAR).
+	EncryptedEnvFile string
+	EnvEncryptionKey string
 	// EnvGenerator: executable path invoked at start-time; its stdout
 	// is parsed as KEY=VALUE lines and merged into the service env.
 	// systemd EnvironmentGenerator sibling.
@@ -95,6 +112,13 @@ type ServiceDescription struct {
 	CloseStdout          bool   // close fd 1
 	CloseStderr          bool   // close fd 2
 
+	// stdin / stderr redirection; the paths are set for StdinFile and
+	// StderrFile.
+	StdinRedirect  process.StdinMode
+	StdinPath      string
+	StderrRedirect process.StderrMode
+	StderrPath     string
+
 	// Namespace isolation (Linux clone flags)
 	NamespacePID    bool // CLONE_NEWPID
 	NamespaceMount  bool // CLONE_NEWNS
@@ -135,6 +159,17 @@ type ServiceDescription struct {
 	// so `slinitctl status` can render a "Bundle members:" section
 	// with each member's live state.
 	BundleMembers []string
+	// Members are the services a type = fanout service starts
+	// (members). Unlike bundle-of they are not dependencies: the fanout
+	// is started without waiting for them.
+	Members []string
+	// Timer settings (type = timer): the service a timer activates and
+	// its schedule. TimerCalendar, when set, replaces TimerInterval;
+	// TimerBootDelay is the delay to the first firing.
+	TimerTarget    string
+	TimerInterval  time.Duration
+	TimerBootDelay time.Duration
+	TimerCalendar  *service.CalendarSpec
 	// TypeExplicit tracks whether the config file wrote a `type =`
 	// setting. NewServiceDescription defaults Type to TypeProcess so
 	// the bare-minimum case (`command = /bin/foo`) works; the loader
@@ -168,6 +203,11 @@ type ServiceDescription struct {
 	RestartForceExitCodes []int
 	NormalExitSignals []syscall.Signal
 	Flags             service.ServiceFlags
+	// console-priority = high: queue for the console ahead of
+	// normal-priority services.
+	ConsolePriorityHigh bool
+	// priority = N: propagation queue priority, higher first.
+	Priority int
 
 	// Logging
 	LogType       service.LogType
@@ -250,6 +290,10 @@ type ServiceDescription struct {
 	// Process management
 	StopTimeout       time.Duration
 	StartTimeout      time.Duration
+	// Start/StopTimeoutSet record an explicit setting, so that 0 (no
+	// timeout) is not mistaken for "use the default".
+	StopTimeoutSet  bool
+	StartTimeoutSet bool
 	// systemd TimeoutAbortSec= — SIGABRT phase between SIGTERM and
 	// SIGKILL during a stop-timeout escalation. Zero disables.
 	TimeoutAbortSec time.Duration
@@ -272,12 +316,33 @@ type ServiceDescription struct {
 	RestartMaxDelay time.Duration
 	RestartInterval   time.Duration
 	RestartLimitCount int
+	// restart-backoff / restart-backoff-max / restart-backoff-reset:
+	// keep retrying, with doubling delays, once the restart limit is
+	// exhausted.
+	RestartBackoff      service.RestartBackoff
+	RestartBackoffMax   time.Duration
+	RestartBackoffReset time.Duration
+	// exec-retry-count / exec-retry-interval: total attempts and delay
+	// for fork/exec failures that look transient (ENOMEM, EAGAIN).
+	ExecRetryCount    int
+	ExecRetryInterval time.Duration
 	TermSignal        syscall.Signal
 	ReloadSignal      syscall.Signal // upstart-inspired; 0 = unset
+	// StopSignalSequence replaces term-signal + stop-timeout when set
+	// (stop-signal-sequence = TERM:5,INT:5,KILL).
+	StopSignalSequence []service.StopSignalStep
+	// notify-shutdown-signal / notify-shutdown-delay: signal sent when
+	// a shutdown is pending (0 = unset) and how long to wait before the
+	// term-signal, for services that drain in-flight work.
+	NotifyShutdownSignal syscall.Signal
+	NotifyShutdownDelay  time.Duration
 	PIDFile           string
+	PIDFileLocking    bool // pid-file-locking: daemon flocks its pid-file
+	CaptureStartError bool // capture-start-error: report a scripted start's stderr
 	ReadyNotification string
 	ReadyNotifyFD     int           // parsed from pipefd:N (-1 if unset)
 	ReadyNotifyVar    string        // parsed from pipevar:VARNAME
+	ReadyNotifySocket bool          // "notify": sd_notify READY=1 on $NOTIFY_SOCKET
 	WatchdogTimeout   time.Duration // 0 = disabled; piggybacks on ready-notification pipe
 
 	// Credentials
@@ -322,6 +387,14 @@ type ServiceDescription struct {
 	SocketUID        int
 	SocketGID        int
 	SocketActivation string // "immediate" (default) or "on-demand"
+	// SocketActivationMode is how a type = socket-activated service
+	// hands connections to its command.
+	SocketActivationMode service.SocketActivationMode
+
+	// Named triggers of a type = triggered service; TriggerAll is
+	// trigger-mode = all.
+	TriggerNames []string
+	TriggerAll   bool
 
 	// Chaining
 	ChainTo string
@@ -335,6 +408,10 @@ type ServiceDescription struct {
 	// Alias
 	Provides string
 
+	// Aliases are further names under which the service can be found
+	// (aliases = httpd webserver), e.g. names other distributions use.
+	Aliases []string
+
 	// Enable-via: default "from" service for enable/disable commands
 	EnableVia string
 
@@ -489,6 +566,10 @@ type ServiceDescription struct {
 	// StartLimitAction=; slinit uses start-limit-action=.
 	StartLimitAction service.SystemAction
 	RebootArgument   string
+	// ConsecutiveFailureThreshold (failure-action-threshold) force-stops
+	// the service and disables auto-restart after this many consecutive
+	// failed starts. Zero disables the check.
+	ConsecutiveFailureThreshold int
 
 	// RuntimeMaxSec is a hard cap on how long the service may stay in
 	// STARTED. Zero means no cap. When the timer fires the service is
@@ -510,6 +591,10 @@ type ServiceDescription struct {
 	// cgroup. Off by default.
 	OOMPolicy service.OOMPolicy
 
+	// WatchFile is a file (a socket, a pid-file, ...) whose removal
+	// while the service is started fails the service.
+	WatchFile string
+
 	// PSI pressure watches (cgroup v2, systemd-parity). Each *Watch bool
 	// enables monitoring of the corresponding <cgroup>/{memory,cpu,io}.pressure
 	// file. Threshold is the stall duration within a fixed 2-second window
@@ -648,6 +733,12 @@ type ServiceDescription struct {
 	UnsetEnvironment   []string
 	// ExecSearchPath overrides $PATH for the child. Empty = inherit.
 	ExecSearchPath     string
+	// LDPreload / LDLibraryPath are prepended to the child's
+	// LD_PRELOAD / LD_LIBRARY_PATH. PreloadSecurityCheck refuses
+	// preload libraries that are not root-owned or are world-writable.
+	LDPreload            []string
+	LDLibraryPath        []string
+	PreloadSecurityCheck bool
 	// StandardInput* bake stdin content: -text is a literal string,
 	// -data is base64-encoded bytes. Both feed the same runner stdin
 	// pipe; the parser stashes the raw bytes.
@@ -729,6 +820,7 @@ func NewServiceDescription(name string) *ServiceDescription {
 		SocketUID:     -1,
 		SocketGID:     -1,
 		ReadyNotifyFD: -1,
+		Priority:      service.DefaultPriority,
 		// Default sched-reset-on-fork=yes is intentional: an RT
 		// service that fork()s a shell or build script must NOT pass
 		// FIFO priority to that child, or a runaway child can starve
@@ -765,9 +857,12 @@ func (e *ParseError) Error() string {
 //   - Settings use "key = value" or "key: value" format
 //   - Dependency settings use ':' operator
 //   - Value settings use '=' operator
+//
+// The description is checked with Validate once parsed; the problems it
+// finds go into desc.Warnings and do not fail the parse.
 func Parse(r io.Reader, name string, fileName string) (*ServiceDescription, error) {
 	desc := NewServiceDescription(name)
-	return parseImpl(r, name, fileName, desc, 0, nil)
+	return validated(parseImpl(r, name, fileName, desc, 0, nil))
 }
 
 // ParseWithArg parses a service description with a service argument ($1 substitution).
@@ -775,7 +870,16 @@ func Parse(r io.Reader, name string, fileName string) (*ServiceDescription, erro
 // file and substitutes $1/${1} with the argument value.
 func ParseWithArg(r io.Reader, name string, fileName string, serviceArg string) (*ServiceDescription, error) {
 	desc := NewServiceDescription(name)
-	return parseImpl(r, name, fileName, desc, 0, &serviceArg)
+	return validated(parseImpl(r, name, fileName, desc, 0, &serviceArg))
+}
+
+// validated fills in desc.Warnings on a successful parse.
+func validated(desc *ServiceDescription, err error) (*ServiceDescription, error) {
+	if err != nil {
+		return nil, err
+	}
+	desc.Warnings = desc.Validate()
+	return desc, nil
 }
 
 // ParseOverlay parses an overlay file and merges its settings into an existing
@@ -809,6 +913,9 @@ func parseImpl(r io.Reader, name string, fileName string, desc *ServiceDescripti
 	for scanner.Scan() {
 		lineNum++
 		line := scanner.Text()
+		if serviceArg != nil {
+			line = expandSpecifiers(line, name, *serviceArg)
+		}
 
 		// Skip empty lines and comments
 		// Fast-path: most config lines have no leading whitespace
@@ -865,6 +972,9 @@ func parseImpl(r io.Reader, name string, fileName string, desc *ServiceDescripti
 			for scanner.Scan() {
 				lineNum++
 				bl := scanner.Text()
+				if serviceArg != nil {
+					bl = expandSpecifiers(bl, name, *serviceArg)
+				}
 				if strings.TrimSpace(bl) == "end script" {
 					closed = true
 					break
@@ -902,7 +1012,7 @@ func parseImpl(r io.Reader, name string, fileName string, desc *ServiceDescripti
 				FileName:    fileName,
 				Line:        lineNum,
 				Setting:     setting,
-				Message:     "unknown setting",
+				Message:     unknownSettingMessage(setting),
 			}
 		}
 
@@ -1303,6 +1413,12 @@ func applySetting(desc *ServiceDescription, setting, value string, op OperatorTy
 		}
 	case "command-argv0":
 		desc.Argv0 = expandEnvVars(value, serviceArg)
+	case "exec-memfd":
+		b, err := parseBool(value)
+		if err != nil {
+			return err
+		}
+		desc.MemfdExec = b
 	case "stop-command":
 		if op == OpPlusEqual {
 			desc.StopCommand = append(desc.StopCommand, splitCommand(expandEnvVarsForCommand(value, serviceArg))...)
@@ -1343,8 +1459,26 @@ func applySetting(desc *ServiceDescription, setting, value string, op OperatorTy
 		desc.WorkingDir = expandEnvVars(value, serviceArg)
 	case "env-file":
 		desc.EnvFile = expandEnvVars(value, serviceArg)
+	case "env":
+		key, val, ok := strings.Cut(strings.TrimSpace(value), "=")
+		if !ok || key == "" || strings.ContainsAny(key, " \t") {
+			return fmt.Errorf("env: expected KEY=VALUE, got %q", value)
+		}
+		entry := key + "=" + expandEnvVars(val, serviceArg)
+		if op == OpEquals {
+			desc.Env = []string{entry}
+		} else {
+			desc.Env = append(desc.Env, entry)
+		}
 	case "env-dir":
 		desc.EnvDir = expandEnvVars(value, serviceArg)
+	case "encrypted-env-file":
+		desc.EncryptedEnvFile = expandEnvVars(value, serviceArg)
+	case "env-encryption-key":
+		if err := process.ValidateEnvKeySpec(value); err != nil {
+			return fmt.Errorf("invalid env-encryption-key: %w", err)
+		}
+		desc.EnvEncryptionKey = value
 	case "env-generator":
 		desc.EnvGenerator = expandEnvVars(value, serviceArg)
 	case "pre-stop-hook":
@@ -1399,7 +1533,7 @@ func applySetting(desc *ServiceDescription, setting, value string, op OperatorTy
 			return err
 		}
 		desc.NamespaceIPC = b
-	case "namespace-user":
+	case "namespace-user", "user-namespace":
 		b, err := parseBool(value)
 		if err != nil {
 			return err
@@ -1411,22 +1545,22 @@ func applySetting(desc *ServiceDescription, setting, value string, op OperatorTy
 			return err
 		}
 		desc.NamespaceCgroup = b
-	case "namespace-uid-map":
+	case "namespace-uid-map", "uid-map":
 		m, err := ParseIDMapping(value)
 		if err != nil {
 			return err
 		}
-		if op == OpPlusEqual {
+		if op != OpEquals {
 			desc.NamespaceUidMap = append(desc.NamespaceUidMap, m)
 		} else {
 			desc.NamespaceUidMap = []IDMapping{m}
 		}
-	case "namespace-gid-map":
+	case "namespace-gid-map", "gid-map":
 		m, err := ParseIDMapping(value)
 		if err != nil {
 			return err
 		}
-		if op == OpPlusEqual {
+		if op != OpEquals {
 			desc.NamespaceGidMap = append(desc.NamespaceGidMap, m)
 		} else {
 			desc.NamespaceGidMap = []IDMapping{m}
@@ -1449,6 +1583,18 @@ func applySetting(desc *ServiceDescription, setting, value string, op OperatorTy
 			return err
 		}
 		desc.CloseStderr = b
+	case "stdin":
+		mode, path, err := parseStdinMode(value)
+		if err != nil {
+			return err
+		}
+		desc.StdinRedirect, desc.StdinPath = mode, path
+	case "stderr":
+		mode, path, err := parseStderrMode(value)
+		if err != nil {
+			return err
+		}
+		desc.StderrRedirect, desc.StderrPath = mode, path
 
 	// Virtual TTY
 	case "vtty":
@@ -1567,10 +1713,10 @@ func applySetting(desc *ServiceDescription, setting, value string, op OperatorTy
 			d = time.Duration(secs * float64(time.Second))
 		}
 		desc.HealthCheckDelay = d
-	case "healthcheck-max-failures":
+	case "healthcheck-max-failures", "healthcheck-retries":
 		n, err := strconv.Atoi(value)
 		if err != nil || n < 0 {
-			return fmt.Errorf("invalid healthcheck-max-failures: %s (must be >= 0)", value)
+			return fmt.Errorf("invalid %s: %s (must be >= 0)", setting, value)
 		}
 		desc.HealthCheckMaxFail = n
 	case "unhealthy-command":
@@ -1618,6 +1764,47 @@ func applySetting(desc *ServiceDescription, setting, value string, op OperatorTy
 			}
 			desc.BundleMembers = append(desc.BundleMembers, name)
 		}
+	case "members":
+		// Same list forms as bundle-of.
+		for _, raw := range strings.FieldsFunc(value, func(r rune) bool {
+			return r == ',' || r == ' ' || r == '\t'
+		}) {
+			name := expandEnvVars(raw, serviceArg)
+			if err := ValidateServiceName(name); err != nil {
+				return fmt.Errorf("invalid member name: %w", err)
+			}
+			desc.Members = append(desc.Members, name)
+		}
+	case "activates":
+		target := expandEnvVars(value, serviceArg)
+		if err := ValidateServiceName(target); err != nil {
+			return fmt.Errorf("invalid activates: %w", err)
+		}
+		desc.TimerTarget = target
+	case "interval", "on-boot-delay":
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			// Try as plain seconds
+			secs, err2 := strconv.ParseFloat(value, 64)
+			if err2 != nil {
+				return fmt.Errorf("invalid %s: %w", setting, err)
+			}
+			d = time.Duration(secs * float64(time.Second))
+		}
+		if d <= 0 {
+			return fmt.Errorf("%s must be > 0", setting)
+		}
+		if setting == "interval" {
+			desc.TimerInterval = d
+		} else {
+			desc.TimerBootDelay = d
+		}
+	case "on-calendar":
+		spec, err := service.ParseCalendar(value)
+		if err != nil {
+			return fmt.Errorf("on-calendar: %w", err)
+		}
+		desc.TimerCalendar = spec
 	case "before":
 		depName := expandEnvVars(value, serviceArg)
 		if err := ValidateServiceName(depName); err != nil {
@@ -1670,6 +1857,12 @@ func applySetting(desc *ServiceDescription, setting, value string, op OperatorTy
 			return err
 		}
 		desc.StartLimitAction = act
+	case "failure-action-threshold":
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			return fmt.Errorf("invalid %s: %s (must be >= 0)", setting, value)
+		}
+		desc.ConsecutiveFailureThreshold = n
 	case "reboot-argument":
 		desc.RebootArgument = expandEnvVars(value, serviceArg)
 	case "runtime-max-sec":
@@ -1700,6 +1893,8 @@ func applySetting(desc *ServiceDescription, setting, value string, op OperatorTy
 			return fmt.Errorf("job-timeout-sec must be >= 0")
 		}
 		desc.JobTimeoutSec = d
+	case "watch-file":
+		desc.WatchFile = expandEnvVars(value, serviceArg)
 	case "oom-policy":
 		p, err := service.ParseOOMPolicy(strings.TrimSpace(value))
 		if err != nil {
@@ -1836,12 +2031,14 @@ func applySetting(desc *ServiceDescription, setting, value string, op OperatorTy
 			return err
 		}
 		desc.StopTimeout = d
+		desc.StopTimeoutSet = true
 	case "start-timeout":
 		d, err := parseDuration(value)
 		if err != nil {
 			return err
 		}
 		desc.StartTimeout = d
+		desc.StartTimeoutSet = true
 	case "timeout-sec":
 		// systemd TimeoutSec= — convenience alias that sets both
 		// start-timeout and stop-timeout to the same value. Explicit
@@ -1852,6 +2049,8 @@ func applySetting(desc *ServiceDescription, setting, value string, op OperatorTy
 		}
 		desc.StartTimeout = d
 		desc.StopTimeout = d
+		desc.StartTimeoutSet = true
+		desc.StopTimeoutSet = true
 	case "timeout-abort-sec":
 		d, err := parseDuration(value)
 		if err != nil {
@@ -1920,17 +2119,17 @@ func applySetting(desc *ServiceDescription, setting, value string, op OperatorTy
 			return fmt.Errorf("restart-max-delay must be >= 0")
 		}
 		desc.RestartMaxDelay = d
-	case "restart-randomized-delay":
+	case "restart-randomized-delay", "restart-jitter":
 		d, err := time.ParseDuration(value)
 		if err != nil {
 			secs, err2 := strconv.ParseFloat(value, 64)
 			if err2 != nil {
-				return fmt.Errorf("invalid restart-randomized-delay: %w", err)
+				return fmt.Errorf("invalid %s: %w", setting, err)
 			}
 			d = time.Duration(secs * float64(time.Second))
 		}
 		if d < 0 {
-			return fmt.Errorf("restart-randomized-delay must be >= 0")
+			return fmt.Errorf("%s must be >= 0", setting)
 		}
 		desc.RestartRandomizedDelay = d
 	case "restart-limit-interval":
@@ -1945,6 +2144,41 @@ func applySetting(desc *ServiceDescription, setting, value string, op OperatorTy
 			return fmt.Errorf("invalid count: %w", err)
 		}
 		desc.RestartLimitCount = n
+	case "restart-backoff":
+		b, err := service.ParseRestartBackoff(strings.TrimSpace(value))
+		if err != nil {
+			return err
+		}
+		desc.RestartBackoff = b
+	case "restart-backoff-max", "restart-backoff-reset":
+		d, err := parseDuration(value)
+		if err != nil {
+			return fmt.Errorf("%s: %w", setting, err)
+		}
+		if setting == "restart-backoff-max" {
+			desc.RestartBackoffMax = d
+		} else {
+			desc.RestartBackoffReset = d
+		}
+	case "exec-retry-count":
+		n, err := strconv.Atoi(strings.TrimSpace(value))
+		if err != nil || n < 1 {
+			return fmt.Errorf("exec-retry-count: expected positive integer, got %q", value)
+		}
+		desc.ExecRetryCount = n
+	case "exec-retry-interval":
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			secs, err2 := strconv.ParseFloat(value, 64)
+			if err2 != nil {
+				return fmt.Errorf("invalid exec-retry-interval: %w", err)
+			}
+			d = time.Duration(secs * float64(time.Second))
+		}
+		if d < 0 {
+			return fmt.Errorf("exec-retry-interval must be >= 0")
+		}
+		desc.ExecRetryInterval = d
 
 	// Signal — OpenRC uses "stopsig" as the shell var name; slinit's
 	// canonical form is "term-signal", with "termsignal" kept as a dinit
@@ -1955,12 +2189,30 @@ func applySetting(desc *ServiceDescription, setting, value string, op OperatorTy
 			return err
 		}
 		desc.TermSignal = sig
+	case "stop-signal-sequence":
+		seq, err := parseStopSignalSequence(value)
+		if err != nil {
+			return fmt.Errorf("stop-signal-sequence: %w", err)
+		}
+		desc.StopSignalSequence = seq
 	case "reload-signal":
 		sig, err := parseSignal(value)
 		if err != nil {
 			return err
 		}
 		desc.ReloadSignal = sig
+	case "notify-shutdown-signal":
+		sig, err := parseSignal(value)
+		if err != nil {
+			return err
+		}
+		desc.NotifyShutdownSignal = sig
+	case "notify-shutdown-delay":
+		d, err := parseDuration(value)
+		if err != nil {
+			return err
+		}
+		desc.NotifyShutdownDelay = d
 
 	// Logging
 	case "logfile":
@@ -2171,6 +2423,18 @@ func applySetting(desc *ServiceDescription, setting, value string, op OperatorTy
 	// Process management
 	case "pid-file":
 		desc.PIDFile = expandEnvVars(value, serviceArg)
+	case "pid-file-locking":
+		b, err := parseBool(value)
+		if err != nil {
+			return fmt.Errorf("pid-file-locking: %w", err)
+		}
+		desc.PIDFileLocking = b
+	case "capture-start-error":
+		b, err := parseBool(value)
+		if err != nil {
+			return fmt.Errorf("capture-start-error: %w", err)
+		}
+		desc.CaptureStartError = b
 	case "ready-notification":
 		desc.ReadyNotification = value
 		if err := parseReadyNotification(desc, value); err != nil {
@@ -2246,6 +2510,28 @@ func applySetting(desc *ServiceDescription, setting, value string, op OperatorTy
 		default:
 			return fmt.Errorf("invalid socket-activation: %q (must be 'immediate' or 'on-demand')", value)
 		}
+	case "socket-activation-mode":
+		m, err := service.ParseSocketActivationMode(strings.TrimSpace(value))
+		if err != nil {
+			return err
+		}
+		desc.SocketActivationMode = m
+	case "trigger-names":
+		names := strings.Fields(value)
+		if op == OpPlusEqual {
+			desc.TriggerNames = append(desc.TriggerNames, names...)
+		} else {
+			desc.TriggerNames = names
+		}
+	case "trigger-mode":
+		switch value {
+		case "any":
+			desc.TriggerAll = false
+		case "all":
+			desc.TriggerAll = true
+		default:
+			return fmt.Errorf("invalid trigger-mode: %q (must be 'any' or 'all')", value)
+		}
 	case "socket-permissions":
 		perms, err := strconv.ParseInt(value, 8, 32)
 		if err != nil {
@@ -2276,6 +2562,21 @@ func applySetting(desc *ServiceDescription, setting, value string, op OperatorTy
 	// Alias
 	case "provides":
 		desc.Provides = value
+	case "aliases":
+		var got []string
+		for _, name := range strings.FieldsFunc(value, func(r rune) bool {
+			return r == ',' || r == ' ' || r == '\t'
+		}) {
+			if err := ValidateServiceName(name); err != nil {
+				return fmt.Errorf("invalid alias name: %w", err)
+			}
+			got = append(got, name)
+		}
+		if op == OpPlusEqual {
+			desc.Aliases = append(desc.Aliases, got...)
+		} else {
+			desc.Aliases = got
+		}
 
 	case "profile":
 		// Accept either "profile = a,b,c" or repeated "profile += X"
@@ -2338,6 +2639,21 @@ func applySetting(desc *ServiceDescription, setting, value string, op OperatorTy
 	// Options
 	case "options":
 		return applyOptions(desc, value, op == OpPlusEqual)
+	case "console-priority":
+		switch strings.ToLower(value) {
+		case "high":
+			desc.ConsolePriorityHigh = true
+		case "normal":
+			desc.ConsolePriorityHigh = false
+		default:
+			return fmt.Errorf("invalid console-priority %q (use high|normal)", value)
+		}
+	case "priority":
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("invalid priority: %s", value)
+		}
+		desc.Priority = n
 
 	// Process attributes
 	case "nice":
@@ -2744,6 +3060,26 @@ func applySetting(desc *ServiceDescription, setting, value string, op OperatorTy
 		}
 	case "exec-search-path":
 		desc.ExecSearchPath = strings.TrimSpace(expandEnvVars(value, serviceArg))
+	case "ld-preload", "ld-library-path":
+		// Space- or colon-separated, like the variables themselves.
+		list := strings.FieldsFunc(expandEnvVars(value, serviceArg), func(r rune) bool {
+			return r == ':' || r == ' ' || r == '\t'
+		})
+		target := &desc.LDPreload
+		if setting == "ld-library-path" {
+			target = &desc.LDLibraryPath
+		}
+		if op == OpPlusEqual {
+			*target = append(*target, list...)
+		} else {
+			*target = list
+		}
+	case "preload-security-check":
+		b, err := parseBool(value)
+		if err != nil {
+			return err
+		}
+		desc.PreloadSecurityCheck = b
 	case "standard-input-text":
 		// Literal text; append with newline separators when += is
 		// used (matches systemd's multi-line semantics). The parser
@@ -2900,6 +3236,9 @@ func applySetting(desc *ServiceDescription, setting, value string, op OperatorTy
 		}
 
 	case "ioprio":
+		if err := validateIOPrio(value); err != nil {
+			return err
+		}
 		desc.IOPrio = value
 
 	case "cgroup", "run-in-cgroup":
@@ -3159,6 +3498,14 @@ func applyType(desc *ServiceDescription, value string) error {
 		desc.Type = service.TypeInternal
 	case "triggered":
 		desc.Type = service.TypeTriggered
+	case "socket-activated":
+		desc.Type = service.TypeSocketActivated
+	case "barrier":
+		desc.Type = service.TypeBarrier
+	case "fanout":
+		desc.Type = service.TypeFanout
+	case "timer":
+		desc.Type = service.TypeTimer
 	default:
 		return fmt.Errorf("unknown service type: %s", value)
 	}
@@ -3188,6 +3535,9 @@ func applyLogType(desc *ServiceDescription, value string) error {
 		desc.LogType = service.LogToFile
 	case "buffer":
 		desc.LogType = service.LogToBuffer
+	case "both-buffer-and-file":
+		// Same as buffer; output is also teed into logfile when set.
+		desc.LogType = service.LogToBuffer
 	case "pipe":
 		desc.LogType = service.LogToPipe
 	case "command":
@@ -3323,6 +3673,35 @@ func parseBool(value string) (bool, error) {
 	}
 }
 
+// parseStdinMode parses a stdin setting: null, tty or an absolute path.
+func parseStdinMode(value string) (process.StdinMode, string, error) {
+	switch value {
+	case "null":
+		return process.StdinNull, "", nil
+	case "tty":
+		return process.StdinTTY, "", nil
+	}
+	if !filepath.IsAbs(value) {
+		return 0, "", fmt.Errorf("stdin must be null, tty or an absolute path, got %q", value)
+	}
+	return process.StdinFile, value, nil
+}
+
+// parseStderrMode parses a stderr setting: stdout, null or an absolute
+// path.
+func parseStderrMode(value string) (process.StderrMode, string, error) {
+	switch value {
+	case "stdout":
+		return process.StderrStdout, "", nil
+	case "null":
+		return process.StderrNull, "", nil
+	}
+	if !filepath.IsAbs(value) {
+		return 0, "", fmt.Errorf("stderr must be stdout, null or an absolute path, got %q", value)
+	}
+	return process.StderrFile, value, nil
+}
+
 // parseDuration parses a duration value in seconds (as a decimal number).
 func parseDuration(value string) (time.Duration, error) {
 	f, err := strconv.ParseFloat(value, 64)
@@ -3431,8 +3810,12 @@ func parseMempolicyMode(value string) (uint32, error) {
 }
 
 // parseReadyNotification parses a ready-notification value.
-// Supported formats: "pipefd:N" or "pipevar:VARNAME".
+// Supported formats: "pipefd:N", "pipevar:VARNAME" or "notify".
 func parseReadyNotification(desc *ServiceDescription, value string) error {
+	if value == "notify" {
+		desc.ReadyNotifySocket = true
+		return nil
+	}
 	if strings.HasPrefix(value, "pipefd:") {
 		fdStr := value[7:]
 		fd, err := strconv.Atoi(fdStr)
@@ -3450,7 +3833,24 @@ func parseReadyNotification(desc *ServiceDescription, value string) error {
 		desc.ReadyNotifyVar = varName
 		return nil
 	}
-	return fmt.Errorf("unrecognised ready-notification setting: %s (expected pipefd:N or pipevar:VARNAME)", value)
+	return fmt.Errorf("unrecognised ready-notification setting: %s (expected pipefd:N, pipevar:VARNAME or notify)", value)
+}
+
+// validateIOPrio checks an ioprio value: a class (realtime/rt,
+// best-effort/be, idle, or 0..3) optionally followed by ":level" with
+// level 0..7. parseIOPrio is lenient about the level; this is not, so a
+// typo is a parse error instead of a silently different priority.
+func validateIOPrio(value string) error {
+	if class, _ := parseIOPrio(value); class < 0 {
+		return fmt.Errorf("invalid ioprio: %s (expected realtime|best-effort|idle[:0..7])", value)
+	}
+	if _, lvl, ok := strings.Cut(value, ":"); ok {
+		n, err := strconv.Atoi(strings.TrimSpace(lvl))
+		if err != nil || n < 0 || n > 7 {
+			return fmt.Errorf("invalid ioprio level: %s (expected 0..7)", lvl)
+		}
+	}
+	return nil
 }
 
 // parseRlimit parses an rlimit value. Formats: "N" (both soft and hard),
@@ -3479,6 +3879,11 @@ func parseRlimit(value string) (*[2]uint64, error) {
 		if err != nil {
 			return nil, err
 		}
+		// setrlimit(2) fails with EINVAL on soft > hard; catch it here
+		// rather than at every start.
+		if soft > hard {
+			return nil, fmt.Errorf("soft limit %s exceeds hard limit %s", value[:idx], value[idx+1:])
+		}
 		return &[2]uint64{soft, hard}, nil
 	}
 	v, err := parseOne(value)
@@ -3894,6 +4299,46 @@ func parseSignal(value string) (syscall.Signal, error) {
 	return syscall.Signal(n), nil
 }
 
+// parseStopSignalSequence parses a list of SIGNAL:SECONDS steps
+// separated by commas or, when there is no comma, by whitespace, e.g.
+// "SIGTERM:5,SIGINT:5,SIGKILL" or "TERM:10 HUP:5 KILL". Every step but
+// the last needs a positive timeout; the last one may omit it to wait
+// for the process indefinitely.
+func parseStopSignalSequence(value string) ([]service.StopSignalStep, error) {
+	var parts []string
+	if strings.Contains(value, ",") {
+		parts = strings.Split(value, ",")
+	} else {
+		parts = strings.Fields(value)
+	}
+	if len(parts) == 0 {
+		return nil, fmt.Errorf("no steps given")
+	}
+	seq := make([]service.StopSignalStep, 0, len(parts))
+	for i, part := range parts {
+		sigStr, timeoutStr, hasTimeout := strings.Cut(strings.TrimSpace(part), ":")
+		sig, err := parseSignal(strings.TrimSpace(sigStr))
+		if err != nil {
+			return nil, err
+		}
+		if sig == 0 {
+			return nil, fmt.Errorf("step %d: a signal is required", i+1)
+		}
+		step := service.StopSignalStep{Signal: sig}
+		if hasTimeout {
+			secs, err := strconv.ParseFloat(strings.TrimSpace(timeoutStr), 64)
+			if err != nil || !(secs > 0) || math.IsInf(secs, 1) {
+				return nil, fmt.Errorf("step %d: invalid timeout %q", i+1, timeoutStr)
+			}
+			step.TimeoutSeconds = secs
+		} else if i < len(parts)-1 {
+			return nil, fmt.Errorf("step %d: only the last step may omit its timeout", i+1)
+		}
+		seq = append(seq, step)
+	}
+	return seq, nil
+}
+
 // parseNormalExit parses an upstart-style `normal exit` value: a
 // space-separated list of decimal exit codes and/or signal names
 // (or numeric signal values). Examples:
@@ -7,6 +7,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/sunlightlinux/slinit/pkg/process"
 	"github.com/sunlightlinux/slinit/pkg/service"
 )
 
@@ -174,6 +175,28 @@ unknown-setting = value
 	}
 }
 
+func TestParseUnknownSettingSuggestion(t *testing.T) {
+	input := `
+type = process
+comand = /usr/bin/myservice
+`
+	_, err := Parse(strings.NewReader(input), "myservice", "test-file")
+	if err == nil || !strings.Contains(err.Error(), "did you mean 'command'?") {
+		t.Errorf("expected suggestion of 'command', got: %v", err)
+	}
+
+	for name, want := range map[string]string{
+		"restart-delya":       "restart-delay",
+		"stop-timout":         "stop-timeout",
+		"depend-on":           "depends-on",
+		"frobnicate-the-list": "",
+	} {
+		if got := SuggestSetting(name); got != want {
+			t.Errorf("SuggestSetting(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
+
 func TestParseInvalidOperator(t *testing.T) {
 	input := `
 type = process
@@ -403,6 +426,52 @@ reload-signal = SIGFOO`
 	}
 }
 
+func TestNotifyShutdownSettings(t *testing.T) {
+	desc, err := Parse(strings.NewReader(`type = process
+command = /bin/true
+notify-shutdown-signal = SIGUSR1
+notify-shutdown-delay = 5`), "test", "test-file")
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if desc.NotifyShutdownSignal != syscall.SIGUSR1 || desc.NotifyShutdownDelay != 5*time.Second {
+		t.Errorf("got %v / %v, want SIGUSR1 / 5s", desc.NotifyShutdownSignal, desc.NotifyShutdownDelay)
+	}
+}
+
+func TestStopSignalSequence(t *testing.T) {
+	desc, err := Parse(strings.NewReader(`type = process
+command = /bin/true
+stop-signal-sequence = SIGTERM:5, INT:0.5,KILL`), "test", "test-file")
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	want := []service.StopSignalStep{
+		{Signal: syscall.SIGTERM, TimeoutSeconds: 5},
+		{Signal: syscall.SIGINT, TimeoutSeconds: 0.5},
+		{Signal: syscall.SIGKILL},
+	}
+	if len(desc.StopSignalSequence) != len(want) {
+		t.Fatalf("got %v, want %v", desc.StopSignalSequence, want)
+	}
+	for i := range want {
+		if desc.StopSignalSequence[i] != want[i] {
+			t.Errorf("step %d = %v, want %v", i, desc.StopSignalSequence[i], want[i])
+		}
+	}
+
+	spaced, err := parseStopSignalSequence("TERM:10 HUP:5  KILL")
+	if err != nil || len(spaced) != 3 || spaced[1] != (service.StopSignalStep{Signal: syscall.SIGHUP, TimeoutSeconds: 5}) {
+		t.Errorf("whitespace-separated sequence = %v, %v", spaced, err)
+	}
+
+	for _, bad := range []string{"TERM,KILL", "TERM KILL", "TERM:0,KILL", "TERM:-1", "TERM:x", "BOGUS:5", "none:5", ""} {
+		if _, err := parseStopSignalSequence(bad); err == nil {
+			t.Errorf("parseStopSignalSequence(%q) should fail", bad)
+		}
+	}
+}
+
 // TestNormalExitPropagates verifies the parser → ServiceDescription
 // path, including the += accumulator semantics declared in
 // settings.go.
@@ -659,6 +728,13 @@ ioprio = be:4
 	if desc.IOPrio != "be:4" {
 		t.Errorf("IOPrio: got %q, expected \"be:4\"", desc.IOPrio)
 	}
+
+	for _, bad := range []string{"fast", "be:8", "rt:x", "4"} {
+		_, err := Parse(strings.NewReader("command = /bin/true\nioprio = "+bad+"\n"), "test", "test-file")
+		if err == nil || !strings.Contains(err.Error(), "ioprio") {
+			t.Errorf("ioprio = %s: expected error, got %v", bad, err)
+		}
+	}
 }
 
 func TestParseCgroup(t *testing.T) {
@@ -1872,6 +1948,26 @@ unhealthy-command = /usr/local/bin/notify-unhealthy
 	}
 }
 
+func TestParseHealthCheckRetries(t *testing.T) {
+	input := `type = scripted
+command = /usr/bin/setup
+healthcheck-command = /usr/bin/check
+healthcheck-retries = 3
+`
+	desc, err := Parse(strings.NewReader(input), "hc-svc", "test")
+	if err != nil {
+		t.Fatalf("parse failed: %v", err)
+	}
+	if desc.HealthCheckMaxFail != 3 {
+		t.Errorf("healthcheck-retries = %d, want 3", desc.HealthCheckMaxFail)
+	}
+
+	_, err = Parse(strings.NewReader("type = scripted\nhealthcheck-retries = -1\n"), "hc-svc", "test")
+	if err == nil || !strings.Contains(err.Error(), "healthcheck-retries") {
+		t.Errorf("expected healthcheck-retries error, got %v", err)
+	}
+}
+
 func TestParseHealthCheckDuration(t *testing.T) {
 	input := `type = process
 command = /bin/app
@@ -2304,6 +2400,20 @@ command-argv0 = sshd: main
 	}
 }
 
+func TestParseExecMemfd(t *testing.T) {
+	desc, err := Parse(strings.NewReader(`
+type = process
+command = /usr/sbin/agent
+exec-memfd = true
+`), "agent", "test")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !desc.MemfdExec {
+		t.Error("MemfdExec = false, want true")
+	}
+}
+
 // TestParseBundleOf covers the s6-rc-style bundle grouping directive.
 // The parser must accept comma-, space- and repeated-line forms; the
 // loader adds each member as a `depends-on:` and forces type=internal
@@ -2452,3 +2562,253 @@ command = /bin/true
 		t.Errorf("AlertLevel default = %d, want -1", desc.AlertLevel)
 	}
 }
+
+func TestParseSocketActivated(t *testing.T) {
+	input := `type = socket-activated
+command = /usr/sbin/in.echod
+socket-listen = tcp:127.0.0.1:7
+socket-activation-mode = systemd
+`
+	desc, err := Parse(strings.NewReader(input), "echo", "test")
+	if err != nil {
+		t.Fatalf("parse failed: %v", err)
+	}
+	if desc.Type != service.TypeSocketActivated {
+		t.Errorf("type = %v, want socket-activated", desc.Type)
+	}
+	if desc.SocketActivationMode != service.SocketModeSystemd {
+		t.Errorf("socket-activation-mode = %v, want systemd", desc.SocketActivationMode)
+	}
+
+	desc, err = Parse(strings.NewReader("type = socket-activated\nsocket-listen = /run/echo.sock\n"), "echo", "test")
+	if err != nil {
+		t.Fatalf("parse failed: %v", err)
+	}
+	if desc.SocketActivationMode != service.SocketModeInetd {
+		t.Errorf("default socket-activation-mode = %v, want inetd", desc.SocketActivationMode)
+	}
+
+	_, err = Parse(strings.NewReader("type = socket-activated\nsocket-activation-mode = wait\n"), "echo", "test")
+	if err == nil || !strings.Contains(err.Error(), "socket-activation-mode") {
+		t.Errorf("expected socket-activation-mode error, got %v", err)
+	}
+}
+
+func TestParseBarrierType(t *testing.T) {
+	input := `type = barrier
+waits-for: eth0
+waits-for: wlan0
+depends-on: lo
+`
+	desc, err := Parse(strings.NewReader(input), "network-ready", "test")
+	if err != nil {
+		t.Fatalf("parse failed: %v", err)
+	}
+	if desc.Type != service.TypeBarrier {
+		t.Errorf("type = %v, want barrier", desc.Type)
+	}
+}
+
+func TestParseFanoutType(t *testing.T) {
+	input := `type = fanout
+members = sshd, crond
+members: ntpd
+`
+	desc, err := Parse(strings.NewReader(input), "boot-fanout", "test")
+	if err != nil {
+		t.Fatalf("parse failed: %v", err)
+	}
+	if desc.Type != service.TypeFanout {
+		t.Errorf("type = %v, want fanout", desc.Type)
+	}
+	want := []string{"sshd", "crond", "ntpd"}
+	if strings.Join(desc.Members, " ") != strings.Join(want, " ") {
+		t.Errorf("members = %v, want %v", desc.Members, want)
+	}
+
+	_, err = Parse(strings.NewReader("type = fanout\nmembers = .hidden\n"), "boot-fanout", "test")
+	if err == nil || !strings.Contains(err.Error(), "member") {
+		t.Errorf("expected invalid member error, got %v", err)
+	}
+}
+
+func TestParseTimerType(t *testing.T) {
+	input := `type = timer
+activates = backup
+interval = 300
+on-boot-delay = 1m
+`
+	desc, err := Parse(strings.NewReader(input), "backup-timer", "test")
+	if err != nil {
+		t.Fatalf("parse failed: %v", err)
+	}
+	if desc.Type != service.TypeTimer {
+		t.Errorf("type = %v, want timer", desc.Type)
+	}
+	if desc.TimerTarget != "backup" || desc.TimerInterval != 5*time.Minute || desc.TimerBootDelay != time.Minute {
+		t.Errorf("got target %q interval %v boot delay %v", desc.TimerTarget, desc.TimerInterval, desc.TimerBootDelay)
+	}
+
+	desc, err = Parse(strings.NewReader("type = timer\nactivates = backup\non-calendar = daily\n"), "t", "test")
+	if err != nil {
+		t.Fatalf("parse failed: %v", err)
+	}
+	if desc.TimerCalendar == nil {
+		t.Error("on-calendar not parsed")
+	}
+
+	for _, bad := range []string{"interval = 0\n", "interval = soon\n", "on-calendar = never\n", "activates = .hidden\n"} {
+		if _, err := Parse(strings.NewReader("type = timer\n"+bad), "t", "test"); err == nil {
+			t.Errorf("%q: expected error", bad)
+		}
+	}
+}
+
+func TestParseEncryptedEnvFile(t *testing.T) {
+	input := `command = /bin/app
+encrypted-env-file = /etc/slinit.d/secrets.enc
+env-encryption-key = keyring:slinit-master
+`
+	desc, err := Parse(strings.NewReader(input), "app", "test")
+	if err != nil {
+		t.Fatalf("parse failed: %v", err)
+	}
+	if desc.EncryptedEnvFile != "/etc/slinit.d/secrets.enc" || desc.EnvEncryptionKey != "keyring:slinit-master" {
+		t.Errorf("got file %q key %q", desc.EncryptedEnvFile, desc.EnvEncryptionKey)
+	}
+
+	_, err = Parse(strings.NewReader("command = /bin/app\nenv-encryption-key = vault:x\n"), "app", "test")
+	if err == nil || !strings.Contains(err.Error(), "env-encryption-key") {
+		t.Errorf("expected invalid key spec error, got %v", err)
+	}
+}
+
+func TestParseEnv(t *testing.T) {
+	input := `command = /bin/app
+env = GREETING=hello world
+env += EMPTY=
+env += NAME=$1
+`
+	desc, err := ParseWithArg(strings.NewReader(input), "app@x", "test", "x")
+	if err != nil {
+		t.Fatalf("parse failed: %v", err)
+	}
+	want := []string{"GREETING=hello world", "EMPTY=", "NAME=x"}
+	if !stringSliceEq(desc.Env, want) {
+		t.Errorf("Env = %q, want %q", desc.Env, want)
+	}
+
+	desc, err = Parse(strings.NewReader("command = /bin/app\nenv = A=1\nenv = B=2\n"), "app", "test")
+	if err != nil {
+		t.Fatalf("parse failed: %v", err)
+	}
+	if !stringSliceEq(desc.Env, []string{"B=2"}) {
+		t.Errorf("env = should replace, got %q", desc.Env)
+	}
+
+	for _, bad := range []string{"NOVALUE", "=1", "A B=1"} {
+		_, err = Parse(strings.NewReader("command = /bin/app\nenv = "+bad+"\n"), "app", "test")
+		if err == nil || !strings.Contains(err.Error(), "env") {
+			t.Errorf("env = %s: expected error, got %v", bad, err)
+		}
+	}
+}
+
+func TestParseFailureActionThreshold(t *testing.T) {
+	desc, err := Parse(strings.NewReader("type = process\ncommand = /bin/app\nfailure-action-threshold = 5\n"), "svc", "test")
+	if err != nil {
+		t.Fatalf("parse failed: %v", err)
+	}
+	if desc.ConsecutiveFailureThreshold != 5 {
+		t.Errorf("failure-action-threshold = %d, want 5", desc.ConsecutiveFailureThreshold)
+	}
+
+	_, err = Parse(strings.NewReader("failure-action-threshold = many\n"), "svc", "test")
+	if err == nil || !strings.Contains(err.Error(), "failure-action-threshold") {
+		t.Errorf("expected failure-action-threshold error, got %v", err)
+	}
+}
+
+func TestParseConsolePriority(t *testing.T) {
+	input := "type = process\ncommand = /bin/sh\noptions = starts-on-console\nconsole-priority = high\n"
+	desc, err := Parse(strings.NewReader(input), "rescue", "test")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !desc.ConsolePriorityHigh {
+		t.Error("expected ConsolePriorityHigh")
+	}
+
+	_, err = Parse(strings.NewReader("type = process\ncommand = /bin/sh\nconsole-priority = urgent\n"), "rescue", "test")
+	if err == nil {
+		t.Error("expected error for unknown console-priority")
+	}
+}
+
+func TestParseLDPreload(t *testing.T) {
+	input := `type = process
+command = /bin/app
+ld-preload = /usr/lib/libjemalloc.so.2
+ld-preload += /usr/lib/libasan.so.8:/usr/lib/libfoo.so
+ld-library-path = /opt/app/lib
+preload-security-check = yes
+`
+	desc, err := Parse(strings.NewReader(input), "app", "test")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []string{"/usr/lib/libjemalloc.so.2", "/usr/lib/libasan.so.8", "/usr/lib/libfoo.so"}
+	if strings.Join(desc.LDPreload, " ") != strings.Join(want, " ") {
+		t.Errorf("LDPreload = %v, want %v", desc.LDPreload, want)
+	}
+	if len(desc.LDLibraryPath) != 1 || desc.LDLibraryPath[0] != "/opt/app/lib" {
+		t.Errorf("LDLibraryPath = %v", desc.LDLibraryPath)
+	}
+	if !desc.PreloadSecurityCheck {
+		t.Error("expected PreloadSecurityCheck")
+	}
+}
+
+func TestParseStdioRedirects(t *testing.T) {
+	input := "type = process\ncommand = /bin/app\nstdin = tty\nstderr = /var/log/app-error.log\n"
+	desc, err := Parse(strings.NewReader(input), "app", "test")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if desc.StdinRedirect != process.StdinTTY {
+		t.Errorf("StdinRedirect = %v, want StdinTTY", desc.StdinRedirect)
+	}
+	if desc.StderrRedirect != process.StderrFile || desc.StderrPath != "/var/log/app-error.log" {
+		t.Errorf("stderr = %v %q", desc.StderrRedirect, desc.StderrPath)
+	}
+
+	desc, err = Parse(strings.NewReader("type = process\ncommand = /bin/app\nstdin = /etc/app.input\nstderr = null\n"), "app", "test")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if desc.StdinRedirect != process.StdinFile || desc.StdinPath != "/etc/app.input" || desc.StderrRedirect != process.StderrNull {
+		t.Errorf("got stdin %v %q, stderr %v", desc.StdinRedirect, desc.StdinPath, desc.StderrRedirect)
+	}
+
+	for _, bad := range []string{"stdin = relative/file", "stderr = err.log"} {
+		if _, err := Parse(strings.NewReader("type = process\ncommand = /bin/app\n"+bad+"\n"), "app", "test"); err == nil {
+			t.Errorf("%s: expected error", bad)
+		}
+	}
+}
+
+func TestParseTriggerNames(t *testing.T) {
+	input := "type = triggered\ntrigger-names = network\ntrigger-names += database\ntrigger-mode = all\n"
+	desc, err := Parse(strings.NewReader(input), "app", "test")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if strings.Join(desc.TriggerNames, " ") != "network database" || !desc.TriggerAll {
+		t.Errorf("TriggerNames = %v, TriggerAll = %v", desc.TriggerNames, desc.TriggerAll)
+	}
+
+	_, err = Parse(strings.NewReader("type = triggered\ntrigger-mode = some\n"), "app", "test")
+	if err == nil {
+		t.Error("expected error for unknown trigger-mode")
+	}
+}
@@ -0,0 +1,281 @@
+package config
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// Patch files adjust individual settings of a service description
+// without replacing it: <service>.d/<priority>-<name>.patch next to the
+// service file. Each non-comment line is a normal setting line prefixed
+// with an action:
+//
+//	+depends-on: newdep        add (append to a list, or set a scalar)
+//	-depends-on: olddep        remove that value
+//	=command = /new/command    replace whatever the setting held
+//
+// This lets a package add a dependency, or an operator try a change,
+// without touching the service file shipped upstream.
+
+// PatchAction is what a patch line does to its setting.
+type PatchAction uint8
+
+const (
+	PatchAdd PatchAction = iota
+	PatchRemove
+	PatchReplace
+)
+
+// PatchOp is one parsed patch line.
+type PatchOp struct {
+	Action  PatchAction
+	Setting string
+	Value   string
+	Op      OperatorType
+	Line    int
+}
+
+// PatchSuffix is the file extension of patch files.
+const PatchSuffix = ".patch"
+
+// ParsePatch reads a patch file. Errors are *ParseError carrying the
+// line number; the caller fills in the service and file names.
+func ParsePatch(r io.Reader) ([]PatchOp, error) {
+	var ops []PatchOp
+	scanner := bufio.NewScanner(r)
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || line[0] == '#' {
+			continue
+		}
+		var action PatchAction
+		switch line[0] {
+		case '+':
+			action = PatchAdd
+		case '-':
+			action = PatchRemove
+		case '=':
+			action = PatchReplace
+		default:
+			return nil, &ParseError{Line: lineNum,
+				Message: "patch line must start with '+', '-' or '='"}
+		}
+		setting, value, op, err := parseLine(line[1:])
+		if err != nil {
+			return nil, &ParseError{Line: lineNum, Message: err.Error()}
+		}
+		if !IsKnownSetting(setting) {
+			return nil, &ParseError{Line: lineNum, Setting: setting, Message: unknownSettingMessage(setting)}
+		}
+		if !ValidOperator(setting, op) {
+			return nil, &ParseError{Line: lineNum, Setting: setting, Message: "invalid operator"}
+		}
+		ops = append(ops, PatchOp{Action: action, Setting: setting, Value: value, Op: op, Line: lineNum})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return ops, nil
+}
+
+// ApplyPatch applies ops to desc in order.
+func ApplyPatch(desc *ServiceDescription, ops []PatchOp) error {
+	return applyPatch(desc, ops, nil)
+}
+
+func applyPatch(desc *ServiceDescription, ops []PatchOp, serviceArg *string) error {
+	for _, op := range ops {
+		if err := applyPatchOp(desc, op, serviceArg); err != nil {
+			return &ParseError{ServiceName: desc.Name, Line: op.Line,
+				Setting: op.Setting, Message: err.Error()}
+		}
+	}
+	return nil
+}
+
+// applyPatchOp works out which description fields a setting line
+// touches by applying it to a fresh description, then adds, removes or
+// replaces those fields in desc. That keeps patches in step with every
+// setting the parser knows without a per-setting table.
+func applyPatchOp(desc *ServiceDescription, op PatchOp, serviceArg *string) error {
+	if op.Action == PatchAdd {
+		setOp := op.Op
+		if setOp == OpEquals && ValidOperator(op.Setting, OpPlusEqual) {
+			setOp = OpPlusEqual
+		}
+		return applySetting(desc, op.Setting, op.Value, setOp, serviceArg)
+	}
+
+	fresh := NewServiceDescription(desc.Name)
+	scratch := NewServiceDescription(desc.Name)
+	if err := applySetting(scratch, op.Setting, op.Value, op.Op, serviceArg); err != nil {
+		return err
+	}
+	dv := reflect.ValueOf(desc).Elem()
+	fv := reflect.ValueOf(fresh).Elem()
+	sv := reflect.ValueOf(scratch).Elem()
+
+	var touched []int
+	for i := 0; i < sv.NumField(); i++ {
+		if !reflect.DeepEqual(sv.Field(i).Interface(), fv.Field(i).Interface()) {
+			touched = append(touched, i)
+		}
+	}
+
+	if op.Action == PatchReplace {
+		for _, i := range touched {
+			dv.Field(i).Set(fv.Field(i))
+		}
+		return applySetting(desc, op.Setting, op.Value, op.Op, serviceArg)
+	}
+
+	for _, i := range touched {
+		if err := removeField(dv.Field(i), sv.Field(i), fv.Field(i)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+var errPatchNotSet = errors.New("value to remove is not set")
+
+// removeField takes the values in want out of cur: matching elements of
+// a slice, matching keys of a map, or the whole value (reset to def)
+// for anything else.
+func removeField(cur, want, def reflect.Value) error {
+	switch cur.Kind() {
+	case reflect.Slice:
+		for j := 0; j < want.Len(); j++ {
+			idx := -1
+			for k := 0; k < cur.Len(); k++ {
+				if reflect.DeepEqual(cur.Index(k).Interface(), want.Index(j).Interface()) {
+					idx = k
+					break
+				}
+			}
+			if idx < 0 {
+				return errPatchNotSet
+			}
+			cur.Set(reflect.AppendSlice(cur.Slice(0, idx), cur.Slice(idx+1, cur.Len())))
+		}
+	case reflect.Map:
+		for _, key := range want.MapKeys() {
+			v := cur.MapIndex(key)
+			if !v.IsValid() || !reflect.DeepEqual(v.Interface(), want.MapIndex(key).Interface()) {
+				return errPatchNotSet
+			}
+			cur.SetMapIndex(key, reflect.Value{})
+		}
+	default:
+		if !reflect.DeepEqual(cur.Interface(), want.Interface()) {
+			return errPatchNotSet
+		}
+		cur.Set(def)
+	}
+	return nil
+}
+
+// PatchFiles returns the patch files for a service in the order they
+// apply: sorted by file name (so a numeric priority prefix orders
+// them), with a file in an earlier directory masking a same-named one
+// in a later directory. For a template instance <name>.d, <base>@.d and
+// <base>.d are searched, the instance directory first.
+func PatchFiles(dirs []string, name string) ([]string, error) {
+	candidates := TemplateFileNames(name)
+	byName := make(map[string]string)
+	for _, dir := range dirs {
+		for _, cand := range candidates {
+			pdir := filepath.Join(dir, cand+".d")
+			entries, err := os.ReadDir(pdir)
+			if err != nil {
+				if os.IsNotExist(err) {
+					continue
+				}
+				return nil, err
+			}
+			for _, e := range entries {
+				fn := e.Name()
+				if e.IsDir() || !strings.HasSuffix(fn, PatchSuffix) {
+					continue
+				}
+				if _, seen := byName[fn]; !seen {
+					byName[fn] = filepath.Join(pdir, fn)
+				}
+			}
+		}
+	}
+	names := make([]string, 0, len(byName))
+	for fn := range byName {
+		names = append(names, fn)
+	}
+	sort.Strings(names)
+	paths := make([]string, len(names))
+	for i, fn := range names {
+		paths[i] = byName[fn]
+	}
+	return paths, nil
+}
+
+// ApplyPatchFile parses the patch file at path and applies it to desc.
+func ApplyPatchFile(desc *ServiceDescription, path string, serviceArg *string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return applyPatchFrom(desc, f, path, serviceArg)
+}
+
+// applyPatchFrom parses a patch read from r, the contents of path, and
+// applies it to desc.
+func applyPatchFrom(desc *ServiceDescription, r io.Reader, path string, serviceArg *string) error {
+	ops, err := ParsePatch(r)
+	if err == nil {
+		err = applyPatch(desc, ops, serviceArg)
+	}
+	var pe *ParseError
+	if errors.As(err, &pe) {
+		pe.ServiceName = desc.Name
+		pe.FileName = path
+	}
+	return err
+}
+
+// applyPatches applies every patch file for the service, after the
+// overlays and the sibling override.
+func (dl *DirLoader) applyPatches(desc *ServiceDescription, name string, serviceArg *string) error {
+	paths, err := PatchFiles(dl.ServiceDirs(), name)
+	if err != nil {
+		return &ServiceLoadError{
+			ServiceName: name,
+			Message:     fmt.Sprintf("error reading patches: %v", err),
+		}
+	}
+	for _, path := range paths {
+		f, err := os.Open(path)
+		if err != nil {
+			return &ServiceLoadError{
+				ServiceName: name,
+				Message:     fmt.Sprintf("error reading patch %s: %v", path, err),
+			}
+		}
+		err = dl.checkOpenFile(f, path, name)
+		if err == nil {
+			err = applyPatchFrom(desc, f, path, serviceArg)
+		}
+		f.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
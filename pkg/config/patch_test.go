@@ -0,0 +1,130 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/sunlightlinux/slinit/pkg/service"
+)
+
+func TestParsePatch(t *testing.T) {
+	ops, err := ParsePatch(strings.NewReader(`# comment
++depends-on: newdep
+-depends-on: olddep
+
+=command = /new/command --flag
+`))
+	if err != nil {
+		t.Fatalf("ParsePatch: %v", err)
+	}
+	want := []PatchOp{
+		{PatchAdd, "depends-on", "newdep", OpColon, 2},
+		{PatchRemove, "depends-on", "olddep", OpColon, 3},
+		{PatchReplace, "command", "/new/command --flag", OpEquals, 5},
+	}
+	if len(ops) != len(want) {
+		t.Fatalf("got %d ops, want %d", len(ops), len(want))
+	}
+	for i := range want {
+		if ops[i] != want[i] {
+			t.Errorf("op %d = %+v, want %+v", i, ops[i], want[i])
+		}
+	}
+
+	for _, bad := range []string{"depends-on: x", "+no-such-setting = 1", "+command: /bin/true"} {
+		if _, err := ParsePatch(strings.NewReader(bad)); err == nil {
+			t.Errorf("ParsePatch(%q): expected error", bad)
+		}
+	}
+}
+
+func TestApplyPatch(t *testing.T) {
+	desc, err := Parse(strings.NewReader(`type = process
+command = /usr/bin/app --old
+depends-on: a
+depends-on: b
+restart = yes
+`), "app", "app")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ops, err := ParsePatch(strings.NewReader(`+depends-on: c
+-depends-on: a
+=command = /usr/bin/app --new
+-restart = yes
++env-file = /etc/default/app
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ApplyPatch(desc, ops); err != nil {
+		t.Fatalf("ApplyPatch: %v", err)
+	}
+	if got := strings.Join(desc.DependsOn, " "); got != "b c" {
+		t.Errorf("depends-on = %q, want %q", got, "b c")
+	}
+	if got := strings.Join(desc.Command, " "); got != "/usr/bin/app --new" {
+		t.Errorf("command = %q", got)
+	}
+	if desc.AutoRestart != NewServiceDescription("app").AutoRestart {
+		t.Errorf("restart not reset to default: %v", desc.AutoRestart)
+	}
+	if desc.EnvFile != "/etc/default/app" {
+		t.Errorf("env-file = %q", desc.EnvFile)
+	}
+
+	// Removing something that is not there is reported.
+	ops, _ = ParsePatch(strings.NewReader("-depends-on: missing\n"))
+	err = ApplyPatch(desc, ops)
+	var pe *ParseError
+	if !errors.As(err, &pe) || pe.Line != 1 {
+		t.Errorf("expected line-1 ParseError, got %v", err)
+	}
+}
+
+func TestLoaderAppliesPatchFiles(t *testing.T) {
+	usrDir := t.TempDir()
+	etcDir := t.TempDir()
+	writeServiceFile(t, usrDir, "web", "type = process\ncommand = /usr/bin/web\ndepends-on: net\n")
+
+	writePatch := func(dir, name, content string) {
+		t.Helper()
+		pdir := filepath.Join(dir, "web.d")
+		if err := os.MkdirAll(pdir, 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(pdir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writePatch(usrDir, "10-db.patch", "+depends-on: db\n")
+	writePatch(usrDir, "20-cmd.patch", "=command = /usr/bin/web --packaged\n")
+	// Same name in the earlier directory masks the packaged patch.
+	writePatch(etcDir, "20-cmd.patch", "=command = /usr/bin/web --local\n")
+	writePatch(etcDir, "30-net.patch", "-depends-on: net\n")
+	writePatch(etcDir, "notes.txt", "ignored\n")
+
+	ss := service.NewServiceSet(&testReloadLogger{})
+	loader := NewDirLoader(ss, []string{etcDir, usrDir})
+	ss.SetLoader(loader)
+
+	desc, _, err := loader.findAndParseTestHelper("web")
+	if err != nil {
+		t.Fatalf("load failed: %v", err)
+	}
+	if got := strings.Join(desc.DependsOn, " "); got != "db" {
+		t.Errorf("depends-on = %q, want %q", got, "db")
+	}
+	if got := strings.Join(desc.Command, " "); got != "/usr/bin/web --local" {
+		t.Errorf("command = %q", got)
+	}
+
+	writePatch(etcDir, "40-bad.patch", "-depends-on: nothing\n")
+	if _, _, err := loader.findAndParseTestHelper("web"); err == nil ||
+		!strings.Contains(err.Error(), "40-bad.patch:1") {
+		t.Errorf("expected error naming the bad patch, got %v", err)
+	}
+}
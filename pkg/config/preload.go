@@ -0,0 +1,138 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"sync"
+)
+
+// PreloadConcurrent loads every service described in the loader's
+// service directories, with their dependencies, and returns the
+// failures joined with errors.Join (nil when all loaded).
+//
+// Reading and parsing description files dominates load time, so that
+// part runs ahead on up to maxWorkers goroutines (GOMAXPROCS when
+// maxWorkers <= 0), fanning out to each parsed service's dependencies.
+// Services are then created and wired into the set on the calling
+// goroutine in name order, exactly as LoadService would, taking parsed
+// descriptions from the prefetch and waiting on any still in flight.
+// Keeping that half serial means the dependency graph and the cycle
+// detection in dl.loading only ever see one load chain at a time, so
+// two workers can never wait on each other's half-built services.
+func (dl *DirLoader) PreloadConcurrent(maxWorkers int) error {
+	if maxWorkers <= 0 {
+		maxWorkers = runtime.GOMAXPROCS(0)
+	}
+	names := dl.DescribedNames()
+
+	pf := &descPrefetch{find: dl.parseDesc, sem: make(chan struct{}, maxWorkers)}
+	for _, name := range names {
+		pf.fetch(name)
+	}
+	dl.prefetch = pf
+	defer func() {
+		dl.prefetch = nil
+		pf.wg.Wait()
+	}()
+
+	var errs []error
+	for _, name := range names {
+		if dl.set.FindService(name, false) != nil {
+			continue
+		}
+		if _, err := dl.LoadService(name); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// DescribedNames lists the services that have a description file in
+// the service directories, sorted, each name once.
+func (dl *DirLoader) DescribedNames() []string {
+	seen := make(map[string]bool)
+	var names []string
+	for _, dir := range dl.ServiceDirs() {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, e := range entries {
+			name := e.Name()
+			if seen[name] || !isDescriptionFileName(name) || ValidateServiceName(name) != nil ||
+				!isRegularFile(filepath.Join(dir, name)) {
+				continue
+			}
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// descPrefetch parses descriptions in the background for
+// PreloadConcurrent. Each name is parsed at most once: the first fetch
+// stores an in-flight entry and later fetches and takes of the same
+// name wait for it.
+type descPrefetch struct {
+	find     func(name string) (*ServiceDescription, string, error)
+	sem      chan struct{} // bounds the number of concurrent parses
+	inFlight sync.Map      // name -> *prefetchedDesc
+	wg       sync.WaitGroup
+}
+
+type prefetchedDesc struct {
+	done  chan struct{} // closed once the fields below are set
+	taken bool          // handed to the loader; guarded by the loader goroutine
+	desc  *ServiceDescription
+	path  string
+	err   error
+}
+
+// fetch starts parsing name, and then its named dependencies, unless
+// that is already under way.
+func (pf *descPrefetch) fetch(name string) {
+	e := &prefetchedDesc{done: make(chan struct{})}
+	if _, loaded := pf.inFlight.LoadOrStore(name, e); loaded {
+		return
+	}
+	pf.wg.Add(1)
+	go func() {
+		defer pf.wg.Done()
+		pf.sem <- struct{}{}
+		e.desc, e.path, e.err = pf.find(name)
+		<-pf.sem
+		// Read the dependencies before publishing: once done is
+		// closed the loader owns, and may modify, the description.
+		var deps []string
+		if e.err == nil {
+			deps = namedDeps(e.desc, true)
+		}
+		close(e.done)
+		for _, dep := range deps {
+			pf.fetch(dep)
+		}
+	}()
+}
+
+// take returns the prefetched parse of name, waiting for it if needed,
+// or nil when name was never fetched or was already taken: the loader
+// may modify a description, so each is handed out once and a second
+// load (after a failed one, say) parses afresh.
+func (pf *descPrefetch) take(name string) *prefetchedDesc {
+	v, found := pf.inFlight.Load(name)
+	if !found {
+		return nil
+	}
+	e := v.(*prefetchedDesc)
+	<-e.done
+	if e.taken {
+		return nil
+	}
+	e.taken = true
+	return e
+}
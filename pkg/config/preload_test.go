@@ -0,0 +1,73 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/sunlightlinux/slinit/pkg/service"
+)
+
+func TestPreloadConcurrentLoadsEachServiceOnce(t *testing.T) {
+	dir, loader := newDepDirLoader(t, "base")
+	const n, libs = 60, 5
+	for i := 0; i < libs; i++ {
+		writeServiceFile(t, dir, fmt.Sprintf("lib%d", i), "type = internal\ndepends-on: base\n")
+	}
+	for i := 0; i < n; i++ {
+		// Every service shares base and two of the libs, so workers
+		// race to parse the same files.
+		writeServiceFile(t, dir, fmt.Sprintf("svc%02d", i), fmt.Sprintf(
+			"type = internal\ndepends-on: base\nwaits-for: lib%d\nwaits-for: lib%d\n", i%libs, (i+1)%libs))
+	}
+	writeServiceFile(t, dir, "base.override", "# not a service\n")
+
+	loads := make(map[string]int)
+	loader.set.OnServiceLoaded = func(svc service.Service) { loads[svc.Name()]++ }
+
+	if err := loader.PreloadConcurrent(8); err != nil {
+		t.Fatalf("PreloadConcurrent: %v", err)
+	}
+	if len(loads) != n+libs+1 {
+		t.Errorf("loaded %d services, want %d", len(loads), n+libs+1)
+	}
+	for name, count := range loads {
+		if count != 1 {
+			t.Errorf("%s loaded %d times", name, count)
+		}
+	}
+	base := loader.set.FindService("base", false)
+	for i := 0; i < n; i++ {
+		svc := loader.set.FindService(fmt.Sprintf("svc%02d", i), false)
+		if svc == nil {
+			t.Fatalf("svc%02d not loaded", i)
+		}
+		if svc.Record().Dependencies()[0].To != base {
+			t.Errorf("%s depends on a different base instance", svc.Name())
+		}
+	}
+	if loader.prefetch != nil {
+		t.Error("prefetch left behind after PreloadConcurrent")
+	}
+}
+
+func TestPreloadConcurrentJoinsErrors(t *testing.T) {
+	dir, loader := newDepDirLoader(t, "good")
+	writeServiceFile(t, dir, "orphan", "type = internal\ndepends-on: missing\n")
+	writeServiceFile(t, dir, "broken", "type = internal\nrestart = sometimes\n")
+
+	err := loader.PreloadConcurrent(0)
+	if err == nil {
+		t.Fatal("expected errors")
+	}
+	if !errors.Is(err, ErrServiceNotFound) {
+		t.Errorf("missing dependency not reported: %v", err)
+	}
+	if !strings.Contains(err.Error(), "broken") {
+		t.Errorf("parse error not reported: %v", err)
+	}
+	if loader.set.FindService("good", false) == nil {
+		t.Error("failures elsewhere kept good from loading")
+	}
+}
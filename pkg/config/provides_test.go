@@ -3,6 +3,8 @@ package config
 import (
 	"strings"
 	"testing"
+
+	"github.com/sunlightlinux/slinit/pkg/service"
 )
 
 func TestProvidesParsing(t *testing.T) {
@@ -33,3 +35,56 @@ command = /bin/sleep 60
 		t.Errorf("expected empty Provides, got '%s'", desc.Provides)
 	}
 }
+
+func TestAliasesParsing(t *testing.T) {
+	input := `
+type = process
+command = /bin/sleep 60
+aliases = httpd webserver
+aliases += www,web
+`
+	desc, err := Parse(strings.NewReader(input), "nginx", "test-file")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []string{"httpd", "webserver", "www", "web"}
+	if strings.Join(desc.Aliases, " ") != strings.Join(want, " ") {
+		t.Errorf("Aliases = %q, want %q", desc.Aliases, want)
+	}
+
+	if _, err := Parse(strings.NewReader("aliases = .hidden\n"), "svc", "test-file"); err == nil {
+		t.Error("expected error for invalid alias name")
+	}
+}
+
+func TestReloadUpdatesAliases(t *testing.T) {
+	dir := t.TempDir()
+	ss := service.NewServiceSet(&testReloadLogger{})
+	loader := NewDirLoader(ss, []string{dir})
+	ss.SetLoader(loader)
+
+	writeServiceFile(t, dir, "nginx", "type = internal\naliases = httpd webserver\n")
+	svc, err := loader.LoadService("nginx")
+	if err != nil {
+		t.Fatalf("load failed: %v", err)
+	}
+	for _, alias := range []string{"httpd", "webserver"} {
+		if ss.FindService(alias, false) != svc {
+			t.Errorf("alias %q does not resolve to nginx", alias)
+		}
+	}
+
+	writeServiceFile(t, dir, "nginx", "type = internal\naliases = httpd www\n")
+	if _, err := loader.ReloadService(svc); err != nil {
+		t.Fatalf("reload failed: %v", err)
+	}
+	if ss.FindService("webserver", false) != nil {
+		t.Error("removed alias still resolves")
+	}
+	if ss.FindService("www", false) != svc || ss.FindService("httpd", false) != svc {
+		t.Error("current aliases do not resolve to nginx")
+	}
+	if ss.FindService("nginx", false) != svc {
+		t.Error("service itself lost on alias removal")
+	}
+}
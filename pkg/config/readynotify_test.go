@@ -85,3 +85,21 @@ ready-notification = pipevar:
 		t.Errorf("expected 'empty' in error, got: %v", err)
 	}
 }
+
+func TestReadyNotificationParsingNotify(t *testing.T) {
+	input := `
+type = process
+command = /bin/sleep 60
+ready-notification = notify
+`
+	desc, err := Parse(strings.NewReader(input), "notify-svc", "test-file")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !desc.ReadyNotifySocket {
+		t.Error("expected ReadyNotifySocket to be set")
+	}
+	if desc.ReadyNotifyFD != -1 || desc.ReadyNotifyVar != "" {
+		t.Errorf("expected no pipe, got fd=%d var=%q", desc.ReadyNotifyFD, desc.ReadyNotifyVar)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/sunlightlinux/slinit/pkg/service"
 )
@@ -268,6 +269,41 @@ func TestReloadDependencyUpdate(t *testing.T) {
 	}
 }
 
+// A reload that fails after the type-specific fields and dependencies
+// were updated (here on an unknown consumer-of producer) must leave the
+// service as it was.
+func TestReloadFailureRestoresService(t *testing.T) {
+	dir := t.TempDir()
+	ss := service.NewServiceSet(&testReloadLogger{})
+	loader := NewDirLoader(ss, []string{dir})
+	ss.SetLoader(loader)
+
+	writeServiceFile(t, dir, "dep-a", "type = internal\n")
+	writeServiceFile(t, dir, "dep-b", "type = internal\n")
+	writeServiceFile(t, dir, "main-svc",
+		"type = process\ncommand = /bin/true\nstop-timeout = 5\ndepends-on: dep-a\n")
+
+	svc, err := loader.LoadService("main-svc")
+	if err != nil {
+		t.Fatalf("load failed: %v", err)
+	}
+
+	writeServiceFile(t, dir, "main-svc",
+		"type = process\ncommand = /bin/false\nstop-timeout = 20\ndepends-on: dep-b\nconsumer-of = no-such-producer\n")
+	if _, err := loader.ReloadService(svc); err == nil {
+		t.Fatal("expected reload to fail on unknown consumer-of producer")
+	}
+
+	ps := svc.(*service.ProcessService)
+	if got := ps.StopTimeout(); got != 5*time.Second {
+		t.Errorf("stop-timeout = %v after failed reload, want 5s", got)
+	}
+	deps := svc.Record().Dependencies()
+	if len(deps) != 1 || deps[0].To.Name() != "dep-a" {
+		t.Errorf("deps after failed reload = %v, want [dep-a]", deps)
+	}
+}
+
 // TestReloadUnchangedDepsDoesNotStopSoleTarget guards against the
 // bug that motivated the descDepsMatchCurrent fast-path: a reload
 // on an unchanged description used to tear down and rebuild the
@@ -454,3 +490,97 @@ func TestBundleOfRejectsIncompatibleType(t *testing.T) {
 		t.Fatal("expected type=process + bundle-of to fail load, got nil")
 	}
 }
+
+// A fanout's members are loaded with it but are not dependencies; a
+// reload picks up a changed member list, and a missing member fails the
+// load.
+func TestFanoutLoadsMembers(t *testing.T) {
+	dir := t.TempDir()
+	ss := service.NewServiceSet(&testReloadLogger{})
+	loader := NewDirLoader(ss, []string{dir})
+	ss.SetLoader(loader)
+
+	writeServiceFile(t, dir, "sshd", "type = internal\n")
+	writeServiceFile(t, dir, "crond", "type = internal\n")
+	writeServiceFile(t, dir, "boot-fanout", "type = fanout\nmembers = sshd\n")
+
+	svc, err := loader.LoadService("boot-fanout")
+	if err != nil {
+		t.Fatalf("load fanout failed: %v", err)
+	}
+	fs, ok := svc.(*service.FanoutService)
+	if !ok {
+		t.Fatalf("boot-fanout is %T, want *service.FanoutService", svc)
+	}
+	if m := fs.Members(); len(m) != 1 || m[0].Name() != "sshd" {
+		t.Fatalf("members = %v, want [sshd]", m)
+	}
+	if n := len(svc.Record().Dependencies()); n != 0 {
+		t.Errorf("fanout has %d deps, want none", n)
+	}
+
+	writeServiceFile(t, dir, "boot-fanout", "type = fanout\nmembers = sshd, crond\n")
+	if _, err := loader.ReloadService(svc); err != nil {
+		t.Fatalf("reload failed: %v", err)
+	}
+	if m := fs.Members(); len(m) != 2 || m[1].Name() != "crond" {
+		t.Errorf("members after reload = %v, want [sshd crond]", m)
+	}
+
+	writeServiceFile(t, dir, "broken-fanout", "type = fanout\nmembers = no-such-service\n")
+	if _, err := loader.LoadService("broken-fanout"); err == nil {
+		t.Error("expected load to fail on a missing member")
+	}
+	if ss.FindService("broken-fanout", false) != nil {
+		t.Error("failed fanout left in the service set")
+	}
+}
+
+// A started timer survives a reload of its file: it stays armed and
+// picks up the new schedule and target.
+func TestTimerReloadKeepsRunning(t *testing.T) {
+	dir := t.TempDir()
+	ss := service.NewServiceSet(&testReloadLogger{})
+	loader := NewDirLoader(ss, []string{dir})
+	ss.SetLoader(loader)
+
+	writeServiceFile(t, dir, "backup", "type = internal\n")
+	writeServiceFile(t, dir, "rotate", "type = internal\n")
+	writeServiceFile(t, dir, "nightly", "type = timer\nactivates = backup\ninterval = 1h\n")
+
+	svc, err := loader.LoadService("nightly")
+	if err != nil {
+		t.Fatalf("load timer failed: %v", err)
+	}
+	ts, ok := svc.(*service.TimerService)
+	if !ok {
+		t.Fatalf("nightly is %T, want *service.TimerService", svc)
+	}
+	if ts.Target() == nil || ts.Target().Name() != "backup" {
+		t.Fatalf("target = %v, want backup", ts.Target())
+	}
+	ss.StartService(svc)
+	next := ts.NextFire()
+	if svc.State() != service.StateStarted || next.IsZero() {
+		t.Fatalf("timer not armed: state %v next %v", svc.State(), next)
+	}
+
+	writeServiceFile(t, dir, "nightly", "type = timer\nactivates = rotate\ninterval = 2h\n")
+	if _, err := loader.ReloadService(svc); err != nil {
+		t.Fatalf("reload failed: %v", err)
+	}
+	if svc.State() != service.StateStarted || !ts.NextFire().Equal(next) {
+		t.Errorf("reload disturbed the timer: state %v next %v, want %v", svc.State(), ts.NextFire(), next)
+	}
+	if ts.Target().Name() != "rotate" {
+		t.Errorf("target after reload = %s, want rotate", ts.Target().Name())
+	}
+
+	writeServiceFile(t, dir, "broken", "type = timer\nactivates = no-such-service\ninterval = 1h\n")
+	if _, err := loader.LoadService("broken"); err == nil {
+		t.Error("expected load to fail on a missing target")
+	}
+	if ss.FindService("broken", false) != nil {
+		t.Error("failed timer left in the service set")
+	}
+}
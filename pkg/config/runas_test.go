@@ -5,6 +5,8 @@ import (
 	"strconv"
 	"strings"
 	"testing"
+
+	"github.com/sunlightlinux/slinit/pkg/service"
 )
 
 // Regression: desc.RunAs was parsed but never plumbed to the service
@@ -74,6 +76,59 @@ func TestResolveRunAsUserGroupSplit(t *testing.T) {
 	}
 }
 
+// A numeric group with no /etc/group entry is still a valid GID, the
+// same leniency supplementary-groups gives.
+func TestResolveRunAsNumericGroupUnlisted(t *testing.T) {
+	uid, gid, ok := resolveRunAs("0:4242424")
+	if !ok {
+		t.Fatal("resolveRunAs(\"0:4242424\"): ok = false")
+	}
+	if uid != 0 || gid != 4242424 {
+		t.Errorf("uid=%d gid=%d, want 0/4242424", uid, gid)
+	}
+}
+
+// export-passwd-vars must look up only the user half of a
+// "user:group" run-as, and report the group the process runs as.
+func TestExportPasswdVarsUserGroup(t *testing.T) {
+	if _, err := user.Lookup("root"); err != nil {
+		t.Skipf("root not resolvable: %v", err)
+	}
+	set := service.NewServiceSet(&testReloadLogger{})
+	svc := service.NewProcessService(set, "svc")
+	applyLoadOptions(svc, &ServiceDescription{ExportPasswdVars: true, RunAs: "root:4242424"})
+
+	env := svc.Record().GetAllEnv()
+	if env["USER"] != "root" {
+		t.Errorf("USER = %q, want root", env["USER"])
+	}
+	if env["GID"] != "4242424" {
+		t.Errorf("GID = %q, want 4242424", env["GID"])
+	}
+}
+
+func TestValidateRunAsUnknownUser(t *testing.T) {
+	src := `
+type = process
+command = /bin/true
+run-as = nosuchuser-acceptance-probe:adm
+`
+	desc, err := Parse(strings.NewReader(src), "svc", "test-file")
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	found := false
+	for _, w := range desc.Warnings {
+		if w.Field == "run-as" && w.Severity == LintWarning &&
+			strings.Contains(w.Message, `"nosuchuser-acceptance-probe"`) {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("no run-as warning for unknown user; warnings = %v", desc.Warnings)
+	}
+}
+
 // TestResolveSupplementaryGroupsNumeric exercises the pure-numeric
 // path so the helper is testable on hosts without a rich /etc/group.
 // De-duplication and order preservation are load-bearing: the runner
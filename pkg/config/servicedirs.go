@@ -0,0 +1,144 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+)
+
+// ServiceDirEditor is implemented by loaders whose service directories
+// can be changed while the daemon runs (slinitctl set-service-dirs,
+// add-service-dir, rm-service-dir). A change applies to the next
+// description looked up; services already loaded keep theirs until
+// they are reloaded.
+type ServiceDirEditor interface {
+	SetServiceDirs(dirs []string) error
+	AddServiceDir(dir string) error
+	RemoveServiceDir(dir string) error
+}
+
+// SetServiceDirs replaces the service directories, in search order.
+// Every entry must be an absolute path to an existing directory; on
+// error the directories are left unchanged.
+func (dl *DirLoader) SetServiceDirs(dirs []string) error {
+	if len(dirs) == 0 {
+		return fmt.Errorf("at least one service directory is required")
+	}
+	clean := make([]string, 0, len(dirs))
+	for _, d := range dirs {
+		if err := checkServiceDir(d); err != nil {
+			return err
+		}
+		d = filepath.Clean(d)
+		if slices.Contains(clean, d) {
+			return fmt.Errorf("service directory %s listed twice", d)
+		}
+		clean = append(clean, d)
+	}
+	dl.dirsMu.Lock()
+	defer dl.dirsMu.Unlock()
+	dl.dirs = clean
+	return nil
+}
+
+// AddServiceDir appends dir to the service directories, so it is
+// searched last.
+func (dl *DirLoader) AddServiceDir(dir string) error {
+	if err := checkServiceDir(dir); err != nil {
+		return err
+	}
+	dir = filepath.Clean(dir)
+	dl.dirsMu.Lock()
+	defer dl.dirsMu.Unlock()
+	if slices.ContainsFunc(dl.dirs, func(d string) bool { return sameDir(d, dir) }) {
+		return fmt.Errorf("%s is already a service directory", dir)
+	}
+	dl.dirs = append(slices.Clip(dl.dirs), dir)
+	return nil
+}
+
+// RemoveServiceDir removes dir from the service directories. The last
+// directory cannot be removed.
+func (dl *DirLoader) RemoveServiceDir(dir string) error {
+	dl.dirsMu.Lock()
+	defer dl.dirsMu.Unlock()
+	i := slices.IndexFunc(dl.dirs, func(d string) bool { return sameDir(d, dir) })
+	if i < 0 {
+		return fmt.Errorf("%s is not a service directory", dir)
+	}
+	if len(dl.dirs) == 1 {
+		return fmt.Errorf("cannot remove the only service directory")
+	}
+	dl.dirs = slices.Delete(slices.Clone(dl.dirs), i, i+1)
+	return nil
+}
+
+// checkServiceDir verifies that d can be used as a service directory.
+func checkServiceDir(d string) error {
+	if !filepath.IsAbs(d) {
+		return fmt.Errorf("service directory %q is not an absolute path", d)
+	}
+	fi, err := os.Stat(d)
+	if err != nil {
+		return err
+	}
+	if !fi.IsDir() {
+		return fmt.Errorf("%s is not a directory", d)
+	}
+	return nil
+}
+
+// sameDir reports whether configured directory d names dir. Configured
+// directories may be relative (to the daemon's working directory).
+func sameDir(d, dir string) bool {
+	abs, err := filepath.Abs(d)
+	if err != nil {
+		abs = d
+	}
+	return abs == filepath.Clean(dir)
+}
+
+// SetServiceDirs replaces the directories of the first member loader
+// that has editable service directories.
+func (cl *CompositeLoader) SetServiceDirs(dirs []string) error {
+	ed, err := cl.dirEditor()
+	if err != nil {
+		return err
+	}
+	return ed.SetServiceDirs(dirs)
+}
+
+// AddServiceDir adds dir to the first member loader that has editable
+// service directories.
+func (cl *CompositeLoader) AddServiceDir(dir string) error {
+	ed, err := cl.dirEditor()
+	if err != nil {
+		return err
+	}
+	return ed.AddServiceDir(dir)
+}
+
+// RemoveServiceDir removes dir from the first member loader that has
+// editable service directories.
+func (cl *CompositeLoader) RemoveServiceDir(dir string) error {
+	ed, err := cl.dirEditor()
+	if err != nil {
+		return err
+	}
+	return ed.RemoveServiceDir(dir)
+}
+
+func (cl *CompositeLoader) dirEditor() (ServiceDirEditor, error) {
+	for _, l := range cl.loaders {
+		// An EmbeddedLoader inherits the methods from its DirLoader
+		// but has no directories to edit.
+		if _, ok := l.(*EmbeddedLoader); ok {
+			continue
+		}
+		if ed, ok := l.(ServiceDirEditor); ok {
+			return ed, nil
+		}
+	}
+	return nil, fmt.Errorf("no loader with service directories")
+}
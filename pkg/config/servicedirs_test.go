@@ -1,8 +1,12 @@
 package config
 
 import (
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
+
+	"github.com/sunlightlinux/slinit/pkg/service"
 )
 
 // TestParseServiceDirsAllFive verifies the five *-directory settings
@@ -153,3 +157,73 @@ func TestResolveServiceDirsModeOverride(t *testing.T) {
 		t.Fatalf("expected /run/r mode 0700, got %v", dirs)
 	}
 }
+
+// A service directory added at runtime is searched by the next load;
+// one removed is not.
+func TestServiceDirsAtRuntime(t *testing.T) {
+	base, extra := t.TempDir(), t.TempDir()
+	ss := service.NewServiceSet(&testReloadLogger{})
+	loader := NewDirLoader(ss, []string{base})
+	ss.SetLoader(loader)
+
+	writeServiceFile(t, extra, "late", "type = internal\n")
+	if _, err := loader.LoadService("late"); err == nil {
+		t.Fatal("loaded a service from a directory not yet configured")
+	}
+
+	if err := loader.AddServiceDir(extra); err != nil {
+		t.Fatalf("add: %v", err)
+	}
+	if _, err := loader.LoadService("late"); err != nil {
+		t.Fatalf("load after add: %v", err)
+	}
+	if err := loader.AddServiceDir(extra); err == nil {
+		t.Error("adding a directory twice: expected error")
+	}
+
+	writeServiceFile(t, extra, "later", "type = internal\n")
+	if err := loader.RemoveServiceDir(extra); err != nil {
+		t.Fatalf("remove: %v", err)
+	}
+	if _, err := loader.LoadService("later"); err == nil {
+		t.Error("loaded a service from a removed directory")
+	}
+	if err := loader.RemoveServiceDir(base); err == nil {
+		t.Error("removing the only directory: expected error")
+	}
+}
+
+func TestSetServiceDirsRejects(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "file")
+	if err := os.WriteFile(file, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	loader := NewDirLoader(service.NewServiceSet(&testReloadLogger{}), []string{dir})
+
+	for _, dirs := range [][]string{
+		nil,
+		{"relative/dir"},
+		{filepath.Join(dir, "missing")},
+		{file},
+		{dir, dir + "/"},
+	} {
+		if err := loader.SetServiceDirs(dirs); err == nil {
+			t.Errorf("SetServiceDirs(%q): expected error", dirs)
+		}
+	}
+	if got := loader.ServiceDirs(); len(got) != 1 || got[0] != dir {
+		t.Errorf("rejected changes modified the dirs: %v", got)
+	}
+
+	// The composite loader edits its directory member, not the
+	// embedded one.
+	cl := NewCompositeLoader(NewEmbeddedLoader(service.NewServiceSet(&testReloadLogger{}), nil), loader)
+	other := t.TempDir()
+	if err := cl.SetServiceDirs([]string{other}); err != nil {
+		t.Fatalf("composite set: %v", err)
+	}
+	if got := loader.ServiceDirs(); len(got) != 1 || got[0] != other {
+		t.Errorf("composite set went elsewhere: %v", got)
+	}
+}
@@ -1,7 +1,11 @@
 // Package config implements the dinit-compatible service configuration file parser.
 package config
 
-import "strings"
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
 
 // OperatorType identifies what assignment operators a setting supports.
 type OperatorType uint8
@@ -43,6 +47,9 @@ var KnownSettings = map[string]OperatorType{
 	// service pulls up as a unit. Accepts either `=` (single-line
 	// comma/space list) or repeated `:` (one name per line).
 	"bundle-of": OpEquals | OpColon,
+	// Services a type = fanout service starts without waiting for them.
+	// Same list forms as bundle-of.
+	"members": OpEquals | OpColon,
 	// s6-log-style regex selection chain; see LogSelect in parser.go
 	// for evaluation order. Repeatable so long chains can span lines.
 	"log-select": OpEquals | OpColon,
@@ -50,6 +57,7 @@ var KnownSettings = map[string]OperatorType{
 	// Commands
 	"command":       OpEquals | OpPlusEqual,
 	"command-argv0": OpEquals, // runit chpst -b: override argv[0] presented to the exec'd target
+	"exec-memfd":    OpEquals, // run an in-memory (memfd) copy of the binary
 	"stop-command":  OpEquals | OpPlusEqual,
 
 	// Working directory
@@ -57,6 +65,12 @@ var KnownSettings = map[string]OperatorType{
 
 	// Environment
 	"env-file": OpEquals,
+	"env":      OpEquals | OpPlusEqual,
+
+	// Dynamic loader
+	"ld-preload":             OpEquals | OpPlusEqual,
+	"ld-library-path":        OpEquals | OpPlusEqual,
+	"preload-security-check": OpEquals,
 
 	// Process management
 	"run-as":                 OpEquals,
@@ -83,14 +97,25 @@ var KnownSettings = map[string]OperatorType{
 	"restart-delay-step":     OpEquals,
 	"restart-delay-cap":      OpEquals,
 	"restart-randomized-delay": OpEquals,
+	"restart-jitter":           OpEquals,
 	"restart-max-delay":      OpEquals,
 	"restart-limit-interval": OpEquals,
 	"restart-limit-count":    OpEquals,
+	"restart-backoff":        OpEquals,
+	"restart-backoff-max":    OpEquals,
+	"restart-backoff-reset":  OpEquals,
+	"exec-retry-count":       OpEquals,
+	"exec-retry-interval":    OpEquals,
 	"term-signal":            OpEquals,
 	"termsignal":             OpEquals, // deprecated alias (dinit compat)
 	"stopsig":                OpEquals, // OpenRC alias
+	"stop-signal-sequence":   OpEquals,
 	"reload-signal":          OpEquals, // upstart-inspired: signal sent by `slinitctl reload-signal`
+	"notify-shutdown-signal": OpEquals,
+	"notify-shutdown-delay":  OpEquals,
 	"pid-file":               OpEquals,
+	"pid-file-locking":       OpEquals,
+	"capture-start-error":    OpEquals,
 	"ready-notification":     OpEquals,
 	"watchdog-timeout":       OpEquals,
 
@@ -103,20 +128,28 @@ var KnownSettings = map[string]OperatorType{
 	"logfile-gid":         OpEquals,
 
 	// Socket activation
-	"socket-listen":      OpEquals | OpPlusEqual, // multiple sockets via +=
-	"socket-permissions": OpEquals,
-	"socket-uid":         OpEquals,
-	"socket-gid":         OpEquals,
-	"socket-activation":  OpEquals, // "immediate" (default) or "on-demand"
+	"socket-listen":          OpEquals | OpPlusEqual, // multiple sockets via +=
+	"socket-permissions":     OpEquals,
+	"socket-uid":             OpEquals,
+	"socket-gid":             OpEquals,
+	"socket-activation":      OpEquals, // "immediate" (default) or "on-demand"
+	"socket-activation-mode": OpEquals, // "inetd" (default) or "systemd"; type = socket-activated
+
+	// Named triggers (type = triggered)
+	"trigger-names": OpEquals | OpPlusEqual,
+	"trigger-mode":  OpEquals, // "any" (default) or "all"
 
 	// Chaining
 	"chain-to": OpEquals,
 
 	// Options (flags)
-	"options": OpEquals | OpPlusEqual,
+	"options":          OpEquals | OpPlusEqual,
+	"console-priority": OpEquals,
+	"priority":         OpEquals,
 
 	// Alias
 	"provides": OpEquals,
+	"aliases":  OpEquals | OpPlusEqual,
 
 	// Profile subsystem (runsvchdir-inspired). Services tagged with
 	// at least one profile become active only when the operator has
@@ -231,6 +264,8 @@ var KnownSettings = map[string]OperatorType{
 	"ready-check-interval": OpEquals,
 	"pre-stop-hook":        OpEquals | OpPlusEqual,
 	"env-dir":              OpEquals,
+	"encrypted-env-file":   OpEquals,
+	"env-encryption-key":   OpEquals,
 	"env-generator":        OpEquals,
 	"chroot":               OpEquals,
 	"lock-file":            OpEquals,
@@ -244,9 +279,15 @@ var KnownSettings = map[string]OperatorType{
 	"namespace-cgroup":     OpEquals,
 	"namespace-uid-map":    OpEquals | OpPlusEqual,
 	"namespace-gid-map":    OpEquals | OpPlusEqual,
+	// Aliases; uid-map/gid-map take the /proc/PID/uid_map line format
+	"user-namespace":       OpEquals,
+	"uid-map":              OpEquals | OpColon | OpPlusEqual,
+	"gid-map":              OpEquals | OpColon | OpPlusEqual,
 	"close-stdin":          OpEquals,
 	"close-stdout":         OpEquals,
 	"close-stderr":         OpEquals,
+	"stdin":                OpEquals,
+	"stderr":               OpEquals,
 
 	// Pre-start fail-fast path checks (OpenRC-inspired)
 	"required-files": OpEquals | OpPlusEqual,
@@ -381,11 +422,18 @@ var KnownSettings = map[string]OperatorType{
 	"cron-accuracy-sec":     OpEquals,
 	"cron-persistent":       OpEquals,
 
+	// Timer services (type = timer)
+	"activates":     OpEquals,
+	"interval":      OpEquals,
+	"on-boot-delay": OpEquals,
+	"on-calendar":   OpEquals,
+
 	// Continuous health checking
 	"healthcheck-command":      OpEquals | OpPlusEqual,
 	"healthcheck-interval":     OpEquals,
 	"healthcheck-delay":        OpEquals,
 	"healthcheck-max-failures": OpEquals,
+	"healthcheck-retries":      OpEquals,
 	"unhealthy-command":        OpEquals | OpPlusEqual,
 
 	// Platform keywords (OpenRC-compatible)
@@ -428,11 +476,13 @@ var KnownSettings = map[string]OperatorType{
 	"failure-action":     OpEquals,
 	"success-action":     OpEquals,
 	"start-limit-action": OpEquals,
+	"failure-action-threshold": OpEquals,
 	"reboot-argument":    OpEquals,
 	"runtime-max-sec":         OpEquals,
 	"runtime-randomized-extra": OpEquals,
 	"job-timeout-sec": OpEquals,
 	"oom-policy":      OpEquals,
+	"watch-file":      OpEquals,
 
 	// systemd-style PSI pressure watches (cgroup v2). Both keys per
 	// resource are needed to arm the trigger: the *-watch key opts in,
@@ -500,6 +550,55 @@ func isPredicateSetting(name string) bool {
 	return strings.HasPrefix(name, "condition-") || strings.HasPrefix(name, "assert-")
 }
 
+// SuggestSetting returns the known setting closest to an unknown name,
+// for a "did you mean" hint: within two edits (one for short names),
+// ties going to the alphabetically first. Returns "" if nothing is
+// that close.
+func SuggestSetting(name string) string {
+	limit := 2
+	if len(name) <= 4 {
+		limit = 1
+	}
+	best, bestDist := "", limit+1
+	for known := range KnownSettings {
+		d := editDistance(name, known)
+		if d < bestDist || (d == bestDist && known < best) {
+			best, bestDist = known, d
+		}
+	}
+	return best
+}
+
+// unknownSettingMessage is the parse error message for an unknown
+// setting, with a suggestion when there is one.
+func unknownSettingMessage(name string) string {
+	if s := SuggestSetting(name); s != "" {
+		return fmt.Sprintf("unknown setting (did you mean '%s'?)", s)
+	}
+	return "unknown setting"
+}
+
+// editDistance is the Levenshtein distance between a and b.
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
+
 // OptionFlags maps option string names to their ServiceFlags field names.
 var OptionFlags = map[string]string{
 	"runs-on-console":     "RunsOnConsole",
@@ -515,3 +614,288 @@ var OptionFlags = map[string]string{
 	"starts-rwfs":         "RWReady",
 	"starts-log":          "LogReady",
 }
+
+// Setting value types reported by GenerateSchema.
+const (
+	settingTypeBool     = "bool"
+	settingTypeDuration = "duration"
+	settingTypeString   = "string"
+	settingTypeSignal   = "signal"
+	settingTypeList     = "list"
+)
+
+// settingDescriptions documents the settings in KnownSettings for
+// GenerateSchema / GenerateManPage. Settings without an entry are still
+// listed, just without prose.
+var settingDescriptions = map[string]string{
+	"type":                   "Service type: process, bgprocess, scripted, internal, triggered, socket-activated, barrier, fanout or timer.",
+	"description":            "Human-readable one-line description shown by slinitctl status.",
+	"author":                 "Free-form author metadata.",
+	"version":                "Free-form version metadata.",
+	"usage":                  "Free-form usage hint shown by slinitctl status.",
+	"depends-on":             "Hard dependency: started before this service and stopped if it stops.",
+	"depends-ms":             "Milestone dependency: must start, but may stop afterwards without effect.",
+	"waits-for":              "Soft dependency: started first, but failure does not block this service.",
+	"prepared-by":            "Service that must complete before this one starts; not held active.",
+	"depends-on.d":           "Directory whose entries are added as depends-on dependencies.",
+	"depends-ms.d":           "Directory whose entries are added as depends-ms dependencies.",
+	"waits-for.d":            "Directory whose entries are added as waits-for dependencies.",
+	"prepared-by.d":          "Directory whose entries are added as prepared-by dependencies.",
+	"before":                 "Ordering only: this service starts before the named service.",
+	"after":                  "Ordering only: this service starts after the named service.",
+	"command":                "Command line used to start the service process.",
+	"stop-command":           "Command run to stop the service instead of sending term-signal.",
+	"exec-memfd":             "Load the service binary into a memfd and run it from memory.",
+	"working-dir":            "Working directory for service commands.",
+	"env-file":               "File of KEY=VALUE lines added to the service environment.",
+	"env":                    "KEY=VALUE added to the service environment; += adds another.",
+	"encrypted-env-file":     "AES-256-GCM encrypted env-file, decrypted with env-encryption-key.",
+	"env-encryption-key":     "Key for encrypted-env-file: keyring:NAME, file:PATH or env:VAR.",
+	"watch-file":             "File whose removal while started fails the service.",
+	"ld-preload":             "Libraries prepended to LD_PRELOAD (space- or colon-separated).",
+	"ld-library-path":        "Directories prepended to LD_LIBRARY_PATH.",
+	"preload-security-check": "Refuse ld-preload libraries that are not root-owned or are world-writable.",
+	"run-as":                 "User (and optional :group) the service runs as.",
+	"restart":                "Automatic restart policy: yes, no or on-failure.",
+	"smooth-recovery":        "Restart the process without stopping dependents.",
+	"stop-timeout":           "Time allowed for the process to stop before it is killed.",
+	"start-timeout":          "Time allowed for the service to start before it is considered failed.",
+	"restart-delay":          "Minimum delay between automatic restarts.",
+	"restart-limit-interval": "Interval over which restart-limit-count restarts are earned back.",
+	"restart-limit-count":    "Restart burst size (token-bucket capacity) for automatic restarts.",
+	"restart-backoff":        "What to do once restart-limit-count is exhausted: none (stay failed) or exponential (retry with doubling delays).",
+	"restart-backoff-max":    "Longest delay between restart-backoff retries.",
+	"restart-backoff-reset":  "How long a run must last for restart-backoff to start over from the shortest delay.",
+	"exec-retry-count":       "Total start attempts when fork/exec fails transiently (ENOMEM, EAGAIN).",
+	"exec-retry-interval":    "Delay between exec retry attempts.",
+	"term-signal":            "Signal sent to stop the service process.",
+	"stop-signal-sequence":   "Escalating stop signals with per-step timeouts, e.g. TERM:5,INT:5,KILL; replaces term-signal and stop-timeout.",
+	"notify-shutdown-signal": "Signal sent to the service process when a shutdown is pending, ahead of term-signal.",
+	"notify-shutdown-delay":  "Time the shutdown waits after notify-shutdown-signal before stopping services.",
+	"pid-file":               "PID file written by a bgprocess service.",
+	"pid-file-locking":       "Decide whether a bgprocess daemon is alive by the flock it holds on its pid-file.",
+	"capture-start-error":    "Include a failed scripted start command's stderr in the failure message.",
+	"ready-notification":     "Readiness protocol: pipefd:N, pipevar:VAR or notify (sd_notify READY=1).",
+	"logfile":                "File that receives the service output (log-type=file, or alongside log-type=buffer).",
+	"log-type":               "Output handling: none, file, buffer, both-buffer-and-file, pipe or command.",
+	"log-buffer-size":        "Size in bytes of the in-memory log buffer (log-type=buffer).",
+	"socket-listen":          "Path of a listening socket passed to the service (socket activation).",
+	"socket-activation-mode": "How a socket-activated service handles connections: inetd or systemd.",
+	"chain-to":               "Service started when this one exits successfully.",
+	"options":                "Service flags such as runs-on-console or starts-rwfs.",
+	"console-priority":       "Console queue priority for starts-on-console: normal or high (jumps the queue).",
+	"priority":               "Order of state propagation: higher runs first (system services use 10).",
+	"provides":               "Alias name under which this service can also be found.",
+	"aliases":                "Further alias names under which this service can also be found.",
+	"consumer-of":            "Service whose output is piped into this service's stdin.",
+	"load-options":           "Parser options: export-passwd-vars, export-service-name, sub-vars.",
+	"rlimit-nofile":          "Open file limit, as soft:hard.",
+	"rlimit-core":            "Core dump size limit, as soft:hard.",
+	"rlimit-data":            "Data segment size limit, as soft:hard.",
+	"rlimit-as":              "Address space limit, as soft:hard.",
+	"cgroup":                 "cgroup (v2) path the service process is placed in.",
+	"nice":                   "Scheduling niceness of the service process.",
+	"ioprio":                 "I/O priority class and level.",
+	"oom-score-adj":          "OOM killer score adjustment (-1000..1000).",
+	"umask":                  "File creation mask of the service process.",
+	"chroot":                 "Directory the service process is chrooted into.",
+	"capabilities":           "Ambient capabilities granted to the service process.",
+	"finish-command":         "Command run after the service process exits.",
+	"pre-start-command":      "Command run before the service command.",
+	"post-start-command":     "Command run after the service has started.",
+	"ready-check-command":    "Command polled until it succeeds to mark the service ready.",
+	"healthcheck-command":    "Command run periodically to verify the service is healthy.",
+	"healthcheck-interval":   "Interval between health checks.",
+	"healthcheck-retries":    "Consecutive health check failures before the service is stopped (alias of healthcheck-max-failures).",
+	"cron-command":           "Command run periodically while the service is started.",
+	"cron-interval":          "Interval between cron-command runs.",
+	"failure-action":         "System action taken when the service fails.",
+	"failure-action-threshold": "Consecutive failed starts after which auto-restart is disabled until reset-failed.",
+	"private-tmp":            "Give the service a private /tmp.",
+	"protect-system":         "Mount system directories read-only: yes, full or strict.",
+	"trigger-names":          "Named triggers a type = triggered service waits for, set with slinitctl trigger <service> <name>.",
+	"trigger-mode":           "Whether any named trigger (any, the default) or all of them (all) complete startup.",
+	"members":                "Services a type = fanout service starts; it is started without waiting for them.",
+	"activates":              "Service a type = timer service starts (or restarts) each time it fires.",
+	"interval":               "Time between firings of a type = timer service.",
+	"on-boot-delay":          "Delay from the start of a type = timer service to its first firing.",
+	"on-calendar":            "Calendar schedule of a type = timer service, replacing interval.",
+	"stdin":                  "Standard input of the service process: null, tty or an absolute file path.",
+	"stderr":                 "Standard error of the service process: stdout, null or an absolute file path (appended to).",
+}
+
+// settingTypes overrides the value type inferred by settingType.
+var settingTypes = map[string]string{
+	"smooth-recovery":     settingTypeBool,
+	"manual":              settingTypeBool,
+	"refuse-manual-start": settingTypeBool,
+	"refuse-manual-stop":  settingTypeBool,
+	"stop-when-unneeded":  settingTypeBool,
+	"debug":               settingTypeBool,
+	"new-session":         settingTypeBool,
+	"private-tmp":         settingTypeBool,
+	"vtty":                settingTypeBool,
+	"cron-persistent":     settingTypeBool,
+	"mlockall":            settingTypeBool,
+	"remove-ipc":          settingTypeBool,
+	"ignore-sigpipe":      settingTypeBool,
+	"close-stdin":         settingTypeBool,
+	"close-stdout":        settingTypeBool,
+	"close-stderr":        settingTypeBool,
+	"lock-personality":    settingTypeBool,
+	"exec-memfd":          settingTypeBool,
+	"log-sanitize":        settingTypeString,
+
+	"preload-security-check": settingTypeBool,
+
+	"restart-backoff-max":   settingTypeDuration,
+	"restart-backoff-reset": settingTypeDuration,
+}
+
+// settingDefaults lists the default value of settings whose default is
+// not simply "unset". Kept in line with NewServiceDescription.
+var settingDefaults = map[string]string{
+	"type":                "process",
+	"restart":             "no",
+	"stop-timeout":        "10",
+	"term-signal":         "TERM",
+	"log-type":            "none",
+	"logfile-permissions": "0600",
+	"socket-permissions":  "0600",
+	"sched-reset-on-fork": "yes",
+	"smooth-recovery":     "no",
+	"console-priority":    "normal",
+	"priority":            "5",
+	"restart-backoff":     "none",
+}
+
+// settingExamples gives an example value for GenerateSchema.
+var settingExamples = map[string]string{
+	"type":               "process",
+	"command":            "/usr/sbin/sshd -D",
+	"depends-on":         "network",
+	"waits-for":          "syslog",
+	"run-as":             "nobody:nogroup",
+	"restart":            "on-failure",
+	"stop-timeout":       "30",
+	"start-timeout":      "60",
+	"term-signal":        "INT",
+	"logfile":            "/var/log/sshd.log",
+	"log-type":           "buffer",
+	"ready-notification": "pipefd:3",
+	"rlimit-nofile":      "1024:4096",
+	"nice":               "5",
+	"options":            "runs-on-console",
+}
+
+// settingType reports the value type of a setting for documentation.
+func settingType(name string) string {
+	if t, ok := settingTypes[name]; ok {
+		return t
+	}
+	op := KnownSettings[name]
+	switch {
+	case op&(OpColon|OpPlusEqual) != 0:
+		return settingTypeList
+	case strings.Contains(name, "signal") || name == "termsignal" || name == "stopsig":
+		return settingTypeSignal
+	case strings.HasSuffix(name, "-timeout"), strings.HasSuffix(name, "-delay"),
+		strings.HasSuffix(name, "-interval"), strings.HasSuffix(name, "-sec"),
+		strings.HasSuffix(name, "-delay-step"), strings.HasSuffix(name, "-delay-cap"):
+		return settingTypeDuration
+	case strings.HasPrefix(name, "protect-"), strings.HasPrefix(name, "restrict-") &&
+		name != "restrict-address-families" && name != "restrict-file-systems" &&
+		name != "restrict-namespaces":
+		return settingTypeBool
+	}
+	return settingTypeString
+}
+
+// operatorString renders an OperatorType as the operators it permits.
+func operatorString(op OperatorType) string {
+	var ops []string
+	if op&OpEquals != 0 {
+		ops = append(ops, "=")
+	}
+	if op&OpColon != 0 {
+		ops = append(ops, ":")
+	}
+	if op&OpPlusEqual != 0 {
+		ops = append(ops, "+=")
+	}
+	return strings.Join(ops, " ")
+}
+
+// sortedSettingNames returns the KnownSettings keys in lexical order.
+func sortedSettingNames() []string {
+	names := make([]string, 0, len(KnownSettings))
+	for name := range KnownSettings {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// GenerateSchema renders every setting in KnownSettings as a Markdown
+// document: name, operators, type, default, example and description.
+// Used by `slinitctl help-settings`.
+func GenerateSchema() string {
+	var b strings.Builder
+	b.WriteString("# slinit service settings\n\n")
+	b.WriteString("| Setting | Operators | Type | Default | Example | Description |\n")
+	b.WriteString("|---|---|---|---|---|---|\n")
+	for _, name := range sortedSettingNames() {
+		fmt.Fprintf(&b, "| `%s` | `%s` | %s | %s | %s | %s |\n",
+			name, operatorString(KnownSettings[name]), settingType(name),
+			mdCode(settingDefaults[name]), mdCode(settingExamples[name]),
+			strings.ReplaceAll(settingDescriptions[name], "|", `\|`))
+	}
+	b.WriteString("\nDynamic settings: `control-command-SIGNAL` (`=` `+=`), " +
+		"`condition-KIND` and `assert-KIND` (`=`).\n")
+	return b.String()
+}
+
+func mdCode(s string) string {
+	if s == "" {
+		return ""
+	}
+	return "`" + s + "`"
+}
+
+// GenerateManPage renders the same content as GenerateSchema as a groff
+// man page (slinit-settings(5)).
+func GenerateManPage() string {
+	var b strings.Builder
+	b.WriteString(".TH SLINIT-SETTINGS 5 \"\" \"slinit\" \"File Formats\"\n")
+	b.WriteString(".SH NAME\nslinit-settings \\- service description settings\n")
+	b.WriteString(".SH SYNOPSIS\n.I setting\n.B =\n.I value\n.br\n.I setting\n.B :\n.I value\n.br\n.I setting\n.B +=\n.I value\n")
+	b.WriteString(".SH DESCRIPTION\nService description files consist of one setting per line. " +
+		"Each setting accepts only the operators listed below; " +
+		"\\fB+=\\fR appends to a previous value.\n")
+	b.WriteString(".SH SETTINGS\n")
+	for _, name := range sortedSettingNames() {
+		fmt.Fprintf(&b, ".TP\n.B %s\n", groffEscape(name))
+		fmt.Fprintf(&b, "Operators: %s. Type: %s.", groffEscape(operatorString(KnownSettings[name])), settingType(name))
+		if def := settingDefaults[name]; def != "" {
+			fmt.Fprintf(&b, " Default: %s.", groffEscape(def))
+		}
+		if ex := settingExamples[name]; ex != "" {
+			fmt.Fprintf(&b, " Example: %s.", groffEscape(ex))
+		}
+		b.WriteString("\n")
+		if desc := settingDescriptions[name]; desc != "" {
+			b.WriteString(".br\n" + groffEscape(desc) + "\n")
+		}
+	}
+	return b.String()
+}
+
+// groffEscape escapes backslashes, hyphens and leading control characters.
+func groffEscape(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, "-", `\-`)
+	if strings.HasPrefix(s, ".") || strings.HasPrefix(s, "'") {
+		s = `\&` + s
+	}
+	return s
+}
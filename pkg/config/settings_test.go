@@ -0,0 +1,58 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSettingDocsReferenceKnownSettings(t *testing.T) {
+	for _, m := range []map[string]string{settingDescriptions, settingTypes, settingDefaults, settingExamples} {
+		for name := range m {
+			if _, ok := KnownSettings[name]; !ok {
+				t.Errorf("documented setting %q is not in KnownSettings", name)
+			}
+		}
+	}
+}
+
+func TestGenerateSchema(t *testing.T) {
+	s := GenerateSchema()
+	for name := range KnownSettings {
+		if !strings.Contains(s, "| `"+name+"` |") {
+			t.Errorf("schema missing setting %q", name)
+		}
+	}
+	if !strings.Contains(s, "| `depends-on` | `:` | list |") {
+		t.Errorf("depends-on row has wrong operator/type:\n%s", s)
+	}
+	if !strings.Contains(s, "| `stop-timeout` | `=` | duration | `10` |") {
+		t.Errorf("stop-timeout row has wrong type/default")
+	}
+}
+
+func TestSettingType(t *testing.T) {
+	tests := map[string]string{
+		"command":       settingTypeList,
+		"term-signal":   settingTypeSignal,
+		"start-timeout": settingTypeDuration,
+		"private-tmp":   settingTypeBool,
+		"working-dir":   settingTypeString,
+	}
+	for name, want := range tests {
+		if got := settingType(name); got != want {
+			t.Errorf("settingType(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestGenerateManPage(t *testing.T) {
+	m := GenerateManPage()
+	for _, sec := range []string{".SH SYNOPSIS", ".SH DESCRIPTION", ".SH SETTINGS"} {
+		if !strings.Contains(m, sec) {
+			t.Errorf("man page missing %s", sec)
+		}
+	}
+	if !strings.Contains(m, ".B depends\\-on\n") {
+		t.Errorf("man page missing escaped depends-on entry")
+	}
+}
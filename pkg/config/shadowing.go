@@ -0,0 +1,96 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// DisabledSuffix marks a service file that has been set aside, our
+// equivalent of a masked systemd unit: <service>.disabled is never
+// loaded.
+const DisabledSuffix = ".disabled"
+
+// ShadowingWarning reports a service file that is never read because a
+// file of the same name comes first in the service directory search
+// order, or, with Masked set, a <service>.disabled file.
+type ShadowingWarning struct {
+	ServiceName string
+	// ActivePath is the file the loader uses; empty for a masked
+	// service that has no other description.
+	ActivePath string
+	// ShadowedPath is the file that is ignored: the later duplicate,
+	// or the .disabled file.
+	ShadowedPath string
+	Masked       bool
+}
+
+// CheckShadowing reports service files present in more than one of the
+// loader's service directories, and masked (.disabled) service files.
+func (dl *DirLoader) CheckShadowing() []ShadowingWarning {
+	return CheckShadowing(dl.ServiceDirs())
+}
+
+// CheckShadowing scans dirs in search order and reports every service
+// file hidden by a same-named file in an earlier directory, and every
+// <service>.disabled file. Unreadable directories are skipped. Results
+// are ordered by service name, then by directory.
+func CheckShadowing(dirs []string) []ShadowingWarning {
+	active := make(map[string]string)
+	var warnings, masked []ShadowingWarning
+	for _, dir := range dirs {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, e := range entries {
+			name := e.Name()
+			path := filepath.Join(dir, name)
+			if !isRegularFile(path) {
+				continue
+			}
+			if svc, ok := strings.CutSuffix(name, DisabledSuffix); ok && svc != "" {
+				masked = append(masked, ShadowingWarning{ServiceName: svc, ShadowedPath: path, Masked: true})
+				continue
+			}
+			if !isDescriptionFileName(name) {
+				continue
+			}
+			if first, ok := active[name]; ok {
+				warnings = append(warnings, ShadowingWarning{
+					ServiceName: name, ActivePath: first, ShadowedPath: path,
+				})
+				continue
+			}
+			active[name] = path
+		}
+	}
+	for _, m := range masked {
+		m.ActivePath = active[m.ServiceName]
+		warnings = append(warnings, m)
+	}
+	sort.SliceStable(warnings, func(i, j int) bool {
+		return warnings[i].ServiceName < warnings[j].ServiceName
+	})
+	return warnings
+}
+
+// isDescriptionFileName reports whether a file in a service directory
+// names a service, as opposed to a sibling .override, an editor backup
+// or a hidden file.
+func isDescriptionFileName(name string) bool {
+	if name == "" || name[0] == '.' || strings.HasSuffix(name, "~") {
+		return false
+	}
+	switch filepath.Ext(name) {
+	case ".override", PatchSuffix, ".swp", ".tmp", ".bak", ".new":
+		return false
+	}
+	return true
+}
+
+func isRegularFile(path string) bool {
+	fi, err := os.Stat(path)
+	return err == nil && fi.Mode().IsRegular()
+}
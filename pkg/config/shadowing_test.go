@@ -0,0 +1,44 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sunlightlinux/slinit/pkg/service"
+)
+
+func TestCheckShadowing(t *testing.T) {
+	etc := t.TempDir()
+	lib := t.TempDir()
+	writeServiceFile(t, etc, "nginx", "type = internal\n")
+	writeServiceFile(t, lib, "nginx", "type = internal\n")
+	writeServiceFile(t, lib, "sshd", "type = internal\n")
+	writeServiceFile(t, etc, "sshd.override", "restart = no\n")
+	writeServiceFile(t, lib, "sshd.override", "restart = no\n")
+	writeServiceFile(t, etc, "cups.disabled", "type = internal\n")
+	writeServiceFile(t, lib, "cups", "type = internal\n")
+	if err := os.Mkdir(filepath.Join(lib, "sshd.d"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Mkdir(filepath.Join(etc, "sshd.d"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	loader := NewDirLoader(service.NewServiceSet(&testReloadLogger{}), []string{etc, lib})
+	got := loader.CheckShadowing()
+	want := []ShadowingWarning{
+		{ServiceName: "cups", ActivePath: filepath.Join(lib, "cups"),
+			ShadowedPath: filepath.Join(etc, "cups.disabled"), Masked: true},
+		{ServiceName: "nginx", ActivePath: filepath.Join(etc, "nginx"),
+			ShadowedPath: filepath.Join(lib, "nginx")},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %+v, want %+v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("warning %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
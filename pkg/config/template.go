@@ -0,0 +1,57 @@
+package config
+
+import "strings"
+
+// A service named "base@arg" is an instance of a template. Unless a
+// file of the full name exists, its description comes from the
+// template file "base@" (the systemd spelling) or plain "base", parsed
+// with arg as the service argument: $1 in values, plus the %i and %N
+// specifiers, which stand for arg and the full instance name.
+
+// SplitInstance splits a "base@arg" instance name into its template
+// base and argument. ok is false for a name without '@'.
+func SplitInstance(name string) (base, arg string, ok bool) {
+	return strings.Cut(name, "@")
+}
+
+// TemplateFileNames returns the description file names tried, in
+// order, for service name: the name itself, then for an instance the
+// "base@" and "base" template files.
+func TemplateFileNames(name string) []string {
+	base, _, ok := SplitInstance(name)
+	if !ok {
+		return []string{name}
+	}
+	return []string{name, base + "@", base}
+}
+
+// expandSpecifiers replaces the template specifiers in line: %i with
+// the instance argument, %N with the full service name and %% with a
+// literal '%'. Any other '%' sequence is left alone, so values such as
+// "date +%Y" keep working.
+func expandSpecifiers(line, name, arg string) string {
+	if strings.IndexByte(line, '%') < 0 {
+		return line
+	}
+	var b strings.Builder
+	b.Grow(len(line))
+	for i := 0; i < len(line); i++ {
+		if line[i] != '%' || i+1 >= len(line) {
+			b.WriteByte(line[i])
+			continue
+		}
+		switch line[i+1] {
+		case 'i':
+			b.WriteString(arg)
+		case 'N':
+			b.WriteString(name)
+		case '%':
+			b.WriteByte('%')
+		default:
+			b.WriteByte('%')
+			continue
+		}
+		i++
+	}
+	return b.String()
+}
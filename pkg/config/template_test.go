@@ -0,0 +1,95 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/sunlightlinux/slinit/pkg/service"
+)
+
+func TestTemplateSpecifiers(t *testing.T) {
+	input := "type = process\n" +
+		"command = /usr/bin/agent --id %i --name %N --date +%Y --pct 100%%\n" +
+		"working-dir = /var/lib/agent/%i\n" +
+		"env = AGENT=%i\n" +
+		"depends-on: net@%i\n"
+	desc, err := ParseWithArg(strings.NewReader(input), "agent@eu1", "agent@", "eu1")
+	if err != nil {
+		t.Fatalf("parse error: %v", err)
+	}
+	want := []string{"/usr/bin/agent", "--id", "eu1", "--name", "agent@eu1", "--date", "+%Y", "--pct", "100%"}
+	if !reflect.DeepEqual(desc.Command, want) {
+		t.Errorf("command: got %q, want %q", desc.Command, want)
+	}
+	if desc.WorkingDir != "/var/lib/agent/eu1" {
+		t.Errorf("working-dir: got %q", desc.WorkingDir)
+	}
+	if len(desc.Env) != 1 || desc.Env[0] != "AGENT=eu1" {
+		t.Errorf("env: got %v", desc.Env)
+	}
+	if len(desc.DependsOn) != 1 || desc.DependsOn[0] != "net@eu1" {
+		t.Errorf("depends-on: got %v", desc.DependsOn)
+	}
+}
+
+func TestTemplateSpecifiersNeedInstance(t *testing.T) {
+	desc, err := Parse(strings.NewReader("type = process\ncommand = /bin/echo %i\n"), "plain", "plain")
+	if err != nil {
+		t.Fatalf("parse error: %v", err)
+	}
+	if len(desc.Command) != 2 || desc.Command[1] != "%i" {
+		t.Errorf("command: got %v, want %%i left alone", desc.Command)
+	}
+}
+
+func TestTemplateFileNames(t *testing.T) {
+	if got := TemplateFileNames("sshd"); !reflect.DeepEqual(got, []string{"sshd"}) {
+		t.Errorf("plain: got %v", got)
+	}
+	if got := TemplateFileNames("getty@tty2"); !reflect.DeepEqual(got, []string{"getty@tty2", "getty@", "getty"}) {
+		t.Errorf("instance: got %v", got)
+	}
+}
+
+// TestDirLoaderAtTemplate checks that an instance loads from a "base@"
+// template file, ahead of a plain "base" file, and that each instance
+// is its own service.
+func TestDirLoaderAtTemplate(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "getty@"), []byte("type = process\ncommand = /sbin/agetty %i\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "getty"), []byte("type = internal\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	set := service.NewServiceSet(nil)
+	loader := NewDirLoader(set, []string{dir})
+	set.SetLoader(loader)
+
+	for _, tty := range []string{"tty1", "tty2"} {
+		desc, path, err := loader.findAndParse("getty@" + tty)
+		if err != nil {
+			t.Fatalf("findAndParse getty@%s: %v", tty, err)
+		}
+		if filepath.Base(path) != "getty@" {
+			t.Errorf("getty@%s loaded from %s", tty, path)
+		}
+		if want := []string{"/sbin/agetty", tty}; !reflect.DeepEqual(desc.Command, want) {
+			t.Errorf("getty@%s command: got %v, want %v", tty, desc.Command, want)
+		}
+	}
+	a, err := loader.LoadService("getty@tty1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := loader.LoadService("getty@tty2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a == b || set.FindService("getty@tty1", false) != a || set.FindService("getty@tty2", false) != b {
+		t.Error("instances are not tracked as separate services")
+	}
+}
@@ -67,6 +67,21 @@ restart-randomized-delay = -1s
 	}
 }
 
+// TestParseRestartJitter checks the restart-jitter alias.
+func TestParseRestartJitter(t *testing.T) {
+	desc, err := parseServiceContent(`
+type = process
+command = /bin/true
+restart-jitter = 500ms
+`, "")
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if desc.RestartRandomizedDelay != 500*time.Millisecond {
+		t.Errorf("got %v, want 500ms", desc.RestartRandomizedDelay)
+	}
+}
+
 // TestParseStartLimitAction covers all recognised action names + a
 // bogus value that must be rejected.
 func TestParseStartLimitAction(t *testing.T) {
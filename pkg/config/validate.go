@@ -2,7 +2,12 @@ package config
 
 import (
 	"fmt"
+	"path/filepath"
+	"strings"
+	"syscall"
 	"unicode/utf8"
+
+	"github.com/sunlightlinux/slinit/pkg/service"
 )
 
 // MaxDepDepth limits the depth of the dependency tree to prevent stack
@@ -55,3 +60,125 @@ func ValidateServiceName(name string) error {
 
 	return nil
 }
+
+// ValidationError is a constraint a parsed service description breaks.
+// Parse collects them in ServiceDescription.Warnings instead of
+// failing, so a service with a questionable setting still loads.
+type ValidationError struct {
+	Field    string // the setting at fault, e.g. "pid-file"
+	Message  string
+	Severity LintSeverity
+}
+
+func (e ValidationError) Error() string {
+	return fmt.Sprintf("%s %s: %s", e.Field, e.Severity, e.Message)
+}
+
+// Validate checks desc for settings that are individually valid but
+// missing, out of range, or contradicting another setting. Errors name
+// a service that cannot work as described; warnings one that probably
+// does not do what its author meant.
+func (desc *ServiceDescription) Validate() []ValidationError {
+	var errs []ValidationError
+	add := func(sev LintSeverity, field, format string, args ...interface{}) {
+		errs = append(errs, ValidationError{Field: field, Message: fmt.Sprintf(format, args...), Severity: sev})
+	}
+
+	switch desc.Type {
+	case service.TypeProcess:
+		if len(desc.Command) == 0 && len(desc.BundleMembers) == 0 {
+			add(LintError, "command", "process service requires a command")
+		}
+	case service.TypeBGProcess:
+		if desc.PIDFile == "" {
+			add(LintError, "pid-file", "bgprocess service requires a pid-file")
+		}
+	case service.TypeInternal, service.TypeTriggered, service.TypeBarrier, service.TypeFanout, service.TypeTimer:
+		if desc.RunAs != "" {
+			add(LintError, "run-as", "%s service runs no process to apply run-as to", desc.Type)
+		}
+	}
+	switch desc.Type {
+	case service.TypeProcess, service.TypeBGProcess, service.TypeScripted, service.TypeSocketActivated:
+		if desc.RunAs == "" {
+			break
+		}
+		if _, _, ok := resolveRunAs(desc.RunAs); !ok {
+			name, _, _ := strings.Cut(desc.RunAs, ":")
+			add(LintWarning, "run-as", "user %q is not known on this host; the service would run as slinit's own user",
+				strings.TrimSpace(name))
+		}
+	}
+	if desc.Type == service.TypeFanout && len(desc.Members) == 0 {
+		add(LintWarning, "members", "fanout service has no members to start")
+	}
+	if len(desc.Members) > 0 && desc.Type != service.TypeFanout {
+		add(LintWarning, "members", "only fanout services start members")
+	}
+	if desc.Type == service.TypeTimer {
+		if desc.TimerTarget == "" {
+			add(LintError, "activates", "timer service has no service to activate")
+		}
+		if desc.TimerInterval == 0 && desc.TimerCalendar == nil && desc.TimerBootDelay == 0 {
+			add(LintWarning, "interval", "timer service has no schedule and never fires")
+		}
+		if desc.TimerInterval > 0 && desc.TimerCalendar != nil {
+			add(LintWarning, "interval", "on-calendar replaces interval")
+		}
+	} else if desc.TimerTarget != "" || desc.TimerInterval > 0 || desc.TimerBootDelay > 0 || desc.TimerCalendar != nil {
+		add(LintWarning, "activates", "only timer services take activates, interval, on-boot-delay and on-calendar")
+	}
+	if desc.PIDFileLocking && desc.Type != service.TypeBGProcess {
+		add(LintWarning, "pid-file-locking", "only bgprocess services read a pid-file")
+	}
+	if desc.CaptureStartError && desc.Type != service.TypeScripted {
+		add(LintWarning, "capture-start-error", "only scripted services run a start command")
+	}
+	if desc.WatchFile != "" && !filepath.IsAbs(desc.WatchFile) {
+		add(LintError, "watch-file", "path %q must be absolute", desc.WatchFile)
+	}
+	if desc.EncryptedEnvFile != "" && desc.EnvEncryptionKey == "" {
+		add(LintError, "env-encryption-key", "encrypted-env-file requires env-encryption-key")
+	}
+	if desc.EnvEncryptionKey != "" && desc.EncryptedEnvFile == "" {
+		add(LintWarning, "env-encryption-key", "no encrypted-env-file to decrypt")
+	}
+	if desc.EncryptedEnvFile != "" && desc.Type != service.TypeProcess {
+		add(LintWarning, "encrypted-env-file", "only process services read an encrypted-env-file")
+	}
+
+	if desc.StartTimeout > 0 && desc.StopTimeout > 0 && desc.StopTimeout < desc.StartTimeout/2 {
+		add(LintWarning, "stop-timeout", "stop-timeout %v is less than half of start-timeout %v",
+			desc.StopTimeout, desc.StartTimeout)
+	}
+
+	if desc.RestartLimitCount > 0 && desc.RestartInterval <= 0 {
+		add(LintError, "restart-limit-interval", "restart-limit-count requires a positive restart-limit-interval")
+	}
+
+	if desc.ChainTo != "" {
+		if err := ValidateServiceName(desc.ChainTo); err != nil {
+			add(LintError, "chain-to", "%v", err)
+		} else if strings.Contains(desc.ChainTo, "/") {
+			add(LintError, "chain-to", "service name %q must not contain '/'", desc.ChainTo)
+		}
+	}
+
+	if desc.SocketPerms < 0 || desc.SocketPerms > 0o7777 {
+		add(LintError, "socket-permissions", "%#o is not a valid file mode", desc.SocketPerms)
+	}
+
+	if desc.TermSignal == syscall.SIGKILL {
+		add(LintWarning, "term-signal", "SIGKILL cannot be caught; the service gets no chance to shut down cleanly")
+	}
+
+	if desc.LogBufMax < 0 {
+		add(LintError, "log-buffer-size", "log-buffer-size must be positive")
+	}
+
+	if desc.SmoothRecovery && desc.AutoRestart == service.RestartNever {
+		add(LintWarning, "smooth-recovery", "smooth-recovery has no effect with restart = no")
+	}
+
+	return errs
+}
@@ -1,6 +1,7 @@
 package config
 
 import (
+	"strings"
 	"testing"
 )
 
@@ -61,3 +62,51 @@ func TestValidateServiceNamePathTraversal(t *testing.T) {
 		t.Error("expected error for dot-prefixed name")
 	}
 }
+
+func TestServiceDescriptionValidate(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		field string // "" = no problems
+		sev   LintSeverity
+	}{
+		{"clean", "type = process\ncommand = /bin/d\n", "", 0},
+		{"process without command", "type = process\n", "command", LintError},
+		{"bundle needs no command", "bundle-of = a b\n", "", 0},
+		{"bgprocess without pid-file", "type = bgprocess\ncommand = /bin/d\n", "pid-file", LintError},
+		{"short stop-timeout", "command = /bin/d\nstart-timeout = 60\nstop-timeout = 10\n", "stop-timeout", LintWarning},
+		{"limit without interval", "command = /bin/d\nrestart-limit-count = 3\n", "restart-limit-interval", LintError},
+		{"chain-to with slash", "command = /bin/d\nchain-to = a/b\n", "chain-to", LintError},
+		{"SIGKILL term-signal", "command = /bin/d\nterm-signal = KILL\n", "term-signal", LintWarning},
+		{"run-as on internal", "type = internal\nrun-as = nobody\n", "run-as", LintError},
+		{"negative log buffer", "command = /bin/d\nlog-buffer-size = -1\n", "log-buffer-size", LintError},
+		{"smooth-recovery without restart", "command = /bin/d\nsmooth-recovery = yes\n", "smooth-recovery", LintWarning},
+		{"pid-file-locking on process", "command = /bin/d\npid-file-locking = yes\n", "pid-file-locking", LintWarning},
+		{"capture-start-error on process", "command = /bin/d\ncapture-start-error = yes\n", "capture-start-error", LintWarning},
+		{"fanout without members", "type = fanout\n", "members", LintWarning},
+		{"members on internal", "type = internal\nmembers = a\n", "members", LintWarning},
+		{"timer", "type = timer\nactivates = backup\ninterval = 300\n", "", 0},
+		{"timer without activates", "type = timer\ninterval = 300\n", "activates", LintError},
+		{"timer without schedule", "type = timer\nactivates = backup\n", "interval", LintWarning},
+		{"interval on internal", "type = internal\ninterval = 300\n", "activates", LintWarning},
+		{"relative watch-file", "command = /bin/d\nwatch-file = run/d.sock\n", "watch-file", LintError},
+		{"encrypted-env-file without key", "command = /bin/d\nencrypted-env-file = /s.enc\n", "env-encryption-key", LintError},
+		{"env-encryption-key without file", "command = /bin/d\nenv-encryption-key = env:K\n", "env-encryption-key", LintWarning},
+		{"encrypted-env-file on scripted", "type = scripted\ncommand = /bin/d\nencrypted-env-file = /s.enc\nenv-encryption-key = env:K\n", "encrypted-env-file", LintWarning},
+	}
+	for _, tt := range tests {
+		desc, err := Parse(strings.NewReader(tt.input), "svc", "svc")
+		if err != nil {
+			t.Fatalf("%s: Parse: %v", tt.name, err)
+		}
+		if tt.field == "" {
+			if len(desc.Warnings) != 0 {
+				t.Errorf("%s: unexpected %v", tt.name, desc.Warnings)
+			}
+			continue
+		}
+		if len(desc.Warnings) != 1 || desc.Warnings[0].Field != tt.field || desc.Warnings[0].Severity != tt.sev {
+			t.Errorf("%s: got %v, want one %s %s", tt.name, desc.Warnings, tt.field, tt.sev)
+		}
+	}
+}
@@ -0,0 +1,268 @@
+package control
+
+import (
+	"fmt"
+	"os"
+	"os/user"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Role is what a control client may do.
+type Role uint8
+
+const (
+	RoleNone     Role = iota // every command is refused
+	RoleReadOnly             // queries only: list, status, logs, boot times, ...
+	RoleFull                 // every command
+)
+
+func (r Role) String() string {
+	switch r {
+	case RoleReadOnly:
+		return "read-only"
+	case RoleFull:
+		return "full"
+	}
+	return "none"
+}
+
+// AccessPolicy says which Unix-socket peers besides root and the
+// daemon's own user (who always have full control) may use the control
+// socket. Peers are identified by SO_PEERCRED; a group matches the
+// peer's effective GID or one of its user's supplementary groups. The
+// zero value admits nobody else.
+type AccessPolicy struct {
+	FullUIDs []uint32
+	FullGIDs []uint32
+	ReadUIDs []uint32
+	ReadGIDs []uint32
+}
+
+// Role returns the role of a peer with the given credentials. A nil
+// policy is the zero policy.
+func (p *AccessPolicy) Role(uid, gid uint32) Role {
+	if uid == 0 || uid == uint32(os.Getuid()) {
+		return RoleFull
+	}
+	if p == nil {
+		return RoleNone
+	}
+	if slices.Contains(p.FullUIDs, uid) {
+		return RoleFull
+	}
+	var groups []uint32
+	if len(p.FullGIDs) > 0 || len(p.ReadGIDs) > 0 {
+		groups = peerGroups(uid, gid)
+	}
+	if containsAny(p.FullGIDs, groups) {
+		return RoleFull
+	}
+	if slices.Contains(p.ReadUIDs, uid) || containsAny(p.ReadGIDs, groups) {
+		return RoleReadOnly
+	}
+	return RoleNone
+}
+
+// admitsOthers reports whether the policy grants access to anyone
+// beyond root and the daemon's own user, in which case the socket file
+// must be reachable by them.
+func (p *AccessPolicy) admitsOthers() bool {
+	return p != nil && len(p.FullUIDs)+len(p.FullGIDs)+len(p.ReadUIDs)+len(p.ReadGIDs) > 0
+}
+
+// peerGroups returns gid plus the supplementary groups of uid's user
+// from the group database.
+func peerGroups(uid, gid uint32) []uint32 {
+	groups := []uint32{gid}
+	u, err := user.LookupId(strconv.FormatUint(uint64(uid), 10))
+	if err != nil {
+		return groups
+	}
+	ids, err := u.GroupIds()
+	if err != nil {
+		return groups
+	}
+	for _, id := range ids {
+		if g, err := strconv.ParseUint(id, 10, 32); err == nil {
+			groups = append(groups, uint32(g))
+		}
+	}
+	return groups
+}
+
+func containsAny(set, vals []uint32) bool {
+	for _, v := range vals {
+		if slices.Contains(set, v) {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseAccessList parses a comma-separated list of users and groups as
+// given to --control-allow and --control-read-only: user names or
+// numeric UIDs, and group names or numeric GIDs prefixed with '@'.
+func ParseAccessList(spec string) (uids, gids []uint32, err error) {
+	for _, item := range strings.Split(spec, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		if group, ok := strings.CutPrefix(item, "@"); ok {
+			gid, err := lookupID(group, true)
+			if err != nil {
+				return nil, nil, err
+			}
+			gids = append(gids, gid)
+			continue
+		}
+		uid, err := lookupID(item, false)
+		if err != nil {
+			return nil, nil, err
+		}
+		uids = append(uids, uid)
+	}
+	return uids, gids, nil
+}
+
+// lookupID resolves a user (or group) name or number to its ID.
+func lookupID(name string, group bool) (uint32, error) {
+	if id, err := strconv.ParseUint(name, 10, 32); err == nil {
+		return uint32(id), nil
+	}
+	var id string
+	if group {
+		g, err := user.LookupGroup(name)
+		if err != nil {
+			return 0, fmt.Errorf("unknown group %q", name)
+		}
+		id = g.Gid
+	} else {
+		u, err := user.Lookup(name)
+		if err != nil {
+			return 0, fmt.Errorf("unknown user %q", name)
+		}
+		id = u.Uid
+	}
+	n, err := strconv.ParseUint(id, 10, 32)
+	if err != nil {
+		return 0, fmt.Errorf("%q has non-numeric id %q", name, id)
+	}
+	return uint32(n), nil
+}
+
+// readOnlyCommands are the commands a RoleReadOnly client may issue:
+// ones that only report state. CmdLoadService is among them but is
+// answered like CmdFindService, so it cannot load new descriptions.
+var readOnlyCommands = map[uint8]bool{
+	CmdQueryVersion:         true,
+	CmdFindService:          true,
+	CmdLoadService:          true,
+	CmdListServices:         true,
+	CmdQueryLoadMech:        true,
+	CmdQueryServiceName:     true,
+	CmdServiceStatus:        true,
+	CmdCatLog:               true, // without CatLogFlagClear
+	CmdQueryServiceDscDir:   true,
+	CmdCloseHandle:          true,
+	CmdGetAllEnv:            true,
+	CmdListServices5:        true,
+	CmdServiceStatus5:       true,
+	CmdListenEnv:            true,
+	CmdServiceStatus6:       true,
+	CmdBootTime:             true,
+	CmdQueryDependents:      true,
+	CmdQueryDependencies:    true,
+	CmdQueryDescription:     true,
+	CmdListActions:          true,
+	CmdQueryShutdown:        true,
+	CmdQueryMetadata:        true,
+	CmdQueryProfile:         true,
+	CmdListProfiles:         true,
+	CmdQueryBundleMembers:   true,
+	CmdQueryHealth:          true,
+	CmdNegotiateCompression: true,
+	CmdListRecentEvents:     true,
+	CmdListenEvents:         true,
+	CmdShutdownPlan:         true,
+	CmdVerifyInternal:       true,
+	CmdQueryInfo:            true,
+	CmdListAnnotations:      true,
+	CmdGetDependencyInfo:    true,
+	CmdQueryTriggers:        true,
+	CmdSubscribeEvents:      true,
+	CmdListAliases:          true,
+	CmdSubscribeList:        true,
+	CmdUnsubscribeList:      true,
+	CmdDumpDiagnostics:      true,
+	CmdListTimers:           true,
+	CmdDumpGraph:            true,
+	CmdQueryJob:             true,
+	CmdListJobs:             true,
+}
+
+// isReadOnlyCommand reports whether cmd with payload leaves service and
+// system state alone.
+func isReadOnlyCommand(cmd uint8, payload []byte) bool {
+	if cmd == CmdCatLog && len(payload) > 0 && payload[0]&CatLogFlagClear != 0 {
+		return false
+	}
+	return readOnlyCommands[cmd]
+}
+
+// auditNames names the privileged commands in audit log lines.
+var auditNames = map[uint8]string{
+	CmdStartService:         "start",
+	CmdStopService:          "stop",
+	CmdWakeService:          "wake",
+	CmdReleaseService:       "release",
+	CmdUnpinService:         "unpin",
+	CmdPinService:           "pin",
+	CmdSwitchTarget:         "switch-to",
+	CmdCancelJob:            "cancel",
+	CmdUnloadService:        "unload",
+	CmdShutdown:             "shutdown",
+	CmdAddDep:               "add-dep",
+	CmdRmDep:                "rm-dep",
+	CmdEnableService:        "enable",
+	CmdEnableServiceV7:      "enable",
+	CmdDisableService:       "disable",
+	CmdReloadService:        "reload",
+	CmdSetEnv:               "setenv",
+	CmdResetEnv:             "reset-env",
+	CmdSetTrigger:           "trigger",
+	CmdSetNamedTrigger:      "trigger",
+	CmdCatLog:               "catlog --clear",
+	CmdSignal:               "signal",
+	CmdPauseService:         "pause",
+	CmdContinueService:      "continue",
+	CmdOnceService:          "once",
+	CmdRunAction:            "action",
+	CmdScheduleShutdown:     "schedule-shutdown",
+	CmdCancelShutdown:       "cancel-shutdown",
+	CmdReloadAll:            "reload-all",
+	CmdReloadSignal:         "reload-signal",
+	CmdActivateProfile:      "activate-profile",
+	CmdWallNotice:           "wall",
+	CmdResetFailed:          "reset-failed",
+	CmdFreezeService:        "freeze",
+	CmdThawService:          "thaw",
+	CmdSetRestartEnabled:    "set-restart",
+	CmdLockServiceFile:      "lock-service-file",
+	CmdUnlockServiceFile:    "unlock-service-file",
+	CmdKillService:          "kill",
+	CmdAnnotate:             "annotate",
+	CmdSetServiceStopReason: "set-stop-reason",
+	CmdSetPriority:          "set-priority",
+	CmdSetServiceDirs:       "set-service-dirs",
+}
+
+// auditName names cmd for an audit log line.
+func auditName(cmd uint8) string {
+	if name, ok := auditNames[cmd]; ok {
+		return name
+	}
+	return fmt.Sprintf("command %d", cmd)
+}
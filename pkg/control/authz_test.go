@@ -0,0 +1,177 @@
+package control
+
+import (
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/sunlightlinux/slinit/pkg/logging"
+	"github.com/sunlightlinux/slinit/pkg/service"
+)
+
+func TestAccessPolicyRole(t *testing.T) {
+	own := uint32(os.Getuid())
+	p := &AccessPolicy{
+		FullUIDs: []uint32{4001},
+		FullGIDs: []uint32{5001},
+		ReadUIDs: []uint32{4002},
+		ReadGIDs: []uint32{5002},
+	}
+	tests := []struct {
+		uid, gid uint32
+		want     Role
+	}{
+		{0, 0, RoleFull},
+		{own, 4999, RoleFull},
+		{4001, 4999, RoleFull},
+		{4003, 5001, RoleFull},
+		{4002, 4999, RoleReadOnly},
+		{4003, 5002, RoleReadOnly},
+		{4003, 4999, RoleNone},
+	}
+	for _, tt := range tests {
+		if tt.uid == own && tt.want != RoleFull {
+			continue // the daemon's own user always has full control
+		}
+		if got := p.Role(tt.uid, tt.gid); got != tt.want {
+			t.Errorf("Role(%d, %d) = %s, want %s", tt.uid, tt.gid, got, tt.want)
+		}
+	}
+
+	var none *AccessPolicy
+	if got := none.Role(0, 0); got != RoleFull {
+		t.Errorf("nil policy: Role(root) = %s, want full", got)
+	}
+	if own != 4003 {
+		if got := none.Role(4003, 4003); got != RoleNone {
+			t.Errorf("nil policy: Role(4003) = %s, want none", got)
+		}
+	}
+	if none.admitsOthers() || (&AccessPolicy{}).admitsOthers() || !p.admitsOthers() {
+		t.Error("admitsOthers wrong")
+	}
+}
+
+func TestParseAccessList(t *testing.T) {
+	uids, gids, err := ParseAccessList(" 1000, @100,root,,@0")
+	if err != nil {
+		t.Fatalf("ParseAccessList: %v", err)
+	}
+	if !slices.Equal(uids, []uint32{1000, 0}) || !slices.Equal(gids, []uint32{100, 0}) {
+		t.Errorf("got uids %v gids %v", uids, gids)
+	}
+	if _, _, err := ParseAccessList("no-such-user-slinit"); err == nil {
+		t.Error("unknown user accepted")
+	}
+	if _, _, err := ParseAccessList("@no-such-group-slinit"); err == nil {
+		t.Error("unknown group accepted")
+	}
+}
+
+func TestIsReadOnlyCommand(t *testing.T) {
+	if !isReadOnlyCommand(CmdServiceStatus6, nil) || !isReadOnlyCommand(CmdListServices5, nil) {
+		t.Error("status/list should be read-only")
+	}
+	for _, cmd := range []uint8{CmdStartService, CmdStopService, CmdShutdown, CmdKillService, CmdSetEnv} {
+		if isReadOnlyCommand(cmd, nil) {
+			t.Errorf("command %d (%s) treated as read-only", cmd, auditName(cmd))
+		}
+	}
+	if !isReadOnlyCommand(CmdCatLog, []byte{0, 1, 0, 0, 0}) {
+		t.Error("catlog should be read-only")
+	}
+	if isReadOnlyCommand(CmdCatLog, []byte{CatLogFlagClear, 1, 0, 0, 0}) {
+		t.Error("catlog --clear treated as read-only")
+	}
+}
+
+// TestReadOnlyPeer checks that a read-only connection gets its queries
+// answered, is refused anything else, and cannot load new services.
+func TestReadOnlyPeer(t *testing.T) {
+	clientConn, serverConn := net.Pipe()
+	defer clientConn.Close()
+	defer serverConn.Close()
+
+	ss := service.NewServiceSet(&testLogger{})
+	ss.AddService(service.NewInternalService(ss, "present"))
+	c := &Connection{
+		server:         NewServer(ss, "", logging.New(logging.LevelError)),
+		conn:           serverConn,
+		handles:        make(map[uint32]service.Service),
+		revHandles:     make(map[service.Service]uint32),
+		handleUsed:     make(map[uint32]time.Time),
+		nextHandle:     1,
+		peerAuthorized: true,
+		readOnly:       true,
+	}
+
+	compressed := false
+	send := func(cmd uint8, payload []byte) uint8 {
+		t.Helper()
+		done := make(chan error, 1)
+		go func() { done <- c.dispatch(cmd, payload) }()
+		clientConn.SetReadDeadline(time.Now().Add(2 * time.Second))
+		rply, _, err := ReadPacketWith(clientConn, compressed)
+		if err != nil {
+			t.Fatalf("ReadPacket: %v", err)
+		}
+		if err := <-done; err != nil {
+			t.Fatalf("dispatch: %v", err)
+		}
+		return rply
+	}
+
+	if rply := send(CmdQueryVersion, nil); rply != RplyCPVersion {
+		t.Errorf("query-version: got %d, want RplyCPVersion", rply)
+	}
+	if rply := send(CmdLoadService, EncodeServiceName("present")); rply != RplyServiceRecord {
+		t.Errorf("load of a loaded service: got %d, want RplyServiceRecord", rply)
+	}
+	if rply := send(CmdShutdown, []byte{uint8(service.ShutdownPoweroff)}); rply != RplyAccessDenied {
+		t.Errorf("shutdown: got %d, want RplyAccessDenied", rply)
+	}
+	if ss.IsShuttingDown() {
+		t.Error("read-only shutdown request was executed")
+	}
+	if rply := send(CmdStartService, []byte{0, 1, 0, 0, 0}); rply != RplyAccessDenied {
+		t.Errorf("start: got %d, want RplyAccessDenied", rply)
+	}
+
+	// The refusal reaches a client that negotiated compression intact.
+	c.compressionAlgo = CompressZlib
+	compressed = true
+	if rply := send(CmdStartService, []byte{0, 1, 0, 0, 0}); rply != RplyAccessDenied {
+		t.Errorf("start with zlib: got %d, want RplyAccessDenied", rply)
+	}
+}
+
+// TestSocketModeFollowsPolicy checks that the socket file is only
+// opened up when the access policy admits other users.
+func TestSocketModeFollowsPolicy(t *testing.T) {
+	for _, tt := range []struct {
+		access *AccessPolicy
+		want   os.FileMode
+	}{
+		{nil, 0600},
+		{&AccessPolicy{ReadGIDs: []uint32{100}}, 0666},
+	} {
+		sockPath := filepath.Join(t.TempDir(), "test.socket")
+		server := NewServer(service.NewServiceSet(&testLogger{}), sockPath, logging.New(logging.LevelError))
+		server.Access = tt.access
+		if err := server.Start(context.Background()); err != nil {
+			t.Fatalf("Start: %v", err)
+		}
+		fi, err := os.Stat(sockPath)
+		server.Stop()
+		if err != nil {
+			t.Fatalf("stat: %v", err)
+		}
+		if got := fi.Mode().Perm(); got != tt.want {
+			t.Errorf("access %+v: socket mode %o, want %o", tt.access, got, tt.want)
+		}
+	}
+}
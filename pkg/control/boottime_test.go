@@ -121,3 +121,111 @@ func TestBootTimeCommand(t *testing.T) {
 		t.Errorf("Kernel uptime mismatch: got %d, want %d", info.KernelUptimeNs, int64(2*time.Second))
 	}
 }
+
+func TestBootTimeEventTrailer(t *testing.T) {
+	base := time.Unix(1000, 0)
+	info := BootTimeInfo{
+		BootSvcName: "boot",
+		Events: []service.BootEvent{
+			{Time: base, Type: service.BootEventKernel},
+			{Time: base.Add(time.Second), Type: service.BootEventServiceStart, Service: "sshd"},
+			{Time: base.Add(2 * time.Second), Type: service.BootEventServiceFailed, Service: "sshd"},
+		},
+	}
+	decoded, err := DecodeBootTime(EncodeBootTime(info))
+	if err != nil {
+		t.Fatalf("Decode error: %v", err)
+	}
+	if len(decoded.Events) != 3 {
+		t.Fatalf("got %d events, want 3", len(decoded.Events))
+	}
+	for i, ev := range decoded.Events {
+		want := info.Events[i]
+		if !ev.Time.Equal(want.Time) || ev.Type != want.Type || ev.Service != want.Service {
+			t.Errorf("event %d = %+v, want %+v", i, ev, want)
+		}
+	}
+
+	// A reply from a daemon without the timeline still decodes.
+	old := EncodeBootTime(BootTimeInfo{BootSvcName: "boot"})
+	old = old[:len(old)-4] // event and start-time counts
+	decoded, err = DecodeBootTime(old)
+	if err != nil || decoded.Events != nil {
+		t.Errorf("legacy reply: events %v, err %v", decoded.Events, err)
+	}
+}
+
+func TestBootTimeStartTimes(t *testing.T) {
+	info := BootTimeInfo{
+		BootSvcName: "boot",
+		Services: []BootTimeEntry{
+			{Name: "a", StartRequestNs: 100, StartedNs: 250},
+			{Name: "b"},
+		},
+		Events: []service.BootEvent{{Time: time.Unix(1, 0), Type: service.BootEventKernel}},
+	}
+	enc := EncodeBootTime(info)
+	decoded, err := DecodeBootTime(enc)
+	if err != nil {
+		t.Fatalf("Decode error: %v", err)
+	}
+	if s := decoded.Services[0]; s.StartRequestNs != 100 || s.StartedNs != 250 {
+		t.Errorf("a = %+v", s)
+	}
+	if s := decoded.Services[1]; s.StartRequestNs != 0 || s.StartedNs != 0 {
+		t.Errorf("b = %+v", s)
+	}
+
+	// A reply that ends after the events has no start times.
+	decoded, err = DecodeBootTime(enc[:len(enc)-2-2*16])
+	if err != nil || decoded.Services[0].StartedNs != 0 || len(decoded.Events) != 1 {
+		t.Errorf("legacy reply: %+v, err %v", decoded, err)
+	}
+}
+
+func TestBootTimeCommandEvents(t *testing.T) {
+	server, sockPath := setupTestServer(t)
+	defer server.Stop()
+
+	server.services.SetBootStartTime(time.Now())
+	server.services.SetKernelUptime(2 * time.Second)
+	svc := service.NewInternalService(server.services, "early")
+	server.services.AddService(svc)
+	server.services.StartService(svc)
+
+	conn := connectTest(t, sockPath)
+	defer conn.Close()
+
+	if err := WritePacket(conn, CmdBootTime, nil); err != nil {
+		t.Fatalf("Write error: %v", err)
+	}
+	_, payload, err := ReadPacket(conn)
+	if err != nil {
+		t.Fatalf("Read error: %v", err)
+	}
+	info, err := DecodeBootTime(payload)
+	if err != nil {
+		t.Fatalf("Decode error: %v", err)
+	}
+	var types []service.BootEventType
+	for _, ev := range info.Events {
+		types = append(types, ev.Type)
+	}
+	want := []service.BootEventType{
+		service.BootEventKernel, service.BootEventSlinitStart,
+		service.BootEventServiceStart, service.BootEventServiceStarted,
+	}
+	if len(types) != len(want) {
+		t.Fatalf("event types %v, want %v", types, want)
+	}
+	for i := range want {
+		if types[i] != want[i] {
+			t.Errorf("event types %v, want %v", types, want)
+			break
+		}
+	}
+	if len(info.Services) != 1 || info.Services[0].StartedNs != svc.Record().StartedTime().UnixNano() ||
+		info.Services[0].StartRequestNs != svc.Record().StartRequestTime().UnixNano() {
+		t.Errorf("start times = %+v", info.Services)
+	}
+}
@@ -0,0 +1,89 @@
+package control
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"io"
+)
+
+// Compression algorithms negotiated with CmdNegotiateCompression. The
+// client offers a bitmask of CompressMask values; the server replies
+// with the single algorithm it picked.
+const (
+	CompressNone uint8 = 0
+	CompressZlib uint8 = 1
+	CompressLZ4  uint8 = 2 // reserved; this build never selects it
+)
+
+// CompressMask returns the offer bit for algo.
+func CompressMask(algo uint8) uint8 { return 1 << algo }
+
+// PacketFlagCompressed is set in the flags byte of a packet whose
+// payload is zlib-compressed. The flags byte only exists once a
+// connection has negotiated compression: from then on every packet the
+// server sends is framed by WritePacketFlags, so the flag never takes a
+// bit of the type byte and every type code stays usable. Only the
+// server sets it; ReadPacketWith inflates the payload. Commands from
+// the client are never compressed and keep the plain framing.
+const PacketFlagCompressed uint8 = 0x01
+
+// CompressThreshold is the payload size above which a connection with
+// compression enabled compresses outgoing packets. Smaller payloads are
+// not worth the zlib header and CPU.
+const CompressThreshold = 256
+
+// chooseCompression picks the algorithm the server uses for an offer.
+func chooseCompression(offer uint8) uint8 {
+	if offer&CompressMask(CompressZlib) != 0 {
+		return CompressZlib
+	}
+	return CompressNone
+}
+
+// compressPayload deflates data with zlib.
+func compressPayload(data []byte) []byte {
+	var buf bytes.Buffer
+	zw := zlib.NewWriter(&buf)
+	zw.Write(data) //nolint: errcheck // bytes.Buffer writes cannot fail
+	zw.Close()
+	return buf.Bytes()
+}
+
+// decompressPayload inflates a zlib payload. Output is capped at
+// MaxPayloadSize, the largest payload an uncompressed packet can carry,
+// so a hostile peer cannot make us inflate without bound.
+func decompressPayload(data []byte) ([]byte, error) {
+	zr, err := zlib.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("decompress: %w", err)
+	}
+	defer zr.Close()
+	out, err := io.ReadAll(io.LimitReader(zr, MaxPayloadSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("decompress: %w", err)
+	}
+	if len(out) > MaxPayloadSize {
+		return nil, fmt.Errorf("decompressed payload too large: > %d", MaxPayloadSize)
+	}
+	return out, nil
+}
+
+// encodeOutgoing compresses payload for a connection using algo and
+// returns the packet flags to send with it. Payloads at or below
+// CompressThreshold are sent as-is unless force is set (used for
+// RplySvcLog). The compressed form is only used when it is actually
+// smaller.
+func encodeOutgoing(algo uint8, payload []byte, force bool) (uint8, []byte) {
+	if algo != CompressZlib || len(payload) == 0 {
+		return 0, payload
+	}
+	if !force && len(payload) <= CompressThreshold {
+		return 0, payload
+	}
+	z := compressPayload(payload)
+	if len(z) >= len(payload) {
+		return 0, payload
+	}
+	return PacketFlagCompressed, z
+}
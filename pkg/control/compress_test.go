@@ -0,0 +1,183 @@
+package control
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/sunlightlinux/slinit/pkg/service"
+)
+
+func TestCompressPayloadRoundTrip(t *testing.T) {
+	data := []byte(strings.Repeat("starting service foo\n", 200))
+	z := compressPayload(data)
+	if len(z) >= len(data) {
+		t.Fatalf("compressed %d bytes to %d, expected smaller", len(data), len(z))
+	}
+	out, err := decompressPayload(z)
+	if err != nil {
+		t.Fatalf("decompressPayload: %v", err)
+	}
+	if !bytes.Equal(out, data) {
+		t.Error("round trip mismatch")
+	}
+
+	if _, err := decompressPayload([]byte("not zlib")); err == nil {
+		t.Error("expected error for garbage input")
+	}
+	bomb := compressPayload(make([]byte, MaxPayloadSize+1))
+	if _, err := decompressPayload(bomb); err == nil {
+		t.Error("expected error for oversized output")
+	}
+}
+
+// negotiateZlib switches conn to zlib compression. Replies from then on
+// must be read with ReadPacketWith(conn, true) or readReplyWith.
+func negotiateZlib(t *testing.T, conn net.Conn) {
+	t.Helper()
+	if err := WritePacket(conn, CmdNegotiateCompression, []byte{CompressMask(CompressZlib)}); err != nil {
+		t.Fatal(err)
+	}
+	rply, payload, err := ReadPacket(conn)
+	if err != nil || rply != RplyCompression || len(payload) < 1 || payload[0] != CompressZlib {
+		t.Fatalf("negotiate zlib: rply=%d payload=%v err=%v", rply, payload, err)
+	}
+}
+
+func TestReadPacketCompressed(t *testing.T) {
+	data := []byte(strings.Repeat("x", 1000))
+	flags, payload := encodeOutgoing(CompressZlib, data, false)
+	if flags != PacketFlagCompressed {
+		t.Fatalf("expected compressed flag, got flags %#x", flags)
+	}
+
+	var buf bytes.Buffer
+	if err := WritePacketFlags(&buf, RplySvcInfo, flags, payload); err != nil {
+		t.Fatal(err)
+	}
+	gotType, gotPayload, err := ReadPacketWith(&buf, true)
+	if err != nil {
+		t.Fatalf("ReadPacket: %v", err)
+	}
+	if gotType != RplySvcInfo || !bytes.Equal(gotPayload, data) {
+		t.Errorf("got type %d, %d bytes; want %d, %d bytes", gotType, len(gotPayload), RplySvcInfo, len(data))
+	}
+}
+
+// Every type code, including those with the top bit set, must come
+// through a compressing connection intact, compressed or not.
+func TestCompressedFramingAllTypes(t *testing.T) {
+	big := []byte(strings.Repeat("y", 1000))
+	small := []byte("ok")
+	for code := 0; code <= 0xff; code++ {
+		for _, data := range [][]byte{nil, small, big} {
+			flags, payload := encodeOutgoing(CompressZlib, data, false)
+			var buf bytes.Buffer
+			if err := WritePacketFlags(&buf, uint8(code), flags, payload); err != nil {
+				t.Fatal(err)
+			}
+			gotType, gotPayload, err := ReadPacketWith(&buf, true)
+			if err != nil {
+				t.Fatalf("type %d, %d bytes: %v", code, len(data), err)
+			}
+			if gotType != uint8(code) || !bytes.Equal(gotPayload, data) {
+				t.Fatalf("type %d, %d bytes: got type %d, %d bytes", code, len(data), gotType, len(gotPayload))
+			}
+		}
+	}
+}
+
+func TestEncodeOutgoingThreshold(t *testing.T) {
+	small := []byte(strings.Repeat("a", CompressThreshold))
+	if fl, _ := encodeOutgoing(CompressZlib, small, false); fl&PacketFlagCompressed != 0 {
+		t.Error("payload at threshold should not be compressed")
+	}
+	if fl, _ := encodeOutgoing(CompressZlib, small, true); fl&PacketFlagCompressed == 0 {
+		t.Error("forced payload should be compressed")
+	}
+	if fl, _ := encodeOutgoing(CompressNone, small, true); fl&PacketFlagCompressed != 0 {
+		t.Error("nothing should be compressed without negotiation")
+	}
+}
+
+func TestNegotiateCompressionCatLog(t *testing.T) {
+	server, sockPath := setupTestServer(t)
+	defer server.Stop()
+
+	logPath := filepath.Join(t.TempDir(), "app.log")
+	content := strings.Repeat("worker: request handled in 3ms\n", 100)
+	if err := os.WriteFile(logPath, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	svc := service.NewProcessService(server.services, "file-svc")
+	svc.SetLogType(service.LogToFile)
+	svc.SetLogFileDetails(logPath, 0644, -1, -1)
+	server.services.AddService(svc)
+
+	conn := connectTest(t, sockPath)
+	defer conn.Close()
+
+	offer := CompressMask(CompressNone) | CompressMask(CompressZlib) | CompressMask(CompressLZ4)
+	if err := WritePacket(conn, CmdNegotiateCompression, []byte{offer}); err != nil {
+		t.Fatal(err)
+	}
+	rply, payload, err := ReadPacket(conn)
+	if err != nil || rply != RplyCompression {
+		t.Fatalf("negotiate failed: rply=%d err=%v", rply, err)
+	}
+	if payload[0] != CompressZlib {
+		t.Fatalf("server chose %d, want zlib", payload[0])
+	}
+
+	if err := WritePacket(conn, CmdLoadService, EncodeServiceName("file-svc")); err != nil {
+		t.Fatal(err)
+	}
+	rply, payload, err = ReadPacketWith(conn, true)
+	if err != nil || rply != RplyServiceRecord {
+		t.Fatalf("load failed: rply=%d err=%v", rply, err)
+	}
+	handle := binary.LittleEndian.Uint32(payload[1:5])
+
+	if err := WritePacket(conn, CmdCatLog, EncodeCatLogRequest(handle, false)); err != nil {
+		t.Fatal(err)
+	}
+	var wire bytes.Buffer
+	rply, payload, err = ReadPacketWith(io.TeeReader(conn, &wire), true)
+	if err != nil || rply != RplySvcLog {
+		t.Fatalf("catlog failed: rply=%d err=%v", rply, err)
+	}
+	if wire.Bytes()[1]&PacketFlagCompressed == 0 {
+		t.Error("RplySvcLog was not compressed on the wire")
+	}
+	if wire.Len() >= len(content) {
+		t.Errorf("wire size %d not smaller than log size %d", wire.Len(), len(content))
+	}
+	_, logData, _ := DecodeSvcLog(payload)
+	if string(logData) != content {
+		t.Error("log content mismatch after decompression")
+	}
+}
+
+func TestNegotiateCompressionNone(t *testing.T) {
+	server, sockPath := setupTestServer(t)
+	defer server.Stop()
+
+	conn := connectTest(t, sockPath)
+	defer conn.Close()
+
+	if err := WritePacket(conn, CmdNegotiateCompression, []byte{CompressMask(CompressLZ4)}); err != nil {
+		t.Fatal(err)
+	}
+	rply, payload, err := ReadPacket(conn)
+	if err != nil || rply != RplyCompression {
+		t.Fatalf("negotiate failed: rply=%d err=%v", rply, err)
+	}
+	if payload[0] != CompressNone {
+		t.Errorf("server chose %d for an lz4-only offer, want none", payload[0])
+	}
+}
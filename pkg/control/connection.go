@@ -1,6 +1,8 @@
 package control
 
 import (
+	"bytes"
+	"crypto/tls"
 	"encoding/binary"
 	"errors"
 	"fmt"
@@ -9,6 +11,7 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"sync"
 	"syscall"
@@ -16,6 +19,7 @@ import (
 
 	"github.com/sunlightlinux/slinit/pkg/config"
 	"github.com/sunlightlinux/slinit/pkg/persist"
+	"github.com/sunlightlinux/slinit/pkg/process"
 	"github.com/sunlightlinux/slinit/pkg/service"
 )
 
@@ -57,10 +61,21 @@ type Connection struct {
 	handles    map[uint32]service.Service
 	revHandles map[service.Service]uint32 // reverse map for O(1) service→handle lookup
 	nextHandle uint32
-	listenEnv  bool       // true if client subscribed to env events
-	writeMu    sync.Mutex // serializes all writes to conn
-	closeOnce  sync.Once
-	closed     bool
+	// handleMu guards handles, revHandles, handleUsed, expired and stale: the
+	// serve goroutine owns them, but revocation on service removal runs
+	// on whichever goroutine removed the service.
+	handleMu     sync.Mutex
+	handleUsed   map[uint32]time.Time        // last time each handle was used
+	expired      map[uint32]struct{}         // handles revoked by expiry
+	stale        map[uint32]struct{}         // handles revoked because their record was replaced
+	listenEnv    bool                        // true if client subscribed to env events
+	listenEvents bool                        // true if client subscribed to every service's events
+	eventSub     <-chan service.EventMessage // CmdSubscribeEvents channel, nil if none
+	listSub      <-chan service.EventMessage // CmdSubscribeList channel, nil if none
+	listDone     chan struct{}               // closed when the listSub forwarder exits
+	writeMu      sync.Mutex                  // serializes all writes to conn
+	closeOnce    sync.Once
+	closed       bool
 
 	// peerAuthorized is set at construction time from SO_PEERCRED.
 	// True iff the connecting client has UID 0 (root) or matches the
@@ -70,6 +85,36 @@ type Connection struct {
 	// this is defense-in-depth against perm/race mistakes and against
 	// fds passed in by less trustworthy parents.
 	peerAuthorized bool
+
+	// readOnly restricts an authorized peer to the commands
+	// isReadOnlyCommand accepts: set for peers the server's
+	// AccessPolicy gives RoleReadOnly.
+	readOnly bool
+
+	// compressionAlgo is the payload compression negotiated with
+	// CmdNegotiateCompression; CompressNone until then. Guarded by
+	// writeMu, since only writePacket consults it.
+	compressionAlgo uint8
+
+	// Per-connection command rate limits (nil = unlimited). bucket
+	// meters every command; mutateBucket additionally meters the
+	// state-mutating ones. rateLimited is set while commands are being
+	// rejected so only the first rejection of a burst is logged.
+	bucket       *tokenBucket
+	mutateBucket *tokenBucket
+	rateLimited  bool
+	peerPID      int32  // from SO_PEERCRED, for log messages; 0 if unknown
+	peerUID      uint32 // from SO_PEERCRED, for audit lines; valid if peerPID != 0
+
+	// lockedFiles holds the service files this client locked with
+	// CmdLockServiceFile, by path. Closing a file drops its lock; any
+	// left are closed when the connection goes away.
+	lockedFilesMu sync.Mutex
+	lockedFiles   map[string]*os.File
+
+	// socketMode is "tcp" for clients of the TCP listener (StartTCP),
+	// "unix" otherwise. Reported by CmdQueryInfo.
+	socketMode string
 }
 
 func newConnection(server *Server, conn net.Conn) *Connection {
@@ -79,16 +124,34 @@ func newConnection(server *Server, conn net.Conn) *Connection {
 		handles:    make(map[uint32]service.Service, 8),
 		revHandles: make(map[service.Service]uint32, 8),
 		nextHandle: 1,
+		handleUsed: make(map[uint32]time.Time, 8),
+		socketMode: "unix",
+	}
+	if addr := conn.LocalAddr(); addr != nil && addr.Network() == "tcp" {
+		c.socketMode = "tcp"
+	}
+	if cred := peerCred(conn); cred != nil {
+		switch server.Access.Role(cred.Uid, cred.Gid) {
+		case RoleFull:
+			c.peerAuthorized = true
+		case RoleReadOnly:
+			c.peerAuthorized = true
+			c.readOnly = true
+		}
+		c.peerPID = cred.Pid
+		c.peerUID = cred.Uid
 	}
-	if uid, ok := peerUID(conn); ok {
-		ownUID := uint32(os.Getuid())
-		c.peerAuthorized = (uid == 0 || uid == ownUID)
+	if server.RateLimitCapacity > 0 {
+		now := time.Now()
+		c.bucket = newTokenBucket(server.RateLimitCapacity, server.RateLimitRefill, now)
+		c.mutateBucket = newTokenBucket(mutateRateLimitCapacity, mutateRateLimitRefill, now)
 	}
-	// If peerUID failed (non-Unix conn / kernel didn't return creds),
+	// If peerCred failed (non-Unix conn / kernel didn't return creds),
 	// peerAuthorized stays false → all commands rejected. This is the
 	// safe default; the only legitimate non-Unix path is unit tests
 	// (net.Pipe) which exercise dispatch directly without going through
-	// this constructor.
+	// this constructor. TLS peers are authorized in serve() once the
+	// handshake has verified their certificate.
 	return c
 }
 
@@ -99,7 +162,11 @@ func (c *Connection) writePacket(pktType uint8, payload []byte) error {
 	if c.closed {
 		return errConnClosed
 	}
-	return WritePacket(c.conn, pktType, payload)
+	if c.compressionAlgo == CompressNone {
+		return WritePacket(c.conn, pktType, payload)
+	}
+	flags, payload := encodeOutgoing(c.compressionAlgo, payload, pktType == RplySvcLog)
+	return WritePacketFlags(c.conn, pktType, flags, payload)
 }
 
 func (c *Connection) close() {
@@ -109,41 +176,184 @@ func (c *Connection) close() {
 		c.writeMu.Unlock()
 		// Unregister as listener from all unique services using revHandles
 		// (revHandles has one entry per unique service, no dedup needed)
+		c.handleMu.Lock()
 		for svc := range c.revHandles {
 			svc.Record().RemoveListener(c)
 		}
+		c.handleMu.Unlock()
+		c.server.handles.removeConnection(c)
 		// Unregister env listener
 		if c.listenEnv {
 			c.server.services.RemoveEnvListener(c)
 		}
+		if c.listenEvents {
+			c.server.services.RemoveGlobalEventListener(c)
+		}
+		if c.eventSub != nil {
+			c.server.services.EventBus().Unsubscribe(c.eventSub)
+		}
+		if c.listSub != nil {
+			c.server.services.EventBus().Unsubscribe(c.listSub)
+		}
+		c.releaseFileLocks()
 		c.conn.Close()
 	})
 }
 
 func (c *Connection) allocHandle(svc service.Service) uint32 {
+	c.handleMu.Lock()
+	defer c.handleMu.Unlock()
 	// O(1) check if this service already has a handle
 	if h, ok := c.revHandles[svc]; ok {
+		c.handleUsed[h] = time.Now()
 		return h
 	}
 	h := c.nextHandle
 	c.nextHandle++
 	c.handles[h] = svc
 	c.revHandles[svc] = h
+	c.handleUsed[h] = time.Now()
+	c.server.handles.add(svc, c, h)
 	// Auto-subscribe as listener for service events
 	svc.Record().AddListener(c)
 	return h
 }
 
+// getService resolves a handle and marks it used. A handle that has sat
+// unused for longer than handleExpiry is revoked on the spot and nil is
+// returned; badHandle then reports it as RplyHandleExpired. So is a
+// handle to a record that a type-changing reload replaced (its
+// generation is no longer the set's), reported as RplyStaleHandle.
 func (c *Connection) getService(handle uint32) service.Service {
-	return c.handles[handle]
+	c.handleMu.Lock()
+	svc := c.handles[handle]
+	if svc == nil {
+		c.handleMu.Unlock()
+		return nil
+	}
+	if time.Since(c.handleUsed[handle]) > handleExpiry {
+		c.handleMu.Unlock()
+		c.revokeHandle(handle, true)
+		return nil
+	}
+	if svc.Record().Generation() != c.server.services.CurrentGeneration(svc.Name()) {
+		if c.stale == nil {
+			c.stale = make(map[uint32]struct{})
+		}
+		c.stale[handle] = struct{}{}
+		c.handleMu.Unlock()
+		c.revokeHandle(handle, false)
+		return nil
+	}
+	c.handleUsed[handle] = time.Now()
+	c.handleMu.Unlock()
+	return svc
+}
+
+// badHandle replies to a command naming a handle that did not resolve:
+// RplyHandleExpired if the handle was revoked by expiry,
+// RplyStaleHandle if its record was replaced, RplyBadReq otherwise.
+func (c *Connection) badHandle(handle uint32) error {
+	c.handleMu.Lock()
+	_, wasExpired := c.expired[handle]
+	_, wasStale := c.stale[handle]
+	c.handleMu.Unlock()
+	if wasExpired {
+		return c.writePacket(RplyHandleExpired, nil)
+	}
+	if wasStale {
+		return c.writePacket(RplyStaleHandle, nil)
+	}
+	return c.writePacket(RplyBadReq, nil)
 }
 
 // findHandle returns the handle for a given service, or 0 and false if not found.
 func (c *Connection) findHandle(svc service.Service) (uint32, bool) {
+	c.handleMu.Lock()
+	defer c.handleMu.Unlock()
 	h, ok := c.revHandles[svc]
 	return h, ok
 }
 
+// setHandle points an existing handle at svc, replacing whatever it
+// referred to. Used when a reload swaps the service object.
+func (c *Connection) setHandle(handle uint32, svc service.Service) {
+	c.handleMu.Lock()
+	defer c.handleMu.Unlock()
+	old := c.handles[handle]
+	if old == svc {
+		return
+	}
+	if old != nil {
+		c.server.handles.remove(old, c, handle)
+		if c.revHandles[old] == handle {
+			delete(c.revHandles, old)
+		}
+	}
+	c.handles[handle] = svc
+	c.revHandles[svc] = handle
+	c.handleUsed[handle] = time.Now()
+	c.server.handles.add(svc, c, handle)
+}
+
+// revokeHandle deletes handle from the connection. The connection stops
+// listening to the service once no other handle refers to it. When
+// expired is true, later use of the handle reports RplyHandleExpired.
+func (c *Connection) revokeHandle(handle uint32, expired bool) {
+	c.handleMu.Lock()
+	svc := c.handles[handle]
+	if svc == nil {
+		c.handleMu.Unlock()
+		return
+	}
+	delete(c.handles, handle)
+	delete(c.handleUsed, handle)
+	if expired {
+		if c.expired == nil {
+			c.expired = make(map[uint32]struct{})
+		}
+		c.expired[handle] = struct{}{}
+	}
+	unlisten := false
+	if rh, ok := c.revHandles[svc]; ok && rh == handle {
+		// The reverse map pointed to this handle; find another or remove
+		var found bool
+		for h, s := range c.handles {
+			if s == svc {
+				c.revHandles[svc] = h
+				found = true
+				break
+			}
+		}
+		if !found {
+			delete(c.revHandles, svc)
+			unlisten = true
+		}
+	}
+	c.handleMu.Unlock()
+
+	c.server.handles.remove(svc, c, handle)
+	if unlisten {
+		svc.Record().RemoveListener(c)
+	}
+}
+
+// expireHandles revokes every handle that has not been used within
+// handleExpiry. Called from the serve loop's idle wakeups.
+func (c *Connection) expireHandles() {
+	var stale []uint32
+	c.handleMu.Lock()
+	for h, used := range c.handleUsed {
+		if time.Since(used) > handleExpiry {
+			stale = append(stale, h)
+		}
+	}
+	c.handleMu.Unlock()
+	for _, h := range stale {
+		c.revokeHandle(h, true)
+	}
+}
+
 // ServiceEvent implements service.ServiceListener.
 // Called from service state machine goroutines when state changes occur.
 func (c *Connection) ServiceEvent(svc service.Service, event service.ServiceEvent) {
@@ -151,6 +361,13 @@ func (c *Connection) ServiceEvent(svc service.Service, event service.ServiceEven
 	if !ok {
 		return
 	}
+	// Event delivery counts as use: a monitor that only watches a
+	// handle must not lose it to expiry while the service is active.
+	c.handleMu.Lock()
+	if _, live := c.handleUsed[handle]; live {
+		c.handleUsed[handle] = time.Now()
+	}
+	c.handleMu.Unlock()
 	// Send v5 event first, then v4 for backwards compatibility
 	payload5 := EncodeServiceEvent5(handle, uint8(event), svc)
 	c.writePacket(InfoServiceEvent5, payload5) //nolint: errcheck
@@ -165,9 +382,19 @@ func (c *Connection) EnvEvent(varString string, override bool) {
 	c.writePacket(InfoEnvEvent, payload) //nolint: errcheck
 }
 
+// GlobalServiceEvent implements service.GlobalEventListener.
+// Called for every service's events once the client sent CmdListenEvents.
+func (c *Connection) GlobalServiceEvent(ev service.GlobalEvent) {
+	c.writePacket(InfoGlobalEvent, EncodeGlobalEvent(ev)) //nolint: errcheck
+}
+
 func (c *Connection) serve() {
 	defer c.close()
 
+	if tc, ok := c.conn.(*tls.Conn); ok && !c.authorizeTLS(tc) {
+		return
+	}
+
 	for {
 		select {
 		case <-c.server.ctx.Done():
@@ -184,6 +411,7 @@ func (c *Connection) serve() {
 		cmd, payload, err := ReadPacket(c.conn)
 		if err != nil {
 			if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
+				c.expireHandles()
 				continue // deadline expired, loop back to check ctx
 			}
 			if err != io.EOF {
@@ -192,6 +420,19 @@ func (c *Connection) serve() {
 			return
 		}
 
+		if retry := c.admit(cmd, time.Now()); retry > 0 {
+			if !c.rateLimited {
+				c.rateLimited = true
+				c.server.logger.Warn("Control client (pid %d) rate limited, retry after %v", c.peerPID, retry)
+			}
+			if err := c.writePacket(RplyRateLimited, EncodeRateLimited(retry)); err != nil {
+				return
+			}
+			time.Sleep(rateLimitPause)
+			continue
+		}
+		c.rateLimited = false
+
 		if err := c.dispatch(cmd, payload); err != nil {
 			c.server.logger.Debug("Control command dispatch error: %v", err)
 			return
@@ -209,6 +450,16 @@ func (c *Connection) dispatch(cmd uint8, payload []byte) error {
 	if !c.peerAuthorized {
 		return c.writePacket(RplyBadReq, nil)
 	}
+	if !isReadOnlyCommand(cmd, payload) {
+		if c.readOnly {
+			c.server.logger.Warn("Control client %s denied %s: read-only access",
+				c.auditCaller(), auditName(cmd))
+			return c.writePacket(RplyAccessDenied, nil)
+		}
+		c.server.logger.Notice("Control: %s by %s", auditName(cmd), c.auditCaller())
+	} else if cmd == CmdLoadService && c.readOnly {
+		return c.handleFindService(payload)
+	}
 	switch cmd {
 	case CmdQueryVersion:
 		return c.handleQueryVersion()
@@ -242,6 +493,16 @@ func (c *Connection) dispatch(cmd uint8, payload []byte) error {
 		return c.handleSignal(payload)
 	case CmdUnpinService:
 		return c.handleUnpinService(payload)
+	case CmdPinService:
+		return c.handlePinService(payload)
+	case CmdSwitchTarget:
+		return c.handleSwitchTarget(payload)
+	case CmdCancelJob:
+		return c.handleCancelJob(payload)
+	case CmdQueryJob:
+		return c.handleQueryJob(payload)
+	case CmdListJobs:
+		return c.handleListJobs()
 	case CmdReloadService:
 		return c.handleReloadService(payload)
 	case CmdReloadAll:
@@ -320,11 +581,219 @@ func (c *Connection) dispatch(cmd uint8, payload []byte) error {
 		return c.handleFreezeService(payload, true)
 	case CmdThawService:
 		return c.handleFreezeService(payload, false)
+	case CmdQueryHealth:
+		return c.handleQueryHealth(payload)
+	case CmdNegotiateCompression:
+		return c.handleNegotiateCompression(payload)
+	case CmdListRecentEvents:
+		return c.handleListRecentEvents(payload)
+	case CmdListenEvents:
+		return c.handleListenEvents()
+	case CmdShutdownPlan:
+		return c.handleShutdownPlan()
+	case CmdSetRestartEnabled:
+		return c.handleSetRestartEnabled(payload)
+	case CmdVerifyInternal:
+		return c.handleVerifyInternal()
+	case CmdLockServiceFile:
+		return c.handleLockServiceFile(payload)
+	case CmdUnlockServiceFile:
+		return c.handleUnlockServiceFile(payload)
+	case CmdQueryInfo:
+		return c.handleQueryInfo()
+	case CmdKillService:
+		return c.handleKillService(payload)
+	case CmdAnnotate:
+		return c.handleAnnotate(payload)
+	case CmdListAnnotations:
+		return c.handleListAnnotations(payload)
+	case CmdGetDependencyInfo:
+		return c.handleGetDependencyInfo(payload)
+	case CmdSetServiceStopReason:
+		return c.handleSetServiceStopReason(payload)
+	case CmdSetNamedTrigger:
+		return c.handleSetNamedTrigger(payload)
+	case CmdQueryTriggers:
+		return c.handleQueryTriggers(payload)
+	case CmdSetPriority:
+		return c.handleSetPriority(payload)
+	case CmdSubscribeEvents:
+		return c.handleSubscribeEvents(payload)
+	case CmdSubscribeList:
+		return c.handleSubscribeList()
+	case CmdUnsubscribeList:
+		return c.handleUnsubscribeList()
+	case CmdListAliases:
+		return c.handleListAliases()
+	case CmdDumpDiagnostics:
+		return c.handleDumpDiagnostics()
+	case CmdListTimers:
+		return c.handleListTimers()
+	case CmdSetServiceDirs:
+		return c.handleSetServiceDirs(payload)
+	case CmdDumpGraph:
+		return c.handleDumpGraph()
 	default:
 		return c.writePacket(RplyBadReq, nil)
 	}
 }
 
+// handleListRecentEvents replies with the daemon-wide service event
+// history, oldest first. Payload: max(2); 0 means everything kept.
+// The oldest entries are dropped if the reply would not fit in one
+// packet.
+func (c *Connection) handleListRecentEvents(payload []byte) error {
+	if len(payload) < 2 {
+		return c.writePacket(RplyBadReq, nil)
+	}
+	max := int(binary.LittleEndian.Uint16(payload))
+	events := c.server.services.RecentEvents(max)
+	buf := EncodeRecentEvents(events)
+	for len(buf) > MaxPayloadSize && len(events) > 0 {
+		events = events[len(events)/8+1:]
+		buf = EncodeRecentEvents(events)
+	}
+	return c.writePacket(RplyRecentEvents, buf)
+}
+
+// handleListenEvents subscribes the connection to InfoGlobalEvent pushes
+// for every service, without needing a handle to each one. It replaces a
+// CmdSubscribeEvents subscription, so each event is sent once.
+func (c *Connection) handleListenEvents() error {
+	if c.eventSub != nil {
+		c.server.services.EventBus().Unsubscribe(c.eventSub)
+		c.eventSub = nil
+	}
+	if !c.listenEvents {
+		c.listenEvents = true
+		c.server.services.AddGlobalEventListener(c)
+	}
+	return c.writePacket(RplyACK, nil)
+}
+
+// handleSubscribeEvents subscribes the connection to InfoGlobalEvent
+// pushes for the named services, or for every service when no names are
+// given. Names need not be loaded yet. A new subscription replaces the
+// previous one, including one made with CmdListenEvents.
+func (c *Connection) handleSubscribeEvents(payload []byte) error {
+	names, err := DecodeSubscribeEvents(payload)
+	if err != nil {
+		return c.writePacket(RplyBadReq, nil)
+	}
+	if c.listenEvents {
+		c.server.services.RemoveGlobalEventListener(c)
+		c.listenEvents = false
+	}
+	bus := c.server.services.EventBus()
+	if c.eventSub != nil {
+		bus.Unsubscribe(c.eventSub)
+	}
+	byName := service.ServiceNameFilter(names...)
+	ch := bus.Subscribe(func(svc service.Service, event service.ServiceEvent) bool {
+		if service.IsMembershipEvent(event) {
+			return false
+		}
+		return byName == nil || byName(svc, event)
+	})
+	c.eventSub = ch
+	// ACK before the forwarder starts, so the reply precedes any event.
+	if err := c.writePacket(RplyACK, nil); err != nil {
+		return err
+	}
+	go func() {
+		for msg := range ch {
+			c.writePacket(InfoGlobalEvent, EncodeGlobalEvent(service.GlobalEvent{ //nolint: errcheck
+				Time:    msg.Time,
+				Service: msg.Service,
+				Event:   msg.Event,
+				Details: msg.Details,
+			}))
+		}
+	}()
+	return nil
+}
+
+// handleSubscribeList sends an RplySvcInfo entry for every service and
+// RplyListDone, as CmdListServices does, then keeps the list current:
+// RplySvcAdded and RplySvcRemoved as services are loaded and unloaded,
+// RplySvcChanged when one changes state. The bus subscription is taken
+// before the list is built, so a change racing the list is sent as an
+// update afterwards rather than lost. A new subscription replaces the
+// previous one.
+func (c *Connection) handleSubscribeList() error {
+	bus := c.server.services.EventBus()
+	c.stopListSub()
+	ch := bus.Subscribe(func(_ service.Service, event service.ServiceEvent) bool {
+		switch event {
+		case service.EventPressureMemory, service.EventPressureCPU,
+			service.EventPressureIO, service.EventAnnotation:
+			return false
+		}
+		return true
+	})
+	c.listSub = ch
+	if err := c.handleListServices(); err != nil {
+		return err
+	}
+	done := make(chan struct{})
+	c.listDone = done
+	go func() {
+		defer close(done)
+		for msg := range ch {
+			if msg.Svc == nil {
+				continue
+			}
+			rply := RplySvcChanged
+			switch msg.Event {
+			case service.EventAdded:
+				rply = RplySvcAdded
+			case service.EventRemoved:
+				rply = RplySvcRemoved
+			}
+			if err := c.writePacket(rply, EncodeSvcInfo(msg.Svc)); err != nil {
+				return
+			}
+		}
+	}()
+	return nil
+}
+
+// handleUnsubscribeList ends a CmdSubscribeList subscription. Updates
+// already queued are written before the ACK, so none follow it.
+func (c *Connection) handleUnsubscribeList() error {
+	c.stopListSub()
+	return c.writePacket(RplyACK, nil)
+}
+
+// stopListSub cancels the list subscription, if any, and waits for its
+// forwarder to finish writing.
+func (c *Connection) stopListSub() {
+	if c.listSub == nil {
+		return
+	}
+	c.server.services.EventBus().Unsubscribe(c.listSub)
+	c.listSub = nil
+	if c.listDone != nil {
+		<-c.listDone
+		c.listDone = nil
+	}
+}
+
+// handleQueryHealth reports the healthcheck-command state of a service.
+// Services without a health check get an all-zero reply, which the
+// client takes as "nothing to show".
+func (c *Connection) handleQueryHealth(payload []byte) error {
+	handle, err := DecodeHandle(payload)
+	if err != nil {
+		return c.writePacket(RplyBadReq, nil)
+	}
+	svc := c.getService(handle)
+	if svc == nil {
+		return c.badHandle(handle)
+	}
+	return c.writePacket(RplyHealth, EncodeHealth(svc))
+}
+
 // handleFreezeService writes to cgroup.freeze on the target service's
 // cgroup v2 directory. `freeze == true` corresponds to CmdFreezeService
 // ("1"), false to CmdThawService ("0"). Returns RplyNAK with the OS
@@ -338,7 +807,7 @@ func (c *Connection) handleFreezeService(payload []byte, freeze bool) error {
 	}
 	svc := c.getService(handle)
 	if svc == nil {
-		return c.writePacket(RplyBadReq, nil)
+		return c.badHandle(handle)
 	}
 	if freeze {
 		err = svc.Record().Freeze()
@@ -358,9 +827,11 @@ func (c *Connection) handleFreezeService(payload []byte, freeze bool) error {
 func (c *Connection) handleResetFailed(payload []byte) error {
 	if len(payload) == 0 {
 		// --all: iterate every loaded service and clear the flag.
-		for _, svc := range c.server.services.ListServices() {
-			svc.Record().ResetFailed()
-		}
+		c.server.services.Apply(func() {
+			for _, svc := range c.server.services.ListServices() {
+				svc.Record().ResetFailed()
+			}
+		})
 		return c.writePacket(RplyACK, nil)
 	}
 	handle, err := DecodeHandle(payload)
@@ -369,9 +840,27 @@ func (c *Connection) handleResetFailed(payload []byte) error {
 	}
 	svc := c.getService(handle)
 	if svc == nil {
+		return c.badHandle(handle)
+	}
+	c.server.services.Apply(svc.Record().ResetFailed)
+	return c.writePacket(RplyACK, nil)
+}
+
+// handleSetRestartEnabled sets the runtime auto-restart override of a
+// service. Payload: handle(4) + enabled(1). The override lasts until the
+// service is reloaded.
+func (c *Connection) handleSetRestartEnabled(payload []byte) error {
+	if len(payload) < 5 {
 		return c.writePacket(RplyBadReq, nil)
 	}
-	svc.Record().ResetFailed()
+	handle := binary.LittleEndian.Uint32(payload)
+	svc := c.getService(handle)
+	if svc == nil {
+		return c.badHandle(handle)
+	}
+	c.server.services.Apply(func() {
+		svc.Record().SetAutoRestartEnabled(payload[4] != 0)
+	})
 	return c.writePacket(RplyACK, nil)
 }
 
@@ -386,6 +875,27 @@ func (c *Connection) handleQueryVersion() error {
 	return err
 }
 
+// handleNegotiateCompression picks a compression algorithm from the
+// client's offer. The reply itself is sent uncompressed in the plain
+// framing; the chosen algorithm, and the WritePacketFlags framing that
+// goes with it, apply from the next packet on.
+func (c *Connection) handleNegotiateCompression(payload []byte) error {
+	if len(payload) < 1 {
+		return c.writePacket(RplyBadReq, nil)
+	}
+	algo := chooseCompression(payload[0])
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	if c.closed {
+		return errConnClosed
+	}
+	if err := WritePacket(c.conn, RplyCompression, []byte{algo}); err != nil {
+		return err
+	}
+	c.compressionAlgo = algo
+	return nil
+}
+
 func (c *Connection) handleFindService(payload []byte) error {
 	name, _, err := DecodeServiceName(payload)
 	if err != nil {
@@ -475,7 +985,7 @@ func (c *Connection) handleStartService(payload []byte) error {
 
 	svc := c.getService(handle)
 	if svc == nil {
-		return c.writePacket(RplyBadReq, nil)
+		return c.badHandle(handle)
 	}
 
 	if c.server.services.IsShuttingDown() {
@@ -502,16 +1012,25 @@ func (c *Connection) handleStartService(payload []byte) error {
 		return err
 	}
 
-	c.server.services.StartService(svc)
+	// The job is recorded first so the events of the start settle it.
+	job := c.server.services.NewJob(svc, service.JobStart)
+
+	// Start and pin in one step, so a concurrent stop cannot get in
+	// between and find the service unpinned.
+	c.server.services.Apply(func() {
+		svc.Start()
+		if pin {
+			svc.PinStart()
+		}
+	})
 	if pin {
-		svc.PinStart()
 		// Persist the pin so a reboot keeps the operator's intent.
 		// Errors are logged; a full disk must not fail the start.
 		if err := c.server.Pins.Set(svc.Name(), persist.IntentPinnedStarted); err != nil {
 			fmt.Fprintf(os.Stderr, "slinit: %v\n", err)
 		}
 	}
-	return c.writePacket(RplyACK, nil)
+	return c.writePacket(RplyACK, EncodeHandle(job))
 }
 
 func (c *Connection) handleWakeService(payload []byte) error {
@@ -527,7 +1046,7 @@ func (c *Connection) handleWakeService(payload []byte) error {
 
 	svc := c.getService(handle)
 	if svc == nil {
-		return c.writePacket(RplyBadReq, nil)
+		return c.badHandle(handle)
 	}
 
 	if c.server.services.IsShuttingDown() {
@@ -539,7 +1058,7 @@ func (c *Connection) handleWakeService(payload []byte) error {
 	}
 
 	if svc.Record().IsStopPinned() {
-		return c.writePacket(RplyNAK, nil)
+		return c.writePacket(RplyPinnedStopped, nil)
 	}
 
 	if err := c.sendPreACK(flags); err != nil {
@@ -552,6 +1071,35 @@ func (c *Connection) handleWakeService(payload []byte) error {
 	return c.writePacket(RplyACK, nil)
 }
 
+// handleSwitchTarget makes the service the active target, as a
+// runlevel change: it is started, and the explicitly active services it
+// does not need are released (see ServiceSet.SwitchTarget). The reply
+// lists what was released and what is stopping as a result.
+func (c *Connection) handleSwitchTarget(payload []byte) error {
+	handle, err := DecodeHandle(payload)
+	if err != nil {
+		return c.writePacket(RplyBadReq, nil)
+	}
+	svc := c.getService(handle)
+	if svc == nil {
+		return c.badHandle(handle)
+	}
+	if c.server.services.IsShuttingDown() {
+		return c.writePacket(RplyShuttingDown, nil)
+	}
+	if svc.Record().IsStopPinned() {
+		return c.writePacket(RplyPinnedStopped, nil)
+	}
+	if svc.Record().RefusesManualStart() {
+		return c.writePacket(RplyManualRefused, nil)
+	}
+	res, serr := c.server.services.SwitchTarget(svc)
+	if serr != nil {
+		return c.writePacket(RplyNAK, []byte(serr.Error()))
+	}
+	return c.writePacket(RplyTargetSwitched, EncodeTargetSwitched(res.Target, res.Released, res.Stopping))
+}
+
 func (c *Connection) handleStopService(payload []byte) error {
 	handle, err := DecodeHandle(payload)
 	if err != nil {
@@ -569,7 +1117,7 @@ func (c *Connection) handleStopService(payload []byte) error {
 
 	svc := c.getService(handle)
 	if svc == nil {
-		return c.writePacket(RplyBadReq, nil)
+		return c.badHandle(handle)
 	}
 
 	if svc.State() == service.StateStopped {
@@ -591,30 +1139,80 @@ func (c *Connection) handleStopService(payload []byte) error {
 		return err
 	}
 
-	if force {
-		c.server.services.ForceStopService(svc)
-	} else {
-		c.server.services.StopService(svc)
+	// A restart is tracked by the job of its start, created below once
+	// the stop has been issued, so the stop does not cancel it.
+	var job uint32
+	if !restart {
+		job = c.server.services.NewJob(svc, service.JobStop)
 	}
+	c.server.services.Apply(func() {
+		if force {
+			svc.Record().ForcedStop()
+		} else {
+			svc.Stop(true)
+		}
+		if pin {
+			svc.PinStop()
+		}
+	})
 	if pin {
-		svc.PinStop()
 		if err := c.server.Pins.Set(svc.Name(), persist.IntentPinnedStopped); err != nil {
 			fmt.Fprintf(os.Stderr, "slinit: %v\n", err)
 		}
 	}
 	if restart {
 		// Re-start the service after stopping (restart operation)
-		c.server.services.StartService(svc)
+		job = c.server.services.NewJob(svc, service.JobStart)
+		c.server.services.Apply(func() {
+			svc.Start()
+			if pin {
+				svc.PinStart()
+			}
+		})
 		if pin {
-			svc.PinStart()
 			if err := c.server.Pins.Set(svc.Name(), persist.IntentPinnedStarted); err != nil {
 				fmt.Fprintf(os.Stderr, "slinit: %v\n", err)
 			}
 		}
 	}
+	return c.writePacket(RplyACK, EncodeHandle(job))
+}
+
+// handleCancelJob withdraws a running start or stop job (see
+// ServiceSet.CancelJob). A job that is unknown or already settled is
+// refused with the reason.
+func (c *Connection) handleCancelJob(payload []byte) error {
+	id, err := DecodeHandle(payload)
+	if err != nil {
+		return c.writePacket(RplyBadReq, nil)
+	}
+	if err := c.server.services.CancelJob(id); err != nil {
+		return c.writePacket(RplyNAK, []byte(err.Error()))
+	}
 	return c.writePacket(RplyACK, nil)
 }
 
+func (c *Connection) handleQueryJob(payload []byte) error {
+	id, err := DecodeHandle(payload)
+	if err != nil {
+		return c.writePacket(RplyBadReq, nil)
+	}
+	j, ok := c.server.services.JobByID(id)
+	if !ok {
+		return c.writePacket(RplyNAK, []byte(fmt.Sprintf("no job %d", id)))
+	}
+	return c.writePacket(RplyJobInfo, EncodeJobInfo(j))
+}
+
+func (c *Connection) handleListJobs() error {
+	for _, j := range c.server.services.Jobs() {
+		if err := c.writePacket(RplyJobInfo, EncodeJobInfo(j)); err != nil {
+			return err
+		}
+	}
+	return c.writePacket(RplyListDone, nil)
+}
+
 func (c *Connection) handleReleaseService(payload []byte) error {
 	handle, err := DecodeHandle(payload)
 	if err != nil {
@@ -628,19 +1226,34 @@ func (c *Connection) handleReleaseService(payload []byte) error {
 
 	svc := c.getService(handle)
 	if svc == nil {
-		return c.writePacket(RplyBadReq, nil)
+		return c.badHandle(handle)
 	}
 
 	if svc.State() == service.StateStopped {
 		return c.writePacket(RplyAlreadySS, nil)
 	}
 
-	if err := c.sendPreACK(flags); err != nil {
-		return err
+	// release: remove explicit activation, stop only if unrequired.
+	// The pre-ack promises a STOPPED event, so it is only sent when the
+	// service will actually go down; otherwise a waiting client would
+	// wait for an event that never comes.
+	var werr error
+	c.server.services.Apply(func() {
+		rec := svc.Record()
+		required := rec.RequiredBy()
+		if rec.IsMarkedActive() {
+			required--
+		}
+		if required == 0 && !rec.IsStartPinned() {
+			if werr = c.sendPreACK(flags); werr != nil {
+				return
+			}
+		}
+		svc.Stop(false)
+	})
+	if werr != nil {
+		return werr
 	}
-
-	svc.Stop(false) // release: remove explicit activation, stop only if unrequired
-	c.server.services.ProcessQueues()
 	return c.writePacket(RplyACK, nil)
 }
 
@@ -663,10 +1276,17 @@ func (c *Connection) handleServiceStatus(payload []byte) error {
 
 	svc := c.getService(handle)
 	if svc == nil {
-		return c.writePacket(RplyBadReq, nil)
+		return c.badHandle(handle)
 	}
 
-	status := EncodeServiceStatus(svc)
+	// Older clients decode only the first 12 bytes and ignore the
+	// failure-stats, stop-reason, restart, last-error and started-time
+	// trailers.
+	status := append(EncodeServiceStatus(svc), EncodeFailureStats(svc)...)
+	status = append(status, EncodeStopReason(svc)...)
+	status = append(status, EncodeEffectiveRestart(svc)...)
+	status = append(status, EncodeLastError(svc)...)
+	status = append(status, EncodeStartedTime(svc)...)
 	return c.writePacket(RplyServiceStatus, status)
 }
 
@@ -689,7 +1309,7 @@ func (c *Connection) handleServiceStatus5(payload []byte) error {
 
 	svc := c.getService(handle)
 	if svc == nil {
-		return c.writePacket(RplyBadReq, nil)
+		return c.badHandle(handle)
 	}
 
 	status := EncodeServiceStatus5(svc)
@@ -783,34 +1403,189 @@ func (c *Connection) handleQueryShutdown() error {
 	return c.writePacket(RplyShutdownStatus, payload)
 }
 
-func (c *Connection) handleCloseHandle(payload []byte) error {
-	handle, err := DecodeHandle(payload)
-	if err != nil {
-		return c.writePacket(RplyBadReq, nil)
+// handleShutdownPlan reports the order and estimated duration of a
+// shutdown without initiating one.
+func (c *Connection) handleShutdownPlan() error {
+	return c.writePacket(RplyShutdownPlan, EncodeShutdownPlan(c.server.services.ShutdownPlan()))
+}
+
+// handleVerifyInternal runs the service set's reference-count checks
+// and replies with every discrepancy found (none when consistent).
+func (c *Connection) handleVerifyInternal() error {
+	errs := c.server.services.VerifyRefCounts()
+	buf := EncodeRefCountErrors(errs)
+	for len(buf) > MaxPayloadSize && len(errs) > 0 {
+		errs = errs[:len(errs)/2]
+		buf = EncodeRefCountErrors(errs)
 	}
+	return c.writePacket(RplyRefCountErrors, buf)
+}
 
-	svc := c.handles[handle]
-	delete(c.handles, handle)
+// handleListAliases replies with every alias (provides and aliases
+// settings) and the service it resolves to.
+func (c *Connection) handleListAliases() error {
+	return c.writePacket(RplyAliases, EncodeAliases(c.server.services.ListAliases()))
+}
 
-	// Only remove listener if no other handle references this service
-	if svc != nil {
-		if rh, ok := c.revHandles[svc]; ok && rh == handle {
-			// The reverse map pointed to this handle; find another or remove
-			var found bool
-			for h, s := range c.handles {
-				if s == svc {
-					c.revHandles[svc] = h
-					found = true
-					break
-				}
-			}
-			if !found {
-				delete(c.revHandles, svc)
-				svc.Record().RemoveListener(c)
+// handleListTimers replies with every timer service, its target and
+// its next and last firing times.
+func (c *Connection) handleListTimers() error {
+	return c.writePacket(RplyTimers, EncodeTimers(c.server.services.ListTimers()))
+}
+
+// handleDumpGraph streams the dependency graph, one RplyGraphNode per
+// service carrying its outgoing edges, and ends with RplyListDone.
+// Per-service packets keep large graphs within the packet size limit.
+func (c *Connection) handleDumpGraph() error {
+	nodes, edges := c.server.services.Graph()
+	for _, n := range nodes {
+		// Edges are sorted by From, in the same order as nodes.
+		i := 0
+		for i < len(edges) && edges[i].From == n.Name {
+			i++
+		}
+		if err := c.writePacket(RplyGraphNode, EncodeGraphNode(n, edges[:i])); err != nil {
+			return err
+		}
+		edges = edges[i:]
+	}
+	return c.writePacket(RplyListDone, nil)
+}
+
+// handleDumpDiagnostics replies with the ServiceSet.PrintDiagnostics
+// text, split into RplyDiagnostics packets since it easily outgrows
+// one, and ends the run with RplyListDone.
+func (c *Connection) handleDumpDiagnostics() error {
+	var buf bytes.Buffer
+	if err := c.server.services.PrintDiagnostics(&buf); err != nil {
+		return c.writePacket(RplyNAK, nil)
+	}
+	text := buf.Bytes()
+	for len(text) > 0 {
+		n := min(len(text), MaxPayloadSize)
+		if err := c.writePacket(RplyDiagnostics, text[:n]); err != nil {
+			return err
+		}
+		text = text[n:]
+	}
+	return c.writePacket(RplyListDone, nil)
+}
+
+// handleQueryInfo replies with key/value facts about the daemon and
+// this connection, so a client knows what it is talking to.
+func (c *Connection) handleQueryInfo() error {
+	pairs := [][2]string{
+		{"socket-mode", c.socketMode},
+		{"protocol", strconv.Itoa(int(CPVersion))},
+		{"pid", strconv.Itoa(os.Getpid())},
+	}
+	// One service-dir entry per directory, in search order.
+	if loader := c.server.services.GetLoader(); loader != nil {
+		for _, d := range loader.ServiceDirs() {
+			if abs, err := filepath.Abs(d); err == nil {
+				d = abs
 			}
+			pairs = append(pairs, [2]string{"service-dir", d})
 		}
-		// else: revHandles points to a different handle for this service, still referenced
 	}
+	return c.writePacket(RplyInfo, EncodeInfo(pairs))
+}
+
+// handleLockServiceFile takes an exclusive lock on a service's
+// description file for the client (slinitctl edit), so a concurrent
+// load or reload waits instead of reading a half-written file. Replies
+// with the file's path; with file locking disabled the path is sent
+// without taking a lock. NAK means someone else holds the lock.
+func (c *Connection) handleLockServiceFile(payload []byte) error {
+	name, _, err := DecodeServiceName(payload)
+	if err != nil {
+		return c.writePacket(RplyBadReq, nil)
+	}
+	path, ok := c.serviceFilePath(name)
+	if !ok {
+		return c.writePacket(RplyNoService, nil)
+	}
+	if !config.FileLocking() {
+		return c.writePacket(RplyServiceFile, EncodeServiceName(path))
+	}
+
+	c.lockedFilesMu.Lock()
+	defer c.lockedFilesMu.Unlock()
+	if _, held := c.lockedFiles[path]; !held {
+		f, err := config.LockServiceFileExclusive(path)
+		if err != nil {
+			return c.writePacket(RplyNAK, nil)
+		}
+		if c.lockedFiles == nil {
+			c.lockedFiles = make(map[string]*os.File)
+		}
+		c.lockedFiles[path] = f
+	}
+	return c.writePacket(RplyServiceFile, EncodeServiceName(path))
+}
+
+// handleUnlockServiceFile releases a lock taken by
+// handleLockServiceFile. NAK if this connection holds none for the
+// service.
+func (c *Connection) handleUnlockServiceFile(payload []byte) error {
+	name, _, err := DecodeServiceName(payload)
+	if err != nil {
+		return c.writePacket(RplyBadReq, nil)
+	}
+	path, ok := c.serviceFilePath(name)
+	if !ok {
+		return c.writePacket(RplyNoService, nil)
+	}
+	if !config.FileLocking() {
+		return c.writePacket(RplyACK, nil)
+	}
+
+	c.lockedFilesMu.Lock()
+	f, held := c.lockedFiles[path]
+	delete(c.lockedFiles, path)
+	c.lockedFilesMu.Unlock()
+	if !held {
+		return c.writePacket(RplyNAK, nil)
+	}
+	f.Close()
+	return c.writePacket(RplyACK, nil)
+}
+
+// serviceFilePath resolves name to the absolute path of its description
+// file in the loader's service directories.
+func (c *Connection) serviceFilePath(name string) (string, bool) {
+	loader := c.server.services.GetLoader()
+	if loader == nil {
+		return "", false
+	}
+	path, err := config.FindServiceFile(loader.ServiceDirs(), name)
+	if err != nil {
+		return "", false
+	}
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+	return path, true
+}
+
+// releaseFileLocks drops every service-file lock the client still
+// holds. Called when the connection closes.
+func (c *Connection) releaseFileLocks() {
+	c.lockedFilesMu.Lock()
+	defer c.lockedFilesMu.Unlock()
+	for path, f := range c.lockedFiles {
+		f.Close()
+		delete(c.lockedFiles, path)
+	}
+}
+
+func (c *Connection) handleCloseHandle(payload []byte) error {
+	handle, err := DecodeHandle(payload)
+	if err != nil {
+		return c.writePacket(RplyBadReq, nil)
+	}
+
+	c.revokeHandle(handle, false)
 	return c.writePacket(RplyACK, nil)
 }
 
@@ -825,7 +1600,7 @@ func (c *Connection) handleSetTrigger(payload []byte) error {
 
 	svc := c.getService(handle)
 	if svc == nil {
-		return c.writePacket(RplyBadReq, nil)
+		return c.badHandle(handle)
 	}
 
 	// Check if it's a triggered service
@@ -834,8 +1609,63 @@ func (c *Connection) handleSetTrigger(payload []byte) error {
 		return c.writePacket(RplyNAK, nil)
 	}
 
-	triggered.SetTrigger(triggerVal)
-	c.server.services.ProcessQueues()
+	c.server.services.Apply(func() { triggered.SetTrigger(triggerVal) })
+	return c.writePacket(RplyACK, nil)
+}
+
+// handleSetNamedTrigger sets or clears one named trigger of a triggered
+// service. NAK if the service is not triggered or does not declare the
+// name.
+func (c *Connection) handleSetNamedTrigger(payload []byte) error {
+	handle, name, value, err := DecodeSetNamedTrigger(payload)
+	if err != nil {
+		return c.writePacket(RplyBadReq, nil)
+	}
+	svc := c.getService(handle)
+	if svc == nil {
+		return c.badHandle(handle)
+	}
+	triggered, ok := svc.(*service.TriggeredService)
+	if !ok {
+		return c.writePacket(RplyNAK, nil)
+	}
+	c.server.services.Apply(func() { err = triggered.SetNamedTrigger(name, value) })
+	if err != nil {
+		return c.writePacket(RplyNAK, nil)
+	}
+	return c.writePacket(RplyACK, nil)
+}
+
+// handleQueryTriggers replies with the plain and named trigger state of
+// a triggered service; NAK for any other service type.
+func (c *Connection) handleQueryTriggers(payload []byte) error {
+	handle, err := DecodeHandle(payload)
+	if err != nil {
+		return c.writePacket(RplyBadReq, nil)
+	}
+	svc := c.getService(handle)
+	if svc == nil {
+		return c.badHandle(handle)
+	}
+	triggered, ok := svc.(*service.TriggeredService)
+	if !ok {
+		return c.writePacket(RplyNAK, nil)
+	}
+	return c.writePacket(RplyTriggers, EncodeTriggers(triggered))
+}
+
+// handleSetPriority changes the propagation queue priority of a
+// service until it is reloaded.
+func (c *Connection) handleSetPriority(payload []byte) error {
+	handle, priority, err := DecodeSetPriority(payload)
+	if err != nil {
+		return c.writePacket(RplyBadReq, nil)
+	}
+	svc := c.getService(handle)
+	if svc == nil {
+		return c.badHandle(handle)
+	}
+	c.server.services.SetServicePriority(svc, int(priority))
 	return c.writePacket(RplyACK, nil)
 }
 
@@ -859,7 +1689,7 @@ func (c *Connection) handleReloadSignal(payload []byte) error {
 
 	svc := c.getService(handle)
 	if svc == nil {
-		return c.writePacket(RplyBadReq, nil)
+		return c.badHandle(handle)
 	}
 
 	sig := svc.Record().ReloadSignal()
@@ -896,7 +1726,7 @@ func (c *Connection) handleSignal(payload []byte) error {
 
 	svc := c.getService(handle)
 	if svc == nil {
-		return c.writePacket(RplyBadReq, nil)
+		return c.badHandle(handle)
 	}
 
 	pid := svc.PID()
@@ -927,7 +1757,7 @@ func (c *Connection) handlePauseService(payload []byte) error {
 	}
 	svc := c.getService(handle)
 	if svc == nil {
-		return c.writePacket(RplyBadReq, nil)
+		return c.badHandle(handle)
 	}
 	ps, ok := svc.(*service.ProcessService)
 	if !ok {
@@ -946,7 +1776,7 @@ func (c *Connection) handleContinueService(payload []byte) error {
 	}
 	svc := c.getService(handle)
 	if svc == nil {
-		return c.writePacket(RplyBadReq, nil)
+		return c.badHandle(handle)
 	}
 	ps, ok := svc.(*service.ProcessService)
 	if !ok {
@@ -965,35 +1795,161 @@ func (c *Connection) handleOnceService(payload []byte) error {
 	}
 	svc := c.getService(handle)
 	if svc == nil {
-		return c.writePacket(RplyBadReq, nil)
+		return c.badHandle(handle)
 	}
 	if c.server.services.IsShuttingDown() {
 		return c.writePacket(RplyShuttingDown, nil)
 	}
 	// Start once: set auto-restart to never, then start
-	svc.Record().SetAutoRestart(service.RestartNever)
-	c.server.services.StartService(svc)
+	c.server.services.Apply(func() {
+		svc.Record().SetAutoRestart(service.RestartNever)
+		svc.Start()
+	})
 	return c.writePacket(RplyACK, nil)
 }
 
-func (c *Connection) handleUnpinService(payload []byte) error {
+// handleKillService stops a service with SIGKILL and keeps it from
+// coming back: unlike a (forced) stop it also drops the service's
+// wanted state and suspends auto-restart until the next start.
+func (c *Connection) handleKillService(payload []byte) error {
 	handle, err := DecodeHandle(payload)
 	if err != nil {
 		return c.writePacket(RplyBadReq, nil)
 	}
+	svc := c.getService(handle)
+	if svc == nil {
+		return c.badHandle(handle)
+	}
+	if svc.State() == service.StateStopped {
+		return c.writePacket(RplyAlreadySS, nil)
+	}
+	if svc.Record().IsStartPinned() {
+		return c.writePacket(RplyPinnedStarted, nil)
+	}
+	c.server.services.KillService(svc)
+	return c.writePacket(RplyACK, nil)
+}
 
+// handleAnnotate attaches an operator note to a service, credited to
+// the connected user.
+func (c *Connection) handleAnnotate(payload []byte) error {
+	handle, msg, err := DecodeAnnotate(payload)
+	if err != nil || msg == "" || len(msg) > MaxAnnotationLen {
+		return c.writePacket(RplyBadReq, nil)
+	}
 	svc := c.getService(handle)
 	if svc == nil {
+		return c.badHandle(handle)
+	}
+	svc.Record().AnnotateEvent(c.peerUser(), msg)
+	return c.writePacket(RplyACK, nil)
+}
+
+// handleSetServiceStopReason records an application-defined reason for
+// a service stopping. Codes below ReasonCustom are slinit's own and are
+// refused.
+func (c *Connection) handleSetServiceStopReason(payload []byte) error {
+	handle, code, msg, err := DecodeSetStopReason(payload)
+	if err != nil || len(msg) > MaxAnnotationLen {
 		return c.writePacket(RplyBadReq, nil)
 	}
+	svc := c.getService(handle)
+	if svc == nil {
+		return c.badHandle(handle)
+	}
+	if err := svc.Record().SetCustomStopReason(code, msg); err != nil {
+		return c.writePacket(RplyNAK, nil)
+	}
+	return c.writePacket(RplyACK, nil)
+}
+
+// handleListAnnotations replies with a service's operator notes, oldest
+// first. The oldest are dropped if the reply would not fit in one
+// packet.
+func (c *Connection) handleListAnnotations(payload []byte) error {
+	handle, err := DecodeHandle(payload)
+	if err != nil {
+		return c.writePacket(RplyBadReq, nil)
+	}
+	svc := c.getService(handle)
+	if svc == nil {
+		return c.badHandle(handle)
+	}
+	as := svc.Record().Annotations()
+	buf := EncodeAnnotations(as)
+	for len(buf) > MaxPayloadSize && len(as) > 0 {
+		as = as[len(as)/8+1:]
+		buf = EncodeAnnotations(as)
+	}
+	return c.writePacket(RplyAnnotations, buf)
+}
 
-	svc.Unpin()
+func (c *Connection) handleUnpinService(payload []byte) error {
+	handle, err := DecodeHandle(payload)
+	if err != nil {
+		return c.writePacket(RplyBadReq, nil)
+	}
+
+	svc := c.getService(handle)
+	if svc == nil {
+		return c.badHandle(handle)
+	}
+
+	c.server.services.Apply(svc.Unpin)
 	// Drop any persisted intent — unpin means the operator no longer
 	// wants slinit to re-apply pins on the next boot.
 	if err := c.server.Pins.Clear(svc.Name()); err != nil {
 		fmt.Fprintf(os.Stderr, "slinit: %v\n", err)
 	}
-	c.server.services.ProcessQueues()
+	return c.writePacket(RplyACK, nil)
+}
+
+// handlePinService pins a service started or stopped and brings it to
+// that state: unlike start/stop --pin it also pins a service that is
+// already there. The opposite pin must be removed (unpin) first.
+func (c *Connection) handlePinService(payload []byte) error {
+	handle, pin, err := DecodePinService(payload)
+	if err != nil {
+		return c.writePacket(RplyBadReq, nil)
+	}
+	svc := c.getService(handle)
+	if svc == nil {
+		return c.badHandle(handle)
+	}
+
+	intent := persist.IntentPinnedStarted
+	if pin == PinStarted {
+		if svc.Record().IsStopPinned() {
+			return c.writePacket(RplyPinnedStopped, nil)
+		}
+		if svc.State() != service.StateStarted {
+			if c.server.services.IsShuttingDown() {
+				return c.writePacket(RplyShuttingDown, nil)
+			}
+			if svc.Record().RefusesManualStart() {
+				return c.writePacket(RplyManualRefused, nil)
+			}
+		}
+		c.server.services.Apply(func() {
+			svc.Start()
+			svc.PinStart()
+		})
+	} else {
+		if svc.Record().IsStartPinned() {
+			return c.writePacket(RplyPinnedStarted, nil)
+		}
+		if svc.State() != service.StateStopped && svc.Record().RefusesManualStop() {
+			return c.writePacket(RplyManualRefused, nil)
+		}
+		intent = persist.IntentPinnedStopped
+		c.server.services.Apply(func() {
+			svc.Stop(true)
+			svc.PinStop()
+		})
+	}
+	if err := c.server.Pins.Set(svc.Name(), intent); err != nil {
+		fmt.Fprintf(os.Stderr, "slinit: %v\n", err)
+	}
 	return c.writePacket(RplyACK, nil)
 }
 
@@ -1022,8 +1978,15 @@ func (c *Connection) handleBootTime() error {
 		if dur > 0 {
 			entry.StartupNs = int64(dur)
 		}
+		if t := svc.Record().StartRequestTime(); !t.IsZero() {
+			entry.StartRequestNs = t.UnixNano()
+		}
+		if t := svc.Record().StartedTime(); !t.IsZero() {
+			entry.StartedNs = t.UnixNano()
+		}
 		info.Services = append(info.Services, entry)
 	}
+	info.Events = ss.BootEventLog()
 
 	payload := EncodeBootTime(info)
 	return c.writePacket(RplyBootTime, payload)
@@ -1037,7 +2000,7 @@ func (c *Connection) handleCatLog(payload []byte) error {
 
 	svc := c.getService(handle)
 	if svc == nil {
-		return c.writePacket(RplyBadReq, nil)
+		return c.badHandle(handle)
 	}
 
 	switch svc.GetLogType() {
@@ -1116,7 +2079,7 @@ func (c *Connection) handleReloadService(payload []byte) error {
 
 	svc := c.getService(handle)
 	if svc == nil {
-		return c.writePacket(RplyBadReq, nil)
+		return c.badHandle(handle)
 	}
 
 	// Refuse if service is in a transitional state
@@ -1137,7 +2100,7 @@ func (c *Connection) handleReloadService(payload []byte) error {
 
 	// If service was replaced (type change), update handle mapping
 	if newSvc != svc {
-		c.handles[handle] = newSvc
+		c.setHandle(handle, newSvc)
 	}
 
 	c.server.services.ProcessQueues()
@@ -1155,49 +2118,26 @@ func (c *Connection) handleReloadService(payload []byte) error {
 // Per-connection handle remapping: if a service was replaced (a type
 // change between reads), update this connection's handle map so the
 // caller's outstanding handle keeps resolving. Other connections that
-// hold a stale handle to the old service object are left untouched —
-// same trade-off as the single-service handleReloadService, fixing
-// it system-wide is a separate concern.
+// hold a handle to the old service object get RplyStaleHandle when
+// they next use it (see getService), as with handleReloadService.
 func (c *Connection) handleReloadAll() error {
-	loader := c.server.services.GetLoader()
-	if loader == nil {
+	if c.server.services.GetLoader() == nil {
 		return c.writePacket(RplyNAK, nil)
 	}
 
-	var ok, failed uint16
-
-	for _, svc := range c.server.services.ListServices() {
-		state := svc.State()
-		if state != service.StateStopped && state != service.StateStarted {
-			// Skipped (transitional). Don't count as failed —
-			// the config on disk may be fine, just bad timing.
-			continue
-		}
-
-		newSvc, err := loader.ReloadService(svc)
-		if err != nil {
-			failed++
-			continue
+	results := c.server.services.ReloadAll(func(oldSvc, newSvc service.Service) {
+		// Type change: swap any of THIS connection's handles
+		// pointing at the old object.
+		if h, found := c.findHandle(oldSvc); found {
+			c.setHandle(h, newSvc)
 		}
-		ok++
+	})
 
-		if newSvc != svc {
-			// Type change: swap any of THIS connection's handles
-			// pointing at the old object.
-			if h, found := c.revHandles[svc]; found {
-				delete(c.revHandles, svc)
-				c.handles[h] = newSvc
-				c.revHandles[newSvc] = h
-			}
-		}
+	buf := EncodeReloadAll(results)
+	if len(buf) > MaxPayloadSize {
+		buf = buf[:4] // too many to list: the counts only
 	}
-
-	c.server.services.ProcessQueues()
-
-	payload := make([]byte, 4)
-	binary.LittleEndian.PutUint16(payload[0:2], ok)
-	binary.LittleEndian.PutUint16(payload[2:4], failed)
-	return c.writePacket(RplyReloadAllResult, payload)
+	return c.writePacket(RplyReloadAllResult, buf)
 }
 
 func (c *Connection) handleUnloadService(payload []byte) error {
@@ -1208,7 +2148,7 @@ func (c *Connection) handleUnloadService(payload []byte) error {
 
 	svc := c.getService(handle)
 	if svc == nil {
-		return c.writePacket(RplyBadReq, nil)
+		return c.badHandle(handle)
 	}
 
 	// Service must be stopped
@@ -1218,31 +2158,24 @@ func (c *Connection) handleUnloadService(payload []byte) error {
 
 	// Count how many handles in this connection point to the service
 	handleCount := 0
+	c.handleMu.Lock()
 	for _, s := range c.handles {
 		if s == svc {
 			handleCount++
 		}
 	}
+	c.handleMu.Unlock()
 
 	// Check if service has only ordering dependents (no active non-ordering refs)
 	if !svc.Record().HasLoneRef(handleCount) {
 		return c.writePacket(RplyNAK, nil)
 	}
 
-	// Unregister as listener before removing handles
-	svc.Record().RemoveListener(c)
-
-	// Unload: clean up deps and remove from set
+	// Unload: clean up deps and remove from set. Removal revokes every
+	// connection's handles to svc (this one included) via the server's
+	// HandleRegistry.
 	c.server.services.UnloadService(svc)
 
-	// Remove all handles pointing to this service
-	for h, s := range c.handles {
-		if s == svc {
-			delete(c.handles, h)
-		}
-	}
-	delete(c.revHandles, svc)
-
 	return c.writePacket(RplyACK, nil)
 }
 
@@ -1263,13 +2196,15 @@ func (c *Connection) handleSetEnv(payload []byte) error {
 		// Per-service environment
 		svc := c.getService(handle)
 		if svc == nil {
-			return c.writePacket(RplyBadReq, nil)
-		}
-		if isUnset {
-			svc.Record().UnsetEnvVar(key)
-		} else {
-			svc.Record().SetEnvVar(key, value)
+			return c.badHandle(handle)
 		}
+		c.server.services.Apply(func() {
+			if isUnset {
+				svc.Record().UnsetEnvVar(key)
+			} else {
+				svc.Record().SetEnvVar(key, value)
+			}
+		})
 	}
 	return c.writePacket(RplyACK, nil)
 }
@@ -1287,9 +2222,9 @@ func (c *Connection) handleResetEnv(payload []byte) error {
 	}
 	svc := c.getService(handle)
 	if svc == nil {
-		return c.writePacket(RplyBadReq, nil)
+		return c.badHandle(handle)
 	}
-	svc.Record().ResetEnv()
+	c.server.services.Apply(svc.Record().ResetEnv)
 	return c.writePacket(RplyACK, nil)
 }
 
@@ -1314,10 +2249,11 @@ func (c *Connection) handleGetAllEnv(payload []byte) error {
 
 	svc := c.getService(handle)
 	if svc == nil {
-		return c.writePacket(RplyBadReq, nil)
+		return c.badHandle(handle)
 	}
 
-	env := svc.Record().GetAllEnv()
+	var env map[string]string
+	c.server.services.View(func() { env = svc.Record().GetAllEnv() })
 	reply := EncodeEnvList(env)
 	return c.writePacket(RplyEnvList, reply)
 }
@@ -1343,26 +2279,34 @@ func (c *Connection) handleAddDep(payload []byte) error {
 		return c.writePacket(RplyBadReq, nil)
 	}
 
-	// Check for circular dependency before adding
-	if service.CheckCircularDep(from, to) {
-		return c.writePacket(RplyNAK, nil)
-	}
-
-	// Add the dependency
-	dep := from.Record().AddDep(to, service.DependencyType(depType))
+	// The cycle check, the new edge and the depth update happen under
+	// one lock, so two clients cannot each add half of a cycle.
+	ok := true
+	c.server.services.Apply(func() {
+		// Check for circular dependency before adding
+		if service.CheckCircularDep(from, to) {
+			ok = false
+			return
+		}
 
-	// Update dependency depths with rollback on failure
-	var updater service.DepDepthUpdater
-	updater.AddPotentialUpdate(from)
-	if err := updater.ProcessUpdates(); err != nil {
-		// Depth limit exceeded — remove the dep we just added and rollback depths
-		from.Record().RmDep(to, service.DependencyType(depType))
-		updater.Rollback()
-		_ = dep
+		// Add the dependency
+		from.Record().AddDep(to, service.DependencyType(depType))
+
+		// Update dependency depths with rollback on failure
+		var updater service.DepDepthUpdater
+		updater.AddPotentialUpdate(from)
+		if err := updater.ProcessUpdates(); err != nil {
+			// Depth limit exceeded — remove the dep we just added and rollback depths
+			from.Record().RmDep(to, service.DependencyType(depType))
+			updater.Rollback()
+			ok = false
+			return
+		}
+		updater.Commit()
+	})
+	if !ok {
 		return c.writePacket(RplyNAK, nil)
 	}
-	updater.Commit()
-
 	return c.writePacket(RplyACK, nil)
 }
 
@@ -1382,26 +2326,31 @@ func (c *Connection) handleRmDep(payload []byte) error {
 		return c.writePacket(RplyBadReq, nil)
 	}
 
-	if !from.Record().RmDep(to, service.DependencyType(depType)) {
+	removed := false
+	c.server.services.Apply(func() {
+		if !from.Record().RmDep(to, service.DependencyType(depType)) {
+			return
+		}
+		removed = true
+
+		// Recalculate depths after removal
+		var updater service.DepDepthUpdater
+		updater.AddPotentialUpdate(from)
+		// Also queue dependents of from since its depth may decrease
+		for _, dept := range from.Record().Dependents() {
+			updater.AddPotentialUpdate(dept.From)
+		}
+		if err := updater.ProcessUpdates(); err != nil {
+			// Depth recalc on remove should never fail (depths only decrease),
+			// but commit anyway to be safe.
+			updater.Rollback()
+		} else {
+			updater.Commit()
+		}
+	})
+	if !removed {
 		return c.writePacket(RplyNAK, nil)
 	}
-
-	// Recalculate depths after removal
-	var updater service.DepDepthUpdater
-	updater.AddPotentialUpdate(from)
-	// Also queue dependents of from since its depth may decrease
-	for _, dept := range from.Record().Dependents() {
-		updater.AddPotentialUpdate(dept.From)
-	}
-	if err := updater.ProcessUpdates(); err != nil {
-		// Depth recalc on remove should never fail (depths only decrease),
-		// but commit anyway to be safe.
-		updater.Rollback()
-	} else {
-		updater.Commit()
-	}
-
-	c.server.services.ProcessQueues()
 	return c.writePacket(RplyACK, nil)
 }
 
@@ -1418,7 +2367,7 @@ func (c *Connection) handleEnableService(payload []byte, v7 bool) error {
 
 	svc := c.getService(handle)
 	if svc == nil {
-		return c.writePacket(RplyBadReq, nil)
+		return c.badHandle(handle)
 	}
 
 	if c.server.services.IsShuttingDown() {
@@ -1454,23 +2403,28 @@ func (c *Connection) handleEnableService(payload []byte, v7 bool) error {
 	// dinit's `add_service_dep` behaviour where a WAITS_FOR request on a
 	// service that already has a REGULAR dep on the same target is a
 	// no-op.
-	depExists := false
-	for _, dep := range fromSvc.Record().Dependencies() {
-		if dep.To == svc && dep.DepType != service.DepBefore &&
-			dep.DepType != service.DepAfter {
-			depExists = true
-			break
+	depExists, circular := false, false
+	c.server.services.Apply(func() {
+		for _, dep := range fromSvc.Record().Dependencies() {
+			if dep.To == svc && dep.DepType != service.DepBefore &&
+				dep.DepType != service.DepAfter {
+				depExists = true
+				return
+			}
 		}
-	}
-
-	if !depExists {
 		if service.CheckCircularDep(fromSvc, svc) {
-			return c.writePacket(RplyNAK, nil)
+			circular = true
+			return
 		}
 		fromSvc.Record().AddDep(svc, service.DepWaitsFor)
+	})
+	if circular {
+		return c.writePacket(RplyNAK, nil)
+	}
 
-		// Persist by creating a waits-for.d symlink in the source
-		// service's load directory, so the dependency survives a
+	if !depExists {
+		// Persist by creating a symlink in the source service's
+		// waits-for.d directory, so the dependency survives a
 		// daemon restart. A persistence failure is logged but does
 		// not undo the runtime change — operators who re-enable after
 		// the disk is full or read-only should see the error in logs
@@ -1504,7 +2458,7 @@ func (c *Connection) handleDisableService(payload []byte) error {
 
 	svc := c.getService(handle)
 	if svc == nil {
-		return c.writePacket(RplyBadReq, nil)
+		return c.badHandle(handle)
 	}
 
 	// Determine "from" service: explicit handle → enable-via → boot service
@@ -1529,7 +2483,7 @@ func (c *Connection) handleDisableService(payload []byte) error {
 	}
 
 	// Remove waits-for dependency from source to target
-	fromSvc.Record().RmDep(svc, service.DepWaitsFor)
+	c.server.services.Apply(func() { fromSvc.Record().RmDep(svc, service.DepWaitsFor) })
 
 	// Remove the persisted waits-for.d symlink (if any). Errors other
 	// than ENOENT are logged but not propagated — the in-memory dep is
@@ -1544,22 +2498,23 @@ func (c *Connection) handleDisableService(payload []byte) error {
 	return c.writePacket(RplyACK, nil)
 }
 
-// persistEnable creates a symlink from <fromSvc-dir>/waits-for.d/<to-name>
-// to ../<to-name>, so the enable survives a daemon restart. If fromSvc has
-// no recorded load directory (e.g. it was added programmatically without
-// loading from disk), the call is a no-op — there is no on-disk service
-// description to anchor the link.
-//
-// The fromSvc service-dir is the directory the description was loaded
-// from. We don't follow user-overridden waits-for.d= paths here; using
-// the canonical subdirectory matches the offline path slinitctl uses and
-// the directory the loader scans by default.
+// persistEnable creates a <to-name> symlink in the first waits-for.d
+// directory of fromSvc, so the enable survives a daemon restart. The
+// link points at the target's description file; the loader only reads
+// the entry name, but a resolvable link keeps the directory readable.
+// A fromSvc with no load directory (e.g. one added programmatically) has
+// no on-disk description to anchor the link, and the call is a no-op.
+// A loaded fromSvc that declares no waits-for.d directory is an error:
+// a link anywhere else would be silently ignored on the next boot.
 func persistEnable(fromSvc, toSvc service.Service) error {
-	dir := fromSvc.Record().ServiceDir()
-	if dir == "" {
+	if fromSvc.Record().ServiceDir() == "" {
 		return nil
 	}
-	waitsDir := filepath.Join(dir, "waits-for.d")
+	dirs := fromSvc.Record().WaitsForDirs()
+	if len(dirs) == 0 {
+		return fmt.Errorf("service '%s' has no waits-for.d directory", fromSvc.Name())
+	}
+	waitsDir := dirs[0]
 	if err := os.MkdirAll(waitsDir, 0755); err != nil {
 		return err
 	}
@@ -1568,20 +2523,30 @@ func persistEnable(fromSvc, toSvc service.Service) error {
 		return nil // already present
 	}
 	target := filepath.Join("..", toSvc.Name())
+	if toDir := toSvc.Record().ServiceDir(); toDir != "" {
+		for _, fn := range config.TemplateFileNames(toSvc.Name()) {
+			file := filepath.Join(toDir, fn)
+			if _, err := os.Stat(file); err != nil {
+				continue
+			}
+			if rel, err := filepath.Rel(waitsDir, file); err == nil {
+				target = rel
+			}
+			break
+		}
+	}
 	return os.Symlink(target, link)
 }
 
-// persistDisable removes the symlink created by persistEnable. ENOENT is
-// not an error: the disable still succeeded in memory and an absent link
-// is the desired end state.
+// persistDisable removes the symlink created by persistEnable from every
+// waits-for.d directory of fromSvc. ENOENT is not an error: the disable
+// still succeeded in memory and an absent link is the desired end state.
 func persistDisable(fromSvc, toSvc service.Service) error {
-	dir := fromSvc.Record().ServiceDir()
-	if dir == "" {
-		return nil
-	}
-	link := filepath.Join(dir, "waits-for.d", toSvc.Name())
-	if err := os.Remove(link); err != nil && !os.IsNotExist(err) {
-		return err
+	for _, dir := range fromSvc.Record().WaitsForDirs() {
+		link := filepath.Join(dir, toSvc.Name())
+		if err := os.Remove(link); err != nil && !os.IsNotExist(err) {
+			return err
+		}
 	}
 	return nil
 }
@@ -1594,7 +2559,7 @@ func (c *Connection) handleQueryServiceName(payload []byte) error {
 
 	svc := c.getService(handle)
 	if svc == nil {
-		return c.writePacket(RplyBadReq, nil)
+		return c.badHandle(handle)
 	}
 
 	return c.writePacket(RplyServiceName, EncodeServiceName(svc.Name()))
@@ -1608,7 +2573,7 @@ func (c *Connection) handleQueryDescription(payload []byte) error {
 
 	svc := c.getService(handle)
 	if svc == nil {
-		return c.writePacket(RplyBadReq, nil)
+		return c.badHandle(handle)
 	}
 
 	// Reuse the length-prefixed string encoding from EncodeServiceName.
@@ -1622,7 +2587,7 @@ func (c *Connection) handleQueryMetadata(payload []byte) error {
 	}
 	svc := c.getService(handle)
 	if svc == nil {
-		return c.writePacket(RplyBadReq, nil)
+		return c.badHandle(handle)
 	}
 	rec := svc.Record()
 	return c.writePacket(RplyMetadata, EncodeMetadata(rec.Author(), rec.Version(), rec.Usage()))
@@ -1669,7 +2634,7 @@ func (c *Connection) handleQueryBundleMembers(payload []byte) error {
 	}
 	svc := c.getService(handle)
 	if svc == nil {
-		return c.writePacket(RplyBadReq, nil)
+		return c.badHandle(handle)
 	}
 	return c.writePacket(RplyBundleMembers, EncodeStringList(svc.Record().BundleMembers()))
 }
@@ -1716,6 +2681,33 @@ func (c *Connection) handleQueryServiceDscDir() error {
 	return c.writePacket(RplyServiceDscDir, buf)
 }
 
+// handleSetServiceDirs replaces, extends or shrinks the loader's
+// service directories. A refused change is answered with RplyNAK and
+// the reason as text; the directories are then unchanged.
+func (c *Connection) handleSetServiceDirs(payload []byte) error {
+	op, dirs, err := DecodeSetServiceDirs(payload)
+	if err != nil {
+		return c.writePacket(RplyBadReq, nil)
+	}
+	ed, ok := c.server.services.GetLoader().(config.ServiceDirEditor)
+	if !ok {
+		return c.writePacket(RplyNAK, []byte("service directories cannot be changed"))
+	}
+	switch op {
+	case ServiceDirsAdd:
+		err = ed.AddServiceDir(dirs[0])
+	case ServiceDirsRemove:
+		err = ed.RemoveServiceDir(dirs[0])
+	default:
+		err = ed.SetServiceDirs(dirs)
+	}
+	if err != nil {
+		return c.writePacket(RplyNAK, []byte(err.Error()))
+	}
+	c.server.logger.Info("Service directories now %v", c.server.services.GetLoader().ServiceDirs())
+	return c.writePacket(RplyACK, nil)
+}
+
 func (c *Connection) handleQueryDependents(payload []byte) error {
 	handle, err := DecodeHandle(payload)
 	if err != nil {
@@ -1724,7 +2716,7 @@ func (c *Connection) handleQueryDependents(payload []byte) error {
 
 	svc := c.getService(handle)
 	if svc == nil {
-		return c.writePacket(RplyBadReq, nil)
+		return c.badHandle(handle)
 	}
 
 	dependents := svc.Dependents()
@@ -1749,7 +2741,7 @@ func (c *Connection) handleQueryDependencies(payload []byte) error {
 
 	svc := c.getService(handle)
 	if svc == nil {
-		return c.writePacket(RplyBadReq, nil)
+		return c.badHandle(handle)
 	}
 
 	deps := svc.Record().Dependencies()
@@ -1766,6 +2758,22 @@ func (c *Connection) handleQueryDependencies(payload []byte) error {
 	return c.writePacket(RplyDependencies, buf)
 }
 
+// handleGetDependencyInfo replies with a service's dependency edges by
+// name, including the acquisition and wait flags and each target's
+// state, followed by its dependents.
+func (c *Connection) handleGetDependencyInfo(payload []byte) error {
+	handle, err := DecodeHandle(payload)
+	if err != nil {
+		return c.writePacket(RplyBadReq, nil)
+	}
+	svc := c.getService(handle)
+	if svc == nil {
+		return c.badHandle(handle)
+	}
+	deps, dependents := c.server.services.DependencyInfo(svc)
+	return c.writePacket(RplyDependencyInfo, EncodeDependencyInfo(deps, dependents))
+}
+
 func (c *Connection) handleQueryLoadMech() error {
 	loader := c.server.services.GetLoader()
 	cwd, _ := os.Getwd()
@@ -1806,7 +2814,7 @@ func (c *Connection) handleServiceStatus6(payload []byte) error {
 
 	svc := c.getService(handle)
 	if svc == nil {
-		return c.writePacket(RplyBadReq, nil)
+		return c.badHandle(handle)
 	}
 
 	status := EncodeServiceStatus6(svc)
@@ -1835,7 +2843,7 @@ func (c *Connection) handleRunAction(payload []byte) error {
 
 	svc := c.getService(handle)
 	if svc == nil {
-		return c.writePacket(RplyBadReq, nil)
+		return c.badHandle(handle)
 	}
 
 	rec := svc.Record()
@@ -1846,7 +2854,7 @@ func (c *Connection) handleRunAction(payload []byte) error {
 
 	// Execute the action command synchronously and capture output.
 	execCmd := exec.Command(cmd[0], cmd[1:]...)
-	output, execErr := execCmd.CombinedOutput()
+	output, execErr := process.CombinedOutput(execCmd)
 	if execErr != nil {
 		// Return NAK with the error message + any partial output
 		msg := fmt.Sprintf("action '%s' failed: %v\n%s", actionName, execErr, string(output))
@@ -1867,7 +2875,7 @@ func (c *Connection) handleListActions(payload []byte) error {
 
 	svc := c.getService(handle)
 	if svc == nil {
-		return c.writePacket(RplyBadReq, nil)
+		return c.badHandle(handle)
 	}
 
 	actions := svc.Record().ListExtraActions()
@@ -1,11 +1,14 @@
 package control
 
 import (
+	"bytes"
 	"context"
 	"encoding/binary"
+	"fmt"
 	"net"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
@@ -23,6 +26,14 @@ func (l *testLogger) Error(format string, args ...interface{}) {}
 func (l *testLogger) Info(format string, args ...interface{})  {}
 
 func setupTestServer(t *testing.T) (*Server, string) {
+	t.Helper()
+	return setupTestServerWith(t, nil)
+}
+
+// setupTestServerWith is setupTestServer with configure applied to the
+// server before it starts accepting connections, for settings the
+// accept loop reads.
+func setupTestServerWith(t *testing.T, configure func(*Server)) (*Server, string) {
 	t.Helper()
 	dir := t.TempDir()
 	sockPath := filepath.Join(dir, "test.socket")
@@ -30,6 +41,9 @@ func setupTestServer(t *testing.T) (*Server, string) {
 	ss := service.NewServiceSet(&testLogger{})
 	logger := logging.New(logging.LevelError)
 	server := NewServer(ss, sockPath, logger)
+	if configure != nil {
+		configure(server)
+	}
 
 	ctx := context.Background()
 	if err := server.Start(ctx); err != nil {
@@ -41,31 +55,31 @@ func setupTestServer(t *testing.T) (*Server, string) {
 
 func connectTest(t *testing.T, sockPath string) net.Conn {
 	t.Helper()
-	// Wait briefly for server to be ready
-	var conn net.Conn
-	var err error
-	for i := 0; i < 10; i++ {
-		conn, err = net.Dial("unix", sockPath)
-		if err == nil {
-			return conn
-		}
-		time.Sleep(10 * time.Millisecond)
+	conn, err := net.Dial("unix", sockPath)
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
 	}
-	t.Fatalf("Failed to connect: %v", err)
-	return nil
+	return conn
 }
 
 // readReply reads packets from conn, skipping any unsolicited info packets
-// (InfoServiceEvent, InfoEnvEvent), and returns the first reply packet.
+// (InfoServiceEvent, InfoEnvEvent, ...), and returns the first reply packet.
 func readReply(t *testing.T, conn net.Conn) (uint8, []byte) {
+	t.Helper()
+	return readReplyWith(t, conn, false)
+}
+
+// readReplyWith is readReply for a connection that may have negotiated
+// compression (see negotiateZlib).
+func readReplyWith(t *testing.T, conn net.Conn, compressed bool) (uint8, []byte) {
 	t.Helper()
 	for {
-		rply, payload, err := ReadPacket(conn)
+		rply, payload, err := ReadPacketWith(conn, compressed)
 		if err != nil {
 			t.Fatalf("Read error: %v", err)
 		}
-		// Skip unsolicited info packets
-		if rply >= 100 {
+		// Skip unsolicited info packets; replies go past 100 too.
+		if rply >= InfoServiceEvent && rply <= InfoGlobalEvent {
 			continue
 		}
 		return rply, payload
@@ -165,6 +179,30 @@ func TestFindServiceExists(t *testing.T) {
 	}
 }
 
+func TestWaitReady(t *testing.T) {
+	sockPath := filepath.Join(t.TempDir(), "test.socket")
+	server := NewServer(service.NewServiceSet(&testLogger{}), sockPath, logging.New(logging.LevelError))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := server.WaitReady(ctx); err != context.DeadlineExceeded {
+		t.Fatalf("WaitReady before Start = %v, want DeadlineExceeded", err)
+	}
+
+	errCh := make(chan error, 1)
+	go func() { errCh <- server.Start(context.Background()) }()
+	if err := server.WaitReady(context.Background()); err != nil {
+		t.Fatalf("WaitReady: %v", err)
+	}
+	if err := <-errCh; err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	defer server.Stop()
+
+	conn := connectTest(t, sockPath)
+	conn.Close()
+}
+
 func TestStartStopService(t *testing.T) {
 	server, sockPath := setupTestServer(t)
 	defer server.Stop()
@@ -501,6 +539,263 @@ func TestServiceStatus(t *testing.T) {
 	if status.SvcType != service.TypeInternal {
 		t.Fatalf("Expected TypeInternal, got %s", status.SvcType)
 	}
+	if !status.HasFailureStats || status.FailureStats.TotalStarts != 1 {
+		t.Fatalf("Expected failure stats with 1 start, got %+v", status)
+	}
+}
+
+func TestServiceStatusFailureStats(t *testing.T) {
+	server, sockPath := setupTestServer(t)
+	defer server.Stop()
+
+	// A process service with no command fails every start.
+	svc := service.NewProcessService(server.services, "flaky-svc")
+	svc.Record().SetConsecutiveFailureThreshold(2)
+	server.services.AddService(svc)
+	server.services.StartService(svc)
+	server.services.StartService(svc)
+
+	conn := connectTest(t, sockPath)
+	defer conn.Close()
+
+	if err := WritePacket(conn, CmdLoadService, EncodeServiceName("flaky-svc")); err != nil {
+		t.Fatalf("Write error: %v", err)
+	}
+	_, payload, err := ReadPacket(conn)
+	if err != nil {
+		t.Fatalf("Read error: %v", err)
+	}
+	handle := binary.LittleEndian.Uint32(payload[1:5])
+
+	if err := WritePacket(conn, CmdServiceStatus, EncodeHandle(handle)); err != nil {
+		t.Fatalf("Write error: %v", err)
+	}
+	_, payload, err = ReadPacket(conn)
+	if err != nil {
+		t.Fatalf("Read error: %v", err)
+	}
+	status, err := DecodeServiceStatus(payload)
+	if err != nil {
+		t.Fatalf("Decode error: %v", err)
+	}
+	fs := status.FailureStats
+	if fs.TotalStarts != 2 || fs.TotalFailures != 2 || fs.ConsecutiveFailures != 2 {
+		t.Errorf("unexpected counters: %+v", fs)
+	}
+	if fs.LastFailureTime.IsZero() {
+		t.Error("LastFailureTime not set")
+	}
+	if !status.RestartSuppressed {
+		t.Error("expected auto-restart to be suppressed after 2 failures")
+	}
+}
+
+func TestSetServiceStopReason(t *testing.T) {
+	server, sockPath := setupTestServer(t)
+	defer server.Stop()
+
+	svc := service.NewInternalService(server.services, "mounted")
+	server.services.AddService(svc)
+	server.services.StartService(svc)
+
+	conn := connectTest(t, sockPath)
+	defer conn.Close()
+	handle := loadHandle(t, conn, "mounted")
+
+	WritePacket(conn, CmdSetServiceStopReason, EncodeSetStopReason(handle, service.ReasonFailed, "nope"))
+	if rply, _ := readReply(t, conn); rply != RplyNAK {
+		t.Errorf("built-in reason: expected NAK, got %d", rply)
+	}
+	WritePacket(conn, CmdSetServiceStopReason, EncodeSetStopReason(handle, service.ReasonCustom, "filesystem not mounted"))
+	if rply, _ := readReply(t, conn); rply != RplyACK {
+		t.Fatalf("custom reason: expected ACK, got %d", rply)
+	}
+	server.services.StopService(svc)
+
+	// Stopping a loaded service sends info packets; readReply skips them.
+	WritePacket(conn, CmdServiceStatus, EncodeHandle(handle))
+	rply, payload := readReply(t, conn)
+	if rply != RplyServiceStatus {
+		t.Fatalf("Expected ServiceStatus, got %d", rply)
+	}
+	status, err := DecodeServiceStatus(payload)
+	if err != nil {
+		t.Fatalf("Decode error: %v", err)
+	}
+	if !status.HasStopReason || status.StopReason != service.ReasonCustom ||
+		status.CustomStopReason != "filesystem not mounted" {
+		t.Errorf("stop reason = %v %q (present %v)", status.StopReason, status.CustomStopReason, status.HasStopReason)
+	}
+
+	// Older 12-byte replies still decode, without the trailer.
+	if status, err := DecodeServiceStatus(payload[:12]); err != nil || status.HasStopReason {
+		t.Errorf("12-byte status: %+v, %v", status, err)
+	}
+}
+
+func TestServiceStatusLastError(t *testing.T) {
+	server, sockPath := setupTestServer(t)
+	defer server.Stop()
+
+	svc := service.NewInternalService(server.services, "migrate")
+	server.services.AddService(svc)
+	svc.Record().SetLastErrorMessage("Error: connection refused")
+
+	conn := connectTest(t, sockPath)
+	defer conn.Close()
+	handle := loadHandle(t, conn, "migrate")
+
+	WritePacket(conn, CmdServiceStatus, EncodeHandle(handle))
+	rply, payload := readReply(t, conn)
+	if rply != RplyServiceStatus {
+		t.Fatalf("Expected ServiceStatus, got %d", rply)
+	}
+	status, err := DecodeServiceStatus(payload)
+	if err != nil {
+		t.Fatalf("Decode error: %v", err)
+	}
+	if status.LastErrorMessage != "Error: connection refused" {
+		t.Errorf("LastErrorMessage = %q", status.LastErrorMessage)
+	}
+
+	// A reply from before the last-error trailer still decodes.
+	status, err = DecodeServiceStatus(payload[:len(payload)-len(EncodeLastError(svc))-len(EncodeStartedTime(svc))])
+	if err != nil || !status.HasEffectiveRestart || status.LastErrorMessage != "" {
+		t.Errorf("status without trailer: %+v, %v", status, err)
+	}
+}
+
+func TestServiceStatusStartedTime(t *testing.T) {
+	server, sockPath := setupTestServer(t)
+	defer server.Stop()
+
+	svc := service.NewInternalService(server.services, "web")
+	server.services.AddService(svc)
+
+	conn := connectTest(t, sockPath)
+	defer conn.Close()
+	handle := loadHandle(t, conn, "web")
+
+	WritePacket(conn, CmdServiceStatus, EncodeHandle(handle))
+	_, payload := readReply(t, conn)
+	status, err := DecodeServiceStatus(payload)
+	if err != nil || !status.StartedTime.IsZero() {
+		t.Fatalf("never started: StartedTime = %v, %v", status.StartedTime, err)
+	}
+
+	before := time.Now()
+	server.services.StartService(svc)
+	WritePacket(conn, CmdServiceStatus, EncodeHandle(handle))
+	_, payload = readReply(t, conn)
+	if status, err = DecodeServiceStatus(payload); err != nil {
+		t.Fatalf("Decode error: %v", err)
+	}
+	if status.State != service.StateStarted || status.StartedTime.Before(before) {
+		t.Errorf("started: state %v, StartedTime %v (before %v)", status.State, status.StartedTime, before)
+	}
+}
+
+func TestSetPriority(t *testing.T) {
+	server, sockPath := setupTestServer(t)
+	defer server.Stop()
+
+	svc := service.NewInternalService(server.services, "syslog")
+	server.services.AddService(svc)
+
+	conn := connectTest(t, sockPath)
+	defer conn.Close()
+	handle := loadHandle(t, conn, "syslog")
+
+	WritePacket(conn, CmdSetPriority, EncodeSetPriority(handle, -3))
+	if rply, _ := readReply(t, conn); rply != RplyACK {
+		t.Fatalf("expected ACK, got %d", rply)
+	}
+	if p := svc.Record().Priority(); p != -3 {
+		t.Errorf("priority = %d, want -3", p)
+	}
+
+	WritePacket(conn, CmdSetPriority, EncodeHandle(handle))
+	if rply, _ := readReply(t, conn); rply != RplyBadReq {
+		t.Errorf("short payload: expected BadReq, got %d", rply)
+	}
+}
+
+func TestSetRestartEnabled(t *testing.T) {
+	server, sockPath := setupTestServer(t)
+	defer server.Stop()
+
+	svc := service.NewInternalService(server.services, "crashy")
+	server.services.AddService(svc)
+
+	conn := connectTest(t, sockPath)
+	defer conn.Close()
+
+	if err := WritePacket(conn, CmdLoadService, EncodeServiceName("crashy")); err != nil {
+		t.Fatalf("Write error: %v", err)
+	}
+	_, payload, err := ReadPacket(conn)
+	if err != nil {
+		t.Fatalf("Read error: %v", err)
+	}
+	handle := binary.LittleEndian.Uint32(payload[1:5])
+
+	status := func() ServiceStatusInfo {
+		t.Helper()
+		if err := WritePacket(conn, CmdServiceStatus, EncodeHandle(handle)); err != nil {
+			t.Fatalf("Write error: %v", err)
+		}
+		_, payload, err := ReadPacket(conn)
+		if err != nil {
+			t.Fatalf("Read error: %v", err)
+		}
+		st, err := DecodeServiceStatus(payload)
+		if err != nil {
+			t.Fatalf("Decode error: %v", err)
+		}
+		return st
+	}
+	if st := status(); st.RestartOverride != nil {
+		t.Fatalf("expected no override, got %v", *st.RestartOverride)
+	} else if !st.HasEffectiveRestart || st.EffectiveRestart != service.RestartNever ||
+		st.RestartSource != service.RestartSourceConfigured {
+		t.Errorf("effective restart = %v (%v), want never (configured)", st.EffectiveRestart, st.RestartSource)
+	}
+
+	for _, enabled := range []bool{false, true} {
+		req := append(EncodeHandle(handle), 0)
+		if enabled {
+			req[4] = 1
+		}
+		if err := WritePacket(conn, CmdSetRestartEnabled, req); err != nil {
+			t.Fatalf("Write error: %v", err)
+		}
+		rply, _, err := ReadPacket(conn)
+		if err != nil {
+			t.Fatalf("Read error: %v", err)
+		}
+		if rply != RplyACK {
+			t.Fatalf("expected ACK, got %d", rply)
+		}
+		st := status()
+		if o := st.RestartOverride; o == nil || *o != enabled {
+			t.Errorf("override = %v, want %v", o, enabled)
+		}
+		want := service.RestartNever
+		if enabled {
+			want = service.RestartAlways
+		}
+		if st.EffectiveRestart != want || st.RestartSource != service.RestartSourceOverride {
+			t.Errorf("effective restart = %v (%v), want %v (runtime override)", st.EffectiveRestart, st.RestartSource, want)
+		}
+	}
+
+	// Short payload
+	if err := WritePacket(conn, CmdSetRestartEnabled, EncodeHandle(handle)); err != nil {
+		t.Fatalf("Write error: %v", err)
+	}
+	if rply, _, _ := ReadPacket(conn); rply != RplyBadReq {
+		t.Errorf("expected BadReq for short payload, got %d", rply)
+	}
 }
 
 func TestShutdown(t *testing.T) {
@@ -578,6 +873,57 @@ func TestSetTrigger(t *testing.T) {
 	}
 }
 
+func TestSetNamedTrigger(t *testing.T) {
+	server, sockPath := setupTestServer(t)
+	defer server.Stop()
+
+	svc := service.NewTriggeredService(server.services, "app")
+	svc.SetTriggerNames([]string{"network", "database"}, true)
+	server.services.AddService(svc)
+	server.services.StartService(svc)
+
+	conn := connectTest(t, sockPath)
+	defer conn.Close()
+	handle := loadHandle(t, conn, "app")
+
+	WritePacket(conn, CmdSetNamedTrigger, EncodeSetNamedTrigger(handle, "disk", true))
+	if rply, _ := readReply(t, conn); rply != RplyNAK {
+		t.Errorf("undeclared trigger: expected NAK, got %d", rply)
+	}
+	WritePacket(conn, CmdSetNamedTrigger, EncodeSetNamedTrigger(handle, "network", true))
+	if rply, _ := readReply(t, conn); rply != RplyACK {
+		t.Fatalf("expected ACK, got %d", rply)
+	}
+
+	WritePacket(conn, CmdQueryTriggers, EncodeHandle(handle))
+	rply, payload, err := ReadPacket(conn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rply != RplyTriggers {
+		t.Fatalf("expected RplyTriggers, got %d", rply)
+	}
+	ti, err := DecodeTriggers(payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []service.NamedTrigger{{Name: "database"}, {Name: "network", Set: true}}
+	if !ti.All || ti.Triggered || len(ti.Named) != 2 || ti.Named[0] != want[0] || ti.Named[1] != want[1] {
+		t.Errorf("triggers = %+v, want all of %+v", ti, want)
+	}
+	if svc.State() != service.StateStarting {
+		t.Errorf("expected STARTING with one of two triggers, got %s", svc.State())
+	}
+
+	WritePacket(conn, CmdSetNamedTrigger, EncodeSetNamedTrigger(handle, "database", true))
+	if rply, _ := readReply(t, conn); rply != RplyACK {
+		t.Fatalf("expected ACK, got %d", rply)
+	}
+	if svc.State() != service.StateStarted {
+		t.Errorf("expected STARTED with both triggers, got %s", svc.State())
+	}
+}
+
 func TestUntrigger(t *testing.T) {
 	server, sockPath := setupTestServer(t)
 	defer server.Stop()
@@ -1300,23 +1646,25 @@ func TestDisableService(t *testing.T) {
 }
 
 // TestEnablePersistsViaSymlink verifies that an online enable creates a
-// waits-for.d/<target> symlink in the source service's load directory,
-// matching dinit's behaviour and allowing the enable to survive a
-// daemon restart.
+// <target> symlink in the source service's declared waits-for.d
+// directory, matching dinit's behaviour and allowing the enable to
+// survive a daemon restart.
 func TestEnablePersistsViaSymlink(t *testing.T) {
 	server, sockPath := setupTestServer(t)
 	defer server.Stop()
 
-	// Simulate a service that was loaded from disk by giving "boot" a
-	// service-dir. The persistence helper keys off ServiceDir().
-	bootDir := t.TempDir()
+	// Simulate services loaded from disk: "boot" declares
+	// waits-for.d = boot.d, as the loader would record it.
+	svcDir := t.TempDir()
 	boot := service.NewInternalService(server.services, "boot")
-	boot.Record().SetServiceDir(bootDir)
+	boot.Record().SetServiceDir(svcDir)
+	boot.Record().SetWaitsForDirs([]string{filepath.Join(svcDir, "boot.d")})
 	server.services.AddService(boot)
 	server.services.SetBootServiceName("boot")
 	server.services.StartService(boot)
 
 	target := service.NewInternalService(server.services, "target-svc")
+	target.Record().SetServiceDir(svcDir)
 	server.services.AddService(target)
 
 	conn := connectTest(t, sockPath)
@@ -1328,7 +1676,7 @@ func TestEnablePersistsViaSymlink(t *testing.T) {
 		t.Fatalf("enable: expected ACK, got %d", rply)
 	}
 
-	link := filepath.Join(bootDir, "waits-for.d", "target-svc")
+	link := filepath.Join(svcDir, "boot.d", "target-svc")
 	dest, err := os.Readlink(link)
 	if err != nil {
 		t.Fatalf("expected symlink at %s: %v", link, err)
@@ -1421,6 +1769,66 @@ func findHandle(t *testing.T, conn net.Conn, name string) uint32 {
 
 // --- PREACK tests ---
 
+// A waiting release only gets a pre-ack when the service will stop, so
+// the client does not wait for a STOPPED event that never comes.
+func TestReleasePreACK(t *testing.T) {
+	server, sockPath := setupTestServer(t)
+	defer server.Stop()
+
+	parent := service.NewInternalService(server.services, "parent")
+	child := service.NewInternalService(server.services, "child")
+	server.services.AddService(parent)
+	server.services.AddService(child)
+	parent.Record().AddDep(child, service.DepRegular)
+	server.services.StartService(parent)
+	server.services.StartService(child)
+
+	conn := connectTest(t, sockPath)
+	defer conn.Close()
+
+	release := func(name string) uint8 {
+		payload := make([]byte, 5)
+		binary.LittleEndian.PutUint32(payload, loadHandle(t, conn, name))
+		payload[4] = 0x80
+		WritePacket(conn, CmdReleaseService, payload)
+		rply, _ := readReply(t, conn)
+		return rply
+	}
+
+	// child is still required by parent: plain ACK.
+	if rply := release("child"); rply != RplyACK {
+		t.Fatalf("release of required service: expected ACK, got %d", rply)
+	}
+	if child.State() != service.StateStarted {
+		t.Fatalf("child should remain STARTED, got %s", child.State())
+	}
+
+	// parent goes down: PREACK, then ACK.
+	if rply := release("parent"); rply != RplyPreACK {
+		t.Fatalf("release that stops: expected PREACK, got %d", rply)
+	}
+	if rply, _ := readReply(t, conn); rply != RplyACK {
+		t.Fatalf("expected ACK after PREACK, got %d", rply)
+	}
+}
+
+func TestWakePinnedStopped(t *testing.T) {
+	server, sockPath := setupTestServer(t)
+	defer server.Stop()
+
+	svc := service.NewInternalService(server.services, "wake-pinned")
+	server.services.AddService(svc)
+	svc.PinStop()
+
+	conn := connectTest(t, sockPath)
+	defer conn.Close()
+
+	WritePacket(conn, CmdWakeService, EncodeHandle(loadHandle(t, conn, "wake-pinned")))
+	if rply, _ := readReply(t, conn); rply != RplyPinnedStopped {
+		t.Fatalf("expected PinnedStopped (%d), got %d", RplyPinnedStopped, rply)
+	}
+}
+
 func TestPreACKOnStop(t *testing.T) {
 	server, sockPath := setupTestServer(t)
 	defer server.Stop()
@@ -1553,6 +1961,201 @@ func TestPinnedStartedReply(t *testing.T) {
 	}
 }
 
+func TestPinService(t *testing.T) {
+	server, sockPath := setupTestServer(t)
+	defer server.Stop()
+
+	svc := service.NewInternalService(server.services, "pin-svc")
+	server.services.AddService(svc)
+	server.services.StartService(svc)
+
+	conn := connectTest(t, sockPath)
+	defer conn.Close()
+	handle := findHandle(t, conn, "pin-svc")
+
+	listPin := func() uint8 {
+		t.Helper()
+		WritePacket(conn, CmdListServices, nil)
+		var pin uint8
+		for {
+			rply, payload := readReply(t, conn)
+			if rply == RplyListDone {
+				return pin
+			}
+			entry, _, err := DecodeSvcInfo(payload)
+			if err != nil {
+				t.Fatalf("DecodeSvcInfo: %v", err)
+			}
+			if entry.Name == "pin-svc" {
+				pin = entry.Pin
+			}
+		}
+	}
+
+	// An already started service can be pinned in place.
+	WritePacket(conn, CmdPinService, EncodePinService(handle, PinStarted))
+	if rply, _ := readReply(t, conn); rply != RplyACK {
+		t.Fatalf("pin-start: got reply %d, want ACK", rply)
+	}
+	if !svc.Record().IsStartPinned() || svc.State() != service.StateStarted {
+		t.Fatalf("after pin-start: pinned %v, state %v", svc.Record().IsStartPinned(), svc.State())
+	}
+	if pin := listPin(); pin != PinStarted {
+		t.Errorf("list pin = %d, want PinStarted", pin)
+	}
+
+	WritePacket(conn, CmdPinService, EncodePinService(handle, PinStopped))
+	if rply, _ := readReply(t, conn); rply != RplyPinnedStarted {
+		t.Fatalf("pin-stop while pinned started: got reply %d, want PinnedStarted", rply)
+	}
+
+	WritePacket(conn, CmdUnpinService, EncodeHandle(handle))
+	readReply(t, conn)
+	WritePacket(conn, CmdPinService, EncodePinService(handle, PinStopped))
+	if rply, _ := readReply(t, conn); rply != RplyACK {
+		t.Fatalf("pin-stop: got reply %d, want ACK", rply)
+	}
+	if !svc.Record().IsStopPinned() || svc.State() != service.StateStopped {
+		t.Fatalf("after pin-stop: pinned %v, state %v", svc.Record().IsStopPinned(), svc.State())
+	}
+	if pin := listPin(); pin != PinStopped {
+		t.Errorf("list pin = %d, want PinStopped", pin)
+	}
+
+	WritePacket(conn, CmdPinService, EncodePinService(handle, 7))
+	if rply, _ := readReply(t, conn); rply != RplyBadReq {
+		t.Errorf("bad pin value: got reply %d, want BadReq", rply)
+	}
+}
+
+func TestSwitchTarget(t *testing.T) {
+	server, sockPath := setupTestServer(t)
+	defer server.Stop()
+
+	multi := service.NewInternalService(server.services, "multi-user")
+	rescue := service.NewInternalService(server.services, "rescue")
+	shared := service.NewInternalService(server.services, "shared")
+	for _, svc := range []service.Service{multi, rescue, shared} {
+		server.services.AddService(svc)
+	}
+	multi.Record().AddDep(shared, service.DepRegular)
+	rescue.Record().AddDep(shared, service.DepRegular)
+	server.services.StartService(multi)
+
+	conn := connectTest(t, sockPath)
+	defer conn.Close()
+
+	WritePacket(conn, CmdSwitchTarget, EncodeHandle(loadHandle(t, conn, "rescue")))
+	rply, payload := readReply(t, conn)
+	if rply != RplyTargetSwitched {
+		t.Fatalf("switch-to: got reply %d, want TargetSwitched", rply)
+	}
+	target, released, stopping, err := DecodeTargetSwitched(payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if target != "rescue" || len(released) != 1 || released[0] != "multi-user" ||
+		len(stopping) != 1 || stopping[0] != "multi-user" {
+		t.Errorf("reply = %q %v %v; want rescue [multi-user] [multi-user]", target, released, stopping)
+	}
+	if rescue.State() != service.StateStarted || shared.State() != service.StateStarted ||
+		multi.State() != service.StateStopped {
+		t.Errorf("states: rescue %v, shared %v, multi-user %v", rescue.State(), shared.State(), multi.State())
+	}
+
+	multi.PinStop()
+	WritePacket(conn, CmdSwitchTarget, EncodeHandle(loadHandle(t, conn, "multi-user")))
+	if rply, _ := readReply(t, conn); rply != RplyPinnedStopped {
+		t.Errorf("switch to a pinned-stopped target: got reply %d, want PinnedStopped", rply)
+	}
+
+	// The reply reaches a client that negotiated compression intact.
+	multi.Unpin()
+	hMulti := loadHandle(t, conn, "multi-user")
+	negotiateZlib(t, conn)
+	WritePacket(conn, CmdSwitchTarget, EncodeHandle(hMulti))
+	rply, payload = readReplyWith(t, conn, true)
+	if rply != RplyTargetSwitched {
+		t.Fatalf("switch-to with zlib: got reply %d, want TargetSwitched", rply)
+	}
+	if target, released, _, err := DecodeTargetSwitched(payload); err != nil || target != "multi-user" ||
+		len(released) != 1 || released[0] != "rescue" {
+		t.Errorf("zlib reply = %q %v, %v; want multi-user [rescue]", target, released, err)
+	}
+}
+
+func TestJobs(t *testing.T) {
+	server, sockPath := setupTestServer(t)
+	defer server.Stop()
+
+	net0 := service.NewTriggeredService(server.services, "net0")
+	server.services.AddService(net0)
+
+	conn := connectTest(t, sockPath)
+	defer conn.Close()
+
+	WritePacket(conn, CmdStartService, EncodeHandle(loadHandle(t, conn, "net0")))
+	rply, payload := readReply(t, conn)
+	if rply != RplyACK {
+		t.Fatalf("start: got reply %d, want ACK", rply)
+	}
+	id := DecodeJobACK(payload)
+	if id == 0 {
+		t.Fatal("start ACK carries no job ID")
+	}
+
+	WritePacket(conn, CmdListJobs, nil)
+	var jobs []service.Job
+	for {
+		rply, payload := readReply(t, conn)
+		if rply == RplyListDone {
+			break
+		}
+		j, err := DecodeJobInfo(payload)
+		if rply != RplyJobInfo || err != nil {
+			t.Fatalf("list-jobs: reply %d, %v", rply, err)
+		}
+		jobs = append(jobs, j)
+	}
+	if len(jobs) != 1 || jobs[0].ID != id || jobs[0].Service != "net0" ||
+		jobs[0].Kind != service.JobStart || jobs[0].State != service.JobRunning {
+		t.Fatalf("jobs = %+v, want one running start of net0", jobs)
+	}
+
+	WritePacket(conn, CmdCancelJob, EncodeHandle(id))
+	if rply, payload := readReply(t, conn); rply != RplyACK {
+		t.Fatalf("cancel: got reply %d (%s), want ACK", rply, payload)
+	}
+	if net0.State() != service.StateStopped {
+		t.Errorf("state %v after cancel, want STOPPED", net0.State())
+	}
+
+	WritePacket(conn, CmdQueryJob, EncodeHandle(id))
+	rply, payload = readReply(t, conn)
+	if rply != RplyJobInfo {
+		t.Fatalf("query-job: got reply %d, want JobInfo", rply)
+	}
+	if j, _ := DecodeJobInfo(payload); j.State != service.JobCancelled || j.Finished.IsZero() {
+		t.Errorf("job = %+v, want cancelled", j)
+	}
+
+	WritePacket(conn, CmdCancelJob, EncodeHandle(id))
+	if rply, _ := readReply(t, conn); rply != RplyNAK {
+		t.Errorf("second cancel: got reply %d, want NAK", rply)
+	}
+
+	// Job info reaches a client that negotiated compression intact.
+	negotiateZlib(t, conn)
+	WritePacket(conn, CmdQueryJob, EncodeHandle(id))
+	rply, payload = readReplyWith(t, conn, true)
+	if rply != RplyJobInfo {
+		t.Fatalf("query-job with zlib: got reply %d, want JobInfo", rply)
+	}
+	if j, err := DecodeJobInfo(payload); err != nil || j.ID != id || j.Service != "net0" {
+		t.Errorf("zlib job = %+v, %v", j, err)
+	}
+}
+
 func TestQueryDependencies(t *testing.T) {
 	server, sockPath := setupTestServer(t)
 	defer server.Stop()
@@ -1665,3 +2268,331 @@ func TestQueryDependenciesEmpty(t *testing.T) {
 		t.Errorf("expected 0 dependencies, got %d", count)
 	}
 }
+
+func TestVerifyInternal(t *testing.T) {
+	server, sockPath := setupTestServer(t)
+	defer server.Stop()
+
+	db := service.NewInternalService(server.services, "db")
+	app := service.NewInternalService(server.services, "app")
+	app.Record().AddDep(db, service.DepRegular)
+	server.services.AddService(db)
+	server.services.AddService(app)
+	server.services.StartService(app)
+
+	conn := connectTest(t, sockPath)
+	defer conn.Close()
+
+	verify := func() []service.RefCountError {
+		t.Helper()
+		if err := WritePacket(conn, CmdVerifyInternal, nil); err != nil {
+			t.Fatalf("Write error: %v", err)
+		}
+		rply, payload, err := ReadPacket(conn)
+		if err != nil {
+			t.Fatalf("Read error: %v", err)
+		}
+		if rply != RplyRefCountErrors {
+			t.Fatalf("expected RplyRefCountErrors, got %d", rply)
+		}
+		errs, err := DecodeRefCountErrors(payload)
+		if err != nil {
+			t.Fatalf("Decode error: %v", err)
+		}
+		return errs
+	}
+	if errs := verify(); len(errs) != 0 {
+		t.Fatalf("expected consistent counts, got %v", errs)
+	}
+
+	// Simulate a lost Release.
+	db.Record().Require()
+	errs := verify()
+	want := service.RefCountError{Service: "db", Field: "requiredBy", Expected: 1, Actual: 2}
+	if len(errs) != 1 || errs[0] != want {
+		t.Errorf("got %+v, want [%+v]", errs, want)
+	}
+}
+
+func TestKillService(t *testing.T) {
+	server, sockPath := setupTestServer(t)
+	defer server.Stop()
+
+	svc := service.NewInternalService(server.services, "kill-svc")
+	svc.Record().SetAutoRestart(service.RestartAlways)
+	server.services.AddService(svc)
+	server.services.StartService(svc)
+
+	conn := connectTest(t, sockPath)
+	defer conn.Close()
+
+	WritePacket(conn, CmdLoadService, EncodeServiceName("kill-svc"))
+	rply, payload, err := ReadPacket(conn)
+	if err != nil || rply != RplyServiceRecord {
+		t.Fatalf("load: reply %d, err %v", rply, err)
+	}
+	handle := binary.LittleEndian.Uint32(payload[1:5])
+
+	WritePacket(conn, CmdKillService, EncodeHandle(handle))
+	if rply, _ := readReply(t, conn); rply != RplyACK {
+		t.Fatalf("expected ACK, got %d", rply)
+	}
+	if svc.State() != service.StateStopped || svc.TargetState() != service.StateStopped {
+		t.Errorf("after kill: state %v, target %v", svc.State(), svc.TargetState())
+	}
+	if svc.Record().IsMarkedActive() {
+		t.Error("kill left the service marked active")
+	}
+
+	WritePacket(conn, CmdKillService, EncodeHandle(handle))
+	if rply, _ := readReply(t, conn); rply != RplyAlreadySS {
+		t.Errorf("second kill: expected AlreadySS, got %d", rply)
+	}
+}
+
+func TestGetDependencyInfo(t *testing.T) {
+	server, sockPath := setupTestServer(t)
+	defer server.Stop()
+
+	app := service.NewInternalService(server.services, "app")
+	network := service.NewInternalService(server.services, "network")
+	syslog := service.NewInternalService(server.services, "syslog")
+	for _, svc := range []service.Service{app, network, syslog} {
+		server.services.AddService(svc)
+	}
+	app.Record().AddDep(network, service.DepRegular)
+	app.Record().AddDep(syslog, service.DepWaitsFor)
+	server.services.StartService(app)
+
+	conn := connectTest(t, sockPath)
+	defer conn.Close()
+
+	query := func(name string) ([]service.DependencyInfo, []service.DependencyInfo) {
+		t.Helper()
+		WritePacket(conn, CmdGetDependencyInfo, EncodeHandle(loadHandle(t, conn, name)))
+		rply, payload, err := ReadPacket(conn)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if rply != RplyDependencyInfo {
+			t.Fatalf("expected RplyDependencyInfo, got %d", rply)
+		}
+		deps, dependents, err := DecodeDependencyInfo(payload)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return deps, dependents
+	}
+
+	deps, dependents := query("app")
+	want := []service.DependencyInfo{
+		{Name: "network", DepType: service.DepRegular, HoldingAcq: true, State: service.StateStarted},
+		{Name: "syslog", DepType: service.DepWaitsFor, HoldingAcq: true, State: service.StateStarted},
+	}
+	if len(deps) != len(want) || len(dependents) != 0 {
+		t.Fatalf("app: deps %+v, dependents %+v", deps, dependents)
+	}
+	for i := range want {
+		if deps[i] != want[i] {
+			t.Errorf("dependency %d = %+v, want %+v", i, deps[i], want[i])
+		}
+	}
+
+	deps, dependents = query("network")
+	if len(deps) != 0 || len(dependents) != 1 ||
+		dependents[0].Name != "app" || dependents[0].DepType != service.DepRegular {
+		t.Errorf("network: deps %+v, dependents %+v", deps, dependents)
+	}
+}
+
+func TestListAliases(t *testing.T) {
+	server, sockPath := setupTestServer(t)
+	defer server.Stop()
+
+	svc := service.NewInternalService(server.services, "nginx")
+	svc.Record().SetAliases([]string{"webserver", "httpd"})
+	server.services.AddService(svc)
+
+	conn := connectTest(t, sockPath)
+	defer conn.Close()
+
+	// Aliases resolve to the same service, hence the same handle.
+	if h1, h2 := findHandle(t, conn, "nginx"), findHandle(t, conn, "httpd"); h1 != h2 {
+		t.Errorf("alias handle %d differs from service handle %d", h2, h1)
+	}
+
+	if err := WritePacket(conn, CmdListAliases, nil); err != nil {
+		t.Fatal(err)
+	}
+	rply, payload, err := ReadPacket(conn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rply != RplyAliases {
+		t.Fatalf("expected RplyAliases, got %d", rply)
+	}
+	got, err := DecodeAliases(payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := [][2]string{{"httpd", "nginx"}, {"webserver", "nginx"}}
+	if len(got) != len(want) || got[0] != want[0] || got[1] != want[1] {
+		t.Errorf("aliases = %q, want %q", got, want)
+	}
+}
+
+func TestListTimers(t *testing.T) {
+	t.Run("plain", func(t *testing.T) { testListTimers(t, false) })
+	t.Run("zlib", func(t *testing.T) { testListTimers(t, true) })
+}
+
+func testListTimers(t *testing.T, compressed bool) {
+	server, sockPath := setupTestServer(t)
+	defer server.Stop()
+
+	backup := service.NewInternalService(server.services, "backup")
+	timer := service.NewTimerService(server.services, "backup-timer")
+	timer.SetTarget(backup)
+	timer.SetSchedule(time.Hour, 0, nil)
+	server.services.AddService(backup)
+	server.services.AddService(timer)
+	server.services.StartService(timer)
+
+	conn := connectTest(t, sockPath)
+	defer conn.Close()
+	if compressed {
+		negotiateZlib(t, conn)
+	}
+
+	if err := WritePacket(conn, CmdListTimers, nil); err != nil {
+		t.Fatal(err)
+	}
+	rply, payload, err := ReadPacketWith(conn, compressed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rply != RplyTimers {
+		t.Fatalf("expected RplyTimers, got %d", rply)
+	}
+	got, err := DecodeTimers(payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || got[0].Name != "backup-timer" || got[0].Target != "backup" ||
+		got[0].State != service.StateStarted || !got[0].LastFire.IsZero() {
+		t.Fatalf("timers = %+v", got)
+	}
+	if !got[0].NextFire.Equal(timer.NextFire()) {
+		t.Errorf("next fire = %v, want %v", got[0].NextFire, timer.NextFire())
+	}
+}
+
+func TestDumpGraph(t *testing.T) {
+	t.Run("plain", func(t *testing.T) { testDumpGraph(t, false) })
+	t.Run("zlib", func(t *testing.T) { testDumpGraph(t, true) })
+}
+
+func testDumpGraph(t *testing.T, compressed bool) {
+	server, sockPath := setupTestServer(t)
+	defer server.Stop()
+
+	web := service.NewInternalService(server.services, "web")
+	db := service.NewInternalService(server.services, "db")
+	lone := service.NewInternalService(server.services, "lone")
+	web.Record().AddDep(db, service.DepRegular)
+	server.services.AddService(web)
+	server.services.AddService(db)
+	server.services.AddService(lone)
+	server.services.StartService(web)
+
+	conn := connectTest(t, sockPath)
+	defer conn.Close()
+	if compressed {
+		negotiateZlib(t, conn)
+	}
+
+	if err := WritePacket(conn, CmdDumpGraph, nil); err != nil {
+		t.Fatal(err)
+	}
+	var nodes []service.GraphNode
+	var edges []service.GraphEdge
+	for {
+		rply, payload, err := ReadPacketWith(conn, compressed)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if rply == RplyListDone {
+			break
+		}
+		if rply != RplyGraphNode {
+			t.Fatalf("expected RplyGraphNode, got %d", rply)
+		}
+		n, e, err := DecodeGraphNode(payload)
+		if err != nil {
+			t.Fatal(err)
+		}
+		nodes = append(nodes, n)
+		edges = append(edges, e...)
+	}
+	if len(nodes) != 3 || nodes[0].Name != "db" || nodes[1].Name != "lone" || nodes[2].Name != "web" {
+		t.Fatalf("nodes = %+v", nodes)
+	}
+	if nodes[2].State != service.StateStarted || nodes[2].Type != service.TypeInternal {
+		t.Errorf("web = %+v", nodes[2])
+	}
+	want := service.GraphEdge{From: "web", To: "db", Type: service.DepRegular}
+	if len(edges) != 1 || edges[0] != want {
+		t.Errorf("edges = %+v, want [%+v]", edges, want)
+	}
+}
+
+func TestDumpDiagnostics(t *testing.T) {
+	t.Run("plain", func(t *testing.T) { testDumpDiagnostics(t, false) })
+	t.Run("zlib", func(t *testing.T) { testDumpDiagnostics(t, true) })
+}
+
+func testDumpDiagnostics(t *testing.T, compressed bool) {
+	server, sockPath := setupTestServer(t)
+	defer server.Stop()
+
+	// Enough services that the dump needs more than one packet.
+	for i := 0; i < 1500; i++ {
+		server.services.AddService(service.NewInternalService(server.services, fmt.Sprintf("svc-%04d", i)))
+	}
+
+	conn := connectTest(t, sockPath)
+	defer conn.Close()
+	if compressed {
+		negotiateZlib(t, conn)
+	}
+
+	if err := WritePacket(conn, CmdDumpDiagnostics, nil); err != nil {
+		t.Fatal(err)
+	}
+	var text bytes.Buffer
+	chunks := 0
+	for {
+		rply, payload, err := ReadPacketWith(conn, compressed)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if rply == RplyListDone {
+			break
+		}
+		if rply != RplyDiagnostics {
+			t.Fatalf("expected RplyDiagnostics, got %d", rply)
+		}
+		text.Write(payload)
+		chunks++
+	}
+	if chunks < 2 {
+		t.Errorf("expected the dump to span several packets, got %d", chunks)
+	}
+	out := text.String()
+	if !strings.Contains(out, "services (1500):") || !strings.HasSuffix(out, "svc-1499 (internal)\n"+
+		"    state: STOPPED, target: STOPPED\n"+
+		"    requiredBy: 0, startExplicit: false, startFailed: false\n"+
+		"    pins: started=false stopped=false dependent-started=false\n") {
+		t.Errorf("unexpected dump tail:\n%s", out[max(0, len(out)-400):])
+	}
+}
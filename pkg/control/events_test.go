@@ -9,8 +9,9 @@ import (
 	"github.com/sunlightlinux/slinit/pkg/service"
 )
 
-// readInfoPacket reads packets until it gets an info packet (code >= 100),
-// with a timeout to prevent hangs.
+// readInfoPacket reads packets until it gets an info packet, with a
+// timeout to prevent hangs. Some replies are numbered above 100 too, so
+// the info codes are matched by range.
 func readInfoPacket(t *testing.T, conn net.Conn, timeout time.Duration) (uint8, []byte) {
 	t.Helper()
 	conn.SetReadDeadline(time.Now().Add(timeout))
@@ -20,7 +21,7 @@ func readInfoPacket(t *testing.T, conn net.Conn, timeout time.Duration) (uint8,
 		if err != nil {
 			t.Fatalf("Read error waiting for info packet: %v", err)
 		}
-		if rply >= 100 {
+		if rply >= InfoServiceEvent && rply <= InfoGlobalEvent {
 			return rply, payload
 		}
 		// Skip reply packets (shouldn't happen, but be safe)
@@ -293,3 +294,331 @@ func TestNoEnvEventsWithoutSubscription(t *testing.T) {
 		t.Fatal("expected timeout (no event without subscription), but got a packet")
 	}
 }
+
+func TestListRecentEvents(t *testing.T) {
+	server, sockPath := setupTestServer(t)
+	defer server.Stop()
+
+	a := service.NewInternalService(server.services, "svc-a")
+	b := service.NewInternalService(server.services, "svc-b")
+	server.services.AddService(a)
+	server.services.AddService(b)
+	server.services.StartService(a)
+	server.services.StartService(b)
+	server.services.StopService(a)
+
+	conn := connectTest(t, sockPath)
+	defer conn.Close()
+
+	list := func(max uint16) []service.GlobalEvent {
+		t.Helper()
+		req := make([]byte, 2)
+		binary.LittleEndian.PutUint16(req, max)
+		if err := WritePacket(conn, CmdListRecentEvents, req); err != nil {
+			t.Fatal(err)
+		}
+		rply, payload, err := ReadPacket(conn)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if rply != RplyRecentEvents {
+			t.Fatalf("expected RplyRecentEvents, got %d", rply)
+		}
+		events, err := DecodeRecentEvents(payload)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return events
+	}
+
+	events := list(0)
+	if len(events) != 3 {
+		t.Fatalf("got %d events, want 3: %+v", len(events), events)
+	}
+	want := []struct {
+		name  string
+		event service.ServiceEvent
+	}{
+		{"svc-a", service.EventStarted},
+		{"svc-b", service.EventStarted},
+		{"svc-a", service.EventStopped},
+	}
+	for i, w := range want {
+		if events[i].Service != w.name || events[i].Event != w.event {
+			t.Errorf("event %d = %s %s, want %s %s", i, events[i].Service, events[i].Event, w.name, w.event)
+		}
+	}
+
+	if events := list(1); len(events) != 1 || events[0].Event != service.EventStopped {
+		t.Errorf("max=1 should return only the newest event, got %+v", events)
+	}
+}
+
+func TestAnnotate(t *testing.T) {
+	server, sockPath := setupTestServer(t)
+	defer server.Stop()
+
+	svc := service.NewInternalService(server.services, "web")
+	server.services.AddService(svc)
+
+	conn := connectTest(t, sockPath)
+	defer conn.Close()
+	handle := loadHandle(t, conn, "web")
+
+	WritePacket(conn, CmdAnnotate, EncodeAnnotate(handle, "planned maintenance"))
+	if rply, _ := readReply(t, conn); rply != RplyACK {
+		t.Fatalf("annotate: expected ACK, got %d", rply)
+	}
+	WritePacket(conn, CmdAnnotate, EncodeAnnotate(handle, ""))
+	if rply, _ := readReply(t, conn); rply != RplyBadReq {
+		t.Errorf("empty annotation: expected BadReq, got %d", rply)
+	}
+
+	WritePacket(conn, CmdListAnnotations, EncodeHandle(handle))
+	rply, payload, err := ReadPacket(conn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rply != RplyAnnotations {
+		t.Fatalf("expected RplyAnnotations, got %d", rply)
+	}
+	notes, err := DecodeAnnotations(payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(notes) != 1 || notes[0].Message != "planned maintenance" || notes[0].User == "" {
+		t.Fatalf("annotations = %+v", notes)
+	}
+
+	events := server.services.RecentEvents(0)
+	if len(events) != 1 || events[0].Event != service.EventAnnotation {
+		t.Errorf("event history = %+v, want the annotation", events)
+	}
+}
+
+func TestListenEvents(t *testing.T) {
+	server, sockPath := setupTestServer(t)
+	defer server.Stop()
+
+	svc := service.NewInternalService(server.services, "watched")
+	server.services.AddService(svc)
+
+	conn := connectTest(t, sockPath)
+	defer conn.Close()
+
+	if err := WritePacket(conn, CmdListenEvents, nil); err != nil {
+		t.Fatal(err)
+	}
+	if rply, _, err := ReadPacket(conn); err != nil || rply != RplyACK {
+		t.Fatalf("listen-events: rply=%d err=%v", rply, err)
+	}
+
+	// No handle was ever taken for the service, yet its events arrive.
+	server.services.StartService(svc)
+
+	payload := readSpecificInfoPacket(t, conn, InfoGlobalEvent, 2*time.Second)
+	ev, err := DecodeGlobalEvent(payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ev.Service != "watched" || ev.Event != service.EventStarted {
+		t.Errorf("unexpected event %+v", ev)
+	}
+}
+
+func TestSubscribeEventsFiltered(t *testing.T) {
+	server, sockPath := setupTestServer(t)
+	defer server.Stop()
+
+	ignored := service.NewInternalService(server.services, "ignored")
+	watched := service.NewInternalService(server.services, "watched")
+	server.services.AddService(ignored)
+	server.services.AddService(watched)
+
+	conn := connectTest(t, sockPath)
+	defer conn.Close()
+
+	if err := WritePacket(conn, CmdSubscribeEvents, EncodeSubscribeEvents([]string{"watched"})); err != nil {
+		t.Fatal(err)
+	}
+	if rply, _, err := ReadPacket(conn); err != nil || rply != RplyACK {
+		t.Fatalf("subscribe-events: rply=%d err=%v", rply, err)
+	}
+
+	server.services.StartService(ignored)
+	server.services.StartService(watched)
+
+	payload := readSpecificInfoPacket(t, conn, InfoGlobalEvent, 2*time.Second)
+	ev, err := DecodeGlobalEvent(payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ev.Service != "watched" || ev.Event != service.EventStarted {
+		t.Errorf("unexpected event %+v, want watched STARTED only", ev)
+	}
+}
+
+func TestEventSubscriptionsReplaceEachOther(t *testing.T) {
+	t.Run("listen-then-subscribe", func(t *testing.T) {
+		testEventSubscriptionReplaced(t, CmdListenEvents, CmdSubscribeEvents)
+	})
+	t.Run("subscribe-then-listen", func(t *testing.T) {
+		testEventSubscriptionReplaced(t, CmdSubscribeEvents, CmdListenEvents)
+	})
+}
+
+// testEventSubscriptionReplaced sends first and then second, both asking
+// for every service's events, and checks that each event arrives once.
+func testEventSubscriptionReplaced(t *testing.T, first, second uint8) {
+	server, sockPath := setupTestServer(t)
+	defer server.Stop()
+
+	svc := service.NewInternalService(server.services, "watched")
+	server.services.AddService(svc)
+
+	conn := connectTest(t, sockPath)
+	defer conn.Close()
+
+	for _, cmd := range []uint8{first, second} {
+		var payload []byte
+		if cmd == CmdSubscribeEvents {
+			payload = EncodeSubscribeEvents(nil)
+		}
+		if err := WritePacket(conn, cmd, payload); err != nil {
+			t.Fatal(err)
+		}
+		if rply, _, err := ReadPacket(conn); err != nil || rply != RplyACK {
+			t.Fatalf("cmd %d: rply=%d err=%v", cmd, rply, err)
+		}
+	}
+
+	server.services.StartService(svc)
+
+	started := 0
+	conn.SetReadDeadline(time.Now().Add(500 * time.Millisecond))
+	for {
+		rply, payload, err := ReadPacket(conn)
+		if err != nil {
+			break
+		}
+		if rply != InfoGlobalEvent {
+			continue
+		}
+		ev, err := DecodeGlobalEvent(payload)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ev.Service == "watched" && ev.Event == service.EventStarted {
+			started++
+		}
+	}
+	if started != 1 {
+		t.Errorf("got %d STARTED events, want 1", started)
+	}
+}
+
+func TestSubscribeEventsBadPayload(t *testing.T) {
+	server, sockPath := setupTestServer(t)
+	defer server.Stop()
+
+	conn := connectTest(t, sockPath)
+	defer conn.Close()
+
+	// Claims one name but carries none.
+	if err := WritePacket(conn, CmdSubscribeEvents, []byte{1, 0}); err != nil {
+		t.Fatal(err)
+	}
+	if rply, _, err := ReadPacket(conn); err != nil || rply != RplyBadReq {
+		t.Fatalf("rply=%d err=%v, want BadReq", rply, err)
+	}
+}
+
+func TestSubscribeList(t *testing.T) {
+	t.Run("plain", func(t *testing.T) { testSubscribeList(t, false) })
+	t.Run("zlib", func(t *testing.T) { testSubscribeList(t, true) })
+}
+
+func testSubscribeList(t *testing.T, compressed bool) {
+	server, sockPath := setupTestServer(t)
+	defer server.Stop()
+
+	existing := service.NewInternalService(server.services, "existing")
+	server.services.AddService(existing)
+
+	conn := connectTest(t, sockPath)
+	defer conn.Close()
+	if compressed {
+		negotiateZlib(t, conn)
+	}
+
+	if err := WritePacket(conn, CmdSubscribeList, nil); err != nil {
+		t.Fatal(err)
+	}
+	var listed []string
+	for {
+		rply, payload, err := ReadPacketWith(conn, compressed)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if rply == RplyListDone {
+			break
+		}
+		if rply != RplySvcInfo {
+			t.Fatalf("unexpected reply %d in initial list", rply)
+		}
+		entry, _, err := DecodeSvcInfo(payload)
+		if err != nil {
+			t.Fatal(err)
+		}
+		listed = append(listed, entry.Name)
+	}
+	if len(listed) != 1 || listed[0] != "existing" {
+		t.Fatalf("initial list = %v, want [existing]", listed)
+	}
+
+	added := service.NewInternalService(server.services, "added")
+	server.services.AddService(added)
+	server.services.StartService(existing)
+	server.services.RemoveService(added)
+
+	want := []struct {
+		rply  uint8
+		name  string
+		state service.ServiceState
+	}{
+		{RplySvcAdded, "added", service.StateStopped},
+		{RplySvcChanged, "existing", service.StateStarted},
+		{RplySvcRemoved, "added", service.StateStopped},
+	}
+	for _, w := range want {
+		conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+		rply, payload, err := ReadPacketWith(conn, compressed)
+		if err != nil {
+			t.Fatal(err)
+		}
+		entry, _, err := DecodeSvcInfo(payload)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if rply != w.rply || entry.Name != w.name || entry.State != w.state {
+			t.Errorf("got rply=%d %s %v, want rply=%d %s %v", rply, entry.Name, entry.State, w.rply, w.name, w.state)
+		}
+	}
+
+	if err := WritePacket(conn, CmdUnsubscribeList, nil); err != nil {
+		t.Fatal(err)
+	}
+	if rply, _, err := ReadPacketWith(conn, compressed); err != nil || rply != RplyACK {
+		t.Fatalf("unsubscribe-list: rply=%d err=%v", rply, err)
+	}
+	server.services.StopService(existing)
+
+	// Nothing more may arrive after the ACK: the next reply must be
+	// the one to this request.
+	if err := WritePacket(conn, CmdListAliases, nil); err != nil {
+		t.Fatal(err)
+	}
+	if rply, _, err := ReadPacketWith(conn, compressed); err != nil || rply != RplyAliases {
+		t.Fatalf("after unsubscribe: rply=%d err=%v, want RplyAliases", rply, err)
+	}
+}
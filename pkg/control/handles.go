@@ -0,0 +1,89 @@
+package control
+
+import (
+	"sync"
+	"time"
+
+	"github.com/sunlightlinux/slinit/pkg/service"
+)
+
+// handleExpiry is how long a handle may sit unused before it is revoked.
+// A later command naming the handle gets RplyHandleExpired so the client
+// knows to re-load the service rather than treating it as a bad request.
+const handleExpiry = 30 * time.Minute
+
+// handleRef identifies one handle in one connection's handle table.
+type handleRef struct {
+	conn   *Connection
+	handle uint32
+}
+
+// HandleRegistry tracks, across all connections, which handles refer to
+// each service. When a service is removed from the ServiceSet the server
+// walks the registry and revokes every handle to it, so no connection is
+// left holding a dangling reference to an unloaded record.
+type HandleRegistry struct {
+	mu             sync.RWMutex
+	serviceHandles map[service.Service][]handleRef
+}
+
+func newHandleRegistry() *HandleRegistry {
+	return &HandleRegistry{serviceHandles: make(map[service.Service][]handleRef)}
+}
+
+func (r *HandleRegistry) add(svc service.Service, c *Connection, handle uint32) {
+	r.mu.Lock()
+	r.serviceHandles[svc] = append(r.serviceHandles[svc], handleRef{c, handle})
+	r.mu.Unlock()
+}
+
+func (r *HandleRegistry) remove(svc service.Service, c *Connection, handle uint32) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	refs := r.serviceHandles[svc]
+	for i, ref := range refs {
+		if ref.conn == c && ref.handle == handle {
+			refs = append(refs[:i], refs[i+1:]...)
+			break
+		}
+	}
+	if len(refs) == 0 {
+		delete(r.serviceHandles, svc)
+	} else {
+		r.serviceHandles[svc] = refs
+	}
+}
+
+// removeConnection drops every reference held by c. Called on close.
+func (r *HandleRegistry) removeConnection(c *Connection) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for svc, refs := range r.serviceHandles {
+		kept := refs[:0]
+		for _, ref := range refs {
+			if ref.conn != c {
+				kept = append(kept, ref)
+			}
+		}
+		if len(kept) == 0 {
+			delete(r.serviceHandles, svc)
+		} else {
+			r.serviceHandles[svc] = kept
+		}
+	}
+}
+
+// refs returns a snapshot of the handles referring to svc.
+func (r *HandleRegistry) refs(svc service.Service) []handleRef {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return append([]handleRef(nil), r.serviceHandles[svc]...)
+}
+
+// Revoke deletes every connection's handles to svc. Wired to
+// ServiceSet.OnServiceRemoved by NewServer.
+func (r *HandleRegistry) Revoke(svc service.Service) {
+	for _, ref := range r.refs(svc) {
+		ref.conn.revokeHandle(ref.handle, false)
+	}
+}
@@ -0,0 +1,150 @@
+package control
+
+import (
+	"encoding/binary"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/sunlightlinux/slinit/pkg/service"
+)
+
+func findHandleOn(t *testing.T, conn net.Conn, name string) uint32 {
+	t.Helper()
+	if err := WritePacket(conn, CmdFindService, EncodeServiceName(name)); err != nil {
+		t.Fatalf("Write error: %v", err)
+	}
+	rply, payload := readReply(t, conn)
+	if rply != RplyServiceRecord {
+		t.Fatalf("Expected ServiceRecord, got %d", rply)
+	}
+	return binary.LittleEndian.Uint32(payload[1:5])
+}
+
+func TestUnloadRevokesOtherConnectionHandles(t *testing.T) {
+	server, sockPath := setupTestServer(t)
+	defer server.Stop()
+
+	svc := service.NewInternalService(server.services, "shared")
+	server.services.AddService(svc)
+
+	holder := connectTest(t, sockPath)
+	defer holder.Close()
+	held := findHandleOn(t, holder, "shared")
+
+	unloader := connectTest(t, sockPath)
+	defer unloader.Close()
+	h := findHandleOn(t, unloader, "shared")
+	if err := WritePacket(unloader, CmdUnloadService, EncodeHandle(h)); err != nil {
+		t.Fatalf("Write error: %v", err)
+	}
+	if rply, _ := readReply(t, unloader); rply != RplyACK {
+		t.Fatalf("Expected ACK, got %d", rply)
+	}
+
+	// The holder's handle must no longer resolve to the unloaded record.
+	if err := WritePacket(holder, CmdServiceStatus, EncodeHandle(held)); err != nil {
+		t.Fatalf("Write error: %v", err)
+	}
+	if rply, _ := readReply(t, holder); rply != RplyBadReq {
+		t.Fatalf("Expected BadReq for revoked handle, got %d", rply)
+	}
+	if refs := server.handles.refs(svc); len(refs) != 0 {
+		t.Errorf("registry still holds %d refs to unloaded service", len(refs))
+	}
+}
+
+func TestExpiredHandleReply(t *testing.T) {
+	server, sockPath := setupTestServer(t)
+	defer server.Stop()
+
+	server.services.AddService(service.NewInternalService(server.services, "idle"))
+
+	conn := connectTest(t, sockPath)
+	defer conn.Close()
+	h := findHandleOn(t, conn, "idle")
+
+	// Age the handle past the expiry window.
+	server.mu.Lock()
+	for c := range server.conns {
+		c.handleMu.Lock()
+		if _, ok := c.handleUsed[h]; ok {
+			c.handleUsed[h] = time.Now().Add(-handleExpiry - time.Minute)
+		}
+		c.handleMu.Unlock()
+	}
+	server.mu.Unlock()
+
+	if err := WritePacket(conn, CmdServiceStatus, EncodeHandle(h)); err != nil {
+		t.Fatalf("Write error: %v", err)
+	}
+	rply, _ := readReply(t, conn)
+	if rply != RplyHandleExpired {
+		t.Fatalf("Expected HandleExpired, got %d", rply)
+	}
+
+	// Re-finding the service hands out a fresh, working handle.
+	h2 := findHandleOn(t, conn, "idle")
+	if h2 == h {
+		t.Errorf("expected a new handle after expiry, got the same one")
+	}
+}
+
+func TestReplacedServiceHandleIsStale(t *testing.T) {
+	server, sockPath := setupTestServer(t)
+	defer server.Stop()
+
+	oldSvc := service.NewInternalService(server.services, "swapped")
+	server.services.AddService(oldSvc)
+
+	conn := connectTest(t, sockPath)
+	defer conn.Close()
+	h := findHandleOn(t, conn, "swapped")
+
+	// A reload that changes the type replaces the record.
+	newSvc := service.NewTriggeredService(server.services, "swapped")
+	server.services.ReplaceService(oldSvc, newSvc)
+
+	if err := WritePacket(conn, CmdServiceStatus, EncodeHandle(h)); err != nil {
+		t.Fatalf("Write error: %v", err)
+	}
+	rply, _ := readReply(t, conn)
+	if rply != RplyStaleHandle {
+		t.Fatalf("Expected StaleHandle, got %d", rply)
+	}
+	if refs := server.handles.refs(oldSvc); len(refs) != 0 {
+		t.Errorf("registry still holds %d refs to the replaced record", len(refs))
+	}
+
+	// Finding the service again resolves to the new record.
+	h2 := findHandleOn(t, conn, "swapped")
+	if err := WritePacket(conn, CmdServiceStatus, EncodeHandle(h2)); err != nil {
+		t.Fatalf("Write error: %v", err)
+	}
+	if rply, _ := readReply(t, conn); rply != RplyServiceStatus {
+		t.Fatalf("Expected ServiceStatus for the fresh handle, got %d", rply)
+	}
+}
+
+// A stale handle must reach a client that negotiated compression as
+// RplyStaleHandle, not as a packet it fails to inflate.
+func TestStaleHandleCompressed(t *testing.T) {
+	server, sockPath := setupTestServer(t)
+	defer server.Stop()
+
+	oldSvc := service.NewInternalService(server.services, "swapped")
+	server.services.AddService(oldSvc)
+
+	conn := connectTest(t, sockPath)
+	defer conn.Close()
+	h := findHandleOn(t, conn, "swapped")
+	negotiateZlib(t, conn)
+
+	server.services.ReplaceService(oldSvc, service.NewTriggeredService(server.services, "swapped"))
+	if err := WritePacket(conn, CmdServiceStatus, EncodeHandle(h)); err != nil {
+		t.Fatalf("Write error: %v", err)
+	}
+	if rply, _ := readReplyWith(t, conn, true); rply != RplyStaleHandle {
+		t.Fatalf("Expected StaleHandle, got %d", rply)
+	}
+}
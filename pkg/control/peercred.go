@@ -1,7 +1,11 @@
 package control
 
 import (
+	"crypto/tls"
+	"fmt"
 	"net"
+	"os/user"
+	"strconv"
 	"syscall"
 )
 
@@ -10,13 +14,66 @@ import (
 // (e.g. the connection is not a Unix socket or the kernel didn't return
 // peer credentials). Callers must treat (false) as untrusted.
 func peerUID(c net.Conn) (uint32, bool) {
+	ucred := peerCred(c)
+	if ucred == nil {
+		return 0, false
+	}
+	return ucred.Uid, true
+}
+
+// peerPID returns the PID of the peer connected via a Unix socket, or 0
+// if it could not be retrieved. Only used for log messages.
+func peerPID(c net.Conn) int32 {
+	if ucred := peerCred(c); ucred != nil {
+		return ucred.Pid
+	}
+	return 0
+}
+
+// peerUser names the client for audit records: the login name of the
+// peer's UID on a Unix socket (or the bare UID if it has no passwd
+// entry), the certificate common name on a TLS connection, and "" if
+// neither is known.
+func (c *Connection) peerUser() string {
+	if tc, ok := c.conn.(*tls.Conn); ok {
+		if certs := tc.ConnectionState().PeerCertificates; len(certs) > 0 {
+			return certs[0].Subject.CommonName
+		}
+		return ""
+	}
+	uid, ok := peerUID(c.conn)
+	if !ok {
+		return ""
+	}
+	id := strconv.FormatUint(uint64(uid), 10)
+	if u, err := user.LookupId(id); err == nil {
+		return u.Username
+	}
+	return id
+}
+
+// auditCaller describes the client in audit log lines: its PID, UID
+// and user name on a Unix socket, its certificate name over TLS.
+func (c *Connection) auditCaller() string {
+	if _, ok := c.conn.(*tls.Conn); ok {
+		return fmt.Sprintf("TLS client %q", c.peerUser())
+	}
+	if c.peerPID == 0 {
+		return "unknown client"
+	}
+	return fmt.Sprintf("pid %d uid %d (%s)", c.peerPID, c.peerUID, c.peerUser())
+}
+
+// peerCred reads SO_PEERCRED from a Unix socket connection; nil on any
+// failure.
+func peerCred(c net.Conn) *syscall.Ucred {
 	uc, ok := c.(*net.UnixConn)
 	if !ok {
-		return 0, false
+		return nil
 	}
 	raw, err := uc.SyscallConn()
 	if err != nil {
-		return 0, false
+		return nil
 	}
 	var (
 		ucred *syscall.Ucred
@@ -25,10 +82,10 @@ func peerUID(c net.Conn) (uint32, bool) {
 	if cerr := raw.Control(func(fd uintptr) {
 		ucred, gerr = syscall.GetsockoptUcred(int(fd), syscall.SOL_SOCKET, syscall.SO_PEERCRED)
 	}); cerr != nil {
-		return 0, false
+		return nil
 	}
-	if gerr != nil || ucred == nil {
-		return 0, false
+	if gerr != nil {
+		return nil
 	}
-	return ucred.Uid, true
+	return ucred
 }
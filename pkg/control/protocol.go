@@ -7,6 +7,8 @@ import (
 	"encoding/binary"
 	"fmt"
 	"io"
+	"sort"
+	"time"
 
 	"github.com/sunlightlinux/slinit/pkg/service"
 )
@@ -26,6 +28,12 @@ func encodeStatusFlags(svc service.Service) uint8 {
 	if svc.Record().DidStartFail() {
 		flags |= StatusFlagStartFailed
 	}
+	if svc.Record().Embedded() {
+		flags |= StatusFlagEmbedded
+	}
+	if svc.Record().WasStartSkipped() {
+		flags |= StatusFlagStartSkipped
+	}
 	return flags
 }
 
@@ -34,7 +42,7 @@ func encodeStatusFlags(svc service.Service) uint8 {
 // MinCompatVersion is the minimum version a peer must support.
 // Version reply format: min_compat(2) + actual_version(2) = 4 bytes.
 const (
-	CPVersion        uint16 = 7
+	CPVersion        uint16 = 8
 	MinCompatVersion uint16 = 1
 )
 
@@ -104,6 +112,37 @@ const (
 	CmdResetFailed        uint8 = 57 // clear the startFailed flag on a specific service or all
 	CmdFreezeService      uint8 = 58 // cgroup v2 freezer: write 1 to cgroup.freeze
 	CmdThawService        uint8 = 59 // cgroup v2 freezer: write 0 to cgroup.freeze
+	CmdQueryHealth        uint8 = 60 // healthcheck-command state of a service
+	CmdNegotiateCompression uint8 = 61 // offer(1): CompressMask bits; v8+
+	CmdListRecentEvents   uint8 = 62 // max(2): daemon-wide service event history
+	CmdListenEvents       uint8 = 63 // subscribe to InfoGlobalEvent for every service
+	CmdShutdownPlan       uint8 = 64 // dry-run: stop order + time estimate, no side effects
+	CmdSetRestartEnabled  uint8 = 65 // handle(4) + enabled(1): runtime auto-restart override
+	CmdVerifyInternal     uint8 = 66 // debug: check service reference counts
+	CmdLockServiceFile    uint8 = 67 // name: exclusive flock on the service file until unlock/disconnect
+	CmdUnlockServiceFile  uint8 = 68 // name: release a CmdLockServiceFile lock
+	CmdQueryInfo          uint8 = 69 // daemon/connection facts (socket-mode, ...)
+	CmdKillService        uint8 = 70 // handle(4): SIGKILL and keep down (no restart, desired stopped)
+	CmdAnnotate           uint8 = 71 // handle(4) + message(2+N): attach an operator note
+	CmdListAnnotations    uint8 = 72 // handle(4): the service's operator notes
+	CmdGetDependencyInfo  uint8 = 73 // handle(4): dependency edges with flags and target state
+	CmdSetServiceStopReason uint8 = 74 // handle(4) + code(1) + message(2+N): custom stop reason
+	CmdSetNamedTrigger    uint8 = 75 // handle(4) + name(2+N) + value(1): set/clear a named trigger
+	CmdQueryTriggers      uint8 = 76 // handle(4): trigger state of a triggered service
+	CmdSetPriority        uint8 = 77 // handle(4) + priority(4, signed): propagation queue priority
+	CmdSubscribeEvents    uint8 = 78 // count(2) + names(2+N each): InfoGlobalEvent for those services (0 = all)
+	CmdListAliases        uint8 = 79 // no payload: every alias → service mapping
+	CmdSubscribeList      uint8 = 80 // no payload: RplySvcInfo list, then RplySvcAdded/Removed/Changed updates
+	CmdUnsubscribeList    uint8 = 81 // no payload: end a CmdSubscribeList subscription
+	CmdDumpDiagnostics    uint8 = 82 // no payload: RplyDiagnostics text chunks, then RplyListDone
+	CmdListTimers         uint8 = 83 // no payload: every timer service with its schedule
+	CmdSetServiceDirs     uint8 = 84 // op(1) + count(2) + [dir(2+N)]*: change the service directories
+	CmdDumpGraph          uint8 = 85 // no payload: the whole dependency graph, one RplyGraphNode per service
+	CmdPinService         uint8 = 86 // handle(4) + pin(1): pin started (PinStarted) or stopped (PinStopped)
+	CmdSwitchTarget       uint8 = 87 // handle(4): make the service the active target, releasing the rest
+	CmdCancelJob          uint8 = 88 // job(4): withdraw a running start or stop job
+	CmdQueryJob           uint8 = 89 // job(4): RplyJobInfo for that job, or RplyNAK
+	CmdListJobs           uint8 = 90 // no payload: RplyJobInfo per job, then RplyListDone
 )
 
 // Reply codes (server → client).
@@ -147,7 +186,7 @@ const (
 	RplyActionOutput    uint8 = 95 // output from extra-command action
 	RplyActionList      uint8 = 96 // list of available actions
 	RplyShutdownStatus  uint8 = 97 // scheduled shutdown status (type + remaining_secs)
-	RplyReloadAllResult uint8 = 98 // reload-all summary (uint16 succeeded + uint16 failed, LE)
+	RplyReloadAllResult uint8 = 98 // reload-all summary: uint16 succeeded + uint16 failed (LE), then per-service results
 	RplyMetadata        uint8 = 99 // author/version/usage triplet (3× uint16 length-prefixed UTF-8)
 	// Profile reply codes: originally 100/101/102 but that collided
 	// with the InfoServiceEvent / InfoServiceEvent5 / InfoEnvEvent
@@ -162,6 +201,46 @@ const (
 	RplyActivateResult  uint8 = 112 // active profile name + 3 lists (stopped/started/kept) all length-prefixed
 	RplyBundleMembers   uint8 = 113 // uint16 count + [uint16 len + name]* (empty when not a bundle)
 	RplyManualRefused   uint8 = 114 // systemd-style refuse-manual-start / refuse-manual-stop rejection
+	RplyHandleExpired   uint8 = 115 // handle was revoked after sitting unused for handleExpiry
+	RplyHealth          uint8 = 116 // flags(1) + failures(2) + max-failures(2) + last-check unix nanos(8)
+	RplyCompression     uint8 = 117 // algo(1) chosen by the server
+	RplyRecentEvents    uint8 = 118 // count(2) + global event entries, oldest first
+	RplyShutdownPlan    uint8 = 119 // count(2) + [estimate ms(4) + string list]* per step
+	// Command rejected by the per-connection rate limit; nothing was
+	// executed. Payload: retry-after ms(4). Not 78, which is dinit's
+	// RplyEnvList.
+	RplyRateLimited     uint8 = 120
+	RplyRefCountErrors  uint8 = 121 // count(2) + [service(2+N) field(2+N) expected(4) actual(4)]*
+	RplyServiceFile     uint8 = 122 // path(2+N) of the service file now locked
+	RplyInfo            uint8 = 123 // count(2) + [key(2+N) value(2+N)]*
+	RplyAnnotations     uint8 = 124 // count(2) + [unix nanos(8) user(2+N) message(2+N)]*
+	RplyDependencyInfo  uint8 = 125 // deps + dependents; see EncodeDependencyInfo
+	RplyTriggers        uint8 = 126 // flags(1) + count(2) + [name(2+N) set(1)]*
+	RplyAliases         uint8 = 127 // count(2) + [alias(2+N) service(2+N)]*, sorted by alias
+	// Reply codes stay below 0x80, so 127 is the last of this run and
+	// later replies fill the free codes above the info range (104-109)
+	// and then 80-89. The type byte carries no flags: once a connection
+	// negotiates compression, every packet the server sends is framed
+	// by WritePacketFlags with PacketFlagCompressed in its own flags
+	// byte, and a connection that never negotiates keeps the plain
+	// framing.
+	//
+	// Service list updates after CmdSubscribeList, each an EncodeSvcInfo
+	// entry; they keep coming until CmdUnsubscribeList is ACKed.
+	RplySvcAdded        uint8 = 104 // service loaded
+	RplySvcRemoved      uint8 = 105 // service unloaded (last known state)
+	RplySvcChanged      uint8 = 106 // service state changed
+	// The handle named a service record a type-changing reload has
+	// since replaced; load the service again for a fresh handle.
+	RplyStaleHandle     uint8 = 107
+	RplyDiagnostics     uint8 = 108 // a chunk of the CmdDumpDiagnostics text
+	RplyTimers          uint8 = 109 // count(2) + [name(2+N) target(2+N) state(1) next(8) last(8)]*
+	RplyGraphNode       uint8 = 80  // name(2+N) type(1) state(1) count(2) + [dep(2+N) depType(1)]*; ends with RplyListDone
+	// The client's access role (see AccessPolicy) does not allow the
+	// command; nothing was executed.
+	RplyAccessDenied    uint8 = 81
+	RplyTargetSwitched  uint8 = 82  // target(2+N) + released list + stopping list, all length-prefixed
+	RplyJobInfo         uint8 = 83  // job(4) kind(1) state(1) created(8) finished(8) name(2+N)
 )
 
 // Info codes (server → client, unsolicited).
@@ -169,6 +248,7 @@ const (
 	InfoServiceEvent  uint8 = 100
 	InfoServiceEvent5 uint8 = 101
 	InfoEnvEvent      uint8 = 102
+	InfoGlobalEvent   uint8 = 103 // one global event entry (after CmdListenEvents/CmdSubscribeEvents)
 )
 
 // ServiceEvent codes (matches service.ServiceEvent).
@@ -185,6 +265,9 @@ const (
 	SvcEventPressureMemory uint8 = 5
 	SvcEventPressureCPU    uint8 = 6
 	SvcEventPressureIO     uint8 = 7
+	// Operator note (CmdAnnotate); only seen in InfoGlobalEvent and
+	// the recent-event history.
+	SvcEventAnnotation     uint8 = 8
 )
 
 // Status flags byte bits.
@@ -194,6 +277,8 @@ const (
 	StatusFlagWaitingDeps  uint8 = 1 << 2
 	StatusFlagHasConsole   uint8 = 1 << 3
 	StatusFlagStartFailed  uint8 = 1 << 4
+	StatusFlagEmbedded     uint8 = 1 << 5 // loaded from the built-in embedded set
+	StatusFlagStartSkipped uint8 = 1 << 6 // last start skipped by a skippable dependent
 )
 
 // Packet header: 1-byte command/reply + 2-byte payload length (little-endian).
@@ -233,14 +318,49 @@ func WritePacket(w io.Writer, pktType uint8, payload []byte) error {
 	return nil
 }
 
+// WritePacketFlags writes a packet in the framing the server switches
+// to once a connection has negotiated compression:
+// [type(1)][flags(1)][payloadLen(2)][payload(N)]. flags carries
+// PacketFlagCompressed.
+func WritePacketFlags(w io.Writer, pktType, flags uint8, payload []byte) error {
+	pLen := len(payload)
+	if pLen > MaxPayloadSize {
+		return fmt.Errorf("payload too large: %d > %d", pLen, MaxPayloadSize)
+	}
+	buf := make([]byte, 4+pLen)
+	buf[0] = pktType
+	buf[1] = flags
+	binary.LittleEndian.PutUint16(buf[2:], uint16(pLen))
+	copy(buf[4:], payload)
+	_, err := w.Write(buf)
+	return err
+}
+
 // ReadPacket reads a packet: [type(1)][payloadLen(2)][payload(N)].
 func ReadPacket(r io.Reader) (pktType uint8, payload []byte, err error) {
-	var hdr [3]byte
-	if _, err = io.ReadFull(r, hdr[:]); err != nil {
+	return ReadPacketWith(r, false)
+}
+
+// ReadPacketWith is ReadPacket for a peer that may have negotiated
+// compression. With compressionEnabled set, packets are read in the
+// WritePacketFlags framing and payloads flagged with
+// PacketFlagCompressed are inflated, so callers never see compressed
+// payloads.
+func ReadPacketWith(r io.Reader, compressionEnabled bool) (pktType uint8, payload []byte, err error) {
+	var hdr [4]byte
+	head := hdr[:3]
+	if compressionEnabled {
+		head = hdr[:4]
+	}
+	if _, err = io.ReadFull(r, head); err != nil {
 		return 0, nil, err
 	}
 	pktType = hdr[0]
-	pLen := binary.LittleEndian.Uint16(hdr[1:])
+	var flags uint8
+	if compressionEnabled {
+		flags = hdr[1]
+	}
+	pLen := binary.LittleEndian.Uint16(head[len(head)-2:])
 	if pLen > MaxPayloadSize {
 		return 0, nil, fmt.Errorf("payload too large: %d", pLen)
 	}
@@ -250,6 +370,11 @@ func ReadPacket(r io.Reader) (pktType uint8, payload []byte, err error) {
 			return 0, nil, err
 		}
 	}
+	if flags&PacketFlagCompressed != 0 {
+		if payload, err = decompressPayload(payload); err != nil {
+			return 0, nil, err
+		}
+	}
 	return pktType, payload, nil
 }
 
@@ -321,6 +446,38 @@ type ServiceStatusInfo struct {
 	Flags       uint8
 	PID         int32
 	ExitStatus  int32
+
+	// HasFailureStats is set when the reply carried the failure-stats
+	// trailer (see EncodeFailureStats); older daemons send 12 bytes.
+	HasFailureStats   bool
+	FailureStats      service.FailureStats
+	RestartSuppressed bool
+	// RestartOverride is the `slinitctl no-restart` / `enable-restart`
+	// override from the failure-stats trailer; nil if none.
+	RestartOverride *bool
+
+	// HasStopReason is set when the reply carried the stop-reason
+	// trailer (see EncodeStopReason), which follows the failure stats.
+	HasStopReason    bool
+	StopReason       service.StoppedReason
+	CustomStopReason string // message of a custom (>= ReasonCustom) reason
+
+	// HasEffectiveRestart is set when the reply carried the restart
+	// trailer (see EncodeEffectiveRestart), which follows the stop
+	// reason.
+	HasEffectiveRestart bool
+	EffectiveRestart    service.AutoRestartMode
+	RestartSource       service.RestartSource
+
+	// LastErrorMessage is the error output of the last failed start
+	// (see EncodeLastError), from the trailer after the restart one;
+	// "" if none was captured or the daemon doesn't send it.
+	LastErrorMessage string
+
+	// StartedTime is when the service last reached STARTED (see
+	// EncodeStartedTime), from the trailer after the last error; zero
+	// if it never has or the daemon doesn't send it.
+	StartedTime time.Time
 }
 
 // EncodeServiceStatus encodes service status into bytes.
@@ -347,14 +504,166 @@ func DecodeServiceStatus(data []byte) (ServiceStatusInfo, error) {
 	if len(data) < 12 {
 		return ServiceStatusInfo{}, fmt.Errorf("data too short for status: need 12, have %d", len(data))
 	}
-	return ServiceStatusInfo{
+	info := ServiceStatusInfo{
 		State:       service.ServiceState(data[0]),
 		TargetState: service.ServiceState(data[1]),
 		SvcType:     service.ServiceType(data[2]),
 		Flags:       data[3],
 		PID:         int32(binary.LittleEndian.Uint32(data[4:])),
 		ExitStatus:  int32(binary.LittleEndian.Uint32(data[8:])),
-	}, nil
+	}
+	if len(data) >= 12+failureStatsSize {
+		var flags uint8
+		info.FailureStats, flags = decodeFailureStats(data[12:])
+		info.RestartSuppressed = flags&failureStatsFlagSuppressed != 0
+		if flags&failureStatsFlagOverride != 0 {
+			enabled := flags&failureStatsFlagOverrideOn != 0
+			info.RestartOverride = &enabled
+		}
+		info.HasFailureStats = true
+		if rest := data[12+failureStatsSize:]; len(rest) >= 1 {
+			msg, n, err := DecodeServiceName(rest[1:])
+			if err != nil {
+				return ServiceStatusInfo{}, fmt.Errorf("stop reason: %w", err)
+			}
+			info.StopReason = service.StoppedReason(rest[0])
+			info.CustomStopReason = msg
+			info.HasStopReason = true
+			if rest = rest[1+n:]; len(rest) >= 2 {
+				info.EffectiveRestart = service.AutoRestartMode(rest[0])
+				info.RestartSource = service.RestartSource(rest[1])
+				info.HasEffectiveRestart = true
+				if rest = rest[2:]; len(rest) >= 2 {
+					msg, n, err := DecodeServiceName(rest)
+					if err != nil {
+						return ServiceStatusInfo{}, fmt.Errorf("last error: %w", err)
+					}
+					info.LastErrorMessage = msg
+					if rest = rest[n:]; len(rest) >= 8 {
+						if ns := int64(binary.LittleEndian.Uint64(rest)); ns != 0 {
+							info.StartedTime = time.Unix(0, ns)
+						}
+					}
+				}
+			}
+		}
+	}
+	return info, nil
+}
+
+// failureStatsSize is the length of the CmdServiceStatus failure-stats
+// trailer: totalStarts(8) + totalFailures(8) + totalRestarts(8) +
+// lastFailure(8, unix nanos, 0 = never) + consecutive(4) + flags(1).
+const failureStatsSize = 37
+
+// failureStatsFlagSuppressed marks a service whose auto-restart was
+// disabled by failure-action-threshold.
+const failureStatsFlagSuppressed uint8 = 1 << 0
+
+// failureStatsFlagOverride marks a runtime auto-restart override (set
+// by CmdSetRestartEnabled); failureStatsFlagOverrideOn is its value.
+const (
+	failureStatsFlagOverride   uint8 = 1 << 1
+	failureStatsFlagOverrideOn uint8 = 1 << 2
+)
+
+// EncodeFailureStats encodes the cumulative failure counters of svc as
+// the trailer appended to the CmdServiceStatus reply.
+func EncodeFailureStats(svc service.Service) []byte {
+	rec := svc.Record()
+	fs := rec.FailureStats()
+	buf := make([]byte, failureStatsSize)
+	binary.LittleEndian.PutUint64(buf[0:], uint64(fs.TotalStarts))
+	binary.LittleEndian.PutUint64(buf[8:], uint64(fs.TotalFailures))
+	binary.LittleEndian.PutUint64(buf[16:], uint64(fs.TotalRestarts))
+	if !fs.LastFailureTime.IsZero() {
+		binary.LittleEndian.PutUint64(buf[24:], uint64(fs.LastFailureTime.UnixNano()))
+	}
+	binary.LittleEndian.PutUint32(buf[32:], uint32(fs.ConsecutiveFailures))
+	if rec.RestartSuppressed() {
+		buf[36] |= failureStatsFlagSuppressed
+	}
+	if o := rec.AutoRestartOverride(); o != nil {
+		buf[36] |= failureStatsFlagOverride
+		if *o {
+			buf[36] |= failureStatsFlagOverrideOn
+		}
+	}
+	return buf
+}
+
+// decodeFailureStats decodes a trailer written by EncodeFailureStats,
+// returning its flags byte; data must hold at least failureStatsSize
+// bytes.
+func decodeFailureStats(data []byte) (service.FailureStats, uint8) {
+	fs := service.FailureStats{
+		TotalStarts:         int64(binary.LittleEndian.Uint64(data[0:])),
+		TotalFailures:       int64(binary.LittleEndian.Uint64(data[8:])),
+		TotalRestarts:       int64(binary.LittleEndian.Uint64(data[16:])),
+		ConsecutiveFailures: int(binary.LittleEndian.Uint32(data[32:])),
+	}
+	if ns := int64(binary.LittleEndian.Uint64(data[24:])); ns != 0 {
+		fs.LastFailureTime = time.Unix(0, ns)
+	}
+	return fs, data[36]
+}
+
+// EncodeStopReason encodes the stop-reason trailer appended to the
+// CmdServiceStatus reply after the failure stats: reason(1) followed by
+// the custom reason message as a length-prefixed string (empty unless
+// the reason is ReasonCustom or above).
+func EncodeStopReason(svc service.Service) []byte {
+	rec := svc.Record()
+	return append([]byte{uint8(rec.StopReason())}, EncodeServiceName(rec.CustomStopReason())...)
+}
+
+// EncodeEffectiveRestart encodes the restart trailer appended to the
+// CmdServiceStatus reply after the stop reason: the effective restart
+// mode(1) and what decided it(1).
+func EncodeEffectiveRestart(svc service.Service) []byte {
+	rec := svc.Record()
+	return []byte{uint8(rec.GetEffectiveAutoRestart()), uint8(rec.AutoRestartSource())}
+}
+
+// EncodeLastError encodes the last-error trailer appended to the
+// CmdServiceStatus reply after the restart trailer: the error output of
+// the last failed start as a length-prefixed string.
+func EncodeLastError(svc service.Service) []byte {
+	return EncodeServiceName(svc.Record().LastErrorMessage())
+}
+
+// EncodeStartedTime encodes the trailer appended to the CmdServiceStatus
+// reply after the last error: the time the service last reached STARTED
+// as unix nanos(8), 0 if it never has.
+func EncodeStartedTime(svc service.Service) []byte {
+	buf := make([]byte, 8)
+	if t := svc.Record().StartedTime(); !t.IsZero() {
+		binary.LittleEndian.PutUint64(buf, uint64(t.UnixNano()))
+	}
+	return buf
+}
+
+// EncodeSetStopReason encodes a CmdSetServiceStopReason payload:
+// handle(4) + code(1) + message(2+N).
+func EncodeSetStopReason(handle uint32, code service.StoppedReason, msg string) []byte {
+	buf := append(EncodeHandle(handle), uint8(code))
+	return append(buf, EncodeServiceName(msg)...)
+}
+
+// DecodeSetStopReason decodes a CmdSetServiceStopReason payload.
+func DecodeSetStopReason(data []byte) (uint32, service.StoppedReason, string, error) {
+	handle, err := DecodeHandle(data)
+	if err != nil {
+		return 0, 0, "", err
+	}
+	if len(data) < 5 {
+		return 0, 0, "", fmt.Errorf("set stop reason: missing code")
+	}
+	msg, _, err := DecodeServiceName(data[5:])
+	if err != nil {
+		return 0, 0, "", fmt.Errorf("set stop reason: %w", err)
+	}
+	return handle, service.StoppedReason(data[4]), msg, nil
 }
 
 // --- Protocol v5 extended formats ---
@@ -478,13 +787,33 @@ type SvcInfoEntry struct {
 	SvcType     service.ServiceType
 	Flags       uint8
 	PID         int32
+	Pin         uint8 // PinNone, PinStarted or PinStopped
+}
+
+// Pin states, as carried by CmdPinService and list entries.
+const (
+	PinNone    uint8 = 0
+	PinStarted uint8 = 1
+	PinStopped uint8 = 2
+)
+
+// encodePin returns the pin state of svc.
+func encodePin(svc service.Service) uint8 {
+	switch {
+	case svc.Record().IsStartPinned():
+		return PinStarted
+	case svc.Record().IsStopPinned():
+		return PinStopped
+	}
+	return PinNone
 }
 
 // EncodeSvcInfo encodes a service info entry for list command.
-// Format: nameLen(2) + name(N) + state(1) + target(1) + type(1) + flags(1) + pid(4).
+// Format: nameLen(2) + name(N) + state(1) + target(1) + type(1) + flags(1) + pid(4) + pin(1).
+// The pin byte was added later; DecodeSvcInfo accepts entries without it.
 func EncodeSvcInfo(svc service.Service) []byte {
 	name := svc.Name()
-	buf := make([]byte, 2+len(name)+8)
+	buf := make([]byte, 2+len(name)+9)
 	binary.LittleEndian.PutUint16(buf, uint16(len(name)))
 	copy(buf[2:], name)
 	off := 2 + len(name)
@@ -493,6 +822,7 @@ func EncodeSvcInfo(svc service.Service) []byte {
 	buf[off+2] = uint8(svc.Type())
 	buf[off+3] = encodeStatusFlags(svc)
 	binary.LittleEndian.PutUint32(buf[off+4:], uint32(int32(svc.PID())))
+	buf[off+8] = encodePin(svc)
 	return buf
 }
 
@@ -513,7 +843,11 @@ func DecodeSvcInfo(data []byte) (SvcInfoEntry, int, error) {
 		Flags:       data[n+3],
 		PID:         int32(binary.LittleEndian.Uint32(data[n+4:])),
 	}
-	return entry, n + 8, nil
+	if len(data) < n+9 {
+		return entry, n + 8, nil
+	}
+	entry.Pin = data[n+8]
+	return entry, n + 9, nil
 }
 
 // --- Boot timing protocol ---
@@ -525,6 +859,11 @@ type BootTimeEntry struct {
 	State     service.ServiceState
 	SvcType   service.ServiceType
 	PID       int32
+	// Wall-clock times (unix ns) of the last start request and of
+	// reaching STARTED; 0 if that never happened, or from daemons
+	// that predate them.
+	StartRequestNs int64
+	StartedNs      int64
 }
 
 // BootTimeInfo holds the complete boot timing data.
@@ -534,12 +873,19 @@ type BootTimeInfo struct {
 	BootReadyNs    int64 // 0 if boot service hasn't reached STARTED yet
 	BootSvcName    string
 	Services       []BootTimeEntry
+	// Events is the boot timeline; nil from daemons that predate it.
+	Events []service.BootEvent
 }
 
 // EncodeBootTime encodes boot timing info into bytes.
 // Wire format: kernelUptime(8) + bootStart(8) + bootReady(8) +
 // nameLen(2) + name(N) + numSvcs(2) +
-// [per svc: nameLen(2) + name(N) + startupNs(8) + state(1) + type(1) + pid(4)]
+// [per svc: nameLen(2) + name(N) + startupNs(8) + state(1) + type(1) + pid(4)] +
+// numEvents(2) + [per event: time(8, unix ns) + type(1) + nameLen(2) + name(N)] +
+// numSvcs(2) + [per svc, in the same order: startRequest(8) + started(8)]
+//
+// The event and start-time sections are trailers: DecodeBootTime
+// accepts replies that end after the services or after the events.
 func EncodeBootTime(info BootTimeInfo) []byte {
 	// Calculate total size
 	size := 8 + 8 + 8 + 2 + len(info.BootSvcName) + 2
@@ -580,6 +926,18 @@ func EncodeBootTime(info BootTimeInfo) []byte {
 		off += 4
 	}
 
+	buf = binary.LittleEndian.AppendUint16(buf, uint16(len(info.Events)))
+	for _, ev := range info.Events {
+		buf = binary.LittleEndian.AppendUint64(buf, uint64(ev.Time.UnixNano()))
+		buf = append(buf, uint8(ev.Type))
+		buf = append(buf, EncodeServiceName(ev.Service)...)
+	}
+
+	buf = binary.LittleEndian.AppendUint16(buf, uint16(len(info.Services)))
+	for _, s := range info.Services {
+		buf = binary.LittleEndian.AppendUint64(buf, uint64(s.StartRequestNs))
+		buf = binary.LittleEndian.AppendUint64(buf, uint64(s.StartedNs))
+	}
 	return buf
 }
 
@@ -637,6 +995,45 @@ func DecodeBootTime(data []byte) (BootTimeInfo, error) {
 		info.Services = append(info.Services, entry)
 	}
 
+	if len(data) < off+2 {
+		return info, nil // no event trailer
+	}
+	numEvents := int(binary.LittleEndian.Uint16(data[off:]))
+	off += 2
+	info.Events = make([]service.BootEvent, 0, numEvents)
+	for i := 0; i < numEvents; i++ {
+		if len(data) < off+9 {
+			return BootTimeInfo{}, fmt.Errorf("data too short for boot event %d", i)
+		}
+		ev := service.BootEvent{
+			Time: time.Unix(0, int64(binary.LittleEndian.Uint64(data[off:]))),
+			Type: service.BootEventType(data[off+8]),
+		}
+		name, used, err := DecodeServiceName(data[off+9:])
+		if err != nil {
+			return BootTimeInfo{}, fmt.Errorf("boot event %d: %w", i, err)
+		}
+		ev.Service = name
+		off += 9 + used
+		info.Events = append(info.Events, ev)
+	}
+
+	if len(data) < off+2 {
+		return info, nil // no start-time trailer
+	}
+	if n := int(binary.LittleEndian.Uint16(data[off:])); n != len(info.Services) {
+		return BootTimeInfo{}, fmt.Errorf("start times for %d services, have %d", n, len(info.Services))
+	}
+	off += 2
+	if len(data) < off+16*len(info.Services) {
+		return BootTimeInfo{}, fmt.Errorf("data too short for service start times")
+	}
+	for i := range info.Services {
+		info.Services[i].StartRequestNs = int64(binary.LittleEndian.Uint64(data[off:]))
+		info.Services[i].StartedNs = int64(binary.LittleEndian.Uint64(data[off+8:]))
+		off += 16
+	}
+
 	return info, nil
 }
 
@@ -891,6 +1288,244 @@ func DecodeServiceStatus6(data []byte) (ServiceStatusInfo6, error) {
 	}, nil
 }
 
+// Health flag bits (first byte of RplyHealth).
+const (
+	HealthFlagConfigured uint8 = 1 << 0 // service has a healthcheck-command
+	HealthFlagHealthy    uint8 = 1 << 1 // most recent check passed
+)
+
+// HealthInfo is the decoded form of RplyHealth.
+type HealthInfo struct {
+	Flags       uint8
+	Failures    uint16
+	MaxFailures uint16    // 0 = failures never stop the service
+	LastCheck   time.Time // zero when no check has completed yet
+}
+
+// EncodeHealth encodes the health check state of svc into 13 bytes.
+// Services without a health check encode as all zeroes.
+func EncodeHealth(svc service.Service) []byte {
+	buf := make([]byte, 13)
+	hr, ok := svc.(service.HealthReporter)
+	if !ok {
+		return buf
+	}
+	st, ok := hr.HealthStatus()
+	if !ok {
+		return buf
+	}
+	buf[0] = HealthFlagConfigured
+	if st.Healthy {
+		buf[0] |= HealthFlagHealthy
+	}
+	binary.LittleEndian.PutUint16(buf[1:], uint16(st.Failures))
+	binary.LittleEndian.PutUint16(buf[3:], uint16(st.MaxFailures))
+	if !st.LastCheck.IsZero() {
+		binary.LittleEndian.PutUint64(buf[5:], uint64(st.LastCheck.UnixNano()))
+	}
+	return buf
+}
+
+// DecodeHealth decodes a RplyHealth payload.
+func DecodeHealth(data []byte) (HealthInfo, error) {
+	if len(data) < 13 {
+		return HealthInfo{}, fmt.Errorf("data too short for health: need 13, have %d", len(data))
+	}
+	info := HealthInfo{
+		Flags:       data[0],
+		Failures:    binary.LittleEndian.Uint16(data[1:]),
+		MaxFailures: binary.LittleEndian.Uint16(data[3:]),
+	}
+	if ns := int64(binary.LittleEndian.Uint64(data[5:])); ns != 0 {
+		info.LastCheck = time.Unix(0, ns)
+	}
+	return info, nil
+}
+
+// encodeGlobalEventInto appends one global event entry:
+// unix nanos(8) + event(1) + name(2+N) + details(2+M).
+func encodeGlobalEventInto(buf []byte, ev service.GlobalEvent) []byte {
+	var hdr [9]byte
+	binary.LittleEndian.PutUint64(hdr[:], uint64(ev.Time.UnixNano()))
+	hdr[8] = uint8(ev.Event)
+	buf = append(buf, hdr[:]...)
+	buf = append(buf, EncodeServiceName(ev.Service)...)
+	return append(buf, EncodeServiceName(ev.Details)...)
+}
+
+// decodeGlobalEvent decodes one global event entry, returning the
+// number of bytes consumed.
+func decodeGlobalEvent(data []byte) (service.GlobalEvent, int, error) {
+	if len(data) < 9 {
+		return service.GlobalEvent{}, 0, fmt.Errorf("global event: too short for header")
+	}
+	ev := service.GlobalEvent{
+		Time:  time.Unix(0, int64(binary.LittleEndian.Uint64(data))),
+		Event: service.ServiceEvent(data[8]),
+	}
+	off := 9
+	name, n, err := DecodeServiceName(data[off:])
+	if err != nil {
+		return service.GlobalEvent{}, 0, fmt.Errorf("global event: %w", err)
+	}
+	off += n
+	details, n, err := DecodeServiceName(data[off:])
+	if err != nil {
+		return service.GlobalEvent{}, 0, fmt.Errorf("global event: %w", err)
+	}
+	off += n
+	ev.Service = name
+	ev.Details = details
+	return ev, off, nil
+}
+
+// EncodeGlobalEvent encodes a single InfoGlobalEvent payload.
+func EncodeGlobalEvent(ev service.GlobalEvent) []byte {
+	return encodeGlobalEventInto(nil, ev)
+}
+
+// DecodeGlobalEvent decodes an InfoGlobalEvent payload.
+func DecodeGlobalEvent(data []byte) (service.GlobalEvent, error) {
+	ev, _, err := decodeGlobalEvent(data)
+	return ev, err
+}
+
+// EncodeRecentEvents encodes a RplyRecentEvents payload:
+// count(2) followed by that many global event entries.
+func EncodeRecentEvents(events []service.GlobalEvent) []byte {
+	buf := make([]byte, 2, 2+len(events)*32)
+	binary.LittleEndian.PutUint16(buf, uint16(len(events)))
+	for _, ev := range events {
+		buf = encodeGlobalEventInto(buf, ev)
+	}
+	return buf
+}
+
+// DecodeRecentEvents decodes a RplyRecentEvents payload.
+func DecodeRecentEvents(data []byte) ([]service.GlobalEvent, error) {
+	if len(data) < 2 {
+		return nil, fmt.Errorf("recent events: too short for count")
+	}
+	n := int(binary.LittleEndian.Uint16(data))
+	off := 2
+	out := make([]service.GlobalEvent, 0, n)
+	for i := 0; i < n; i++ {
+		ev, used, err := decodeGlobalEvent(data[off:])
+		if err != nil {
+			return nil, fmt.Errorf("recent events: entry %d: %w", i, err)
+		}
+		out = append(out, ev)
+		off += used
+	}
+	return out, nil
+}
+
+// EncodeScheduleShutdown encodes a CmdScheduleShutdown payload:
+// type(1), delay in whole seconds(4, big-endian), then msgLen(2) +
+// msg when msg is non-empty. Daemons that predate the message ignore
+// the tail. msg is truncated to 64 KiB.
+func EncodeScheduleShutdown(st service.ShutdownType, delay time.Duration, msg string) []byte {
+	secs := uint32(delay / time.Second)
+	buf := []byte{uint8(st), byte(secs >> 24), byte(secs >> 16), byte(secs >> 8), byte(secs)}
+	if msg == "" {
+		return buf
+	}
+	if len(msg) > 0xFFFF {
+		msg = msg[:0xFFFF]
+	}
+	buf = binary.LittleEndian.AppendUint16(buf, uint16(len(msg)))
+	return append(buf, msg...)
+}
+
+// EncodeShutdownPlan encodes a RplyShutdownPlan payload: count(2), then
+// per step the estimated duration in ms (uint32) followed by the
+// step's service names as a string list.
+func EncodeShutdownPlan(plan []service.ShutdownStep) []byte {
+	buf := make([]byte, 2)
+	binary.LittleEndian.PutUint16(buf, uint16(len(plan)))
+	for _, step := range plan {
+		var est [4]byte
+		binary.LittleEndian.PutUint32(est[:], uint32(step.EstimatedDurationMs))
+		buf = append(buf, est[:]...)
+		buf = append(buf, EncodeStringList(step.Services)...)
+	}
+	return buf
+}
+
+// DecodeShutdownPlan decodes a RplyShutdownPlan payload.
+func DecodeShutdownPlan(data []byte) ([]service.ShutdownStep, error) {
+	if len(data) < 2 {
+		return nil, fmt.Errorf("shutdown plan: too short for count")
+	}
+	n := int(binary.LittleEndian.Uint16(data))
+	off := 2
+	plan := make([]service.ShutdownStep, 0, n)
+	for i := 0; i < n; i++ {
+		if len(data) < off+4 {
+			return nil, fmt.Errorf("shutdown plan: step %d: too short for estimate", i)
+		}
+		est := int(binary.LittleEndian.Uint32(data[off:]))
+		off += 4
+		names, used, err := DecodeStringList(data[off:])
+		if err != nil {
+			return nil, fmt.Errorf("shutdown plan: step %d: %w", i, err)
+		}
+		off += used
+		plan = append(plan, service.ShutdownStep{Services: names, EstimatedDurationMs: est})
+	}
+	return plan, nil
+}
+
+// EncodeRefCountErrors encodes a RplyRefCountErrors payload: count(2),
+// then per entry the service and field names followed by the expected
+// and actual counts as int32.
+func EncodeRefCountErrors(errs []service.RefCountError) []byte {
+	buf := make([]byte, 2)
+	binary.LittleEndian.PutUint16(buf, uint16(len(errs)))
+	for _, e := range errs {
+		buf = append(buf, EncodeServiceName(e.Service)...)
+		buf = append(buf, EncodeServiceName(e.Field)...)
+		var counts [8]byte
+		binary.LittleEndian.PutUint32(counts[:], uint32(int32(e.Expected)))
+		binary.LittleEndian.PutUint32(counts[4:], uint32(int32(e.Actual)))
+		buf = append(buf, counts[:]...)
+	}
+	return buf
+}
+
+// DecodeRefCountErrors decodes a RplyRefCountErrors payload.
+func DecodeRefCountErrors(data []byte) ([]service.RefCountError, error) {
+	if len(data) < 2 {
+		return nil, fmt.Errorf("refcount errors: too short for count")
+	}
+	n := int(binary.LittleEndian.Uint16(data))
+	off := 2
+	out := make([]service.RefCountError, 0, n)
+	for i := 0; i < n; i++ {
+		name, used, err := DecodeServiceName(data[off:])
+		if err != nil {
+			return nil, fmt.Errorf("refcount errors: entry %d: %w", i, err)
+		}
+		off += used
+		field, used, err := DecodeServiceName(data[off:])
+		if err != nil {
+			return nil, fmt.Errorf("refcount errors: entry %d: %w", i, err)
+		}
+		off += used
+		if len(data) < off+8 {
+			return nil, fmt.Errorf("refcount errors: entry %d: too short for counts", i)
+		}
+		out = append(out, service.RefCountError{
+			Service:  name,
+			Field:    field,
+			Expected: int(int32(binary.LittleEndian.Uint32(data[off:]))),
+			Actual:   int(int32(binary.LittleEndian.Uint32(data[off+4:]))),
+		})
+		off += 8
+	}
+	return out, nil
+}
+
 // EncodeStringList encodes a []string as [count(2)][len(2)][s]* using
 // little-endian uint16. Reused by RplyProfileList and by the three
 // name lists inside RplyActivateResult (stopped/started/kept). Empty
@@ -937,6 +1572,42 @@ func DecodeStringList(data []byte) ([]string, int, error) {
 	return out, off, nil
 }
 
+// CmdSetServiceDirs operations.
+const (
+	ServiceDirsSet    uint8 = 0 // replace the list with the given directories
+	ServiceDirsAdd    uint8 = 1 // append one directory
+	ServiceDirsRemove uint8 = 2 // remove one directory
+)
+
+// EncodeSetServiceDirs encodes a CmdSetServiceDirs payload: the
+// operation followed by the directories as a string list.
+func EncodeSetServiceDirs(op uint8, dirs []string) []byte {
+	return append([]byte{op}, EncodeStringList(dirs)...)
+}
+
+// DecodeSetServiceDirs decodes a CmdSetServiceDirs payload. Add and
+// remove take exactly one directory.
+func DecodeSetServiceDirs(data []byte) (uint8, []string, error) {
+	if len(data) < 1 {
+		return 0, nil, fmt.Errorf("set service dirs: empty payload")
+	}
+	op := data[0]
+	dirs, _, err := DecodeStringList(data[1:])
+	if err != nil {
+		return 0, nil, err
+	}
+	switch op {
+	case ServiceDirsSet:
+	case ServiceDirsAdd, ServiceDirsRemove:
+		if len(dirs) != 1 {
+			return 0, nil, fmt.Errorf("set service dirs: op %d takes one directory, got %d", op, len(dirs))
+		}
+	default:
+		return 0, nil, fmt.Errorf("set service dirs: unknown op %d", op)
+	}
+	return op, dirs, nil
+}
+
 // EncodeActivateResult packs the four fields of a profile activation
 // response: the newly-active profile name plus the stopped/started/
 // kept service lists. All four use the length-prefixed string(s)
@@ -973,3 +1644,584 @@ func DecodeActivateResult(data []byte) (active string, stopped, started, kept []
 	}
 	return active, stopped, started, kept, nil
 }
+
+// EncodeInfo encodes a RplyInfo payload: count(2), then each key and
+// value as a length-prefixed string.
+func EncodeInfo(pairs [][2]string) []byte {
+	buf := make([]byte, 2)
+	binary.LittleEndian.PutUint16(buf, uint16(len(pairs)))
+	for _, kv := range pairs {
+		buf = append(buf, EncodeServiceName(kv[0])...)
+		buf = append(buf, EncodeServiceName(kv[1])...)
+	}
+	return buf
+}
+
+// EncodeAliases encodes a RplyAliases payload from alias → service
+// name mappings. The layout is RplyInfo's, sorted by alias.
+func EncodeAliases(aliases map[string]string) []byte {
+	pairs := make([][2]string, 0, len(aliases))
+	for alias, name := range aliases {
+		pairs = append(pairs, [2]string{alias, name})
+	}
+	sort.Slice(pairs, func(i, j int) bool { return pairs[i][0] < pairs[j][0] })
+	return EncodeInfo(pairs)
+}
+
+// DecodeAliases decodes a RplyAliases payload into (alias, service)
+// pairs.
+func DecodeAliases(data []byte) ([][2]string, error) {
+	return DecodeInfo(data)
+}
+
+// EncodeTimers encodes a RplyTimers payload. The next and last firing
+// times are unix nanos, 0 when unset.
+func EncodeTimers(timers []service.TimerInfo) []byte {
+	buf := make([]byte, 2, 2+len(timers)*32)
+	binary.LittleEndian.PutUint16(buf, uint16(len(timers)))
+	for _, t := range timers {
+		buf = append(buf, EncodeServiceName(t.Name)...)
+		buf = append(buf, EncodeServiceName(t.Target)...)
+		buf = append(buf, uint8(t.State))
+		buf = binary.LittleEndian.AppendUint64(buf, timeNanos(t.NextFire))
+		buf = binary.LittleEndian.AppendUint64(buf, timeNanos(t.LastFire))
+	}
+	return buf
+}
+
+// DecodeTimers decodes a RplyTimers payload.
+func DecodeTimers(data []byte) ([]service.TimerInfo, error) {
+	if len(data) < 2 {
+		return nil, fmt.Errorf("timers: too short for count")
+	}
+	n := int(binary.LittleEndian.Uint16(data))
+	off := 2
+	out := make([]service.TimerInfo, 0, n)
+	for i := 0; i < n; i++ {
+		var t service.TimerInfo
+		var used int
+		var err error
+		if t.Name, used, err = DecodeServiceName(data[off:]); err != nil {
+			return nil, fmt.Errorf("timers: entry %d: %w", i, err)
+		}
+		off += used
+		if t.Target, used, err = DecodeServiceName(data[off:]); err != nil {
+			return nil, fmt.Errorf("timers: entry %d: %w", i, err)
+		}
+		off += used
+		if len(data) < off+17 {
+			return nil, fmt.Errorf("timers: entry %d: too short", i)
+		}
+		t.State = service.ServiceState(data[off])
+		t.NextFire = nanosTime(binary.LittleEndian.Uint64(data[off+1:]))
+		t.LastFire = nanosTime(binary.LittleEndian.Uint64(data[off+9:]))
+		off += 17
+		out = append(out, t)
+	}
+	return out, nil
+}
+
+// EncodeGraphNode encodes a RplyGraphNode payload: node and its
+// dependencies. Every edge must have node as its From.
+func EncodeGraphNode(node service.GraphNode, edges []service.GraphEdge) []byte {
+	buf := EncodeServiceName(node.Name)
+	buf = append(buf, uint8(node.Type), uint8(node.State))
+	buf = binary.LittleEndian.AppendUint16(buf, uint16(len(edges)))
+	for _, e := range edges {
+		buf = append(buf, EncodeServiceName(e.To)...)
+		buf = append(buf, uint8(e.Type))
+	}
+	return buf
+}
+
+// DecodeGraphNode decodes a RplyGraphNode payload.
+func DecodeGraphNode(data []byte) (service.GraphNode, []service.GraphEdge, error) {
+	var node service.GraphNode
+	name, off, err := DecodeServiceName(data)
+	if err != nil {
+		return node, nil, fmt.Errorf("graph node: %w", err)
+	}
+	if len(data) < off+4 {
+		return node, nil, fmt.Errorf("graph node %s: too short", name)
+	}
+	node = service.GraphNode{
+		Name:  name,
+		Type:  service.ServiceType(data[off]),
+		State: service.ServiceState(data[off+1]),
+	}
+	n := int(binary.LittleEndian.Uint16(data[off+2:]))
+	off += 4
+	edges := make([]service.GraphEdge, 0, n)
+	for i := 0; i < n; i++ {
+		to, used, err := DecodeServiceName(data[off:])
+		if err != nil {
+			return node, nil, fmt.Errorf("graph node %s: edge %d: %w", name, i, err)
+		}
+		off += used
+		if len(data) < off+1 {
+			return node, nil, fmt.Errorf("graph node %s: edge %d: too short", name, i)
+		}
+		edges = append(edges, service.GraphEdge{From: name, To: to, Type: service.DependencyType(data[off])})
+		off++
+	}
+	return node, edges, nil
+}
+
+// EncodeReloadAll encodes a RplyReloadAllResult payload:
+// succeeded(2) + failed(2) + count(2) + count × [name][outcome(1)][detail].
+// Only services whose outcome is not ReloadUnchanged are listed; the
+// first two counts cover every service. Clients that predate the list
+// read the counts and ignore the rest.
+func EncodeReloadAll(results []service.ReloadResult) []byte {
+	reloaded, failed := service.ReloadCounts(results)
+	buf := make([]byte, 6, 6+len(results)*16)
+	binary.LittleEndian.PutUint16(buf[0:], uint16(reloaded))
+	binary.LittleEndian.PutUint16(buf[2:], uint16(failed))
+	n := 0
+	for _, r := range results {
+		if r.Outcome == service.ReloadUnchanged {
+			continue
+		}
+		buf = append(buf, EncodeServiceName(r.Name)...)
+		buf = append(buf, uint8(r.Outcome))
+		buf = append(buf, EncodeServiceName(r.Detail)...)
+		n++
+	}
+	binary.LittleEndian.PutUint16(buf[4:], uint16(n))
+	return buf
+}
+
+// DecodeReloadAll decodes a RplyReloadAllResult payload. A 4-byte
+// payload from an older daemon yields the counts and no results.
+func DecodeReloadAll(data []byte) (reloaded, failed int, results []service.ReloadResult, err error) {
+	if len(data) < 4 {
+		return 0, 0, nil, fmt.Errorf("reload-all: too short for counts")
+	}
+	reloaded = int(binary.LittleEndian.Uint16(data[0:]))
+	failed = int(binary.LittleEndian.Uint16(data[2:]))
+	if len(data) < 6 {
+		return reloaded, failed, nil, nil
+	}
+	n := int(binary.LittleEndian.Uint16(data[4:]))
+	off := 6
+	results = make([]service.ReloadResult, 0, n)
+	for i := 0; i < n; i++ {
+		var r service.ReloadResult
+		var used int
+		if r.Name, used, err = DecodeServiceName(data[off:]); err != nil {
+			return 0, 0, nil, fmt.Errorf("reload-all: entry %d: %w", i, err)
+		}
+		off += used
+		if len(data) < off+1 {
+			return 0, 0, nil, fmt.Errorf("reload-all: entry %d: too short", i)
+		}
+		r.Outcome = service.ReloadOutcome(data[off])
+		off++
+		if r.Detail, used, err = DecodeServiceName(data[off:]); err != nil {
+			return 0, 0, nil, fmt.Errorf("reload-all: entry %d: %w", i, err)
+		}
+		off += used
+		results = append(results, r)
+	}
+	return reloaded, failed, results, nil
+}
+
+// timeNanos returns t as unix nanos, 0 for the zero time.
+func timeNanos(t time.Time) uint64 {
+	if t.IsZero() {
+		return 0
+	}
+	return uint64(t.UnixNano())
+}
+
+// nanosTime is the inverse of timeNanos.
+func nanosTime(ns uint64) time.Time {
+	if ns == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, int64(ns))
+}
+
+// DecodeInfo decodes a RplyInfo payload, preserving the server's order.
+func DecodeInfo(data []byte) ([][2]string, error) {
+	if len(data) < 2 {
+		return nil, fmt.Errorf("info: too short for count")
+	}
+	n := int(binary.LittleEndian.Uint16(data))
+	off := 2
+	out := make([][2]string, 0, n)
+	for i := 0; i < n; i++ {
+		key, used, err := DecodeServiceName(data[off:])
+		if err != nil {
+			return nil, fmt.Errorf("info: entry %d: %w", i, err)
+		}
+		off += used
+		val, used, err := DecodeServiceName(data[off:])
+		if err != nil {
+			return nil, fmt.Errorf("info: entry %d: %w", i, err)
+		}
+		off += used
+		out = append(out, [2]string{key, val})
+	}
+	return out, nil
+}
+
+// MaxAnnotationLen bounds the message of a CmdAnnotate request.
+const MaxAnnotationLen = 1024
+
+// EncodeAnnotate encodes a CmdAnnotate payload: handle(4) followed by
+// the message as a length-prefixed string.
+func EncodeAnnotate(handle uint32, msg string) []byte {
+	return append(EncodeHandle(handle), EncodeServiceName(msg)...)
+}
+
+// DecodeAnnotate decodes a CmdAnnotate payload.
+func DecodeAnnotate(data []byte) (uint32, string, error) {
+	handle, err := DecodeHandle(data)
+	if err != nil {
+		return 0, "", err
+	}
+	msg, _, err := DecodeServiceName(data[4:])
+	if err != nil {
+		return 0, "", fmt.Errorf("annotate: %w", err)
+	}
+	return handle, msg, nil
+}
+
+// EncodeAnnotations encodes a RplyAnnotations payload: count(2), then
+// per note its time in Unix nanoseconds (int64) and the user and
+// message as length-prefixed strings.
+func EncodeAnnotations(as []service.Annotation) []byte {
+	buf := make([]byte, 2)
+	binary.LittleEndian.PutUint16(buf, uint16(len(as)))
+	for _, a := range as {
+		var ts [8]byte
+		binary.LittleEndian.PutUint64(ts[:], uint64(a.Time.UnixNano()))
+		buf = append(buf, ts[:]...)
+		buf = append(buf, EncodeServiceName(a.User)...)
+		buf = append(buf, EncodeServiceName(a.Message)...)
+	}
+	return buf
+}
+
+// DecodeAnnotations decodes a RplyAnnotations payload.
+func DecodeAnnotations(data []byte) ([]service.Annotation, error) {
+	if len(data) < 2 {
+		return nil, fmt.Errorf("annotations: too short for count")
+	}
+	n := int(binary.LittleEndian.Uint16(data))
+	off := 2
+	out := make([]service.Annotation, 0, n)
+	for i := 0; i < n; i++ {
+		if len(data) < off+8 {
+			return nil, fmt.Errorf("annotations: entry %d: too short for time", i)
+		}
+		ts := time.Unix(0, int64(binary.LittleEndian.Uint64(data[off:])))
+		off += 8
+		user, used, err := DecodeServiceName(data[off:])
+		if err != nil {
+			return nil, fmt.Errorf("annotations: entry %d: %w", i, err)
+		}
+		off += used
+		msg, used, err := DecodeServiceName(data[off:])
+		if err != nil {
+			return nil, fmt.Errorf("annotations: entry %d: %w", i, err)
+		}
+		off += used
+		out = append(out, service.Annotation{Time: ts, User: user, Message: msg})
+	}
+	return out, nil
+}
+
+// EncodeDependencyInfo encodes a RplyDependencyInfo payload: count(2)
+// then per dependency depType(1) holdingAcq(1) waitingOn(1) toState(1)
+// and the target name (2+N); then count(2) and per dependent
+// depType(1) and the dependent's name (2+N).
+func EncodeDependencyInfo(deps, dependents []service.DependencyInfo) []byte {
+	buf := make([]byte, 2)
+	binary.LittleEndian.PutUint16(buf, uint16(len(deps)))
+	for _, d := range deps {
+		buf = append(buf, uint8(d.DepType), boolByte(d.HoldingAcq), boolByte(d.WaitingOn), uint8(d.State))
+		buf = append(buf, EncodeServiceName(d.Name)...)
+	}
+	var n [2]byte
+	binary.LittleEndian.PutUint16(n[:], uint16(len(dependents)))
+	buf = append(buf, n[:]...)
+	for _, d := range dependents {
+		buf = append(buf, uint8(d.DepType))
+		buf = append(buf, EncodeServiceName(d.Name)...)
+	}
+	return buf
+}
+
+// DecodeDependencyInfo decodes a RplyDependencyInfo payload.
+func DecodeDependencyInfo(data []byte) (deps, dependents []service.DependencyInfo, err error) {
+	if len(data) < 2 {
+		return nil, nil, fmt.Errorf("dependency info: too short for count")
+	}
+	n := int(binary.LittleEndian.Uint16(data))
+	off := 2
+	for i := 0; i < n; i++ {
+		if len(data) < off+4 {
+			return nil, nil, fmt.Errorf("dependency info: dependency %d: too short for flags", i)
+		}
+		d := service.DependencyInfo{
+			DepType:    service.DependencyType(data[off]),
+			HoldingAcq: data[off+1] != 0,
+			WaitingOn:  data[off+2] != 0,
+			State:      service.ServiceState(data[off+3]),
+		}
+		off += 4
+		name, used, err := DecodeServiceName(data[off:])
+		if err != nil {
+			return nil, nil, fmt.Errorf("dependency info: dependency %d: %w", i, err)
+		}
+		off += used
+		d.Name = name
+		deps = append(deps, d)
+	}
+	if len(data) < off+2 {
+		return nil, nil, fmt.Errorf("dependency info: too short for dependents count")
+	}
+	n = int(binary.LittleEndian.Uint16(data[off:]))
+	off += 2
+	for i := 0; i < n; i++ {
+		if len(data) < off+1 {
+			return nil, nil, fmt.Errorf("dependency info: dependent %d: too short for type", i)
+		}
+		d := service.DependencyInfo{DepType: service.DependencyType(data[off])}
+		off++
+		name, used, err := DecodeServiceName(data[off:])
+		if err != nil {
+			return nil, nil, fmt.Errorf("dependency info: dependent %d: %w", i, err)
+		}
+		off += used
+		d.Name = name
+		dependents = append(dependents, d)
+	}
+	return deps, dependents, nil
+}
+
+// EncodeSetNamedTrigger encodes a CmdSetNamedTrigger payload:
+// handle(4) + name(2+N) + value(1).
+func EncodeSetNamedTrigger(handle uint32, name string, value bool) []byte {
+	buf := append(EncodeHandle(handle), EncodeServiceName(name)...)
+	return append(buf, boolByte(value))
+}
+
+// DecodeSetNamedTrigger decodes a CmdSetNamedTrigger payload.
+func DecodeSetNamedTrigger(data []byte) (uint32, string, bool, error) {
+	handle, err := DecodeHandle(data)
+	if err != nil {
+		return 0, "", false, err
+	}
+	name, n, err := DecodeServiceName(data[4:])
+	if err != nil {
+		return 0, "", false, fmt.Errorf("named trigger: %w", err)
+	}
+	if len(data) < 4+n+1 {
+		return 0, "", false, fmt.Errorf("named trigger: missing value")
+	}
+	return handle, name, data[4+n] != 0, nil
+}
+
+// RplyTriggers flag bits.
+const (
+	TriggersFlagTriggered uint8 = 1 << 0 // the plain trigger is set
+	TriggersFlagAll       uint8 = 1 << 1 // trigger-mode = all
+)
+
+// TriggerInfo is the decoded form of RplyTriggers.
+type TriggerInfo struct {
+	Triggered bool
+	All       bool
+	Named     []service.NamedTrigger
+}
+
+// EncodeTriggers encodes the trigger state of ts as a RplyTriggers
+// payload.
+func EncodeTriggers(ts *service.TriggeredService) []byte {
+	var flags uint8
+	if ts.IsTriggered() {
+		flags |= TriggersFlagTriggered
+	}
+	if _, all := ts.TriggerNames(); all {
+		flags |= TriggersFlagAll
+	}
+	named := ts.NamedTriggers()
+	buf := []byte{flags, 0, 0}
+	binary.LittleEndian.PutUint16(buf[1:], uint16(len(named)))
+	for _, nt := range named {
+		buf = append(buf, EncodeServiceName(nt.Name)...)
+		buf = append(buf, boolByte(nt.Set))
+	}
+	return buf
+}
+
+// DecodeTriggers decodes a RplyTriggers payload.
+func DecodeTriggers(data []byte) (TriggerInfo, error) {
+	if len(data) < 3 {
+		return TriggerInfo{}, fmt.Errorf("triggers: data too short")
+	}
+	info := TriggerInfo{
+		Triggered: data[0]&TriggersFlagTriggered != 0,
+		All:       data[0]&TriggersFlagAll != 0,
+	}
+	n := int(binary.LittleEndian.Uint16(data[1:]))
+	off := 3
+	for i := 0; i < n; i++ {
+		name, used, err := DecodeServiceName(data[off:])
+		if err != nil {
+			return TriggerInfo{}, fmt.Errorf("triggers: entry %d: %w", i, err)
+		}
+		off += used
+		if len(data) < off+1 {
+			return TriggerInfo{}, fmt.Errorf("triggers: entry %d: missing state", i)
+		}
+		info.Named = append(info.Named, service.NamedTrigger{Name: name, Set: data[off] != 0})
+		off++
+	}
+	return info, nil
+}
+
+// EncodeSetPriority encodes a CmdSetPriority payload: handle(4) +
+// priority(4, two's complement).
+func EncodeSetPriority(handle uint32, priority int32) []byte {
+	buf := make([]byte, 8)
+	binary.LittleEndian.PutUint32(buf, handle)
+	binary.LittleEndian.PutUint32(buf[4:], uint32(priority))
+	return buf
+}
+
+// DecodeSetPriority decodes a CmdSetPriority payload.
+func DecodeSetPriority(data []byte) (uint32, int32, error) {
+	if len(data) < 8 {
+		return 0, 0, fmt.Errorf("set priority: data too short")
+	}
+	return binary.LittleEndian.Uint32(data), int32(binary.LittleEndian.Uint32(data[4:])), nil
+}
+
+// EncodePinService encodes a CmdPinService payload: handle(4) + pin(1).
+func EncodePinService(handle uint32, pin uint8) []byte {
+	buf := make([]byte, 5)
+	binary.LittleEndian.PutUint32(buf, handle)
+	buf[4] = pin
+	return buf
+}
+
+// DecodePinService decodes a CmdPinService payload.
+func DecodePinService(data []byte) (uint32, uint8, error) {
+	if len(data) < 5 {
+		return 0, 0, fmt.Errorf("pin service: data too short")
+	}
+	if data[4] != PinStarted && data[4] != PinStopped {
+		return 0, 0, fmt.Errorf("pin service: bad pin %d", data[4])
+	}
+	return binary.LittleEndian.Uint32(data), data[4], nil
+}
+
+// EncodeTargetSwitched packs a RplyTargetSwitched payload: the new
+// target's name, then the released and stopping service lists.
+func EncodeTargetSwitched(target string, released, stopping []string) []byte {
+	var buf []byte
+	buf = append(buf, EncodeServiceName(target)...)
+	buf = append(buf, EncodeStringList(released)...)
+	buf = append(buf, EncodeStringList(stopping)...)
+	return buf
+}
+
+// DecodeTargetSwitched reverses EncodeTargetSwitched.
+func DecodeTargetSwitched(data []byte) (target string, released, stopping []string, err error) {
+	target, n, err := DecodeServiceName(data)
+	if err != nil {
+		return "", nil, nil, err
+	}
+	data = data[n:]
+	released, n, err = DecodeStringList(data)
+	if err != nil {
+		return "", nil, nil, err
+	}
+	data = data[n:]
+	stopping, _, err = DecodeStringList(data)
+	if err != nil {
+		return "", nil, nil, err
+	}
+	return target, released, stopping, nil
+}
+
+// EncodeJobInfo encodes a RplyJobInfo payload.
+func EncodeJobInfo(j service.Job) []byte {
+	buf := binary.LittleEndian.AppendUint32(nil, j.ID)
+	buf = append(buf, uint8(j.Kind), uint8(j.State))
+	buf = binary.LittleEndian.AppendUint64(buf, timeNanos(j.Created))
+	buf = binary.LittleEndian.AppendUint64(buf, timeNanos(j.Finished))
+	return append(buf, EncodeServiceName(j.Service)...)
+}
+
+// DecodeJobInfo decodes a RplyJobInfo payload.
+func DecodeJobInfo(data []byte) (service.Job, error) {
+	if len(data) < 22 {
+		return service.Job{}, fmt.Errorf("job info: data too short")
+	}
+	j := service.Job{
+		ID:       binary.LittleEndian.Uint32(data),
+		Kind:     service.JobKind(data[4]),
+		State:    service.JobState(data[5]),
+		Created:  nanosTime(binary.LittleEndian.Uint64(data[6:])),
+		Finished: nanosTime(binary.LittleEndian.Uint64(data[14:])),
+	}
+	name, _, err := DecodeServiceName(data[22:])
+	if err != nil {
+		return service.Job{}, fmt.Errorf("job info: %w", err)
+	}
+	j.Service = name
+	return j, nil
+}
+
+// DecodeJobACK returns the job ID an RplyACK to CmdStartService or
+// CmdStopService carries, or 0 if the daemon sent none (an older daemon,
+// or a stop with the restart flag, which is tracked by its start job).
+func DecodeJobACK(payload []byte) uint32 {
+	if len(payload) < 4 {
+		return 0
+	}
+	return binary.LittleEndian.Uint32(payload)
+}
+
+// EncodeSubscribeEvents encodes a CmdSubscribeEvents payload: count(2)
+// followed by that many length-prefixed service names. No names means
+// every service.
+func EncodeSubscribeEvents(names []string) []byte {
+	buf := make([]byte, 2)
+	binary.LittleEndian.PutUint16(buf, uint16(len(names)))
+	for _, n := range names {
+		buf = append(buf, EncodeServiceName(n)...)
+	}
+	return buf
+}
+
+// DecodeSubscribeEvents decodes a CmdSubscribeEvents payload.
+func DecodeSubscribeEvents(data []byte) ([]string, error) {
+	if len(data) < 2 {
+		return nil, fmt.Errorf("subscribe events: data too short")
+	}
+	count := int(binary.LittleEndian.Uint16(data))
+	off := 2
+	names := make([]string, 0, count)
+	for i := 0; i < count; i++ {
+		name, used, err := DecodeServiceName(data[off:])
+		if err != nil {
+			return nil, fmt.Errorf("subscribe events: name %d: %w", i, err)
+		}
+		names = append(names, name)
+		off += used
+	}
+	return names, nil
+}
+
+func boolByte(b bool) uint8 {
+	if b {
+		return 1
+	}
+	return 0
+}
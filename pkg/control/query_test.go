@@ -3,6 +3,7 @@ package control
 import (
 	"encoding/binary"
 	"testing"
+	"time"
 
 	"github.com/sunlightlinux/slinit/pkg/config"
 	"github.com/sunlightlinux/slinit/pkg/service"
@@ -114,6 +115,68 @@ func TestQueryServiceDscDir(t *testing.T) {
 	}
 }
 
+func TestSetServiceDirs(t *testing.T) {
+	server, sockPath := setupTestServer(t)
+	defer server.Stop()
+
+	a, b := t.TempDir(), t.TempDir()
+	loader := config.NewDirLoader(server.services, []string{a})
+	server.services.SetLoader(loader)
+
+	conn := connectTest(t, sockPath)
+	defer conn.Close()
+
+	send := func(op uint8, dirs ...string) (uint8, string) {
+		t.Helper()
+		if err := WritePacket(conn, CmdSetServiceDirs, EncodeSetServiceDirs(op, dirs)); err != nil {
+			t.Fatal(err)
+		}
+		rply, payload, err := ReadPacket(conn)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return rply, string(payload)
+	}
+
+	if rply, msg := send(ServiceDirsAdd, b); rply != RplyACK {
+		t.Fatalf("add: reply %d %q", rply, msg)
+	}
+	if got := loader.ServiceDirs(); len(got) != 2 || got[1] != b {
+		t.Fatalf("after add: %v", got)
+	}
+	if rply, msg := send(ServiceDirsRemove, a); rply != RplyACK {
+		t.Fatalf("remove: reply %d %q", rply, msg)
+	}
+	if got := loader.ServiceDirs(); len(got) != 1 || got[0] != b {
+		t.Fatalf("after remove: %v", got)
+	}
+
+	// A refused change leaves the directories alone and says why.
+	if rply, msg := send(ServiceDirsSet, a, "/no/such/dir"); rply != RplyNAK || msg == "" {
+		t.Errorf("set with a missing dir: reply %d %q, want NAK with reason", rply, msg)
+	}
+	if rply, _ := send(ServiceDirsRemove, b); rply != RplyNAK {
+		t.Errorf("removing the last dir: reply %d, want NAK", rply)
+	}
+	if got := loader.ServiceDirs(); len(got) != 1 || got[0] != b {
+		t.Errorf("refused changes modified the dirs: %v", got)
+	}
+
+	if rply, msg := send(ServiceDirsSet, a, b); rply != RplyACK {
+		t.Fatalf("set: reply %d %q", rply, msg)
+	}
+	if got := loader.ServiceDirs(); len(got) != 2 || got[0] != a || got[1] != b {
+		t.Errorf("after set: %v", got)
+	}
+
+	if err := WritePacket(conn, CmdSetServiceDirs, EncodeSetServiceDirs(ServiceDirsAdd, []string{a, b})); err != nil {
+		t.Fatal(err)
+	}
+	if rply, _, err := ReadPacket(conn); err != nil || rply != RplyBadReq {
+		t.Errorf("add with two dirs: reply %d, %v; want BadReq", rply, err)
+	}
+}
+
 func TestQueryServiceDscDirNoLoader(t *testing.T) {
 	server, sockPath := setupTestServer(t)
 	defer server.Stop()
@@ -234,3 +297,55 @@ func TestQueryMetadataBadHandle(t *testing.T) {
 		t.Errorf("expected RplyBadReq, got %d", rply)
 	}
 }
+
+func TestQueryHealth(t *testing.T) {
+	server, sockPath := setupTestServer(t)
+	defer server.Stop()
+
+	plain := service.NewInternalService(server.services, "no-check")
+	server.services.AddService(plain)
+	checked := service.NewScriptedService(server.services, "checked")
+	checked.SetHealthCheck([]string{"/bin/true"}, time.Second, 0, 3, nil)
+	server.services.AddService(checked)
+
+	conn := connectTest(t, sockPath)
+	defer conn.Close()
+
+	query := func(name string) HealthInfo {
+		t.Helper()
+		if err := WritePacket(conn, CmdLoadService, EncodeServiceName(name)); err != nil {
+			t.Fatal(err)
+		}
+		_, payload, err := ReadPacket(conn)
+		if err != nil {
+			t.Fatal(err)
+		}
+		handle := binary.LittleEndian.Uint32(payload[1:5])
+		if err := WritePacket(conn, CmdQueryHealth, EncodeHandle(handle)); err != nil {
+			t.Fatal(err)
+		}
+		rply, payload, err := ReadPacket(conn)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if rply != RplyHealth {
+			t.Fatalf("expected RplyHealth, got %d", rply)
+		}
+		h, err := DecodeHealth(payload)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return h
+	}
+
+	if h := query("no-check"); h.Flags != 0 {
+		t.Errorf("service without health check: flags = %#x, want 0", h.Flags)
+	}
+	h := query("checked")
+	if h.Flags&HealthFlagConfigured == 0 {
+		t.Error("expected HealthFlagConfigured")
+	}
+	if h.MaxFailures != 3 || !h.LastCheck.IsZero() {
+		t.Errorf("unexpected health info %+v", h)
+	}
+}
@@ -0,0 +1,121 @@
+package control
+
+import (
+	"encoding/binary"
+	"fmt"
+	"time"
+)
+
+// Default per-connection command budget: bursts of up to 100 commands,
+// sustained 10 per second. Overridable with --rate-limit-capacity and
+// --rate-limit-refill.
+const (
+	DefaultRateLimitCapacity = 100
+	DefaultRateLimitRefill   = 10.0
+)
+
+// State-mutating commands (start, stop, shutdown) draw from a second,
+// smaller bucket on top of the general one.
+const (
+	mutateRateLimitCapacity = 10
+	mutateRateLimitRefill   = 1.0
+)
+
+// rateLimitPause is how long the connection sleeps after rejecting a
+// command, so a client ignoring RplyRateLimited cannot spin the daemon.
+const rateLimitPause = 100 * time.Millisecond
+
+// tokenBucket is a classic token bucket: it holds up to capacity tokens
+// and regains refill tokens per second. Not safe for concurrent use;
+// each connection's serve goroutine owns its buckets.
+type tokenBucket struct {
+	capacity float64
+	refill   float64
+	tokens   float64
+	last     time.Time
+}
+
+func newTokenBucket(capacity int, refill float64, now time.Time) *tokenBucket {
+	return &tokenBucket{
+		capacity: float64(capacity),
+		refill:   refill,
+		tokens:   float64(capacity),
+		last:     now,
+	}
+}
+
+// update credits the tokens accrued since the last call.
+func (b *tokenBucket) update(now time.Time) {
+	if elapsed := now.Sub(b.last).Seconds(); elapsed > 0 {
+		b.tokens += elapsed * b.refill
+		if b.tokens > b.capacity {
+			b.tokens = b.capacity
+		}
+	}
+	b.last = now
+}
+
+// wait returns how long until a token is available; 0 if one is now.
+func (b *tokenBucket) wait(now time.Time) time.Duration {
+	b.update(now)
+	if b.tokens >= 1 {
+		return 0
+	}
+	if b.refill <= 0 {
+		return time.Hour
+	}
+	return time.Duration((1 - b.tokens) / b.refill * float64(time.Second))
+}
+
+// take consumes one token. Callers check wait first.
+func (b *tokenBucket) take() { b.tokens-- }
+
+// isMutatingCommand reports whether cmd changes service or system state
+// and so draws from the stricter bucket.
+func isMutatingCommand(cmd uint8) bool {
+	switch cmd {
+	case CmdStartService, CmdStopService, CmdKillService, CmdShutdown:
+		return true
+	}
+	return false
+}
+
+// admit charges cmd against the connection's rate limits. It returns 0
+// if the command may run, otherwise how long the client should wait
+// before retrying; nothing is consumed in that case.
+func (c *Connection) admit(cmd uint8, now time.Time) time.Duration {
+	if c.bucket == nil {
+		return 0
+	}
+	retry := c.bucket.wait(now)
+	if isMutatingCommand(cmd) {
+		if w := c.mutateBucket.wait(now); w > retry {
+			retry = w
+		}
+	}
+	if retry > 0 {
+		return retry
+	}
+	c.bucket.take()
+	if isMutatingCommand(cmd) {
+		c.mutateBucket.take()
+	}
+	return 0
+}
+
+// EncodeRateLimited encodes a RplyRateLimited payload: retry-after in
+// milliseconds (uint32, LE), rounded up.
+func EncodeRateLimited(retryAfter time.Duration) []byte {
+	ms := (retryAfter + time.Millisecond - 1) / time.Millisecond
+	buf := make([]byte, 4)
+	binary.LittleEndian.PutUint32(buf, uint32(ms))
+	return buf
+}
+
+// DecodeRateLimited decodes a RplyRateLimited payload.
+func DecodeRateLimited(data []byte) (time.Duration, error) {
+	if len(data) < 4 {
+		return 0, fmt.Errorf("data too short for rate-limited reply: need 4, have %d", len(data))
+	}
+	return time.Duration(binary.LittleEndian.Uint32(data)) * time.Millisecond, nil
+}
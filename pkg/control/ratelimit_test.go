@@ -0,0 +1,119 @@
+package control
+
+import (
+	"encoding/binary"
+	"testing"
+	"time"
+
+	"github.com/sunlightlinux/slinit/pkg/service"
+)
+
+func TestTokenBucket(t *testing.T) {
+	now := time.Unix(1000, 0)
+	b := newTokenBucket(2, 10, now)
+	for i := 0; i < 2; i++ {
+		if w := b.wait(now); w != 0 {
+			t.Fatalf("token %d: wait = %v, want 0", i, w)
+		}
+		b.take()
+	}
+	if w := b.wait(now); w != 100*time.Millisecond {
+		t.Errorf("empty bucket: wait = %v, want 100ms", w)
+	}
+	if w := b.wait(now.Add(100 * time.Millisecond)); w != 0 {
+		t.Errorf("after refill: wait = %v, want 0", w)
+	}
+	// Refill is capped at capacity.
+	b.wait(now.Add(time.Hour))
+	if b.tokens != 2 {
+		t.Errorf("tokens = %v, want capped at 2", b.tokens)
+	}
+}
+
+func TestRateLimitedReply(t *testing.T) {
+	server, sockPath := setupTestServerWith(t, func(s *Server) {
+		s.RateLimitCapacity = 3
+		s.RateLimitRefill = 1
+	})
+	defer server.Stop()
+
+	conn := connectTest(t, sockPath)
+	defer conn.Close()
+
+	for i := 0; i < 3; i++ {
+		if err := WritePacket(conn, CmdQueryVersion, nil); err != nil {
+			t.Fatal(err)
+		}
+		if rply, _, err := ReadPacket(conn); err != nil || rply != RplyCPVersion {
+			t.Fatalf("command %d: rply=%d err=%v", i, rply, err)
+		}
+	}
+
+	if err := WritePacket(conn, CmdQueryVersion, nil); err != nil {
+		t.Fatal(err)
+	}
+	rply, payload, err := ReadPacket(conn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rply != RplyRateLimited {
+		t.Fatalf("expected RplyRateLimited, got %d", rply)
+	}
+	retry, err := DecodeRateLimited(payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if retry <= 0 || retry > time.Second {
+		t.Errorf("retry-after = %v, want (0, 1s]", retry)
+	}
+}
+
+func TestRateLimitMutatingCommands(t *testing.T) {
+	server, sockPath := setupTestServer(t)
+	defer server.Stop()
+
+	svc := service.NewInternalService(server.services, "svc")
+	server.services.AddService(svc)
+
+	conn := connectTest(t, sockPath)
+	defer conn.Close()
+
+	if err := WritePacket(conn, CmdLoadService, EncodeServiceName("svc")); err != nil {
+		t.Fatal(err)
+	}
+	_, payload, err := ReadPacket(conn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	handle := binary.LittleEndian.Uint32(payload[1:5])
+
+	start := EncodeHandle(handle)
+	limited := false
+	for i := 0; i <= mutateRateLimitCapacity; i++ {
+		if err := WritePacket(conn, CmdStartService, start); err != nil {
+			t.Fatal(err)
+		}
+		rply, _, err := ReadPacket(conn)
+		for err == nil && (rply == InfoServiceEvent || rply == InfoServiceEvent5) {
+			rply, _, err = ReadPacket(conn)
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if rply == RplyRateLimited {
+			limited = i == mutateRateLimitCapacity
+			break
+		}
+	}
+	if !limited {
+		t.Errorf("start #%d should be rate limited by the mutating bucket", mutateRateLimitCapacity+1)
+	}
+
+	// Read-only commands still have budget left.
+	if err := WritePacket(conn, CmdQueryVersion, nil); err != nil {
+		t.Fatal(err)
+	}
+	if rply, _, err := ReadPacket(conn); err != nil || rply != RplyCPVersion {
+		t.Errorf("query after mutate limit: rply=%d err=%v", rply, err)
+	}
+}
@@ -2,9 +2,11 @@ package control
 
 import (
 	"encoding/binary"
+	"net"
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/sunlightlinux/slinit/pkg/config"
 	"github.com/sunlightlinux/slinit/pkg/service"
@@ -317,3 +319,166 @@ func TestReloadAllPartialFailure(t *testing.T) {
 		t.Errorf("expected 1 ok / 1 failed, got ok=%d failed=%d", ok, failed)
 	}
 }
+
+func TestReloadAllReportsChanges(t *testing.T) {
+	server, sockPath := setupTestServer(t)
+	defer server.Stop()
+
+	svcDir := t.TempDir()
+	loader := config.NewDirLoader(server.services, []string{svcDir})
+	server.services.SetLoader(loader)
+
+	for _, name := range []string{"idle", "running", "same", "doomed"} {
+		if err := os.WriteFile(filepath.Join(svcDir, name), []byte("type = internal\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := loader.LoadService(name); err != nil {
+			t.Fatalf("load %s: %v", name, err)
+		}
+	}
+	server.services.StartService(server.services.FindService("running", false))
+
+	// Explicit mtimes: a rewrite within the same clock tick would
+	// otherwise look unchanged.
+	later := time.Now().Add(time.Minute)
+	for _, name := range []string{"idle", "running"} {
+		path := filepath.Join(svcDir, name)
+		if err := os.WriteFile(path, []byte("type = internal\nrestart = true\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(path, later, later); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Remove(filepath.Join(svcDir, "doomed")); err != nil {
+		t.Fatal(err)
+	}
+
+	conn := connectTest(t, sockPath)
+	defer conn.Close()
+
+	if err := WritePacket(conn, CmdReloadAll, nil); err != nil {
+		t.Fatal(err)
+	}
+	rply, payload := readReply(t, conn)
+	if rply != RplyReloadAllResult {
+		t.Fatalf("expected RplyReloadAllResult, got %d", rply)
+	}
+	ok, failed, results, err := DecodeReloadAll(payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ok != 3 || failed != 1 {
+		t.Errorf("expected 3 ok / 1 failed, got ok=%d failed=%d", ok, failed)
+	}
+	want := map[string]service.ReloadOutcome{
+		"doomed":  service.ReloadFailed,
+		"idle":    service.ReloadApplied,
+		"running": service.ReloadRestartNeeded,
+	}
+	if len(results) != len(want) {
+		t.Fatalf("results = %+v, want %d entries", results, len(want))
+	}
+	for _, r := range results {
+		if r.Outcome != want[r.Name] {
+			t.Errorf("%s: outcome %v, want %v", r.Name, r.Outcome, want[r.Name])
+		}
+	}
+	if results[0].Name != "doomed" || results[0].Detail == "" {
+		t.Errorf("failed entry should come first with its error, got %+v", results[0])
+	}
+
+	// A second pass finds nothing new.
+	if err := WritePacket(conn, CmdReloadAll, nil); err != nil {
+		t.Fatal(err)
+	}
+	_, payload = readReply(t, conn)
+	if _, _, results, err = DecodeReloadAll(payload); err != nil {
+		t.Fatal(err)
+	}
+	for _, r := range results {
+		if r.Outcome != service.ReloadFailed {
+			t.Errorf("second pass: %s reported %v", r.Name, r.Outcome)
+		}
+	}
+}
+
+func TestDecodeReloadAllCountsOnly(t *testing.T) {
+	// An older daemon sends just the two counts.
+	ok, failed, results, err := DecodeReloadAll([]byte{5, 0, 1, 0})
+	if err != nil || ok != 5 || failed != 1 || results != nil {
+		t.Errorf("got %d/%d %v %v", ok, failed, results, err)
+	}
+}
+
+func TestLockServiceFile(t *testing.T) {
+	server, sockPath := setupTestServer(t)
+	defer server.Stop()
+
+	svcDir := t.TempDir()
+	server.services.SetLoader(config.NewDirLoader(server.services, []string{svcDir}))
+	path := filepath.Join(svcDir, "edit-svc")
+	if err := os.WriteFile(path, []byte("type = internal\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	request := func(conn net.Conn, cmd uint8, name string) (uint8, []byte) {
+		t.Helper()
+		if err := WritePacket(conn, cmd, EncodeServiceName(name)); err != nil {
+			t.Fatalf("Write error: %v", err)
+		}
+		rply, payload, err := ReadPacket(conn)
+		if err != nil {
+			t.Fatalf("Read error: %v", err)
+		}
+		return rply, payload
+	}
+
+	conn := connectTest(t, sockPath)
+	rply, payload := request(conn, CmdLockServiceFile, "edit-svc")
+	if rply != RplyServiceFile {
+		t.Fatalf("expected RplyServiceFile, got %d", rply)
+	}
+	if got, _, _ := DecodeServiceName(payload); got != path {
+		t.Errorf("path = %q, want %q", got, path)
+	}
+	if f, err := config.LockServiceFileExclusive(path); err == nil {
+		f.Close()
+		t.Error("file not locked after CmdLockServiceFile")
+	}
+
+	other := connectTest(t, sockPath)
+	defer other.Close()
+	if rply, _ := request(other, CmdLockServiceFile, "edit-svc"); rply != RplyNAK {
+		t.Errorf("second client lock: expected NAK, got %d", rply)
+	}
+	if rply, _ := request(other, CmdLockServiceFile, "no-such-svc"); rply != RplyNoService {
+		t.Errorf("missing service: expected RplyNoService, got %d", rply)
+	}
+
+	if rply, _ := request(conn, CmdUnlockServiceFile, "edit-svc"); rply != RplyACK {
+		t.Errorf("unlock: expected ACK, got %d", rply)
+	}
+	if rply, _ := request(conn, CmdUnlockServiceFile, "edit-svc"); rply != RplyNAK {
+		t.Errorf("second unlock: expected NAK, got %d", rply)
+	}
+
+	// A lock still held at disconnect is released.
+	if rply, _ := request(other, CmdLockServiceFile, "edit-svc"); rply != RplyServiceFile {
+		t.Fatalf("relock: expected RplyServiceFile, got %d", rply)
+	}
+	other.Close()
+	conn.Close()
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		f, err := config.LockServiceFileExclusive(path)
+		if err == nil {
+			f.Close()
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("lock not released on disconnect")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -243,3 +243,71 @@ func TestCancelShutdownClearsReminders(t *testing.T) {
 		t.Error("scheduledTimer should be nil after cancel")
 	}
 }
+
+func TestShutdownPlanCommand(t *testing.T) {
+	server, sockPath := setupTestServer(t)
+	defer server.Stop()
+
+	var shutdownCalled bool
+	server.ShutdownFunc = func(service.ShutdownType) { shutdownCalled = true }
+
+	db := service.NewInternalService(server.services, "db")
+	app := service.NewInternalService(server.services, "app")
+	app.Record().AddDep(db, service.DepRegular)
+	server.services.AddService(db)
+	server.services.AddService(app)
+	server.services.StartService(app)
+
+	conn := connectTest(t, sockPath)
+	defer conn.Close()
+
+	if err := WritePacket(conn, CmdShutdownPlan, nil); err != nil {
+		t.Fatal(err)
+	}
+	rply, payload, err := ReadPacket(conn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rply != RplyShutdownPlan {
+		t.Fatalf("expected RplyShutdownPlan, got %d", rply)
+	}
+	plan, err := DecodeShutdownPlan(payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(plan) != 2 || plan[0].Services[0] != "app" || plan[1].Services[0] != "db" {
+		t.Errorf("unexpected plan %+v", plan)
+	}
+	if shutdownCalled || app.State() != service.StateStarted {
+		t.Error("dry-run must not shut anything down")
+	}
+}
+
+// TestScheduleShutdownCommand sends an EncodeScheduleShutdown payload
+// over the socket and checks the type, delay and message arrive intact.
+func TestScheduleShutdownCommand(t *testing.T) {
+	server, sockPath := setupTestServer(t)
+	defer server.Stop()
+
+	var gotType service.ShutdownType
+	var gotDelay time.Duration
+	var gotMsg string
+	server.WallFunc = func(st service.ShutdownType, delay time.Duration, _ bool, msg string) {
+		gotType, gotDelay, gotMsg = st, delay, msg
+	}
+	defer server.CancelShutdown()
+
+	conn := connectTest(t, sockPath)
+	defer conn.Close()
+
+	payload := EncodeScheduleShutdown(service.ShutdownReboot, 5*time.Minute, "kernel update")
+	if err := WritePacket(conn, CmdScheduleShutdown, payload); err != nil {
+		t.Fatal(err)
+	}
+	if rply, _ := readReply(t, conn); rply != RplyACK {
+		t.Fatalf("expected ACK, got %d", rply)
+	}
+	if gotType != service.ShutdownReboot || gotDelay != 5*time.Minute || gotMsg != "kernel update" {
+		t.Errorf("got %v, %v, %q", gotType, gotDelay, gotMsg)
+	}
+}
@@ -2,6 +2,7 @@ package control
 
 import (
 	"context"
+	"crypto/tls"
 	"net"
 	"os"
 	"sync"
@@ -13,38 +14,41 @@ import (
 	"github.com/sunlightlinux/slinit/pkg/service"
 )
 
-// listenUnixRestricted creates a Unix socket at path with mode 0600. The
-// umask is tightened to 0177 around bind() so the socket file is never
-// briefly created with a permissive mode (the os.Chmod that we issue
-// afterwards is kept as belt-and-suspenders for filesystems where umask
-// is honored differently).
-func listenUnixRestricted(path string) (net.Listener, error) {
+// listenUnixRestricted creates a Unix socket at path with the given mode
+// (0600 unless an AccessPolicy admits other users). The umask is tightened to 0177 around bind() so
+// the socket file is never briefly created with a permissive mode (the
+// os.Chmod that we issue afterwards is kept as belt-and-suspenders for
+// filesystems where umask is honored differently, and widens the mode
+// once the socket is in place).
+func listenUnixRestricted(path string, mode os.FileMode) (net.Listener, error) {
 	old := syscall.Umask(0177)
 	listener, err := net.Listen("unix", path)
 	syscall.Umask(old)
 	if err != nil {
 		return nil, err
 	}
-	if err := os.Chmod(path, 0600); err != nil {
+	if err := os.Chmod(path, mode); err != nil {
 		listener.Close()
 		return nil, err
 	}
 	return listener, nil
 }
 
-// Server listens on a Unix domain socket and handles control connections.
+// Server listens on a Unix domain socket (and optionally a TCP address)
+// and handles control connections.
 type Server struct {
 	services *service.ServiceSet
 	listener net.Listener
 	sockPath string
 	logger   *logging.Logger
 	conns    map[*Connection]struct{}
+	handles  *HandleRegistry
 	mu       sync.Mutex
 	ctx      context.Context
 	cancel   context.CancelFunc
 	wg       sync.WaitGroup
 
-	// acceptWg tracks only the current acceptLoop goroutine so that
+	// acceptWg tracks only the current Unix-socket acceptLoop goroutine so that
 	// Reopen() can wait for the old loop to exit before starting a new one.
 	acceptWg sync.WaitGroup
 
@@ -52,6 +56,14 @@ type Server struct {
 	// Replaced on each Reopen() call.
 	stopAccept chan struct{}
 
+	// ready is closed once Start has bound the Unix socket; see WaitReady.
+	ready     chan struct{}
+	readyOnce sync.Once
+
+	// Optional TCP listener (StartTCP). Not affected by Reopen.
+	tcpListener net.Listener
+	tcpStop     chan struct{}
+
 	// ShutdownFunc is called when a shutdown command is received.
 	ShutdownFunc func(service.ShutdownType)
 
@@ -87,42 +99,124 @@ type Server struct {
 	// (or a store built with an empty dir) is a valid no-op — every
 	// call site invokes it unconditionally.
 	Pins *persist.PinStore
+
+	// RateLimitCapacity and RateLimitRefill size each connection's
+	// command token bucket (burst size, tokens per second). Read when a
+	// connection is accepted; a capacity of 0 disables rate limiting.
+	RateLimitCapacity int
+	RateLimitRefill   float64
+
+	// Access grants control to Unix-socket peers other than root and
+	// the daemon's own user; nil admits nobody else. Read when a
+	// connection is accepted and when the socket is bound: a policy
+	// that admits others makes the socket file mode 0666, leaving the
+	// SO_PEERCRED check as the boundary.
+	Access *AccessPolicy
 }
 
 // NewServer creates a new control socket server.
 func NewServer(services *service.ServiceSet, sockPath string, logger *logging.Logger) *Server {
-	return &Server{
+	s := &Server{
 		services: services,
 		sockPath: sockPath,
 		logger:   logger,
 		conns:    make(map[*Connection]struct{}),
+		handles:  newHandleRegistry(),
+		ready:    make(chan struct{}),
+
+		RateLimitCapacity: DefaultRateLimitCapacity,
+		RateLimitRefill:   DefaultRateLimitRefill,
+	}
+	if services != nil {
+		services.OnServiceRemoved = s.handles.Revoke
 	}
+	return s
 }
 
-// Start binds the Unix socket and begins accepting connections.
+// socketFileMode returns the mode of the control socket file.
+func (s *Server) socketFileMode() os.FileMode {
+	if s.Access.admitsOthers() {
+		return 0666
+	}
+	return 0600
+}
+
+// Start binds the Unix socket and begins accepting connections. The
+// socket is bound when Start returns, so a client may connect right
+// away; only the accept loop runs in the background.
 func (s *Server) Start(ctx context.Context) error {
 	// Remove stale socket file if it exists
 	if err := os.Remove(s.sockPath); err != nil && !os.IsNotExist(err) {
 		return err
 	}
 
-	listener, err := listenUnixRestricted(s.sockPath)
+	listener, err := listenUnixRestricted(s.sockPath, s.socketFileMode())
 	if err != nil {
 		return err
 	}
 
 	s.listener = listener
 	s.ctx, s.cancel = context.WithCancel(ctx)
-	s.stopAccept = make(chan struct{})
+	stopCh := make(chan struct{})
+	s.mu.Lock()
+	s.stopAccept = stopCh
+	s.mu.Unlock()
 
 	s.wg.Add(1)
 	s.acceptWg.Add(1)
-	go s.acceptLoop(s.listener, s.stopAccept)
+	go func() {
+		defer s.acceptWg.Done()
+		s.acceptLoop(listener, stopCh)
+	}()
 
+	s.readyOnce.Do(func() { close(s.ready) })
 	s.logger.Info("Control socket listening on %s", s.sockPath)
 	return nil
 }
 
+// WaitReady blocks until Start has bound the control socket, or ctx is
+// done. It is for callers that run Start on another goroutine; after a
+// synchronous Start it returns immediately.
+func (s *Server) WaitReady(ctx context.Context) error {
+	select {
+	case <-s.ready:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// StartTCP additionally accepts control connections on a TCP address,
+// for managing remote systems. With a non-nil tlsConfig the listener is
+// TLS-wrapped; a client is only authorized once it has presented a
+// certificate verified against tlsConfig.ClientCAs, so a plain TCP
+// listener (tlsConfig nil) accepts connections but rejects every
+// command. Call after Start; Stop closes it.
+func (s *Server) StartTCP(ctx context.Context, addr string, tlsConfig *tls.Config) error {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	if tlsConfig != nil {
+		listener = tls.NewListener(listener, tlsConfig)
+	}
+
+	if s.ctx == nil {
+		s.ctx, s.cancel = context.WithCancel(ctx)
+	}
+	stopCh := make(chan struct{})
+	s.mu.Lock()
+	s.tcpListener = listener
+	s.tcpStop = stopCh
+	s.mu.Unlock()
+
+	s.wg.Add(1)
+	go s.acceptLoop(listener, stopCh)
+
+	s.logger.Info("Control socket listening on tcp %s", listener.Addr())
+	return nil
+}
+
 // Stop closes the listener and all active connections.
 func (s *Server) Stop() error {
 	if s.cancel != nil {
@@ -135,12 +229,21 @@ func (s *Server) Stop() error {
 		close(s.stopAccept)
 		s.stopAccept = nil
 	}
+	if s.tcpStop != nil {
+		close(s.tcpStop)
+		s.tcpStop = nil
+	}
+	tcpListener := s.tcpListener
+	s.tcpListener = nil
 	s.mu.Unlock()
 
 	var err error
 	if s.listener != nil {
 		err = s.listener.Close()
 	}
+	if tcpListener != nil {
+		tcpListener.Close()
+	}
 
 	// Collect connections under lock, close outside to avoid holding lock during I/O
 	s.mu.Lock()
@@ -164,7 +267,6 @@ func (s *Server) Stop() error {
 
 func (s *Server) acceptLoop(listener net.Listener, stopCh chan struct{}) {
 	defer s.wg.Done()
-	defer s.acceptWg.Done()
 
 	var acceptDelay time.Duration
 	const maxAcceptDelay = 1 * time.Second
@@ -240,7 +342,7 @@ func (s *Server) Reopen() error {
 	// Remove stale socket file
 	os.Remove(s.sockPath)
 
-	listener, err := listenUnixRestricted(s.sockPath)
+	listener, err := listenUnixRestricted(s.sockPath, s.socketFileMode())
 	if err != nil {
 		return err
 	}
@@ -253,7 +355,10 @@ func (s *Server) Reopen() error {
 
 	s.acceptWg.Add(1)
 	s.wg.Add(1)
-	go s.acceptLoop(listener, stopCh)
+	go func() {
+		defer s.acceptWg.Done()
+		s.acceptLoop(listener, stopCh)
+	}()
 
 	s.logger.Info("Control socket re-opened on %s", s.sockPath)
 	return nil
@@ -0,0 +1,94 @@
+package control
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"os"
+	"time"
+)
+
+// tlsHandshakeTimeout bounds how long an accepted TCP client may take
+// to complete the TLS handshake before the connection is dropped.
+const tlsHandshakeTimeout = 10 * time.Second
+
+// loadCertPool reads a PEM bundle of CA certificates.
+func loadCertPool(path string) (*x509.CertPool, error) {
+	pem, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("%s: no certificates found", path)
+	}
+	return pool, nil
+}
+
+// ServerTLSConfig builds the TLS configuration for the TCP control
+// listener. Clients must present a certificate signed by the CA in
+// caFile: a TCP peer has no SO_PEERCRED, so the verified certificate is
+// the only thing that authorizes it.
+func ServerTLSConfig(certFile, keyFile, caFile string) (*tls.Config, error) {
+	if certFile == "" || keyFile == "" || caFile == "" {
+		return nil, fmt.Errorf("TLS needs a certificate, key and client CA")
+	}
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, err
+	}
+	pool, err := loadCertPool(caFile)
+	if err != nil {
+		return nil, err
+	}
+	return &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		ClientCAs:    pool,
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		MinVersion:   tls.VersionTLS12,
+	}, nil
+}
+
+// ClientTLSConfig builds the TLS configuration slinitctl uses to reach
+// a TCP control listener at addr (host:port). The server certificate is
+// verified against caFile and must be valid for the host part of addr.
+func ClientTLSConfig(addr, certFile, keyFile, caFile string) (*tls.Config, error) {
+	if certFile == "" || keyFile == "" || caFile == "" {
+		return nil, fmt.Errorf("TLS needs a certificate, key and CA")
+	}
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, err
+	}
+	pool, err := loadCertPool(caFile)
+	if err != nil {
+		return nil, err
+	}
+	return &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		RootCAs:      pool,
+		ServerName:   host,
+		MinVersion:   tls.VersionTLS12,
+	}, nil
+}
+
+// authorizeTLS completes the handshake on a TLS control connection and
+// authorizes the peer iff it presented a certificate that verified
+// against the server's client CA. Returns false if the handshake fails.
+func (c *Connection) authorizeTLS(tc *tls.Conn) bool {
+	tc.SetDeadline(time.Now().Add(tlsHandshakeTimeout))
+	err := tc.Handshake()
+	tc.SetDeadline(time.Time{})
+	if err != nil {
+		c.server.logger.Warn("Control connection from %s: TLS handshake failed: %v",
+			tc.RemoteAddr(), err)
+		return false
+	}
+	c.peerAuthorized = len(tc.ConnectionState().VerifiedChains) > 0
+	return true
+}
@@ -0,0 +1,190 @@
+package control
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// testCA issues certificates for the TLS tests.
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pem  []byte
+}
+
+func newTestCA(t *testing.T, name string) *testCA {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, _ := x509.ParseCertificate(der)
+	return &testCA{cert: cert, key: key,
+		pem: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})}
+}
+
+// issue writes a leaf certificate and key signed by ca into dir and
+// returns their paths.
+func (ca *testCA) issue(t *testing.T, dir, name string, usage x509.ExtKeyUsage) (string, string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1)},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, ca.cert, &key.PublicKey, ca.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certPath := filepath.Join(dir, name+".crt")
+	keyPath := filepath.Join(dir, name+".key")
+	os.WriteFile(certPath, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600)
+	os.WriteFile(keyPath, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600)
+	return certPath, keyPath
+}
+
+func (ca *testCA) write(t *testing.T, dir, name string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, ca.pem, 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func queryInfo(t *testing.T, conn net.Conn) map[string]string {
+	t.Helper()
+	if err := WritePacket(conn, CmdQueryInfo, nil); err != nil {
+		t.Fatal(err)
+	}
+	rply, payload, err := ReadPacket(conn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rply != RplyInfo {
+		t.Fatalf("expected RplyInfo, got %d", rply)
+	}
+	pairs, err := DecodeInfo(payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	info := make(map[string]string, len(pairs))
+	for _, kv := range pairs {
+		info[kv[0]] = kv[1]
+	}
+	return info
+}
+
+func TestQueryInfoUnix(t *testing.T) {
+	server, sockPath := setupTestServer(t)
+	defer server.Stop()
+
+	conn := connectTest(t, sockPath)
+	defer conn.Close()
+
+	if mode := queryInfo(t, conn)["socket-mode"]; mode != "unix" {
+		t.Errorf("socket-mode = %q, want unix", mode)
+	}
+}
+
+func TestStartTCPMutualTLS(t *testing.T) {
+	server, _ := setupTestServer(t)
+	defer server.Stop()
+
+	dir := t.TempDir()
+	ca := newTestCA(t, "slinit-ca")
+	caPath := ca.write(t, dir, "ca.pem")
+	srvCert, srvKey := ca.issue(t, dir, "server", x509.ExtKeyUsageServerAuth)
+	cliCert, cliKey := ca.issue(t, dir, "client", x509.ExtKeyUsageClientAuth)
+
+	srvCfg, err := ServerTLSConfig(srvCert, srvKey, caPath)
+	if err != nil {
+		t.Fatalf("ServerTLSConfig: %v", err)
+	}
+	if err := server.StartTCP(context.Background(), "127.0.0.1:0", srvCfg); err != nil {
+		t.Fatalf("StartTCP: %v", err)
+	}
+	addr := server.tcpListener.Addr().String()
+
+	t.Run("trusted client", func(t *testing.T) {
+		cfg, err := ClientTLSConfig(addr, cliCert, cliKey, caPath)
+		if err != nil {
+			t.Fatalf("ClientTLSConfig: %v", err)
+		}
+		conn, err := tls.Dial("tcp", addr, cfg)
+		if err != nil {
+			t.Fatalf("dial: %v", err)
+		}
+		defer conn.Close()
+
+		info := queryInfo(t, conn)
+		if info["socket-mode"] != "tcp" {
+			t.Errorf("socket-mode = %q, want tcp", info["socket-mode"])
+		}
+	})
+
+	t.Run("untrusted client", func(t *testing.T) {
+		rogue := newTestCA(t, "rogue-ca")
+		rogueCert, rogueKey := rogue.issue(t, dir, "rogue", x509.ExtKeyUsageClientAuth)
+		cfg, err := ClientTLSConfig(addr, rogueCert, rogueKey, caPath)
+		if err != nil {
+			t.Fatalf("ClientTLSConfig: %v", err)
+		}
+		conn, err := tls.Dial("tcp", addr, cfg)
+		if err != nil {
+			return // rejected during the handshake (TLS 1.2)
+		}
+		defer conn.Close()
+		// TLS 1.3 reports the client-certificate rejection on first read.
+		conn.SetDeadline(time.Now().Add(5 * time.Second))
+		if err := WritePacket(conn, CmdQueryInfo, nil); err != nil {
+			return
+		}
+		if _, _, err := ReadPacket(conn); err == nil {
+			t.Fatal("untrusted client got a reply")
+		}
+	})
+}
+
+func TestServerTLSConfigRequiresCA(t *testing.T) {
+	if _, err := ServerTLSConfig("cert.pem", "key.pem", ""); err == nil {
+		t.Error("expected an error without a client CA")
+	}
+}
@@ -11,7 +11,6 @@ import (
 	"time"
 
 	"github.com/sunlightlinux/slinit/pkg/logging"
-	"github.com/sunlightlinux/slinit/pkg/process"
 	"github.com/sunlightlinux/slinit/pkg/service"
 )
 
@@ -39,13 +38,27 @@ type EventLoop struct {
 	// the field is only written at startup, before any goroutine reads it.
 	emergencyTimeout time.Duration
 
+	// Per-service shutdown deadlines (--service-stop-timeout), by
+	// service name; set before Run(). stopDeadlinesCancel disarms the
+	// outstanding ones once shutdown is over; guarded by mu.
+	stopDeadlines       map[string]time.Duration
+	stopDeadlinesCancel context.CancelFunc
+
+	// SIGPWR handling (see power.go). powerFailTimer is the pending
+	// poweroff after a power failure, guarded by mu; when it fires it
+	// signals powerFailCh so the shutdown starts on the loop goroutine.
+	powerStatusFile string
+	powerFailGrace  time.Duration
+	powerFailTimer  *time.Timer
+	powerFailCh     chan struct{}
+
 	// Atomic counter for repeated shutdown signals (escalation).
 	shutdownSignals atomic.Int32
 
-	// PID 1 mode enables boot failure detection and orphan reaping
+	// PID 1 mode enables boot failure detection
 	isPID1 bool
 
-	// Container mode: SIGINT/SIGTERM trigger graceful halt instead of reboot
+	// Container mode: SIGTERM triggers poweroff, SIGINT halt, instead of reboot
 	isContainer bool
 
 	// Channel for forcing event loop exit (emergency timeout)
@@ -60,6 +73,25 @@ type EventLoop struct {
 	// OnReopenSocket is called on SIGUSR1 to reopen the control socket
 	OnReopenSocket func()
 
+	// OnReopenLog is called on SIGHUP to reconnect the syslog backend
+	// and reopen service logfiles
+	OnReopenLog func()
+
+	// OnReloadConfig, when set, is called on SIGHUP after OnReopenLog to
+	// reload the service descriptions (--sighup-action=reload). When nil
+	// SIGHUP does nothing more.
+	OnReloadConfig func()
+
+	// OnDumpDiagnostics, when set, is called on SIGUSR2 to write a
+	// diagnostic dump of the service graph. Only outside PID 1 and
+	// container mode: there SIGUSR2 stays busybox poweroff.
+	OnDumpDiagnostics func()
+
+	// SignalMapping overrides the shutdown type SIGTERM, SIGINT and
+	// SIGQUIT initiate (--sigterm-action and friends); a signal not in
+	// it keeps its default. Set before Run().
+	SignalMapping map[syscall.Signal]service.ShutdownType
+
 	// SignalShutdownGate, when set, is consulted before every signal-driven
 	// shutdown attempt (CAD, SIGTERM/SIGINT to PID 1, RT signals, etc.).
 	// Returning false aborts the shutdown; the signal is logged and
@@ -80,21 +112,23 @@ type EventLoop struct {
 // New creates a new EventLoop.
 func New(services *service.ServiceSet, logger *logging.Logger) *EventLoop {
 	return &EventLoop{
-		services:    services,
-		logger:      logger,
-		forceExitCh: make(chan struct{}, 1),
+		services:       services,
+		logger:         logger,
+		forceExitCh:    make(chan struct{}, 1),
+		powerFailGrace: DefaultPowerFailGrace,
+		powerFailCh:    make(chan struct{}, 1),
 	}
 }
 
 // SetPID1Mode enables PID 1 specific behavior:
 // - Boot failure detection when all services stop without explicit shutdown
-// - Orphan process reaping on SIGCHLD
+// Orphans are reaped by the child reaper (see StartReaper).
 func (el *EventLoop) SetPID1Mode(v bool) {
 	el.isPID1 = v
 }
 
 // SetContainerMode enables container-specific behavior:
-// - SIGINT/SIGTERM trigger graceful halt instead of reboot
+// - SIGTERM triggers a clean poweroff and SIGINT a halt, instead of reboot
 // - Boot failure detection (same as PID 1)
 func (el *EventLoop) SetContainerMode(v bool) {
 	el.isContainer = v
@@ -109,6 +143,14 @@ func (el *EventLoop) SetEmergencyTimeout(d time.Duration) {
 	el.emergencyTimeout = d
 }
 
+// SetServiceStopDeadlines gives the named services a hard stop deadline
+// during shutdown: a service still running when its deadline passes is
+// sent SIGKILL, independently of the emergency timeout. Must be called
+// before Run().
+func (el *EventLoop) SetServiceStopDeadlines(deadlines map[string]time.Duration) {
+	el.stopDeadlines = deadlines
+}
+
 // effectiveEmergencyTimeout returns the configured emergency timeout,
 // falling back to the compile-time default when unset or non-positive.
 func (el *EventLoop) effectiveEmergencyTimeout() time.Duration {
@@ -158,6 +200,13 @@ func (el *EventLoop) Run(ctx context.Context) error {
 				}
 			}
 
+		case <-el.powerFailCh:
+			if el.powerFailExpired() && el.services.CountActiveServices() == 0 {
+				el.logger.Info("All services stopped, exiting")
+				el.cancelEmergencyTimer()
+				return nil
+			}
+
 		case <-inactiveCh:
 			if el.checkInactive() {
 				el.cancelEmergencyTimer()
@@ -229,9 +278,14 @@ func (el *EventLoop) handleSignal(sig os.Signal) bool {
 		if !el.gateAllows("SIGTERM") {
 			return false
 		}
+		if el.mappedShutdown(sysSignal, "SIGTERM") {
+			return true
+		}
 		if el.isContainer {
-			el.logger.Notice("Received SIGTERM, initiating graceful halt (container mode)")
-			el.initiateShutdown(service.ShutdownHalt)
+			// A container runtime stopping us (docker stop, podman
+			// stop) is the container's power button.
+			el.logger.Notice("Received SIGTERM, initiating poweroff (container mode)")
+			el.initiateShutdown(service.ShutdownPoweroff)
 		} else if el.isPID1 {
 			el.logger.Notice("Received SIGTERM, initiating reboot")
 			el.initiateShutdown(service.ShutdownReboot)
@@ -248,6 +302,9 @@ func (el *EventLoop) handleSignal(sig os.Signal) bool {
 		if !el.gateAllows("SIGINT") {
 			return false
 		}
+		if el.mappedShutdown(sysSignal, "SIGINT") {
+			return true
+		}
 		if el.isContainer {
 			el.logger.Notice("Received SIGINT, initiating graceful halt (container mode)")
 			el.initiateShutdown(service.ShutdownHalt)
@@ -267,6 +324,9 @@ func (el *EventLoop) handleSignal(sig os.Signal) bool {
 		if !el.gateAllows("SIGQUIT") {
 			return false
 		}
+		if el.mappedShutdown(sysSignal, "SIGQUIT") {
+			return true
+		}
 		el.logger.Notice("Received SIGQUIT, initiating poweroff")
 		el.initiateShutdown(service.ShutdownPoweroff)
 		return true
@@ -279,6 +339,11 @@ func (el *EventLoop) handleSignal(sig os.Signal) bool {
 		return false
 
 	case syscall.SIGUSR2:
+		if !el.isPID1 && el.OnDumpDiagnostics != nil {
+			el.logger.Notice("Received SIGUSR2, dumping diagnostics")
+			el.OnDumpDiagnostics()
+			return false
+		}
 		if shutting {
 			return el.escalateShutdown("SIGUSR2")
 		}
@@ -290,48 +355,23 @@ func (el *EventLoop) handleSignal(sig os.Signal) bool {
 		return true
 
 	case syscall.SIGHUP:
-		el.logger.Notice("Received SIGHUP")
+		el.logger.Notice("Received SIGHUP, reopening log")
+		if el.OnReopenLog != nil {
+			el.OnReopenLog()
+		}
+		if el.OnReloadConfig != nil && !shutting {
+			el.logger.Notice("Reloading service descriptions (SIGHUP)")
+			el.OnReloadConfig()
+		}
 		return false
 
-	case syscall.SIGCHLD:
-		el.reapOrphans()
-		return false
+	case sigPower:
+		return el.handlePowerSignal()
 	}
 
 	return false
 }
 
-// reapOrphans collects zombie orphan processes (PID 1 only).
-//
-// Each managed service child gets its own process group (Setpgid), and
-// group members are reaped directly in handleChildExit via
-// KillProcessGroup(-pgid). This loop catches remaining orphans:
-// double-forked daemons, setsid'd children, etc.
-//
-// Race resolution: Wait4(-1, ...) also collects any managed child that
-// exits at the same moment. When that happens process.DefaultExitRouter
-// routes the real WaitStatus to the per-service wait goroutine; without
-// the router, cmd.Wait() would observe ECHILD and silently report
-// status=0, losing the real exit code in finish-command, is-failed,
-// and chain-to gating.
-func (el *EventLoop) reapOrphans() {
-	if !el.isPID1 {
-		return
-	}
-	for {
-		var status syscall.WaitStatus
-		pid, err := syscall.Wait4(-1, &status, syscall.WNOHANG, nil)
-		if pid <= 0 || err != nil {
-			break
-		}
-		if process.DefaultExitRouter.Route(pid, status) {
-			el.logger.Debug("Routed reaped pid %d to managed-child waiter (status: %v)", pid, status)
-			continue
-		}
-		el.logger.Debug("Reaped orphan process %d (status: %v)", pid, status)
-	}
-}
-
 // gateAllows consults el.SignalShutdownGate and returns true if the
 // shutdown should proceed. With no gate installed it is a no-op that
 // always allows. When the gate denies, it logs a notice so the operator
@@ -476,6 +516,8 @@ func (el *EventLoop) initiateShutdown(shutdownType service.ShutdownType) {
 		default:
 		}
 	})
+	deadlineCtx, cancelDeadlines := context.WithCancel(context.Background())
+	el.stopDeadlinesCancel = cancelDeadlines
 	// Release mutex before calling StopAllServices to avoid potential
 	// deadlock if service state changes try to signal back to the event loop.
 	el.mu.Unlock()
@@ -487,7 +529,14 @@ func (el *EventLoop) initiateShutdown(shutdownType service.ShutdownType) {
 		el.OnPreShutdown(shutdownType)
 	}
 
-	el.services.StopAllServices(shutdownType)
+	// Services with notify-shutdown-signal are told first; the
+	// deadlines are armed once the stop has actually been issued.
+	deadlines := el.stopDeadlines
+	services.GracefulShutdownWithCallback(shutdownType, 0, func() {
+		if len(deadlines) > 0 {
+			services.ArmStopDeadlines(deadlineCtx, deadlines)
+		}
+	})
 
 	// Start periodic reporting of blocking services
 	el.startShutdownReporter()
@@ -502,6 +551,10 @@ func (el *EventLoop) cancelEmergencyTimer() {
 		el.emergencyTimer.Stop()
 		el.emergencyTimer = nil
 	}
+	if el.stopDeadlinesCancel != nil {
+		el.stopDeadlinesCancel()
+		el.stopDeadlinesCancel = nil
+	}
 }
 
 // resetEmergencyTimer replaces the emergency timer with a shorter duration.
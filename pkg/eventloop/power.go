@@ -0,0 +1,173 @@
+package eventloop
+
+import (
+	"os"
+	"strings"
+	"time"
+
+	"github.com/sunlightlinux/slinit/pkg/service"
+)
+
+// Defaults for SIGPWR handling, matching sysvinit's powerfail protocol:
+// a UPS daemon writes the line state to the status file, then signals
+// PID 1.
+const (
+	DefaultPowerStatusFile = "/etc/powerstatus"
+	DefaultPowerFailGrace  = 60 * time.Second
+)
+
+// powerFailService is started (or triggered) when power is lost, so it
+// can drain work before the grace period runs out.
+const powerFailService = "power-fail"
+
+// SetPowerStatusFile overrides the file read on SIGPWR (default
+// DefaultPowerStatusFile). Must be called before Run().
+func (el *EventLoop) SetPowerStatusFile(path string) {
+	el.powerStatusFile = path
+}
+
+// SetPowerFailGrace sets how long services keep running after a power
+// failure before the poweroff begins. Zero powers off immediately.
+// Must be called before Run().
+func (el *EventLoop) SetPowerFailGrace(d time.Duration) {
+	el.powerFailGrace = d
+}
+
+// readPowerStatus returns the line state from the power status file.
+// As in sysvinit, a missing or unreadable file counts as FAIL: a UPS
+// daemon that signals without writing a status means trouble.
+func (el *EventLoop) readPowerStatus() string {
+	path := el.powerStatusFile
+	if path == "" {
+		path = DefaultPowerStatusFile
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		el.logger.Notice("SIGPWR: cannot read %s (%v), assuming FAIL", path, err)
+		return "FAIL"
+	}
+	return strings.ToUpper(strings.TrimSpace(string(data)))
+}
+
+// handlePowerSignal reacts to SIGPWR according to the power status file:
+//
+//	OK   — power is back: cancel a pending power-fail shutdown
+//	LOW  — battery low: power off now
+//	FAIL — power lost: start the power-fail service and power off
+//	       once the grace period expires
+//
+// Returns true if shutdown was initiated.
+func (el *EventLoop) handlePowerSignal() bool {
+	status := el.readPowerStatus()
+
+	if status == "OK" {
+		el.logger.Notice("Received SIGPWR, power restored")
+		el.cancelPowerFail()
+		return false
+	}
+
+	if el.isShuttingDown() {
+		// UPS daemons may repeat SIGPWR while on battery; that must
+		// not count as an operator escalating a stuck shutdown.
+		el.logger.Notice("Received SIGPWR (%s), already shutting down", status)
+		return false
+	}
+	if !el.gateAllows("SIGPWR") {
+		return false
+	}
+
+	if status == "LOW" {
+		el.logger.Notice("Received SIGPWR, battery low, initiating poweroff")
+		el.stopPowerFailTimer()
+		el.initiateShutdown(service.ShutdownPoweroff)
+		return true
+	}
+
+	el.startPowerFailService()
+
+	if el.powerFailGrace <= 0 {
+		el.logger.Notice("Received SIGPWR, power failure, initiating poweroff")
+		el.initiateShutdown(service.ShutdownPoweroff)
+		return true
+	}
+
+	el.mu.Lock()
+	defer el.mu.Unlock()
+	if el.powerFailTimer != nil {
+		// Repeated FAIL while already counting down: keep the deadline.
+		return false
+	}
+	el.logger.Notice("Received SIGPWR, power failure, powering off in %v", el.powerFailGrace)
+	ch := el.powerFailCh
+	el.powerFailTimer = time.AfterFunc(el.powerFailGrace, func() {
+		select {
+		case ch <- struct{}{}:
+		default:
+		}
+	})
+	return false
+}
+
+// powerFailExpired runs on the event loop goroutine when the grace
+// period timer fires. Returns true if shutdown was initiated; false if
+// power came back after the timer had already fired.
+func (el *EventLoop) powerFailExpired() bool {
+	if !el.stopPowerFailTimer() {
+		return false
+	}
+	el.logger.Notice("Power-fail grace period expired, initiating poweroff")
+	el.initiateShutdown(service.ShutdownPoweroff)
+	return true
+}
+
+// stopPowerFailTimer stops a pending power-fail countdown. Returns
+// false if none was pending (or it was already cancelled).
+func (el *EventLoop) stopPowerFailTimer() bool {
+	el.mu.Lock()
+	defer el.mu.Unlock()
+	if el.powerFailTimer == nil {
+		return false
+	}
+	el.powerFailTimer.Stop()
+	el.powerFailTimer = nil
+	// Drop a tick from a timer that fired before Stop, so it cannot
+	// cut short a later countdown.
+	select {
+	case <-el.powerFailCh:
+	default:
+	}
+	return true
+}
+
+// cancelPowerFail stops a pending power-fail countdown and releases the
+// power-fail service. A shutdown already in progress is not undone.
+func (el *EventLoop) cancelPowerFail() {
+	if !el.stopPowerFailTimer() {
+		return
+	}
+	el.logger.Notice("Power-fail shutdown cancelled")
+
+	svc := el.services.FindService(powerFailService, false)
+	if svc == nil {
+		return
+	}
+	if ts, ok := svc.(*service.TriggeredService); ok {
+		ts.SetTrigger(false)
+	}
+	el.services.StopService(svc)
+}
+
+// startPowerFailService starts the power-fail service, if one can be
+// loaded. A triggered service gets its trigger set first so it reaches
+// STARTED instead of waiting for an external trigger.
+func (el *EventLoop) startPowerFailService() {
+	svc, err := el.services.LoadService(powerFailService)
+	if err != nil {
+		el.logger.Debug("SIGPWR: no %s service: %v", powerFailService, err)
+		return
+	}
+	if ts, ok := svc.(*service.TriggeredService); ok {
+		ts.SetTrigger(true)
+	}
+	el.services.StartService(svc)
+}
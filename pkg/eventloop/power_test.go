@@ -0,0 +1,164 @@
+//go:build linux
+
+package eventloop
+
+import (
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/sunlightlinux/slinit/pkg/logging"
+	"github.com/sunlightlinux/slinit/pkg/service"
+)
+
+// newPowerTestLoop returns a loop whose power status file holds status
+// (no file at all when status is empty) and a set with a power-fail
+// internal service.
+func newPowerTestLoop(t *testing.T, status string) (*EventLoop, *service.ServiceSet, service.Service) {
+	t.Helper()
+	logger := logging.New(logging.LevelDebug)
+	set := service.NewServiceSet(logger)
+	pf := service.NewInternalService(set, "power-fail")
+	set.AddService(pf)
+
+	el := New(set, logger)
+	path := filepath.Join(t.TempDir(), "powerstatus")
+	if status != "" {
+		if err := os.WriteFile(path, []byte(status+"\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	el.SetPowerStatusFile(path)
+	t.Cleanup(func() {
+		el.stopPowerFailTimer()
+		el.cancelEmergencyTimer()
+	})
+	return el, set, pf
+}
+
+func (el *EventLoop) shutdownStarted() bool {
+	el.mu.Lock()
+	defer el.mu.Unlock()
+	return el.shutdownInitiated
+}
+
+func TestSIGPWR_FailGraceThenPoweroff(t *testing.T) {
+	el, _, pf := newPowerTestLoop(t, "FAIL")
+	el.SetPowerFailGrace(50 * time.Millisecond)
+
+	if el.handleSignal(syscall.SIGPWR) {
+		t.Error("shutdown should wait for the grace period")
+	}
+	if pf.State() != service.StateStarted {
+		t.Errorf("power-fail service state = %v, want STARTED", pf.State())
+	}
+	if el.shutdownStarted() {
+		t.Fatal("shutdown started before the grace period expired")
+	}
+
+	select {
+	case <-el.powerFailCh:
+	case <-time.After(2 * time.Second):
+		t.Fatal("grace period timer did not fire")
+	}
+	if !el.powerFailExpired() {
+		t.Fatal("shutdown not started after the grace period")
+	}
+	if st := el.GetShutdownType(); st != service.ShutdownPoweroff {
+		t.Errorf("shutdown type = %v, want poweroff", st)
+	}
+}
+
+func TestSIGPWR_OKCancelsPendingShutdown(t *testing.T) {
+	el, _, pf := newPowerTestLoop(t, "FAIL")
+	el.SetPowerFailGrace(100 * time.Millisecond)
+
+	el.handleSignal(syscall.SIGPWR)
+	if pf.State() != service.StateStarted {
+		t.Fatalf("power-fail service state = %v, want STARTED", pf.State())
+	}
+
+	if err := os.WriteFile(el.powerStatusFile, []byte("OK\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if el.handleSignal(syscall.SIGPWR) {
+		t.Error("OK should not initiate shutdown")
+	}
+	if pf.State() != service.StateStopped {
+		t.Errorf("power-fail service state = %v, want STOPPED", pf.State())
+	}
+
+	time.Sleep(200 * time.Millisecond)
+	select {
+	case <-el.powerFailCh:
+		t.Error("cancelled grace period timer still fired")
+	default:
+	}
+	if el.powerFailExpired() || el.shutdownStarted() {
+		t.Error("cancelled power-fail shutdown went ahead")
+	}
+}
+
+func TestSIGPWR_LowPowersOffNow(t *testing.T) {
+	el, _, _ := newPowerTestLoop(t, "LOW")
+
+	if !el.handleSignal(syscall.SIGPWR) {
+		t.Error("LOW should initiate shutdown immediately")
+	}
+	if st := el.GetShutdownType(); st != service.ShutdownPoweroff {
+		t.Errorf("shutdown type = %v, want poweroff", st)
+	}
+}
+
+func TestSIGPWR_MissingFileIsFail(t *testing.T) {
+	el, _, pf := newPowerTestLoop(t, "")
+	el.SetPowerFailGrace(0)
+
+	if !el.handleSignal(syscall.SIGPWR) {
+		t.Error("missing status file with no grace should power off")
+	}
+	if el.GetShutdownType() != service.ShutdownPoweroff {
+		t.Errorf("shutdown type = %v, want poweroff", el.GetShutdownType())
+	}
+	// power-fail was started before StopAllServices took it down again.
+	if pf.Record().StopReason() == service.ReasonFailed {
+		t.Error("power-fail service should not have failed")
+	}
+}
+
+func TestSIGPWR_TriggersTriggeredService(t *testing.T) {
+	logger := logging.New(logging.LevelDebug)
+	set := service.NewServiceSet(logger)
+	pf := service.NewTriggeredService(set, "power-fail")
+	set.AddService(pf)
+
+	el := New(set, logger)
+	path := filepath.Join(t.TempDir(), "powerstatus")
+	os.WriteFile(path, []byte("FAIL\n"), 0644)
+	el.SetPowerStatusFile(path)
+	el.SetPowerFailGrace(time.Hour)
+	defer el.stopPowerFailTimer()
+
+	el.handleSignal(syscall.SIGPWR)
+	if !pf.IsTriggered() {
+		t.Error("power-fail trigger not set")
+	}
+	if pf.State() != service.StateStarted {
+		t.Errorf("power-fail service state = %v, want STARTED", pf.State())
+	}
+}
+
+func TestSIGPWR_GateDenies(t *testing.T) {
+	el, _, pf := newPowerTestLoop(t, "FAIL")
+	el.SetPowerFailGrace(0)
+	el.SignalShutdownGate = func(string) bool { return false }
+
+	if el.handleSignal(syscall.SIGPWR) {
+		t.Error("gate should block the power-fail shutdown")
+	}
+	if pf.State() != service.StateStopped {
+		t.Errorf("power-fail service state = %v, want STOPPED", pf.State())
+	}
+}
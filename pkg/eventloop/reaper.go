@@ -0,0 +1,32 @@
+package eventloop
+
+import (
+	"syscall"
+
+	"github.com/sunlightlinux/slinit/pkg/logging"
+	"github.com/sunlightlinux/slinit/pkg/process"
+	"github.com/sunlightlinux/slinit/pkg/service"
+)
+
+// StartReaper starts process.DefaultReaper, the single owner of waitpid,
+// for PID 1 and container mode. It must run before the first service is
+// started. Managed children get their status through the watcher
+// StartProcess registers; every other reaped pid comes here.
+//
+// Adoption: an orphan that turns out to be a bgprocess service's
+// daemon (re-parented to us via PID 1 / PR_SET_CHILD_SUBREAPER) has
+// its status delivered to that service's monitor, which otherwise
+// would only notice the death by polling and lose the exit status.
+// Anything else (double-forked helpers, setsid'd grandchildren) is
+// just logged.
+func StartReaper(services *service.ServiceSet, logger *logging.Logger) {
+	process.DefaultReaper.Start(func(pid int, status syscall.WaitStatus) {
+		if svc := services.FindByPID(pid); svc != nil {
+			if rcv, ok := svc.(service.ChildExitReceiver); ok && rcv.DeliverChildExit(pid, status) {
+				logger.Debug("Delivered reaped pid %d to service '%s' (status: %v)", pid, svc.Name(), status)
+				return
+			}
+		}
+		logger.Debug("Reaped orphan process %d (status: %v)", pid, status)
+	})
+}
@@ -30,6 +30,15 @@ var (
 	sigKexec    = syscall.Signal(sigRTMin + 6)
 )
 
+// sigPower is sent by the kernel or a UPS daemon on power events; the
+// line state is read from the power status file (see power.go).
+const sigPower = syscall.SIGPWR
+
+// powerSignals returns the power event signals SetupSignals registers.
+func powerSignals() []syscall.Signal {
+	return []syscall.Signal{sigPower}
+}
+
 // extraShutdownSignals returns the RT signals that SetupSignals should
 // register with signal.Notify in addition to the classic Unix signals.
 func extraShutdownSignals() []syscall.Signal {
@@ -8,6 +8,12 @@ import (
 	"github.com/sunlightlinux/slinit/pkg/service"
 )
 
+// sigPower has no equivalent off Linux; signal 0 is never delivered.
+const sigPower = syscall.Signal(0)
+
+// powerSignals is a no-op on non-Linux platforms.
+func powerSignals() []syscall.Signal { return nil }
+
 // extraShutdownSignals is a no-op on non-Linux platforms. Real-time
 // signal numbering is Linux-specific and the systemd convention does
 // not apply elsewhere.
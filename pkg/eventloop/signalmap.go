@@ -0,0 +1,35 @@
+package eventloop
+
+import (
+	"fmt"
+	"syscall"
+
+	"github.com/sunlightlinux/slinit/pkg/service"
+)
+
+// ParseSignalAction parses the value of --sigterm-action, --sigint-action
+// or --sigquit-action: the shutdown type the signal initiates.
+func ParseSignalAction(s string) (service.ShutdownType, error) {
+	switch s {
+	case "halt":
+		return service.ShutdownHalt, nil
+	case "poweroff":
+		return service.ShutdownPoweroff, nil
+	case "reboot":
+		return service.ShutdownReboot, nil
+	}
+	return service.ShutdownNone, fmt.Errorf("unknown signal action %q (use halt/poweroff/reboot)", s)
+}
+
+// mappedShutdown initiates the shutdown SignalMapping assigns to sig, if
+// any, and reports whether it did. The caller has already dealt with
+// escalation and the shutdown gate.
+func (el *EventLoop) mappedShutdown(sig syscall.Signal, name string) bool {
+	st, ok := el.SignalMapping[sig]
+	if !ok {
+		return false
+	}
+	el.logger.Notice("Received %s, initiating %s", name, st)
+	el.initiateShutdown(st)
+	return true
+}
@@ -0,0 +1,120 @@
+package eventloop
+
+import (
+	"syscall"
+	"testing"
+
+	"github.com/sunlightlinux/slinit/pkg/logging"
+	"github.com/sunlightlinux/slinit/pkg/service"
+)
+
+func TestParseSignalAction(t *testing.T) {
+	for in, want := range map[string]service.ShutdownType{
+		"halt":     service.ShutdownHalt,
+		"poweroff": service.ShutdownPoweroff,
+		"reboot":   service.ShutdownReboot,
+	} {
+		if got, err := ParseSignalAction(in); err != nil || got != want {
+			t.Errorf("ParseSignalAction(%q) = %v, %v; want %v", in, got, err, want)
+		}
+	}
+	for _, bad := range []string{"", "kexec", "Halt"} {
+		if _, err := ParseSignalAction(bad); err == nil {
+			t.Errorf("ParseSignalAction(%q) should fail", bad)
+		}
+	}
+}
+
+func TestSignalMappingOverridesDefault(t *testing.T) {
+	logger := logging.New(logging.LevelDebug)
+	set := service.NewServiceSet(logger)
+	el := New(set, logger)
+	el.SetPID1Mode(true) // SIGTERM would default to reboot
+	el.SignalMapping = map[syscall.Signal]service.ShutdownType{
+		syscall.SIGTERM: service.ShutdownPoweroff,
+	}
+
+	if !el.handleSignal(syscall.SIGTERM) {
+		t.Fatal("handleSignal should initiate shutdown")
+	}
+	if st := el.GetShutdownType(); st != service.ShutdownPoweroff {
+		t.Errorf("shutdown type = %v, want poweroff", st)
+	}
+}
+
+func TestSignalMappingFallsBackToDefault(t *testing.T) {
+	logger := logging.New(logging.LevelDebug)
+	set := service.NewServiceSet(logger)
+	el := New(set, logger)
+	el.SignalMapping = map[syscall.Signal]service.ShutdownType{
+		syscall.SIGTERM: service.ShutdownPoweroff,
+	}
+
+	// SIGQUIT is not mapped and keeps its poweroff default; SIGINT
+	// outside PID 1 keeps halt.
+	if !el.handleSignal(syscall.SIGINT) {
+		t.Fatal("handleSignal should initiate shutdown")
+	}
+	if st := el.GetShutdownType(); st != service.ShutdownHalt {
+		t.Errorf("shutdown type = %v, want halt", st)
+	}
+}
+
+func TestSighupReloadConfig(t *testing.T) {
+	logger := logging.New(logging.LevelDebug)
+	set := service.NewServiceSet(logger)
+	el := New(set, logger)
+
+	var reopened, reloaded int
+	el.OnReopenLog = func() { reopened++ }
+	el.OnReloadConfig = func() { reloaded++ }
+
+	if el.handleSignal(syscall.SIGHUP) {
+		t.Error("SIGHUP must not initiate shutdown")
+	}
+	if reopened != 1 || reloaded != 1 {
+		t.Errorf("reopened=%d reloaded=%d, want 1 each", reopened, reloaded)
+	}
+}
+
+func TestSIGUSR2DumpsDiagnostics(t *testing.T) {
+	logger := logging.New(logging.LevelDebug)
+	set := service.NewServiceSet(logger)
+	el := New(set, logger)
+	dumps := 0
+	el.OnDumpDiagnostics = func() { dumps++ }
+
+	if el.handleSignal(syscall.SIGUSR2) {
+		t.Fatal("SIGUSR2 should not initiate shutdown outside PID 1")
+	}
+	if dumps != 1 {
+		t.Errorf("OnDumpDiagnostics called %d times, want 1", dumps)
+	}
+
+	// As PID 1, SIGUSR2 stays busybox poweroff.
+	el.SetPID1Mode(true)
+	if !el.handleSignal(syscall.SIGUSR2) {
+		t.Fatal("SIGUSR2 should initiate poweroff as PID 1")
+	}
+	if dumps != 1 {
+		t.Errorf("OnDumpDiagnostics called as PID 1")
+	}
+	if st := el.GetShutdownType(); st != service.ShutdownPoweroff {
+		t.Errorf("shutdown type = %v, want poweroff", st)
+	}
+}
+
+func TestContainerSIGTERMPowersOff(t *testing.T) {
+	logger := logging.New(logging.LevelDebug)
+	set := service.NewServiceSet(logger)
+	el := New(set, logger)
+	el.SetPID1Mode(true)
+	el.SetContainerMode(true)
+
+	if !el.handleSignal(syscall.SIGTERM) {
+		t.Fatal("handleSignal should initiate shutdown")
+	}
+	if st := el.GetShutdownType(); st != service.ShutdownPoweroff {
+		t.Errorf("shutdown type = %v, want poweroff", st)
+	}
+}
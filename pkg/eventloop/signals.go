@@ -9,9 +9,8 @@ import (
 )
 
 // SetupSignals registers OS signal handlers and returns a channel
-// that receives intercepted signals. The buffer is sized generously
-// to avoid dropping signals under heavy SIGCHLD load (PID 1 with
-// many orphan processes exiting simultaneously).
+// that receives intercepted signals. SIGCHLD is not among them: the
+// child reaper (see StartReaper) handles it.
 func SetupSignals() chan os.Signal {
 	sigCh := make(chan os.Signal, 32)
 	sigs := []os.Signal{
@@ -21,13 +20,16 @@ func SetupSignals() chan os.Signal {
 		syscall.SIGHUP,
 		syscall.SIGUSR1, // SysV: halt/reboot (busybox reboot)
 		syscall.SIGUSR2, // SysV: poweroff (busybox poweroff)
-		syscall.SIGCHLD, // For PID 1 orphan reaping
 	}
 	// Linux real-time signals (systemd-compatible shutdown triggers).
 	// On non-Linux platforms extraShutdownSignals() returns nil.
 	for _, s := range extraShutdownSignals() {
 		sigs = append(sigs, s)
 	}
+	// SIGPWR from the kernel or a UPS daemon (Linux only).
+	for _, s := range powerSignals() {
+		sigs = append(sigs, s)
+	}
 	signal.Notify(sigCh, sigs...)
 	return sigCh
 }
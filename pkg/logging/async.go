@@ -0,0 +1,136 @@
+package logging
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+)
+
+// asyncBufferSize is the number of entries an AsyncLogger holds before
+// it starts dropping.
+const asyncBufferSize = 1000
+
+// LogEntry is one formatted log record on its way to the backends.
+type LogEntry struct {
+	Level Level
+	// Console is the complete console line, newline included; empty
+	// when the entry is below the console level.
+	Console string
+	// Ring mirrors Console into the ring buffer as well.
+	Ring bool
+	// Main is the message for the main log (syslog); empty when the
+	// entry is below the main level.
+	Main string
+
+	// flushed, when non-nil, marks a Flush request rather than a record.
+	flushed chan struct{}
+}
+
+// AsyncLogger moves log writes off the caller's goroutine. Entries are
+// queued on a buffered channel and written by a single background
+// goroutine, so a slow console or a stalled syslog daemon cannot hold
+// up the event loop. When the queue is full entries are dropped, and
+// one "N log entries dropped" message is written once it drains.
+type AsyncLogger struct {
+	write   func(LogEntry)
+	ch      chan LogEntry
+	done    chan struct{}
+	dropped atomic.Uint64
+
+	closeOnce sync.Once
+	mu        sync.RWMutex // held for reading while sending, for writing by Close
+	closed    bool
+}
+
+// NewAsyncLogger starts a background writer that hands every queued
+// entry to write. Call Close to stop it.
+func NewAsyncLogger(write func(LogEntry)) *AsyncLogger {
+	a := &AsyncLogger{
+		write: write,
+		ch:    make(chan LogEntry, asyncBufferSize),
+		done:  make(chan struct{}),
+	}
+	go a.run()
+	return a
+}
+
+// Log queues e without blocking. It reports false when the entry was
+// dropped because the queue is full or the logger is closed.
+func (a *AsyncLogger) Log(e LogEntry) bool {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	if a.closed {
+		return false
+	}
+	select {
+	case a.ch <- e:
+		return true
+	default:
+		a.dropped.Add(1)
+		return false
+	}
+}
+
+// Dropped returns the number of entries dropped and not yet reported.
+func (a *AsyncLogger) Dropped() uint64 {
+	return a.dropped.Load()
+}
+
+// Flush blocks until every entry queued before the call has been
+// written. Used before the reboot syscall so the last messages reach
+// the console.
+func (a *AsyncLogger) Flush() {
+	done := make(chan struct{})
+	a.mu.RLock()
+	if a.closed {
+		a.mu.RUnlock()
+		return
+	}
+	a.ch <- LogEntry{flushed: done}
+	a.mu.RUnlock()
+	<-done
+}
+
+// Close writes the remaining entries and stops the background writer.
+// Entries logged afterwards are discarded.
+func (a *AsyncLogger) Close() {
+	a.closeOnce.Do(func() {
+		a.mu.Lock()
+		a.closed = true
+		close(a.ch)
+		a.mu.Unlock()
+	})
+	<-a.done
+}
+
+func (a *AsyncLogger) run() {
+	defer close(a.done)
+	for e := range a.ch {
+		if e.flushed != nil {
+			a.reportDropped()
+			close(e.flushed)
+			continue
+		}
+		a.write(e)
+		if len(a.ch) == 0 {
+			a.reportDropped()
+		}
+	}
+	a.reportDropped()
+}
+
+// reportDropped writes a single summary for entries lost to a full
+// queue since the last report.
+func (a *AsyncLogger) reportDropped() {
+	n := a.dropped.Swap(0)
+	if n == 0 {
+		return
+	}
+	msg := fmt.Sprintf("%d log entries dropped (log buffer full)", n)
+	a.write(LogEntry{
+		Level:   LevelWarn,
+		Console: formatConsoleLine(LevelWarn, msg),
+		Ring:    true,
+		Main:    msg,
+	})
+}
@@ -0,0 +1,101 @@
+package logging
+
+import (
+	"strings"
+	"sync"
+	"testing"
+)
+
+// TestAsyncLoggerWritesInOrder: queued entries reach the sink in the
+// order they were logged, and Flush waits for all of them.
+func TestAsyncLoggerWritesInOrder(t *testing.T) {
+	var mu sync.Mutex
+	var got []string
+	a := NewAsyncLogger(func(e LogEntry) {
+		mu.Lock()
+		got = append(got, e.Main)
+		mu.Unlock()
+	})
+	defer a.Close()
+
+	for _, m := range []string{"one", "two", "three"} {
+		if !a.Log(LogEntry{Level: LevelInfo, Main: m}) {
+			t.Fatalf("Log(%q) dropped", m)
+		}
+	}
+	a.Flush()
+
+	mu.Lock()
+	defer mu.Unlock()
+	if strings.Join(got, ",") != "one,two,three" {
+		t.Errorf("got %v, want [one two three]", got)
+	}
+}
+
+// TestAsyncLoggerReportsDrops: with the writer stalled, entries past
+// the buffer capacity are dropped, and one summary entry is written
+// once the queue drains.
+func TestAsyncLoggerReportsDrops(t *testing.T) {
+	release := make(chan struct{})
+	var mu sync.Mutex
+	var got []LogEntry
+	a := NewAsyncLogger(func(e LogEntry) {
+		<-release
+		mu.Lock()
+		got = append(got, e)
+		mu.Unlock()
+	})
+
+	// The first entry may already be held by the stalled writer, so
+	// capacity+1 fit; everything after that is dropped.
+	const extra = 5
+	for i := 0; i < asyncBufferSize+1+extra; i++ {
+		a.Log(LogEntry{Level: LevelInfo, Main: "x"})
+	}
+	dropped := a.Dropped()
+	if dropped < extra {
+		t.Fatalf("Dropped = %d, want at least %d", dropped, extra)
+	}
+	close(release)
+	a.Close()
+
+	mu.Lock()
+	defer mu.Unlock()
+	var summaries int
+	for _, e := range got {
+		if strings.Contains(e.Main, "log entries dropped") {
+			summaries++
+			if e.Level != LevelWarn {
+				t.Errorf("summary level = %v, want WARN", e.Level)
+			}
+		}
+	}
+	if summaries != 1 {
+		t.Errorf("got %d drop summaries, want 1", summaries)
+	}
+	if uint64(len(got)-1)+dropped != asyncBufferSize+1+extra {
+		t.Errorf("written %d + dropped %d != logged %d", len(got)-1, dropped, asyncBufferSize+1+extra)
+	}
+}
+
+// TestLoggerAsyncClose: a Logger with async enabled writes everything
+// to its output by the time Close returns, and drops later entries.
+func TestLoggerAsyncClose(t *testing.T) {
+	var buf safeBuffer
+	l := New(LevelInfo)
+	l.SetOutput(&buf)
+	l.EnableAsync()
+
+	l.Info("first")
+	l.ServiceStarted("svc")
+	l.Close()
+	l.Info("after close")
+
+	out := buf.String()
+	if !strings.Contains(out, "first") || !strings.Contains(out, "Service 'svc' started") {
+		t.Errorf("output missing entries: %q", out)
+	}
+	if strings.Contains(out, "after close") {
+		t.Errorf("entry logged after Close was written: %q", out)
+	}
+}
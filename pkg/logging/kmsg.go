@@ -0,0 +1,126 @@
+package logging
+
+import (
+	"fmt"
+	"io"
+	"log/syslog"
+	"os"
+	"sync"
+)
+
+// kmsgPath is the kernel log device. Swapped out by tests.
+var kmsgPath = "/dev/kmsg"
+
+// maxHeldMain bounds the number of main-log messages kept while waiting
+// for the system logger; later ones are counted and dropped.
+const maxHeldMain = 1000
+
+// KmsgBackend writes log messages to the kernel log buffer (/dev/kmsg).
+// Early in boot, before any syslog daemon is running, it is the only
+// place PID 1 can leave a record that survives to be read with dmesg(1)
+// or picked up by the logger once it starts.
+type KmsgBackend struct {
+	mu       sync.Mutex
+	facility syslog.Priority
+	w        io.WriteCloser
+}
+
+// OpenKmsg opens the kernel log for writing, tagging records with the
+// given facility (e.g. syslog.LOG_DAEMON).
+func OpenKmsg(facility syslog.Priority) (*KmsgBackend, error) {
+	f, err := os.OpenFile(kmsgPath, os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		return nil, err
+	}
+	return &KmsgBackend{facility: facility, w: f}, nil
+}
+
+// Log writes msg as one kernel log record, prefixed with its syslog
+// priority so the kernel and the syslog daemon file it correctly.
+func (b *KmsgBackend) Log(level Level, msg string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.w == nil {
+		return
+	}
+	fmt.Fprintf(b.w, "<%d>slinit: %s\n", b.facility|level.syslogPriority(), msg)
+}
+
+// Close closes the kernel log device.
+func (b *KmsgBackend) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.w != nil {
+		b.w.Close()
+		b.w = nil
+	}
+}
+
+// HoldMainLog stands in for syslog until ConnectSyslog is called: main
+// log messages are written to kmsg (when non-nil) and kept, up to a
+// bound, to be replayed to syslog once the logger service is up (the
+// starts-log flag). Used by PID 1 when /dev/log is not there yet.
+func (l *Logger) HoldMainLog(kmsg *KmsgBackend) {
+	l.mainMu.Lock()
+	defer l.mainMu.Unlock()
+	l.kmsg = kmsg
+	l.holding = true
+}
+
+// HoldingMainLog reports whether main log messages are being held for
+// a syslog connection that has not been made yet.
+func (l *Logger) HoldingMainLog() bool {
+	l.mainMu.Lock()
+	defer l.mainMu.Unlock()
+	return l.holding
+}
+
+// ConnectSyslog makes b the main log and replays the messages held
+// since HoldMainLog, in order, ahead of any new ones. Writing to kmsg
+// stops from here on.
+func (l *Logger) ConnectSyslog(b *SyslogBackend) {
+	l.mainMu.Lock()
+	defer l.mainMu.Unlock()
+	for _, e := range l.held {
+		b.Log(e.Level, e.Main)
+	}
+	if l.heldDropped > 0 {
+		b.Log(LevelWarn, fmt.Sprintf("%d early log message(s) dropped", l.heldDropped))
+	}
+	l.held = nil
+	l.heldDropped = 0
+	l.holding = false
+	if l.kmsg != nil {
+		l.kmsg.Close()
+		l.kmsg = nil
+	}
+	l.syslogB = b
+}
+
+// writeMain sends e to the main log: syslog when connected, otherwise
+// kmsg and the held queue.
+func (l *Logger) writeMain(e LogEntry) {
+	l.mainMu.Lock()
+	defer l.mainMu.Unlock()
+	if l.syslogB != nil {
+		l.syslogB.Log(e.Level, e.Main)
+		return
+	}
+	if l.kmsg != nil {
+		l.kmsg.Log(e.Level, e.Main)
+	}
+	if l.holding {
+		if len(l.held) < maxHeldMain {
+			l.held = append(l.held, e)
+		} else {
+			l.heldDropped++
+		}
+	}
+}
+
+// hasMainLog reports whether there is any main log to write to.
+func (l *Logger) hasMainLog() bool {
+	l.mainMu.Lock()
+	defer l.mainMu.Unlock()
+	return l.syslogB != nil || l.holding
+}
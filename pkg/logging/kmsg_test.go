@@ -0,0 +1,80 @@
+package logging
+
+import (
+	"bytes"
+	"log/syslog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestLoggerHoldsMainLogUntilSyslog checks that held main-log messages
+// go to kmsg straight away and are replayed to syslog, in order, once
+// it is connected, after which kmsg is no longer written.
+func TestLoggerHoldsMainLogUntilSyslog(t *testing.T) {
+	orig := kmsgPath
+	kmsgPath = filepath.Join(t.TempDir(), "kmsg")
+	t.Cleanup(func() { kmsgPath = orig })
+	if err := os.WriteFile(kmsgPath, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	var conns []*fakeSyslog
+	withFakeSyslog(t, &conns, nil, nil)
+
+	kmsg, err := OpenKmsg(syslog.LOG_DAEMON)
+	if err != nil {
+		t.Fatalf("OpenKmsg: %v", err)
+	}
+	var console bytes.Buffer
+	logger := New(LevelInfo)
+	logger.SetOutput(&console)
+	logger.HoldMainLog(kmsg)
+	logger.Info("early %d", 1)
+	logger.Error("early %d", 2)
+
+	got, _ := os.ReadFile(kmsgPath)
+	if want := "<30>slinit: early 1\n<27>slinit: early 2\n"; string(got) != want {
+		t.Errorf("kmsg: got %q, want %q", got, want)
+	}
+
+	b, err := NewSyslogBackend(syslog.LOG_DAEMON)
+	if err != nil {
+		t.Fatalf("NewSyslogBackend: %v", err)
+	}
+	logger.ConnectSyslog(b)
+	logger.Info("late")
+	if logger.HoldingMainLog() {
+		t.Error("still holding after ConnectSyslog")
+	}
+	if got, want := strings.Join(conns[0].lines, ","), "info:early 1,err:early 2,info:late"; got != want {
+		t.Errorf("syslog: got %s, want %s", got, want)
+	}
+	if got, _ := os.ReadFile(kmsgPath); strings.Contains(string(got), "late") {
+		t.Errorf("kmsg written after syslog connected: %q", got)
+	}
+}
+
+func TestLoggerHeldMainLogIsBounded(t *testing.T) {
+	var conns []*fakeSyslog
+	withFakeSyslog(t, &conns, nil, nil)
+
+	logger := New(LevelInfo)
+	logger.SetOutput(&bytes.Buffer{})
+	logger.HoldMainLog(nil)
+	for i := 0; i < maxHeldMain+5; i++ {
+		logger.Info("m%d", i)
+	}
+	b, err := NewSyslogBackend(syslog.LOG_DAEMON)
+	if err != nil {
+		t.Fatalf("NewSyslogBackend: %v", err)
+	}
+	logger.ConnectSyslog(b)
+	lines := conns[0].lines
+	if len(lines) != maxHeldMain+1 {
+		t.Fatalf("got %d lines, want %d", len(lines), maxHeldMain+1)
+	}
+	if last := lines[len(lines)-1]; last != "warning:5 early log message(s) dropped" {
+		t.Errorf("last line: %q", last)
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"io"
 	"log/syslog"
 	"os"
+	"sync"
 	"time"
 )
 
@@ -128,7 +129,7 @@ func (l Level) syslogPriority() syslog.Priority {
 type Logger struct {
 	level     Level
 	output    io.Writer
-	syslogW   *syslog.Writer
+	syslogB   *SyslogBackend
 	mainLevel Level // minimum level for main log (syslog/file); defaults to same as level
 
 	// consoleDup is an optional secondary writer that receives a copy of
@@ -155,6 +156,19 @@ type Logger struct {
 	// line the Logger emits. Used by the runsvdir-inspired periodic
 	// re-emitter to keep transient warnings visible.
 	ringBuf *RingBuffer
+
+	// async, when non-nil, queues entries for a background writer
+	// instead of writing them on the caller's goroutine (--log-async).
+	async *AsyncLogger
+
+	// mainMu guards syslogB once it can change at runtime, and the
+	// kmsg/held stand-in used until the logger service is up (see
+	// HoldMainLog).
+	mainMu      sync.Mutex
+	kmsg        *KmsgBackend
+	holding     bool
+	held        []LogEntry
+	heldDropped int
 }
 
 // ANSI escape sequences for boot-console status markers.
@@ -221,11 +235,7 @@ func (l *Logger) SetShutdownConsole(enabled bool) {
 // console-dup writer, if any). The marker comes from markerOK/markerFail/
 // markerStopped, which apply ANSI color when l.color is set.
 func (l *Logger) bootStatus(marker, name string) {
-	line := fmt.Sprintf("%s %s\n", marker, name)
-	fmt.Fprint(l.output, line)
-	if l.consoleDup != nil {
-		fmt.Fprint(l.consoleDup, line)
-	}
+	l.dispatch(LogEntry{Level: LevelInfo, Console: fmt.Sprintf("%s %s\n", marker, name)})
 }
 
 // markerOK renders the "[ OK ]" success marker, green when color is enabled.
@@ -254,10 +264,10 @@ func (l *Logger) markerStopped() string {
 // console. Used by the boot-console reporter, which prints its own compact
 // status line to the console but still wants the full event in the main log.
 func (l *Logger) mainLog(level Level, format string, args ...interface{}) {
-	if l.syslogW == nil || level < l.mainLevel {
+	if level < l.mainLevel || !l.hasMainLog() {
 		return
 	}
-	l.logToSyslog(level, fmt.Sprintf(format, args...))
+	l.dispatch(LogEntry{Level: level, Main: fmt.Sprintf(format, args...)})
 }
 
 // SetSyslog enables syslog output as the main log facility (like dinit's /dev/log).
@@ -265,69 +275,130 @@ func (l *Logger) mainLog(level Level, format string, args ...interface{}) {
 // connection cannot be established; in that case the logger continues to work
 // with console output only.
 func (l *Logger) SetSyslog() error {
-	w, err := syslog.New(syslog.LOG_DAEMON|syslog.LOG_NOTICE, "slinit")
+	b, err := NewSyslogBackend(syslog.LOG_DAEMON)
 	if err != nil {
 		return err
 	}
-	l.syslogW = w
+	l.SetSyslogBackend(b)
 	return nil
 }
 
-// CloseSyslog closes the syslog connection if one is open.
+// SetSyslogBackend makes b the main log, alongside the console output.
+// Pass nil to detach.
+func (l *Logger) SetSyslogBackend(b *SyslogBackend) {
+	l.mainMu.Lock()
+	defer l.mainMu.Unlock()
+	l.syslogB = b
+}
+
+// ReopenSyslog re-establishes the syslog connection, e.g. after syslogd
+// was restarted for log rotation. A no-op when syslog is not enabled.
+func (l *Logger) ReopenSyslog() error {
+	l.mainMu.Lock()
+	b := l.syslogB
+	l.mainMu.Unlock()
+	if b == nil {
+		return nil
+	}
+	return b.Reconnect()
+}
+
+// CloseSyslog closes the syslog connection if one is open, and the
+// kernel log if messages are still being held for syslog.
 func (l *Logger) CloseSyslog() {
-	if l.syslogW != nil {
-		l.syslogW.Close()
-		l.syslogW = nil
+	l.mainMu.Lock()
+	defer l.mainMu.Unlock()
+	if l.syslogB != nil {
+		l.syslogB.Close()
+		l.syslogB = nil
+	}
+	if l.kmsg != nil {
+		l.kmsg.Close()
+		l.kmsg = nil
 	}
 }
 
 func (l *Logger) log(level Level, format string, args ...interface{}) {
 	consoleOK := level >= l.level
-	syslogOK := l.syslogW != nil && level >= l.mainLevel
+	syslogOK := level >= l.mainLevel && l.hasMainLog()
 	if !consoleOK && !syslogOK {
 		return
 	}
 
 	msg := fmt.Sprintf(format, args...)
 
+	e := LogEntry{Level: level}
 	if consoleOK {
-		timestamp := formatTimestamp(time.Now())
-		var line string
-		if timestamp == "" {
-			line = fmt.Sprintf("%s: %s\n", level, msg)
-		} else {
-			line = fmt.Sprintf("[%s] %s: %s\n", timestamp, level, msg)
-		}
-		fmt.Fprint(l.output, line)
+		e.Console = formatConsoleLine(level, msg)
+		e.Ring = true
+	}
+	if syslogOK {
+		e.Main = msg
+	}
+	l.dispatch(e)
+}
+
+// formatConsoleLine renders msg as a console line: "[ts] LEVEL: msg\n",
+// or without the timestamp when timestamps are disabled.
+func formatConsoleLine(level Level, msg string) string {
+	timestamp := formatTimestamp(time.Now())
+	if timestamp == "" {
+		return fmt.Sprintf("%s: %s\n", level, msg)
+	}
+	return fmt.Sprintf("[%s] %s: %s\n", timestamp, level, msg)
+}
+
+// dispatch hands e to the async writer when one is enabled, or writes
+// it straight away.
+func (l *Logger) dispatch(e LogEntry) {
+	if l.async != nil {
+		l.async.Log(e)
+		return
+	}
+	l.emit(e)
+}
+
+// emit writes e to the console, the console-dup writer, the ring buffer
+// and the main log.
+func (l *Logger) emit(e LogEntry) {
+	if e.Console != "" {
+		fmt.Fprint(l.output, e.Console)
 		if l.consoleDup != nil {
-			fmt.Fprint(l.consoleDup, line)
+			fmt.Fprint(l.consoleDup, e.Console)
 		}
-		if l.ringBuf != nil {
+		if e.Ring && l.ringBuf != nil {
 			// Ring buffer capture is best-effort — it returns nil
 			// unconditionally, so the error return is elided.
-			_, _ = l.ringBuf.Write([]byte(line))
+			_, _ = l.ringBuf.Write([]byte(e.Console))
 		}
 	}
+	if e.Main != "" {
+		l.writeMain(e)
+	}
+}
 
-	if syslogOK {
-		l.logToSyslog(level, msg)
+// EnableAsync routes log writes through an AsyncLogger (slinit
+// --log-async), so callers never block on a slow console or syslog.
+// Call once during setup, before logging from other goroutines.
+func (l *Logger) EnableAsync() {
+	if l.async == nil {
+		l.async = NewAsyncLogger(l.emit)
 	}
 }
 
-func (l *Logger) logToSyslog(level Level, msg string) {
-	switch level {
-	case LevelDebug:
-		l.syslogW.Debug(msg)
-	case LevelInfo:
-		l.syslogW.Info(msg)
-	case LevelNotice:
-		l.syslogW.Notice(msg)
-	case LevelWarn:
-		l.syslogW.Warning(msg)
-	case LevelError:
-		l.syslogW.Err(msg)
-	default:
-		l.syslogW.Crit(msg)
+// Flush waits until every queued entry has been written. A no-op when
+// async logging is off.
+func (l *Logger) Flush() {
+	if l.async != nil {
+		l.async.Flush()
+	}
+}
+
+// Close writes any queued entries and stops the async writer, if any.
+// Call on the way out; later messages are discarded.
+func (l *Logger) Close() {
+	if l.async != nil {
+		l.async.Close()
 	}
 }
 
@@ -0,0 +1,127 @@
+package logging
+
+import (
+	"fmt"
+	"log/syslog"
+	"strings"
+	"sync"
+)
+
+// syslogWriter is the subset of *syslog.Writer used by SyslogBackend,
+// split out so tests can substitute a recorder for the real socket.
+type syslogWriter interface {
+	Debug(m string) error
+	Info(m string) error
+	Notice(m string) error
+	Warning(m string) error
+	Err(m string) error
+	Crit(m string) error
+	Close() error
+}
+
+// syslogDial opens a connection to the local syslog daemon. Swapped out
+// by tests.
+var syslogDial = func(facility syslog.Priority, tag string) (syslogWriter, error) {
+	return syslog.New(facility|syslog.LOG_NOTICE, tag)
+}
+
+// SyslogBackend sends log messages to the system logger (/dev/log).
+// The connection can be re-established with Reconnect, which slinit
+// does on SIGHUP so a restarted syslogd picks up its messages again.
+type SyslogBackend struct {
+	mu       sync.Mutex
+	facility syslog.Priority
+	tag      string
+	w        syslogWriter
+}
+
+// NewSyslogBackend connects to syslog with the given facility (e.g.
+// syslog.LOG_DAEMON). Fails when no syslog daemon is listening.
+func NewSyslogBackend(facility syslog.Priority) (*SyslogBackend, error) {
+	b := &SyslogBackend{facility: facility, tag: "slinit"}
+	w, err := syslogDial(facility, b.tag)
+	if err != nil {
+		return nil, err
+	}
+	b.w = w
+	return b, nil
+}
+
+// ParseSyslogFacility accepts the CLI spelling of a syslog facility:
+// "daemon" or "local0" through "local7".
+func ParseSyslogFacility(s string) (syslog.Priority, error) {
+	switch strings.ToLower(s) {
+	case "", "daemon":
+		return syslog.LOG_DAEMON, nil
+	case "local0":
+		return syslog.LOG_LOCAL0, nil
+	case "local1":
+		return syslog.LOG_LOCAL1, nil
+	case "local2":
+		return syslog.LOG_LOCAL2, nil
+	case "local3":
+		return syslog.LOG_LOCAL3, nil
+	case "local4":
+		return syslog.LOG_LOCAL4, nil
+	case "local5":
+		return syslog.LOG_LOCAL5, nil
+	case "local6":
+		return syslog.LOG_LOCAL6, nil
+	case "local7":
+		return syslog.LOG_LOCAL7, nil
+	default:
+		return syslog.LOG_DAEMON, fmt.Errorf("invalid syslog facility %q (want daemon|local0..local7)", s)
+	}
+}
+
+// Log writes msg at the syslog severity matching level. Write errors are
+// dropped: there is nowhere better to report them.
+func (b *SyslogBackend) Log(level Level, msg string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.w == nil {
+		return
+	}
+	switch level.syslogPriority() {
+	case syslog.LOG_DEBUG:
+		b.w.Debug(msg)
+	case syslog.LOG_INFO:
+		b.w.Info(msg)
+	case syslog.LOG_NOTICE:
+		b.w.Notice(msg)
+	case syslog.LOG_WARNING:
+		b.w.Warning(msg)
+	case syslog.LOG_ERR:
+		b.w.Err(msg)
+	default:
+		b.w.Crit(msg)
+	}
+}
+
+// Reconnect closes the current connection and dials syslog again with the
+// same facility. On failure the old connection is already gone and
+// messages are dropped until a later Reconnect succeeds.
+func (b *SyslogBackend) Reconnect() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.w != nil {
+		b.w.Close()
+		b.w = nil
+	}
+	w, err := syslogDial(b.facility, b.tag)
+	if err != nil {
+		return err
+	}
+	b.w = w
+	return nil
+}
+
+// Close closes the syslog connection.
+func (b *SyslogBackend) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.w != nil {
+		b.w.Close()
+		b.w = nil
+	}
+}
@@ -0,0 +1,158 @@
+package logging
+
+import (
+	"bytes"
+	"errors"
+	"log/syslog"
+	"strings"
+	"testing"
+)
+
+// fakeSyslog records each message prefixed with its severity.
+type fakeSyslog struct {
+	lines  []string
+	closed bool
+}
+
+func (f *fakeSyslog) add(sev, m string) error { f.lines = append(f.lines, sev+":"+m); return nil }
+func (f *fakeSyslog) Debug(m string) error    { return f.add("debug", m) }
+func (f *fakeSyslog) Info(m string) error     { return f.add("info", m) }
+func (f *fakeSyslog) Notice(m string) error   { return f.add("notice", m) }
+func (f *fakeSyslog) Warning(m string) error  { return f.add("warning", m) }
+func (f *fakeSyslog) Err(m string) error      { return f.add("err", m) }
+func (f *fakeSyslog) Crit(m string) error     { return f.add("crit", m) }
+func (f *fakeSyslog) Close() error            { f.closed = true; return nil }
+
+// withFakeSyslog swaps syslogDial for the duration of the test. Each dial
+// returns a fresh fakeSyslog, appended to *conns, or err when non-nil.
+func withFakeSyslog(t *testing.T, conns *[]*fakeSyslog, facilities *[]syslog.Priority, err *error) {
+	t.Helper()
+	orig := syslogDial
+	syslogDial = func(facility syslog.Priority, tag string) (syslogWriter, error) {
+		if err != nil && *err != nil {
+			return nil, *err
+		}
+		if facilities != nil {
+			*facilities = append(*facilities, facility)
+		}
+		f := &fakeSyslog{}
+		*conns = append(*conns, f)
+		return f, nil
+	}
+	t.Cleanup(func() { syslogDial = orig })
+}
+
+func TestSyslogBackendLevelMapping(t *testing.T) {
+	var conns []*fakeSyslog
+	withFakeSyslog(t, &conns, nil, nil)
+
+	b, err := NewSyslogBackend(syslog.LOG_DAEMON)
+	if err != nil {
+		t.Fatalf("NewSyslogBackend: %v", err)
+	}
+	b.Log(LevelDebug, "d")
+	b.Log(LevelInfo, "i")
+	b.Log(LevelNotice, "n")
+	b.Log(LevelWarn, "w")
+	b.Log(LevelError, "e")
+
+	want := []string{"debug:d", "info:i", "notice:n", "warning:w", "err:e"}
+	got := conns[0].lines
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestSyslogBackendReconnect(t *testing.T) {
+	var conns []*fakeSyslog
+	var facilities []syslog.Priority
+	withFakeSyslog(t, &conns, &facilities, nil)
+
+	b, err := NewSyslogBackend(syslog.LOG_LOCAL3)
+	if err != nil {
+		t.Fatalf("NewSyslogBackend: %v", err)
+	}
+	if err := b.Reconnect(); err != nil {
+		t.Fatalf("Reconnect: %v", err)
+	}
+	b.Log(LevelInfo, "after")
+
+	if len(conns) != 2 {
+		t.Fatalf("expected 2 connections, got %d", len(conns))
+	}
+	if !conns[0].closed {
+		t.Error("old connection not closed on reconnect")
+	}
+	if len(conns[1].lines) != 1 || conns[1].lines[0] != "info:after" {
+		t.Errorf("new connection got %v", conns[1].lines)
+	}
+	for _, f := range facilities {
+		if f != syslog.LOG_LOCAL3 {
+			t.Errorf("facility: got %v, want LOG_LOCAL3", f)
+		}
+	}
+}
+
+func TestSyslogBackendDialFailure(t *testing.T) {
+	var conns []*fakeSyslog
+	dialErr := errors.New("no syslogd")
+	withFakeSyslog(t, &conns, nil, &dialErr)
+
+	if _, err := NewSyslogBackend(syslog.LOG_DAEMON); err == nil {
+		t.Fatal("expected error when syslog is unavailable")
+	}
+}
+
+func TestLoggerFansOutToSyslogAndConsole(t *testing.T) {
+	var conns []*fakeSyslog
+	withFakeSyslog(t, &conns, nil, nil)
+
+	b, err := NewSyslogBackend(syslog.LOG_DAEMON)
+	if err != nil {
+		t.Fatalf("NewSyslogBackend: %v", err)
+	}
+	var console bytes.Buffer
+	logger := New(LevelInfo)
+	logger.SetOutput(&console)
+	logger.SetSyslogBackend(b)
+
+	logger.Warn("disk %s", "full")
+
+	if !strings.Contains(console.String(), "WARN: disk full") {
+		t.Errorf("console missing message: %q", console.String())
+	}
+	if len(conns[0].lines) != 1 || conns[0].lines[0] != "warning:disk full" {
+		t.Errorf("syslog got %v", conns[0].lines)
+	}
+
+	if err := logger.ReopenSyslog(); err != nil {
+		t.Fatalf("ReopenSyslog: %v", err)
+	}
+	logger.CloseSyslog()
+	if !conns[1].closed {
+		t.Error("CloseSyslog did not close the reconnected writer")
+	}
+}
+
+func TestParseSyslogFacility(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    syslog.Priority
+		wantErr bool
+	}{
+		{"daemon", syslog.LOG_DAEMON, false},
+		{"", syslog.LOG_DAEMON, false},
+		{"local0", syslog.LOG_LOCAL0, false},
+		{"LOCAL7", syslog.LOG_LOCAL7, false},
+		{"kern", syslog.LOG_DAEMON, true},
+	}
+	for _, tt := range tests {
+		got, err := ParseSyslogFacility(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseSyslogFacility(%q): err = %v, wantErr %v", tt.in, err, tt.wantErr)
+		}
+		if got != tt.want {
+			t.Errorf("ParseSyslogFacility(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package migrate
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sunlightlinux/slinit/pkg/config"
+	"github.com/sunlightlinux/slinit/pkg/service"
+)
+
+// defaultStopTimeout mirrors config.NewServiceDescription; a stop-timeout
+// equal to it is left out of the output.
+const defaultStopTimeout = 10 * time.Second
+
+// WriteServiceDescription renders the settings ParseSystemdUnit can
+// produce as a slinit service description. source names the original
+// unit in the header comment; warnings are listed there too so they stay
+// with the file after migration.
+func WriteServiceDescription(w io.Writer, desc *config.ServiceDescription, source string, warns []MigrationWarning) error {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "# Migrated from %s by slinitctl migrate.\n", source)
+	for _, wn := range warns {
+		fmt.Fprintf(&b, "# WARNING: %s\n", wn)
+	}
+	b.WriteString("\n")
+
+	switch desc.Type {
+	case service.TypeScripted:
+		b.WriteString("type = scripted\n")
+	default:
+		b.WriteString("type = process\n")
+	}
+	if desc.Description != "" {
+		fmt.Fprintf(&b, "description = %s\n", desc.Description)
+	}
+	fmt.Fprintf(&b, "command = %s\n", joinCommand(desc.Command))
+	if len(desc.StopCommand) > 0 {
+		fmt.Fprintf(&b, "stop-command = %s\n", joinCommand(desc.StopCommand))
+	}
+	if desc.WorkingDir != "" {
+		fmt.Fprintf(&b, "working-dir = %s\n", desc.WorkingDir)
+	}
+	if desc.RunAs != "" {
+		fmt.Fprintf(&b, "run-as = %s\n", desc.RunAs)
+	}
+	if desc.EnvFile != "" {
+		fmt.Fprintf(&b, "env-file = %s\n", desc.EnvFile)
+	}
+
+	switch desc.AutoRestart {
+	case service.RestartAlways:
+		b.WriteString("restart = yes\n")
+	case service.RestartOnFailure:
+		b.WriteString("restart = on-failure\n")
+	}
+	if desc.RestartDelay != 0 {
+		fmt.Fprintf(&b, "restart-delay = %s\n", seconds(desc.RestartDelay))
+	}
+	// An explicit 0 comes from "infinity" and means no timeout, so it
+	// is written out rather than left to the default.
+	if desc.StartTimeout != 0 || desc.StartTimeoutSet {
+		fmt.Fprintf(&b, "start-timeout = %s\n", seconds(desc.StartTimeout))
+	}
+	if desc.StopTimeout != defaultStopTimeout {
+		fmt.Fprintf(&b, "stop-timeout = %s\n", seconds(desc.StopTimeout))
+	}
+
+	for _, list := range []struct {
+		setting string
+		names   []string
+	}{
+		{"depends-on", desc.DependsOn},
+		{"waits-for", desc.WaitsFor},
+		{"after", desc.After},
+		{"before", desc.Before},
+	} {
+		for _, n := range list.names {
+			fmt.Fprintf(&b, "%s: %s\n", list.setting, n)
+		}
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// seconds renders d in the float-seconds form slinit duration settings use.
+func seconds(d time.Duration) string {
+	return strconv.FormatFloat(d.Seconds(), 'f', -1, 64)
+}
+
+// joinCommand quotes each argument that the service file parser would
+// otherwise split or unescape.
+func joinCommand(args []string) string {
+	quoted := make([]string, len(args))
+	for i, a := range args {
+		if a != "" && !strings.ContainsAny(a, " \t\"'\\") {
+			quoted[i] = a
+			continue
+		}
+		r := strings.NewReplacer(`\`, `\\`, `"`, `\"`)
+		quoted[i] = `"` + r.Replace(a) + `"`
+	}
+	return strings.Join(quoted, " ")
+}
@@ -0,0 +1,390 @@
+// Package migrate converts service definitions from other init systems
+// into slinit service descriptions.
+//
+// Conversion is best-effort: settings with a direct slinit equivalent
+// are translated, everything else is reported as a MigrationWarning so
+// the operator can finish the job by hand. See systemd.unit(5) and
+// systemd.service(5) for the source format.
+package migrate
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sunlightlinux/slinit/pkg/config"
+	"github.com/sunlightlinux/slinit/pkg/service"
+)
+
+// MigrationWarning describes a unit file setting that was dropped or only
+// approximately translated.
+type MigrationWarning struct {
+	Line    int    // 1-based line in the unit file; 0 if not tied to a line
+	Section string // e.g. "Service"
+	Key     string // e.g. "PrivateTmp"
+	Message string
+}
+
+func (w MigrationWarning) String() string {
+	loc := w.Key
+	if w.Section != "" {
+		loc = "[" + w.Section + "] " + w.Key
+	}
+	if w.Line > 0 {
+		return fmt.Sprintf("line %d: %s: %s", w.Line, loc, w.Message)
+	}
+	return fmt.Sprintf("%s: %s", loc, w.Message)
+}
+
+// unitEntry is one key=value assignment from a unit file.
+type unitEntry struct {
+	line    int
+	section string
+	key     string
+	value   string
+}
+
+// warnFunc records a MigrationWarning against a unit file entry.
+type warnFunc func(e unitEntry, format string, args ...interface{})
+
+// ParseSystemdUnit converts a systemd service unit into a service
+// description. The returned description has no Name; callers derive it
+// from the unit file name (see UnitServiceName).
+func ParseSystemdUnit(r io.Reader) (*config.ServiceDescription, []MigrationWarning, error) {
+	entries, err := readUnit(r)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	desc := config.NewServiceDescription("")
+	var warns []MigrationWarning
+	warn := func(e unitEntry, format string, args ...interface{}) {
+		warns = append(warns, MigrationWarning{
+			Line:    e.line,
+			Section: e.section,
+			Key:     e.key,
+			Message: fmt.Sprintf(format, args...),
+		})
+	}
+
+	var user, group unitEntry
+	typ := unitEntry{section: "Service", key: "Type", value: "simple"}
+	var execStarts []unitEntry
+
+	for _, e := range entries {
+		switch e.section {
+		case "Unit":
+			switch e.key {
+			case "Description":
+				desc.Description = e.value
+			case "After":
+				desc.After = append(desc.After, unitNames(e, warn)...)
+			case "Before":
+				desc.Before = append(desc.Before, unitNames(e, warn)...)
+			case "Requires":
+				desc.DependsOn = append(desc.DependsOn, unitNames(e, warn)...)
+			case "Wants":
+				desc.WaitsFor = append(desc.WaitsFor, unitNames(e, warn)...)
+			case "Documentation":
+				// Informational only; nothing to carry over.
+			default:
+				warn(e, "unsupported setting, dropped")
+			}
+
+		case "Service":
+			switch e.key {
+			case "Type":
+				typ = e
+			case "ExecStart":
+				if e.value == "" {
+					// An empty assignment resets the list.
+					execStarts = nil
+				} else {
+					execStarts = append(execStarts, e)
+				}
+			case "ExecStop":
+				if len(desc.StopCommand) > 0 {
+					warn(e, "only the first ExecStop is migrated")
+					continue
+				}
+				desc.StopCommand = execCommand(e, warn)
+			case "WorkingDirectory":
+				dir := strings.TrimPrefix(e.value, "-")
+				if dir == "~" {
+					warn(e, "home-directory shorthand not supported, dropped")
+					continue
+				}
+				desc.WorkingDir = dir
+			case "User":
+				user = e
+			case "Group":
+				group = e
+			case "Restart":
+				migrateRestart(desc, e, warn)
+			case "RestartSec":
+				if d, ok := timespan(e, warn); ok {
+					desc.RestartDelay = d
+				}
+			case "TimeoutStartSec":
+				if d, ok := timespan(e, warn); ok {
+					desc.StartTimeout = d
+					desc.StartTimeoutSet = true
+				}
+			case "TimeoutStopSec":
+				if d, ok := timespan(e, warn); ok {
+					desc.StopTimeout = d
+					desc.StopTimeoutSet = true
+				}
+			case "TimeoutSec":
+				if d, ok := timespan(e, warn); ok {
+					desc.StartTimeout = d
+					desc.StopTimeout = d
+					desc.StartTimeoutSet = true
+					desc.StopTimeoutSet = true
+				}
+			case "EnvironmentFile":
+				if desc.EnvFile != "" {
+					warn(e, "only one env-file is supported, dropped")
+					continue
+				}
+				// A leading '-' makes the file optional in systemd;
+				// a missing env-file is not an error in slinit either.
+				desc.EnvFile = strings.TrimPrefix(e.value, "-")
+			default:
+				warn(e, "unsupported setting, dropped")
+			}
+
+		case "Install":
+			warn(e, "install section ignored; use 'slinitctl enable' instead")
+
+		default:
+			warn(e, "unsupported section, dropped")
+		}
+	}
+
+	switch typ.value {
+	case "simple", "exec":
+		desc.Type = service.TypeProcess
+	case "forking":
+		desc.Type = service.TypeProcess
+		warn(typ, "forking daemon migrated as process; run it in the foreground or switch to type = bgprocess with pid-file")
+	case "oneshot":
+		desc.Type = service.TypeScripted
+	default:
+		desc.Type = service.TypeProcess
+		warn(typ, "type %q has no equivalent, migrated as process", typ.value)
+	}
+
+	for i, e := range execStarts {
+		if i == 0 {
+			desc.Command = execCommand(e, warn)
+			continue
+		}
+		warn(e, "only the first ExecStart is migrated")
+	}
+	if len(desc.Command) == 0 {
+		return nil, warns, fmt.Errorf("unit has no ExecStart")
+	}
+
+	switch {
+	case user.value != "" && group.value != "":
+		desc.RunAs = user.value + ":" + group.value
+	case user.value != "":
+		desc.RunAs = user.value
+	case group.value != "":
+		warn(group, "Group without User is not supported, dropped")
+	}
+
+	return desc, warns, nil
+}
+
+// UnitServiceName derives a slinit service name from a unit file name:
+// "foo.service" → "foo". Template units keep their '@', which is how
+// slinit names templates too: "getty@.service" → "getty@" and
+// "getty@tty1.service" → "getty@tty1".
+func UnitServiceName(unitFile string) string {
+	return strings.TrimSuffix(unitFile, ".service")
+}
+
+// readUnit splits a unit file into its assignments, joining lines
+// continued with a trailing backslash.
+func readUnit(r io.Reader) ([]unitEntry, error) {
+	var entries []unitEntry
+	section := ""
+	scanner := bufio.NewScanner(r)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		start := lineNo
+		line := strings.TrimSpace(scanner.Text())
+		for strings.HasSuffix(line, "\\") && scanner.Scan() {
+			lineNo++
+			line = strings.TrimSuffix(line, "\\") + " " + strings.TrimSpace(scanner.Text())
+		}
+		if line == "" || line[0] == '#' || line[0] == ';' {
+			continue
+		}
+		if line[0] == '[' {
+			if !strings.HasSuffix(line, "]") {
+				return nil, fmt.Errorf("line %d: malformed section header %q", start, line)
+			}
+			section = line[1 : len(line)-1]
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			return nil, fmt.Errorf("line %d: expected key=value, got %q", start, line)
+		}
+		if section == "" {
+			return nil, fmt.Errorf("line %d: assignment outside of a section", start)
+		}
+		entries = append(entries, unitEntry{
+			line:    start,
+			section: section,
+			key:     strings.TrimSpace(key),
+			value:   strings.TrimSpace(value),
+		})
+	}
+	return entries, scanner.Err()
+}
+
+// unitNames maps a space-separated unit list to service names. Units
+// other than .service keep their base name, with a warning, since slinit
+// has no targets, sockets or mounts of the same name by default.
+func unitNames(e unitEntry, warn warnFunc) []string {
+	var names []string
+	for _, u := range strings.Fields(e.value) {
+		if strings.HasSuffix(u, ".service") {
+			names = append(names, UnitServiceName(u))
+			continue
+		}
+		base := u
+		if i := strings.LastIndexByte(u, '.'); i > 0 {
+			base = u[:i]
+		}
+		warn(e, "unit %q mapped to service %q; make sure it exists", u, base)
+		names = append(names, base)
+	}
+	return names
+}
+
+// execCommand splits an Exec*= line, dropping systemd's special prefixes
+// ("-", "@", ":", "+", "!") with a warning when they change semantics.
+func execCommand(e unitEntry, warn warnFunc) []string {
+	v := e.value
+	for len(v) > 0 && strings.IndexByte("-@:+!", v[0]) >= 0 {
+		switch v[0] {
+		case '-':
+			warn(e, "'-' prefix (ignore failure) not supported, dropped")
+		case '@':
+			warn(e, "'@' prefix (custom argv[0]) not supported, dropped")
+		case '+', '!':
+			warn(e, "'%c' prefix (full privileges) not supported, dropped", v[0])
+		}
+		v = v[1:]
+	}
+	return splitExec(v)
+}
+
+// splitExec splits a command line using systemd's quoting rules: double
+// or single quotes group words, backslash escapes the next character.
+func splitExec(s string) []string {
+	var args []string
+	var cur strings.Builder
+	inWord := false
+	var quote byte
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '\\' && i+1 < len(s):
+			i++
+			cur.WriteByte(s[i])
+			inWord = true
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			} else {
+				cur.WriteByte(c)
+			}
+		case c == '"' || c == '\'':
+			quote = c
+			inWord = true
+		case c == ' ' || c == '\t':
+			if inWord {
+				args = append(args, cur.String())
+				cur.Reset()
+				inWord = false
+			}
+		default:
+			cur.WriteByte(c)
+			inWord = true
+		}
+	}
+	if inWord {
+		args = append(args, cur.String())
+	}
+	return args
+}
+
+func migrateRestart(desc *config.ServiceDescription, e unitEntry, warn warnFunc) {
+	switch e.value {
+	case "no":
+		desc.AutoRestart = service.RestartNever
+	case "always":
+		desc.AutoRestart = service.RestartAlways
+	case "on-failure":
+		desc.AutoRestart = service.RestartOnFailure
+	case "on-abnormal", "on-abort", "on-watchdog":
+		desc.AutoRestart = service.RestartOnFailure
+		warn(e, "%q approximated as restart = on-failure", e.value)
+	default:
+		warn(e, "restart policy %q has no equivalent, dropped", e.value)
+	}
+}
+
+// timespanUnits maps systemd time span suffixes to durations.
+var timespanUnits = map[string]time.Duration{
+	"us": time.Microsecond, "usec": time.Microsecond,
+	"ms": time.Millisecond, "msec": time.Millisecond,
+	"s": time.Second, "sec": time.Second, "second": time.Second, "seconds": time.Second,
+	"m": time.Minute, "min": time.Minute, "minute": time.Minute, "minutes": time.Minute,
+	"h": time.Hour, "hr": time.Hour, "hour": time.Hour, "hours": time.Hour,
+	"d": 24 * time.Hour, "day": 24 * time.Hour, "days": 24 * time.Hour,
+}
+
+// timespan parses a systemd time span ("30", "5s", "1min 30s", "500ms",
+// "infinity"). A bare number is seconds. infinity maps to 0, which slinit
+// treats as "no timeout".
+func timespan(e unitEntry, warn warnFunc) (time.Duration, bool) {
+	v := e.value
+	if v == "infinity" {
+		return 0, true
+	}
+	if secs, err := strconv.ParseFloat(v, 64); err == nil {
+		return time.Duration(secs * float64(time.Second)), true
+	}
+	var total time.Duration
+	rest := strings.ReplaceAll(v, " ", "")
+	for rest != "" {
+		i := 0
+		for i < len(rest) && (rest[i] >= '0' && rest[i] <= '9' || rest[i] == '.') {
+			i++
+		}
+		j := i
+		for j < len(rest) && rest[j] >= 'a' && rest[j] <= 'z' {
+			j++
+		}
+		n, err := strconv.ParseFloat(rest[:i], 64)
+		unit, ok := timespanUnits[rest[i:j]]
+		if err != nil || !ok {
+			warn(e, "cannot parse time span %q, dropped", v)
+			return 0, false
+		}
+		total += time.Duration(n * float64(unit))
+		rest = rest[j:]
+	}
+	return total, true
+}
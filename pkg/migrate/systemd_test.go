@@ -0,0 +1,242 @@
+package migrate
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sunlightlinux/slinit/pkg/config"
+	"github.com/sunlightlinux/slinit/pkg/service"
+)
+
+const nginxUnit = `[Unit]
+Description=A high performance web server
+Documentation=man:nginx(8)
+After=network.target syslog.service
+Requires=local-fs.service
+Wants=dns.service
+
+[Service]
+Type=simple
+ExecStart=/usr/sbin/nginx -g "daemon off;" \
+    -c /etc/nginx/nginx.conf
+ExecStop=/usr/sbin/nginx -s quit
+WorkingDirectory=/var/www
+User=www-data
+Group=www-data
+Restart=on-failure
+RestartSec=5
+TimeoutStartSec=1min 30s
+TimeoutStopSec=infinity
+EnvironmentFile=-/etc/default/nginx
+PrivateTmp=yes
+
+[Install]
+WantedBy=multi-user.target
+`
+
+func TestParseSystemdUnit(t *testing.T) {
+	desc, warns, err := ParseSystemdUnit(strings.NewReader(nginxUnit))
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+
+	if desc.Type != service.TypeProcess {
+		t.Errorf("Type: got %v", desc.Type)
+	}
+	if desc.Description != "A high performance web server" {
+		t.Errorf("Description: got %q", desc.Description)
+	}
+	wantCmd := []string{"/usr/sbin/nginx", "-g", "daemon off;", "-c", "/etc/nginx/nginx.conf"}
+	if strings.Join(desc.Command, "|") != strings.Join(wantCmd, "|") {
+		t.Errorf("Command: got %q", desc.Command)
+	}
+	if strings.Join(desc.StopCommand, " ") != "/usr/sbin/nginx -s quit" {
+		t.Errorf("StopCommand: got %q", desc.StopCommand)
+	}
+	if desc.WorkingDir != "/var/www" {
+		t.Errorf("WorkingDir: got %q", desc.WorkingDir)
+	}
+	if desc.RunAs != "www-data:www-data" {
+		t.Errorf("RunAs: got %q", desc.RunAs)
+	}
+	if desc.AutoRestart != service.RestartOnFailure {
+		t.Errorf("AutoRestart: got %v", desc.AutoRestart)
+	}
+	if desc.RestartDelay != 5*time.Second {
+		t.Errorf("RestartDelay: got %v", desc.RestartDelay)
+	}
+	if desc.StartTimeout != 90*time.Second {
+		t.Errorf("StartTimeout: got %v", desc.StartTimeout)
+	}
+	if desc.StopTimeout != 0 {
+		t.Errorf("StopTimeout: got %v, want 0 (infinity)", desc.StopTimeout)
+	}
+	if desc.EnvFile != "/etc/default/nginx" {
+		t.Errorf("EnvFile: got %q", desc.EnvFile)
+	}
+	if strings.Join(desc.After, ",") != "network,syslog" {
+		t.Errorf("After: got %v", desc.After)
+	}
+	if strings.Join(desc.DependsOn, ",") != "local-fs" {
+		t.Errorf("DependsOn: got %v", desc.DependsOn)
+	}
+	if strings.Join(desc.WaitsFor, ",") != "dns" {
+		t.Errorf("WaitsFor: got %v", desc.WaitsFor)
+	}
+
+	// network.target mapping, PrivateTmp and WantedBy should be flagged.
+	var keys []string
+	for _, w := range warns {
+		keys = append(keys, w.Key)
+	}
+	if got := strings.Join(keys, ","); got != "After,PrivateTmp,WantedBy" {
+		t.Errorf("warnings: got %v", warns)
+	}
+}
+
+func TestParseSystemdUnitTypes(t *testing.T) {
+	tests := []struct {
+		unitType string
+		want     service.ServiceType
+		warn     bool
+	}{
+		{"simple", service.TypeProcess, false},
+		{"exec", service.TypeProcess, false},
+		{"forking", service.TypeProcess, true},
+		{"oneshot", service.TypeScripted, false},
+		{"notify", service.TypeProcess, true},
+	}
+	for _, tt := range tests {
+		unit := "[Service]\nType=" + tt.unitType + "\nExecStart=/bin/true\n"
+		desc, warns, err := ParseSystemdUnit(strings.NewReader(unit))
+		if err != nil {
+			t.Fatalf("%s: %v", tt.unitType, err)
+		}
+		if desc.Type != tt.want {
+			t.Errorf("%s: type %v, want %v", tt.unitType, desc.Type, tt.want)
+		}
+		if (len(warns) > 0) != tt.warn {
+			t.Errorf("%s: warnings %v, want warning=%v", tt.unitType, warns, tt.warn)
+		}
+	}
+}
+
+func TestParseSystemdUnitExecPrefixes(t *testing.T) {
+	unit := "[Service]\nExecStart=-/usr/bin/foo --bar\nExecStart=/usr/bin/second\n"
+	desc, warns, err := ParseSystemdUnit(strings.NewReader(unit))
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if strings.Join(desc.Command, " ") != "/usr/bin/foo --bar" {
+		t.Errorf("Command: got %q", desc.Command)
+	}
+	if len(warns) != 2 {
+		t.Errorf("expected prefix and extra-ExecStart warnings, got %v", warns)
+	}
+}
+
+func TestParseSystemdUnitTemplateDeps(t *testing.T) {
+	unit := "[Unit]\nWants=getty@tty1.service\nAfter=serial-getty@.service\n[Service]\nExecStart=/sbin/agetty %i\n"
+	desc, warns, err := ParseSystemdUnit(strings.NewReader(unit))
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if strings.Join(desc.WaitsFor, ",") != "getty@tty1" || strings.Join(desc.After, ",") != "serial-getty@" {
+		t.Errorf("deps: waits-for %v after %v", desc.WaitsFor, desc.After)
+	}
+	if len(warns) != 0 {
+		t.Errorf("unexpected warnings: %v", warns)
+	}
+}
+
+func TestParseSystemdUnitErrors(t *testing.T) {
+	for name, unit := range map[string]string{
+		"no ExecStart":  "[Service]\nType=simple\n",
+		"no section":    "ExecStart=/bin/true\n",
+		"bad header":    "[Service\nExecStart=/bin/true\n",
+		"not key=value": "[Service]\nExecStart /bin/true\n",
+	} {
+		if _, _, err := ParseSystemdUnit(strings.NewReader(unit)); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
+
+func TestParseSystemdUnitBadTimespan(t *testing.T) {
+	unit := "[Service]\nExecStart=/bin/true\nRestartSec=soon\n"
+	desc, warns, err := ParseSystemdUnit(strings.NewReader(unit))
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if desc.RestartDelay != 0 || len(warns) != 1 {
+		t.Errorf("RestartDelay %v, warnings %v", desc.RestartDelay, warns)
+	}
+}
+
+// TestWriteServiceDescriptionRoundTrip checks the rendered file parses
+// back to the same settings with slinit's own parser.
+func TestWriteServiceDescriptionRoundTrip(t *testing.T) {
+	desc, warns, err := ParseSystemdUnit(strings.NewReader(nginxUnit))
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	var buf bytes.Buffer
+	if err := WriteServiceDescription(&buf, desc, "nginx.service", warns); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	if !strings.Contains(buf.String(), "# WARNING: line 21: [Service] PrivateTmp: unsupported setting, dropped") {
+		t.Errorf("warnings not rendered:\n%s", buf.String())
+	}
+
+	got, err := config.Parse(&buf, "nginx", "nginx")
+	if err != nil {
+		t.Fatalf("re-parse: %v\n%s", err, buf.String())
+	}
+	if strings.Join(got.Command, "|") != strings.Join(desc.Command, "|") {
+		t.Errorf("Command: got %q, want %q", got.Command, desc.Command)
+	}
+	if got.RunAs != desc.RunAs || got.WorkingDir != desc.WorkingDir || got.EnvFile != desc.EnvFile {
+		t.Errorf("got %+v", got)
+	}
+	if got.AutoRestart != desc.AutoRestart || got.RestartDelay != desc.RestartDelay {
+		t.Errorf("restart: got %v/%v", got.AutoRestart, got.RestartDelay)
+	}
+	if got.StartTimeout != desc.StartTimeout || got.StopTimeout != desc.StopTimeout {
+		t.Errorf("timeouts: got %v/%v", got.StartTimeout, got.StopTimeout)
+	}
+	if strings.Join(got.After, ",") != "network,syslog" || strings.Join(got.DependsOn, ",") != "local-fs" {
+		t.Errorf("deps: after %v depends-on %v", got.After, got.DependsOn)
+	}
+}
+
+func TestWriteServiceDescriptionInfiniteTimeouts(t *testing.T) {
+	unit := "[Service]\nExecStart=/bin/true\nTimeoutSec=infinity\n"
+	desc, warns, err := ParseSystemdUnit(strings.NewReader(unit))
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	var buf bytes.Buffer
+	if err := WriteServiceDescription(&buf, desc, "forever.service", warns); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	for _, line := range []string{"start-timeout = 0\n", "stop-timeout = 0\n"} {
+		if !strings.Contains(buf.String(), line) {
+			t.Errorf("missing %q in:\n%s", line, buf.String())
+		}
+	}
+}
+
+func TestUnitServiceName(t *testing.T) {
+	for in, want := range map[string]string{
+		"nginx.service":      "nginx",
+		"getty@.service":     "getty@",
+		"getty@tty1.service": "getty@tty1",
+		"plain":              "plain",
+	} {
+		if got := UnitServiceName(in); got != want {
+			t.Errorf("UnitServiceName(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
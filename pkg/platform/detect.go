@@ -67,6 +67,27 @@ func Detect() Type {
 	return detectVM()
 }
 
+// IsContainer reports whether t is a container platform, one where
+// slinit as PID 1 runs in a PID namespace under a container runtime
+// rather than on a machine of its own.
+func IsContainer(t Type) bool {
+	switch t {
+	case Docker, Podman, LXC, SystemdNspawn, OpenVZ, Vserver, RKT:
+		return true
+	}
+	return false
+}
+
+// DetectContainer returns the container platform slinit runs in, or
+// None outside a container. Unlike Detect it skips the VM probes and
+// does not report UML or WSL, which run a full init of their own.
+func DetectContainer() Type {
+	if t := detectContainer(); IsContainer(t) {
+		return t
+	}
+	return None
+}
+
 // readFileFunc is mockable for testing.
 var readFileFunc = os.ReadFile
 var statFunc = os.Stat
@@ -311,6 +311,39 @@ func TestDetectRKT(t *testing.T) {
 	})
 }
 
+func TestDetectContainer(t *testing.T) {
+	m := newMockFS()
+	m.files["/proc/1/environ"] = []byte("PATH=/bin\x00container=podman\x00")
+	withMock(m, func() {
+		if got := DetectContainer(); got != Podman {
+			t.Errorf("expected Podman, got %q", got)
+		}
+	})
+
+	// WSL and VMs are not containers.
+	m = newMockFS()
+	m.files["/proc/sys/kernel/osrelease"] = []byte("5.15.0-microsoft-standard-WSL2")
+	m.files["/sys/hypervisor/type"] = []byte("kvm")
+	withMock(m, func() {
+		if got := DetectContainer(); got != None {
+			t.Errorf("expected None, got %q", got)
+		}
+	})
+}
+
+func TestIsContainer(t *testing.T) {
+	for _, typ := range []Type{Docker, Podman, LXC, SystemdNspawn, OpenVZ, Vserver, RKT} {
+		if !IsContainer(typ) {
+			t.Errorf("IsContainer(%q) = false", typ)
+		}
+	}
+	for _, typ := range []Type{None, WSL, UML, KVM, XenU} {
+		if IsContainer(typ) {
+			t.Errorf("IsContainer(%q) = true", typ)
+		}
+	}
+}
+
 func TestMatchesKeyword(t *testing.T) {
 	tests := []struct {
 		keyword  string
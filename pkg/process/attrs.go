@@ -12,14 +12,11 @@ import (
 	"golang.org/x/sys/unix"
 )
 
-// Linux syscall numbers not in Go's syscall package.
+// ioprio_set(2) constants; x/sys/unix has the syscall number but not
+// these.
 const (
-	sysPrlimit64 = 302 // SYS_prlimit64 (amd64)
-	sysIoprioSet = 251 // SYS_ioprio_set (amd64)
-	sysPrctl     = 157 // SYS_prctl (amd64)
-
 	ioprioWhoProcess = 1
-	prSetNoNewPrivs  = 38
+	ioprioClassShift = 13
 )
 
 // applyPostForkAttrs applies process attributes after fork.
@@ -101,37 +98,20 @@ func applyOOMScoreAdj(pid, adj int) error {
 
 func applyRlimits(pid int, limits []Rlimit) error {
 	for _, rl := range limits {
-		lim := syscall.Rlimit{
+		lim := unix.Rlimit{
 			Cur: rl.Soft,
 			Max: rl.Hard,
 		}
-		if err := prlimit(pid, rl.Resource, &lim); err != nil {
+		if err := unix.Prlimit(pid, rl.Resource, &lim, nil); err != nil {
 			return fmt.Errorf("resource %d: %w", rl.Resource, err)
 		}
 	}
 	return nil
 }
 
-// prlimit wraps the prlimit64 syscall to set resource limits on another process.
-func prlimit(pid, resource int, newLim *syscall.Rlimit) error {
-	_, _, errno := syscall.RawSyscall6(
-		sysPrlimit64,
-		uintptr(pid),
-		uintptr(resource),
-		uintptr(unsafe.Pointer(newLim)),
-		0, // old limit (nil)
-		0, 0,
-	)
-	if errno != 0 {
-		return errno
-	}
-	return nil
-}
-
 func applyIOPrio(pid, class, level int) error {
-	// ioprio value = (class << 13) | level
-	ioprio := uintptr((class << 13) | level)
-	_, _, errno := syscall.Syscall(sysIoprioSet, ioprioWhoProcess, uintptr(pid), ioprio)
+	ioprio := uintptr(class<<ioprioClassShift | level)
+	_, _, errno := syscall.Syscall(unix.SYS_IOPRIO_SET, ioprioWhoProcess, uintptr(pid), ioprio)
 	if errno != 0 {
 		return errno
 	}
@@ -2,9 +2,12 @@ package process
 
 import (
 	"os"
+	"os/exec"
 	"path/filepath"
 	"syscall"
 	"testing"
+
+	"golang.org/x/sys/unix"
 )
 
 func TestKillCgroupEmptyPath(t *testing.T) {
@@ -209,3 +212,50 @@ func TestKillCgroupRecursiveSkipsNonDirs(t *testing.T) {
 		t.Fatalf("KillCgroup: %v", err)
 	}
 }
+
+func TestApplyRlimits(t *testing.T) {
+	cmd := exec.Command("/bin/sleep", "10")
+	if err := cmd.Start(); err != nil {
+		t.Skipf("cannot start sleep: %v", err)
+	}
+	defer func() {
+		cmd.Process.Kill()
+		cmd.Wait()
+	}()
+
+	// Lowering a limit is always permitted, root or not.
+	if err := applyRlimits(cmd.Process.Pid, []Rlimit{{Resource: unix.RLIMIT_CORE, Soft: 0, Hard: 0}}); err != nil {
+		t.Fatalf("applyRlimits: %v", err)
+	}
+	var got unix.Rlimit
+	if err := unix.Prlimit(cmd.Process.Pid, unix.RLIMIT_CORE, nil, &got); err != nil {
+		t.Fatalf("prlimit get: %v", err)
+	}
+	if got.Cur != 0 || got.Max != 0 {
+		t.Errorf("RLIMIT_CORE = %d:%d, want 0:0", got.Cur, got.Max)
+	}
+}
+
+func TestApplyIOPrio(t *testing.T) {
+	cmd := exec.Command("/bin/sleep", "10")
+	if err := cmd.Start(); err != nil {
+		t.Skipf("cannot start sleep: %v", err)
+	}
+	defer func() {
+		cmd.Process.Kill()
+		cmd.Wait()
+	}()
+
+	// best-effort:7 is the lowest best-effort priority; dropping to it
+	// needs no privilege.
+	if err := applyIOPrio(cmd.Process.Pid, 2, 7); err != nil {
+		t.Fatalf("applyIOPrio: %v", err)
+	}
+	prio, _, errno := syscall.Syscall(unix.SYS_IOPRIO_GET, ioprioWhoProcess, uintptr(cmd.Process.Pid), 0)
+	if errno != 0 {
+		t.Fatalf("ioprio_get: %v", errno)
+	}
+	if prio != 2<<ioprioClassShift|7 {
+		t.Errorf("ioprio = %#x, want %#x", prio, 2<<ioprioClassShift|7)
+	}
+}
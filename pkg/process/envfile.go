@@ -2,10 +2,20 @@ package process
 
 import (
 	"bufio"
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
+
+	"golang.org/x/sys/unix"
 )
 
 // ReadEnvFile reads a file of KEY=VALUE environment variable assignments.
@@ -32,7 +42,11 @@ func ReadEnvFileWithOrigEnv(path string, origEnv []string) (map[string]string, e
 		return nil, fmt.Errorf("open env-file: %w", err)
 	}
 	defer f.Close()
+	return parseEnvFile(f, origEnv)
+}
 
+// parseEnvFile parses env-file content; see ReadEnvFileWithOrigEnv.
+func parseEnvFile(r io.Reader, origEnv []string) (map[string]string, error) {
 	// Build lookup for original environment
 	orig := make(map[string]string)
 	if origEnv == nil {
@@ -45,7 +59,7 @@ func ReadEnvFileWithOrigEnv(path string, origEnv []string) (map[string]string, e
 	}
 
 	env := make(map[string]string)
-	scanner := bufio.NewScanner(f)
+	scanner := bufio.NewScanner(r)
 	for scanner.Scan() {
 		line := strings.TrimSpace(scanner.Text())
 		if line == "" || strings.HasPrefix(line, "#") {
@@ -146,3 +160,180 @@ func ReadEnvDir(dirPath string) (map[string]string, error) {
 	}
 	return env, nil
 }
+
+// encryptedEnvMagic starts every encrypted env-file. The layout is
+// magic(4) + nonce(12) + AES-256-GCM ciphertext(N) + tag(16); the magic
+// is also the GCM additional data.
+var encryptedEnvMagic = []byte("SLE1")
+
+// envKeySize is the AES-256 key length.
+const envKeySize = 32
+
+// LoadEncryptedEnvFile decrypts an env-file written by EncryptEnvFile
+// with the key named by keySpec and parses it as ReadEnvFile does.
+// Returns the variables as sorted KEY=VALUE entries.
+func LoadEncryptedEnvFile(path, keySpec string) ([]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read encrypted env-file: %w", err)
+	}
+	key, err := LoadEnvKey(keySpec)
+	if err != nil {
+		return nil, err
+	}
+	plain, err := decryptEnv(data, key)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	env, err := parseEnvFile(bytes.NewReader(plain), nil)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]string, 0, len(env))
+	for k, v := range env {
+		result = append(result, k+"="+v)
+	}
+	sort.Strings(result)
+	return result, nil
+}
+
+// EncryptEnvFile encrypts env-file content with the key named by
+// keySpec, in the format LoadEncryptedEnvFile reads.
+func EncryptEnvFile(plain []byte, keySpec string) ([]byte, error) {
+	key, err := LoadEnvKey(keySpec)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := newEnvGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]byte, len(encryptedEnvMagic)+gcm.NonceSize(), len(encryptedEnvMagic)+gcm.NonceSize()+len(plain)+gcm.Overhead())
+	copy(out, encryptedEnvMagic)
+	nonce := out[len(encryptedEnvMagic):]
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("generate nonce: %w", err)
+	}
+	return gcm.Seal(out, nonce, plain, encryptedEnvMagic), nil
+}
+
+func decryptEnv(data, key []byte) ([]byte, error) {
+	if !bytes.HasPrefix(data, encryptedEnvMagic) {
+		return nil, errors.New("not an encrypted env-file")
+	}
+	gcm, err := newEnvGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	data = data[len(encryptedEnvMagic):]
+	if len(data) < gcm.NonceSize()+gcm.Overhead() {
+		return nil, errors.New("encrypted env-file truncated")
+	}
+	nonce, sealed := data[:gcm.NonceSize()], data[gcm.NonceSize():]
+	plain, err := gcm.Open(nil, nonce, sealed, encryptedEnvMagic)
+	if err != nil {
+		return nil, errors.New("decryption failed (wrong key or corrupted file)")
+	}
+	return plain, nil
+}
+
+func newEnvGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// ValidateEnvKeySpec checks that keySpec has one of the forms
+// LoadEnvKey accepts, without fetching the key.
+func ValidateEnvKeySpec(keySpec string) error {
+	kind, arg, ok := strings.Cut(keySpec, ":")
+	if !ok || arg == "" {
+		return fmt.Errorf("key spec %q: want keyring:NAME, file:PATH or env:VAR", keySpec)
+	}
+	switch kind {
+	case "keyring", "file", "env":
+		return nil
+	}
+	return fmt.Errorf("key spec %q: unknown source %q", keySpec, kind)
+}
+
+// LoadEnvKey fetches the 32-byte env-file key named by keySpec:
+//   - keyring:NAME — a "user" key in the kernel keyring, looked up
+//     from the session keyring, then the user keyring
+//   - file:PATH    — the contents of a file
+//   - env:VAR      — the value of an environment variable
+//
+// The key material is either the 32 raw bytes or 64 hex digits;
+// surrounding whitespace is ignored.
+func LoadEnvKey(keySpec string) ([]byte, error) {
+	if err := ValidateEnvKeySpec(keySpec); err != nil {
+		return nil, err
+	}
+	kind, arg, _ := strings.Cut(keySpec, ":")
+	var raw []byte
+	switch kind {
+	case "keyring":
+		var err error
+		if raw, err = readKeyringKey(arg); err != nil {
+			return nil, fmt.Errorf("keyring key %q: %w", arg, err)
+		}
+	case "file":
+		var err error
+		if raw, err = os.ReadFile(arg); err != nil {
+			return nil, fmt.Errorf("key file: %w", err)
+		}
+	case "env":
+		v, ok := os.LookupEnv(arg)
+		if !ok {
+			return nil, fmt.Errorf("key variable %s is not set", arg)
+		}
+		raw = []byte(v)
+	}
+	return decodeEnvKey(raw, keySpec)
+}
+
+func decodeEnvKey(raw []byte, keySpec string) ([]byte, error) {
+	if len(raw) == envKeySize {
+		return raw, nil
+	}
+	trimmed := bytes.TrimSpace(raw)
+	if len(trimmed) == envKeySize {
+		return trimmed, nil
+	}
+	if len(trimmed) == 2*envKeySize {
+		key := make([]byte, envKeySize)
+		if _, err := hex.Decode(key, trimmed); err == nil {
+			return key, nil
+		}
+	}
+	return nil, fmt.Errorf("key from %s must be %d bytes or %d hex digits", keySpec, envKeySize, 2*envKeySize)
+}
+
+// readKeyringKey reads the payload of the "user" key named desc.
+func readKeyringKey(desc string) ([]byte, error) {
+	var id int
+	var err error
+	for _, ring := range []int{unix.KEY_SPEC_SESSION_KEYRING, unix.KEY_SPEC_USER_KEYRING} {
+		if id, err = unix.KeyctlSearch(ring, "user", desc, 0); err == nil {
+			break
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	size, err := unix.KeyctlBuffer(unix.KEYCTL_READ, id, nil, 0)
+	if err != nil {
+		return nil, err
+	}
+	buf := make([]byte, size)
+	n, err := unix.KeyctlBuffer(unix.KEYCTL_READ, id, buf, 0)
+	if err != nil {
+		return nil, err
+	}
+	if n < len(buf) {
+		buf = buf[:n]
+	}
+	return buf, nil
+}
@@ -1,9 +1,13 @@
 package process
 
 import (
+	"bytes"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
+
+	"golang.org/x/sys/unix"
 )
 
 func TestReadEnvFile(t *testing.T) {
@@ -122,3 +126,80 @@ func TestEnvFileImport(t *testing.T) {
 		t.Error("OTHER should not be imported")
 	}
 }
+
+func TestEncryptedEnvFileRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	keyPath := filepath.Join(dir, "key")
+	if err := os.WriteFile(keyPath, []byte(strings.Repeat("ab", 32)+"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("SLINIT_TEST_ENV_KEY", strings.Repeat("ab", 32))
+
+	sealed, err := EncryptEnvFile([]byte("DB_PASS=hunter2\n# comment\nAPI=k=v\n"), "file:"+keyPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(sealed, []byte("hunter2")) {
+		t.Fatal("encrypted file contains the clear text")
+	}
+	path := filepath.Join(dir, "secrets.enc")
+	if err := os.WriteFile(path, sealed, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	// The same key from another source decrypts it.
+	env, err := LoadEncryptedEnvFile(path, "env:SLINIT_TEST_ENV_KEY")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := strings.Join(env, " "), "API=k=v DB_PASS=hunter2"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	t.Setenv("SLINIT_TEST_ENV_KEY", strings.Repeat("cd", 32))
+	if _, err := LoadEncryptedEnvFile(path, "env:SLINIT_TEST_ENV_KEY"); err == nil {
+		t.Error("wrong key should fail")
+	}
+	sealed[len(sealed)-1] ^= 1
+	os.WriteFile(path, sealed, 0600)
+	if _, err := LoadEncryptedEnvFile(path, "file:"+keyPath); err == nil {
+		t.Error("corrupted file should fail")
+	}
+	os.WriteFile(path, []byte("DB_PASS=hunter2\n"), 0600)
+	if _, err := LoadEncryptedEnvFile(path, "file:"+keyPath); err == nil {
+		t.Error("clear-text file should fail")
+	}
+}
+
+func TestLoadEnvKey(t *testing.T) {
+	t.Setenv("SLINIT_TEST_ENV_KEY", "too short")
+	for _, spec := range []string{"", "keyring", "env:", "vault:x", "env:SLINIT_TEST_ENV_KEY", "env:SLINIT_TEST_UNSET_KEY"} {
+		if _, err := LoadEnvKey(spec); err == nil {
+			t.Errorf("LoadEnvKey(%q) should fail", spec)
+		}
+	}
+	t.Setenv("SLINIT_TEST_ENV_KEY", strings.Repeat("k", 32))
+	if key, err := LoadEnvKey("env:SLINIT_TEST_ENV_KEY"); err != nil || string(key) != strings.Repeat("k", 32) {
+		t.Errorf("raw key: got %q, %v", key, err)
+	}
+}
+
+func TestLoadEnvKeyFromKeyring(t *testing.T) {
+	desc := "slinit-test-env-key"
+	id, err := unix.AddKey("user", desc, bytes.Repeat([]byte{7}, 32), unix.KEY_SPEC_SESSION_KEYRING)
+	if err != nil {
+		t.Skipf("kernel keyring unavailable: %v", err)
+	}
+	defer unix.KeyctlInt(unix.KEYCTL_UNLINK, id, unix.KEY_SPEC_SESSION_KEYRING, 0, 0)
+
+	key, err := LoadEnvKey("keyring:" + desc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(key, bytes.Repeat([]byte{7}, 32)) {
+		t.Errorf("got key %x", key)
+	}
+	if _, err := LoadEnvKey("keyring:slinit-test-no-such-key"); err == nil {
+		t.Error("missing keyring key should fail")
+	}
+}
@@ -0,0 +1,66 @@
+package process
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"os/exec"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// MaxErrorCapture is how much of a child's stderr ExecParams.ErrorCapture
+// keeps: the last MaxErrorCapture bytes, where the reason for a failure
+// usually is.
+const MaxErrorCapture = 4096
+
+// errorCaptureWaitDelay bounds how long the exit is held back for the
+// stderr copy to finish once the child is gone, in case something it
+// left running (a daemon it started) still holds stderr open.
+const errorCaptureWaitDelay = time.Second
+
+// tailWriter keeps the last max bytes written to it in buf.
+type tailWriter struct {
+	buf *bytes.Buffer
+	max int
+}
+
+func (w *tailWriter) Write(p []byte) (int, error) {
+	n := len(p)
+	if len(p) > w.max {
+		p = p[len(p)-w.max:]
+		w.buf.Reset()
+	}
+	w.buf.Write(p)
+	if excess := w.buf.Len() - w.max; excess > 0 {
+		w.buf.Next(excess)
+	}
+	return n, nil
+}
+
+// captureStderr adds capture to wherever cmd's stderr already goes.
+// The child's stderr becomes a pipe copied from this process, which
+// outlives StartProcess, so a file it went to is written through a
+// duplicate descriptor: the caller closes its own once StartProcess
+// returns. The returned func closes the duplicate when the copy is done.
+func captureStderr(cmd *exec.Cmd, capture *bytes.Buffer) (func(), error) {
+	tw := &tailWriter{buf: capture, max: MaxErrorCapture}
+	done := func() {}
+	switch dst := cmd.Stderr.(type) {
+	case nil:
+		cmd.Stderr = tw
+	case *os.File:
+		fd, err := unix.FcntlInt(dst.Fd(), unix.F_DUPFD_CLOEXEC, 0)
+		if err != nil {
+			return nil, err
+		}
+		dup := os.NewFile(uintptr(fd), dst.Name())
+		cmd.Stderr = io.MultiWriter(tw, dup)
+		done = func() { dup.Close() }
+	default:
+		cmd.Stderr = io.MultiWriter(tw, dst)
+	}
+	cmd.WaitDelay = errorCaptureWaitDelay
+	return done, nil
+}
@@ -0,0 +1,79 @@
+package process
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTailWriterKeepsTail(t *testing.T) {
+	var buf bytes.Buffer
+	w := &tailWriter{buf: &buf, max: 8}
+	w.Write([]byte("hello "))
+	w.Write([]byte("world"))
+	if got := buf.String(); got != "lo world" {
+		t.Errorf("after two writes = %q, want %q", got, "lo world")
+	}
+	if n, _ := w.Write([]byte("0123456789abcdef")); n != 16 {
+		t.Errorf("Write returned %d, want 16", n)
+	}
+	if got := buf.String(); got != "89abcdef" {
+		t.Errorf("after long write = %q, want %q", got, "89abcdef")
+	}
+}
+
+func TestErrorCaptureTeesStderr(t *testing.T) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	var capture bytes.Buffer
+	_, ch, err := StartProcess(ExecParams{
+		Command:      []string{"/bin/sh", "-c", "echo out; echo 'connection refused' >&2; exit 1"},
+		OutputPipe:   w,
+		ErrorCapture: &capture,
+	})
+	w.Close()
+	if err != nil {
+		t.Fatalf("StartProcess: %v", err)
+	}
+	if exit := <-ch; exit.ExitedClean() {
+		t.Fatal("child should have failed")
+	}
+	if got := capture.String(); got != "connection refused\n" {
+		t.Errorf("captured %q, want only stderr", got)
+	}
+	out, _ := io.ReadAll(r)
+	if !strings.Contains(string(out), "out\n") || !strings.Contains(string(out), "connection refused\n") {
+		t.Errorf("output pipe got %q, want stdout and stderr", out)
+	}
+}
+
+func TestErrorCaptureDoesNotWaitForLeftoverChild(t *testing.T) {
+	var capture bytes.Buffer
+	start := time.Now()
+	_, ch, err := StartProcess(ExecParams{
+		// The backgrounded sleep inherits stderr and outlives the shell.
+		Command:      []string{"/bin/sh", "-c", "echo failed >&2; sleep 30 & exit 1"},
+		ErrorCapture: &capture,
+	})
+	if err != nil {
+		t.Fatalf("StartProcess: %v", err)
+	}
+	select {
+	case <-ch:
+	case <-time.After(10 * time.Second):
+		t.Fatal("exit held back by a child holding stderr open")
+	}
+	if d := time.Since(start); d > 5*time.Second {
+		t.Errorf("exit took %v", d)
+	}
+	if got := capture.String(); got != "failed\n" {
+		t.Errorf("captured %q", got)
+	}
+}
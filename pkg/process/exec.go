@@ -51,6 +51,14 @@ func StartProcess(params ExecParams) (int, <-chan ChildExit, error) {
 		}
 	}
 
+	// preload-security-check: refuse to inject a library into the
+	// child that a non-root user could have replaced.
+	if params.PreloadSecurityCheck && len(params.LDPreload) > 0 {
+		if err := CheckPreloadLibs(params.LDPreload); err != nil {
+			return 0, nil, &ExecError{Stage: StageDoExec, Err: err}
+		}
+	}
+
 	// Populate $CREDENTIALS_DIRECTORY from the configured sources.
 	// A failure aborts the start — running without an expected
 	// credential is worse than not running, and secrets are by design
@@ -80,13 +88,37 @@ func StartProcess(params ExecParams) (int, <-chan ChildExit, error) {
 		command = wrapWithRunner(params)
 	}
 
+	// exec-memfd: run a copy of the binary held in memory. The parent
+	// closes its memfd on every return path; the child's copy goes
+	// away at exec, after the kernel has opened the image.
+	var memfd *os.File
+	if params.MemfdExec {
+		if wrapped {
+			return 0, nil, &ExecError{Stage: StageDoExec,
+				Err: fmt.Errorf("exec-memfd cannot be combined with settings that need slinit-runner")}
+		}
+		path, err := exec.LookPath(command[0])
+		if err != nil {
+			return 0, nil, &ExecError{Stage: StageDoExec, Err: err}
+		}
+		memfd, err = loadMemfd(path)
+		if err != nil {
+			return 0, nil, &ExecError{Stage: StageDoExec, Err: err}
+		}
+		defer func() { memfd.Close() }()
+		command = append([]string{memfdExecPath(memfd)}, command[1:]...)
+	}
+
 	cmd := exec.Command(command[0], command[1:]...)
 
 	// argv[0] override (runit chpst -b). Only apply in the unwrapped
 	// path — wrapWithRunner emits --argv0 so the runner does the
-	// substitution across its own exec.
+	// substitution across its own exec. A memfd exec keeps the
+	// configured name rather than the /proc/self/fd path.
 	if !wrapped && params.Argv0 != "" {
 		cmd.Args[0] = params.Argv0
+	} else if memfd != nil {
+		cmd.Args[0] = params.Command[0]
 	}
 
 	// Working directory
@@ -102,6 +134,17 @@ func StartProcess(params ExecParams) (int, <-chan ChildExit, error) {
 		cmd.Env = append(cmd.Env, params.Env...)
 	}
 
+	// ld-preload / ld-library-path go in front of any value the
+	// environment (env-file included) already carries.
+	if len(params.LDPreload) > 0 || len(params.LDLibraryPath) > 0 {
+		if cmd.Env == nil {
+			cmd.Env = make([]string, len(baseEnv), len(baseEnv)+2)
+			copy(cmd.Env, baseEnv)
+		}
+		cmd.Env = prependEnvList(cmd.Env, "LD_PRELOAD", params.LDPreload)
+		cmd.Env = prependEnvList(cmd.Env, "LD_LIBRARY_PATH", params.LDLibraryPath)
+	}
+
 	// Set process group so we can signal the group later
 	cmd.SysProcAttr = &syscall.SysProcAttr{
 		Setpgid: true,
@@ -190,6 +233,21 @@ func StartProcess(params ExecParams) (int, <-chan ChildExit, error) {
 		// lockFD stays open for the lifetime of the process (flock released on close)
 	}
 
+	// Tee output to a log file next to the primary pipe. The parent's
+	// copy of the tee's write end is closed on every return path; the
+	// tee goroutine finishes once the child's copies are gone too.
+	if mo := params.MultiOutput; mo != nil && !params.OnConsole {
+		tee, err := NewTeeWriter(mo.PrimaryPipe, mo.SecondaryFile, mo.SecondaryPerms)
+		if err != nil {
+			return 0, nil, &ExecError{Stage: StageOpenLogFile, Err: err}
+		}
+		if mo.SecondaryUID >= 0 || mo.SecondaryGID >= 0 {
+			_ = tee.file.Chown(mo.SecondaryUID, mo.SecondaryGID)
+		}
+		defer tee.CloseWriteEnd()
+		params.OutputPipe = tee.WriteEnd()
+	}
+
 	// Virtual TTY: open slave PTY as stdin/stdout/stderr, create new session
 	var ptySlaveFd *os.File
 	if params.PTYSlave != "" {
@@ -270,6 +328,18 @@ func StartProcess(params ExecParams) (int, <-chan ChildExit, error) {
 		cmd.Stdin = bytesReader(params.StdinBytes)
 	}
 
+	// stdin / stderr settings win over the default wiring above.
+	stdioFiles, err := redirectStdio(cmd, &params)
+	if err != nil {
+		if lockFD != nil {
+			lockFD.Close()
+		}
+		return 0, nil, err
+	}
+	for _, f := range stdioFiles {
+		defer f.Close()
+	}
+
 	// Close stdin/stdout/stderr: redirect to /dev/null (runit -0/-1/-2 style)
 	if params.CloseStdin && cmd.Stdin == nil {
 		devNull, err := os.Open("/dev/null")
@@ -293,6 +363,16 @@ func StartProcess(params ExecParams) (int, <-chan ChildExit, error) {
 		}
 	}
 
+	captureDone := func() {}
+	if params.ErrorCapture != nil {
+		if captureDone, err = captureStderr(cmd, params.ErrorCapture); err != nil {
+			if lockFD != nil {
+				lockFD.Close()
+			}
+			return 0, nil, &ExecError{Stage: StageArrangeFDs, Err: err}
+		}
+	}
+
 	// Set up extra file descriptors for the child process.
 	// ExtraFiles[i] becomes fd 3+i in the child.
 	//
@@ -334,6 +414,7 @@ func StartProcess(params ExecParams) (int, <-chan ChildExit, error) {
 			for _, prior := range openedForClose {
 				prior.Close()
 			}
+			captureDone()
 			return 0, nil, fmt.Errorf("open-file %q: %w", of.Path, err)
 		}
 		cmd.ExtraFiles = append(cmd.ExtraFiles, f)
@@ -389,6 +470,7 @@ func StartProcess(params ExecParams) (int, <-chan ChildExit, error) {
 		for len(cmd.ExtraFiles) < slotIndex {
 			devNull, err := os.Open("/dev/null")
 			if err != nil {
+				captureDone()
 				return 0, nil, &ExecError{Stage: StageArrangeFDs, Err: err}
 			}
 			extraFdNullFiles = append(extraFdNullFiles, devNull)
@@ -418,6 +500,17 @@ func StartProcess(params ExecParams) (int, <-chan ChildExit, error) {
 		cmd.Env = append(cmd.Env, fmt.Sprintf("SLINIT_CS_FD=%d", csFD))
 	}
 
+	// The child dup2()s stdio and ExtraFiles into fds 0..n-1, using
+	// fds n..2n-1 as scratch space; keep the memfd clear of both.
+	if memfd != nil {
+		memfd, err = moveMemfdAbove(memfd, 2*(3+len(cmd.ExtraFiles)))
+		if err != nil {
+			captureDone()
+			return 0, nil, &ExecError{Stage: StageArrangeFDs, Err: err}
+		}
+		cmd.Path = memfdExecPath(memfd)
+	}
+
 	// Cgroup pre-attach: open the target cgroup as a directory fd and
 	// route fork through clone3+CLONE_INTO_CGROUP. Without this, the
 	// child shell could fork (e.g. setsid'd) grandchildren in the root
@@ -448,8 +541,9 @@ func StartProcess(params ExecParams) (int, <-chan ChildExit, error) {
 		prevUmask = syscall.Umask(int(*params.Umask))
 	}
 
-	// Start the process
-	err = cmd.Start()
+	// Start the process. A transient failure is returned as is; the
+	// caller decides whether and when to retry (see ExecRetry).
+	exited, err := DefaultReaper.StartCmd(cmd)
 	if prevUmask >= 0 {
 		syscall.Umask(prevUmask)
 	}
@@ -469,6 +563,7 @@ func StartProcess(params ExecParams) (int, <-chan ChildExit, error) {
 		if lockFD != nil {
 			lockFD.Close()
 		}
+		captureDone()
 		// Roll back the credentials tmpfs so a failed start does not
 		// leave a populated /run/credentials/<svc>/ behind.
 		if credDir != "" {
@@ -507,48 +602,20 @@ func StartProcess(params ExecParams) (int, <-chan ChildExit, error) {
 
 	exitCh := make(chan ChildExit, 1)
 
-	// Register with the exit router BEFORE the wait goroutine starts. If
-	// PID 1's SIGCHLD handler reaps this child before cmd.Wait() does,
-	// the router delivers the real WaitStatus here; otherwise cmd.Wait()
-	// is the source of truth. Without this, an orphan-reaper win silently
-	// loses the exit code and finish-command sees ExitStatus()==0.
-	routedCh := DefaultExitRouter.Register(pid)
-
-	// Goroutine that waits for the process to finish
+	// Goroutine that waits for the process to finish. Under PID 1 the
+	// status comes from DefaultReaper, which registered the pid before
+	// it could be reaped; WaitCmd also finishes the ErrorCapture stderr
+	// copy (bounded by WaitDelay), which the caller reads once it sees
+	// the exit.
 	go func() {
 		defer close(exitCh)
-		defer DefaultExitRouter.Unregister(pid)
 		// Release lock file when process exits
 		if lockFD != nil {
 			defer lockFD.Close()
 		}
 
-		// Run cmd.Wait() in a sub-goroutine so we can race it against
-		// the router. Buffered cap 1 so the sub-goroutine's send never
-		// blocks if the router wins.
-		waitDone := make(chan syscall.WaitStatus, 1)
-		go func() {
-			err := cmd.Wait()
-			var status syscall.WaitStatus
-			if err != nil {
-				if exitErr, ok := err.(*exec.ExitError); ok {
-					status = exitErr.Sys().(syscall.WaitStatus)
-				}
-			}
-			waitDone <- status
-		}()
-
-		var status syscall.WaitStatus
-		select {
-		case status = <-routedCh:
-			// Orphan reaper got there first. Drain the cmd.Wait() goroutine
-			// in the background so it doesn't leak — Wait4 will eventually
-			// return ECHILD now that the child is reaped.
-			go func() { <-waitDone }()
-		case status = <-waitDone:
-			// cmd.Wait() won the race; routedCh will be unregistered by
-			// the deferred Unregister above.
-		}
+		status, _ := DefaultReaper.WaitCmd(cmd, exited)
+		captureDone()
 
 		exitCh <- ChildExit{
 			PID:    pid,
@@ -574,15 +641,19 @@ func SignalProcess(pid int, sig syscall.Signal, processOnly bool) error {
 
 // KillProcessGroup sends SIGKILL to all remaining processes in a process
 // group and reaps their zombie entries. The group leader should already have
-// been reaped by cmd.Wait(). Because each service uses Setpgid, the pgid
-// equals the leader's PID. Using wait4(-pgid) is safe: it only reaps
-// children in this specific group, never other managed service processes.
+// been reaped. Because each service uses Setpgid, the pgid equals the
+// leader's PID. Using wait4(-pgid) is safe: it only reaps children in this
+// specific group, never other managed service processes. While
+// DefaultReaper runs, the members are left to it instead.
 func KillProcessGroup(pgid int) {
 	if pgid <= 0 {
 		return
 	}
 	// Kill remaining group members (ESRCH if group is already empty)
 	_ = syscall.Kill(-pgid, syscall.SIGKILL)
+	if DefaultReaper.Running() {
+		return
+	}
 	// Reap zombies from this specific group
 	for {
 		var status syscall.WaitStatus
@@ -626,7 +697,7 @@ func loadAppArmorProfile(path string) error {
 			return fmt.Errorf("apparmor_parser not found: %w", err)
 		}
 	}
-	out, runErr := exec.Command(bin, "-r", path).CombinedOutput()
+	out, runErr := CombinedOutput(exec.Command(bin, "-r", path))
 	if runErr != nil {
 		return fmt.Errorf("apparmor_parser -r %s: %w: %s",
 			path, runErr, strings.TrimSpace(string(out)))
@@ -1,8 +1,10 @@
 package process
 
 import (
+	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"syscall"
 	"testing"
 )
@@ -34,6 +36,43 @@ func TestStartProcessWithUserNamespace(t *testing.T) {
 	}
 }
 
+// TestStartProcessUserNamespaceIdentity checks the default mapping: the
+// child is root inside its namespace but the caller's UID outside.
+func TestStartProcessUserNamespaceIdentity(t *testing.T) {
+	params := ExecParams{
+		Command:    []string{"/bin/sh", "-c", `[ "$(id -u)" = 0 ] && [ "$(id -g)" = 0 ] && sleep 0.5`},
+		Cloneflags: syscall.CLONE_NEWUSER,
+	}
+
+	pid, ch, err := StartProcess(params)
+	if err != nil {
+		t.Skipf("user namespace not supported: %v", err)
+	}
+
+	// The kernel reports IDs as seen from the reader's namespace.
+	status, err := os.ReadFile(fmt.Sprintf("/proc/%d/status", pid))
+	if err != nil {
+		t.Fatalf("read status: %v", err)
+	}
+	var outsideUID int
+	for _, line := range strings.Split(string(status), "\n") {
+		if strings.HasPrefix(line, "Uid:") {
+			fmt.Sscanf(line, "Uid:\t%d", &outsideUID)
+		}
+	}
+	if outsideUID != os.Getuid() {
+		t.Errorf("UID outside the namespace = %d, want %d", outsideUID, os.Getuid())
+	}
+
+	exit := <-ch
+	if exit.ExecErr != nil {
+		t.Skipf("exec failed in user ns: %v", exit.ExecErr)
+	}
+	if !exit.ExitedClean() {
+		t.Errorf("child did not see itself as root inside the namespace: %v", exit.Status)
+	}
+}
+
 func TestStartProcessWithCustomUidGidMappings(t *testing.T) {
 	uid := os.Getuid()
 	gid := os.Getgid()
@@ -0,0 +1,65 @@
+package process
+
+import (
+	"errors"
+	"syscall"
+	"time"
+)
+
+// ETXTBSY means the binary is open for writing — typically a package
+// manager replacing it in place. It clears within milliseconds, so it is
+// retried even when the service did not configure exec retries.
+const (
+	etxtbsyRetries  = 5
+	etxtbsyInterval = 100 * time.Millisecond
+)
+
+// ExecRetry controls retrying of fork/exec failures that are likely to be
+// transient (ENOMEM / EAGAIN under memory or process-table pressure).
+// StartProcess makes a single attempt; the caller schedules the retry,
+// so that nothing waits on the delay.
+type ExecRetry struct {
+	// MaxAttempts is the total number of start attempts. Values <= 1
+	// disable retrying (ETXTBSY is still retried, see etxtbsyRetries).
+	MaxAttempts int
+
+	// RetryInterval is the delay between attempts.
+	RetryInterval time.Duration
+}
+
+// ExecRetryState counts the retries already made for one start. The
+// zero value is a start that has not been retried.
+type ExecRetryState struct {
+	retries int // retries of transient errors under ExecRetry
+	txtbsy  int // retries of ETXTBSY
+}
+
+// isTransientExecErr reports whether err is worth retrying under the
+// service's ExecRetry policy.
+func isTransientExecErr(err error) bool {
+	return errors.Is(err, syscall.ENOMEM) || errors.Is(err, syscall.EAGAIN)
+}
+
+// Next reports whether a start that failed with err should be tried
+// again under retry, and how long to wait first. Only failures of the
+// fork/exec itself qualify. A true result counts the retry in st.
+func (st *ExecRetryState) Next(retry ExecRetry, err error) (time.Duration, bool) {
+	var execErr *ExecError
+	if !errors.As(err, &execErr) || execErr.Stage != StageDoExec {
+		return 0, false
+	}
+	switch {
+	case errors.Is(execErr.Err, syscall.ETXTBSY) && st.txtbsy < etxtbsyRetries:
+		st.txtbsy++
+		return etxtbsyInterval, true
+	case isTransientExecErr(execErr.Err) && st.retries+1 < retry.MaxAttempts:
+		st.retries++
+		return retry.RetryInterval, true
+	}
+	return 0, false
+}
+
+// Retries returns the number of retries made so far, of either kind.
+func (st *ExecRetryState) Retries() int {
+	return st.retries + st.txtbsy
+}
@@ -0,0 +1,68 @@
+package process
+
+import (
+	"os"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func doExecErr(errno syscall.Errno) error {
+	return &ExecError{Stage: StageDoExec, Err: &os.PathError{Op: "fork/exec", Path: "x", Err: errno}}
+}
+
+func TestExecRetryETXTBSY(t *testing.T) {
+	// ETXTBSY is retried without any configured policy, up to its cap.
+	var st ExecRetryState
+	for i := range etxtbsyRetries {
+		d, ok := st.Next(ExecRetry{}, doExecErr(syscall.ETXTBSY))
+		if !ok || d != etxtbsyInterval {
+			t.Fatalf("retry %d: got (%v, %v), want (%v, true)", i, d, ok, etxtbsyInterval)
+		}
+	}
+	if _, ok := st.Next(ExecRetry{}, doExecErr(syscall.ETXTBSY)); ok {
+		t.Error("ETXTBSY retried past its cap")
+	}
+}
+
+func TestExecRetryTransient(t *testing.T) {
+	retry := ExecRetry{MaxAttempts: 3, RetryInterval: 50 * time.Millisecond}
+	var st ExecRetryState
+	// Three attempts in total: the first start plus two retries.
+	for i := range 2 {
+		d, ok := st.Next(retry, doExecErr(syscall.EAGAIN))
+		if !ok || d != retry.RetryInterval {
+			t.Fatalf("retry %d: got (%v, %v)", i, d, ok)
+		}
+	}
+	if _, ok := st.Next(retry, doExecErr(syscall.ENOMEM)); ok {
+		t.Error("retried past exec-retry-count")
+	}
+
+	// Without a policy, transient errors are not retried at all.
+	if _, ok := new(ExecRetryState).Next(ExecRetry{}, doExecErr(syscall.ENOMEM)); ok {
+		t.Error("ENOMEM retried without exec-retry-count")
+	}
+}
+
+func TestExecRetryPermanentError(t *testing.T) {
+	retry := ExecRetry{MaxAttempts: 3, RetryInterval: time.Millisecond}
+	if _, ok := new(ExecRetryState).Next(retry, doExecErr(syscall.ENOENT)); ok {
+		t.Error("ENOENT must not be retried")
+	}
+	// A failure before the fork (here setting up fds) is not an exec
+	// failure, whatever its errno.
+	err := &ExecError{Stage: StageArrangeFDs, Err: syscall.EAGAIN}
+	if _, ok := new(ExecRetryState).Next(retry, err); ok {
+		t.Error("non-exec stage must not be retried")
+	}
+}
+
+func TestIsTransientExecErr(t *testing.T) {
+	if !isTransientExecErr(&os.PathError{Op: "fork/exec", Path: "x", Err: syscall.ENOMEM}) {
+		t.Error("ENOMEM should be transient")
+	}
+	if isTransientExecErr(&os.PathError{Op: "fork/exec", Path: "x", Err: syscall.EACCES}) {
+		t.Error("EACCES should not be transient")
+	}
+}
@@ -0,0 +1,63 @@
+package process
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// loadMemfd copies the executable at path into an anonymous memfd and
+// returns it. Exec'ing /proc/self/fd/N of the result runs the program
+// without the filesystem copy being the running image: once the
+// service has started, /proc/<pid>/exe names the memfd, not path.
+//
+// The memfd is close-on-exec. The kernel resolves the /proc path
+// before the close happens, so ELF binaries run fine; a #! script
+// does not, as its interpreter would have to reopen the path.
+func loadMemfd(path string) (*os.File, error) {
+	src, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer src.Close()
+
+	fd, err := unix.MemfdCreate("svc", unix.MFD_CLOEXEC)
+	if err != nil {
+		return nil, fmt.Errorf("memfd_create: %w", err)
+	}
+	mem := os.NewFile(uintptr(fd), "memfd:svc")
+	if _, err := io.Copy(mem, src); err != nil {
+		mem.Close()
+		return nil, fmt.Errorf("copy %s to memfd: %w", path, err)
+	}
+	if err := mem.Chmod(0o500); err != nil {
+		mem.Close()
+		return nil, err
+	}
+	return mem, nil
+}
+
+// memfdExecPath is the path that executes mem in the child. The child
+// inherits the parent's descriptor table at fork, so the fd number is
+// the same on both sides until exec.
+func memfdExecPath(mem *os.File) string {
+	return fmt.Sprintf("/proc/self/fd/%d", mem.Fd())
+}
+
+// moveMemfdAbove re-numbers mem so that it is at least minFD, closing
+// the old descriptor. Between fork and exec the child rearranges the
+// low fds for stdio and ExtraFiles; a memfd among them would be
+// replaced before the exec that names it.
+func moveMemfdAbove(mem *os.File, minFD int) (*os.File, error) {
+	if int(mem.Fd()) >= minFD {
+		return mem, nil
+	}
+	nfd, err := unix.FcntlInt(mem.Fd(), unix.F_DUPFD_CLOEXEC, minFD)
+	if err != nil {
+		return mem, fmt.Errorf("move memfd: %w", err)
+	}
+	mem.Close()
+	return os.NewFile(uintptr(nfd), mem.Name()), nil
+}
@@ -0,0 +1,75 @@
+package process
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestStartProcessMemfdExec(t *testing.T) {
+	bin, err := filepath.EvalSymlinks("/bin/sleep")
+	if err != nil {
+		t.Skipf("no sleep binary: %v", err)
+	}
+	// A notify pipe forced to fd 6 fills fds 3..6 in the child, which
+	// a low memfd number would collide with.
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	defer w.Close()
+	params := ExecParams{
+		Command:       []string{"sleep", "5"},
+		MemfdExec:     true,
+		NotifyPipe:    w,
+		ForceNotifyFD: 6,
+	}
+
+	pid, ch, err := StartProcess(params)
+	if err != nil {
+		t.Skipf("memfd exec not supported: %v", err)
+	}
+	defer func() {
+		syscall.Kill(pid, syscall.SIGKILL)
+		<-ch
+	}()
+
+	// StartProcess returns once the child is forked; wait for the
+	// exec to complete before inspecting it.
+	var exe string
+	var cmdline []byte
+	for deadline := time.Now().Add(2 * time.Second); time.Now().Before(deadline); time.Sleep(5 * time.Millisecond) {
+		exe, _ = os.Readlink(fmt.Sprintf("/proc/%d/exe", pid))
+		cmdline, _ = os.ReadFile(fmt.Sprintf("/proc/%d/cmdline", pid))
+		if strings.HasPrefix(exe, "/memfd:") && len(cmdline) > 0 {
+			break
+		}
+	}
+	if exe == bin || !strings.HasPrefix(exe, "/memfd:svc") {
+		t.Errorf("/proc/%d/exe = %q, want the memfd rather than %s", pid, exe, bin)
+	}
+	if argv0, _, _ := strings.Cut(string(cmdline), "\x00"); argv0 != "sleep" {
+		t.Errorf("argv[0] = %q, want sleep", argv0)
+	}
+	fds, _ := os.ReadDir(fmt.Sprintf("/proc/%d/fd", pid))
+	for _, fd := range fds {
+		if target, _ := os.Readlink(fmt.Sprintf("/proc/%d/fd/%s", pid, fd.Name())); strings.HasPrefix(target, "/memfd:") {
+			t.Errorf("child still holds the memfd as fd %s", fd.Name())
+		}
+	}
+}
+
+func TestStartProcessMemfdExecMissingBinary(t *testing.T) {
+	_, _, err := StartProcess(ExecParams{
+		Command:   []string{"/nonexistent/binary"},
+		MemfdExec: true,
+	})
+	if err == nil {
+		t.Fatal("expected an error for a missing binary")
+	}
+}
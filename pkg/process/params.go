@@ -2,6 +2,7 @@
 package process
 
 import (
+	"bytes"
 	"fmt"
 	"os"
 	"syscall"
@@ -83,6 +84,12 @@ type ExecParams struct {
 	// invoked with --argv0 so the override survives the intermediate exec.
 	Argv0 string
 
+	// MemfdExec loads Command[0] into a memfd and executes that instead
+	// of the file (exec-memfd), so the running image is not the one on
+	// disk. Command[0] must be an ELF binary. Not available together
+	// with settings that go through slinit-runner.
+	MemfdExec bool
+
 	// WorkingDir is the working directory for the process.
 	WorkingDir string
 
@@ -121,6 +128,11 @@ type ExecParams struct {
 	// StartProcess returns. Ignored when OnConsole is true.
 	OutputPipe *os.File
 
+	// MultiOutput, if non-nil, tees the child's stdout and stderr into a
+	// primary pipe and a log file (log-type = buffer with a logfile).
+	// Takes precedence over OutputPipe. Ignored when OnConsole is true.
+	MultiOutput *MultiOutput
+
 	// ErrorPipe, if non-nil, is the write end of a pipe used to capture
 	// the child's stderr separately from stdout. When set, OutputPipe
 	// captures only stdout and ErrorPipe captures stderr. Used by the
@@ -128,6 +140,13 @@ type ExecParams struct {
 	// it after StartProcess returns.
 	ErrorPipe *os.File
 
+	// ErrorCapture, if non-nil, receives a copy of the child's stderr
+	// (the last MaxErrorCapture bytes) on top of wherever stderr goes
+	// otherwise, so a failure can be reported with its error message.
+	// It is complete once the exit is delivered, and must not be read
+	// before.
+	ErrorCapture *bytes.Buffer
+
 	// InputPipe, if non-nil, is the read end of a pipe used as the child's
 	// stdin. Used for consumer-of services. The caller should NOT close it
 	// after StartProcess (the pipe persists across restarts).
@@ -189,6 +208,14 @@ type ExecParams struct {
 	// NoNewPrivs sets PR_SET_NO_NEW_PRIVS on the child process.
 	NoNewPrivs bool
 
+	// LDPreload and LDLibraryPath are prepended to the child's
+	// LD_PRELOAD and LD_LIBRARY_PATH. With PreloadSecurityCheck the
+	// start fails unless every LDPreload entry is root-owned and not
+	// world-writable.
+	LDPreload            []string
+	LDLibraryPath        []string
+	PreloadSecurityCheck bool
+
 	// AmbientCaps is the list of ambient capabilities (CAP_* numbers)
 	// to set on the child process via SysProcAttr.AmbientCaps.
 	AmbientCaps []uintptr
@@ -363,6 +390,12 @@ type ExecParams struct {
 	// If the lock cannot be acquired, the process fails to start.
 	LockFile string
 
+	// EnvFile, SocketPaths and PIDFile are not used to start the
+	// process; PreExecCheck validates them before a start is attempted.
+	EnvFile     string
+	SocketPaths []string
+	PIDFile     string
+
 	// PTYSlave, if non-empty, is the path to a PTY slave device.
 	// When set, the child's stdin/stdout/stderr are connected to this PTY
 	// and a new session is created (setsid + TIOCSCTTY) so the PTY becomes
@@ -388,6 +421,14 @@ type ExecParams struct {
 	// CloseStderr closes fd 2 in the child process.
 	CloseStderr bool
 
+	// StdinRedirect and StderrRedirect override where fd 0 and fd 2
+	// point (the stdin / stderr settings); StdinPath and StderrPath
+	// name the file for StdinFile and StderrFile.
+	StdinRedirect  StdinMode
+	StdinPath      string
+	StderrRedirect StderrMode
+	StderrPath     string
+
 	// Filesystem sandbox (systemd-style), applied by slinit-runner in
 	// the service's private mount namespace. The loader auto-implies
 	// CLONE_NEWNS into Cloneflags whenever any of these are set.
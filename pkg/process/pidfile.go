@@ -8,6 +8,8 @@ import (
 	"strconv"
 	"strings"
 	"syscall"
+
+	"golang.org/x/sys/unix"
 )
 
 // PIDResult represents the outcome of reading a PID file.
@@ -37,16 +39,41 @@ func ReadPIDFile(path string) (int, PIDResult, error) {
 		return 0, PIDResultFailed, fmt.Errorf("reading PID file: %w", err)
 	}
 	defer f.Close()
+	pid, err := readPID(f)
+	if err != nil {
+		return 0, PIDResultFailed, err
+	}
+
+	// Check if process exists
+	err = syscall.Kill(pid, 0)
+	if err == nil {
+		return pid, PIDResultOK, nil
+	}
+
+	if errors.Is(err, syscall.ESRCH) {
+		return pid, PIDResultTerminated, nil
+	}
+
+	// EPERM means the process exists but we don't have permission to signal it
+	if errors.Is(err, syscall.EPERM) {
+		return pid, PIDResultOK, nil
+	}
+
+	return pid, PIDResultFailed, fmt.Errorf("checking process %d: %w", pid, err)
+}
+
+// readPID parses the PID on the first line of a PID file.
+func readPID(r io.Reader) (int, error) {
 	// Cap the read so a hostile/junk file can't drive an unbounded
 	// allocation. A real PID file is < 64 bytes; 4 KiB is generous.
-	data, err := io.ReadAll(io.LimitReader(f, 4096))
+	data, err := io.ReadAll(io.LimitReader(r, 4096))
 	if err != nil {
-		return 0, PIDResultFailed, fmt.Errorf("reading PID file: %w", err)
+		return 0, fmt.Errorf("reading PID file: %w", err)
 	}
 
 	content := strings.TrimSpace(string(data))
 	if content == "" {
-		return 0, PIDResultFailed, errors.New("PID file is empty")
+		return 0, errors.New("PID file is empty")
 	}
 
 	// PID file may contain PID on first line followed by other data
@@ -56,27 +83,61 @@ func ReadPIDFile(path string) (int, PIDResult, error) {
 
 	pid, err := strconv.Atoi(strings.TrimSpace(content))
 	if err != nil {
-		return 0, PIDResultFailed, fmt.Errorf("invalid PID in file: %w", err)
+		return 0, fmt.Errorf("invalid PID in file: %w", err)
 	}
 
 	if pid <= 0 {
-		return 0, PIDResultFailed, fmt.Errorf("invalid PID value: %d", pid)
+		return 0, fmt.Errorf("invalid PID value: %d", pid)
 	}
+	return pid, nil
+}
 
-	// Check if process exists
-	err = syscall.Kill(pid, 0)
-	if err == nil {
-		return pid, PIDResultOK, nil
-	}
+// ErrPIDLockUnsupported is returned by ReadAndLockPIDFile when the file
+// system cannot answer the lock test (e.g. some NFS setups), so the
+// caller has to fall back to ReadPIDFile's kill(pid, 0) check.
+var ErrPIDLockUnsupported = errors.New("PID file locking not supported")
 
-	if errors.Is(err, syscall.ESRCH) {
-		return pid, PIDResultTerminated, nil
+// ReadAndLockPIDFile reads a process ID from path and decides whether
+// the daemon is alive by trying to take an exclusive flock on the file,
+// for daemons that hold such a lock on their PID file while they run.
+// Unlike kill(pid, 0) this cannot be fooled by a recycled PID.
+//
+// When the lock is taken the daemon is gone (a stale PID file): lockFd
+// is the locked descriptor, which the caller must close, and may hold
+// to keep a restarted daemon from racing it. When the daemon holds the
+// lock, lockFd is -1 and err nil. The file is opened with O_NOFOLLOW
+// for the same reason as in ReadPIDFile.
+func ReadAndLockPIDFile(path string) (pid int, lockFd int, err error) {
+	fd, err := unix.Open(path, unix.O_RDONLY|unix.O_NOFOLLOW|unix.O_CLOEXEC, 0)
+	if err != nil {
+		return 0, -1, fmt.Errorf("reading PID file: %w", err)
 	}
-
-	// EPERM means the process exists but we don't have permission to signal it
-	if errors.Is(err, syscall.EPERM) {
-		return pid, PIDResultOK, nil
+	// Read through a dup so closing the *os.File leaves fd open.
+	dup, err := unix.Dup(fd)
+	if err != nil {
+		unix.Close(fd)
+		return 0, -1, fmt.Errorf("reading PID file: %w", err)
+	}
+	f := os.NewFile(uintptr(dup), path)
+	pid, err = readPID(f)
+	f.Close()
+	if err != nil {
+		unix.Close(fd)
+		return 0, -1, err
 	}
 
-	return pid, PIDResultFailed, fmt.Errorf("checking process %d: %w", pid, err)
+	err = unix.Flock(fd, unix.LOCK_EX|unix.LOCK_NB)
+	switch {
+	case err == nil:
+		return pid, fd, nil
+	case errors.Is(err, unix.EWOULDBLOCK):
+		unix.Close(fd)
+		return pid, -1, nil
+	case errors.Is(err, unix.ENOLCK), errors.Is(err, unix.EINVAL), errors.Is(err, unix.EOPNOTSUPP):
+		unix.Close(fd)
+		return pid, -1, fmt.Errorf("%w: %v", ErrPIDLockUnsupported, err)
+	default:
+		unix.Close(fd)
+		return pid, -1, fmt.Errorf("locking PID file: %w", err)
+	}
 }
@@ -5,6 +5,8 @@ import (
 	"path/filepath"
 	"strconv"
 	"testing"
+
+	"golang.org/x/sys/unix"
 )
 
 func TestReadPIDFileValid(t *testing.T) {
@@ -70,3 +72,55 @@ func TestReadPIDFileNotFound(t *testing.T) {
 		t.Error("expected error for missing file")
 	}
 }
+
+func TestReadAndLockPIDFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "daemon.pid")
+	if err := os.WriteFile(path, []byte("4242\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// Nobody holds the lock: the PID file is stale.
+	pid, lockFd, err := ReadAndLockPIDFile(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if pid != 4242 || lockFd < 0 {
+		t.Fatalf("got pid %d lockFd %d, want 4242 and a held lock", pid, lockFd)
+	}
+	unix.Close(lockFd)
+
+	// A "daemon" holding the lock is alive, whatever its PID.
+	daemon, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer daemon.Close()
+	if err := unix.Flock(int(daemon.Fd()), unix.LOCK_EX); err != nil {
+		t.Fatal(err)
+	}
+	pid, lockFd, err = ReadAndLockPIDFile(path)
+	if err != nil || pid != 4242 || lockFd != -1 {
+		t.Errorf("got pid %d lockFd %d err %v, want 4242, -1, nil", pid, lockFd, err)
+	}
+}
+
+func TestReadAndLockPIDFileErrors(t *testing.T) {
+	dir := t.TempDir()
+	if _, _, err := ReadAndLockPIDFile(filepath.Join(dir, "missing.pid")); err == nil {
+		t.Error("expected an error for a missing file")
+	}
+	bad := filepath.Join(dir, "bad.pid")
+	if err := os.WriteFile(bad, []byte("nope\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, lockFd, err := ReadAndLockPIDFile(bad); err == nil || lockFd != -1 {
+		t.Errorf("got lockFd %d err %v, want an error and no lock", lockFd, err)
+	}
+	link := filepath.Join(dir, "link.pid")
+	if err := os.Symlink(bad, link); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := ReadAndLockPIDFile(link); err == nil {
+		t.Error("symlinked PID file should be refused")
+	}
+}
@@ -0,0 +1,128 @@
+package process
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/user"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"golang.org/x/sys/unix"
+)
+
+// Errors returned (wrapped) by PreExecCheck. Use errors.Is to tell them
+// apart; the wrapping error names the offending path or ID.
+var (
+	ErrCommandNotFound       = errors.New("command not found")
+	ErrCommandNotExecutable  = errors.New("command not executable")
+	ErrWorkingDirMissing     = errors.New("working directory missing")
+	ErrEnvFileUnreadable     = errors.New("env-file unreadable")
+	ErrSocketDirNotWritable  = errors.New("socket directory not writable")
+	ErrPIDFileDirNotWritable = errors.New("pid-file directory not writable")
+	ErrUnknownUser           = errors.New("unknown user")
+	ErrUnknownGroup          = errors.New("unknown group")
+)
+
+// PreExecCheck validates, before any fork, the conditions a start needs:
+// the command exists and is executable, the working directory exists,
+// the env-file is readable, socket and pid-file directories are
+// writable, and the run-as UID/GID exist. It returns the first problem
+// found, so a misconfigured service fails with a precise message instead
+// of a generic exec error (or a start timeout for bgprocess).
+//
+// Paths under Chroot are resolved inside it; a bare command name under
+// Chroot is not checked since the chroot's PATH is unknown.
+func PreExecCheck(params ExecParams) error {
+	if len(params.Command) > 0 {
+		if err := checkCommand(params); err != nil {
+			return err
+		}
+	}
+
+	if params.WorkingDir != "" {
+		dir := inChroot(params.Chroot, params.WorkingDir)
+		if info, err := os.Stat(dir); err != nil {
+			return fmt.Errorf("%w: %s", ErrWorkingDirMissing, params.WorkingDir)
+		} else if !info.IsDir() {
+			return fmt.Errorf("%w: %s is not a directory", ErrWorkingDirMissing, params.WorkingDir)
+		}
+	}
+
+	if params.EnvFile != "" {
+		if err := unix.Access(params.EnvFile, unix.R_OK); err != nil {
+			return fmt.Errorf("%w: %s: %v", ErrEnvFileUnreadable, params.EnvFile, err)
+		}
+	}
+
+	for _, path := range params.SocketPaths {
+		// tcp:/udp: addresses have no directory.
+		if path == "" || strings.Contains(path, ":") {
+			continue
+		}
+		if dir := filepath.Dir(path); unix.Access(dir, unix.W_OK) != nil {
+			return fmt.Errorf("%w: %s", ErrSocketDirNotWritable, dir)
+		}
+	}
+
+	if params.PIDFile != "" {
+		if dir := filepath.Dir(params.PIDFile); unix.Access(dir, unix.W_OK) != nil {
+			return fmt.Errorf("%w: %s", ErrPIDFileDirNotWritable, dir)
+		}
+	}
+
+	if params.RunAsUID != 0 {
+		id := strconv.FormatUint(uint64(params.RunAsUID), 10)
+		var unknown user.UnknownUserIdError
+		if _, err := user.LookupId(id); errors.As(err, &unknown) {
+			return fmt.Errorf("%w: uid %s", ErrUnknownUser, id)
+		}
+	}
+	if params.RunAsGID != 0 {
+		id := strconv.FormatUint(uint64(params.RunAsGID), 10)
+		var unknown user.UnknownGroupIdError
+		if _, err := user.LookupGroupId(id); errors.As(err, &unknown) {
+			return fmt.Errorf("%w: gid %s", ErrUnknownGroup, id)
+		}
+	}
+	return nil
+}
+
+// checkCommand verifies that Command[0] resolves to an executable file.
+func checkCommand(params ExecParams) error {
+	name := params.Command[0]
+	if !strings.Contains(name, "/") {
+		if params.Chroot != "" {
+			return nil
+		}
+		if _, err := exec.LookPath(name); err != nil {
+			return fmt.Errorf("%w: %s", ErrCommandNotFound, name)
+		}
+		return nil
+	}
+
+	path := name
+	if !filepath.IsAbs(path) && params.WorkingDir != "" {
+		path = filepath.Join(params.WorkingDir, path)
+	}
+	path = inChroot(params.Chroot, path)
+	info, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrCommandNotFound, name)
+	}
+	if info.IsDir() || info.Mode()&0111 == 0 {
+		return fmt.Errorf("%w: %s", ErrCommandNotExecutable, name)
+	}
+	return nil
+}
+
+// inChroot maps an absolute path to where it lives on the host when the
+// child is chrooted to root.
+func inChroot(root, path string) string {
+	if root == "" || !filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(root, path)
+}
@@ -0,0 +1,90 @@
+package process
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestPreExecCheckOK(t *testing.T) {
+	dir := t.TempDir()
+	envFile := filepath.Join(dir, "env")
+	if err := os.WriteFile(envFile, []byte("A=1\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	params := ExecParams{
+		Command:     []string{"sh", "-c", "true"},
+		WorkingDir:  dir,
+		EnvFile:     envFile,
+		SocketPaths: []string{filepath.Join(dir, "svc.sock"), "tcp:127.0.0.1:80"},
+		PIDFile:     filepath.Join(dir, "svc.pid"),
+	}
+	if err := PreExecCheck(params); err != nil {
+		t.Fatalf("PreExecCheck: %v", err)
+	}
+}
+
+func TestPreExecCheckErrors(t *testing.T) {
+	dir := t.TempDir()
+	notExec := filepath.Join(dir, "script")
+	if err := os.WriteFile(notExec, []byte("#!/bin/sh\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	missing := filepath.Join(dir, "missing")
+
+	cases := []struct {
+		name   string
+		params ExecParams
+		want   error
+	}{
+		{"absolute command", ExecParams{Command: []string{missing}}, ErrCommandNotFound},
+		{"command on PATH", ExecParams{Command: []string{"slinit-no-such-command"}}, ErrCommandNotFound},
+		{"not executable", ExecParams{Command: []string{notExec}}, ErrCommandNotExecutable},
+		{"directory command", ExecParams{Command: []string{dir}}, ErrCommandNotExecutable},
+		{"working dir", ExecParams{Command: []string{"/bin/sh"}, WorkingDir: missing}, ErrWorkingDirMissing},
+		{"working dir is file", ExecParams{Command: []string{"/bin/sh"}, WorkingDir: notExec}, ErrWorkingDirMissing},
+		{"env file", ExecParams{EnvFile: missing}, ErrEnvFileUnreadable},
+		{"socket dir", ExecParams{SocketPaths: []string{filepath.Join(missing, "s.sock")}}, ErrSocketDirNotWritable},
+		{"pid-file dir", ExecParams{PIDFile: filepath.Join(missing, "x.pid")}, ErrPIDFileDirNotWritable},
+	}
+	for _, c := range cases {
+		err := PreExecCheck(c.params)
+		if !errors.Is(err, c.want) {
+			t.Errorf("%s: got %v, want %v", c.name, err, c.want)
+		}
+	}
+}
+
+func TestPreExecCheckChroot(t *testing.T) {
+	root := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(root, "bin"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "bin", "app"), []byte("#!/bin/sh\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := PreExecCheck(ExecParams{Command: []string{"/bin/app"}, Chroot: root, WorkingDir: "/bin"}); err != nil {
+		t.Errorf("command inside chroot: %v", err)
+	}
+	// Bare names cannot be resolved against the chroot's PATH.
+	if err := PreExecCheck(ExecParams{Command: []string{"app"}, Chroot: root}); err != nil {
+		t.Errorf("bare command under chroot: %v", err)
+	}
+	err := PreExecCheck(ExecParams{Command: []string{"/bin/missing"}, Chroot: root})
+	if !errors.Is(err, ErrCommandNotFound) {
+		t.Errorf("missing command inside chroot: got %v", err)
+	}
+}
+
+func TestPreExecCheckUnknownUser(t *testing.T) {
+	// Pick IDs far outside any real passwd/group database.
+	err := PreExecCheck(ExecParams{RunAsUID: 4000000001})
+	if !errors.Is(err, ErrUnknownUser) {
+		t.Errorf("unknown uid: got %v", err)
+	}
+	err = PreExecCheck(ExecParams{RunAsGID: 4000000001})
+	if !errors.Is(err, ErrUnknownGroup) {
+		t.Errorf("unknown gid: got %v", err)
+	}
+}
@@ -0,0 +1,67 @@
+package process
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+)
+
+// CheckPreloadLibs refuses preload libraries an unprivileged user could
+// have planted: each must be an absolute path to a file owned by root
+// and not world-writable. Symlinks are followed, so the check applies
+// to the file the dynamic loader will actually map.
+func CheckPreloadLibs(libs []string) error {
+	for _, lib := range libs {
+		if !filepath.IsAbs(lib) {
+			return fmt.Errorf("ld-preload %q: not an absolute path", lib)
+		}
+		fi, err := os.Stat(lib)
+		if err != nil {
+			return fmt.Errorf("ld-preload: %w", err)
+		}
+		if st, ok := fi.Sys().(*syscall.Stat_t); ok && st.Uid != 0 {
+			return fmt.Errorf("ld-preload %q: owned by uid %d, not root", lib, st.Uid)
+		}
+		if fi.Mode().Perm()&0o002 != 0 {
+			return fmt.Errorf("ld-preload %q: world-writable", lib)
+		}
+	}
+	return nil
+}
+
+// PreloadOwner returns the uid owning the file lib resolves to. ok is
+// false when that cannot be determined; the dynamic loader (or
+// CheckPreloadLibs) reports such entries.
+func PreloadOwner(lib string) (uid uint32, ok bool) {
+	fi, err := os.Stat(lib)
+	if err != nil {
+		return 0, false
+	}
+	st, ok := fi.Sys().(*syscall.Stat_t)
+	if !ok {
+		return 0, false
+	}
+	return st.Uid, true
+}
+
+// prependEnvList puts entries in front of the colon-separated list
+// NAME holds in env, keeping whatever was already there. The result is
+// appended as a new NAME= entry; exec.Cmd keeps the last duplicate.
+func prependEnvList(env []string, name string, entries []string) []string {
+	if len(entries) == 0 {
+		return env
+	}
+	value := strings.Join(entries, ":")
+	prefix := name + "="
+	for i := len(env) - 1; i >= 0; i-- {
+		if strings.HasPrefix(env[i], prefix) {
+			if old := env[i][len(prefix):]; old != "" {
+				value += ":" + old
+			}
+			break
+		}
+	}
+	return append(env, prefix+value)
+}
@@ -0,0 +1,85 @@
+package process
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestPrependEnvList(t *testing.T) {
+	env := []string{"LD_PRELOAD=/old.so", "PATH=/bin"}
+	got := prependEnvList(env, "LD_PRELOAD", []string{"/a.so", "/b.so"})
+	if last := got[len(got)-1]; last != "LD_PRELOAD=/a.so:/b.so:/old.so" {
+		t.Errorf("got %q", last)
+	}
+
+	got = prependEnvList([]string{"PATH=/bin"}, "LD_LIBRARY_PATH", []string{"/opt/lib"})
+	if last := got[len(got)-1]; last != "LD_LIBRARY_PATH=/opt/lib" {
+		t.Errorf("got %q", last)
+	}
+
+	if got := prependEnvList(env, "LD_PRELOAD", nil); len(got) != len(env) {
+		t.Errorf("empty entries changed env: %v", got)
+	}
+}
+
+func TestStartProcessLDLibraryPath(t *testing.T) {
+	params := ExecParams{
+		Command:       []string{"/bin/sh", "-c", `test "$LD_LIBRARY_PATH" = /opt/app/lib:/from/env`},
+		Env:           []string{"LD_LIBRARY_PATH=/from/env"},
+		LDLibraryPath: []string{"/opt/app/lib"},
+	}
+	_, ch, err := StartProcess(params)
+	if err != nil {
+		t.Fatalf("StartProcess failed: %v", err)
+	}
+	if exit := <-ch; !exit.ExitedClean() {
+		t.Errorf("LD_LIBRARY_PATH not prepended, exit status: %d", exit.Status.ExitStatus())
+	}
+}
+
+func TestCheckPreloadLibs(t *testing.T) {
+	dir := t.TempDir()
+	lib := filepath.Join(dir, "libfoo.so")
+	if err := os.WriteFile(lib, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	err := CheckPreloadLibs([]string{lib})
+	if os.Getuid() == 0 && err != nil {
+		t.Errorf("root-owned 0644 library rejected: %v", err)
+	}
+	if os.Getuid() != 0 && (err == nil || !strings.Contains(err.Error(), "not root")) {
+		t.Errorf("non-root-owned library accepted (err=%v)", err)
+	}
+
+	if err := CheckPreloadLibs([]string{"libfoo.so"}); err == nil {
+		t.Error("relative path accepted")
+	}
+
+	if err := os.Chmod(lib, 0o666); err != nil {
+		t.Fatal(err)
+	}
+	if err := CheckPreloadLibs([]string{lib}); err == nil {
+		t.Error("world-writable library accepted")
+	}
+}
+
+func TestStartProcessPreloadSecurityCheck(t *testing.T) {
+	lib := filepath.Join(t.TempDir(), "libwritable.so")
+	if err := os.WriteFile(lib, nil, 0o666); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chmod(lib, 0o666); err != nil {
+		t.Fatal(err)
+	}
+	_, _, err := StartProcess(ExecParams{
+		Command:              []string{"/bin/true"},
+		LDPreload:            []string{lib},
+		PreloadSecurityCheck: true,
+	})
+	if err == nil {
+		t.Fatal("expected preload-security-check to refuse a world-writable library")
+	}
+}
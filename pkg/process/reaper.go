@@ -0,0 +1,259 @@
+package process
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"syscall"
+)
+
+// Reaper is the single owner of waitpid for the daemon. When slinit runs
+// as PID 1 (or a container's init, or a subreaper) it must collect every
+// process that exits below it: its own children and the orphans the
+// kernel re-parents to it. Calling Wait4(-1) for the orphans races with
+// any per-child cmd.Wait(): whichever wins steals the other's status, and
+// a service's real exit code is lost. So once Start has been called the
+// reaper goroutine is the only caller of wait4. Code that forks starts
+// the child through StartCmd, which registers a watcher for the pid, and
+// learns the exit status from the reaper; a reaped pid nobody watches is
+// handed to the orphan callback.
+//
+// Without Start (not PID 1, unit tests, helper binaries) StartCmd and
+// WaitCmd fall back to cmd.Start and cmd.Wait.
+type Reaper struct {
+	// forkMu is held shared across a fork and the registration of its
+	// watcher, and exclusively while reaping, so a child cannot be
+	// reaped in between and mistaken for an orphan.
+	forkMu sync.RWMutex
+
+	mu       sync.Mutex
+	watchers map[int]chan syscall.WaitStatus
+
+	running atomic.Bool
+	wake    chan struct{}
+	stop    chan struct{}
+	done    chan struct{}
+	orphan  func(pid int, status syscall.WaitStatus)
+}
+
+// NewReaper returns a reaper that has not been started. Most callers
+// should use the process-wide DefaultReaper; this constructor exists for
+// tests.
+func NewReaper() *Reaper {
+	return &Reaper{
+		watchers: make(map[int]chan syscall.WaitStatus),
+		wake:     make(chan struct{}, 1),
+	}
+}
+
+// DefaultReaper is the process-wide reaper used by StartProcess, Run
+// and friends. slinit starts it when it runs as PID 1 or in container
+// mode.
+var DefaultReaper = NewReaper()
+
+// Start launches the reaper goroutine, which reaps on every SIGCHLD from
+// then on. orphan, if non-nil, is called (on the reaper goroutine, with
+// no locks held) for each reaped pid that has no watcher. Must be called
+// before the daemon forks anything it waits for; a second call is a
+// no-op.
+func (r *Reaper) Start(orphan func(pid int, status syscall.WaitStatus)) {
+	if r.running.Swap(true) {
+		return
+	}
+	r.orphan = orphan
+	r.stop = make(chan struct{})
+	r.done = make(chan struct{})
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, syscall.SIGCHLD)
+	go func() {
+		defer close(r.done)
+		defer signal.Stop(sigCh)
+		for {
+			r.reap()
+			select {
+			case <-sigCh:
+			case <-r.wake:
+			case <-r.stop:
+				return
+			}
+		}
+	}()
+}
+
+// Stop ends the reaper goroutine; children started afterwards are waited
+// for directly again. Used by tests.
+func (r *Reaper) Stop() {
+	if !r.running.Load() {
+		return
+	}
+	close(r.stop)
+	<-r.done
+	r.running.Store(false)
+}
+
+// Running reports whether the reaper goroutine owns waitpid.
+func (r *Reaper) Running() bool { return r.running.Load() }
+
+// Notify asks the reaper for a pass without waiting for SIGCHLD.
+func (r *Reaper) Notify() {
+	select {
+	case r.wake <- struct{}{}:
+	default:
+	}
+}
+
+// reap collects every child that has exited and dispatches the statuses.
+func (r *Reaper) reap() {
+	type exit struct {
+		pid    int
+		status syscall.WaitStatus
+	}
+	var orphans []exit
+	r.forkMu.Lock()
+	for {
+		var status syscall.WaitStatus
+		pid, err := syscall.Wait4(-1, &status, syscall.WNOHANG, nil)
+		if pid <= 0 || err != nil {
+			break
+		}
+		if !r.deliver(pid, status) {
+			orphans = append(orphans, exit{pid, status})
+		}
+	}
+	r.forkMu.Unlock()
+	// Outside forkMu: the callback may start processes of its own.
+	if r.orphan != nil {
+		for _, o := range orphans {
+			r.orphan(o.pid, o.status)
+		}
+	}
+}
+
+// Watch registers interest in pid's exit and returns a channel that
+// receives its wait status. The channel has room for the status, so the
+// reaper never blocks on it. Callers that fork the pid themselves should
+// use StartCmd instead, which closes the window between fork and Watch.
+func (r *Reaper) Watch(pid int) <-chan syscall.WaitStatus {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	ch := make(chan syscall.WaitStatus, 1)
+	r.watchers[pid] = ch
+	return ch
+}
+
+// Unwatch drops the watcher for pid. Idempotent.
+func (r *Reaper) Unwatch(pid int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.watchers, pid)
+}
+
+// deliver hands status to pid's watcher and drops the registration, so
+// a later reap of the same pid number (after pid reuse) cannot reach a
+// stale watcher. Reports false if nobody watches pid.
+func (r *Reaper) deliver(pid int, status syscall.WaitStatus) bool {
+	r.mu.Lock()
+	ch, ok := r.watchers[pid]
+	if ok {
+		delete(r.watchers, pid)
+	}
+	r.mu.Unlock()
+	if ok {
+		ch <- status
+	}
+	return ok
+}
+
+// StartCmd starts cmd. While the reaper runs, the child's watcher is
+// registered before the reaper can collect it and returned; otherwise
+// the channel is nil. Pass both to WaitCmd.
+func (r *Reaper) StartCmd(cmd *exec.Cmd) (<-chan syscall.WaitStatus, error) {
+	if !r.running.Load() {
+		return nil, cmd.Start()
+	}
+	r.forkMu.RLock()
+	defer r.forkMu.RUnlock()
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+	return r.Watch(cmd.Process.Pid), nil
+}
+
+// WaitCmd waits for a command started with StartCmd and returns its wait
+// status, with an error as cmd.Wait would give one: nil for a clean exit,
+// an error with an ExitCode method otherwise. cmd's I/O copying is
+// finished either way.
+func (r *Reaper) WaitCmd(cmd *exec.Cmd, exited <-chan syscall.WaitStatus) (syscall.WaitStatus, error) {
+	if exited == nil {
+		err := cmd.Wait()
+		var status syscall.WaitStatus
+		if cmd.ProcessState != nil {
+			status = cmd.ProcessState.Sys().(syscall.WaitStatus)
+		}
+		return status, err
+	}
+	status := <-exited
+	// The child is reaped already. Releasing the process first makes
+	// cmd.Wait skip the wait syscall (which could hit a reused pid)
+	// and only finish the I/O goroutines and close the pipes.
+	cmd.Process.Release()
+	cmd.Wait()
+	if status.Exited() && status.ExitStatus() == 0 {
+		return status, nil
+	}
+	return status, &ExitError{Status: status}
+}
+
+// ExitError reports an unsuccessful exit of a command waited for through
+// the reaper, in the form of exec.ExitError.
+type ExitError struct {
+	Status syscall.WaitStatus
+}
+
+func (e *ExitError) Error() string {
+	switch {
+	case e.Status.Exited():
+		return "exit status " + strconv.Itoa(e.Status.ExitStatus())
+	case e.Status.Signaled():
+		return "signal: " + e.Status.Signal().String()
+	}
+	return "wait status " + strconv.Itoa(int(e.Status))
+}
+
+// ExitCode returns the exit code, or -1 if the process was killed by a
+// signal.
+func (e *ExitError) ExitCode() int { return e.Status.ExitStatus() }
+
+// Run starts cmd and waits for it through DefaultReaper: exec.Cmd.Run
+// for code inside the daemon.
+func Run(cmd *exec.Cmd) error {
+	exited, err := DefaultReaper.StartCmd(cmd)
+	if err != nil {
+		return err
+	}
+	_, err = DefaultReaper.WaitCmd(cmd, exited)
+	return err
+}
+
+// Output runs cmd like Run and returns its standard output, as
+// exec.Cmd.Output does.
+func Output(cmd *exec.Cmd) ([]byte, error) {
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	err := Run(cmd)
+	return out.Bytes(), err
+}
+
+// CombinedOutput runs cmd like Run and returns its standard output and
+// standard error together, as exec.Cmd.CombinedOutput does.
+func CombinedOutput(cmd *exec.Cmd) ([]byte, error) {
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	cmd.Stderr = &out
+	err := Run(cmd)
+	return out.Bytes(), err
+}
@@ -0,0 +1,161 @@
+package process
+
+import (
+	"bytes"
+	"errors"
+	"os/exec"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestReaperDeliverToWatcher(t *testing.T) {
+	r := NewReaper()
+	ch := r.Watch(1234)
+
+	want := syscall.WaitStatus(7 << 8) // exited with code 7
+	if !r.deliver(1234, want) {
+		t.Fatal("deliver returned false for a watched pid")
+	}
+	select {
+	case got := <-ch:
+		if got != want {
+			t.Errorf("got status %v, want %v", got, want)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("status not delivered")
+	}
+
+	// The registration is consumed: a reused pid number must not reach
+	// the old watcher.
+	if r.deliver(1234, want) {
+		t.Error("second deliver returned true; watcher should be consumed")
+	}
+	if r.deliver(9999, 0) {
+		t.Error("deliver to an unwatched pid returned true")
+	}
+
+	r.Watch(42)
+	r.Unwatch(42)
+	r.Unwatch(42) // idempotent
+	if r.deliver(42, 0) {
+		t.Error("deliver after Unwatch returned true")
+	}
+}
+
+// TestReaperWaitsForCommands runs commands while the reaper owns
+// waitpid and checks that each one's real status and output arrive.
+func TestReaperWaitsForCommands(t *testing.T) {
+	r := NewReaper()
+	r.Start(nil)
+	defer r.Stop()
+
+	cmd := exec.Command("/bin/sh", "-c", "echo hello; exit 7")
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	exited, err := r.StartCmd(cmd)
+	if err != nil {
+		t.Fatalf("StartCmd: %v", err)
+	}
+	if exited == nil {
+		t.Fatal("StartCmd returned no exit channel while the reaper runs")
+	}
+	status, err := r.WaitCmd(cmd, exited)
+	if status.ExitStatus() != 7 {
+		t.Errorf("exit status = %d, want 7", status.ExitStatus())
+	}
+	var ee *ExitError
+	if !errors.As(err, &ee) || ee.ExitCode() != 7 || err.Error() != "exit status 7" {
+		t.Errorf("err = %v, want exit status 7", err)
+	}
+	if out.String() != "hello\n" {
+		t.Errorf("output = %q, want %q", out.String(), "hello\n")
+	}
+
+	cmd = exec.Command("/bin/sh", "-c", "kill -KILL $$")
+	exited, err = r.StartCmd(cmd)
+	if err != nil {
+		t.Fatalf("StartCmd: %v", err)
+	}
+	if _, err := r.WaitCmd(cmd, exited); err == nil || err.Error() != "signal: killed" {
+		t.Errorf("err = %v, want signal: killed", err)
+	}
+
+	cmd = exec.Command("/bin/true")
+	exited, err = r.StartCmd(cmd)
+	if err != nil {
+		t.Fatalf("StartCmd: %v", err)
+	}
+	if _, err := r.WaitCmd(cmd, exited); err != nil {
+		t.Errorf("clean exit: err = %v", err)
+	}
+}
+
+// TestReaperOrphans checks that a child nobody watches is reaped and
+// handed to the orphan callback.
+func TestReaperOrphans(t *testing.T) {
+	orphans := make(chan int, 1)
+	r := NewReaper()
+	r.Start(func(pid int, status syscall.WaitStatus) {
+		if status.ExitStatus() == 3 {
+			orphans <- pid
+		}
+	})
+	defer r.Stop()
+
+	cmd := exec.Command("/bin/sh", "-c", "exit 3")
+	if err := cmd.Start(); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	select {
+	case pid := <-orphans:
+		if pid != cmd.Process.Pid {
+			t.Errorf("orphan pid = %d, want %d", pid, cmd.Process.Pid)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("orphan not reaped")
+	}
+	cmd.Process.Release()
+}
+
+// TestReaperNotRunning checks the fallback to cmd.Wait.
+func TestReaperNotRunning(t *testing.T) {
+	r := NewReaper()
+	cmd := exec.Command("/bin/sh", "-c", "exit 5")
+	exited, err := r.StartCmd(cmd)
+	if err != nil {
+		t.Fatalf("StartCmd: %v", err)
+	}
+	if exited != nil {
+		t.Error("StartCmd returned an exit channel with the reaper stopped")
+	}
+	status, err := r.WaitCmd(cmd, exited)
+	if status.ExitStatus() != 5 {
+		t.Errorf("exit status = %d, want 5", status.ExitStatus())
+	}
+	var ee *exec.ExitError
+	if !errors.As(err, &ee) {
+		t.Errorf("err = %v, want *exec.ExitError", err)
+	}
+}
+
+// TestStartProcessUnderReaper checks that a service process's exit
+// status survives the reaper collecting it, with the reaper also
+// reaping every other child at the same time.
+func TestStartProcessUnderReaper(t *testing.T) {
+	DefaultReaper.Start(nil)
+	defer DefaultReaper.Stop()
+
+	pid, ch, err := StartProcess(ExecParams{Command: []string{"/bin/sh", "-c", "sleep 0.05; exit 9"}})
+	if err != nil {
+		t.Fatalf("StartProcess: %v", err)
+	}
+	select {
+	case exit := <-ch:
+		if exit.PID != pid || exit.Status.ExitStatus() != 9 {
+			t.Errorf("got pid %d status %d, want pid %d status 9", exit.PID, exit.Status.ExitStatus(), pid)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no exit reported")
+	}
+}
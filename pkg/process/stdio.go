@@ -0,0 +1,96 @@
+package process
+
+import (
+	"os"
+	"os/exec"
+	"syscall"
+)
+
+// StdinMode selects what a service's fd 0 is connected to (the stdin
+// setting).
+type StdinMode uint8
+
+const (
+	// StdinDefault wires stdin as before: the console, the input pipe
+	// of a consumer-of service, or /dev/null.
+	StdinDefault StdinMode = iota
+	// StdinNull reads from /dev/null.
+	StdinNull
+	// StdinTTY reads from /dev/tty.
+	StdinTTY
+	// StdinFile reads from ExecParams.StdinPath.
+	StdinFile
+)
+
+// StderrMode selects where a service's fd 2 goes (the stderr setting).
+type StderrMode uint8
+
+const (
+	// StderrDefault wires stderr as before: with stdout, or to the
+	// error-logger pipe when one is configured.
+	StderrDefault StderrMode = iota
+	// StderrStdout merges stderr into stdout, error-logger or not.
+	StderrStdout
+	// StderrNull discards stderr.
+	StderrNull
+	// StderrFile appends stderr to ExecParams.StderrPath.
+	StderrFile
+)
+
+// stderrFilePerms is the mode of a stderr file slinit creates.
+const stderrFilePerms = 0o640
+
+// redirectStdio applies the stdin/stderr settings on top of the wiring
+// already done for the console, the log pipe and the input pipe. The
+// returned files are the parent's copies, to be closed once the child
+// has started.
+func redirectStdio(cmd *exec.Cmd, params *ExecParams) ([]*os.File, error) {
+	var opened []*os.File
+	fail := func(err error) ([]*os.File, error) {
+		for _, f := range opened {
+			f.Close()
+		}
+		return nil, &ExecError{Stage: StageSetupStdio, Err: err}
+	}
+
+	var in string
+	switch params.StdinRedirect {
+	case StdinNull:
+		in = os.DevNull
+	case StdinTTY:
+		in = "/dev/tty"
+	case StdinFile:
+		in = params.StdinPath
+	}
+	if in != "" {
+		f, err := os.OpenFile(in, os.O_RDONLY|syscall.O_NOCTTY, 0)
+		if err != nil {
+			return fail(err)
+		}
+		opened = append(opened, f)
+		cmd.Stdin = f
+	}
+
+	switch params.StderrRedirect {
+	case StderrStdout:
+		cmd.Stderr = cmd.Stdout
+	case StderrNull:
+		f, err := os.OpenFile(os.DevNull, os.O_WRONLY, 0)
+		if err != nil {
+			return fail(err)
+		}
+		opened = append(opened, f)
+		cmd.Stderr = f
+	case StderrFile:
+		// O_NOFOLLOW, as for tee logs: slinit runs as root and must not
+		// follow a symlink planted at the path.
+		f, err := os.OpenFile(params.StderrPath,
+			os.O_WRONLY|os.O_CREATE|os.O_APPEND|syscall.O_NOCTTY|syscall.O_NOFOLLOW, stderrFilePerms)
+		if err != nil {
+			return fail(err)
+		}
+		opened = append(opened, f)
+		cmd.Stderr = f
+	}
+	return opened, nil
+}
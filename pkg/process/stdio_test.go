@@ -0,0 +1,105 @@
+package process
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestStdioRedirectFiles(t *testing.T) {
+	dir := t.TempDir()
+	in := filepath.Join(dir, "in")
+	errLog := filepath.Join(dir, "err.log")
+	if err := os.WriteFile(in, []byte("from file\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(errLog, []byte("earlier\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	params := ExecParams{
+		Command:        []string{"/bin/sh", "-c", "read line; echo \"$line\" >&2"},
+		StdinRedirect:  StdinFile,
+		StdinPath:      in,
+		StderrRedirect: StderrFile,
+		StderrPath:     errLog,
+	}
+	_, ch, err := StartProcess(params)
+	if err != nil {
+		t.Fatalf("StartProcess: %v", err)
+	}
+	if exit := <-ch; !exit.ExitedClean() {
+		t.Fatalf("child failed: %+v", exit)
+	}
+
+	got, err := os.ReadFile(errLog)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != "earlier\nfrom file\n" {
+		t.Errorf("stderr file = %q, want the line appended", got)
+	}
+}
+
+func TestStdioRedirectStderrNull(t *testing.T) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	params := ExecParams{
+		Command:        []string{"/bin/sh", "-c", "echo out; echo err >&2"},
+		OutputPipe:     w,
+		StderrRedirect: StderrNull,
+	}
+	_, ch, err := StartProcess(params)
+	w.Close()
+	if err != nil {
+		t.Fatalf("StartProcess: %v", err)
+	}
+	<-ch
+	buf := make([]byte, 64)
+	n, _ := r.Read(buf)
+	if out := string(buf[:n]); strings.Contains(out, "err") || !strings.Contains(out, "out") {
+		t.Errorf("pipe got %q, want stdout only", out)
+	}
+}
+
+func TestStdioRedirectMissingStdinFile(t *testing.T) {
+	params := ExecParams{
+		Command:       []string{"/bin/true"},
+		StdinRedirect: StdinFile,
+		StdinPath:     filepath.Join(t.TempDir(), "missing"),
+	}
+	_, _, err := StartProcess(params)
+	var ee *ExecError
+	if !errors.As(err, &ee) || ee.Stage != StageSetupStdio {
+		t.Fatalf("err = %v, want an ExecError at %s", err, StageSetupStdio)
+	}
+}
+
+func TestStdioRedirectStderrSymlinkRefused(t *testing.T) {
+	dir := t.TempDir()
+	target := filepath.Join(dir, "target")
+	link := filepath.Join(dir, "err.log")
+	if err := os.Symlink(target, link); err != nil {
+		t.Fatal(err)
+	}
+
+	params := ExecParams{
+		Command:        []string{"/bin/true"},
+		StderrRedirect: StderrFile,
+		StderrPath:     link,
+	}
+	_, _, err := StartProcess(params)
+	var ee *ExecError
+	if !errors.As(err, &ee) || ee.Stage != StageSetupStdio {
+		t.Fatalf("err = %v, want an ExecError at %s", err, StageSetupStdio)
+	}
+	if _, err := os.Lstat(target); !os.IsNotExist(err) {
+		t.Errorf("symlink target was created (lstat err %v)", err)
+	}
+}
@@ -0,0 +1,125 @@
+package process
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// MultiOutput sends a child's stdout/stderr to two places: the primary
+// pipe (normally the write end of a log buffer) and a log file.
+type MultiOutput struct {
+	// PrimaryPipe receives every byte the child writes. StartProcess
+	// duplicates it, so the caller closes its copy after fork exactly
+	// as it would an OutputPipe.
+	PrimaryPipe *os.File
+
+	// SecondaryFile is appended to. Created with SecondaryPerms (0600
+	// when zero) and chowned to SecondaryUID/SecondaryGID when either is
+	// >= 0.
+	SecondaryFile  string
+	SecondaryPerms os.FileMode
+	SecondaryUID   int
+	SecondaryGID   int
+}
+
+// teeBufSize is the read size of the tee goroutine.
+const teeBufSize = 32 * 1024
+
+// TeeWriter copies everything written to its pipe to a primary pipe and
+// a log file. The child gets WriteEnd as its output; a goroutine drains
+// the read end until every copy of the write end is closed, then closes
+// the primary pipe and the file.
+type TeeWriter struct {
+	r       *os.File
+	w       *os.File
+	primary *os.File
+	file    *os.File
+	done    chan struct{}
+}
+
+// NewTeeWriter opens secondaryPath for appending and starts copying the
+// new pipe into it and into primary. perm is the mode of the file if it
+// has to be created; zero means 0600. primary is duplicated; the caller
+// keeps ownership of its own descriptor.
+func NewTeeWriter(primary *os.File, secondaryPath string, perm os.FileMode) (*TeeWriter, error) {
+	if perm == 0 {
+		perm = 0o600
+	}
+	// O_NOFOLLOW: slinit runs as root and must not follow a symlink an
+	// unprivileged user planted at the log path.
+	file, err := os.OpenFile(secondaryPath, os.O_WRONLY|os.O_CREATE|os.O_APPEND|syscall.O_NOFOLLOW, perm)
+	if err != nil {
+		return nil, fmt.Errorf("open logfile '%s': %w", secondaryPath, err)
+	}
+	fd, err := unix.FcntlInt(primary.Fd(), unix.F_DUPFD_CLOEXEC, 0)
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("dup primary pipe: %w", err)
+	}
+	dup := os.NewFile(uintptr(fd), primary.Name())
+	r, w, err := os.Pipe()
+	if err != nil {
+		file.Close()
+		dup.Close()
+		return nil, err
+	}
+	t := &TeeWriter{r: r, w: w, primary: dup, file: file, done: make(chan struct{})}
+	go t.copyLoop()
+	return t, nil
+}
+
+// WriteEnd returns the write end of the tee pipe, to be handed to the
+// child as its output.
+func (t *TeeWriter) WriteEnd() *os.File { return t.w }
+
+// CloseWriteEnd closes the parent's copy of the write end. The tee sees
+// EOF once the child's copies are gone too.
+func (t *TeeWriter) CloseWriteEnd() {
+	if t.w != nil {
+		t.w.Close()
+		t.w = nil
+	}
+}
+
+// Done is closed once the copy goroutine has drained the pipe and closed
+// both outputs.
+func (t *TeeWriter) Done() <-chan struct{} { return t.done }
+
+// copyLoop forwards reads to both outputs. A failing output is dropped
+// and the other keeps receiving data, so a full disk does not starve
+// the log buffer or vice versa.
+func (t *TeeWriter) copyLoop() {
+	defer close(t.done)
+	defer t.r.Close()
+	primary, file := t.primary, t.file
+	buf := make([]byte, teeBufSize)
+	for {
+		n, err := t.r.Read(buf)
+		if n > 0 {
+			if primary != nil {
+				if _, werr := primary.Write(buf[:n]); werr != nil {
+					primary.Close()
+					primary = nil
+				}
+			}
+			if file != nil {
+				if _, werr := file.Write(buf[:n]); werr != nil {
+					file.Close()
+					file = nil
+				}
+			}
+		}
+		if err != nil {
+			break
+		}
+	}
+	if primary != nil {
+		primary.Close()
+	}
+	if file != nil {
+		file.Close()
+	}
+}
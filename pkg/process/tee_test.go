@@ -0,0 +1,135 @@
+package process
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestTeeWriterCopiesToBoth(t *testing.T) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("pipe: %v", err)
+	}
+	defer r.Close()
+	logPath := filepath.Join(t.TempDir(), "svc.log")
+
+	tee, err := NewTeeWriter(w, logPath, 0)
+	if err != nil {
+		t.Fatalf("NewTeeWriter: %v", err)
+	}
+	w.Close() // the tee holds its own duplicate
+
+	if _, err := tee.WriteEnd().Write([]byte("hello\n")); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	tee.CloseWriteEnd()
+
+	got, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatalf("read primary: %v", err)
+	}
+	if string(got) != "hello\n" {
+		t.Errorf("primary got %q", got)
+	}
+	<-tee.Done()
+	data, err := os.ReadFile(logPath)
+	if err != nil {
+		t.Fatalf("read logfile: %v", err)
+	}
+	if string(data) != "hello\n" {
+		t.Errorf("logfile got %q", data)
+	}
+	if fi, err := os.Stat(logPath); err == nil && fi.Mode().Perm() != 0o600 {
+		t.Errorf("logfile mode = %v, want 0600", fi.Mode().Perm())
+	}
+}
+
+func TestTeeWriterKeepsFileWhenPrimaryGone(t *testing.T) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("pipe: %v", err)
+	}
+	logPath := filepath.Join(t.TempDir(), "svc.log")
+	tee, err := NewTeeWriter(w, logPath, 0o640)
+	if err != nil {
+		t.Fatalf("NewTeeWriter: %v", err)
+	}
+	w.Close()
+	r.Close() // nobody reads the primary any more
+
+	tee.WriteEnd().Write([]byte("one\n"))
+	tee.WriteEnd().Write([]byte("two\n"))
+	tee.CloseWriteEnd()
+
+	select {
+	case <-tee.Done():
+	case <-time.After(2 * time.Second):
+		t.Fatal("tee did not finish")
+	}
+	data, _ := os.ReadFile(logPath)
+	if string(data) != "one\ntwo\n" {
+		t.Errorf("logfile got %q", data)
+	}
+	if fi, err := os.Stat(logPath); err == nil && fi.Mode().Perm() != 0o640 {
+		t.Errorf("logfile mode = %v, want 0640", fi.Mode().Perm())
+	}
+}
+
+func TestStartProcessMultiOutput(t *testing.T) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("pipe: %v", err)
+	}
+	defer r.Close()
+	logPath := filepath.Join(t.TempDir(), "svc.log")
+
+	params := ExecParams{
+		Command: []string{"/bin/sh", "-c", "echo out; echo err >&2"},
+		MultiOutput: &MultiOutput{
+			PrimaryPipe:   w,
+			SecondaryFile: logPath,
+			SecondaryUID:  -1,
+			SecondaryGID:  -1,
+		},
+	}
+	_, ch, err := StartProcess(params)
+	w.Close()
+	if err != nil {
+		t.Fatalf("StartProcess failed: %v", err)
+	}
+	<-ch
+
+	got, _ := io.ReadAll(r)
+	if string(got) != "out\nerr\n" {
+		t.Errorf("primary got %q", got)
+	}
+	// The primary reached EOF, so the tee has closed the file too.
+	data, _ := os.ReadFile(logPath)
+	if string(data) != "out\nerr\n" {
+		t.Errorf("logfile got %q", data)
+	}
+}
+
+func TestStartProcessMultiOutputBadPath(t *testing.T) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("pipe: %v", err)
+	}
+	defer r.Close()
+	defer w.Close()
+
+	_, _, err = StartProcess(ExecParams{
+		Command: []string{"/bin/true"},
+		MultiOutput: &MultiOutput{
+			PrimaryPipe:   w,
+			SecondaryFile: filepath.Join(t.TempDir(), "missing", "svc.log"),
+		},
+	})
+	ee, ok := err.(*ExecError)
+	if !ok || ee.Stage != StageOpenLogFile {
+		t.Fatalf("expected StageOpenLogFile error, got %v", err)
+	}
+}
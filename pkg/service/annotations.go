@@ -0,0 +1,63 @@
+package service
+
+import (
+	"time"
+)
+
+// maxAnnotations bounds the operator notes kept per service; the oldest
+// are dropped first.
+const maxAnnotations = 100
+
+// Annotation is an operator note attached to a service with
+// `slinitctl annotate`, kept for the audit trail.
+type Annotation struct {
+	Time    time.Time
+	User    string // who added it; empty if unknown
+	Message string
+}
+
+// AnnotateEvent attaches a note from user to the service and records it
+// in the daemon-wide event history, so it reads in line with the state
+// changes around it.
+func (sr *ServiceRecord) AnnotateEvent(user, msg string) {
+	a := Annotation{Time: time.Now(), User: user, Message: msg}
+	sr.annotationsMu.Lock()
+	if len(sr.annotations) >= maxAnnotations {
+		copy(sr.annotations, sr.annotations[1:])
+		sr.annotations = sr.annotations[:maxAnnotations-1]
+	}
+	sr.annotations = append(sr.annotations, a)
+	sr.annotationsMu.Unlock()
+
+	if sr.services == nil {
+		return
+	}
+	details := msg
+	if user != "" {
+		details = "[" + user + "] " + msg
+	}
+	sr.services.publishGlobalEvent(sr.self, GlobalEvent{
+		Time:    a.Time,
+		Service: sr.serviceName,
+		Event:   EventAnnotation,
+		Details: details,
+	})
+}
+
+// Annotations returns a copy of the service's notes, oldest first.
+func (sr *ServiceRecord) Annotations() []Annotation {
+	sr.annotationsMu.Lock()
+	defer sr.annotationsMu.Unlock()
+	return append([]Annotation(nil), sr.annotations...)
+}
+
+// SetAnnotations replaces the service's notes, e.g. when restoring a
+// state snapshot. Only the newest maxAnnotations are kept.
+func (sr *ServiceRecord) SetAnnotations(as []Annotation) {
+	if len(as) > maxAnnotations {
+		as = as[len(as)-maxAnnotations:]
+	}
+	sr.annotationsMu.Lock()
+	defer sr.annotationsMu.Unlock()
+	sr.annotations = append([]Annotation(nil), as...)
+}
@@ -0,0 +1,50 @@
+package service
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestAnnotateEvent(t *testing.T) {
+	set, _ := newTestSet()
+	svc := NewInternalService(set, "web")
+	set.AddService(svc)
+
+	svc.Record().AnnotateEvent("alice", "Starting planned maintenance window")
+	set.StartService(svc)
+
+	notes := svc.Record().Annotations()
+	if len(notes) != 1 || notes[0].User != "alice" ||
+		notes[0].Message != "Starting planned maintenance window" || notes[0].Time.IsZero() {
+		t.Fatalf("annotations = %+v", notes)
+	}
+
+	events := set.RecentEvents(0)
+	if len(events) != 2 {
+		t.Fatalf("got %d events, want 2: %+v", len(events), events)
+	}
+	if events[0].Event != EventAnnotation || events[0].Service != "web" ||
+		events[0].Details != "[alice] Starting planned maintenance window" {
+		t.Errorf("first event = %+v, want the annotation", events[0])
+	}
+	if events[1].Event != EventStarted {
+		t.Errorf("second event = %+v, want STARTED", events[1])
+	}
+}
+
+func TestAnnotationsBounded(t *testing.T) {
+	set, _ := newTestSet()
+	svc := NewInternalService(set, "web")
+	set.AddService(svc)
+
+	for i := 0; i < maxAnnotations+3; i++ {
+		svc.Record().AnnotateEvent("", fmt.Sprintf("note %d", i))
+	}
+	notes := svc.Record().Annotations()
+	if len(notes) != maxAnnotations {
+		t.Fatalf("kept %d notes, want %d", len(notes), maxAnnotations)
+	}
+	if notes[0].Message != "note 3" || notes[len(notes)-1].Message != fmt.Sprintf("note %d", maxAnnotations+2) {
+		t.Errorf("kept %q..%q, want the newest", notes[0].Message, notes[len(notes)-1].Message)
+	}
+}
@@ -0,0 +1,112 @@
+package service
+
+// RestartSource says what decided a service's effective restart mode.
+type RestartSource uint8
+
+const (
+	RestartSourceConfigured RestartSource = iota // the restart setting
+	RestartSourceOverride                        // slinitctl no-restart / enable-restart
+	RestartSourceKilled                          // slinitctl kill, until the next start
+	RestartSourceSuppressed                      // failure-action-threshold tripped
+	RestartSourcePinned                          // pinned stopped
+	RestartSourceShutdown                        // system shutdown in progress
+)
+
+func (s RestartSource) String() string {
+	switch s {
+	case RestartSourceConfigured:
+		return "configured"
+	case RestartSourceOverride:
+		return "runtime override"
+	case RestartSourceKilled:
+		return "killed"
+	case RestartSourceSuppressed:
+		return "failure threshold reached"
+	case RestartSourcePinned:
+		return "pinned stopped"
+	case RestartSourceShutdown:
+		return "shutdown in progress"
+	default:
+		return "unknown"
+	}
+}
+
+// SetAutoRestartEnabled overrides the configured restart mode at runtime:
+// false disables every automatic restart (useful while attaching a
+// debugger to a crashing service), true enables it even for a service
+// configured with restart = no, which then restarts always.
+func (sr *ServiceRecord) SetAutoRestartEnabled(enabled bool) {
+	mode := RestartNever
+	if enabled {
+		mode = sr.autoRestart
+		if mode == RestartNever {
+			mode = RestartAlways
+		}
+	}
+	sr.SetOverrideAutoRestart(mode)
+}
+
+// SetOverrideAutoRestart replaces the configured restart mode with mode
+// until ClearAutoRestartOverride or a reload.
+func (sr *ServiceRecord) SetOverrideAutoRestart(mode AutoRestartMode) {
+	sr.autoRestartOverride = &mode
+}
+
+// ClearAutoRestartOverride drops the runtime override, going back to
+// the configured mode.
+func (sr *ServiceRecord) ClearAutoRestartOverride() { sr.autoRestartOverride = nil }
+
+// OverrideAutoRestart returns the runtime restart mode override, or nil
+// if none.
+func (sr *ServiceRecord) OverrideAutoRestart() *AutoRestartMode { return sr.autoRestartOverride }
+
+// AutoRestartOverride reports the runtime override as enabled/disabled,
+// the form `slinitctl no-restart` / `enable-restart` set it in; nil if
+// none.
+func (sr *ServiceRecord) AutoRestartOverride() *bool {
+	if sr.autoRestartOverride == nil {
+		return nil
+	}
+	enabled := *sr.autoRestartOverride != RestartNever
+	return &enabled
+}
+
+// GetEffectiveAutoRestart returns the restart mode that applies if the
+// service stopped now: RestartNever during shutdown, while the service
+// is pinned stopped, killed, or past its failure-action-threshold, else
+// the runtime override if one is set, else the configured mode. It does
+// not look at forceStop: an unexpected exit is delivered as a forced
+// stop and is exactly when the mode matters.
+func (sr *ServiceRecord) GetEffectiveAutoRestart() AutoRestartMode {
+	mode, _ := sr.resolveAutoRestart()
+	return mode
+}
+
+// AutoRestartSource returns what decided GetEffectiveAutoRestart.
+func (sr *ServiceRecord) AutoRestartSource() RestartSource {
+	_, src := sr.resolveAutoRestart()
+	return src
+}
+
+func (sr *ServiceRecord) resolveAutoRestart() (AutoRestartMode, RestartSource) {
+	switch {
+	case sr.services.IsShuttingDown():
+		return RestartNever, RestartSourceShutdown
+	case sr.pinnedStopped:
+		return RestartNever, RestartSourcePinned
+	case sr.killed:
+		return RestartNever, RestartSourceKilled
+	case sr.restartSuppressed:
+		return RestartNever, RestartSourceSuppressed
+	case sr.autoRestartOverride != nil:
+		return *sr.autoRestartOverride, RestartSourceOverride
+	}
+	return sr.autoRestart, RestartSourceConfigured
+}
+
+// autoRestartBlocked reports whether something other than the
+// configured mode has turned automatic restarts off.
+func (sr *ServiceRecord) autoRestartBlocked() bool {
+	mode, src := sr.resolveAutoRestart()
+	return mode == RestartNever && src != RestartSourceConfigured
+}
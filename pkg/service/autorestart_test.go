@@ -0,0 +1,148 @@
+package service
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEffectiveAutoRestart(t *testing.T) {
+	set, _ := newTestSet()
+	svc := NewInternalService(set, "svc")
+	rec := svc.Record()
+
+	tests := []struct {
+		configured AutoRestartMode
+		override   *bool
+		want       AutoRestartMode
+	}{
+		{RestartOnFailure, nil, RestartOnFailure},
+		{RestartAlways, boolPtr(false), RestartNever},
+		{RestartOnFailure, boolPtr(true), RestartOnFailure},
+		{RestartNever, boolPtr(true), RestartAlways},
+	}
+	for _, tt := range tests {
+		rec.SetAutoRestart(tt.configured)
+		rec.ClearAutoRestartOverride()
+		if tt.override != nil {
+			rec.SetAutoRestartEnabled(*tt.override)
+		}
+		if got := rec.GetEffectiveAutoRestart(); got != tt.want {
+			t.Errorf("configured %v, override %v: got %v, want %v", tt.configured, tt.override, got, tt.want)
+		}
+	}
+}
+
+func boolPtr(b bool) *bool { return &b }
+
+func TestEffectiveAutoRestartSource(t *testing.T) {
+	set, _ := newTestSet()
+	svc := NewInternalService(set, "svc")
+	rec := svc.Record()
+	rec.SetAutoRestart(RestartAlways)
+
+	check := func(want AutoRestartMode, src RestartSource) {
+		t.Helper()
+		if got := rec.GetEffectiveAutoRestart(); got != want {
+			t.Errorf("mode = %v, want %v", got, want)
+		}
+		if got := rec.AutoRestartSource(); got != src {
+			t.Errorf("source = %v, want %v", got, src)
+		}
+	}
+	check(RestartAlways, RestartSourceConfigured)
+
+	rec.SetOverrideAutoRestart(RestartOnFailure)
+	check(RestartOnFailure, RestartSourceOverride)
+	if o := rec.AutoRestartOverride(); o == nil || !*o {
+		t.Errorf("AutoRestartOverride = %v, want enabled", o)
+	}
+	rec.ClearAutoRestartOverride()
+
+	rec.pinnedStopped = true
+	check(RestartNever, RestartSourcePinned)
+	rec.pinnedStopped = false
+
+	set.StopAllServices(ShutdownPoweroff)
+	check(RestartNever, RestartSourceShutdown)
+}
+
+func TestNoRestartOverrideKeepsServiceDown(t *testing.T) {
+	set, _ := newTestSet()
+	marker := filepath.Join(t.TempDir(), "starts")
+
+	svc := NewProcessService(set, "crashy")
+	svc.SetCommand([]string{"/bin/sh", "-c", "echo >> " + marker + "; sleep 0.1; exit 1"})
+	svc.SetAutoRestart(RestartAlways)
+	svc.SetRestartDelay(20 * time.Millisecond)
+	svc.SetAutoRestartEnabled(false)
+	set.AddService(svc)
+
+	set.StartService(svc)
+	time.Sleep(600 * time.Millisecond)
+
+	data, _ := os.ReadFile(marker)
+	if n := strings.Count(string(data), "\n"); n != 1 {
+		t.Errorf("expected exactly 1 start with no-restart, got %d", n)
+	}
+	if st := svc.State(); st != StateStopped {
+		t.Errorf("expected STOPPED, got %v", st)
+	}
+}
+
+func TestKillServiceStaysDown(t *testing.T) {
+	set, _ := newTestSet()
+	marker := filepath.Join(t.TempDir(), "starts")
+
+	// Ignores SIGTERM, so only an immediate SIGKILL stops it quickly.
+	svc := NewProcessService(set, "stubborn")
+	svc.SetCommand([]string{"/bin/sh", "-c", "trap '' TERM; echo >> " + marker + "; sleep 60"})
+	svc.SetAutoRestart(RestartAlways)
+	svc.SetRestartDelay(20 * time.Millisecond)
+	set.AddService(svc)
+
+	set.StartService(svc)
+	// Wait for the marker too: STARTED comes at fork, and killing the
+	// shell before it runs would leave no start recorded.
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		if data, _ := os.ReadFile(marker); svc.State() == StateStarted && len(data) > 0 {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if svc.State() != StateStarted {
+		t.Fatalf("expected STARTED, got %v", svc.State())
+	}
+
+	set.KillService(svc)
+	deadline = time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) && svc.State() != StateStopped {
+		time.Sleep(10 * time.Millisecond)
+	}
+	time.Sleep(200 * time.Millisecond) // room for an unwanted restart
+
+	if st := svc.State(); st != StateStopped {
+		t.Fatalf("expected STOPPED after kill, got %v", st)
+	}
+	data, _ := os.ReadFile(marker)
+	if n := strings.Count(string(data), "\n"); n != 1 {
+		t.Errorf("expected exactly 1 start, got %d", n)
+	}
+	if svc.TargetState() != StateStopped || svc.Record().IsMarkedActive() {
+		t.Errorf("kill left the service wanted: target %v, active %v",
+			svc.TargetState(), svc.Record().IsMarkedActive())
+	}
+	if errs := set.VerifyRefCounts(); len(errs) != 0 {
+		t.Errorf("refcount errors after kill: %v", errs)
+	}
+
+	// An explicit start lifts the kill.
+	set.StartService(svc)
+	if svc.Record().Killed() {
+		t.Error("Start did not clear the kill")
+	}
+	set.KillService(svc)
+}
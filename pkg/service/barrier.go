@@ -0,0 +1,61 @@
+package service
+
+// BarrierService groups other services: it stays STARTING until every
+// service it depends on is STARTED, then starts itself. Unlike an
+// internal service, soft (waits-for) dependencies that fail or are not
+// yet up do not let it through; it keeps waiting and starts as soon as
+// the last of them comes up. A typical use is network-ready with
+// waits-for on each interface, so applications depend on one service
+// instead of every interface.
+type BarrierService struct {
+	ServiceRecord
+}
+
+// NewBarrierService creates a new barrier service.
+func NewBarrierService(set *ServiceSet, name string) *BarrierService {
+	svc := &BarrierService{}
+	svc.ServiceRecord = *NewServiceRecord(svc, set, name, TypeBarrier)
+	return svc
+}
+
+// BringUp is reached once the usual dependency wait is over. It marks
+// the barrier started if every dependency is STARTED; otherwise it
+// waits on the stragglers again, so their Started() re-queues the
+// barrier through dependencyStarted.
+func (s *BarrierService) BringUp() bool {
+	ready := true
+	for _, dep := range s.dependsOn {
+		if dep.IsOnlyOrdering() {
+			continue
+		}
+		if dep.To.Record().state.Load() != StateStarted {
+			dep.WaitingOn = true
+			ready = false
+		}
+	}
+	if ready {
+		s.Started()
+		return true
+	}
+	s.waitingForDeps = true
+	// Waiting may take indefinitely long; don't hold a start slot.
+	if limiter := s.services.GetStartLimiter(); limiter != nil {
+		limiter.Release(s.self)
+	}
+	return true
+}
+
+// BringDown stops the barrier immediately.
+func (s *BarrierService) BringDown() {
+	s.Stopped()
+}
+
+// CanInterruptStart returns true since there is no process to interrupt.
+func (s *BarrierService) CanInterruptStart() bool {
+	return true
+}
+
+// InterruptStart cancels the start immediately.
+func (s *BarrierService) InterruptStart() bool {
+	return true
+}
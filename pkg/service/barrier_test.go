@@ -0,0 +1,92 @@
+package service
+
+import (
+	"testing"
+)
+
+func TestBarrierWaitsForAllDeps(t *testing.T) {
+	set, _ := newTestSet()
+
+	lo := NewInternalService(set, "lo")
+	eth0 := NewTriggeredService(set, "eth0")
+	wlan0 := NewTriggeredService(set, "wlan0")
+	barrier := NewBarrierService(set, "network-ready")
+	app := NewInternalService(set, "application")
+	for _, s := range []Service{lo, eth0, wlan0, barrier, app} {
+		set.AddService(s)
+	}
+	barrier.Record().AddDep(lo, DepRegular)
+	barrier.Record().AddDep(eth0, DepWaitsFor)
+	barrier.Record().AddDep(wlan0, DepWaitsFor)
+	app.Record().AddDep(barrier, DepRegular)
+
+	set.StartService(app)
+	if barrier.State() != StateStarting || app.State() != StateStarting {
+		t.Fatalf("expected barrier and app STARTING, got %v / %v", barrier.State(), app.State())
+	}
+
+	eth0.SetTrigger(true)
+	set.ProcessQueues()
+	if barrier.State() != StateStarting {
+		t.Fatalf("barrier started with wlan0 still down: %v", barrier.State())
+	}
+
+	wlan0.SetTrigger(true)
+	set.ProcessQueues()
+	if barrier.State() != StateStarted {
+		t.Fatalf("expected barrier STARTED, got %v", barrier.State())
+	}
+	if app.State() != StateStarted {
+		t.Errorf("expected application STARTED, got %v", app.State())
+	}
+}
+
+func TestBarrierHoldsOnFailedSoftDep(t *testing.T) {
+	set, _ := newTestSet()
+
+	eth0 := NewTriggeredService(set, "eth0")
+	barrier := NewBarrierService(set, "network-ready")
+	set.AddService(eth0)
+	set.AddService(barrier)
+	barrier.Record().AddDep(eth0, DepWaitsFor)
+
+	set.StartService(barrier)
+	if eth0.State() != StateStarting {
+		t.Fatalf("expected eth0 STARTING, got %v", eth0.State())
+	}
+
+	// An internal service would start once its waits-for dep gives
+	// up; the barrier must keep waiting.
+	set.StopService(eth0)
+	if eth0.State() != StateStopped {
+		t.Fatalf("expected eth0 STOPPED, got %v", eth0.State())
+	}
+	if barrier.State() != StateStarting {
+		t.Fatalf("expected barrier still STARTING, got %v", barrier.State())
+	}
+
+	eth0.SetTrigger(true)
+	set.StartService(eth0)
+	if barrier.State() != StateStarted {
+		t.Errorf("expected barrier STARTED once eth0 came up, got %v", barrier.State())
+	}
+}
+
+func TestBarrierNoDepsStartsImmediately(t *testing.T) {
+	set, _ := newTestSet()
+
+	barrier := NewBarrierService(set, "empty")
+	set.AddService(barrier)
+	set.StartService(barrier)
+	if barrier.State() != StateStarted {
+		t.Errorf("expected STARTED, got %v", barrier.State())
+	}
+	if barrier.Type() != TypeBarrier || barrier.Type().String() != "barrier" {
+		t.Errorf("unexpected type %v", barrier.Type())
+	}
+
+	set.StopService(barrier)
+	if barrier.State() != StateStopped {
+		t.Errorf("expected STOPPED, got %v", barrier.State())
+	}
+}
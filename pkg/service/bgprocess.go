@@ -2,6 +2,7 @@ package service
 
 import (
 	"bytes"
+	"errors"
 	"os"
 	"strconv"
 	"syscall"
@@ -24,12 +25,16 @@ type BGProcessService struct {
 	// Command configuration
 	command     []string
 	argv0       string // override argv[0] presented to the exec'd binary (runit chpst -b)
+	memfdExec   bool   // exec-memfd: run an in-memory copy of command[0]
 	stopCommand []string
 	workingDir  string
 	envFile     string
 
 	// PID file path (required)
 	pidFile string
+	// pidFileLocking: the daemon holds an flock on pidFile while it
+	// runs, so the lock rather than kill(pid, 0) tells if it is alive.
+	pidFileLocking bool
 
 	// Credentials
 	runAsUID          uint32
@@ -75,11 +80,10 @@ type BGProcessService struct {
 	restartMaxDelay time.Duration
 
 	// Restart rate limiting
-	restartInterval      time.Duration
-	maxRestartCount      int
-	restartIntervalTime  time.Time
-	restartIntervalCount int
-	lastStartTime        time.Time
+	restartInterval time.Duration
+	maxRestartCount int
+	restartTokens   tokenBucket // zero until the first CheckRestart
+	lastStartTime   time.Time
 
 	// State tracking
 	stopIssued       bool
@@ -97,6 +101,11 @@ type BGProcessService struct {
 	// Channels for monitoring goroutine coordination
 	doneCh        chan struct{}
 	timerUpdateCh chan struct{}
+
+	// daemonExitCh receives the daemon's wait status when the PID-1
+	// orphan reaper collects it (see DeliverChildExit). Buffered so the
+	// reaper never blocks; recreated for each monitorDaemon run.
+	daemonExitCh chan process.ChildExit
 }
 
 type bgTimerPurpose uint8
@@ -137,11 +146,13 @@ func NewBGProcessService(set *ServiceSet, name string) *BGProcessService {
 
 func (s *BGProcessService) SetCommand(cmd []string)         { s.command = cmd }
 func (s *BGProcessService) SetArgv0(a string)               { s.argv0 = a }
+func (s *BGProcessService) SetMemfdExec(v bool)             { s.memfdExec = v }
 func (s *BGProcessService) SetStopCommand(cmd []string)     { s.stopCommand = cmd }
 func (s *BGProcessService) SetWorkingDir(dir string)        { s.workingDir = dir }
 func (s *BGProcessService) SetEnvFile(path string)          { s.envFile = path }
 func (s *BGProcessService) SetPIDFile(path string)          { s.pidFile = path }
 func (s *BGProcessService) GetPIDFile() string              { return s.pidFile }
+func (s *BGProcessService) SetPIDFileLocking(v bool)        { s.pidFileLocking = v }
 func (s *BGProcessService) SetRunAs(uid, gid uint32)        { s.runAsUID = uid; s.runAsGID = gid }
 func (s *BGProcessService) SetSupplementaryGroups(gids []uint32) {
 	s.supplementaryGIDs = gids
@@ -163,6 +174,7 @@ func (s *BGProcessService) effectiveRunAsGID() uint32 {
 }
 func (s *BGProcessService) SetStartTimeout(d time.Duration) { s.startTimeout = d }
 func (s *BGProcessService) SetStopTimeout(d time.Duration)  { s.stopTimeout = d }
+func (s *BGProcessService) StopTimeout() time.Duration      { return s.stopTimeout }
 func (s *BGProcessService) SetTimeoutAbortSec(d time.Duration) { s.timeoutAbortSec = d }
 func (s *BGProcessService) SetTimeoutStartFailureMode(m TimeoutFailureMode) {
 	s.timeoutStartFailureMode = m
@@ -189,7 +201,8 @@ func (s *BGProcessService) SetRestartMaxDelay(d time.Duration) {
 
 // nextRestartDelay returns the delay to use for the next restart and advances
 // the progressive backoff counter. When step <= 0, always returns restartDelay.
-// Jitter (restartRandomizedDelay) is applied on top of the base value when set.
+// Jitter (restartRandomizedDelay) is applied on top of the base value when set,
+// after the global restart jitter factor has scaled it.
 func (s *BGProcessService) nextRestartDelay() time.Duration {
 	var delay time.Duration
 	if s.restartDelayStep <= 0 {
@@ -209,7 +222,7 @@ func (s *BGProcessService) nextRestartDelay() time.Duration {
 		}
 		s.currentRestartDelay = next
 	}
-	total := delay + jitter(s.restartRandomizedDelay)
+	total := scaleJitter(delay, s.services.restartJitterFactor) + jitter(s.restartRandomizedDelay)
 	if s.restartMaxDelay > 0 && total > s.restartMaxDelay {
 		total = s.restartMaxDelay
 	}
@@ -256,6 +269,7 @@ func (s *BGProcessService) GetLogType() LogType { return s.logType }
 func (s *BGProcessService) SetRestartLimits(interval time.Duration, maxCount int) {
 	s.restartInterval = interval
 	s.maxRestartCount = maxCount
+	s.restartTokens = tokenBucket{}
 }
 
 // PID returns the daemon PID if known, otherwise the launcher PID.
@@ -283,6 +297,16 @@ func (s *BGProcessService) BringUp() bool {
 		s.services.logger.Error("Service '%s': no command specified", s.serviceName)
 		return false
 	}
+	if !s.preExecCheck(process.ExecParams{
+		Command:    s.command,
+		WorkingDir: s.workingDir,
+		EnvFile:    s.envFile,
+		PIDFile:    s.pidFile,
+		RunAsUID:   s.runAsUID,
+		RunAsGID:   s.runAsGID,
+	}) {
+		return false
+	}
 
 	// systemd GuessMainPID= opt-in: allow bgprocess with no pid-file
 	// as long as the service runs in a delegated cgroup — we scan
@@ -358,18 +382,12 @@ func (s *BGProcessService) BringUp() bool {
 		}
 		outputPipe = s.outputPipeW
 	} else if s.logType == LogToFile && s.logFile != "" {
-		// O_NOFOLLOW + fchown-via-fd: close the symlink-swap TOCTOU
-		// where root slinit could be tricked into writing/chowning an
-		// attacker-pointed path.
-		f, err := os.OpenFile(s.logFile, os.O_WRONLY|os.O_CREATE|os.O_APPEND|syscall.O_NOFOLLOW, os.FileMode(s.logFilePerms))
+		f, err := openLogFile(s.logFile, os.FileMode(s.logFilePerms), s.logFileUID, s.logFileGID)
 		if err != nil {
 			s.services.logger.Error("Service '%s': failed to open logfile '%s': %v",
 				s.serviceName, s.logFile, err)
 			return false
 		}
-		if s.logFileUID >= 0 || s.logFileGID >= 0 {
-			_ = f.Chown(s.logFileUID, s.logFileGID)
-		}
 		outputPipe = f
 	}
 
@@ -389,6 +407,7 @@ func (s *BGProcessService) BringUp() bool {
 	params := process.ExecParams{
 		Command:           s.command,
 		Argv0:             s.argv0,
+		MemfdExec:         s.memfdExec,
 		WorkingDir:        s.workingDir,
 		Env:               s.buildEnv(),
 		TermSignal:        s.termSignal,
@@ -399,6 +418,9 @@ func (s *BGProcessService) BringUp() bool {
 		OutputPipe:        outputPipe,
 		InputPipe:         inputPipe,
 	}
+	if s.logType == LogToBuffer {
+		params.MultiOutput = bufferTee(outputPipe, s.logFile, s.logFilePerms, s.logFileUID, s.logFileGID)
+	}
 	s.Record().ApplyProcessAttrs(&params)
 
 	pid, exitCh, err := process.StartProcess(params)
@@ -409,6 +431,7 @@ func (s *BGProcessService) BringUp() bool {
 			outputPipe.Close()
 		}
 		s.services.logger.Error("Service '%s': failed to start launcher: %v", s.serviceName, err)
+		s.Record().execErr = err
 		return false
 	}
 
@@ -573,29 +596,26 @@ func (s *BGProcessService) InterruptStart() bool {
 
 // CheckRestart checks if the service should auto-restart (rate limiting).
 func (s *BGProcessService) CheckRestart() bool {
-	now := time.Now()
-
-	if s.maxRestartCount > 0 {
-		elapsed := now.Sub(s.restartIntervalTime)
-
-		if elapsed < s.restartInterval {
-			if s.restartIntervalCount >= s.maxRestartCount {
-				s.services.logger.Error("Service '%s': restarting too quickly, stopping",
-					s.serviceName)
-				return false
-			}
-			s.restartIntervalCount++
-		} else {
-			// Stable period: reset progressive backoff
-			s.restartIntervalTime = now
-			s.restartIntervalCount = 1
-			s.currentRestartDelay = s.restartDelay
-		}
+	ok, rested := checkRestartLimit(&s.restartTokens, s.maxRestartCount, s.restartInterval)
+	if !ok {
+		s.services.logger.Error("Service '%s': restarting too quickly, stopping",
+			s.serviceName)
+		return false
+	}
+	if rested {
+		// Stable period: reset progressive backoff
+		s.currentRestartDelay = s.restartDelay
 	}
-
 	return true
 }
 
+// resetRestartLimit refills the restart-limit-count budget and resets
+// progressive backoff (slinitctl reset-failed).
+func (s *BGProcessService) resetRestartLimit() {
+	s.restartTokens = tokenBucket{}
+	s.currentRestartDelay = s.restartDelay
+}
+
 // monitorLauncher waits for the launcher process to exit, then reads
 // the PID file and starts monitoring the daemon.
 func (s *BGProcessService) monitorLauncher(exitCh <-chan process.ChildExit) {
@@ -687,7 +707,7 @@ func (s *BGProcessService) handleLauncherExit(exit process.ChildExit) {
 		err    error
 	)
 	if s.pidFile != "" {
-		pid, result, err = process.ReadPIDFile(s.pidFile)
+		pid, result, err = s.readDaemonPID()
 	} else if s.Record().GuessMainPID() {
 		pid, err = guessMainPIDFromCgroup(s.EffectiveCgroupPath())
 		if err != nil {
@@ -729,16 +749,41 @@ func (s *BGProcessService) handleLauncherExit(exit process.ChildExit) {
 	s.services.processQueuesLocked()
 
 	// Start monitoring the daemon process
-	go s.monitorDaemon()
+	s.daemonExitCh = make(chan process.ChildExit, 1)
+	go s.monitorDaemon(s.daemonExitCh)
+}
+
+// readDaemonPID reads the daemon PID from pid-file. With
+// pid-file-locking an unlocked file means the daemon is gone even if
+// its PID is now in use by something else; kill(pid, 0) only decides
+// when the file system cannot do the lock test.
+func (s *BGProcessService) readDaemonPID() (int, process.PIDResult, error) {
+	if !s.pidFileLocking {
+		return process.ReadPIDFile(s.pidFile)
+	}
+	pid, lockFd, err := process.ReadAndLockPIDFile(s.pidFile)
+	switch {
+	case errors.Is(err, process.ErrPIDLockUnsupported):
+		s.services.logger.Info("Service '%s': %v, checking the PID instead", s.serviceName, err)
+		return process.ReadPIDFile(s.pidFile)
+	case err != nil:
+		return 0, process.PIDResultFailed, err
+	case lockFd >= 0:
+		syscall.Close(lockFd)
+		return pid, process.PIDResultTerminated, nil
+	}
+	return pid, process.PIDResultOK, nil
 }
 
 // monitorDaemon polls for daemon process existence.
-// Uses /proc/PID/stat start time to detect PID recycling.
-func (s *BGProcessService) monitorDaemon() {
+// Uses /proc/PID/stat start time to detect PID recycling. An exit
+// injected on exitCh (the daemon was reaped by the orphan reaper)
+// ends monitoring immediately, with the real wait status.
+func (s *BGProcessService) monitorDaemon(exitCh <-chan process.ChildExit) {
 	if s.daemonPID <= 0 {
 		s.services.logger.Error("Service '%s': monitorDaemon called with invalid PID %d",
 			s.serviceName, s.daemonPID)
-		s.handleDaemonTermination()
+		s.handleDaemonTermination(nil)
 		return
 	}
 
@@ -752,13 +797,13 @@ func (s *BGProcessService) monitorDaemon() {
 		select {
 		case <-ticker.C:
 			if s.daemonPID <= 0 {
-				s.handleDaemonTermination()
+				s.handleDaemonTermination(nil)
 				return
 			}
 			err := syscall.Kill(s.daemonPID, 0)
 			if err != nil {
 				// Process is gone
-				s.handleDaemonTermination()
+				s.handleDaemonTermination(nil)
 				return
 			}
 			// Guard against PID recycling: if the start time changed,
@@ -768,11 +813,15 @@ func (s *BGProcessService) monitorDaemon() {
 				if curStartTime != "" && curStartTime != origStartTime {
 					s.services.logger.Error("Service '%s': PID %d was recycled (start time changed), treating as terminated",
 						s.serviceName, s.daemonPID)
-					s.handleDaemonTermination()
+					s.handleDaemonTermination(nil)
 					return
 				}
 			}
 
+		case exit := <-exitCh:
+			s.handleDaemonTermination(&exit)
+			return
+
 		case <-s.getTimerChan():
 			s.handleTimerExpired()
 
@@ -785,12 +834,39 @@ func (s *BGProcessService) monitorDaemon() {
 	}
 }
 
+// DeliverChildExit hands the daemon's wait status, collected by the
+// PID-1 orphan reaper, to the monitorDaemon goroutine. Without it the
+// kill(0) poll still notices the death but the exit status is lost.
+// Returns false if pid is not this service's daemon.
+func (s *BGProcessService) DeliverChildExit(pid int, status syscall.WaitStatus) bool {
+	s.services.queueMu.RLock()
+	defer s.services.queueMu.RUnlock()
+	if pid <= 0 || pid != s.daemonPID || s.daemonExitCh == nil {
+		return false
+	}
+	select {
+	case s.daemonExitCh <- process.ChildExit{PID: pid, Status: status}:
+	default:
+	}
+	return true
+}
+
 // handleDaemonTermination handles when the daemon process disappears.
-// Runs in the monitorDaemon goroutine; acquires queueMu.
-func (s *BGProcessService) handleDaemonTermination() {
+// exit is the reaped wait status when known, nil when the death was
+// detected by polling. Runs in the monitorDaemon goroutine; acquires
+// queueMu.
+func (s *BGProcessService) handleDaemonTermination(exit *process.ChildExit) {
 	s.services.queueMu.Lock()
 	defer s.services.queueMu.Unlock()
 
+	s.daemonExitCh = nil
+	if exit != nil {
+		s.exitStatus = ExitStatus{
+			WaitStatus: exit.Status,
+			HasStatus:  true,
+		}
+	}
+
 	// Log severity follows expectation: if we initiated the stop
 	// (state == StateStopping), the daemon dying IS the success
 	// case — operators should not see ERROR during a clean shutdown.
@@ -819,7 +895,7 @@ func (s *BGProcessService) handleDaemonTermination() {
 		s.services.processQueuesLocked()
 
 	case StateStarted:
-		if s.smoothRecovery && s.CheckRestart() {
+		if s.smoothRecovery && s.canAutoRestart() {
 			s.doingSmoothRecov = true
 			s.doSmoothRecovery()
 		} else {
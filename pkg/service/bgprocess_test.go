@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strconv"
+	"syscall"
 	"testing"
 	"time"
 )
@@ -175,3 +177,75 @@ func TestBGProcessServiceWithDependency(t *testing.T) {
 		t.Errorf("dep should be STOPPED, got %v", dep.State())
 	}
 }
+
+func TestBGProcessServiceDeliverChildExit(t *testing.T) {
+	set, _ := newTestSet()
+
+	pidFile := filepath.Join(t.TempDir(), "daemon.pid")
+
+	svc := NewBGProcessService(set, "bg-svc-reaped")
+	svc.SetCommand(bgTestDaemonScript(pidFile, 60))
+	svc.SetPIDFile(pidFile)
+	set.AddService(svc)
+
+	set.StartService(svc)
+
+	deadline := time.Now().Add(3 * time.Second)
+	for time.Now().Before(deadline) && svc.State() != StateStarted {
+		time.Sleep(50 * time.Millisecond)
+	}
+	if svc.State() != StateStarted {
+		t.Fatalf("expected STARTED, got %v", svc.State())
+	}
+
+	daemonPID := svc.PID()
+	t.Cleanup(func() { syscall.Kill(daemonPID, syscall.SIGKILL) })
+
+	if got := set.FindByPID(daemonPID); got != svc {
+		t.Fatalf("FindByPID(%d) = %v, want bg-svc-reaped", daemonPID, got)
+	}
+	if svc.DeliverChildExit(daemonPID+1, 0) {
+		t.Error("DeliverChildExit accepted a pid that is not the daemon")
+	}
+
+	// Simulate the orphan reaper collecting "exit 3". The monitor must
+	// act on it well before its next kill(0) poll and keep the status.
+	if !svc.DeliverChildExit(daemonPID, syscall.WaitStatus(3<<8)) {
+		t.Fatal("DeliverChildExit rejected the daemon pid")
+	}
+	deadline = time.Now().Add(daemonPollInterval / 2)
+	for time.Now().Before(deadline) && svc.State() != StateStopped {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if svc.State() != StateStopped {
+		t.Fatalf("expected STOPPED after injected exit, got %v", svc.State())
+	}
+	if es := svc.GetExitStatus(); !es.Exited() || es.ExitCode() != 3 {
+		t.Errorf("exit status = %+v, want exited with code 3", es)
+	}
+}
+
+func TestBGProcessPIDFileLockingDetectsStaleFile(t *testing.T) {
+	set, _ := newTestSet()
+
+	// A stale PID file naming a live process (the test itself), as if
+	// the daemon crashed and its PID was reused. kill(pid, 0) would
+	// take it for the daemon; the missing lock gives it away.
+	pidFile := filepath.Join(t.TempDir(), "daemon.pid")
+	os.WriteFile(pidFile, []byte(strconv.Itoa(os.Getpid())+"\n"), 0644)
+
+	svc := NewBGProcessService(set, "bg-svc-stale")
+	svc.SetCommand([]string{"/bin/true"})
+	svc.SetPIDFile(pidFile)
+	svc.SetPIDFileLocking(true)
+	set.AddService(svc)
+
+	set.StartService(svc)
+	deadline := time.Now().Add(3 * time.Second)
+	for time.Now().Before(deadline) && svc.State() != StateStopped {
+		time.Sleep(20 * time.Millisecond)
+	}
+	if svc.State() != StateStopped {
+		t.Fatalf("expected STOPPED for an unlocked PID file, got %v", svc.State())
+	}
+}
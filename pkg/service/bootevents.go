@@ -0,0 +1,94 @@
+package service
+
+import (
+	"sync"
+	"time"
+)
+
+// maxBootEvents bounds the boot event log; a boot that starts more
+// services than this keeps its first events.
+const maxBootEvents = 4096
+
+// BootEventType identifies an entry of the boot event log.
+type BootEventType uint8
+
+const (
+	BootEventKernel         BootEventType = iota // kernel started (derived from its uptime)
+	BootEventSlinitStart                         // slinit started: userspace begins
+	BootEventServiceStart                        // a service was asked to start
+	BootEventServiceStarted                      // a service reached STARTED
+	BootEventServiceFailed                       // a service failed to start
+)
+
+func (t BootEventType) String() string {
+	switch t {
+	case BootEventKernel:
+		return "kernel"
+	case BootEventSlinitStart:
+		return "slinit"
+	case BootEventServiceStart:
+		return "start"
+	case BootEventServiceStarted:
+		return "started"
+	case BootEventServiceFailed:
+		return "failed"
+	}
+	return "unknown"
+}
+
+// BootEvent is one entry of the boot timeline (slinitctl boot-time
+// --events).
+type BootEvent struct {
+	Time    time.Time
+	Type    BootEventType
+	Service string // empty for the kernel and slinit events
+}
+
+// bootEventLog collects service events until the boot service reaches
+// STARTED. It has its own lock since the control socket reads it from
+// outside the scheduler.
+type bootEventLog struct {
+	mu     sync.Mutex
+	events []BootEvent
+	closed bool // boot finished; nothing more is recorded
+}
+
+// recordBootEvent appends a service event to the boot timeline while
+// the boot is still in progress.
+func (ss *ServiceSet) recordBootEvent(typ BootEventType, name string) {
+	l := &ss.bootEvents
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.closed || len(l.events) >= maxBootEvents {
+		return
+	}
+	l.events = append(l.events, BootEvent{Time: time.Now(), Type: typ, Service: name})
+	// The boot service reaching STARTED is the last event of the boot.
+	if typ == BootEventServiceStarted && name != "" && name == ss.bootServiceName {
+		l.closed = true
+	}
+}
+
+// BootEventLog returns the boot timeline, oldest first: the kernel and
+// slinit start, then every service start request, start and start
+// failure up to the boot service reaching STARTED.
+func (ss *ServiceSet) BootEventLog() []BootEvent {
+	var log []BootEvent
+	if !ss.bootStartTime.IsZero() {
+		if ss.kernelUptime > 0 {
+			log = append(log, BootEvent{Time: ss.bootStartTime.Add(-ss.kernelUptime), Type: BootEventKernel})
+		}
+		log = append(log, BootEvent{Time: ss.bootStartTime, Type: BootEventSlinitStart})
+	}
+	ss.bootEvents.mu.Lock()
+	defer ss.bootEvents.mu.Unlock()
+	return append(log, ss.bootEvents.events...)
+}
+
+// resetBootEvents starts a fresh boot timeline (ResetBootTiming).
+func (ss *ServiceSet) resetBootEvents() {
+	ss.bootEvents.mu.Lock()
+	defer ss.bootEvents.mu.Unlock()
+	ss.bootEvents.events = nil
+	ss.bootEvents.closed = false
+}
@@ -0,0 +1,56 @@
+package service
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBootEventLog(t *testing.T) {
+	set, _ := newTestSet()
+	start := time.Now()
+	set.SetBootStartTime(start)
+	set.SetKernelUptime(3 * time.Second)
+	set.SetBootServiceName("boot")
+
+	broken := NewProcessService(set, "broken")
+	broken.SetCommand([]string{"/nonexistent/binary"})
+	boot := NewInternalService(set, "boot")
+	set.AddService(broken)
+	set.AddService(boot)
+
+	set.StartService(broken)
+	waitStopped(broken, 2*time.Second)
+	set.StartService(boot)
+	// Boot is over: later events are not part of the timeline.
+	set.StopService(broken)
+	set.StartService(broken)
+
+	log := set.BootEventLog()
+	want := []struct {
+		typ  BootEventType
+		name string
+	}{
+		{BootEventKernel, ""},
+		{BootEventSlinitStart, ""},
+		{BootEventServiceStart, "broken"},
+		{BootEventServiceFailed, "broken"},
+		{BootEventServiceStart, "boot"},
+		{BootEventServiceStarted, "boot"},
+	}
+	if len(log) != len(want) {
+		t.Fatalf("got %d events, want %d: %+v", len(log), len(want), log)
+	}
+	for i, w := range want {
+		if log[i].Type != w.typ || log[i].Service != w.name {
+			t.Errorf("event %d = %v %q, want %v %q", i, log[i].Type, log[i].Service, w.typ, w.name)
+		}
+	}
+	if !log[0].Time.Equal(start.Add(-3 * time.Second)) {
+		t.Errorf("kernel event at %v, want boot start minus uptime", log[0].Time)
+	}
+
+	set.ResetBootTiming()
+	if got := set.BootEventLog(); len(got) != 2 {
+		t.Errorf("after reset got %+v, want only the kernel and slinit events", got)
+	}
+}
@@ -0,0 +1,63 @@
+package service
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestConsoleQueueHighPriorityFirst(t *testing.T) {
+	set, _ := newTestSet()
+
+	// Triggered services hold the console until triggered, so each
+	// hand-off can be observed.
+	mk := func(name string, high bool) *TriggeredService {
+		svc := NewTriggeredService(set, name)
+		svc.Record().Flags.StartsOnConsole = true
+		svc.Record().SetHighConsolePriority(high)
+		set.AddService(svc)
+		return svc
+	}
+	a := mk("a", false)
+	b := mk("b", false)
+	rescue := mk("rescue", true)
+
+	// Nobody holds the console yet, so everything queues.
+	set.StartService(a)
+	set.StartService(b)
+	set.StartService(rescue)
+
+	holder := func() string {
+		var names []string
+		for _, svc := range []*TriggeredService{a, b, rescue} {
+			if svc.Record().haveConsole {
+				names = append(names, svc.Name())
+			}
+		}
+		return strings.Join(names, ",")
+	}
+
+	set.PullConsoleQueue()
+	for _, want := range []string{"rescue", "a", "b"} {
+		if got := holder(); got != want {
+			t.Fatalf("console holder = %q, want %q", got, want)
+		}
+		svc := set.FindService(want, false).(*TriggeredService)
+		svc.SetTrigger(true)
+		if svc.State() != StateStarted {
+			t.Fatalf("%s: state = %v after trigger, want started", want, svc.State())
+		}
+	}
+	if got := holder(); got != "" {
+		t.Errorf("console still held by %q", got)
+	}
+}
+
+func TestUnqueueConsoleHighPriority(t *testing.T) {
+	set, _ := newTestSet()
+	svc := NewInternalService(set, "rescue")
+	set.AppendHighPriorityConsoleQueue(svc)
+	set.UnqueueConsole(svc)
+	if len(set.highPriorityConsoleQueue) != 0 {
+		t.Errorf("service still queued after UnqueueConsole")
+	}
+}
@@ -0,0 +1,58 @@
+package service
+
+import "time"
+
+// ChainLink is one service on a critical chain.
+type ChainLink struct {
+	Name    string
+	Started time.Time     // when the service reached STARTED
+	Took    time.Duration // its own start time, excluding the wait for the next link
+}
+
+// CriticalChain walks back from root along the dependencies that held
+// it up, in the manner of systemd-analyze critical-chain: at each step
+// it follows the dependency that reached STARTED last, but no later
+// than the service itself did. The result starts with root and ends
+// with a service that waited for nothing. times returns when a service
+// was asked to start and when it reached STARTED (ok false when it
+// never did); deps returns the names a service depends on. Neither
+// needs a ServiceSet, so clients holding only the wire-level data can
+// use it. Returns nil if root never started.
+func CriticalChain(root string, times func(name string) (requested, started time.Time, ok bool),
+	deps func(name string) []string) []ChainLink {
+	requested, started, ok := times(root)
+	if !ok {
+		return nil
+	}
+	var chain []ChainLink
+	seen := map[string]bool{root: true}
+	name := root
+	for {
+		var next string
+		var nextReq, nextStarted time.Time
+		for _, d := range deps(name) {
+			if seen[d] {
+				continue
+			}
+			r, s, ok := times(d)
+			if !ok || s.After(started) {
+				continue
+			}
+			if next == "" || s.After(nextStarted) {
+				next, nextReq, nextStarted = d, r, s
+			}
+		}
+		// The service's own work began once both its start request and
+		// the dependency it waited for longest were in.
+		begin := requested
+		if next != "" && nextStarted.After(begin) {
+			begin = nextStarted
+		}
+		chain = append(chain, ChainLink{Name: name, Started: started, Took: max(started.Sub(begin), 0)})
+		if next == "" {
+			return chain
+		}
+		seen[next] = true
+		name, requested, started = next, nextReq, nextStarted
+	}
+}
@@ -0,0 +1,51 @@
+package service
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCriticalChain(t *testing.T) {
+	base := time.Unix(1000, 0)
+	at := func(ms int) time.Time { return base.Add(time.Duration(ms) * time.Millisecond) }
+	// name -> requested, started (ms); "late" started after boot, "never"
+	// did not start at all.
+	times := map[string][2]int{
+		"boot":    {0, 900},
+		"network": {0, 700},
+		"udev":    {0, 300},
+		"syslog":  {0, 200},
+		"late":    {0, 950},
+	}
+	deps := map[string][]string{
+		"boot":    {"syslog", "network", "late", "never"},
+		"network": {"udev", "syslog"},
+		"udev":    {"network"}, // a cycle must not loop forever
+	}
+	chain := CriticalChain("boot",
+		func(name string) (time.Time, time.Time, bool) {
+			tt, ok := times[name]
+			return at(tt[0]), at(tt[1]), ok
+		},
+		func(name string) []string { return deps[name] })
+
+	want := []ChainLink{
+		{Name: "boot", Started: at(900), Took: 200 * time.Millisecond},
+		{Name: "network", Started: at(700), Took: 400 * time.Millisecond},
+		{Name: "udev", Started: at(300), Took: 300 * time.Millisecond},
+	}
+	if len(chain) != len(want) {
+		t.Fatalf("chain = %+v", chain)
+	}
+	for i := range want {
+		if chain[i] != want[i] {
+			t.Errorf("link %d = %+v, want %+v", i, chain[i], want[i])
+		}
+	}
+
+	if c := CriticalChain("never", func(string) (time.Time, time.Time, bool) {
+		return time.Time{}, time.Time{}, false
+	}, func(string) []string { return nil }); c != nil {
+		t.Errorf("unstarted root: %+v", c)
+	}
+}
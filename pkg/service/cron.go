@@ -10,6 +10,8 @@ import (
 	"strings"
 	"sync"
 	"time"
+
+	"github.com/sunlightlinux/slinit/pkg/process"
 )
 
 // cronPersistDir is the directory where CronRunner writes lastRun
@@ -365,5 +367,5 @@ func (cr *CronRunner) executeCommand() error {
 	defer cancel()
 
 	cmd := exec.CommandContext(ctx, cr.command[0], cr.command[1:]...)
-	return cmd.Run()
+	return process.Run(cmd)
 }
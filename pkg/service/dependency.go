@@ -68,3 +68,41 @@ type PrelimDep struct {
 	To      string
 	DepType DependencyType
 }
+
+// DependencyInfo is a snapshot of one dependency edge for reporting.
+// For a dependency, Name and State describe the target; for a
+// dependent, the service that depends on it (State is then unset).
+type DependencyInfo struct {
+	Name       string
+	DepType    DependencyType
+	HoldingAcq bool
+	WaitingOn  bool
+	State      ServiceState
+}
+
+// DependencyInfo returns svc's dependencies, with their acquisition and
+// wait flags and the target's current state, and its dependents. The
+// edges are read under the set lock so the flags are consistent.
+func (ss *ServiceSet) DependencyInfo(svc Service) (deps, dependents []DependencyInfo) {
+	ss.queueMu.RLock()
+	defer ss.queueMu.RUnlock()
+	rec := svc.Record()
+	for _, d := range rec.Dependencies() {
+		deps = append(deps, DependencyInfo{
+			Name:       d.To.Name(),
+			DepType:    d.DepType,
+			HoldingAcq: d.HoldingAcq,
+			WaitingOn:  d.WaitingOn,
+			State:      d.To.State(),
+		})
+	}
+	for _, d := range rec.Dependents() {
+		dependents = append(dependents, DependencyInfo{
+			Name:       d.From.Name(),
+			DepType:    d.DepType,
+			HoldingAcq: d.HoldingAcq,
+			WaitingOn:  d.WaitingOn,
+		})
+	}
+	return deps, dependents
+}
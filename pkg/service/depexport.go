@@ -0,0 +1,136 @@
+package service
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"sort"
+	"strconv"
+)
+
+// DepExportRow is one dependency edge in a tabular dependency export,
+// together with the depending service's own state. Dep is nil for a
+// service with no dependencies, which still gets a row of its own.
+type DepExportRow struct {
+	Service  string
+	Type     ServiceType
+	State    ServiceState
+	PID      int // 0 when the service has no process
+	Restarts int64
+	Dep      *DependencyInfo
+}
+
+// depExportColumns is the header of the CSV/TSV export; WriteDepsJSON
+// uses the same names as object keys.
+var depExportColumns = []string{
+	"service_name", "service_type", "dep_name", "dep_type", "dep_state",
+	"dep_holding_acq", "service_state", "service_pid", "service_restart_count",
+}
+
+// DepExportRows returns the dependency edges of every loaded service,
+// ordered by service name with each service's dependencies in the order
+// they were declared.
+func DepExportRows(set *ServiceSet) []DepExportRow {
+	svcs := set.ListServices()
+	sort.Slice(svcs, func(i, j int) bool { return svcs[i].Name() < svcs[j].Name() })
+	var rows []DepExportRow
+	for _, svc := range svcs {
+		deps, _ := set.DependencyInfo(svc)
+		row := DepExportRow{
+			Service:  svc.Name(),
+			Type:     svc.Type(),
+			State:    svc.State(),
+			PID:      svc.PID(),
+			Restarts: svc.Record().FailureStats().TotalRestarts,
+		}
+		if len(deps) == 0 {
+			rows = append(rows, row)
+			continue
+		}
+		for i := range deps {
+			row.Dep = &deps[i]
+			rows = append(rows, row)
+		}
+	}
+	return rows
+}
+
+// ExportDepsCSV writes one CSV row per dependency edge of every loaded
+// service, for loading into a spreadsheet or a database.
+func ExportDepsCSV(set *ServiceSet, w io.Writer) error {
+	return WriteDepsCSV(w, DepExportRows(set), ',')
+}
+
+// WriteDepsCSV renders rows as delimited text with a header line; comma
+// is ',' for CSV and '\t' for TSV. A service without dependencies has
+// empty dep_* fields and an empty service_pid when it has no process.
+func WriteDepsCSV(w io.Writer, rows []DepExportRow, comma rune) error {
+	cw := csv.NewWriter(w)
+	cw.Comma = comma
+	if err := cw.Write(depExportColumns); err != nil {
+		return err
+	}
+	for _, r := range rows {
+		var depName, depType, depState, depHolding string
+		if r.Dep != nil {
+			depName = r.Dep.Name
+			depType = r.Dep.DepType.String()
+			depState = r.Dep.State.String()
+			depHolding = strconv.FormatBool(r.Dep.HoldingAcq)
+		}
+		pid := ""
+		if r.PID > 0 {
+			pid = strconv.Itoa(r.PID)
+		}
+		rec := []string{
+			r.Service, r.Type.String(), depName, depType, depState,
+			depHolding, r.State.String(), pid, strconv.FormatInt(r.Restarts, 10),
+		}
+		if err := cw.Write(rec); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// depExportJSON is the JSON form of a DepExportRow. The dep_* fields
+// are null for a service without dependencies.
+type depExportJSON struct {
+	ServiceName  string  `json:"service_name"`
+	ServiceType  string  `json:"service_type"`
+	DepName      *string `json:"dep_name"`
+	DepType      *string `json:"dep_type"`
+	DepState     *string `json:"dep_state"`
+	DepHolding   *bool   `json:"dep_holding_acq"`
+	ServiceState string  `json:"service_state"`
+	ServicePID   *int    `json:"service_pid"`
+	RestartCount int64   `json:"service_restart_count"`
+}
+
+// WriteDepsJSON renders rows as a JSON array of objects keyed by the
+// CSV column names.
+func WriteDepsJSON(w io.Writer, rows []DepExportRow) error {
+	out := make([]depExportJSON, 0, len(rows))
+	for _, r := range rows {
+		j := depExportJSON{
+			ServiceName:  r.Service,
+			ServiceType:  r.Type.String(),
+			ServiceState: r.State.String(),
+			RestartCount: r.Restarts,
+		}
+		if r.Dep != nil {
+			name, typ, state := r.Dep.Name, r.Dep.DepType.String(), r.Dep.State.String()
+			holding := r.Dep.HoldingAcq
+			j.DepName, j.DepType, j.DepState, j.DepHolding = &name, &typ, &state, &holding
+		}
+		if r.PID > 0 {
+			pid := r.PID
+			j.ServicePID = &pid
+		}
+		out = append(out, j)
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(out)
+}
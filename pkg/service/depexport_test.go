@@ -0,0 +1,71 @@
+package service
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+)
+
+func TestExportDepsCSV(t *testing.T) {
+	set, _ := newTestSet()
+	a := NewInternalService(set, "a")
+	b := NewInternalService(set, "b,c")
+	d := NewInternalService(set, "d")
+	a.Record().AddDep(b, DepRegular)
+	a.Record().AddDep(d, DepWaitsFor)
+	set.AddService(a)
+	set.AddService(b)
+	set.AddService(d)
+	set.StartService(a)
+
+	var buf bytes.Buffer
+	if err := ExportDepsCSV(set, &buf); err != nil {
+		t.Fatal(err)
+	}
+	want := `service_name,service_type,dep_name,dep_type,dep_state,dep_holding_acq,service_state,service_pid,service_restart_count
+a,internal,"b,c",regular,STARTED,true,STARTED,,0
+a,internal,d,waits-for,STARTED,true,STARTED,,0
+"b,c",internal,,,,,STARTED,,0
+d,internal,,,,,STARTED,,0
+`
+	if got := buf.String(); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestWriteDepsTSVAndJSON(t *testing.T) {
+	rows := []DepExportRow{
+		{Service: "web", Type: TypeProcess, State: StateStarted, PID: 42, Restarts: 3,
+			Dep: &DependencyInfo{Name: "db", DepType: DepSoft, State: StateStopped}},
+		{Service: "db", Type: TypeProcess, State: StateStopped},
+	}
+
+	var buf bytes.Buffer
+	if err := WriteDepsCSV(&buf, rows, '\t'); err != nil {
+		t.Fatal(err)
+	}
+	want := "service_name\tservice_type\tdep_name\tdep_type\tdep_state\tdep_holding_acq\tservice_state\tservice_pid\tservice_restart_count\n" +
+		"web\tprocess\tdb\tsoft\tSTOPPED\tfalse\tSTARTED\t42\t3\n" +
+		"db\tprocess\t\t\t\t\tSTOPPED\t\t0\n"
+	if got := buf.String(); got != want {
+		t.Errorf("tsv got:\n%q\nwant:\n%q", got, want)
+	}
+
+	buf.Reset()
+	if err := WriteDepsJSON(&buf, rows); err != nil {
+		t.Fatal(err)
+	}
+	var out []map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &out); err != nil {
+		t.Fatalf("invalid JSON: %v\n%s", err, buf.String())
+	}
+	if len(out) != 2 {
+		t.Fatalf("got %d objects, want 2", len(out))
+	}
+	if out[0]["dep_name"] != "db" || out[0]["service_pid"] != float64(42) || out[0]["dep_holding_acq"] != false {
+		t.Errorf("first object = %v", out[0])
+	}
+	if out[1]["dep_name"] != nil || out[1]["service_pid"] != nil {
+		t.Errorf("service without deps should have null dep fields: %v", out[1])
+	}
+}
@@ -0,0 +1,118 @@
+package service
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// SetVersion records the daemon version reported by PrintDiagnostics.
+func (ss *ServiceSet) SetVersion(v string) { ss.version = v }
+
+// PrintDiagnostics writes a human-readable dump of the whole service
+// graph to w: daemon identity and uptime, boot timing, shutdown state,
+// queue depths, and for every service its state, reference counts,
+// pins and dependency edges. It is meant for debugging stuck starts or
+// shutdowns (SIGUSR2, slinitctl diagnostics), not for parsing.
+// Read-only; safe to call at any time.
+func (ss *ServiceSet) PrintDiagnostics(w io.Writer) error {
+	ss.queueMu.RLock()
+	defer ss.queueMu.RUnlock()
+
+	bw := bufio.NewWriter(w)
+	now := time.Now()
+
+	version := ss.version
+	if version == "" {
+		version = "unknown"
+	}
+	fmt.Fprintf(bw, "slinit diagnostics at %s\n", now.Format(time.RFC3339))
+	fmt.Fprintf(bw, "version: %s\n", version)
+	fmt.Fprintf(bw, "pid: %d\n", os.Getpid())
+	if !ss.bootStartTime.IsZero() {
+		fmt.Fprintf(bw, "uptime: %s\n", now.Sub(ss.bootStartTime).Round(time.Millisecond))
+	}
+
+	fmt.Fprintln(bw)
+	fmt.Fprintln(bw, "boot:")
+	boot := ss.bootServiceName
+	if boot == "" {
+		boot = "-"
+	}
+	fmt.Fprintf(bw, "  service: %s\n", boot)
+	if ss.kernelUptime > 0 {
+		fmt.Fprintf(bw, "  kernel: %s\n", ss.kernelUptime.Round(time.Millisecond))
+	}
+	if ss.bootReadyTime.IsZero() {
+		fmt.Fprintln(bw, "  ready: not yet")
+	} else {
+		fmt.Fprintf(bw, "  ready: after %s\n", ss.bootReadyTime.Sub(ss.bootStartTime).Round(time.Millisecond))
+	}
+
+	fmt.Fprintln(bw)
+	if ss.IsShuttingDown() {
+		fmt.Fprintf(bw, "shutdown: in progress (%s)\n", ss.shutdownType)
+	} else {
+		fmt.Fprintln(bw, "shutdown: no")
+	}
+	fmt.Fprintf(bw, "active services: %d\n", ss.activeServices)
+	fmt.Fprintf(bw, "propQueue len: %d, stopQueue len: %d\n", ss.propQueue.Len(), len(ss.stopQueue))
+	fmt.Fprintf(bw, "console queue: %s\n", diagNames(ss.consoleQueue))
+	fmt.Fprintf(bw, "high-priority console queue: %s\n", diagNames(ss.highPriorityConsoleQueue))
+
+	services := ss.ListServices()
+	sort.Slice(services, func(i, j int) bool { return services[i].Name() < services[j].Name() })
+	fmt.Fprintln(bw)
+	fmt.Fprintf(bw, "services (%d):\n", len(services))
+	for _, svc := range services {
+		sr := svc.Record()
+		fmt.Fprintf(bw, "  %s (%s)\n", svc.Name(), sr.recordType)
+		fmt.Fprintf(bw, "    state: %s, target: %s", sr.State(), sr.TargetState())
+		if pid := svc.PID(); pid > 0 {
+			fmt.Fprintf(bw, ", pid: %d", pid)
+		}
+		fmt.Fprintln(bw)
+		fmt.Fprintf(bw, "    requiredBy: %d, startExplicit: %t, startFailed: %t\n",
+			sr.requiredBy, sr.startExplicit, sr.startFailed)
+		fmt.Fprintf(bw, "    pins: started=%t stopped=%t dependent-started=%t\n",
+			sr.pinnedStarted, sr.pinnedStopped, sr.deptPinnedStarted)
+		var waiting []string
+		if sr.waitingForDeps {
+			waiting = append(waiting, "deps")
+		}
+		if sr.waitingForConsole {
+			waiting = append(waiting, "console")
+		}
+		if sr.waitingForStartSlot {
+			waiting = append(waiting, "start-slot")
+		}
+		if len(waiting) > 0 {
+			fmt.Fprintf(bw, "    waiting for: %s\n", strings.Join(waiting, ", "))
+		}
+		if len(sr.dependsOn) == 0 {
+			continue
+		}
+		fmt.Fprintln(bw, "    depends on:")
+		for _, dep := range sr.dependsOn {
+			fmt.Fprintf(bw, "      %s (%s) %s HoldingAcq=%t WaitingOn=%t\n",
+				dep.To.Name(), dep.DepType, dep.To.State(), dep.HoldingAcq, dep.WaitingOn)
+		}
+	}
+	return bw.Flush()
+}
+
+// diagNames lists the services in a queue, or "-" when it is empty.
+func diagNames(q []Service) string {
+	if len(q) == 0 {
+		return "-"
+	}
+	names := make([]string, len(q))
+	for i, svc := range q {
+		names[i] = svc.Name()
+	}
+	return strings.Join(names, ", ")
+}
@@ -0,0 +1,42 @@
+package service
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestPrintDiagnostics(t *testing.T) {
+	set, _ := newTestSet()
+	set.SetVersion("v9.9.9")
+	dep := NewInternalService(set, "dep")
+	main := NewInternalService(set, "main")
+	set.AddService(dep)
+	set.AddService(main)
+	main.Record().AddDep(dep, DepRegular)
+	set.StartService(main)
+
+	var buf bytes.Buffer
+	if err := set.PrintDiagnostics(&buf); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	for _, want := range []string{
+		"version: v9.9.9",
+		"shutdown: no",
+		"active services: 2",
+		"propQueue len: 0, stopQueue len: 0",
+		"services (2):",
+		"  main (internal)",
+		"requiredBy: 1, startExplicit: true, startFailed: false",
+		"dep (regular) STARTED HoldingAcq=true WaitingOn=false",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("missing %q in:\n%s", want, out)
+		}
+	}
+	// Services are listed by name.
+	if strings.Index(out, "  dep (") > strings.Index(out, "  main (") {
+		t.Errorf("services not sorted:\n%s", out)
+	}
+}
@@ -0,0 +1,52 @@
+package service
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/sunlightlinux/slinit/pkg/process"
+)
+
+// A start whose encrypted-env-file cannot be decrypted fails instead of
+// running the service without its secrets.
+func TestEncryptedEnvFileWrongKeyFailsStart(t *testing.T) {
+	set, logger := newTestSet()
+	t.Setenv("SLINIT_TEST_SEAL_KEY", strings.Repeat("ab", 32))
+	t.Setenv("SLINIT_TEST_WRONG_KEY", strings.Repeat("cd", 32))
+
+	sealed, err := process.EncryptEnvFile([]byte("DB_PASS=hunter2\n"), "env:SLINIT_TEST_SEAL_KEY")
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "secrets.enc")
+	if err := os.WriteFile(path, sealed, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	svc := NewProcessService(set, "secret-svc")
+	svc.SetCommand([]string{"/bin/sleep", "60"})
+	svc.SetEncryptedEnvFile(path, "env:SLINIT_TEST_WRONG_KEY")
+	set.AddService(svc)
+
+	set.StartService(svc)
+	if svc.State() != StateStopped {
+		t.Fatalf("state = %v, want STOPPED", svc.State())
+	}
+	if svc.PID() != 0 {
+		t.Errorf("pid = %d, want no process", svc.PID())
+	}
+	if len(logger.failed) == 0 {
+		t.Error("expected the start to be reported as failed")
+	}
+
+	// With the right key the same service starts.
+	svc.SetEncryptedEnvFile(path, "env:SLINIT_TEST_SEAL_KEY")
+	set.StartService(svc)
+	if svc.State() != StateStarted {
+		t.Fatalf("state with the right key = %v, want STARTED", svc.State())
+	}
+	set.StopService(svc)
+	waitForState(t, svc, StateStopped)
+}
@@ -0,0 +1,124 @@
+package service
+
+import (
+	"sync"
+	"time"
+)
+
+// eventBusBuffer is how many messages a subscriber may fall behind by
+// before the bus starts dropping its messages.
+const eventBusBuffer = 64
+
+// EventFilter selects the events a subscriber receives; nil receives
+// every event.
+type EventFilter func(svc Service, event ServiceEvent) bool
+
+// EventMessage is one service event delivered by the EventBus.
+type EventMessage struct {
+	Service string
+	Event   ServiceEvent
+	Time    time.Time
+	Details string // as GlobalEvent.Details
+	// Svc is the service itself; after EventRemoved it is no longer
+	// in the set.
+	Svc Service
+}
+
+// EventBus fans service events from the whole set out to channel
+// subscribers, each with its own filter. Publish never blocks: the
+// scheduler publishes with queueMu held, so a subscriber that does not
+// keep up loses messages rather than stalling every service.
+type EventBus struct {
+	mu   sync.Mutex
+	subs map[<-chan EventMessage]*eventSub
+}
+
+type eventSub struct {
+	ch      chan EventMessage
+	filter  EventFilter
+	dropped uint64
+}
+
+func newEventBus() *EventBus {
+	return &EventBus{subs: make(map[<-chan EventMessage]*eventSub)}
+}
+
+// EventBus returns the set's event bus.
+func (ss *ServiceSet) EventBus() *EventBus { return ss.eventBus }
+
+// Subscribe returns a channel that receives the events filter accepts
+// until Unsubscribe.
+func (b *EventBus) Subscribe(filter EventFilter) <-chan EventMessage {
+	sub := &eventSub{ch: make(chan EventMessage, eventBusBuffer), filter: filter}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.subs[sub.ch] = sub
+	return sub.ch
+}
+
+// Unsubscribe stops delivery to ch and closes it. Unknown channels are
+// ignored.
+func (b *EventBus) Unsubscribe(ch <-chan EventMessage) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if sub, ok := b.subs[ch]; ok {
+		delete(b.subs, ch)
+		close(sub.ch)
+	}
+}
+
+// Dropped returns how many messages ch has lost to a full buffer.
+func (b *EventBus) Dropped(ch <-chan EventMessage) uint64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if sub, ok := b.subs[ch]; ok {
+		return sub.dropped
+	}
+	return 0
+}
+
+// Publish delivers msg, an event of svc, to every subscriber whose
+// filter accepts it.
+func (b *EventBus) Publish(svc Service, msg EventMessage) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, sub := range b.subs {
+		if sub.filter != nil && !sub.filter(svc, msg.Event) {
+			continue
+		}
+		select {
+		case sub.ch <- msg:
+		default:
+			sub.dropped++
+		}
+	}
+}
+
+// publishMembership tells subscribers that svc was added to or removed
+// from the set. Membership events only go to the bus: they are not
+// state changes, so global listeners and the event history never see
+// them.
+func (ss *ServiceSet) publishMembership(svc Service, event ServiceEvent) {
+	ss.eventBus.Publish(svc, EventMessage{Service: svc.Name(), Event: event, Time: time.Now(), Svc: svc})
+}
+
+// IsMembershipEvent reports whether event is EventAdded or EventRemoved.
+func IsMembershipEvent(event ServiceEvent) bool {
+	return event == EventAdded || event == EventRemoved
+}
+
+// ServiceNameFilter returns a filter accepting the events of the named
+// services; with no names it accepts everything.
+func ServiceNameFilter(names ...string) EventFilter {
+	if len(names) == 0 {
+		return nil
+	}
+	want := make(map[string]struct{}, len(names))
+	for _, n := range names {
+		want[n] = struct{}{}
+	}
+	return func(svc Service, _ ServiceEvent) bool {
+		_, ok := want[svc.Name()]
+		return ok
+	}
+}
@@ -0,0 +1,81 @@
+package service
+
+import "testing"
+
+func drainBus(ch <-chan EventMessage) []EventMessage {
+	var out []EventMessage
+	for {
+		select {
+		case m := <-ch:
+			out = append(out, m)
+		default:
+			return out
+		}
+	}
+}
+
+func TestEventBusFilter(t *testing.T) {
+	set, _ := newTestSet()
+	a := NewInternalService(set, "a")
+	b := NewInternalService(set, "b")
+	set.AddService(a)
+	set.AddService(b)
+
+	bus := set.EventBus()
+	all := bus.Subscribe(nil)
+	onlyB := bus.Subscribe(ServiceNameFilter("b"))
+	started := bus.Subscribe(func(_ Service, ev ServiceEvent) bool { return ev == EventStarted })
+
+	set.StartService(a)
+	set.StartService(b)
+	set.StopService(a)
+
+	if got := drainBus(all); len(got) != 3 {
+		t.Errorf("unfiltered subscriber got %d events, want 3: %+v", len(got), got)
+	}
+	got := drainBus(onlyB)
+	if len(got) != 1 || got[0].Service != "b" || got[0].Event != EventStarted {
+		t.Errorf("name-filtered subscriber got %+v, want b STARTED", got)
+	}
+	if got[0].Time.IsZero() {
+		t.Error("event not timestamped")
+	}
+	if got := drainBus(started); len(got) != 2 {
+		t.Errorf("event-filtered subscriber got %d events, want 2: %+v", len(got), got)
+	}
+}
+
+func TestEventBusUnsubscribe(t *testing.T) {
+	set, _ := newTestSet()
+	svc := NewInternalService(set, "svc")
+	set.AddService(svc)
+
+	bus := set.EventBus()
+	ch := bus.Subscribe(nil)
+	bus.Unsubscribe(ch)
+	if _, ok := <-ch; ok {
+		t.Fatal("channel still open after Unsubscribe")
+	}
+	bus.Unsubscribe(ch) // second call is a no-op
+
+	set.StartService(svc) // must not panic sending on the closed channel
+}
+
+func TestEventBusDropsWhenFull(t *testing.T) {
+	set, _ := newTestSet()
+	svc := NewInternalService(set, "svc")
+	set.AddService(svc)
+
+	bus := set.EventBus()
+	ch := bus.Subscribe(nil)
+	for i := 0; i < eventBusBuffer/2+1; i++ {
+		set.StartService(svc)
+		set.StopService(svc)
+	}
+	if n := len(drainBus(ch)); n != eventBusBuffer {
+		t.Errorf("buffered %d events, want %d", n, eventBusBuffer)
+	}
+	if d := bus.Dropped(ch); d != 2 {
+		t.Errorf("Dropped = %d, want 2", d)
+	}
+}
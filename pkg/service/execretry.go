@@ -0,0 +1,71 @@
+package service
+
+import (
+	"time"
+
+	"github.com/sunlightlinux/slinit/pkg/process"
+)
+
+// bringUpFailed handles a BringUp that returned false. A transient
+// fork/exec failure allowed another attempt by the exec retry policy
+// leaves the service STARTING and retries on a timer; anything else
+// fails the start. Caller must hold queueMu.
+func (sr *ServiceRecord) bringUpFailed() {
+	err := sr.execErr
+	sr.execErr = nil
+	if err != nil {
+		retry := process.ExecRetry{
+			MaxAttempts:   sr.execRetryCount,
+			RetryInterval: sr.execRetryInterval,
+		}
+		if d, ok := sr.execRetries.Next(retry, err); ok {
+			sr.services.debugf("Service '%s': exec failed (%v), retrying in %v",
+				sr.serviceName, err, d)
+			sr.armExecRetry(d)
+			return
+		}
+		if n := sr.execRetries.Retries(); n > 0 {
+			sr.services.warnf("Service '%s': exec failed (%v), giving up after %d retries",
+				sr.serviceName, err, n)
+		}
+	}
+	sr.state.Store(StateStopping)
+	sr.failedToStart(false, true)
+}
+
+// armExecRetry schedules BringUp to run again after d. The timer runs in
+// its own goroutine and takes queueMu, so other services keep starting
+// meanwhile; by then the start may have been cancelled, in which case
+// the retry is dropped.
+func (sr *ServiceRecord) armExecRetry(d time.Duration) {
+	sr.cancelExecRetry()
+	svc := sr.self
+	set := sr.services
+	var t *time.Timer
+	t = time.AfterFunc(d, func() {
+		set.queueMu.Lock()
+		defer set.queueMu.Unlock()
+		rec := svc.Record()
+		if rec.execRetryTimer != t {
+			return
+		}
+		rec.execRetryTimer = nil
+		if svc.State() != StateStarting || set.FindService(svc.Name(), false) != svc {
+			return
+		}
+		if !svc.BringUp() {
+			rec.bringUpFailed()
+		}
+		set.processQueuesLocked()
+	})
+	sr.execRetryTimer = t
+}
+
+// cancelExecRetry drops a pending exec retry. Safe to call when none is
+// pending.
+func (sr *ServiceRecord) cancelExecRetry() {
+	if sr.execRetryTimer != nil {
+		sr.execRetryTimer.Stop()
+		sr.execRetryTimer = nil
+	}
+}
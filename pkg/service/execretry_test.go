@@ -0,0 +1,108 @@
+package service
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// busyScript creates a script that stays open for writing, so exec fails
+// with ETXTBSY until the returned file is closed — the package manager
+// replacing a binary in place.
+func busyScript(t *testing.T) (string, *os.File) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "busy.sh")
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY, 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fmt.Fprintln(f, "#!/bin/sh\nexec sleep 60")
+	t.Cleanup(func() { f.Close() })
+	return path, f
+}
+
+func TestExecRetryDoesNotBlockOtherStarts(t *testing.T) {
+	set, _ := newTestSet()
+	path, f := busyScript(t)
+
+	busy := NewProcessService(set, "busy")
+	busy.SetCommand([]string{path})
+	set.AddService(busy)
+	other := NewProcessService(set, "other")
+	other.SetCommand([]string{"/bin/sleep", "60"})
+	set.AddService(other)
+
+	// The first exec fails; the retry is left to a timer, so the start
+	// returns at once with the service still STARTING.
+	set.StartService(busy)
+	if busy.State() != StateStarting {
+		t.Fatalf("busy: state = %v, want STARTING", busy.State())
+	}
+
+	set.StartService(other)
+	waitForState(t, other, StateStarted)
+	if busy.State() != StateStarting {
+		t.Fatalf("busy: state = %v while retrying, want STARTING", busy.State())
+	}
+
+	f.Close()
+	waitForState(t, busy, StateStarted)
+
+	set.StopService(busy)
+	set.StopService(other)
+	waitForState(t, busy, StateStopped)
+	waitForState(t, other, StateStopped)
+}
+
+func TestExecRetryDroppedOnStop(t *testing.T) {
+	set, _ := newTestSet()
+	path, _ := busyScript(t)
+
+	svc := NewProcessService(set, "busy")
+	svc.SetCommand([]string{path})
+	set.AddService(svc)
+
+	set.StartService(svc)
+	if svc.State() != StateStarting {
+		t.Fatalf("state = %v, want STARTING", svc.State())
+	}
+
+	// Stopping while a retry is pending cancels it rather than waiting
+	// for the start to finish.
+	set.StopService(svc)
+	waitForState(t, svc, StateStopped)
+	set.queueMu.Lock()
+	pending := svc.Record().execRetryTimer != nil
+	set.queueMu.Unlock()
+	if pending {
+		t.Error("exec retry still pending after stop")
+	}
+}
+
+func TestExecRetryExhaustedWarns(t *testing.T) {
+	set, logger := newTestSet()
+	path, _ := busyScript(t)
+
+	svc := NewProcessService(set, "busy")
+	svc.SetCommand([]string{path})
+	set.AddService(svc)
+
+	// The script stays busy, so the ETXTBSY retries run out and the start
+	// fails with a single warning (reported through Error by testLogger).
+	set.StartService(svc)
+	waitForState(t, svc, StateStopped)
+
+	set.queueMu.Lock()
+	defer set.queueMu.Unlock()
+	warned := 0
+	for _, e := range logger.errors {
+		if strings.Contains(e, "giving up after") {
+			warned++
+		}
+	}
+	if warned != 1 {
+		t.Errorf("got %d give-up warnings, want 1 (errors: %q)", warned, logger.errors)
+	}
+}
@@ -0,0 +1,81 @@
+package service
+
+import "time"
+
+// FailureStats holds cumulative start/failure counters for a service
+// over the lifetime of the slinit process, for SLO-style reliability
+// reporting. They survive stop/start and reload but not a daemon restart.
+type FailureStats struct {
+	TotalStarts         int64     // start attempts (initiateStart)
+	TotalFailures       int64     // failed starts (failedToStart)
+	TotalRestarts       int64     // automatic restarts after the service stopped
+	LastFailureTime     time.Time // zero if the service never failed
+	ConsecutiveFailures int       // failed starts since the last successful one
+}
+
+// SuccessRate returns the fraction of start attempts that did not fail,
+// or 1 when the service was never started.
+func (fs FailureStats) SuccessRate() float64 {
+	if fs.TotalStarts <= 0 {
+		return 1
+	}
+	ok := fs.TotalStarts - fs.TotalFailures
+	if ok < 0 {
+		ok = 0
+	}
+	return float64(ok) / float64(fs.TotalStarts)
+}
+
+// FailureStats returns a snapshot of the service's failure counters.
+func (sr *ServiceRecord) FailureStats() FailureStats { return sr.failureStats }
+
+// SetConsecutiveFailureThreshold sets failure-action-threshold: after n
+// consecutive failed starts the service is force-stopped and auto-restart
+// is suppressed until ResetFailed. Zero disables the check.
+func (sr *ServiceRecord) SetConsecutiveFailureThreshold(n int) {
+	sr.consecutiveFailureThreshold = n
+}
+
+// ConsecutiveFailureThreshold returns the failure-action-threshold.
+func (sr *ServiceRecord) ConsecutiveFailureThreshold() int {
+	return sr.consecutiveFailureThreshold
+}
+
+// RestartSuppressed reports whether failure-action-threshold has tripped
+// and auto-restart is disabled.
+func (sr *ServiceRecord) RestartSuppressed() bool { return sr.restartSuppressed }
+
+// autoRestartDisabled reports whether `slinitctl no-restart` or
+// `slinitctl kill` is in effect.
+func (sr *ServiceRecord) autoRestartDisabled() bool {
+	return sr.killed || (sr.autoRestartOverride != nil && *sr.autoRestartOverride == RestartNever)
+}
+
+// canAutoRestart gates restarts slinit decides on by itself (smooth
+// recovery, watchdog, health checks): false once the failure threshold
+// has tripped, otherwise the service's own rate limiting decides.
+func (sr *ServiceRecord) canAutoRestart() bool {
+	if sr.restartSuppressed || sr.autoRestartDisabled() {
+		return false
+	}
+	return sr.self.CheckRestart()
+}
+
+// noteStartFailure records a failed start and trips
+// failure-action-threshold once enough failures happen in a row.
+func (sr *ServiceRecord) noteStartFailure() {
+	fs := &sr.failureStats
+	fs.TotalFailures++
+	fs.ConsecutiveFailures++
+	fs.LastFailureTime = time.Now()
+
+	if sr.consecutiveFailureThreshold <= 0 || sr.restartSuppressed ||
+		fs.ConsecutiveFailures < sr.consecutiveFailureThreshold {
+		return
+	}
+	sr.restartSuppressed = true
+	sr.services.logger.Error(
+		"Service '%s': %d consecutive start failures (failure-action-threshold), auto-restart disabled until reset-failed",
+		sr.serviceName, fs.ConsecutiveFailures)
+	sr.ForcedStop()
+}
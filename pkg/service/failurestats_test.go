@@ -0,0 +1,77 @@
+package service
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFailureStatsCounting(t *testing.T) {
+	set, _ := newTestSet()
+
+	// No command: BringUp fails and the start is recorded as a failure.
+	bad := NewProcessService(set, "bad")
+	set.AddService(bad)
+	set.StartService(bad)
+	set.StartService(bad)
+
+	fs := bad.FailureStats()
+	if fs.TotalStarts != 2 || fs.TotalFailures != 2 || fs.ConsecutiveFailures != 2 {
+		t.Errorf("after two failed starts: %+v", fs)
+	}
+	if fs.LastFailureTime.IsZero() || time.Since(fs.LastFailureTime) > time.Minute {
+		t.Errorf("LastFailureTime = %v", fs.LastFailureTime)
+	}
+	if bad.RestartSuppressed() {
+		t.Error("no threshold configured, restart must not be suppressed")
+	}
+
+	good := NewInternalService(set, "good")
+	set.AddService(good)
+	set.StartService(good)
+	set.StopService(good)
+	set.StartService(good)
+	fs = good.FailureStats()
+	if fs.TotalStarts != 2 || fs.TotalFailures != 0 || fs.ConsecutiveFailures != 0 {
+		t.Errorf("internal service: %+v", fs)
+	}
+	if r := fs.SuccessRate(); r != 1 {
+		t.Errorf("SuccessRate() = %v, want 1", r)
+	}
+}
+
+func TestFailureThresholdSuppressesRestart(t *testing.T) {
+	set, _ := newTestSet()
+	svc := NewProcessService(set, "flaky")
+	svc.SetConsecutiveFailureThreshold(3)
+	set.AddService(svc)
+
+	for i := 0; i < 2; i++ {
+		set.StartService(svc)
+	}
+	if svc.RestartSuppressed() {
+		t.Fatal("suppressed before reaching the threshold")
+	}
+	set.StartService(svc)
+	if !svc.RestartSuppressed() {
+		t.Fatal("expected auto-restart suppressed after 3 consecutive failures")
+	}
+	if svc.canAutoRestart() {
+		t.Error("canAutoRestart() must be false while suppressed")
+	}
+
+	svc.ResetFailed()
+	if svc.RestartSuppressed() || svc.FailureStats().ConsecutiveFailures != 0 {
+		t.Errorf("ResetFailed did not re-enable restart: suppressed=%v stats=%+v",
+			svc.RestartSuppressed(), svc.FailureStats())
+	}
+	if svc.FailureStats().TotalFailures != 3 {
+		t.Errorf("ResetFailed must keep cumulative counters, got %+v", svc.FailureStats())
+	}
+}
+
+func TestFailureStatsSuccessRate(t *testing.T) {
+	fs := FailureStats{TotalStarts: 45, TotalFailures: 3}
+	if r := fs.SuccessRate(); r < 0.933 || r > 0.934 {
+		t.Errorf("SuccessRate() = %v, want ~0.933", r)
+	}
+}
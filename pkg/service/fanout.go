@@ -0,0 +1,92 @@
+package service
+
+// FanoutService starts a set of member services and counts as STARTED
+// as soon as it has asked for them, without waiting for any of them to
+// come up. That is the difference from a bundle or a barrier, which
+// hold their dependents back until the members are started. A typical
+// use is boot depending on a boot-fanout of the services wanted at
+// boot: boot is ready at once while they keep starting in the
+// background. Members are held like dependencies, so stopping the
+// fanout releases them and a member nothing else needs stops with it.
+type FanoutService struct {
+	ServiceRecord
+	members []Service
+	held    []Service // members acquired by the current start
+}
+
+// NewFanoutService creates a new fanout service.
+func NewFanoutService(set *ServiceSet, name string) *FanoutService {
+	svc := &FanoutService{}
+	svc.ServiceRecord = *NewServiceRecord(svc, set, name, TypeFanout)
+	return svc
+}
+
+// SetMembers sets the services the fanout starts. A started fanout
+// keeps holding the members of its current start until it stops.
+func (s *FanoutService) SetMembers(members []Service) { s.members = members }
+
+// Members returns the services the fanout starts.
+func (s *FanoutService) Members() []Service { return s.members }
+
+// BringUp asks every member to start and marks the fanout started
+// without waiting for them.
+func (s *FanoutService) BringUp() bool {
+	if len(s.held) == 0 {
+		for _, m := range s.members {
+			m.Record().Require()
+			s.held = append(s.held, m)
+		}
+	}
+	s.Started()
+	return true
+}
+
+// BringDown releases the members and stops the fanout immediately.
+func (s *FanoutService) BringDown() {
+	s.releaseMembers()
+	s.Stopped()
+}
+
+// BecomingInactive releases members still held when the fanout will
+// not be started again.
+func (s *FanoutService) BecomingInactive() {
+	s.releaseMembers()
+}
+
+// releaseMembers drops the fanout's hold on the members it started,
+// stopping those nothing else requires.
+func (s *FanoutService) releaseMembers() {
+	held := s.held
+	s.held = nil
+	for _, m := range held {
+		m.Record().Release(true)
+	}
+}
+
+// CanInterruptStart returns true since a fanout starts instantly.
+func (s *FanoutService) CanInterruptStart() bool {
+	return true
+}
+
+// InterruptStart cancels the start immediately.
+func (s *FanoutService) InterruptStart() bool {
+	return true
+}
+
+// fanoutSnapshot holds the FanoutService fields a reload may change.
+type fanoutSnapshot struct {
+	members []Service
+}
+
+// SaveSnapshot copies the configuration a reload may change.
+func (s *FanoutService) SaveSnapshot() ServiceSnapshot {
+	return &fanoutSnapshot{members: s.members}
+}
+
+// RestoreSnapshot puts back configuration saved by SaveSnapshot.
+// Snapshots of another service type are ignored.
+func (s *FanoutService) RestoreSnapshot(snap ServiceSnapshot) {
+	if c, ok := snap.(*fanoutSnapshot); ok {
+		s.members = c.members
+	}
+}
@@ -0,0 +1,73 @@
+package service
+
+import (
+	"testing"
+)
+
+func TestFanoutStartsWithoutWaiting(t *testing.T) {
+	set, _ := newTestSet()
+
+	sshd := NewTriggeredService(set, "sshd")
+	crond := NewInternalService(set, "crond")
+	fanout := NewFanoutService(set, "boot-fanout")
+	boot := NewInternalService(set, "boot")
+	for _, s := range []Service{sshd, crond, fanout, boot} {
+		set.AddService(s)
+	}
+	fanout.SetMembers([]Service{sshd, crond})
+	boot.Record().AddDep(fanout, DepRegular)
+
+	set.StartService(boot)
+	if fanout.State() != StateStarted || boot.State() != StateStarted {
+		t.Fatalf("expected fanout and boot STARTED, got %v / %v", fanout.State(), boot.State())
+	}
+	if crond.State() != StateStarted {
+		t.Errorf("expected crond STARTED, got %v", crond.State())
+	}
+	// sshd is still starting in the background.
+	if sshd.State() != StateStarting {
+		t.Fatalf("expected sshd STARTING, got %v", sshd.State())
+	}
+
+	sshd.SetTrigger(true)
+	set.ProcessQueues()
+	if sshd.State() != StateStarted {
+		t.Errorf("expected sshd STARTED, got %v", sshd.State())
+	}
+}
+
+func TestFanoutStopReleasesMembers(t *testing.T) {
+	set, _ := newTestSet()
+
+	a := NewInternalService(set, "a")
+	b := NewInternalService(set, "b")
+	fanout := NewFanoutService(set, "fanout")
+	for _, s := range []Service{a, b, fanout} {
+		set.AddService(s)
+	}
+	fanout.SetMembers([]Service{a, b})
+
+	// b is also started explicitly, so it outlives the fanout.
+	set.StartService(b)
+	set.StartService(fanout)
+	if a.State() != StateStarted || b.State() != StateStarted {
+		t.Fatalf("expected members STARTED, got %v / %v", a.State(), b.State())
+	}
+
+	set.StopService(fanout)
+	if fanout.State() != StateStopped {
+		t.Fatalf("expected fanout STOPPED, got %v", fanout.State())
+	}
+	if a.State() != StateStopped {
+		t.Errorf("expected a STOPPED with the fanout, got %v", a.State())
+	}
+	if b.State() != StateStarted {
+		t.Errorf("expected explicitly started b to stay STARTED, got %v", b.State())
+	}
+
+	// A second start acquires the members again.
+	set.StartService(fanout)
+	if a.State() != StateStarted {
+		t.Errorf("expected a STARTED after restarting the fanout, got %v", a.State())
+	}
+}
@@ -0,0 +1,65 @@
+package service
+
+import (
+	"encoding/json"
+	"io"
+	"sort"
+)
+
+// Graph returns the dependency graph of every loaded service: nodes
+// sorted by name, and edges sorted by dependent then dependency.
+func (ss *ServiceSet) Graph() ([]GraphNode, []GraphEdge) {
+	var nodes []GraphNode
+	var edges []GraphEdge
+	for _, svc := range ss.ListServices() {
+		nodes = append(nodes, GraphNode{Name: svc.Name(), Type: svc.Type(), State: svc.State()})
+		for _, dep := range svc.Record().Dependencies() {
+			edges = append(edges, GraphEdge{From: svc.Name(), To: dep.To.Name(), Type: dep.DepType})
+		}
+	}
+	sort.Slice(nodes, func(i, j int) bool { return nodes[i].Name < nodes[j].Name })
+	sort.SliceStable(edges, func(i, j int) bool {
+		if edges[i].From != edges[j].From {
+			return edges[i].From < edges[j].From
+		}
+		return edges[i].To < edges[j].To
+	})
+	return nodes, edges
+}
+
+// graphJSON is the JSON form of a dependency graph.
+type graphJSON struct {
+	Nodes []graphNodeJSON `json:"nodes"`
+	Edges []graphEdgeJSON `json:"edges"`
+}
+
+type graphNodeJSON struct {
+	Name  string `json:"name"`
+	Type  string `json:"type"`
+	State string `json:"state"`
+}
+
+type graphEdgeJSON struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+	Type string `json:"type"`
+}
+
+// WriteGraphJSON renders nodes and edges as one JSON object with a
+// "nodes" and an "edges" array, for tools that lay out or analyse the
+// graph themselves.
+func WriteGraphJSON(w io.Writer, nodes []GraphNode, edges []GraphEdge) error {
+	out := graphJSON{
+		Nodes: make([]graphNodeJSON, 0, len(nodes)),
+		Edges: make([]graphEdgeJSON, 0, len(edges)),
+	}
+	for _, n := range nodes {
+		out.Nodes = append(out.Nodes, graphNodeJSON{Name: n.Name, Type: n.Type.String(), State: n.State.String()})
+	}
+	for _, e := range edges {
+		out.Edges = append(out.Edges, graphEdgeJSON{From: e.From, To: e.To, Type: e.Type.String()})
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(out)
+}
@@ -0,0 +1,67 @@
+package service
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+)
+
+func TestGraph(t *testing.T) {
+	set, _ := newTestSet()
+	web := NewInternalService(set, "web")
+	db := NewInternalService(set, "db")
+	net := NewInternalService(set, "net")
+	web.Record().AddDep(net, DepWaitsFor)
+	web.Record().AddDep(db, DepRegular)
+	db.Record().AddDep(net, DepRegular)
+	for _, s := range []Service{web, db, net} {
+		set.AddService(s)
+	}
+	set.StartService(db)
+
+	nodes, edges := set.Graph()
+	if len(nodes) != 3 || nodes[0].Name != "db" || nodes[1].Name != "net" || nodes[2].Name != "web" {
+		t.Fatalf("nodes = %+v", nodes)
+	}
+	if nodes[0].State != StateStarted || nodes[2].State != StateStopped {
+		t.Errorf("states = %v %v", nodes[0].State, nodes[2].State)
+	}
+	want := []GraphEdge{
+		{From: "db", To: "net", Type: DepRegular},
+		{From: "web", To: "db", Type: DepRegular},
+		{From: "web", To: "net", Type: DepWaitsFor},
+	}
+	if len(edges) != len(want) {
+		t.Fatalf("edges = %+v", edges)
+	}
+	for i := range want {
+		if edges[i] != want[i] {
+			t.Errorf("edge %d = %+v, want %+v", i, edges[i], want[i])
+		}
+	}
+}
+
+func TestWriteGraphJSON(t *testing.T) {
+	var buf bytes.Buffer
+	err := WriteGraphJSON(&buf,
+		[]GraphNode{{Name: "a", Type: TypeProcess, State: StateStarted}, {Name: "b", Type: TypeInternal}},
+		[]GraphEdge{{From: "a", To: "b", Type: DepSoft}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got struct {
+		Nodes []map[string]string `json:"nodes"`
+		Edges []map[string]string `json:"edges"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("invalid JSON: %v\n%s", err, buf.String())
+	}
+	if len(got.Nodes) != 2 || got.Nodes[0]["name"] != "a" || got.Nodes[0]["type"] != TypeProcess.String() ||
+		got.Nodes[0]["state"] != StateStarted.String() {
+		t.Errorf("nodes = %v", got.Nodes)
+	}
+	if len(got.Edges) != 1 || got.Edges[0]["from"] != "a" || got.Edges[0]["to"] != "b" ||
+		got.Edges[0]["type"] != DepSoft.String() {
+		t.Errorf("edges = %v", got.Edges)
+	}
+}
@@ -5,7 +5,10 @@ import (
 	"fmt"
 	"os/exec"
 	"sync"
+	"syscall"
 	"time"
+
+	"github.com/sunlightlinux/slinit/pkg/process"
 )
 
 // HealthChecker runs periodic health checks on a STARTED service.
@@ -23,14 +26,36 @@ type HealthChecker struct {
 	maxFailures  int           // consecutive failures before restart (0 = never restart)
 	unhealthyCmd []string      // command to run on each failure
 
+	// Execution context inherited from the service (see SetExecContext).
+	workingDir string
+	runAsUID   uint32
+	runAsGID   uint32
+	suppGIDs   []uint32
+
 	svc    Service
 	logger ServiceLogger
 	onFail func() // called when maxFailures reached (triggers service restart)
 
-	mu       sync.Mutex
-	failures int // consecutive failure count
-	stopCh   chan struct{}
-	doneCh   chan struct{}
+	mu        sync.Mutex
+	failures  int       // consecutive failure count
+	lastCheck time.Time // completion time of the most recent check
+	lastOK    bool      // result of the most recent check
+	stopCh    chan struct{}
+	doneCh    chan struct{}
+}
+
+// HealthStatus is a snapshot of a health checker's state.
+type HealthStatus struct {
+	LastCheck   time.Time // zero until the first check has completed
+	Healthy     bool      // result of the most recent check
+	Failures    int       // consecutive failures so far
+	MaxFailures int       // failures before the service is stopped; 0 = never
+}
+
+// HealthReporter is implemented by service types that support
+// healthcheck-command. ok is false when no health check is configured.
+type HealthReporter interface {
+	HealthStatus() (status HealthStatus, ok bool)
 }
 
 // NewHealthChecker creates a new health checker.
@@ -54,10 +79,32 @@ func NewHealthChecker(svc Service, cmd []string, interval, delay time.Duration,
 	}
 }
 
+// SetExecContext makes health check commands run in dir with the given
+// credentials, matching the service's own working-dir and run-as. A zero
+// uid and gid leave the credentials of slinit itself in place.
+func (hc *HealthChecker) SetExecContext(dir string, uid, gid uint32, suppGIDs []uint32) {
+	hc.workingDir = dir
+	hc.runAsUID = uid
+	hc.runAsGID = gid
+	hc.suppGIDs = suppGIDs
+}
+
+// Status returns a snapshot of the checker's state.
+func (hc *HealthChecker) Status() HealthStatus {
+	hc.mu.Lock()
+	defer hc.mu.Unlock()
+	return HealthStatus{
+		LastCheck:   hc.lastCheck,
+		Healthy:     hc.lastOK,
+		Failures:    hc.failures,
+		MaxFailures: hc.maxFailures,
+	}
+}
+
 // Start launches the periodic health check goroutine.
 func (hc *HealthChecker) Start() {
 	hc.mu.Lock()
-	if hc.stopCh != nil {
+	if hc.runningLocked() {
 		hc.mu.Unlock()
 		return
 	}
@@ -69,6 +116,24 @@ func (hc *HealthChecker) Start() {
 	go hc.loop()
 }
 
+// runningLocked reports whether a check loop is active: started, not
+// asked to stop, and not exited on its own after reaching maxFailures.
+// A checker is started again on every start of its service, so a
+// finished loop must not block the next Start. Caller holds hc.mu.
+func (hc *HealthChecker) runningLocked() bool {
+	if hc.stopCh == nil {
+		return false
+	}
+	select {
+	case <-hc.stopCh:
+		return false
+	case <-hc.doneCh:
+		return false
+	default:
+		return true
+	}
+}
+
 // Stop signals the health check loop to exit and waits for completion.
 func (hc *HealthChecker) Stop() {
 	hc.mu.Lock()
@@ -141,11 +206,23 @@ func (hc *HealthChecker) checkOnce() bool {
 	defer cancel()
 
 	cmd := exec.CommandContext(ctx, hc.command[0], hc.command[1:]...)
-	err := cmd.Run()
+	cmd.Dir = hc.workingDir
+	if hc.runAsUID != 0 || hc.runAsGID != 0 {
+		cmd.SysProcAttr = &syscall.SysProcAttr{
+			Credential: &syscall.Credential{
+				Uid:    hc.runAsUID,
+				Gid:    hc.runAsGID,
+				Groups: hc.suppGIDs,
+			},
+		}
+	}
+	err := process.Run(cmd)
 
 	if err == nil {
 		// Healthy — reset failure counter
 		hc.mu.Lock()
+		hc.lastCheck = time.Now()
+		hc.lastOK = true
 		if hc.failures > 0 {
 			hc.logger.Info("Service '%s': health check passed (recovered after %d failures)",
 				hc.svc.Name(), hc.failures)
@@ -157,6 +234,8 @@ func (hc *HealthChecker) checkOnce() bool {
 
 	// Unhealthy
 	hc.mu.Lock()
+	hc.lastCheck = time.Now()
+	hc.lastOK = false
 	hc.failures++
 	failures := hc.failures
 	hc.mu.Unlock()
@@ -197,7 +276,23 @@ func (hc *HealthChecker) runUnhealthyCmd() {
 	defer cancel()
 
 	cmd := exec.CommandContext(ctx, hc.unhealthyCmd[0], hc.unhealthyCmd[1:]...)
-	if err := cmd.Run(); err != nil {
+	if err := process.Run(cmd); err != nil {
 		hc.logger.Info("Service '%s': unhealthy-command failed: %v", hc.svc.Name(), err)
 	}
 }
+
+// failHealthCheck stops a STARTED service whose health check reached its
+// failure threshold. With restart = yes or on-failure (and the restart
+// rate limit permitting) the service is restarted; otherwise it stays
+// down. Either way the stop is recorded as a failure. Caller holds queueMu.
+func (sr *ServiceRecord) failHealthCheck() {
+	if sr.state.Load() != StateStarted {
+		return
+	}
+	if sr.GetEffectiveAutoRestart() != RestartNever && sr.desired.Load() == StateStarted && sr.canAutoRestart() {
+		sr.Restart()
+	} else {
+		sr.Stop(true)
+	}
+	sr.stopReason = ReasonFailed
+}
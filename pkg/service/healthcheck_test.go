@@ -142,3 +142,108 @@ func TestHealthChecker_DoubleStartStop(t *testing.T) {
 	hc.Stop()
 	hc.Stop()
 }
+
+func TestHealthChecker_RestartAfterStop(t *testing.T) {
+	set, _ := newTestSet()
+	svc := NewInternalService(set, "hc-again")
+
+	marker := filepath.Join(t.TempDir(), "ran")
+	hc := NewHealthChecker(svc, []string{"/bin/sh", "-c", "touch " + marker},
+		20*time.Millisecond, 0, 0, nil, set.logger, nil)
+
+	hc.Start()
+	hc.Stop()
+	os.Remove(marker)
+
+	// A service start after a stop must bring the checker back.
+	hc.Start()
+	time.Sleep(100 * time.Millisecond)
+	hc.Stop()
+
+	if _, err := os.Stat(marker); err != nil {
+		t.Errorf("health check did not run after restart: %v", err)
+	}
+}
+
+func TestScriptedHealthCheckFailureStopsService(t *testing.T) {
+	set, _ := newTestSet()
+
+	svc := NewScriptedService(set, "hc-scripted")
+	svc.SetHealthCheck([]string{"/bin/sh", "-c", "exit 1"},
+		20*time.Millisecond, 0, 2, nil)
+	set.AddService(svc)
+
+	set.StartService(svc)
+	if svc.State() != StateStarted {
+		t.Fatalf("expected STARTED, got %v", svc.State())
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for svc.State() != StateStopped && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if svc.State() != StateStopped {
+		t.Fatalf("expected STOPPED after failing health check, got %v", svc.State())
+	}
+
+	set.queueMu.Lock()
+	reason := svc.Record().StopReason()
+	set.queueMu.Unlock()
+	if reason != ReasonFailed {
+		t.Errorf("expected stop reason %v, got %v", ReasonFailed, reason)
+	}
+
+	st, ok := svc.HealthStatus()
+	if !ok {
+		t.Fatal("HealthStatus should report a configured check")
+	}
+	if st.Healthy || st.Failures < 2 || st.LastCheck.IsZero() {
+		t.Errorf("unexpected health status %+v", st)
+	}
+}
+
+func TestScriptedHealthCheckRestartsOnFailure(t *testing.T) {
+	set, logger := newTestSet()
+
+	svc := NewScriptedService(set, "hc-scripted-restart")
+	svc.Record().SetAutoRestart(RestartOnFailure)
+	// Fail only the first time so the restarted service stays up.
+	marker := filepath.Join(t.TempDir(), "failed")
+	svc.SetHealthCheck([]string{"/bin/sh", "-c",
+		"if [ -f " + marker + " ]; then exit 0; else touch " + marker + "; exit 1; fi"},
+		20*time.Millisecond, 0, 1, nil)
+	set.AddService(svc)
+
+	set.StartService(svc)
+
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		set.queueMu.Lock()
+		starts := 0
+		for _, n := range logger.started {
+			if n == "hc-scripted-restart" {
+				starts++
+			}
+		}
+		set.queueMu.Unlock()
+		if starts >= 2 {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if svc.State() != StateStarted {
+		t.Fatalf("expected service restarted to STARTED, got %v", svc.State())
+	}
+	set.queueMu.Lock()
+	starts := 0
+	for _, n := range logger.started {
+		if n == "hc-scripted-restart" {
+			starts++
+		}
+	}
+	set.queueMu.Unlock()
+	if starts < 2 {
+		t.Errorf("expected a restart after the failed check, got %d starts", starts)
+	}
+	svc.healthChecker.Stop()
+}
@@ -19,3 +19,14 @@ func jitter(max time.Duration) time.Duration {
 	}
 	return time.Duration(rand.Int64N(int64(max)))
 }
+
+// scaleJitter multiplies d by a random factor in [1, 1+factor). Used for
+// the global --restart-jitter-factor, which spreads out services whose
+// restart-delay values are identical. A non-positive factor or delay
+// returns d unchanged.
+func scaleJitter(d time.Duration, factor float64) time.Duration {
+	if factor <= 0 || d <= 0 {
+		return d
+	}
+	return d + time.Duration(rand.Float64()*factor*float64(d))
+}
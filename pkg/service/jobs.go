@@ -0,0 +1,196 @@
+package service
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// maxFinishedJobs bounds how many settled jobs are kept for queries
+// after they finish; running jobs are always kept.
+const maxFinishedJobs = 64
+
+// JobKind is the transition a job asked for.
+type JobKind uint8
+
+const (
+	JobStart JobKind = iota
+	JobStop
+)
+
+func (k JobKind) String() string {
+	if k == JobStop {
+		return "stop"
+	}
+	return "start"
+}
+
+// JobState is how far a job has got.
+type JobState uint8
+
+const (
+	JobRunning   JobState = iota // the transition is under way
+	JobDone                      // the service reached the state asked for
+	JobFailed                    // the service failed to start
+	JobCancelled                 // withdrawn by CancelJob, or overtaken by an opposite request
+)
+
+func (s JobState) String() string {
+	switch s {
+	case JobDone:
+		return "done"
+	case JobFailed:
+		return "failed"
+	case JobCancelled:
+		return "cancelled"
+	}
+	return "running"
+}
+
+// Job tracks a start or stop requested through the control socket
+// until the service settles, so a client that did not wait for the
+// transition can follow it, or cancel it, by ID.
+type Job struct {
+	ID       uint32
+	Kind     JobKind
+	Service  string
+	State    JobState
+	Created  time.Time
+	Finished time.Time // zero while running
+}
+
+// jobTable holds the jobs of a set. It is a global event listener:
+// the service events settle the running jobs.
+type jobTable struct {
+	mu     sync.Mutex
+	nextID uint32
+	jobs   map[uint32]*Job
+}
+
+// GlobalServiceEvent implements GlobalEventListener.
+func (t *jobTable) GlobalServiceEvent(ev GlobalEvent) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, j := range t.jobs {
+		if j.State != JobRunning || j.Service != ev.Service {
+			continue
+		}
+		switch {
+		case j.Kind == JobStart && ev.Event == EventStarted,
+			j.Kind == JobStop && ev.Event == EventStopped:
+			t.finish(j, JobDone, ev.Time)
+		case j.Kind == JobStart && ev.Event == EventFailedStart:
+			t.finish(j, JobFailed, ev.Time)
+		case j.Kind == JobStart && ev.Event == EventStartCancelled,
+			j.Kind == JobStop && ev.Event == EventStopCancelled:
+			t.finish(j, JobCancelled, ev.Time)
+		}
+	}
+}
+
+// finish settles j and drops the oldest settled jobs beyond
+// maxFinishedJobs. Called with t.mu held.
+func (t *jobTable) finish(j *Job, state JobState, at time.Time) {
+	j.State = state
+	j.Finished = at
+	var done []*Job
+	for _, o := range t.jobs {
+		if o.State != JobRunning {
+			done = append(done, o)
+		}
+	}
+	if len(done) <= maxFinishedJobs {
+		return
+	}
+	sort.Slice(done, func(a, b int) bool { return done[a].ID < done[b].ID })
+	for _, o := range done[:len(done)-maxFinishedJobs] {
+		delete(t.jobs, o.ID)
+	}
+}
+
+// NewJob records a start or stop of svc requested by a client and
+// returns its ID. Call it before acting on the request, so the events
+// of the transition find the job. A running job of the same kind for
+// svc is reused: two clients starting one service follow one job.
+func (ss *ServiceSet) NewJob(svc Service, kind JobKind) uint32 {
+	t := ss.jobs
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, j := range t.jobs {
+		if j.State == JobRunning && j.Kind == kind && j.Service == svc.Name() {
+			return j.ID
+		}
+	}
+	t.nextID++
+	if t.nextID == 0 {
+		t.nextID = 1
+	}
+	t.jobs[t.nextID] = &Job{ID: t.nextID, Kind: kind, Service: svc.Name(), Created: time.Now()}
+	return t.nextID
+}
+
+// JobByID returns a copy of the job with the given ID.
+func (ss *ServiceSet) JobByID(id uint32) (Job, bool) {
+	t := ss.jobs
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	j, ok := t.jobs[id]
+	if !ok {
+		return Job{}, false
+	}
+	return *j, true
+}
+
+// Jobs returns copies of the running jobs and the recently settled
+// ones, by ID.
+func (ss *ServiceSet) Jobs() []Job {
+	t := ss.jobs
+	t.mu.Lock()
+	out := make([]Job, 0, len(t.jobs))
+	for _, j := range t.jobs {
+		out = append(out, *j)
+	}
+	t.mu.Unlock()
+	sort.Slice(out, func(a, b int) bool { return out[a].ID < out[b].ID })
+	return out
+}
+
+// CancelJob withdraws a running job. Cancelling a start undoes the
+// explicit activation the start added, as a release does: the service
+// stops, unless other active services still need it. Cancelling a stop
+// starts the service again, as slinitctl start would.
+func (ss *ServiceSet) CancelJob(id uint32) error {
+	ss.queueMu.Lock()
+	defer ss.queueMu.Unlock()
+
+	t := ss.jobs
+	t.mu.Lock()
+	j, ok := t.jobs[id]
+	if !ok {
+		t.mu.Unlock()
+		return fmt.Errorf("no job %d", id)
+	}
+	if j.State != JobRunning {
+		state := j.State
+		t.mu.Unlock()
+		return fmt.Errorf("job %d is already %s", id, state)
+	}
+	t.finish(j, JobCancelled, time.Now())
+	kind, name := j.Kind, j.Service
+	t.mu.Unlock()
+
+	// The events of the reversal run with t.mu free; they find the job
+	// settled and leave it alone.
+	svc := ss.FindService(name, false)
+	if svc == nil {
+		return nil
+	}
+	if kind == JobStart {
+		svc.Stop(false)
+	} else if !svc.Record().IsStopPinned() {
+		svc.Start()
+	}
+	ss.processQueuesLocked()
+	return nil
+}
@@ -0,0 +1,82 @@
+package service
+
+import "testing"
+
+func TestJobSettles(t *testing.T) {
+	set, _ := newTestSet()
+	svc := NewTriggeredService(set, "net")
+	set.AddService(svc)
+
+	id := set.NewJob(svc, JobStart)
+	set.StartService(svc)
+	if j, _ := set.JobByID(id); j.State != JobRunning {
+		t.Fatalf("job state %v while the service is starting, want running", j.State)
+	}
+	if again := set.NewJob(svc, JobStart); again != id {
+		t.Errorf("second start got job %d, want the running job %d", again, id)
+	}
+
+	svc.SetTrigger(true)
+	set.ProcessQueues()
+	j, ok := set.JobByID(id)
+	if !ok || j.State != JobDone || j.Finished.IsZero() {
+		t.Fatalf("job = %+v, want done with a finish time", j)
+	}
+
+	stop := set.NewJob(svc, JobStop)
+	if stop == id {
+		t.Fatal("stop reused the settled start job")
+	}
+	set.StopService(svc)
+	if j, _ := set.JobByID(stop); j.State != JobDone || j.Kind != JobStop {
+		t.Errorf("stop job = %+v, want a done stop", j)
+	}
+	if jobs := set.Jobs(); len(jobs) != 2 || jobs[0].ID != id || jobs[1].ID != stop {
+		t.Errorf("Jobs() = %+v, want the start and stop jobs in order", jobs)
+	}
+}
+
+func TestCancelJob(t *testing.T) {
+	set, _ := newTestSet()
+	svc := NewTriggeredService(set, "net")
+	set.AddService(svc)
+
+	id := set.NewJob(svc, JobStart)
+	set.StartService(svc)
+	if err := set.CancelJob(id); err != nil {
+		t.Fatal(err)
+	}
+	if svc.State() != StateStopped {
+		t.Errorf("state %v after cancelling the start, want STOPPED", svc.State())
+	}
+	if j, _ := set.JobByID(id); j.State != JobCancelled {
+		t.Errorf("job state %v, want cancelled", j.State)
+	}
+	if err := set.CancelJob(id); err == nil || err.Error() != "job 1 is already cancelled" {
+		t.Errorf("second cancel: %v", err)
+	}
+	if err := set.CancelJob(99); err == nil {
+		t.Error("cancelling an unknown job succeeded")
+	}
+}
+
+func TestJobPruning(t *testing.T) {
+	set, _ := newTestSet()
+	svc := NewInternalService(set, "svc")
+	set.AddService(svc)
+
+	first := set.NewJob(svc, JobStart)
+	set.StartService(svc)
+	for range maxFinishedJobs {
+		set.NewJob(svc, JobStop)
+		set.StopService(svc)
+		set.NewJob(svc, JobStart)
+		set.StartService(svc)
+	}
+	if _, ok := set.JobByID(first); ok {
+		t.Error("oldest settled job was kept")
+	}
+	if n := len(set.Jobs()); n != maxFinishedJobs {
+		t.Errorf("%d jobs kept, want %d", n, maxFinishedJobs)
+	}
+}
@@ -3,6 +3,8 @@ package service
 import (
 	"os"
 	"sync"
+
+	"github.com/sunlightlinux/slinit/pkg/process"
 )
 
 const defaultLogBufMax = 8192
@@ -172,3 +174,19 @@ func (lb *LogBuffer) Close() {
 		<-doneCh
 	}
 }
+
+// bufferTee returns the MultiOutput that mirrors a log buffer's pipe
+// into logFile (log-type = buffer combined with a logfile), or nil if
+// there is no pipe or no logfile.
+func bufferTee(pipe *os.File, logFile string, perms, uid, gid int) *process.MultiOutput {
+	if pipe == nil || logFile == "" {
+		return nil
+	}
+	return &process.MultiOutput{
+		PrimaryPipe:    pipe,
+		SecondaryFile:  logFile,
+		SecondaryPerms: os.FileMode(perms),
+		SecondaryUID:   uid,
+		SecondaryGID:   gid,
+	}
+}
@@ -3,6 +3,7 @@ package service
 import (
 	"bytes"
 	"os"
+	"path/filepath"
 	"sync"
 	"testing"
 	"time"
@@ -195,3 +196,29 @@ func TestLogBuffer_OutputPipeIntegration(t *testing.T) {
 		t.Errorf("buffer = %q, want %q", got, "child output\n")
 	}
 }
+
+func TestLogBuffer_TeeToLogfile(t *testing.T) {
+	set, _ := newTestSet()
+	logPath := filepath.Join(t.TempDir(), "tee.log")
+
+	svc := NewProcessService(set, "tee-svc")
+	svc.SetCommand([]string{"/bin/sh", "-c", "echo to-both"})
+	svc.SetLogType(LogToBuffer)
+	svc.SetLogBufMax(4096)
+	svc.SetLogFileDetails(logPath, 0o600, -1, -1)
+	set.AddService(svc)
+
+	set.StartService(svc)
+
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		data, _ := os.ReadFile(logPath)
+		if lb := svc.GetLogBuffer(); lb != nil && string(data) == "to-both\n" &&
+			bytes.Contains(lb.GetBuffer(), []byte("to-both\n")) {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	data, _ := os.ReadFile(logPath)
+	t.Errorf("expected output in both buffer and logfile; logfile=%q", data)
+}
@@ -14,6 +14,8 @@ import (
 	"sync"
 	"syscall"
 	"time"
+
+	"github.com/sunlightlinux/slinit/pkg/process"
 )
 
 // defaultReadBufferSize is the fallback read-chunk size used by
@@ -109,6 +111,7 @@ type LogRotator struct {
 	lastRotate      time.Time
 	rotateTimer     *time.Timer
 	enospcReported  bool // one-shot: we already logged the ENOSPC drain event
+	openErrReported bool // one-shot: we already logged a failed open; reset on success
 	pipeR       *os.File
 	pipeW       *os.File
 	doneCh      chan struct{}
@@ -570,11 +573,19 @@ func (lr *LogRotator) processLine(line []byte) {
 		return
 	}
 
-	// Open file if needed
+	// Open file if needed. A failed open drops the line and is retried
+	// with the next one, so output resumes once the path is usable
+	// (e.g. /var/log mounted later in boot).
 	if lr.file == nil {
 		if err := lr.openFileLocked(); err != nil {
+			if !lr.openErrReported && lr.logger != nil {
+				lr.logger.Error("Service '%s': cannot open logfile, dropping output until it can be opened: %v",
+					lr.serviceName, err)
+			}
+			lr.openErrReported = true
 			return
 		}
+		lr.openErrReported = false
 	}
 
 	// Check size-based rotation
@@ -734,21 +745,35 @@ func (lr *LogRotator) writeAlertLocked(out []byte) {
 	}
 }
 
-// openFileLocked opens the logfile for appending. Must be called with mu held.
+// openLogFile opens a service logfile for appending, creating it and
+// any missing parent directories (mode 0755) first, so a logfile under a
+// directory that does not exist yet at boot still works.
 //
 // O_NOFOLLOW prevents an attacker (or a buggy service writing to a shared
 // /var/log path) from replacing the logfile with a symlink to /etc/passwd
 // or similar — slinit runs as root so a followed symlink would be an
 // arbitrary write primitive. fchown on the open fd (instead of path-based
 // os.Chown) closes the same TOCTOU window for the chown step.
+func openLogFile(path string, perm os.FileMode, uid, gid int) (*os.File, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND|syscall.O_NOFOLLOW, perm)
+	if err != nil {
+		return nil, err
+	}
+	if uid >= 0 || gid >= 0 {
+		_ = f.Chown(uid, gid)
+	}
+	return f, nil
+}
+
+// openFileLocked opens the logfile for appending. Must be called with mu held.
 func (lr *LogRotator) openFileLocked() error {
-	f, err := os.OpenFile(lr.filePath, os.O_WRONLY|os.O_CREATE|os.O_APPEND|syscall.O_NOFOLLOW, lr.filePerms)
+	f, err := openLogFile(lr.filePath, lr.filePerms, lr.fileUID, lr.fileGID)
 	if err != nil {
 		return err
 	}
-	if lr.fileUID >= 0 || lr.fileGID >= 0 {
-		_ = f.Chown(lr.fileUID, lr.fileGID)
-	}
 	// Get current file size
 	info, err := f.Stat()
 	if err == nil {
@@ -905,13 +930,42 @@ func (lr *LogRotator) runProcessor(rotatedFile string) {
 	if lr.logger != nil {
 		lr.logger.Info("Service '%s': running log-processor on %s", lr.serviceName, rotatedFile)
 	}
-	if err := cmd.Run(); err != nil {
+	if err := process.Run(cmd); err != nil {
 		if lr.logger != nil {
 			lr.logger.Error("Service '%s': log-processor failed: %v", lr.serviceName, err)
 		}
 	}
 }
 
+// Reopen closes the current logfile so the next line opens path afresh.
+// After an external logrotate(8) has renamed the file, output moves to
+// a new file instead of following the renamed one; a changed path
+// takes effect the same way.
+func (lr *LogRotator) Reopen(path string) {
+	lr.mu.Lock()
+	defer lr.mu.Unlock()
+	lr.filePath = path
+	if lr.file != nil {
+		lr.file.Close()
+		lr.file = nil
+		lr.currentSize = 0
+	}
+}
+
+// ReopenLogFiles makes every service that writes its logfile through a
+// LogRotator reopen it by path (see LogRotator.Reopen). Called on
+// SIGHUP. A logfile handed to the process directly cannot be reopened;
+// external rotation must truncate it in place (copytruncate) instead.
+func (ss *ServiceSet) ReopenLogFiles() {
+	ss.queueMu.RLock()
+	defer ss.queueMu.RUnlock()
+	for _, svc := range ss.ListServices() {
+		if ps, ok := svc.(*ProcessService); ok && ps.logRotator != nil {
+			ps.logRotator.Reopen(ps.logFile)
+		}
+	}
+}
+
 // Close stops the reader and cleans up resources.
 func (lr *LogRotator) Close() {
 	if lr.pipeW != nil {
@@ -1111,3 +1111,49 @@ func TestLogRotatorRotateNoCollision(t *testing.T) {
 		t.Errorf("second rotated body: got %q want %q", secondBody, "second\n")
 	}
 }
+
+// TestLogRotatorReopenAfterRename checks that the logfile's directory is
+// created on first open and that after an external rename, Reopen moves
+// output to a fresh file at the configured path.
+func TestLogRotatorReopenAfterRename(t *testing.T) {
+	dir := t.TempDir()
+	logPath := filepath.Join(dir, "sub", "svc.log")
+
+	lr, err := NewLogRotator(LogRotatorConfig{
+		FilePath:    logPath,
+		FilePerms:   0600,
+		FileUID:     -1,
+		FileGID:     -1,
+		ServiceName: "t",
+		LogLevelMax: -1,
+	})
+	if err != nil {
+		t.Fatalf("new: %v", err)
+	}
+	w, err := lr.CreatePipe()
+	if err != nil {
+		t.Fatalf("pipe: %v", err)
+	}
+	lr.StartReader()
+	defer lr.Close()
+
+	if _, err := w.Write([]byte("before\n")); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	waitForLogFile(t, logPath, len("before\n"))
+
+	rotated := logPath + ".1"
+	if err := os.Rename(logPath, rotated); err != nil {
+		t.Fatal(err)
+	}
+	lr.Reopen(logPath)
+	if _, err := w.Write([]byte("after\n")); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	if got := waitForLogFile(t, logPath, len("after\n")); string(got) != "after\n" {
+		t.Errorf("new logfile: got %q, want %q", got, "after\n")
+	}
+	if got, _ := os.ReadFile(rotated); string(got) != "before\n" {
+		t.Errorf("rotated logfile: got %q, want %q", got, "before\n")
+	}
+}
@@ -0,0 +1,149 @@
+package service
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// GraphNode is one service in a dependency graph export.
+type GraphNode struct {
+	Name  string
+	Type  ServiceType
+	State ServiceState
+}
+
+// GraphEdge is one dependency in a dependency graph export: From
+// depends on To.
+type GraphEdge struct {
+	From string
+	To   string
+	Type DependencyType
+}
+
+// ExportMermaid writes the dependency graph of every loaded service as a
+// Mermaid flowchart (`graph TD`), which GitHub and most Markdown viewers
+// render without external tooling.
+func ExportMermaid(set *ServiceSet, w io.Writer) error {
+	nodes, edges := set.Graph()
+	return WriteMermaid(w, nodes, edges)
+}
+
+// WriteMermaid renders nodes and edges as a Mermaid flowchart. Node
+// shapes follow the service type and STARTED services are filled green;
+// hard dependencies are solid arrows, soft ones dashed and waits-for
+// dotted. It operates on plain names so that clients holding only a
+// wire-level view of the graph can share it. Nodes are emitted in name
+// order and edges in the order given.
+func WriteMermaid(w io.Writer, nodes []GraphNode, edges []GraphEdge) error {
+	sorted := make([]GraphNode, len(nodes))
+	copy(sorted, nodes)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Name < sorted[j].Name })
+
+	// Service names may hold characters Mermaid does not accept in node
+	// IDs, so nodes get synthetic IDs and the name becomes the label.
+	ids := make(map[string]string, len(sorted))
+	id := func(name string) string {
+		if v, ok := ids[name]; ok {
+			return v
+		}
+		v := fmt.Sprintf("n%d", len(ids))
+		ids[name] = v
+		return v
+	}
+
+	bw := bufio.NewWriter(w)
+	fmt.Fprintln(bw, "graph TD")
+	var started []string
+	for _, n := range sorted {
+		l, r := mermaidShape(n.Type)
+		fmt.Fprintf(bw, "  %s%s%s%s\n", id(n.Name), l, mermaidLabel(n.Name), r)
+		if n.State == StateStarted {
+			started = append(started, id(n.Name))
+		}
+	}
+
+	// Edge endpoints the caller had no node for still need a label.
+	for _, e := range edges {
+		for _, name := range []string{e.From, e.To} {
+			if _, ok := ids[name]; !ok {
+				fmt.Fprintf(bw, "  %s[%s]\n", id(name), mermaidLabel(name))
+			}
+		}
+	}
+
+	var linkStyles []string
+	for i, e := range edges {
+		arrow, label, dash := mermaidEdge(e.Type)
+		if label != "" {
+			arrow += "|" + label + "|"
+		}
+		fmt.Fprintf(bw, "  %s %s %s\n", id(e.From), arrow, id(e.To))
+		if dash != "" {
+			linkStyles = append(linkStyles, fmt.Sprintf("  linkStyle %d stroke-dasharray:%s", i, dash))
+		}
+	}
+	for _, ls := range linkStyles {
+		fmt.Fprintln(bw, ls)
+	}
+
+	if len(started) > 0 {
+		fmt.Fprintln(bw, "  classDef started fill:#c8e6c9,stroke:#2e7d32")
+		fmt.Fprintf(bw, "  class %s started\n", strings.Join(started, ","))
+	}
+	return bw.Flush()
+}
+
+// mermaidShape returns the node delimiters for a service type.
+func mermaidShape(t ServiceType) (string, string) {
+	switch t {
+	case TypeProcess:
+		return "(", ")"
+	case TypeScripted:
+		return "[[", "]]"
+	case TypeBGProcess:
+		return "{", "}"
+	case TypeTriggered:
+		return ">", "]"
+	case TypeSocketActivated:
+		return "((", "))"
+	case TypeBarrier:
+		return "[/", "\\]"
+	case TypeFanout:
+		return "[\\", "/]"
+	case TypeTimer:
+		return "{{", "}}"
+	default: // TypeInternal, TypePlaceholder
+		return "[", "]"
+	}
+}
+
+// mermaidEdge returns the arrow, label and stroke-dasharray (empty for a
+// solid line) for a dependency type.
+func mermaidEdge(dt DependencyType) (arrow, label, dash string) {
+	switch dt {
+	case DepRegular:
+		return "-->", "", ""
+	case DepSoft:
+		return "-.->", "soft", "6 4"
+	case DepWaitsFor:
+		return "-.->", "waits-for", "2 2"
+	case DepMilestone:
+		return "==>", "milestone", ""
+	case DepPreparedBy:
+		return "==>", "prepared-by", ""
+	case DepBefore:
+		return "-.->", "before", "2 2"
+	case DepAfter:
+		return "-.->", "after", "2 2"
+	default:
+		return "-->", "", ""
+	}
+}
+
+// mermaidLabel quotes a service name for use as a node label.
+func mermaidLabel(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, "#quot;") + `"`
+}
@@ -0,0 +1,81 @@
+package service
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestWriteMermaid(t *testing.T) {
+	var buf bytes.Buffer
+	err := WriteMermaid(&buf, []GraphNode{
+		{Name: "web", Type: TypeProcess, State: StateStarted},
+		{Name: "boot", Type: TypeInternal, State: StateStarted},
+		{Name: "db", Type: TypeBGProcess, State: StateStopped},
+	}, []GraphEdge{
+		{From: "boot", To: "web", Type: DepWaitsFor},
+		{From: "web", To: "db", Type: DepRegular},
+		{From: "web", To: "cache", Type: DepSoft},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `graph TD
+  n0["boot"]
+  n1{"db"}
+  n2("web")
+  n3["cache"]
+  n0 -.->|waits-for| n2
+  n2 --> n1
+  n2 -.->|soft| n3
+  linkStyle 0 stroke-dasharray:2 2
+  linkStyle 2 stroke-dasharray:6 4
+  classDef started fill:#c8e6c9,stroke:#2e7d32
+  class n0,n2 started
+`
+	if got := buf.String(); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestMermaidShapes(t *testing.T) {
+	for typ, want := range map[ServiceType]string{
+		TypeInternal:  `n0["x"]`,
+		TypeProcess:   `n0("x")`,
+		TypeScripted:  `n0[["x"]]`,
+		TypeBGProcess: `n0{"x"}`,
+		TypeTriggered: `n0>"x"]`,
+	} {
+		var buf bytes.Buffer
+		WriteMermaid(&buf, []GraphNode{{Name: "x", Type: typ}}, nil)
+		if !strings.Contains(buf.String(), "  "+want+"\n") {
+			t.Errorf("%v: got %q, want node %s", typ, buf.String(), want)
+		}
+	}
+}
+
+func TestExportMermaid(t *testing.T) {
+	set, _ := newTestSet()
+	a := NewInternalService(set, `svc "a"`)
+	b := NewInternalService(set, "svc-b")
+	a.Record().AddDep(b, DepRegular)
+	set.AddService(a)
+	set.AddService(b)
+	set.StartService(a)
+
+	var buf bytes.Buffer
+	if err := ExportMermaid(set, &buf); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	for _, want := range []string{
+		`n0["svc #quot;a#quot;"]`,
+		`n1["svc-b"]`,
+		"n0 --> n1",
+		"class n0,n1 started",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output missing %q:\n%s", want, out)
+		}
+	}
+}
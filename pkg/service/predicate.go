@@ -10,6 +10,8 @@ import (
 	"strconv"
 	"strings"
 	"time"
+
+	"github.com/sunlightlinux/slinit/pkg/process"
 )
 
 // PredicateKind identifies one of the systemd-style start preconditions.
@@ -327,11 +329,11 @@ func checkExecCondition(cmdline string) (bool, string) {
 	ctx, cancel := context.WithTimeout(context.Background(), execConditionTimeout)
 	defer cancel()
 	cmd := exec.CommandContext(ctx, "/bin/sh", "-c", cmdline)
-	if err := cmd.Run(); err != nil {
+	if err := process.Run(cmd); err != nil {
 		if ctx.Err() == context.DeadlineExceeded {
 			return false, fmt.Sprintf("exec-condition %q timed out after %s", cmdline, execConditionTimeout)
 		}
-		if ee, ok := err.(*exec.ExitError); ok {
+		if ee, ok := err.(interface{ ExitCode() int }); ok {
 			return false, fmt.Sprintf("exec-condition %q exited %d", cmdline, ee.ExitCode())
 		}
 		return false, fmt.Sprintf("exec-condition %q: %v", cmdline, err)
@@ -0,0 +1,46 @@
+package service
+
+import (
+	"strings"
+	"testing"
+)
+
+func hasErrorFormat(l *testLogger, substr string) bool {
+	for _, e := range l.errors {
+		if strings.Contains(e, substr) {
+			return true
+		}
+	}
+	return false
+}
+
+func TestPreExecCheckFailsStart(t *testing.T) {
+	set, logger := newTestSet()
+	svc := NewProcessService(set, "missing-cmd")
+	svc.SetCommand([]string{"/nonexistent/slinit-test-binary"})
+	set.AddService(svc)
+
+	set.StartService(svc)
+	if svc.State() != StateStopped || !svc.DidStartFail() {
+		t.Fatalf("expected failed start, got state %v failed=%v", svc.State(), svc.DidStartFail())
+	}
+	if !hasErrorFormat(logger, "pre-exec check failed") {
+		t.Errorf("expected pre-exec check error, got %v", logger.errors)
+	}
+}
+
+func TestSkipPreExecChecks(t *testing.T) {
+	set, logger := newTestSet()
+	set.SetSkipPreExecChecks(true)
+	svc := NewProcessService(set, "missing-cmd")
+	svc.SetCommand([]string{"/nonexistent/slinit-test-binary"})
+	set.AddService(svc)
+
+	set.StartService(svc)
+	if !svc.DidStartFail() {
+		t.Fatal("start of a missing binary must still fail")
+	}
+	if hasErrorFormat(logger, "pre-exec check failed") {
+		t.Error("pre-exec check ran despite SetSkipPreExecChecks(true)")
+	}
+}
@@ -0,0 +1,52 @@
+package service
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/sunlightlinux/slinit/pkg/process"
+)
+
+func TestForeignPreloadWarnsOnce(t *testing.T) {
+	set, logger := newTestSet()
+	lib := filepath.Join(t.TempDir(), "libtrace.so")
+	if err := os.WriteFile(lib, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	newTraced := func(name string) Service {
+		svc := NewProcessService(set, name)
+		svc.Record().SetLDPreload([]string{lib})
+		return svc
+	}
+
+	warnings := func() int {
+		n := 0
+		for _, e := range logger.errors {
+			if strings.Contains(e, "preloads") {
+				n++
+			}
+		}
+		return n
+	}
+
+	// Running as the library's owner (or without run-as) is fine.
+	owner := uint32(os.Getuid())
+	newTraced("owned").Record().ApplyProcessAttrs(&process.ExecParams{RunAsUID: owner})
+	newTraced("root").Record().ApplyProcessAttrs(&process.ExecParams{})
+	if n := warnings(); n != 0 {
+		t.Fatalf("got %d warnings for an owned preload, want 0", n)
+	}
+
+	// Any other uid does not own it; the warning is given once, not on
+	// every start.
+	foreign := newTraced("foreign")
+	other := &process.ExecParams{RunAsUID: owner + 1000}
+	foreign.Record().ApplyProcessAttrs(other)
+	foreign.Record().ApplyProcessAttrs(other)
+	if n := warnings(); n != 1 {
+		t.Errorf("got %d warnings for a foreign preload, want 1", n)
+	}
+}
@@ -9,9 +9,12 @@ import (
 	"os/exec"
 	"strconv"
 	"strings"
+	"sync"
 	"syscall"
 	"time"
 
+	"golang.org/x/sys/unix"
+
 	"github.com/sunlightlinux/slinit/pkg/process"
 )
 
@@ -37,6 +40,7 @@ type ProcessService struct {
 	// Command configuration
 	command            []string
 	argv0              string // override argv[0] presented to the exec'd binary (runit chpst -b)
+	memfdExec          bool   // exec-memfd: run an in-memory copy of command[0]
 	stopCommand        []string
 	finishCommand      []string            // runs after process exits (before restart decision)
 	preStartCommand    []string            // runs before fork+exec; non-zero exit fails the start
@@ -48,6 +52,11 @@ type ProcessService struct {
 	workingDir         string
 	envFile            string
 	envDir             string // directory with one file per env var
+	// encryptedEnvFile is an env-file encrypted with the key named by
+	// envEncryptionKey (see process.LoadEncryptedEnvFile). Merged after
+	// env-file and env-dir.
+	encryptedEnvFile string
+	envEncryptionKey string
 	// envGenerator: path to an executable that emits `KEY=VALUE\n` lines
 	// on stdout at service-start time. Merged after env-file and env-dir
 	// so it wins conflicts. Failure aborts the start (systemd
@@ -81,6 +90,10 @@ type ProcessService struct {
 	// the next stop cycle reads normal config.
 	pendingStopSignal syscall.Signal
 
+	// stopSeqStep is the stop-signal-sequence step in effect, -1 when
+	// the current stop does not follow the sequence.
+	stopSeqStep int
+
 	// Timeout configuration
 	startTimeout time.Duration
 	stopTimeout  time.Duration
@@ -115,11 +128,10 @@ type ProcessService struct {
 	restartMaxDelay time.Duration
 
 	// Restart rate limiting
-	restartInterval      time.Duration
-	maxRestartCount      int
-	restartIntervalTime  time.Time
-	restartIntervalCount int
-	lastStartTime        time.Time
+	restartInterval time.Duration
+	maxRestartCount int
+	restartTokens   tokenBucket // zero until the first CheckRestart
+	lastStartTime   time.Time
 
 	// State tracking
 	stopIssued       bool
@@ -130,15 +142,21 @@ type ProcessService struct {
 	socketFD         *os.File      // primary listening socket (fd 3, nil if no socket-listen)
 	socketFDs        []*os.File    // additional sockets (fd 4, 5, ... for multiple socket-listen)
 	socketOnDemand   bool          // start service on first connection (socket-activation = on-demand)
+	// socketDemandMu guards the two watcher channels: the watcher is
+	// started and stopped both from the child-exit path, under queueMu,
+	// and from set membership changes and the loader, without it.
+	socketDemandMu   sync.Mutex
 	socketDemandStop chan struct{} // signal to stop on-demand watcher
 	socketDemandDone chan struct{} // closed when watcher goroutine exits
-	socketDemandLn   net.Listener  // listener owned by watcher; closed to break Accept
 
 	// Readiness notification
 	readyNotifyFD  int       // fd number child writes to (-1 if none)
 	readyNotifyVar string    // env var name ("" if none)
 	readyPipeRead  *os.File  // read-end of notification pipe (parent watches)
 	readyCh        chan bool // receives true=ready, false=EOF/error
+	// readyNotifySocket waits for sd_notify READY=1 on $NOTIFY_SOCKET
+	// instead of a pipe write (ready-notification = notify).
+	readyNotifySocket bool
 
 	// Service-level watchdog. Piggybacks on the ready-notification pipe:
 	// the first message marks the service ready, subsequent writes act
@@ -225,6 +243,8 @@ const (
 	// timerAbortTimeout: SIGABRT phase inserted between stop-timeout
 	// (SIGTERM) and the SIGKILL escalation. Wraps timeout-abort-sec.
 	timerAbortTimeout
+	// timerStopSequence: timeout of a stop-signal-sequence step.
+	timerStopSequence
 )
 
 // NewProcessService creates a new process service.
@@ -238,6 +258,7 @@ func NewProcessService(set *ServiceSet, name string) *ProcessService {
 		readyNotifyFD:   -1,
 		logLevelMax:     -1,
 		alertLevel:      -1,
+		stopSeqStep:     -1,
 	}
 	svc.ServiceRecord = *NewServiceRecord(svc, set, name, TypeProcess)
 	return svc
@@ -250,6 +271,10 @@ func (s *ProcessService) SetCommand(cmd []string) { s.command = cmd }
 // Empty means "use command[0]" (default). Mirrors runit's chpst -b.
 func (s *ProcessService) SetArgv0(a string) { s.argv0 = a }
 
+// SetMemfdExec makes the service run an in-memory copy of its binary
+// (exec-memfd).
+func (s *ProcessService) SetMemfdExec(v bool) { s.memfdExec = v }
+
 // effectiveRunAsUID returns the dynamic-user UID when allocated,
 // otherwise the configured runAsUID. Called by startProcess so the
 // switch between static run-as and dynamic-user is transparent to
@@ -321,6 +346,13 @@ func (s *ProcessService) SetPreStopHook(cmd []string) { s.preStopHook = cmd }
 // SetEnvDir sets the environment directory path.
 func (s *ProcessService) SetEnvDir(dir string) { s.envDir = dir }
 
+// SetEncryptedEnvFile sets the encrypted env-file and the spec of the
+// key that decrypts it.
+func (s *ProcessService) SetEncryptedEnvFile(path, keySpec string) {
+	s.encryptedEnvFile = path
+	s.envEncryptionKey = keySpec
+}
+
 // SetControlCommands sets the custom signal handler commands.
 func (s *ProcessService) SetControlCommands(cmds map[string][]string) { s.controlCommands = cmds }
 
@@ -382,6 +414,9 @@ func (s *ProcessService) SetStartTimeout(d time.Duration) { s.startTimeout = d }
 // SetStopTimeout sets the stop timeout.
 func (s *ProcessService) SetStopTimeout(d time.Duration) { s.stopTimeout = d }
 
+// StopTimeout returns the stop timeout (0 = wait forever).
+func (s *ProcessService) StopTimeout() time.Duration { return s.stopTimeout }
+
 // SetTimeoutStartFailureMode picks the signal delivered when the
 // start-timeout expires. Default (terminate) preserves the legacy
 // SIGINT-then-escalation behaviour.
@@ -403,8 +438,9 @@ func (s *ProcessService) SetExitType(t ExitType) { s.exitType = t }
 // picker order matches systemd's precedence: a watchdog-triggered stop
 // uses watchdog-signal (default SIGABRT), a restart-driven stop uses
 // restart-kill-signal (falls back to term-signal), everything else
-// uses term-signal.
+// uses the first step of stop-signal-sequence or term-signal.
 func (s *ProcessService) pickStopSignal() syscall.Signal {
+	s.stopSeqStep = -1
 	// watchdog-signal wins when set — Stop() sets a per-invocation
 	// pendingStopSignal that we consume here.
 	if s.pendingStopSignal != 0 {
@@ -417,6 +453,10 @@ func (s *ProcessService) pickStopSignal() syscall.Signal {
 			return sig
 		}
 	}
+	if seq := s.Record().StopSignalSequence(); len(seq) > 0 {
+		s.stopSeqStep = 0
+		return seq[0].Signal
+	}
 	sig := s.termSignal
 	if sig == 0 {
 		sig = syscall.SIGTERM
@@ -499,7 +539,8 @@ func (s *ProcessService) SetRestartMaxDelay(d time.Duration) {
 
 // nextRestartDelay returns the delay to use for the next restart and advances
 // the progressive backoff counter. When step <= 0, always returns restartDelay.
-// Jitter (restartRandomizedDelay) is applied on top of the base value when set.
+// Jitter (restartRandomizedDelay) is applied on top of the base value when set,
+// after the global restart jitter factor has scaled it.
 func (s *ProcessService) nextRestartDelay() time.Duration {
 	var delay time.Duration
 	if s.restartDelayStep <= 0 {
@@ -519,7 +560,7 @@ func (s *ProcessService) nextRestartDelay() time.Duration {
 		}
 		s.currentRestartDelay = next
 	}
-	total := delay + jitter(s.restartRandomizedDelay)
+	total := scaleJitter(delay, s.services.restartJitterFactor) + jitter(s.restartRandomizedDelay)
 	if s.restartMaxDelay > 0 && total > s.restartMaxDelay {
 		total = s.restartMaxDelay
 	}
@@ -611,12 +652,22 @@ func (s *ProcessService) SetHealthCheck(cmd []string, interval, delay time.Durat
 // startHealthCheckIfConfigured starts the health checker if configured.
 func (s *ProcessService) startHealthCheckIfConfigured() {
 	if s.healthChecker != nil {
+		s.healthChecker.SetExecContext(s.workingDir, s.effectiveRunAsUID(),
+			s.effectiveRunAsGID(), s.supplementaryGIDs)
 		s.services.logger.Info("Service '%s': starting health check (interval=%v, max-failures=%d)",
 			s.serviceName, s.healthChecker.interval, s.healthChecker.maxFailures)
 		s.healthChecker.Start()
 	}
 }
 
+// HealthStatus reports the health checker state (HealthReporter).
+func (s *ProcessService) HealthStatus() (HealthStatus, bool) {
+	if s.healthChecker == nil {
+		return HealthStatus{}, false
+	}
+	return s.healthChecker.Status(), true
+}
+
 // stopHealthChecker stops the health checker if active.
 func (s *ProcessService) stopHealthChecker() {
 	if s.healthChecker != nil {
@@ -786,9 +837,9 @@ func (s *ProcessService) fireWatchdogStop() {
 	}
 
 	withRestart := false
-	switch s.autoRestart {
+	switch s.GetEffectiveAutoRestart() {
 	case RestartAlways, RestartOnFailure:
-		withRestart = s.CheckRestart()
+		withRestart = s.canAutoRestart()
 	}
 
 	s.doStop(withRestart)
@@ -910,7 +961,15 @@ func (s *ProcessService) SetReadyNotification(fd int, varName string) {
 	s.readyNotifyVar = varName
 }
 
-// HasReadyNotification returns true if readiness notification is configured.
+// SetReadyNotifySocket makes the service count as started only once it
+// sends sd_notify READY=1 on its $NOTIFY_SOCKET.
+func (s *ProcessService) SetReadyNotifySocket(on bool) { s.readyNotifySocket = on }
+
+// ReadyNotifySocket reports whether readiness comes over $NOTIFY_SOCKET.
+func (s *ProcessService) ReadyNotifySocket() bool { return s.readyNotifySocket }
+
+// HasReadyNotification returns true if a readiness notification pipe
+// is configured.
 func (s *ProcessService) HasReadyNotification() bool {
 	return s.readyNotifyFD >= 0 || s.readyNotifyVar != ""
 }
@@ -935,14 +994,19 @@ func (s *ProcessService) WatchdogTimeout() time.Duration {
 	return s.watchdogTimeout
 }
 
+// listenPaths returns the configured socket-listen paths.
+func (s *ProcessService) listenPaths() []string {
+	if len(s.socketPaths) == 0 && s.socketPath != "" {
+		return []string{s.socketPath}
+	}
+	return s.socketPaths
+}
+
 // openSocket creates and binds listening sockets for socket activation.
 // Supports Unix sockets (path), TCP (tcp:host:port), and UDP (udp:host:port).
 // Multiple socket-listen directives result in multiple fds (LISTEN_FDS=N).
 func (s *ProcessService) openSocket() error {
-	paths := s.socketPaths
-	if len(paths) == 0 && s.socketPath != "" {
-		paths = []string{s.socketPath}
-	}
+	paths := s.listenPaths()
 	if len(paths) == 0 || s.socketFD != nil {
 		return nil
 	}
@@ -968,7 +1032,7 @@ func (s *ProcessService) openSocket() error {
 //   - "tcp:host:port" or "tcp4:host:port" or "tcp6:host:port" → TCP
 //   - "udp:host:port" or "udp4:host:port" or "udp6:host:port" → UDP
 //   - anything else → Unix domain socket
-func (s *ProcessService) openOneSocket(path string) (*os.File, error) {
+func (sr *ServiceRecord) openOneSocket(path string) (*os.File, error) {
 	// TCP socket
 	if strings.HasPrefix(path, "tcp:") || strings.HasPrefix(path, "tcp4:") || strings.HasPrefix(path, "tcp6:") {
 		parts := strings.SplitN(path, ":", 2)
@@ -1032,14 +1096,14 @@ func (s *ProcessService) openOneSocket(path string) (*os.File, error) {
 	unixListener.Close()
 
 	// Set permissions/ownership on Unix socket
-	if s.socketPerms != 0 {
-		if err := os.Chmod(path, os.FileMode(s.socketPerms)); err != nil {
+	if sr.socketPerms != 0 {
+		if err := os.Chmod(path, os.FileMode(sr.socketPerms)); err != nil {
 			fd.Close()
 			return nil, fmt.Errorf("chmod: %w", err)
 		}
 	}
-	if s.socketUID >= 0 || s.socketGID >= 0 {
-		uid, gid := s.socketUID, s.socketGID
+	if sr.socketUID >= 0 || sr.socketGID >= 0 {
+		uid, gid := sr.socketUID, sr.socketGID
 		if uid < 0 {
 			uid = -1
 		}
@@ -1067,82 +1131,126 @@ func (s *ProcessService) closeSocket() {
 	s.socketFDs = nil
 
 	// Remove Unix socket files
-	paths := s.socketPaths
-	if len(paths) == 0 && s.socketPath != "" {
-		paths = []string{s.socketPath}
-	}
-	for _, p := range paths {
+	for _, p := range s.listenPaths() {
 		if !strings.Contains(p, ":") {
 			os.Remove(p)
 		}
 	}
 }
 
-// startOnDemandWatcher starts a goroutine that watches the primary socket
-// for incoming connections. On first activity, it starts the service.
-// The watcher uses epoll-like polling (Accept with timeout) to detect connections.
+// ArmSocketActivation opens the activation socket of an on-demand
+// (socket-activation = on-demand) service and starts watching it, so
+// the first incoming connection starts the service. The loader calls it
+// once the description has been applied; it is a no-op for services
+// that are not on-demand, not stopped, or already armed.
+func (s *ProcessService) ArmSocketActivation() {
+	if !s.socketOnDemand || s.onDemandWatching() || s.State() != StateStopped {
+		return
+	}
+	if err := s.openSocket(); err != nil {
+		s.services.logger.Error("Service '%s': %v", s.serviceName, err)
+		return
+	}
+	s.startOnDemandWatcher()
+}
+
+// DisarmSocketActivation stops the on-demand watcher and, unless the
+// process is running, closes the activation socket. Called when the
+// service leaves the set.
+func (s *ProcessService) DisarmSocketActivation() {
+	s.stopOnDemandWatcher()
+	if s.pid <= 0 {
+		s.closeSocket()
+	}
+}
+
+// onDemandWatching reports whether the on-demand watcher is running.
+func (s *ProcessService) onDemandWatching() bool {
+	s.socketDemandMu.Lock()
+	defer s.socketDemandMu.Unlock()
+	return s.socketDemandStop != nil
+}
+
+// startOnDemandWatcher starts a goroutine that polls the primary socket
+// for incoming connections. The first time it becomes readable the
+// service is started; the pending connection is left in the backlog for
+// the child to accept on the fd it inherits. Does nothing if a watcher
+// is already running.
 func (s *ProcessService) startOnDemandWatcher() {
-	if s.socketFD == nil || !s.socketOnDemand {
+	s.socketDemandMu.Lock()
+	defer s.socketDemandMu.Unlock()
+	if s.socketFD == nil || !s.socketOnDemand || s.socketDemandStop != nil {
 		return
 	}
-	s.socketDemandStop = make(chan struct{})
-	s.socketDemandDone = make(chan struct{})
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	s.socketDemandStop = stop
+	s.socketDemandDone = done
 
 	// Dup the fd on the caller's goroutine so a concurrent closeSocket
-	// cannot race the child's read of s.socketFD.
+	// cannot race the watcher's poll of s.socketFD.
 	fdDup, err := syscall.Dup(int(s.socketFD.Fd()))
 	if err != nil {
 		s.services.logger.Error("Service '%s': on-demand socket dup failed: %v", s.serviceName, err)
-		close(s.socketDemandDone)
-		return
-	}
-
-	f := os.NewFile(uintptr(fdDup), "on-demand-socket")
-	ln, err := net.FileListener(f)
-	f.Close()
-	if err != nil {
-		s.services.logger.Error("Service '%s': on-demand listener failed: %v", s.serviceName, err)
-		close(s.socketDemandDone)
+		close(done)
 		return
 	}
-	s.socketDemandLn = ln
 
 	go func() {
-		defer close(s.socketDemandDone)
-		defer ln.Close()
-
-		conn, err := ln.Accept()
-		if err != nil {
-			// Listener closed (stop) or other error — exit.
-			return
-		}
-		// Got a connection! Close it back (the real service will accept it
-		// after being started — the connection stays in the socket backlog).
-		conn.Close()
+		defer close(done)
+		defer syscall.Close(fdDup)
 
-		select {
-		case <-s.socketDemandStop:
-			return
-		default:
+		fds := []unix.PollFd{{Fd: int32(fdDup), Events: unix.POLLIN}}
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+			fds[0].Revents = 0
+			n, err := unix.Poll(fds, socketPollTimeoutMs)
+			if err != nil {
+				if errors.Is(err, syscall.EINTR) {
+					continue
+				}
+				s.services.logger.Error("Service '%s': on-demand socket poll failed: %v", s.serviceName, err)
+				return
+			}
+			if n > 0 && fds[0].Revents&unix.POLLIN != 0 {
+				break
+			}
 		}
 
-		s.services.logger.Info("Service '%s': on-demand socket activation triggered", s.serviceName)
-
-		// Start the service via the normal path
-		s.services.StartService(s.self)
+		// Starting takes queueMu, which stopOnDemandWatcher's callers
+		// may hold while waiting for done; hand off instead of blocking.
+		go s.demandActivate(stop)
 	}()
 }
 
+// demandActivate starts the service after the on-demand watcher saw a
+// connection, unless the watcher was stopped in the meantime.
+func (s *ProcessService) demandActivate(stop chan struct{}) {
+	s.services.queueMu.Lock()
+	defer s.services.queueMu.Unlock()
+	select {
+	case <-stop:
+		return
+	default:
+	}
+	s.services.logger.Info("Service '%s': on-demand socket activation triggered", s.serviceName)
+	s.Start()
+	s.services.processQueuesLocked()
+}
+
 // stopOnDemandWatcher stops the on-demand socket watcher and waits for
 // its goroutine to exit, so the caller can safely close the socket after.
+// Waiting with socketDemandMu held is safe: the watcher never takes it.
 func (s *ProcessService) stopOnDemandWatcher() {
+	s.socketDemandMu.Lock()
+	defer s.socketDemandMu.Unlock()
 	if s.socketDemandStop != nil {
 		close(s.socketDemandStop)
 		s.socketDemandStop = nil
-		if s.socketDemandLn != nil {
-			s.socketDemandLn.Close() // unblocks Accept
-			s.socketDemandLn = nil
-		}
 		if s.socketDemandDone != nil {
 			<-s.socketDemandDone
 			s.socketDemandDone = nil
@@ -1150,11 +1258,17 @@ func (s *ProcessService) stopOnDemandWatcher() {
 	}
 }
 
-// BecomingInactive is called when the service won't restart. Cleans up socket.
+// BecomingInactive is called when the service won't restart. Cleans up
+// the socket, except that an on-demand service keeps it bound and goes
+// back to watching it until shutdown.
 func (s *ProcessService) BecomingInactive() {
 	s.stopOnDemandWatcher()
 	s.closeDoneCh()
-	s.closeSocket()
+	if s.socketOnDemand && s.socketFD != nil && !s.services.IsShuttingDown() {
+		s.startOnDemandWatcher()
+	} else {
+		s.closeSocket()
+	}
 	s.CloseOutputPipe()
 	s.stopLoggerCommands()
 	if s.vtty != nil {
@@ -1175,6 +1289,7 @@ func (s *ProcessService) closeDoneCh() {
 func (s *ProcessService) SetRestartLimits(interval time.Duration, maxCount int) {
 	s.restartInterval = interval
 	s.maxRestartCount = maxCount
+	s.restartTokens = tokenBucket{}
 }
 
 // PID returns the process ID of the running service.
@@ -1263,6 +1378,17 @@ func (s *ProcessService) BringUp() bool {
 		s.services.logger.Error("Service '%s': no command specified", s.serviceName)
 		return false
 	}
+	if !s.preExecCheck(process.ExecParams{
+		Command:     s.command,
+		WorkingDir:  s.workingDir,
+		Chroot:      s.chroot,
+		EnvFile:     s.envFile,
+		SocketPaths: s.listenPaths(),
+		RunAsUID:    s.runAsUID,
+		RunAsGID:    s.runAsGID,
+	}) {
+		return false
+	}
 
 	// Dynamic-user: allocate a transient UID/GID from the pool. This
 	// must happen before any UID-dependent setup (ServiceDirs chown,
@@ -1272,13 +1398,13 @@ func (s *ProcessService) BringUp() bool {
 		return false
 	}
 
-	// fd-store setup: open $NOTIFY_SOCKET when the feature is enabled
-	// so the child can sd_notify FDSTORE=1 us back. The socket lives
-	// at /run/slinit/notify/<svc>.sock, owned by the run-as user.
-	if s.Record().FDStoreMax() > 0 {
+	// fd-store and sd_notify readiness: open $NOTIFY_SOCKET so the
+	// child can sd_notify FDSTORE=1 or READY=1 us back. The socket
+	// lives at /run/slinit/notify/<svc>.sock, owned by the run-as user.
+	if s.Record().FDStoreMax() > 0 || s.readyNotifySocket {
 		path, err := s.Record().setupNotifySocket(s.effectiveRunAsUID(), s.effectiveRunAsGID())
 		if err != nil {
-			s.services.logger.Error("Service '%s': fd-store setup: %v", s.serviceName, err)
+			s.services.logger.Error("Service '%s': notify socket setup: %v", s.serviceName, err)
 			return false
 		}
 		_ = path // exported into ExecParams below
@@ -1305,7 +1431,10 @@ func (s *ProcessService) BringUp() bool {
 		return false
 	}
 
-	// Open activation socket before starting the process
+	// Open activation socket before starting the process. An armed
+	// on-demand watcher must let go first so it does not keep polling
+	// the socket the child is about to accept on.
+	s.stopOnDemandWatcher()
 	if err := s.openSocket(); err != nil {
 		s.services.logger.Error("Service '%s': %v", s.serviceName, err)
 		return false
@@ -1325,6 +1454,7 @@ func (s *ProcessService) BringUp() bool {
 
 	if err := s.startProcess(); err != nil {
 		s.services.logger.Error("Service '%s': failed to start: %v", s.serviceName, err)
+		s.Record().execErr = err
 		return false
 	}
 
@@ -1361,7 +1491,7 @@ func (s *ProcessService) runHookCommand(cmd []string, label string) error {
 	c.Dir = s.workingDir
 	c.Env = s.buildEnv()
 	s.services.logger.Info("Service '%s': running %s", s.serviceName, label)
-	return c.Run()
+	return process.Run(c)
 }
 
 // BringDown stops the service process.
@@ -1450,8 +1580,11 @@ func (s *ProcessService) BringDown() {
 		s.killCgroupTree(sig)
 	}
 
-	// Arm stop timeout for SIGKILL escalation
-	if s.stopTimeout > 0 {
+	// Arm stop timeout for SIGKILL escalation, or the first step's
+	// timeout when following stop-signal-sequence.
+	if s.stopSeqStep >= 0 {
+		s.armStopSequenceStep()
+	} else if s.stopTimeout > 0 {
 		s.armTimer(s.stopTimeout, timerStopTimeout)
 	}
 }
@@ -1540,32 +1673,41 @@ func (s *ProcessService) InterruptStart() bool {
 
 // CheckRestart checks if the service should auto-restart (rate limiting).
 func (s *ProcessService) CheckRestart() bool {
-	now := time.Now()
-
-	if s.maxRestartCount > 0 {
-		elapsed := now.Sub(s.restartIntervalTime)
-
-		if elapsed < s.restartInterval {
-			// Still in the limiting interval
-			if s.restartIntervalCount >= s.maxRestartCount {
-				s.services.logger.Error("Service '%s': restarting too quickly, stopping",
-					s.serviceName)
-				return false
-			}
-			s.restartIntervalCount++
-		} else {
-			// New interval: stable period elapsed, reset progressive backoff
-			s.restartIntervalTime = now
-			s.restartIntervalCount = 1
-			s.currentRestartDelay = s.restartDelay
-		}
+	ok, rested := checkRestartLimit(&s.restartTokens, s.maxRestartCount, s.restartInterval)
+	if !ok {
+		s.services.logger.Error("Service '%s': restarting too quickly, stopping",
+			s.serviceName)
+		return false
+	}
+	if rested {
+		// Stable period elapsed: reset progressive backoff
+		s.currentRestartDelay = s.restartDelay
 	}
-
 	return true
 }
 
-// buildEnv merges env-file, env-dir, and runtime extraEnv into a single slice.
+// resetRestartLimit refills the restart-limit-count budget and resets
+// progressive backoff (slinitctl reset-failed).
+func (s *ProcessService) resetRestartLimit() {
+	s.restartTokens = tokenBucket{}
+	s.currentRestartDelay = s.restartDelay
+}
+
+// buildEnv merges env-file, env-dir, encrypted-env-file and runtime
+// extraEnv into a single slice.
 func (s *ProcessService) buildEnv() []string {
+	env, err := s.buildEnvChecked()
+	if err != nil {
+		s.services.logger.Error("Service '%s': %v", s.serviceName, err)
+	}
+	return env
+}
+
+// buildEnvChecked is buildEnv for the main start: an encrypted-env-file
+// that cannot be read or decrypted is returned as an error, so the
+// service does not run without its secrets. The rest of the
+// environment is built either way.
+func (s *ProcessService) buildEnvChecked() ([]string, error) {
 	env := s.Record().BuildEnvWithFile(s.envFile)
 
 	// Merge env-dir variables (runit-style: one file per variable)
@@ -1604,6 +1746,30 @@ func (s *ProcessService) buildEnv() []string {
 		}
 	}
 
+	// Merge the decrypted encrypted-env-file over env-file and env-dir.
+	var secretErr error
+	if s.encryptedEnvFile != "" {
+		secretEnv, err := process.LoadEncryptedEnvFile(s.encryptedEnvFile, s.envEncryptionKey)
+		if err != nil {
+			secretErr = fmt.Errorf("failed to read encrypted-env-file '%s': %w",
+				s.encryptedEnvFile, err)
+		}
+		for _, entry := range secretEnv {
+			k := entry[:strings.IndexByte(entry, '=')+1]
+			replaced := false
+			for i, e := range env {
+				if strings.HasPrefix(e, k) {
+					env[i] = entry
+					replaced = true
+					break
+				}
+			}
+			if !replaced {
+				env = append(env, entry)
+			}
+		}
+	}
+
 	// env-generator (systemd EnvironmentGenerator sibling): run the
 	// helper and merge its stdout as KEY=VALUE lines. Merged AFTER
 	// env-file/env-dir so it can override them per systemd semantics.
@@ -1633,7 +1799,7 @@ func (s *ProcessService) buildEnv() []string {
 			}
 		}
 	}
-	return env
+	return env, secretErr
 }
 
 // runEnvGenerator invokes the executable and parses its stdout as
@@ -1642,7 +1808,7 @@ func (s *ProcessService) buildEnv() []string {
 // comments. Non-zero exit yields an error.
 func runEnvGenerator(path string) (map[string]string, error) {
 	cmd := exec.Command(path)
-	out, err := cmd.Output()
+	out, err := process.Output(cmd)
 	if err != nil {
 		return nil, err
 	}
@@ -1667,6 +1833,11 @@ func (s *ProcessService) startProcess() error {
 	s.stopIssued = false
 	s.exitStatus = ExitStatus{}
 
+	env, err := s.buildEnvChecked()
+	if err != nil {
+		return err
+	}
+
 	// Set up output pipe based on log type
 	var outputPipe *os.File
 	if s.logType == LogToBuffer {
@@ -1770,16 +1941,10 @@ func (s *ProcessService) startProcess() error {
 				return fmt.Errorf("failed to create log rotator pipe: %w", pipeErr)
 			}
 		} else {
-			// O_NOFOLLOW + fchown-via-fd close the symlink-swap window
-			// where slinit (running as root) would otherwise write to —
-			// or chown — an attacker-pointed path.
-			f, err := os.OpenFile(s.logFile, os.O_WRONLY|os.O_CREATE|os.O_APPEND|syscall.O_NOFOLLOW, os.FileMode(s.logFilePerms))
+			f, err := openLogFile(s.logFile, os.FileMode(s.logFilePerms), s.logFileUID, s.logFileGID)
 			if err != nil {
 				return fmt.Errorf("failed to open logfile '%s': %w", s.logFile, err)
 			}
-			if s.logFileUID >= 0 || s.logFileGID >= 0 {
-				_ = f.Chown(s.logFileUID, s.logFileGID)
-			}
 			outputPipe = f
 		}
 	} else if s.logType == LogToCommand && len(s.outputLogger) > 0 {
@@ -1799,12 +1964,9 @@ func (s *ProcessService) startProcess() error {
 		var err error
 		errorPipe, s.errLoggerCmd, err = spawnLoggerCommand(s.errorLogger, s.serviceName, "error-logger")
 		if err != nil {
-			// Clean up the output-logger we already started
-			if s.loggerCmd != nil {
-				s.loggerCmd.Process.Kill()
-				s.loggerCmd.Wait()
-				s.loggerCmd = nil
-			}
+			// Kill the output-logger we already started; the reaper
+			// goroutine from spawnLoggerCommand collects it.
+			s.stopLoggerCommands()
 			if outputPipe != nil {
 				outputPipe.Close()
 				outputPipe = nil
@@ -1880,8 +2042,9 @@ func (s *ProcessService) startProcess() error {
 	params := process.ExecParams{
 		Command:           s.command,
 		Argv0:             s.argv0,
+		MemfdExec:         s.memfdExec,
 		WorkingDir:        s.workingDir,
-		Env:               s.buildEnv(),
+		Env:               env,
 		TermSignal:        s.termSignal,
 		OnConsole:         s.Flags.RunsOnConsole || s.Flags.StartsOnConsole,
 		UnmaskSigint:      s.Flags.UnmaskIntr,
@@ -1908,6 +2071,9 @@ func (s *ProcessService) startProcess() error {
 		CloseStdout:       s.closeStdout,
 		CloseStderr:       s.closeStderr,
 	}
+	if s.logType == LogToBuffer {
+		params.MultiOutput = bufferTee(outputPipe, s.logFile, s.logFilePerms, s.logFileUID, s.logFileGID)
+	}
 	s.Record().ApplyProcessAttrs(&params)
 
 	pid, exitCh, err := process.StartProcess(params)
@@ -2004,6 +2170,13 @@ func (s *ProcessService) startProcess() error {
 		go s.monitorProcess(exitCh)
 
 		// Arm start timeout while waiting for readiness
+		if s.startTimeout > 0 {
+			s.armTimer(s.startTimeout, timerStartTimeout)
+		}
+	} else if s.readyNotifySocket {
+		// sd_notify: READY=1 on $NOTIFY_SOCKET calls notifyReady.
+		go s.monitorProcess(exitCh)
+
 		if s.startTimeout > 0 {
 			s.armTimer(s.startTimeout, timerStartTimeout)
 		}
@@ -2120,6 +2293,25 @@ func (s *ProcessService) handleReadyNotification(ready bool) {
 	}
 }
 
+// notifyReady handles sd_notify READY=1 received on listener l, the
+// service's $NOTIFY_SOCKET. Acquires queueMu. Only the first READY=1
+// of the current start counts; later ones (sent again after a reload,
+// say) and ones from a previous start's socket are ignored.
+func (s *ProcessService) notifyReady(l *process.NotifySocketListener) {
+	s.services.queueMu.Lock()
+	defer s.services.queueMu.Unlock()
+
+	if !s.readyNotifySocket || s.notifySock != l || s.state.Load() != StateStarting || s.pid <= 0 {
+		return
+	}
+	s.cancelTimer()
+	s.services.logger.Info("Service '%s': readiness notification received", s.serviceName)
+	s.Started()
+	s.startCronIfConfigured()
+	s.startHealthCheckIfConfigured()
+	s.services.processQueuesLocked()
+}
+
 // closeReadyPipe closes the read-end of the notification pipe if open.
 func (s *ProcessService) closeReadyPipe() {
 	if s.readyPipeRead != nil {
@@ -2251,7 +2443,7 @@ func (s *ProcessService) dispatchAfterExitLocked(state ServiceState, exit proces
 				s.serviceName, exit.Status.Signal())
 		}
 
-		if s.smoothRecovery && s.CheckRestart() {
+		if s.smoothRecovery && s.canAutoRestart() {
 			// Smooth recovery: restart without notifying dependents
 			s.doingSmoothRecov = true
 			s.doSmoothRecovery()
@@ -2335,6 +2527,13 @@ func (s *ProcessService) handleTimerExpired() {
 			s.failedToStart(false, false) // Don't immediately stop, wait for process
 		}
 
+	case timerStopSequence:
+		if s.advanceStopSequence() {
+			return
+		}
+		// The last step timed out too: escalate as on stop-timeout.
+		fallthrough
+
 	case timerStopTimeout:
 		// systemd TimeoutStopFailureMode= — if the operator picked
 		// abort or kill, honour that instead of the historical
@@ -2494,7 +2693,7 @@ func (s *ProcessService) execFinishCommand(exit process.ChildExit) {
 	cmd.Env = s.buildEnv()
 
 	s.services.logger.Info("Service '%s': running finish-command", s.serviceName)
-	if err := cmd.Run(); err != nil {
+	if err := process.Run(cmd); err != nil {
 		s.services.logger.Error("Service '%s': finish-command failed: %v",
 			s.serviceName, err)
 	}
@@ -2545,7 +2744,7 @@ func (s *ProcessService) runReadyCheckOnce() bool {
 	cmd := exec.Command(s.readyCheckCommand[0], s.readyCheckCommand[1:]...)
 	cmd.Dir = s.workingDir
 	cmd.Env = s.buildEnv()
-	return cmd.Run() == nil
+	return process.Run(cmd) == nil
 }
 
 // execPreStopHook runs the pre-stop-hook before sending stop signal.
@@ -2564,7 +2763,7 @@ func (s *ProcessService) execPreStopHook() {
 	cmd.Env = s.buildEnv()
 
 	s.services.logger.Info("Service '%s': running pre-stop-hook", s.serviceName)
-	if err := cmd.Run(); err != nil {
+	if err := process.Run(cmd); err != nil {
 		s.services.logger.Error("Service '%s': pre-stop-hook failed: %v",
 			s.serviceName, err)
 	}
@@ -2586,7 +2785,7 @@ func (s *ProcessService) execControlCommand(sigName string, command []string) {
 	cmd.Env = s.buildEnv()
 
 	s.services.logger.Info("Service '%s': running control-command-%s", s.serviceName, sigName)
-	if err := cmd.Run(); err != nil {
+	if err := process.Run(cmd); err != nil {
 		s.services.logger.Error("Service '%s': control-command-%s failed: %v",
 			s.serviceName, sigName, err)
 	}
@@ -2691,7 +2890,8 @@ func spawnLoggerCommand(cmdArgs []string, svcName, label string) (*os.File, *exe
 	cmd.Stderr = nil
 	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
 
-	if err := cmd.Start(); err != nil {
+	exited, err := process.DefaultReaper.StartCmd(cmd)
+	if err != nil {
 		r.Close()
 		w.Close()
 		return nil, nil, fmt.Errorf("start %s for %s: %w", label, svcName, err)
@@ -2701,7 +2901,7 @@ func spawnLoggerCommand(cmdArgs []string, svcName, label string) (*os.File, *exe
 	r.Close()
 
 	// Reap the logger process in the background so it doesn't zombie.
-	go cmd.Wait()
+	go process.DefaultReaper.WaitCmd(cmd, exited)
 
 	return w, cmd, nil
 }
@@ -198,11 +198,11 @@ func TestRestartBackoffResetOnStable(t *testing.T) {
 		t.Fatal("expected backoff to have advanced")
 	}
 
-	// Simulate stable period: push restartIntervalTime into the past
-	svc.restartIntervalTime = time.Now().Add(-1 * time.Second)
-	svc.restartIntervalCount = 1
+	// Simulate stable period: one restart charged a second ago
+	svc.restartTokens = newRestartBucket(10, 50*time.Millisecond, time.Now().Add(-1*time.Second))
+	svc.restartTokens.tokens--
 
-	// CheckRestart should reset the backoff (elapsed > restartInterval)
+	// CheckRestart should reset the backoff (bucket refilled meanwhile)
 	if !svc.CheckRestart() {
 		t.Fatal("CheckRestart unexpectedly refused restart")
 	}
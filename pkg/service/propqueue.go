@@ -0,0 +1,73 @@
+package service
+
+import "container/heap"
+
+// Service priorities for the propagation queue (priority = N). A
+// higher number is propagated first, so a system logger or a PID
+// namespace parent settles before the services that depend on it.
+const (
+	DefaultPriority = 5
+	SystemPriority  = 10
+)
+
+// propEntry is one queued propagation: the priority is captured when
+// the service is queued, and seq keeps services of equal priority in
+// FIFO order.
+type propEntry struct {
+	svc      Service
+	priority int
+	seq      uint64
+}
+
+// priorityHeap is the propagation queue, a container/heap ordered so
+// that the highest priority, then the earliest queued, pops first.
+type priorityHeap []propEntry
+
+func (h priorityHeap) Len() int { return len(h) }
+
+func (h priorityHeap) Less(i, j int) bool {
+	if h[i].priority != h[j].priority {
+		return h[i].priority > h[j].priority
+	}
+	return h[i].seq < h[j].seq
+}
+
+func (h priorityHeap) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+
+func (h *priorityHeap) Push(x any) { *h = append(*h, x.(propEntry)) }
+
+func (h *priorityHeap) Pop() any {
+	old := *h
+	n := len(old)
+	e := old[n-1]
+	old[n-1] = propEntry{} // allow GC
+	*h = old[:n-1]
+	return e
+}
+
+// pushProp queues svc at its current priority. Caller must hold queueMu.
+func (ss *ServiceSet) pushProp(svc Service) {
+	ss.propSeq++
+	heap.Push(&ss.propQueue, propEntry{svc: svc, priority: svc.Record().priority, seq: ss.propSeq})
+}
+
+// popProp removes the next service to propagate. Caller must hold
+// queueMu and have checked the queue is not empty.
+func (ss *ServiceSet) popProp() Service {
+	return heap.Pop(&ss.propQueue).(propEntry).svc
+}
+
+// Priority returns the service's propagation priority.
+func (sr *ServiceRecord) Priority() int { return sr.priority }
+
+// SetPriority sets the service's propagation priority. A service that
+// is already queued keeps the priority it was queued with.
+func (sr *ServiceRecord) SetPriority(p int) { sr.priority = p }
+
+// SetServicePriority changes the propagation priority of svc at
+// runtime (`slinitctl set-priority`).
+func (ss *ServiceSet) SetServicePriority(svc Service, p int) {
+	ss.queueMu.Lock()
+	defer ss.queueMu.Unlock()
+	svc.Record().SetPriority(p)
+}
@@ -0,0 +1,56 @@
+package service
+
+import "testing"
+
+// orderListener records the order services reach STARTED.
+type orderListener struct{ names *[]string }
+
+func (l orderListener) ServiceEvent(svc Service, event ServiceEvent) {
+	if event == EventStarted {
+		*l.names = append(*l.names, svc.Name())
+	}
+}
+
+func TestPropagationQueuePriority(t *testing.T) {
+	set, _ := newTestSet()
+	top := NewInternalService(set, "top")
+	set.AddService(top)
+
+	var started []string
+	for _, dep := range []struct {
+		name     string
+		priority int
+	}{{"low", 1}, {"normal-a", DefaultPriority}, {"syslog", SystemPriority}, {"normal-b", DefaultPriority}} {
+		svc := NewInternalService(set, dep.name)
+		svc.SetPriority(dep.priority)
+		svc.AddListener(orderListener{&started})
+		set.AddService(svc)
+		top.Record().AddDep(svc, DepRegular)
+	}
+
+	set.StartService(top)
+	want := []string{"syslog", "normal-a", "normal-b", "low"}
+	if len(started) != len(want) {
+		t.Fatalf("started %v, want %v", started, want)
+	}
+	for i := range want {
+		if started[i] != want[i] {
+			t.Fatalf("started %v, want %v", started, want)
+		}
+	}
+}
+
+func TestPriorityHeapFIFOWithinPriority(t *testing.T) {
+	set, _ := newTestSet()
+	var names []string
+	for _, n := range []string{"a", "b", "c"} {
+		svc := NewInternalService(set, n)
+		set.pushProp(svc)
+	}
+	for len(set.propQueue) > 0 {
+		names = append(names, set.popProp().Name())
+	}
+	if len(names) != 3 || names[0] != "a" || names[1] != "b" || names[2] != "c" {
+		t.Errorf("pop order %v, want [a b c]", names)
+	}
+}
@@ -110,3 +110,50 @@ func TestDependencyViaAlias(t *testing.T) {
 		t.Errorf("main service should be STARTED, got %v", svc.State())
 	}
 }
+
+func TestSyncAliases(t *testing.T) {
+	set, _ := newTestSet()
+
+	svc := NewInternalService(set, "nginx")
+	svc.Record().SetProvides("web")
+	svc.Record().SetAliases([]string{"httpd", "webserver"})
+	set.AddService(svc)
+
+	for _, alias := range []string{"web", "httpd", "webserver"} {
+		if set.FindService(alias, false) != svc {
+			t.Errorf("alias %q should resolve to nginx", alias)
+		}
+	}
+
+	svc.Record().SetAliases([]string{"httpd", "www"})
+	set.SyncAliases(svc)
+	if set.FindService("webserver", false) != nil {
+		t.Error("removed alias should no longer resolve")
+	}
+	if set.FindService("www", false) != svc || set.FindService("web", false) != svc {
+		t.Error("current aliases should resolve to nginx")
+	}
+
+	got := set.ListAliases()
+	if len(got) != 3 || got["httpd"] != "nginx" || got["www"] != "nginx" || got["web"] != "nginx" {
+		t.Errorf("ListAliases() = %v", got)
+	}
+}
+
+func TestSyncAliasesKeepsOtherClaims(t *testing.T) {
+	set, _ := newTestSet()
+
+	a := NewInternalService(set, "a")
+	a.Record().SetAliases([]string{"shared"})
+	set.AddService(a)
+	b := NewInternalService(set, "b")
+	b.Record().SetAliases([]string{"shared"})
+	set.AddService(b)
+
+	// Dropping a's aliases must not remove b's claim on "shared".
+	a.Record().SetAliases(nil)
+	set.SyncAliases(a)
+	if set.FindService("shared", false) != b {
+		t.Error("alias claimed by another service was removed")
+	}
+}
@@ -1,6 +1,8 @@
 package service
 
 import (
+	"net"
+	"os"
 	"testing"
 	"time"
 )
@@ -125,3 +127,80 @@ func TestProcessServiceWithoutReadyNotification(t *testing.T) {
 	set.StopService(svc)
 	time.Sleep(500 * time.Millisecond)
 }
+
+// sendNotify sends an sd_notify datagram to the service's $NOTIFY_SOCKET.
+func sendNotify(t *testing.T, svc *ProcessService, msg string) {
+	t.Helper()
+	set := svc.services
+	set.queueMu.RLock()
+	path := svc.notifySocketPath()
+	set.queueMu.RUnlock()
+	if path == "" {
+		t.Fatal("no notify socket")
+	}
+	conn, err := net.Dial("unixgram", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	if _, err := conn.Write([]byte(msg)); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestReadyNotifySocket(t *testing.T) {
+	if err := os.MkdirAll("/run/slinit/notify", 0755); err != nil {
+		t.Skipf("cannot create notify socket directory: %v", err)
+	}
+	set, _ := newTestSet()
+
+	svc := NewProcessService(set, "sdnotify-svc")
+	svc.SetCommand([]string{"/bin/sleep", "60"})
+	svc.SetReadyNotifySocket(true)
+	svc.SetStartTimeout(5 * time.Second)
+	set.AddService(svc)
+
+	set.StartService(svc)
+	defer set.StopService(svc)
+
+	time.Sleep(100 * time.Millisecond)
+	if svc.State() != StateStarting {
+		t.Fatalf("expected STARTING while waiting for READY=1, got %v", svc.State())
+	}
+
+	// Anything but READY=1 leaves it starting.
+	sendNotify(t, svc, "STATUS=warming up\n")
+	time.Sleep(100 * time.Millisecond)
+	if svc.State() != StateStarting {
+		t.Fatalf("expected STARTING after STATUS=, got %v", svc.State())
+	}
+
+	sendNotify(t, svc, "READY=1\n")
+	deadline := time.Now().Add(2 * time.Second)
+	for svc.State() != StateStarted && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if svc.State() != StateStarted {
+		t.Errorf("expected STARTED after READY=1, got %v", svc.State())
+	}
+}
+
+func TestReadyNotifySocketTimeout(t *testing.T) {
+	if err := os.MkdirAll("/run/slinit/notify", 0755); err != nil {
+		t.Skipf("cannot create notify socket directory: %v", err)
+	}
+	set, _ := newTestSet()
+
+	svc := NewProcessService(set, "sdnotify-timeout-svc")
+	svc.SetCommand([]string{"/bin/sleep", "60"})
+	svc.SetReadyNotifySocket(true)
+	svc.SetStartTimeout(300 * time.Millisecond)
+	set.AddService(svc)
+
+	set.StartService(svc)
+
+	time.Sleep(1100 * time.Millisecond)
+	if state := svc.State(); state != StateStopped && state != StateStopping {
+		t.Errorf("expected STOPPED or STOPPING after timeout, got %v", state)
+	}
+}
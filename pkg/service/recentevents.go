@@ -0,0 +1,129 @@
+package service
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// maxRecentEvents bounds the daemon-wide event history kept for
+// `slinitctl events`.
+const maxRecentEvents = 1000
+
+// GlobalEvent is one service state change, as recorded in the
+// daemon-wide event history.
+type GlobalEvent struct {
+	Time    time.Time
+	Service string
+	Event   ServiceEvent
+	Details string // e.g. "exit code 1", "signal 9"; empty if none
+}
+
+// GlobalEventListener is notified of every service event in the set,
+// regardless of which service it concerns.
+type GlobalEventListener interface {
+	GlobalServiceEvent(ev GlobalEvent)
+}
+
+// recentEventLog is a fixed-size ring of the most recent events. It is
+// registered as a global listener when the set is created.
+type recentEventLog struct {
+	mu     sync.Mutex
+	events []GlobalEvent
+	next   int // slot the next event is written to once the ring is full
+}
+
+// GlobalServiceEvent implements GlobalEventListener.
+func (l *recentEventLog) GlobalServiceEvent(ev GlobalEvent) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if len(l.events) < maxRecentEvents {
+		l.events = append(l.events, ev)
+		return
+	}
+	l.events[l.next] = ev
+	l.next = (l.next + 1) % maxRecentEvents
+}
+
+// RecentEvents returns up to max of the most recent service events,
+// oldest first. max <= 0 returns the whole history.
+func (ss *ServiceSet) RecentEvents(max int) []GlobalEvent {
+	l := ss.recentEvents
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	n := len(l.events)
+	if max <= 0 || max > n {
+		max = n
+	}
+	out := make([]GlobalEvent, 0, max)
+	for i := n - max; i < n; i++ {
+		out = append(out, l.events[(l.next+i)%n])
+	}
+	return out
+}
+
+// AddGlobalEventListener registers a listener for every service event.
+func (ss *ServiceSet) AddGlobalEventListener(l GlobalEventListener) {
+	ss.eventsMu.Lock()
+	defer ss.eventsMu.Unlock()
+	ss.globalListeners = append(ss.globalListeners, l)
+}
+
+// RemoveGlobalEventListener unregisters a global event listener.
+func (ss *ServiceSet) RemoveGlobalEventListener(l GlobalEventListener) {
+	ss.eventsMu.Lock()
+	defer ss.eventsMu.Unlock()
+	for i, existing := range ss.globalListeners {
+		if existing == l {
+			last := len(ss.globalListeners) - 1
+			ss.globalListeners[i] = ss.globalListeners[last]
+			ss.globalListeners[last] = nil
+			ss.globalListeners = ss.globalListeners[:last]
+			return
+		}
+	}
+}
+
+// recordGlobalEvent stamps an event for svc and hands it to the global
+// listeners (the recent-event history among them) outside the lock.
+func (ss *ServiceSet) recordGlobalEvent(svc Service, event ServiceEvent) {
+	ev := GlobalEvent{
+		Time:    time.Now(),
+		Service: svc.Name(),
+		Event:   event,
+		Details: eventDetails(svc, event),
+	}
+	ss.publishGlobalEvent(svc, ev)
+}
+
+// publishGlobalEvent hands a stamped event of svc to every global
+// listener and to the event bus.
+func (ss *ServiceSet) publishGlobalEvent(svc Service, ev GlobalEvent) {
+	ss.eventBus.Publish(svc, EventMessage{Service: ev.Service, Event: ev.Event, Time: ev.Time, Details: ev.Details, Svc: svc})
+
+	ss.eventsMu.Lock()
+	snapshot := make([]GlobalEventListener, len(ss.globalListeners))
+	copy(snapshot, ss.globalListeners)
+	ss.eventsMu.Unlock()
+	for _, l := range snapshot {
+		l.GlobalServiceEvent(ev)
+	}
+}
+
+// eventDetails describes how the service's process ended for stop and
+// failure events.
+func eventDetails(svc Service, event ServiceEvent) string {
+	if event != EventStopped && event != EventFailedStart {
+		return ""
+	}
+	es := svc.GetExitStatus()
+	switch {
+	case es.ExecFailed:
+		return fmt.Sprintf("exec failed (stage %d, errno %d)", es.ExecStage, es.ExecErrno)
+	case es.Exited():
+		return fmt.Sprintf("exit code %d", es.ExitCode())
+	case es.Signaled():
+		return fmt.Sprintf("signal %d", es.Signal())
+	}
+	return ""
+}
@@ -0,0 +1,80 @@
+package service
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRecentEventsRecorded(t *testing.T) {
+	set, _ := newTestSet()
+	svc := NewInternalService(set, "svc")
+	set.AddService(svc)
+
+	set.StartService(svc)
+	set.StopService(svc)
+
+	events := set.RecentEvents(0)
+	if len(events) != 2 {
+		t.Fatalf("got %d events, want 2: %+v", len(events), events)
+	}
+	if events[0].Service != "svc" || events[0].Event != EventStarted {
+		t.Errorf("first event = %+v, want svc STARTED", events[0])
+	}
+	if events[1].Event != EventStopped {
+		t.Errorf("second event = %+v, want STOPPED", events[1])
+	}
+	if events[0].Time.IsZero() || events[1].Time.Before(events[0].Time) {
+		t.Errorf("events not timestamped in order: %v, %v", events[0].Time, events[1].Time)
+	}
+}
+
+func TestRecentEventsRingWraps(t *testing.T) {
+	set, _ := newTestSet()
+	base := time.Unix(1000, 0)
+	for i := 0; i < maxRecentEvents+5; i++ {
+		set.recentEvents.GlobalServiceEvent(GlobalEvent{Time: base.Add(time.Duration(i) * time.Second)})
+	}
+
+	all := set.RecentEvents(0)
+	if len(all) != maxRecentEvents {
+		t.Fatalf("history holds %d events, want %d", len(all), maxRecentEvents)
+	}
+	if want := base.Add(5 * time.Second); !all[0].Time.Equal(want) {
+		t.Errorf("oldest kept event at %v, want %v", all[0].Time, want)
+	}
+	for i := 1; i < len(all); i++ {
+		if !all[i].Time.After(all[i-1].Time) {
+			t.Fatalf("events out of order at %d", i)
+		}
+	}
+
+	last := set.RecentEvents(3)
+	if len(last) != 3 || !last[2].Time.Equal(all[len(all)-1].Time) {
+		t.Errorf("RecentEvents(3) = %+v, want the 3 newest", last)
+	}
+}
+
+type captureGlobalListener struct{ events []GlobalEvent }
+
+func (l *captureGlobalListener) GlobalServiceEvent(ev GlobalEvent) {
+	l.events = append(l.events, ev)
+}
+
+func TestGlobalEventListener(t *testing.T) {
+	set, _ := newTestSet()
+	svc := NewInternalService(set, "svc")
+	set.AddService(svc)
+
+	l := &captureGlobalListener{}
+	set.AddGlobalEventListener(l)
+	set.StartService(svc)
+	set.RemoveGlobalEventListener(l)
+	set.StopService(svc)
+
+	if len(l.events) != 1 || l.events[0].Event != EventStarted {
+		t.Errorf("listener got %+v, want only the STARTED event", l.events)
+	}
+	if n := len(set.RecentEvents(0)); n != 2 {
+		t.Errorf("history should still record both events, got %d", n)
+	}
+}
@@ -40,6 +40,7 @@ type Service interface {
 	InterruptStart() bool
 	BecomingInactive()
 	CheckRestart() bool
+	NotifyShutdownPending() // shutdown is about to stop the service
 
 	// Process info (for process-based services; defaults return -1/{})
 	PID() int
@@ -85,20 +86,38 @@ type ServiceRecord struct {
 	self        Service // pointer back to the implementing Service
 	serviceName string
 	serviceDir  string // directory where service description was found
+	embedded    bool   // description came from the built-in embedded set
 	description string // human-readable description for status/list output
 	author      string // upstart-style metadata (informational)
 	version     string
 	usage       string
 	recordType  ServiceType
 
+	// plain waits-for.d directories, absolute (see WaitsForDirs)
+	waitsForDirs []string
+
 	// State (atomic: written under queueMu.Lock, read lockless)
 	state   atomicServiceState
 	desired atomicServiceState
 
+	// priority orders the propagation queue (priority = N); higher
+	// goes first. See propqueue.go.
+	priority int
+
 	// Flags
 	autoRestart    AutoRestartMode
 	smoothRecovery bool
 	manualStart    bool // upstart-style: refuse all auto-activation
+
+	// autoRestartOverride is the runtime override set by
+	// `slinitctl no-restart` / `enable-restart`: nil uses autoRestart.
+	// Cleared on reload.
+	autoRestartOverride *AutoRestartMode
+
+	// killed is set by KillService and keeps auto-restart off until the
+	// next explicit Start.
+	killed bool
+
 	// systemd-style RefuseManualStart / RefuseManualStop. Reject the
 	// direct control-socket path only — dependency-driven activation
 	// or teardown is still allowed. See connection.handleStartService /
@@ -137,6 +156,7 @@ type ServiceRecord struct {
 	haveConsole         bool
 	startExplicit       bool
 	waitingForStartSlot bool // waiting for start limiter slot
+	highConsolePriority bool // queue for the console ahead of normal waiters
 
 	// Propagation flags
 	propRequire bool
@@ -154,6 +174,16 @@ type ServiceRecord struct {
 	inAutoRestart bool
 	inUserRestart bool
 
+	// Cumulative start/failure counters (see failurestats.go).
+	failureStats FailureStats
+	// consecutiveFailureThreshold (failure-action-threshold) is the
+	// number of consecutive start failures after which auto-restart is
+	// suppressed; 0 disables the check.
+	consecutiveFailureThreshold int
+	// restartSuppressed is set when the threshold trips and cleared by
+	// ResetFailed.
+	restartSuppressed bool
+
 	// Loading
 	isLoading bool
 
@@ -178,6 +208,14 @@ type ServiceRecord struct {
 	// Process settings (shared across service types)
 	termSignal   syscall.Signal
 	reloadSignal syscall.Signal // 0 = unset; sent by `slinitctl reload-signal`
+	// stopSignalSeq replaces termSignal + stop-timeout when set
+	// (stop-signal-sequence, see stopsequence.go).
+	stopSignalSeq []StopSignalStep
+
+	// notify-shutdown-signal / notify-shutdown-delay: sent ahead of a
+	// shutdown and how long to wait after it (see shutdownnotify.go).
+	notifyShutdownSignal syscall.Signal // 0 = unset
+	notifyShutdownDelay  time.Duration
 	socketPath   string         // primary socket path (for backwards compat)
 	socketPaths  []string       // all socket-listen paths (for multiple sockets)
 	socketPerms  int
@@ -188,6 +226,7 @@ type ServiceRecord struct {
 
 	// Service alias (alternative name for lookup)
 	provides string
+	aliases  []string // further alternative names (aliases setting)
 
 	// Enable-via: default "from" service for enable/disable commands
 	enableVia string
@@ -322,6 +361,14 @@ type ServiceRecord struct {
 	// PR_SET_NO_NEW_PRIVS when any knob is set.
 	hardening HardeningConfig
 
+	// exec-retry-count / exec-retry-interval: retry policy for transient
+	// fork/exec failures of the start (see execretry.go).
+	execRetryCount    int
+	execRetryInterval time.Duration
+	execErr           error                  // fork/exec error of the last failed BringUp
+	execRetries       process.ExecRetryState // retries made for this start
+	execRetryTimer    *time.Timer            // pending retry, nil if none
+
 	// Bucket B — legacy-safe niches. Each is a small runner-side apply
 	// (coredumpFilter, timerSlackNsec, memoryKSM, personality,
 	// ignoreSIGPIPE) plus one master-side cleanup (removeIPC) and one
@@ -361,10 +408,15 @@ type ServiceRecord struct {
 
 	// Bucket D — env + credential pipeline. Flat fields, none share
 	// cluster semantics with each other.
+	configEnv          []string // KEY=VALUE from `env` lines
 	passEnvironment    []string
 	passEnvSet         bool
 	unsetEnvironment   []string
 	execSearchPath     string
+	ldPreload          []string
+	ldLibraryPath      []string
+	preloadCheck       bool
+	preloadWarned      bool // warnForeignPreloads already ran for ldPreload
 	standardInput      []byte
 	standardInputSet   bool
 	openFiles          []OpenFileRecord
@@ -383,6 +435,13 @@ type ServiceRecord struct {
 	ttyVTDisallocate bool
 	ttyReset         bool
 
+	// stdin / stderr settings, forwarded as ExecParams.StdinRedirect
+	// and StderrRedirect.
+	stdinRedirect  process.StdinMode
+	stdinPath      string
+	stderrRedirect process.StderrMode
+	stderrPath     string
+
 	// Queue membership flags
 	InPropQueue bool
 	InStopQueue bool
@@ -397,10 +456,36 @@ type ServiceRecord struct {
 	// Used by protocol v6 to detect stale configurations.
 	loadModTime time.Time
 
+	// Which record of this name the set holds: bumped each time a
+	// reload with a type change replaces the record, so handles to an
+	// older one can be told apart. See ServiceSet.CurrentGeneration.
+	generation atomic.Uint64
+
 	// Boot timing timestamps
 	startRequestTime time.Time // when doStart() was called
 	startedTime      time.Time // when Started() was called (reached STARTED)
 	stoppedTime      time.Time // when Stopped() was called (reached STOPPED)
+	bringDownTime    time.Time // when BringDown() was called; zero once STOPPED
+
+	// stopDurations holds the most recent BringDown→STOPPED times
+	// (at most stopHistorySize), used to estimate shutdown time.
+	stopDurations []time.Duration
+
+	// Operator notes from `slinitctl annotate` (protected by
+	// annotationsMu; see annotations.go).
+	annotationsMu sync.Mutex
+	annotations   []Annotation
+
+	// Application-defined stop reason from SetCustomStopReason
+	// (protected by customStopMu; see stopreason.go). customStopCode
+	// is 0 when none is set.
+	customStopMu     sync.Mutex
+	customStopCode   StoppedReason
+	customStopReason string
+
+	// Error output of the last failed start, where captured
+	// (capture-start-error); protected by customStopMu.
+	lastErrorMessage string
 
 	// Pre-start fail-fast path checks (OpenRC-inspired):
 	// BringUp refuses to start the service if any of these paths is missing.
@@ -423,6 +508,15 @@ type ServiceRecord struct {
 	// re-entering initiateStart.
 	restartLimitExhausted bool
 
+	// restart-backoff: once the restart limit is exhausted, retry after
+	// a delay that doubles each time (see restartbackoff.go).
+	restartBackoff  RestartBackoff
+	backoffMax      time.Duration // 0 = defaultBackoffMax
+	backoffReset    time.Duration // 0 = defaultBackoffReset
+	backoffAttempts int           // retries since the service last ran for backoffReset
+	backoffTimer    *time.Timer   // pending retry, nil if none
+	backoffNext     time.Time     // when backoffTimer fires
+
 	// startedEmitted collapses redundant Started() calls within one
 	// session so the boot console shows exactly one "[ OK ] name"
 	// per successful start, even when multiple readiness paths race
@@ -443,6 +537,10 @@ type ServiceRecord struct {
 	// identical services doesn't all hit the ceiling simultaneously.
 	runtimeMaxExtra time.Duration
 
+	// stopDeadlineTimer SIGKILLs the process if the service has not
+	// stopped by its shutdown deadline (see stopdeadline.go).
+	stopDeadlineTimer *time.Timer
+
 	// jobTimeout is systemd's JobTimeoutSec=: a hard cap on the whole
 	// start job (waiting-for-deps + own start-timeout). It fires even
 	// when start-timeout=0 or the service is stuck waiting on a slow
@@ -459,6 +557,12 @@ type ServiceRecord struct {
 	oomPolicy OOMPolicy
 	oomWatch  *oomWatcher
 
+	// watchFile is a file whose removal while STARTED fails the
+	// service (watch-file); fileWatch is its watcher, armed in
+	// Started() and cancelled in Stopped().
+	watchFile string
+	fileWatch *fileWatcher
+
 	// PSI pressure watches (cgroup v2, systemd-parity). Each threshold
 	// is the stall time within a fixed 2s window that must be exceeded
 	// before a SERVICEEVENT is emitted. A zero threshold when the
@@ -505,6 +609,7 @@ func NewServiceRecord(self Service, set *ServiceSet, name string, recordType Ser
 		recordType:  recordType,
 		autoRestart: RestartNever,
 		termSignal:  syscall.SIGTERM,
+		priority:    DefaultPriority,
 		services:    set,
 	}
 	sr.state.Store(StateStopped)
@@ -517,6 +622,8 @@ func NewServiceRecord(self Service, set *ServiceSet, name string, recordType Ser
 func (sr *ServiceRecord) Name() string             { return sr.serviceName }
 func (sr *ServiceRecord) ServiceDir() string       { return sr.serviceDir }
 func (sr *ServiceRecord) SetServiceDir(dir string) { sr.serviceDir = dir }
+func (sr *ServiceRecord) Embedded() bool           { return sr.embedded }
+func (sr *ServiceRecord) SetEmbedded(e bool)       { sr.embedded = e }
 func (sr *ServiceRecord) Description() string      { return sr.description }
 func (sr *ServiceRecord) SetDescription(d string)  { sr.description = d }
 func (sr *ServiceRecord) Author() string           { return sr.author }
@@ -547,6 +654,13 @@ func (sr *ServiceRecord) RequiredFiles() []string { return sr.requiredFiles }
 // RequiredDirs returns the configured list of required directories.
 func (sr *ServiceRecord) RequiredDirs() []string { return sr.requiredDirs }
 
+// WaitsForDirs returns the plain waits-for.d directories of the service,
+// made absolute. slinitctl enable persists into the first of them.
+func (sr *ServiceRecord) WaitsForDirs() []string { return sr.waitsForDirs }
+
+// SetWaitsForDirs records the service's waits-for.d directories.
+func (sr *ServiceRecord) SetWaitsForDirs(dirs []string) { sr.waitsForDirs = dirs }
+
 // SetPredicates records the systemd-style start preconditions for this
 // service. Copies the slice so the caller may reuse it.
 func (sr *ServiceRecord) SetPredicates(preds []Predicate) {
@@ -712,8 +826,8 @@ func (sr *ServiceRecord) FDStore() *process.FDStore { return sr.fdStore }
 
 // OnNotify implements process.NotifySocketHandler — the listener
 // goroutine calls it for every sd_notify packet. FDSTORE=1 messages
-// stash their attached fds; other messages are currently logged-only
-// (READY=1 is handled via the legacy pipe path in ProcessService).
+// stash their attached fds; STATUS= is logged. READY=1 is routed to
+// the service by notifyHandler.
 func (sr *ServiceRecord) OnNotify(msg process.NotifyMessage, fds []*os.File) {
 	if msg.FDStore && sr.fdStore != nil {
 		for _, f := range fds {
@@ -749,10 +863,31 @@ func (sr *ServiceRecord) setupNotifySocket(uid, gid uint32) (string, error) {
 		return "", err
 	}
 	sr.notifySock = l
-	l.Start(sr)
+	l.Start(notifyHandler{sr: sr, l: l})
 	return l.Path(), nil
 }
 
+// notifyHandler feeds one listener's packets to its record. READY=1
+// goes to the service's notifyReady on a goroutine of its own: that
+// takes queueMu, which Stopped holds while it waits for the listener
+// to finish. l lets notifyReady ignore a packet from a listener an
+// earlier start left behind.
+type notifyHandler struct {
+	sr *ServiceRecord
+	l  *process.NotifySocketListener
+}
+
+func (h notifyHandler) OnNotify(msg process.NotifyMessage, fds []*os.File) {
+	if msg.Ready {
+		if r, ok := h.sr.self.(interface {
+			notifyReady(*process.NotifySocketListener)
+		}); ok {
+			go r.notifyReady(h.l)
+		}
+	}
+	h.sr.OnNotify(msg, fds)
+}
+
 // teardownNotifySocket stops the listener if running.
 func (sr *ServiceRecord) teardownNotifySocket() {
 	if sr.notifySock != nil {
@@ -927,6 +1062,20 @@ func (sr *ServiceRecord) CheckPredicates() (PredicateOutcome, string) {
 	return PredOK, ""
 }
 
+// preExecCheck runs process.PreExecCheck on params unless disabled with
+// --skip-preexec-checks, logging the specific problem. Returns false if
+// the start must be aborted.
+func (sr *ServiceRecord) preExecCheck(params process.ExecParams) bool {
+	if sr.services.skipPreExecChecks {
+		return true
+	}
+	if err := process.PreExecCheck(params); err != nil {
+		sr.services.logger.Error("Service '%s': pre-exec check failed: %v", sr.serviceName, err)
+		return false
+	}
+	return true
+}
+
 // CheckRequiredPaths verifies all configured required paths exist. Returns
 // the first error encountered with a clear message suitable for logging.
 // The check is deliberately read-only: it does not create or modify anything.
@@ -968,10 +1117,10 @@ func (sr *ServiceRecord) CheckRequiredPaths() error {
 }
 func (sr *ServiceRecord) LoadModTime() time.Time      { return sr.loadModTime }
 func (sr *ServiceRecord) SetLoadModTime(t time.Time)  { sr.loadModTime = t }
+func (sr *ServiceRecord) Generation() uint64          { return sr.generation.Load() }
 func (sr *ServiceRecord) Type() ServiceType           { return sr.recordType }
 func (sr *ServiceRecord) State() ServiceState         { return sr.state.Load() }
 func (sr *ServiceRecord) TargetState() ServiceState   { return sr.desired.Load() }
-func (sr *ServiceRecord) StopReason() StoppedReason   { return sr.stopReason }
 func (sr *ServiceRecord) RequiredBy() int             { return sr.requiredBy }
 func (sr *ServiceRecord) Dependencies() []*ServiceDep { return sr.dependsOn }
 func (sr *ServiceRecord) Dependents() []*ServiceDep   { return sr.dependents }
@@ -1018,6 +1167,7 @@ func (sr *ServiceRecord) RemoveListener(l ServiceListener) {
 // --- Setters ---
 
 func (sr *ServiceRecord) SetAutoRestart(mode AutoRestartMode) { sr.autoRestart = mode }
+
 func (sr *ServiceRecord) SetSmoothRecovery(v bool)            { sr.smoothRecovery = v }
 func (sr *ServiceRecord) SetManualStart(v bool)               { sr.manualStart = v }
 func (sr *ServiceRecord) SetRefuseManualStart(v bool)         { sr.refuseManualStart = v }
@@ -1085,9 +1235,23 @@ func (sr *ServiceRecord) SetTermSignal(sig syscall.Signal)   { sr.termSignal = s
 func (sr *ServiceRecord) SetReloadSignal(sig syscall.Signal) { sr.reloadSignal = sig }
 func (sr *ServiceRecord) ReloadSignal() syscall.Signal       { return sr.reloadSignal }
 
+// SetNotifyShutdown sets the signal sent when a shutdown is pending and
+// how long the shutdown waits after sending it.
+func (sr *ServiceRecord) SetNotifyShutdown(sig syscall.Signal, delay time.Duration) {
+	sr.notifyShutdownSignal = sig
+	sr.notifyShutdownDelay = delay
+}
+
 func (sr *ServiceRecord) SetFlags(flags ServiceFlags) { sr.Flags = flags }
+
+// SetHighConsolePriority makes the service jump the console queue
+// (console-priority = high).
+func (sr *ServiceRecord) SetHighConsolePriority(high bool) { sr.highConsolePriority = high }
+func (sr *ServiceRecord) HighConsolePriority() bool         { return sr.highConsolePriority }
 func (sr *ServiceRecord) SetProvides(name string)     { sr.provides = name }
 func (sr *ServiceRecord) Provides() string            { return sr.provides }
+func (sr *ServiceRecord) SetAliases(names []string)   { sr.aliases = names }
+func (sr *ServiceRecord) Aliases() []string           { return sr.aliases }
 func (sr *ServiceRecord) SetEnableVia(name string)    { sr.enableVia = name }
 func (sr *ServiceRecord) EnableVia() string           { return sr.enableVia }
 
@@ -1264,8 +1428,20 @@ func (sr *ServiceRecord) DidStartFail() bool      { return sr.startFailed }
 // ResetFailed clears the startFailed flag so subsequent status queries
 // no longer report the service as failed. Mirrors systemd's
 // `systemctl reset-failed`. No-op on a service that isn't marked failed.
+//
+// It also re-enables auto-restart after failure-action-threshold
+// tripped, clears the consecutive failure count, refills the
+// restart-limit-count budget, and forgets restart-backoff retries,
+// dropping a pending one.
 func (sr *ServiceRecord) ResetFailed() {
 	sr.startFailed = false
+	sr.restartSuppressed = false
+	sr.failureStats.ConsecutiveFailures = 0
+	sr.cancelBackoffRetry()
+	sr.backoffAttempts = 0
+	if r, ok := sr.self.(restartLimitResetter); ok {
+		r.resetRestartLimit()
+	}
 }
 func (sr *ServiceRecord) WasStartSkipped() bool   { return sr.startSkipped }
 func (sr *ServiceRecord) IsLoading() bool         { return sr.isLoading }
@@ -1316,22 +1492,25 @@ func (sr *ServiceRecord) BuildEnvSlice() []string {
 	return result
 }
 
-// BuildFullEnv returns global daemon env + per-service extraEnv combined.
-// Used by service types that don't have their own env-file (e.g., scripted).
+// BuildFullEnv returns global daemon env + `env` lines + per-service
+// extraEnv combined. Used by service types that don't have their own
+// env-file (e.g., scripted).
 func (sr *ServiceRecord) BuildFullEnv() []string {
 	globalEnv := sr.services.GlobalEnv()
 	extra := sr.BuildEnvSlice()
-	if len(globalEnv) == 0 && len(extra) == 0 {
+	if len(globalEnv) == 0 && len(sr.configEnv) == 0 && len(extra) == 0 {
 		return nil
 	}
-	result := make([]string, 0, len(globalEnv)+len(extra))
+	result := make([]string, 0, len(globalEnv)+len(sr.configEnv)+len(extra))
 	result = append(result, globalEnv...)
+	result = append(result, sr.configEnv...)
 	result = append(result, extra...)
 	return sr.applyBucketDEnvFilters(result)
 }
 
-// BuildEnvWithFile returns global env + env-file vars + per-service extraEnv
-// with a single pre-allocated slice. Used by ProcessService and BGProcessService.
+// BuildEnvWithFile returns global env + env-file vars + `env` lines +
+// per-service extraEnv with a single pre-allocated slice; later entries
+// win. Used by ProcessService and BGProcessService.
 func (sr *ServiceRecord) BuildEnvWithFile(envFile string) []string {
 	globalEnv := sr.services.GlobalEnv()
 	extra := sr.BuildEnvSlice()
@@ -1346,7 +1525,7 @@ func (sr *ServiceRecord) BuildEnvWithFile(envFile string) []string {
 		}
 	}
 
-	totalCap := len(globalEnv) + len(fileEnv) + len(extra)
+	totalCap := len(globalEnv) + len(fileEnv) + len(sr.configEnv) + len(extra)
 	if totalCap == 0 {
 		return nil
 	}
@@ -1355,6 +1534,7 @@ func (sr *ServiceRecord) BuildEnvWithFile(envFile string) []string {
 	for k, v := range fileEnv {
 		env = append(env, k+"="+v)
 	}
+	env = append(env, sr.configEnv...)
 	env = append(env, extra...)
 	return sr.applyBucketDEnvFilters(env)
 }
@@ -1452,7 +1632,6 @@ func (sr *ServiceRecord) SetSlice(s string)                           { sr.slice
 func (sr *ServiceRecord) Slice() string                               { return sr.slice }
 func (sr *ServiceRecord) SetCgroupSettings(s []process.CgroupSetting) { sr.cgroupSettings = s }
 func (sr *ServiceRecord) SetRlimits(rl []process.Rlimit)              { sr.rlimits = rl }
-func (sr *ServiceRecord) AddRlimit(rl process.Rlimit)                 { sr.rlimits = append(sr.rlimits, rl) }
 func (sr *ServiceRecord) SetAmbientCaps(caps []uintptr)               { sr.ambientCaps = caps }
 func (sr *ServiceRecord) SetBoundingCaps(caps []uintptr)              { sr.boundingCaps = caps }
 func (sr *ServiceRecord) SetSecurebits(bits uint32)                   { sr.securebits = bits }
@@ -1587,6 +1766,13 @@ func (sr *ServiceRecord) Hardening() HardeningConfig { return sr.hardening }
 // HardeningActive reports whether any Restrict*/Protect* knob is set.
 func (sr *ServiceRecord) HardeningActive() bool { return sr.hardening.Active() }
 
+// SetExecRetry sets the exec retry policy (exec-retry-count /
+// exec-retry-interval). count <= 1 disables retrying.
+func (sr *ServiceRecord) SetExecRetry(count int, interval time.Duration) {
+	sr.execRetryCount = count
+	sr.execRetryInterval = interval
+}
+
 // Bucket B setters + accessors.
 func (sr *ServiceRecord) SetCoredumpFilter(s string)      { sr.coredumpFilter = s }
 func (sr *ServiceRecord) SetTimerSlackNsec(n int64)       { sr.timerSlackNsec = n }
@@ -1719,6 +1905,13 @@ type OpenFileRecord struct {
 	Options string
 }
 
+// SetConfigEnv sets the KEY=VALUE pairs from the description's `env`
+// lines. They override env-file and are overridden by setenv.
+func (sr *ServiceRecord) SetConfigEnv(env []string) { sr.configEnv = env }
+
+// ConfigEnv returns the description's `env` pairs.
+func (sr *ServiceRecord) ConfigEnv() []string { return sr.configEnv }
+
 // Bucket D setters + accessors.
 func (sr *ServiceRecord) SetPassEnvironment(names []string, set bool) {
 	sr.passEnvironment = names
@@ -1726,11 +1919,24 @@ func (sr *ServiceRecord) SetPassEnvironment(names []string, set bool) {
 }
 func (sr *ServiceRecord) SetUnsetEnvironment(names []string) { sr.unsetEnvironment = names }
 func (sr *ServiceRecord) SetExecSearchPath(p string)         { sr.execSearchPath = p }
+func (sr *ServiceRecord) SetLDPreload(libs []string) {
+	sr.ldPreload = libs
+	sr.preloadWarned = false
+}
+func (sr *ServiceRecord) SetLDLibraryPath(dirs []string) { sr.ldLibraryPath = dirs }
+func (sr *ServiceRecord) SetPreloadSecurityCheck(v bool) { sr.preloadCheck = v }
 func (sr *ServiceRecord) SetStandardInput(data []byte, set bool) {
 	sr.standardInput = data
 	sr.standardInputSet = set
 }
 func (sr *ServiceRecord) SetOpenFiles(files []OpenFileRecord)  { sr.openFiles = files }
+
+// SetStdioRedirects sets the stdin and stderr redirections; the paths
+// are used for StdinFile and StderrFile.
+func (sr *ServiceRecord) SetStdioRedirects(in process.StdinMode, inPath string, errMode process.StderrMode, errPath string) {
+	sr.stdinRedirect, sr.stdinPath = in, inPath
+	sr.stderrRedirect, sr.stderrPath = errMode, errPath
+}
 func (sr *ServiceRecord) SetImportCredentials(pats []string)   { sr.importCredentials = pats }
 func (sr *ServiceRecord) SetNotifyAccess(n NotifyAccess, set bool) {
 	sr.notifyAccess = n
@@ -1905,11 +2111,32 @@ func (sr *ServiceRecord) EffectiveCgroupPath() string {
 	return sr.services.DefaultCgroupPath()
 }
 
+// warnForeignPreloads warns, once per ld-preload setting, when a service
+// running as the unprivileged uid preloads a library it does not own:
+// root-owned objects included, since the service's user can neither
+// update nor vouch for them. uid 0 means no run-as and is not checked.
+func (sr *ServiceRecord) warnForeignPreloads(uid uint32) {
+	if uid == 0 || sr.preloadWarned || len(sr.ldPreload) == 0 {
+		return
+	}
+	sr.preloadWarned = true
+	for _, lib := range sr.ldPreload {
+		if owner, ok := process.PreloadOwner(lib); ok && owner != uid {
+			sr.services.warnf("Service '%s': runs as uid %d but preloads %s, owned by uid %d",
+				sr.serviceName, uid, lib, owner)
+		}
+	}
+}
+
 // ApplyProcessAttrs fills ExecParams with process attributes from this record.
 func (sr *ServiceRecord) ApplyProcessAttrs(params *process.ExecParams) {
 	params.Nice = sr.nice
 	params.OOMScoreAdj = sr.oomScoreAdj
 	params.NoNewPrivs = sr.noNewPrivs
+	params.LDPreload = sr.ldPreload
+	params.LDLibraryPath = sr.ldLibraryPath
+	params.PreloadSecurityCheck = sr.preloadCheck
+	sr.warnForeignPreloads(params.RunAsUID)
 	params.IOPrioClass = sr.ioPrioClass
 	params.IOPrioLevel = sr.ioPrioLevel
 	params.CgroupPath = sr.EffectiveCgroupPath()
@@ -2007,6 +2234,10 @@ func (sr *ServiceRecord) ApplyProcessAttrs(params *process.ExecParams) {
 		// see it mutated by the child's stdin pipe writer goroutine.
 		params.StdinBytes = append([]byte(nil), sr.standardInput...)
 	}
+	params.StdinRedirect = sr.stdinRedirect
+	params.StdinPath = sr.stdinPath
+	params.StderrRedirect = sr.stderrRedirect
+	params.StderrPath = sr.stderrPath
 	if len(sr.openFiles) > 0 {
 		ofs := make([]process.OpenFileEntry, len(sr.openFiles))
 		for i, f := range sr.openFiles {
@@ -2068,6 +2299,7 @@ func (sr *ServiceRecord) Start() {
 	if sr.markedDown {
 		sr.markedDown = false
 	}
+	sr.killed = false
 
 	if !sr.startExplicit {
 		sr.requiredBy++
@@ -2167,6 +2399,25 @@ func (sr *ServiceRecord) ForcedStop() {
 	}
 }
 
+// KillService stops the service and keeps it down: unlike ForcedStop,
+// which leaves desired state alone so a restart = always service comes
+// straight back, it drops the explicit activation, marks the service
+// desired-stopped and suppresses auto-restart until the next explicit
+// Start. Caller must hold queueMu; ServiceSet.KillService also sends
+// SIGKILL instead of waiting for a graceful exit.
+func (sr *ServiceRecord) KillService() {
+	sr.killed = true
+	if sr.startExplicit {
+		sr.startExplicit = false
+		sr.Release(false)
+	}
+	sr.desired.Store(StateStopped)
+	sr.ForcedStop()
+}
+
+// Killed reports whether KillService is in effect.
+func (sr *ServiceRecord) Killed() bool { return sr.killed }
+
 // PinStart pins the service in started state.
 // SetMarkedDown sets the down-file marker.
 func (sr *ServiceRecord) SetMarkedDown(v bool) { sr.markedDown = v }
@@ -2367,6 +2618,7 @@ func (sr *ServiceRecord) ExecuteTransition() {
 	} else if sr.state.Load() == StateStopping {
 		if sr.stopCheckDependents() {
 			sr.waitingForDeps = false
+			sr.bringDownTime = time.Now()
 			sr.self.BringDown()
 		}
 	}
@@ -2375,6 +2627,8 @@ func (sr *ServiceRecord) ExecuteTransition() {
 // --- Internal state machine helpers ---
 
 func (sr *ServiceRecord) notifyListeners(event ServiceEvent) {
+	sr.services.recordGlobalEvent(sr.self, event)
+
 	sr.listenerMu.Lock()
 	n := len(sr.listeners)
 	if n == 0 {
@@ -2451,11 +2705,18 @@ func (sr *ServiceRecord) doStart() {
 
 func (sr *ServiceRecord) initiateStart() {
 	sr.startFailed = false
+	sr.cancelBackoffRetry()
+	sr.cancelExecRetry()
+	sr.execErr = nil
+	sr.execRetries = process.ExecRetryState{}
+	sr.clearCustomStopReason()
 	// Clear the per-session Started()-emitted flag so the next
 	// successful start emits its own boot-console line.
 	sr.startedEmitted = false
 	sr.startSkipped = false
 	sr.startRequestTime = time.Now()
+	sr.services.recordBootEvent(BootEventServiceStart, sr.serviceName)
+	sr.failureStats.TotalStarts++
 	sr.state.Store(StateStarting)
 	sr.waitingForDeps = true
 
@@ -2522,8 +2783,7 @@ func (sr *ServiceRecord) allDepsStarted() {
 				sr.services.queueMu.Lock()
 				sr.waitingForStartSlot = false
 				if !sr.self.BringUp() {
-					sr.state.Store(StateStopping)
-					sr.failedToStart(false, true)
+					sr.bringUpFailed()
 				}
 				sr.services.processQueuesLocked()
 				sr.services.queueMu.Unlock()
@@ -2533,8 +2793,7 @@ func (sr *ServiceRecord) allDepsStarted() {
 	}
 
 	if !sr.self.BringUp() {
-		sr.state.Store(StateStopping)
-		sr.failedToStart(false, true)
+		sr.bringUpFailed()
 	}
 }
 
@@ -2565,6 +2824,8 @@ func (sr *ServiceRecord) Started() {
 	}
 
 	sr.startedTime = time.Now()
+	sr.failureStats.ConsecutiveFailures = 0
+	sr.services.recordBootEvent(BootEventServiceStarted, sr.serviceName)
 
 	// systemd StartupAllowedCPUs= / StartupAllowedMemoryNodes= — after
 	// the service reaches Started, retune the cgroup cpuset to the
@@ -2592,6 +2853,9 @@ func (sr *ServiceRecord) Started() {
 	if sr.Flags.LogReady && !sr.services.LogReady() {
 		sr.services.SetLogReady()
 		sr.services.logger.Info("Logging system is now ready (service '%s')", sr.serviceName)
+		if sr.services.OnLogReady != nil {
+			sr.services.OnLogReady()
+		}
 	}
 
 	sr.services.logger.ServiceStarted(sr.serviceName)
@@ -2606,6 +2870,9 @@ func (sr *ServiceRecord) Started() {
 	// Start the cgroup OOM watcher (if oom-policy demands it).
 	sr.armOOMWatcher()
 
+	// Watch the watch-file (if any) for removal.
+	sr.armFileWatch()
+
 	// Arm the cgroup v2 PSI pressure watches (if any pressure-watch is
 	// enabled). No-op when no cgroup path is set — pressure files live
 	// under the cgroup tree.
@@ -2628,6 +2895,7 @@ func (sr *ServiceRecord) Started() {
 // Stopped is called when the service has actually stopped.
 func (sr *ServiceRecord) Stopped() {
 	sr.stoppedTime = time.Now()
+	sr.noteStopDuration()
 
 	// Disarm the runtime-max-sec timer (if any). Safe to call when no
 	// timer was armed (service never reached STARTED, or runtime-max
@@ -2639,9 +2907,15 @@ func (sr *ServiceRecord) Stopped() {
 	// the timer to fire and race a re-start.
 	sr.cancelJobTimeoutTimer()
 
+	// The process is gone; no deadline left to enforce.
+	sr.cancelStopDeadline()
+
 	// Cancel the OOM watcher (if armed). Idempotent — nil-safe.
 	sr.cancelOOMWatcher()
 
+	// Cancel the watch-file watcher (if armed). Idempotent — nil-safe.
+	sr.cancelFileWatch()
+
 	// Cancel any armed PSI pressure watchers. Idempotent — nil-safe.
 	sr.cancelPSIWatcher()
 
@@ -2669,6 +2943,7 @@ func (sr *ServiceRecord) Stopped() {
 	}
 
 	sr.forceStop = false
+	sr.noteBackoffRun()
 
 	willRestart := sr.desired.Load() == StateStarted && !sr.pinnedStopped
 	// restart-limit-count exhausted overrides willRestart: settle into
@@ -2678,14 +2953,22 @@ func (sr *ServiceRecord) Stopped() {
 		sr.startFailed = true
 		sr.desired.Store(StateStopped)
 		sr.restartLimitExhausted = false
-		sr.services.logger.Error(
-			"Service '%s': restart-limit-count exhausted, marking failed",
-			sr.serviceName)
+		if sr.restartBackoff == BackoffExponential {
+			// restart-backoff: not giving up, so no start-limit-action.
+			d := sr.armBackoffRetry()
+			sr.services.logger.Error(
+				"Service '%s': restart-limit-count exhausted, marking failed, retrying in %v",
+				sr.serviceName, d)
+		} else {
+			sr.services.logger.Error(
+				"Service '%s': restart-limit-count exhausted, marking failed",
+				sr.serviceName)
+		}
 		// systemd StartLimitAction=: escalate a rate-limit failure into a
 		// system-level action (reboot/kexec/etc). Fires here — not from
 		// chooseStoppedAction — so it's independent of the failure-action
 		// path and always triggers when the operator asked for it.
-		if act := sr.startLimitAction; act != ActionNone {
+		if act := sr.startLimitAction; act != ActionNone && sr.restartBackoff != BackoffExponential {
 			if cb := sr.services.OnSystemAction; cb != nil {
 				sr.services.logger.Info(
 					"Service '%s': start-limit-action=%s — initiating system action",
@@ -2748,6 +3031,7 @@ func (sr *ServiceRecord) Stopped() {
 		// Record this as a supervisor-driven restart for heartbeat /
 		// health-signal accounting. First-boot BringUp does not count.
 		sr.services.NoteRestart()
+		sr.failureStats.TotalRestarts++
 
 		// Restart any PREPARED_BY dependencies first. They are hard deps
 		// (IsHard returns true), so startCheckDependencies in initiateStart
@@ -2854,9 +3138,11 @@ func (sr *ServiceRecord) failedToStart(depFailed bool, immediateStop bool) {
 	}
 
 	sr.startFailed = true
+	sr.services.recordBootEvent(BootEventServiceFailed, sr.serviceName)
 	sr.services.logger.ServiceFailed(sr.serviceName, depFailed)
 	sr.notifyListeners(EventFailedStart)
 	sr.pinnedStarted = false
+	sr.noteStartFailure()
 
 	if immediateStop {
 		sr.Stopped()
@@ -2877,7 +3163,10 @@ func (sr *ServiceRecord) doStop(withRestart bool) {
 	// itself, only the cascade to dependents is suppressed.
 	restartDeps := withRestart && sr.restartMode != RestartModeDirect
 
-	if !withRestart {
+	// Anything but the configured mode turning restarts off (a tripped
+	// failure-action-threshold, no-restart, kill, a stop pin, shutdown)
+	// also rules out restart-force-exit-status.
+	if !withRestart && !sr.autoRestartBlocked() {
 		// upstart-style `normal exit`: codes / signals the operator
 		// declared as success suppress respawn even with restart=yes.
 		// Apply this *before* the per-mode logic so it shadows both
@@ -2890,6 +3179,7 @@ func (sr *ServiceRecord) doStop(withRestart bool) {
 		// restart-limit-exhausted branch below and treat the service
 		// as failed instead of looping.
 		wantedRestart := false
+		mode := sr.GetEffectiveAutoRestart()
 
 		// systemd RestartForceExitStatus: codes that force a restart
 		// regardless of the `restart =` setting. Applied FIRST so
@@ -2907,13 +3197,13 @@ func (sr *ServiceRecord) doStop(withRestart bool) {
 		}
 
 		// Check for auto-restart
-		if sr.autoRestart == RestartAlways && sr.desired.Load() == StateStarted {
+		if mode == RestartAlways && sr.desired.Load() == StateStarted {
 			if !normal {
 				wantedRestart = true
 				forRestart = sr.self.CheckRestart()
 				sr.inAutoRestart = forRestart
 			}
-		} else if sr.autoRestart == RestartOnFailure && sr.desired.Load() == StateStarted {
+		} else if mode == RestartOnFailure && sr.desired.Load() == StateStarted {
 			if !normal {
 				if exitStatus.Signaled() {
 					// Don't auto-restart for administrative signals (matching dinit)
@@ -2955,7 +3245,11 @@ func (sr *ServiceRecord) doStop(withRestart bool) {
 
 	if sr.state.Load() != StateStarted {
 		if sr.state.Load() == StateStarting {
-			if !sr.waitingForDeps && !sr.waitingForConsole {
+			if sr.execRetryTimer != nil {
+				// Waiting to retry the exec: there is no process to
+				// interrupt, just drop the retry.
+				sr.cancelExecRetry()
+			} else if !sr.waitingForDeps && !sr.waitingForConsole {
 				if !sr.self.CanInterruptStart() {
 					return
 				}
@@ -3072,7 +3366,11 @@ func (sr *ServiceRecord) stopDependents(forRestart bool, restartDeps bool) bool
 
 func (sr *ServiceRecord) queueForConsole() {
 	sr.waitingForConsole = true
-	sr.services.AppendConsoleQueue(sr.self)
+	if sr.highConsolePriority {
+		sr.services.AppendHighPriorityConsoleQueue(sr.self)
+	} else {
+		sr.services.AppendConsoleQueue(sr.self)
+	}
 }
 
 func (sr *ServiceRecord) releaseConsole() {
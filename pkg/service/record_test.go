@@ -1,6 +1,8 @@
 package service
 
 import (
+	"os"
+	"path/filepath"
 	"strings"
 	"syscall"
 	"testing"
@@ -407,6 +409,42 @@ func TestGlobalEnvPropagation(t *testing.T) {
 	}
 }
 
+// env lines override env-file and are themselves overridden by setenv;
+// exec keeps the last of duplicate names, so order is precedence.
+func TestConfigEnvPrecedence(t *testing.T) {
+	set, _ := newTestSet()
+	envFile := filepath.Join(t.TempDir(), "env")
+	if err := os.WriteFile(envFile, []byte("FROM_FILE=1\nSHARED=file\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	svc := NewProcessService(set, "env-svc")
+	set.AddService(svc)
+	svc.Record().SetConfigEnv([]string{"SHARED=config", "OVERRIDDEN=config"})
+	svc.Record().SetEnvVar("OVERRIDDEN", "runtime")
+
+	env := svc.Record().BuildEnvWithFile(envFile)
+	last := make(map[string]string)
+	for _, e := range env {
+		k, v, _ := strings.Cut(e, "=")
+		last[k] = v
+	}
+	if last["FROM_FILE"] != "1" {
+		t.Errorf("FROM_FILE = %q, want 1", last["FROM_FILE"])
+	}
+	if last["SHARED"] != "config" {
+		t.Errorf("SHARED = %q, want config (env over env-file)", last["SHARED"])
+	}
+	if last["OVERRIDDEN"] != "runtime" {
+		t.Errorf("OVERRIDDEN = %q, want runtime (setenv over env)", last["OVERRIDDEN"])
+	}
+
+	full := svc.Record().BuildFullEnv()
+	if len(full) == 0 || full[0] != "SHARED=config" {
+		t.Errorf("BuildFullEnv = %q, want env lines included", full)
+	}
+}
+
 func TestGlobalEnvEmptyWhenUnset(t *testing.T) {
 	set, _ := newTestSet()
 
@@ -0,0 +1,105 @@
+package service
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// RefCountError is one broken reference-count invariant reported by
+// VerifyRefCounts.
+type RefCountError struct {
+	Service  string
+	Field    string // "requiredBy" or "startExplicit"
+	Expected int    // for startExplicit, the minimum requiredBy
+	Actual   int
+}
+
+func (e RefCountError) Error() string {
+	if e.Field == "startExplicit" {
+		return fmt.Sprintf("%s: explicitly started but requiredBy is %d (want >= %d)",
+			e.Service, e.Actual, e.Expected)
+	}
+	return fmt.Sprintf("%s: %s is %d, expected %d", e.Service, e.Field, e.Actual, e.Expected)
+}
+
+// VerifyRefCounts checks every service's requiredBy against what the
+// dependency graph says it should be: one for an explicit start plus
+// one per dependency edge holding an acquisition on it, and one per
+// fanout holding it as a member. A mismatch
+// means a Require/Release pair went missing somewhere in propagation.
+// Results are ordered by service name. Read-only; safe to call at any
+// time.
+func (ss *ServiceSet) VerifyRefCounts() []RefCountError {
+	ss.queueMu.RLock()
+	defer ss.queueMu.RUnlock()
+
+	// Placeholders and services removed from the set can still be the
+	// target of a held edge, so collect targets as well as records.
+	services := ss.ListServices()
+	seen := make(map[Service]bool, len(services))
+	for _, svc := range services {
+		seen[svc] = true
+	}
+	held := make(map[Service]int)
+	hold := func(to Service) {
+		held[to]++
+		if !seen[to] {
+			seen[to] = true
+			services = append(services, to)
+		}
+	}
+	for _, svc := range services {
+		for _, dep := range svc.Record().dependsOn {
+			if dep.HoldingAcq {
+				hold(dep.To)
+			}
+		}
+		// A started fanout holds its members without dependency edges.
+		if f, ok := svc.(*FanoutService); ok {
+			for _, m := range f.held {
+				hold(m)
+			}
+		}
+	}
+
+	var errs []RefCountError
+	for _, svc := range services {
+		sr := svc.Record()
+		if sr.startExplicit && sr.requiredBy < 1 {
+			errs = append(errs, RefCountError{
+				Service: svc.Name(), Field: "startExplicit", Expected: 1, Actual: sr.requiredBy,
+			})
+			continue
+		}
+		want := held[svc]
+		if sr.startExplicit {
+			want++
+		}
+		if sr.requiredBy != want {
+			errs = append(errs, RefCountError{
+				Service: svc.Name(), Field: "requiredBy", Expected: want, Actual: sr.requiredBy,
+			})
+		}
+	}
+	sort.Slice(errs, func(i, j int) bool { return errs[i].Service < errs[j].Service })
+	return errs
+}
+
+// VerifyRefCountsEvery runs VerifyRefCounts every interval and logs each
+// discrepancy at error level, until quit is closed. Debug aid behind
+// slinit --debug-verify-refcounts.
+func (ss *ServiceSet) VerifyRefCountsEvery(interval time.Duration, quit <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-quit:
+			return
+		case <-ticker.C:
+			for _, e := range ss.VerifyRefCounts() {
+				ss.logger.Error("refcount check: %v", e)
+			}
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package service
+
+import "testing"
+
+func assertRefCounts(t *testing.T, set *ServiceSet, when string) {
+	t.Helper()
+	for _, e := range set.VerifyRefCounts() {
+		t.Errorf("%s: %v", when, e)
+	}
+}
+
+func TestVerifyRefCountsThroughLifecycle(t *testing.T) {
+	set, _ := newTestSet()
+
+	db := NewInternalService(set, "db")
+	syslog := NewInternalService(set, "syslog")
+	app := NewInternalService(set, "app")
+	web := NewInternalService(set, "web")
+	for _, svc := range []Service{db, syslog, app, web} {
+		set.AddService(svc)
+	}
+	app.Record().AddDep(db, DepRegular)
+	app.Record().AddDep(syslog, DepWaitsFor)
+	web.Record().AddDep(app, DepRegular)
+	web.Record().AddDep(db, DepRegular)
+
+	assertRefCounts(t, set, "initial")
+
+	set.StartService(web)
+	if db.Record().RequiredBy() != 2 {
+		t.Errorf("db requiredBy = %d, want 2", db.Record().RequiredBy())
+	}
+	assertRefCounts(t, set, "after start")
+
+	set.StartService(db) // explicit on top of the acquisitions
+	assertRefCounts(t, set, "after explicit start of a dependency")
+
+	set.StopService(web)
+	assertRefCounts(t, set, "after stop")
+
+	set.StopService(db)
+	assertRefCounts(t, set, "after stopping everything")
+	for _, svc := range []Service{db, syslog, app, web} {
+		if svc.State() != StateStopped {
+			t.Errorf("%s: state = %v, want stopped", svc.Name(), svc.State())
+		}
+	}
+}
+
+func TestVerifyRefCountsReportsDrift(t *testing.T) {
+	set, _ := newTestSet()
+
+	dep := NewInternalService(set, "dep")
+	main := NewInternalService(set, "main")
+	set.AddService(dep)
+	set.AddService(main)
+	main.Record().AddDep(dep, DepRegular)
+	set.StartService(main)
+
+	dep.Record().requiredBy++ // a Require with no matching acquisition
+	main.Record().requiredBy = 0
+
+	errs := set.VerifyRefCounts()
+	if len(errs) != 2 {
+		t.Fatalf("got %v, want two errors", errs)
+	}
+	if e := errs[0]; e.Service != "dep" || e.Field != "requiredBy" || e.Expected != 1 || e.Actual != 2 {
+		t.Errorf("errs[0] = %+v", e)
+	}
+	if e := errs[1]; e.Service != "main" || e.Field != "startExplicit" || e.Actual != 0 {
+		t.Errorf("errs[1] = %+v", e)
+	}
+}
+
+// A fanout holds its members without dependency edges; those holds are
+// expected, not drift.
+func TestVerifyRefCountsFanout(t *testing.T) {
+	set, _ := newTestSet()
+
+	a := NewInternalService(set, "a")
+	b := NewInternalService(set, "b")
+	fanout := NewFanoutService(set, "fanout")
+	for _, svc := range []Service{a, b, fanout} {
+		set.AddService(svc)
+	}
+	fanout.SetMembers([]Service{a, b})
+
+	set.StartService(b)
+	set.StartService(fanout)
+	if a.State() != StateStarted {
+		t.Fatalf("a: state = %v, want started", a.State())
+	}
+	assertRefCounts(t, set, "fanout started")
+
+	set.StopService(fanout)
+	assertRefCounts(t, set, "fanout stopped")
+}
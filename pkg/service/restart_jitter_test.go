@@ -0,0 +1,56 @@
+package service
+
+import (
+	"testing"
+	"time"
+)
+
+func TestScaleJitterDisabled(t *testing.T) {
+	if d := scaleJitter(time.Second, 0); d != time.Second {
+		t.Errorf("scaleJitter(1s, 0) = %v, want 1s", d)
+	}
+	if d := scaleJitter(time.Second, -1); d != time.Second {
+		t.Errorf("scaleJitter(1s, -1) = %v, want 1s", d)
+	}
+	if d := scaleJitter(0, 0.5); d != 0 {
+		t.Errorf("scaleJitter(0, 0.5) = %v, want 0", d)
+	}
+}
+
+// TestRestartJitterFactorSpreadsDelays checks that services sharing a
+// restart-delay get delays in [base, base*(1+factor)] and do not all
+// land on the same value once --restart-jitter-factor is set.
+func TestRestartJitterFactorSpreadsDelays(t *testing.T) {
+	set, _ := newTestSet()
+	set.SetRestartJitterFactor(0.25)
+
+	const base = time.Second
+	seen := make(map[time.Duration]bool)
+	for i := 0; i < 20; i++ {
+		svc := &ProcessService{}
+		svc.services = set
+		svc.SetRestartDelay(base)
+		d := svc.nextRestartDelay()
+		if d < base || d > base+base/4 {
+			t.Fatalf("nextRestartDelay=%v out of [1s, 1.25s]", d)
+		}
+		seen[d] = true
+	}
+	if len(seen) < 2 {
+		t.Error("20 services got identical restart delays despite jitter factor")
+	}
+}
+
+func TestRestartJitterFactorRespectsMaxDelay(t *testing.T) {
+	set, _ := newTestSet()
+	set.SetRestartJitterFactor(10)
+
+	svc := NewBGProcessService(set, "bg")
+	svc.SetRestartDelay(time.Second)
+	svc.SetRestartMaxDelay(1500 * time.Millisecond)
+	for i := 0; i < 20; i++ {
+		if d := svc.nextRestartDelay(); d < time.Second || d > 1500*time.Millisecond {
+			t.Fatalf("nextRestartDelay=%v out of [1s, 1.5s]", d)
+		}
+	}
+}
@@ -0,0 +1,143 @@
+package service
+
+import (
+	"fmt"
+	"time"
+)
+
+// RestartBackoff picks what happens once restart-limit-count is
+// exhausted. With BackoffNone the service is marked failed and stays
+// stopped; with BackoffExponential it is marked failed too, but started
+// again after a delay that doubles with each exhaustion, so a service
+// that cannot come up keeps retrying at a falling rate instead of
+// giving up.
+type RestartBackoff uint8
+
+const (
+	BackoffNone        RestartBackoff = iota // give up (default)
+	BackoffExponential                       // retry after 1s, 2s, 4s, ... up to restart-backoff-max
+)
+
+func (b RestartBackoff) String() string {
+	if b == BackoffExponential {
+		return "exponential"
+	}
+	return "none"
+}
+
+// ParseRestartBackoff decodes the restart-backoff setting.
+func ParseRestartBackoff(s string) (RestartBackoff, error) {
+	switch s {
+	case "", "none", "no":
+		return BackoffNone, nil
+	case "exponential":
+		return BackoffExponential, nil
+	}
+	return 0, fmt.Errorf("unknown restart-backoff %q (use none|exponential)", s)
+}
+
+const (
+	backoffBase         = time.Second
+	defaultBackoffMax   = 5 * time.Minute
+	defaultBackoffReset = 5 * time.Minute
+)
+
+// SetBackoffPolicy sets restart-backoff, restart-backoff-max and
+// restart-backoff-reset. A zero max or reset picks the default
+// (five minutes each).
+func (sr *ServiceRecord) SetBackoffPolicy(policy RestartBackoff, max, reset time.Duration) {
+	sr.restartBackoff = policy
+	sr.backoffMax = max
+	sr.backoffReset = reset
+	if policy == BackoffNone {
+		sr.cancelBackoffRetry()
+		sr.backoffAttempts = 0
+	}
+}
+
+// BackoffPolicy returns the restart-backoff policy.
+func (sr *ServiceRecord) BackoffPolicy() RestartBackoff { return sr.restartBackoff }
+
+// NextBackoffRetry returns when a service that exhausted its restart
+// limit will be started again, or the zero time if no retry is pending.
+func (sr *ServiceRecord) NextBackoffRetry() time.Time { return sr.backoffNext }
+
+// backoffDelay returns the delay before retry number attempt (from 0):
+// one second, doubling each time, capped at restart-backoff-max.
+func (sr *ServiceRecord) backoffDelay(attempt int) time.Duration {
+	max := sr.backoffMax
+	if max <= 0 {
+		max = defaultBackoffMax
+	}
+	d := backoffBase
+	for i := 0; i < attempt && d < max; i++ {
+		d *= 2
+	}
+	return min(d, max)
+}
+
+// noteBackoffRun forgets earlier backoff retries once a run of the
+// service has lasted restart-backoff-reset: it recovered, and the next
+// exhaustion starts over from the shortest delay. Called from Stopped.
+func (sr *ServiceRecord) noteBackoffRun() {
+	if sr.backoffAttempts == 0 || sr.startedTime.Before(sr.startRequestTime) {
+		return
+	}
+	reset := sr.backoffReset
+	if reset <= 0 {
+		reset = defaultBackoffReset
+	}
+	if sr.stoppedTime.Sub(sr.startedTime) >= reset {
+		sr.backoffAttempts = 0
+	}
+}
+
+// armBackoffRetry schedules the next start of a service that has just
+// exhausted its restart limit. The timer runs in its own goroutine and
+// takes queueMu; by then the operator may have started, pinned or
+// unloaded the service, or the system may be shutting down, in which
+// case the retry is dropped.
+func (sr *ServiceRecord) armBackoffRetry() time.Duration {
+	sr.cancelBackoffRetry()
+	d := sr.backoffDelay(sr.backoffAttempts)
+	sr.backoffAttempts++
+	sr.backoffNext = time.Now().Add(d)
+	svc := sr.self
+	set := sr.services
+	var t *time.Timer
+	t = time.AfterFunc(d, func() {
+		set.queueMu.Lock()
+		defer set.queueMu.Unlock()
+		rec := svc.Record()
+		if rec.backoffTimer != t {
+			return
+		}
+		rec.backoffTimer = nil
+		rec.backoffNext = time.Time{}
+		if svc.State() != StateStopped || rec.pinnedStopped || set.IsShuttingDown() ||
+			set.FindService(svc.Name(), false) != svc {
+			return
+		}
+		set.logger.Info("Service '%s': restart-backoff retry %d", svc.Name(), rec.backoffAttempts)
+		svc.Start()
+		set.processQueuesLocked()
+	})
+	sr.backoffTimer = t
+	return d
+}
+
+// cancelBackoffRetry drops a pending backoff retry. Safe to call when
+// none is pending.
+func (sr *ServiceRecord) cancelBackoffRetry() {
+	if sr.backoffTimer != nil {
+		sr.backoffTimer.Stop()
+		sr.backoffTimer = nil
+	}
+	sr.backoffNext = time.Time{}
+}
+
+// restartLimitResetter is implemented by service types that rate-limit
+// their restarts, so ResetFailed can refill the limit.
+type restartLimitResetter interface {
+	resetRestartLimit()
+}
@@ -0,0 +1,130 @@
+package service
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBackoffDelay(t *testing.T) {
+	set, _ := newTestSet()
+	rec := NewInternalService(set, "b").Record()
+	rec.SetBackoffPolicy(BackoffExponential, 10*time.Second, 0)
+
+	want := []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 8 * time.Second, 10 * time.Second, 10 * time.Second}
+	for i, w := range want {
+		if got := rec.backoffDelay(i); got != w {
+			t.Errorf("backoffDelay(%d) = %v, want %v", i, got, w)
+		}
+	}
+
+	rec.SetBackoffPolicy(BackoffExponential, 0, 0)
+	if got := rec.backoffDelay(100); got != defaultBackoffMax {
+		t.Errorf("backoffDelay(100) with default max = %v, want %v", got, defaultBackoffMax)
+	}
+
+	for in, want := range map[string]RestartBackoff{"": BackoffNone, "none": BackoffNone, "exponential": BackoffExponential} {
+		if got, err := ParseRestartBackoff(in); err != nil || got != want {
+			t.Errorf("ParseRestartBackoff(%q) = %v, %v; want %v", in, got, err, want)
+		}
+	}
+	if _, err := ParseRestartBackoff("linear"); err == nil {
+		t.Error("ParseRestartBackoff(linear): expected error")
+	}
+}
+
+// exhaustRestartLimit drives svc through the restart-limit-exhausted
+// branch of Stopped, as doStop leaves it when CheckRestart denies a
+// wanted restart.
+func exhaustRestartLimit(set *ServiceSet, svc Service) {
+	set.queueMu.Lock()
+	defer set.queueMu.Unlock()
+	rec := svc.Record()
+	rec.desired.Store(StateStarted)
+	rec.restartLimitExhausted = true
+	rec.state.Store(StateStopping)
+	rec.Stopped()
+	set.processQueuesLocked()
+}
+
+func TestRestartBackoffRetries(t *testing.T) {
+	set, _ := newTestSet()
+	svc := NewInternalService(set, "flaky")
+	set.AddService(svc)
+	rec := svc.Record()
+	rec.SetBackoffPolicy(BackoffExponential, 20*time.Millisecond, 0)
+
+	exhaustRestartLimit(set, svc)
+	if svc.State() != StateStopped || !rec.DidStartFail() {
+		t.Fatalf("after exhaustion: state %v, failed %v; want STOPPED and failed", svc.State(), rec.DidStartFail())
+	}
+	set.View(func() {
+		if rec.NextBackoffRetry().IsZero() {
+			t.Error("no backoff retry scheduled")
+		}
+	})
+
+	deadline := time.Now().Add(2 * time.Second)
+	for svc.State() != StateStarted {
+		if time.Now().After(deadline) {
+			t.Fatal("service was not retried")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	set.View(func() {
+		if rec.DidStartFail() {
+			t.Error("failed mark survived a successful retry")
+		}
+		if rec.backoffAttempts != 1 {
+			t.Errorf("backoffAttempts = %d, want 1", rec.backoffAttempts)
+		}
+	})
+}
+
+func TestRestartBackoffNoneGivesUp(t *testing.T) {
+	set, _ := newTestSet()
+	svc := NewInternalService(set, "gives-up")
+	set.AddService(svc)
+
+	exhaustRestartLimit(set, svc)
+	set.View(func() {
+		if !svc.Record().NextBackoffRetry().IsZero() {
+			t.Error("retry scheduled without restart-backoff")
+		}
+	})
+}
+
+func TestResetFailedCancelsBackoff(t *testing.T) {
+	set, _ := newTestSet()
+	svc := NewInternalService(set, "reset")
+	set.AddService(svc)
+	rec := svc.Record()
+	rec.SetBackoffPolicy(BackoffExponential, time.Hour, 0)
+
+	exhaustRestartLimit(set, svc)
+	exhaustRestartLimit(set, svc)
+	set.Apply(rec.ResetFailed)
+
+	set.View(func() {
+		if !rec.NextBackoffRetry().IsZero() || rec.backoffAttempts != 0 || rec.DidStartFail() {
+			t.Errorf("after reset-failed: next %v, attempts %d, failed %v; want all cleared",
+				rec.NextBackoffRetry(), rec.backoffAttempts, rec.DidStartFail())
+		}
+	})
+}
+
+func TestResetFailedRefillsRestartLimit(t *testing.T) {
+	set, _ := newTestSet()
+	svc := NewProcessService(set, "limited")
+	svc.SetRestartLimits(time.Hour, 1)
+
+	if !svc.CheckRestart() {
+		t.Fatal("first restart denied")
+	}
+	if svc.CheckRestart() {
+		t.Fatal("second restart allowed with restart-limit-count = 1")
+	}
+	svc.ResetFailed()
+	if !svc.CheckRestart() {
+		t.Error("restart denied after reset-failed")
+	}
+}
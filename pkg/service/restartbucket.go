@@ -0,0 +1,74 @@
+package service
+
+import "time"
+
+// tokenBucket rate-limits automatic restarts (restart-limit-count /
+// restart-limit-interval). It holds up to capacity tokens, one spent
+// per restart, and earns them back continuously at refillRate per
+// second, so an empty bucket regains its full capacity over one
+// restart-limit-interval. Unlike counting restarts per fixed window
+// this never lets a burst at the end of one window run on into the
+// start of the next: at most capacity restarts happen in any span of
+// one interval, plus the ones earned back meanwhile.
+type tokenBucket struct {
+	tokens     float64
+	lastRefill time.Time
+	capacity   float64
+	refillRate float64 // tokens per second
+}
+
+// newRestartBucket returns a full bucket allowing count restarts per
+// interval.
+func newRestartBucket(count int, interval time.Duration, now time.Time) tokenBucket {
+	return tokenBucket{
+		tokens:     float64(count),
+		lastRefill: now,
+		capacity:   float64(count),
+		refillRate: float64(count) / interval.Seconds(),
+	}
+}
+
+// refillTokens adds the tokens earned since the last refill.
+func (b *tokenBucket) refillTokens(now time.Time) {
+	if elapsed := now.Sub(b.lastRefill).Seconds(); elapsed > 0 {
+		b.tokens += elapsed * b.refillRate
+		if b.tokens > b.capacity {
+			b.tokens = b.capacity
+		}
+	}
+	b.lastRefill = now
+}
+
+// full reports whether no restart has been charged for a whole
+// interval, as of the last refill.
+func (b *tokenBucket) full() bool { return b.tokens >= b.capacity }
+
+// consumeRestartToken refills the bucket and spends one token, or
+// returns false if less than one is left.
+func (b *tokenBucket) consumeRestartToken(now time.Time) bool {
+	b.refillTokens(now)
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+// checkRestartLimit applies restart-limit-count restarts per
+// restart-limit-interval through bucket, setting it up on first use
+// (a zero bucket). rested reports that the service had gone a full
+// interval without a restart, which resets progressive backoff. A
+// non-positive count or interval means no limit; with no interval to
+// rest over, rested is always false and the backoff keeps growing.
+func checkRestartLimit(bucket *tokenBucket, count int, interval time.Duration) (ok, rested bool) {
+	if count <= 0 || interval <= 0 {
+		return true, false
+	}
+	now := time.Now()
+	if bucket.capacity == 0 {
+		*bucket = newRestartBucket(count, interval, now)
+	}
+	bucket.refillTokens(now)
+	rested = bucket.full()
+	return bucket.consumeRestartToken(now), rested
+}
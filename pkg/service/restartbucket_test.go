@@ -0,0 +1,95 @@
+package service
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRestartBucketPreventsBoundaryBurst(t *testing.T) {
+	// 3 restarts per 3s. A fixed window would allow 3 restarts just
+	// before the window ends and 3 more right after it.
+	now := time.Unix(1000, 0)
+	b := newRestartBucket(3, 3*time.Second, now)
+
+	now = now.Add(2900 * time.Millisecond)
+	for i := 0; i < 3; i++ {
+		if !b.consumeRestartToken(now) {
+			t.Fatalf("restart %d refused with a full bucket", i+1)
+		}
+	}
+	now = now.Add(200 * time.Millisecond)
+	if b.consumeRestartToken(now) {
+		t.Fatal("burst across the interval boundary was allowed")
+	}
+
+	// One token is earned back per second.
+	now = now.Add(time.Second)
+	if !b.consumeRestartToken(now) {
+		t.Fatal("restart refused after earning a token")
+	}
+	if b.consumeRestartToken(now) {
+		t.Fatal("second restart allowed with no token left")
+	}
+}
+
+func TestRestartBucketRefillCapped(t *testing.T) {
+	now := time.Unix(1000, 0)
+	b := newRestartBucket(2, time.Second, now)
+	b.consumeRestartToken(now)
+	b.refillTokens(now.Add(time.Hour))
+	if b.tokens != 2 || !b.full() {
+		t.Errorf("tokens = %v, want capped at 2", b.tokens)
+	}
+}
+
+func TestCheckRestartRateLimited(t *testing.T) {
+	set, _ := newTestSet()
+	svc := NewProcessService(set, "burst")
+	svc.SetRestartLimits(time.Minute, 3)
+
+	for i := 0; i < 3; i++ {
+		if !svc.CheckRestart() {
+			t.Fatalf("restart %d refused", i+1)
+		}
+	}
+	if svc.CheckRestart() {
+		t.Error("fourth restart within the interval was allowed")
+	}
+
+	// Changing the limits starts over with a full bucket.
+	svc.SetRestartLimits(time.Minute, 1)
+	if !svc.CheckRestart() {
+		t.Error("restart refused after resetting the limits")
+	}
+}
+
+func TestCheckRestartUnlimited(t *testing.T) {
+	set, _ := newTestSet()
+	svc := NewBGProcessService(set, "unlimited")
+	svc.SetRestartLimits(time.Minute, 0)
+	for i := 0; i < 100; i++ {
+		if !svc.CheckRestart() {
+			t.Fatalf("restart %d refused with no limit", i+1)
+		}
+	}
+}
+
+// With the restart limit disabled there is no interval to rest over, so
+// progressive backoff must keep growing rather than reset each restart.
+func TestCheckRestartUnlimitedKeepsBackoff(t *testing.T) {
+	set, _ := newTestSet()
+	svc := NewProcessService(set, "flappy")
+	svc.SetRestartDelay(200 * time.Millisecond)
+	svc.SetRestartBackoff(500*time.Millisecond, 0)
+	svc.SetRestartLimits(time.Minute, 0)
+
+	want := []time.Duration{200 * time.Millisecond, 700 * time.Millisecond, 1200 * time.Millisecond, 1700 * time.Millisecond}
+	for i, w := range want {
+		if !svc.CheckRestart() {
+			t.Fatalf("restart %d refused with no limit", i+1)
+		}
+		if got := svc.nextRestartDelay(); got != w {
+			t.Errorf("restart %d: delay = %v, want %v", i+1, got, w)
+		}
+	}
+}
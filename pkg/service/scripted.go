@@ -1,7 +1,9 @@
 package service
 
 import (
+	"bytes"
 	"os"
+	"strings"
 	"syscall"
 	"time"
 
@@ -47,9 +49,17 @@ type ScriptedService struct {
 	logFileUID   int
 	logFileGID   int
 
+	// capture-start-error: stderr of the running start command, for
+	// the failure message (nil when not capturing)
+	captureStartError bool
+	startErrBuf       *bytes.Buffer
+
 	// Monitoring
 	doneCh        chan struct{}
 	timerUpdateCh chan struct{} // signaled when a new timer is armed
+
+	// Continuous health checking (while STARTED)
+	healthChecker *HealthChecker
 }
 
 type scriptedTimerPurpose uint8
@@ -88,6 +98,11 @@ func (s *ScriptedService) SetStartCommand(cmd []string) { s.startCommand = cmd }
 // SetStopCommand sets the stop command.
 func (s *ScriptedService) SetStopCommand(cmd []string) { s.stopCommand = cmd }
 
+// SetCaptureStartError makes a failed start command's stderr part of the
+// failure message and the service's last error message
+// (capture-start-error).
+func (s *ScriptedService) SetCaptureStartError(capture bool) { s.captureStartError = capture }
+
 // SetWorkingDir sets the working directory.
 func (s *ScriptedService) SetWorkingDir(dir string) { s.workingDir = dir }
 
@@ -119,6 +134,65 @@ func (s *ScriptedService) effectiveRunAsGID() uint32 {
 	return s.runAsGID
 }
 
+// SetHealthCheck configures the health check run while the service is
+// STARTED. Once maxFailures consecutive checks fail, the service is
+// stopped as failed and restarted if its restart policy allows. A nil
+// cmd removes the health check.
+func (s *ScriptedService) SetHealthCheck(cmd []string, interval, delay time.Duration,
+	maxFailures int, unhealthyCmd []string) {
+	running := false
+	if s.healthChecker != nil {
+		running = s.State() == StateStarted
+		s.healthChecker.Stop()
+		s.healthChecker = nil
+	}
+	if len(cmd) == 0 {
+		return
+	}
+	// The checker calls onFail from its own goroutine, which Stop waits
+	// for; take queueMu on a fresh goroutine so a BringDown holding the
+	// lock while stopping the checker cannot deadlock against it.
+	onFail := func() { go s.healthCheckFailed() }
+	s.healthChecker = NewHealthChecker(s, cmd, interval, delay, maxFailures, unhealthyCmd,
+		s.services.logger, onFail)
+	if running {
+		s.startHealthCheck()
+	}
+}
+
+// HealthStatus reports the health checker state (HealthReporter).
+func (s *ScriptedService) HealthStatus() (HealthStatus, bool) {
+	if s.healthChecker == nil {
+		return HealthStatus{}, false
+	}
+	return s.healthChecker.Status(), true
+}
+
+// startHealthCheck starts the health checker, if configured, with the
+// service's working directory and credentials.
+func (s *ScriptedService) startHealthCheck() {
+	if s.healthChecker == nil {
+		return
+	}
+	s.healthChecker.SetExecContext(s.workingDir, s.effectiveRunAsUID(),
+		s.effectiveRunAsGID(), s.supplementaryGIDs)
+	s.services.logger.Info("Service '%s': starting health check (interval=%v, max-failures=%d)",
+		s.serviceName, s.healthChecker.interval, s.healthChecker.maxFailures)
+	s.healthChecker.Start()
+}
+
+// healthCheckFailed is the checker's onFail callback; acquires queueMu.
+func (s *ScriptedService) healthCheckFailed() {
+	s.services.queueMu.Lock()
+	defer s.services.queueMu.Unlock()
+	if s.State() != StateStarted {
+		return
+	}
+	s.services.logger.Error("Service '%s': unhealthy, stopping", s.serviceName)
+	s.failHealthCheck()
+	s.services.processQueuesLocked()
+}
+
 // SetLogType sets the log output type.
 func (s *ScriptedService) SetLogType(lt LogType) { s.logType = lt }
 
@@ -162,6 +236,9 @@ func (s *ScriptedService) SetStartTimeout(d time.Duration) { s.startTimeout = d
 // SetStopTimeout sets the stop command timeout.
 func (s *ScriptedService) SetStopTimeout(d time.Duration) { s.stopTimeout = d }
 
+// StopTimeout returns the stop command timeout (0 = wait forever).
+func (s *ScriptedService) StopTimeout() time.Duration { return s.stopTimeout }
+
 // PID returns the PID of the currently running command (start or stop).
 func (s *ScriptedService) PID() int {
 	// See ProcessService.PID() — same reentrancy rationale.
@@ -176,8 +253,17 @@ func (s *ScriptedService) BringUp() bool {
 	if len(s.startCommand) == 0 {
 		// No start command = started immediately (like internal)
 		s.Started()
+		s.startHealthCheck()
 		return true
 	}
+	if !s.preExecCheck(process.ExecParams{
+		Command:    s.startCommand,
+		WorkingDir: s.workingDir,
+		RunAsUID:   s.runAsUID,
+		RunAsGID:   s.runAsGID,
+	}) {
+		return false
+	}
 
 	// Dynamic-user allocation (#13). Mirrors ProcessService.BringUp:
 	// happens before any UID-dependent setup.
@@ -236,18 +322,12 @@ func (s *ScriptedService) BringUp() bool {
 		}
 		outputPipe = s.outputPipeW
 	} else if s.logType == LogToFile && s.logFile != "" {
-		// O_NOFOLLOW + fchown-via-fd: close the symlink-swap TOCTOU
-		// where root slinit could be tricked into writing/chowning an
-		// attacker-pointed path.
-		f, err := os.OpenFile(s.logFile, os.O_WRONLY|os.O_CREATE|os.O_APPEND|syscall.O_NOFOLLOW, os.FileMode(s.logFilePerms))
+		f, err := openLogFile(s.logFile, os.FileMode(s.logFilePerms), s.logFileUID, s.logFileGID)
 		if err != nil {
 			s.services.logger.Error("Service '%s': failed to open logfile '%s': %v",
 				s.serviceName, s.logFile, err)
 			return false
 		}
-		if s.logFileUID >= 0 || s.logFileGID >= 0 {
-			_ = f.Chown(s.logFileUID, s.logFileGID)
-		}
 		outputPipe = f
 	}
 
@@ -274,6 +354,14 @@ func (s *ScriptedService) BringUp() bool {
 		OutputPipe:        outputPipe,
 		InputPipe:         inputPipe,
 	}
+	if s.logType == LogToBuffer {
+		params.MultiOutput = bufferTee(outputPipe, s.logFile, s.logFilePerms, s.logFileUID, s.logFileGID)
+	}
+	s.startErrBuf = nil
+	if s.captureStartError {
+		s.startErrBuf = new(bytes.Buffer)
+		params.ErrorCapture = s.startErrBuf
+	}
 	s.Record().ApplyProcessAttrs(&params)
 
 	pid, exitCh, err := process.StartProcess(params)
@@ -285,6 +373,7 @@ func (s *ScriptedService) BringUp() bool {
 		}
 		s.services.logger.Error("Service '%s': failed to run start command: %v",
 			s.serviceName, err)
+		s.Record().execErr = err
 		return false
 	}
 
@@ -319,6 +408,10 @@ func (s *ScriptedService) BringUp() bool {
 
 // BringDown runs the stop command.
 func (s *ScriptedService) BringDown() {
+	if s.healthChecker != nil {
+		s.healthChecker.Stop()
+	}
+
 	if len(s.stopCommand) == 0 {
 		// No stop command = stopped immediately
 		s.Stopped()
@@ -471,6 +564,7 @@ func (s *ScriptedService) handleStartExit(exit process.ChildExit) {
 		}
 		// Start command succeeded
 		s.Started()
+		s.startHealthCheck()
 		s.services.processQueuesLocked()
 	} else {
 		// Start command failed
@@ -478,8 +572,14 @@ func (s *ScriptedService) handleStartExit(exit process.ChildExit) {
 		if exit.Exited() {
 			exitCode = exit.Status.ExitStatus()
 		}
-		s.services.logger.Error("Service '%s': start command failed (exit code: %d)",
-			s.serviceName, exitCode)
+		if msg := s.takeStartError(); msg != "" {
+			s.services.logger.Error("Service '%s': start command failed (exit code: %d): %q",
+				s.serviceName, exitCode, msg)
+			s.SetLastErrorMessage(msg)
+		} else {
+			s.services.logger.Error("Service '%s': start command failed (exit code: %d)",
+				s.serviceName, exitCode)
+		}
 		if s.stopReason != ReasonTimedOut {
 			s.stopReason = ReasonFailed
 		}
@@ -488,6 +588,18 @@ func (s *ScriptedService) handleStartExit(exit process.ChildExit) {
 	}
 }
 
+// takeStartError returns the captured stderr of the start command that
+// just exited, trimmed, and stops holding on to it. Only valid once the
+// exit has been delivered.
+func (s *ScriptedService) takeStartError() string {
+	if s.startErrBuf == nil {
+		return ""
+	}
+	msg := strings.TrimSpace(s.startErrBuf.String())
+	s.startErrBuf = nil
+	return msg
+}
+
 // handleStopExit processes stop-command termination. Runs in the
 // monitorStop goroutine; acquires queueMu.
 func (s *ScriptedService) handleStopExit(exit process.ChildExit) {
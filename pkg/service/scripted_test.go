@@ -120,3 +120,37 @@ func TestScriptedServiceWithDependency(t *testing.T) {
 		t.Errorf("dependency should be STOPPED, got %v", dep.State())
 	}
 }
+
+func TestScriptedServiceCaptureStartError(t *testing.T) {
+	set, _ := newTestSet()
+
+	svc := NewScriptedService(set, "capture-svc")
+	svc.SetStartCommand([]string{"/bin/sh", "-c", "echo progress; echo 'Error: connection refused' >&2; exit 1"})
+	svc.SetCaptureStartError(true)
+	set.AddService(svc)
+
+	set.StartService(svc)
+	waitStopped(svc, 5*time.Second)
+
+	if !svc.DidStartFail() {
+		t.Fatal("expected start to be marked as failed")
+	}
+	if got := svc.LastErrorMessage(); got != "Error: connection refused" {
+		t.Errorf("LastErrorMessage() = %q", got)
+	}
+}
+
+func TestScriptedServiceNoCaptureByDefault(t *testing.T) {
+	set, _ := newTestSet()
+
+	svc := NewScriptedService(set, "nocapture-svc")
+	svc.SetStartCommand([]string{"/bin/sh", "-c", "echo oops >&2; exit 1"})
+	set.AddService(svc)
+
+	set.StartService(svc)
+	waitStopped(svc, 5*time.Second)
+
+	if got := svc.LastErrorMessage(); got != "" {
+		t.Errorf("LastErrorMessage() = %q, want none without capture-start-error", got)
+	}
+}
@@ -3,11 +3,14 @@ package service
 import (
 	"fmt"
 	"net"
+	"sort"
 	"strings"
 	"sync"
 	"sync/atomic"
 	"syscall"
 	"time"
+
+	"github.com/sunlightlinux/slinit/pkg/process"
 )
 
 // ServiceLogger is the interface for logging service events.
@@ -44,11 +47,18 @@ type EnvListener interface {
 type ServiceSet struct {
 	mu             sync.RWMutex
 	records        map[string]Service
-	aliases        map[string]Service // provides → service mapping
+	aliases        map[string]Service // provides/aliases → service mapping
 	activeServices int
-	restartEnabled bool
+	// restartEnabled is cleared when shutdown begins. Written under
+	// queueMu, but IsShuttingDown reads it from any goroutine.
+	restartEnabled atomic.Bool
 	shutdownType   ShutdownType
 
+	// currentGeneration is the generation of the record held under
+	// each name (absent = 0); it only grows, so a replaced record
+	// stays stale. Guarded by mu.
+	currentGeneration map[string]uint64
+
 	// queueMu protects the processing queues, console queue, and
 	// activeServices counter, plus service-state fields mutated during
 	// scheduling. It is held across entire ProcessQueues drain loops
@@ -57,12 +67,17 @@ type ServiceSet struct {
 	// ServiceActive, ServiceInactive) can be called without re-locking.
 	// Monitor goroutines (process exit, timer expiry, daemon polling)
 	// acquire it before mutating state; getters (State, PID) RLock.
+	// Control connections go through the entry points or Apply.
 	queueMu sync.RWMutex
 
 	// Processing queues
-	propQueue    []Service // propagation queue
-	stopQueue    []Service // transition/stop queue
-	consoleQueue []Service // console access queue
+	propQueue    priorityHeap // propagation queue, highest priority first
+	propSeq      uint64       // enqueue counter, FIFO among equal priorities
+	stopQueue    []Service    // transition/stop queue
+	consoleQueue []Service    // console access queue
+	// highPriorityConsoleQueue is served before consoleQueue
+	// (console-priority = high).
+	highPriorityConsoleQueue []Service
 
 	// Service loader
 	loader ServiceLoader
@@ -75,6 +90,7 @@ type ServiceSet struct {
 	bootReadyTime   time.Time     // when boot service reached STARTED
 	bootServiceName string        // name of the boot target service
 	kernelUptime    time.Duration // kernel uptime at slinit start
+	bootEvents      bootEventLog  // service events until boot is ready
 
 	// Filesystem/logging readiness flags (set by services with starts-rwfs / starts-log)
 	rwReady  bool
@@ -90,16 +106,25 @@ type ServiceSet struct {
 	OnUtmpCreate func(id, line, mode string, pid int)
 	OnUtmpClear  func(id, line string)
 	OnRWReady    func() // called when starts-rwfs service reaches STARTED
+	OnLogReady   func() // called when starts-log service reaches STARTED
 	OnBootReady  func() // called when boot service reaches STARTED (for --ready-fd)
 
 	// Path-activation hooks. The loader invokes OnServiceLoaded after a
 	// service description has been fully applied (so StartOnPath is
-	// readable); OnServiceUnloaded fires from UnloadService. Both are
-	// wired by main.go to a pkg/pathwatch.Watcher. Keeping them as
-	// callbacks avoids importing pkg/pathwatch from pkg/service.
+	// readable) and OnServiceReloaded after a successful reload, with
+	// the record the reload produced (a new one if the type changed);
+	// OnServiceUnloaded fires from UnloadService. All are wired by
+	// main.go to a pkg/pathwatch.Watcher. Keeping them as callbacks
+	// avoids importing pkg/pathwatch from pkg/service.
 	OnServiceLoaded   func(svc Service)
+	OnServiceReloaded func(oldSvc, newSvc Service)
 	OnServiceUnloaded func(svc Service)
 
+	// OnServiceRemoved fires from RemoveService, after the record has
+	// left the set. The control server wires it to revoke every
+	// connection's handles to svc so none dangle after an unload.
+	OnServiceRemoved func(svc Service)
+
 	// OnSystemAction is wired by main to the event loop's shutdown
 	// initiator. It fires when a service's configured failure-action /
 	// success-action triggers a system-level transition (reboot,
@@ -119,6 +144,18 @@ type ServiceSet struct {
 	globalEnvIdx   map[string]int // key → index in globalEnv for O(1) lookup
 	envListeners   []EnvListener
 
+	// Daemon-wide service event history and listeners notified of
+	// every service's events (slinitctl events). recentEvents is
+	// also the first entry of globalListeners. Protected by eventsMu.
+	eventsMu        sync.Mutex
+	recentEvents    *recentEventLog
+	globalListeners []GlobalEventListener
+	eventBus        *EventBus // filtered channel subscribers; own lock
+
+	// Start and stop jobs requested by control clients (see NewJob),
+	// settled by service events. Has its own lock, taken after queueMu.
+	jobs *jobTable
+
 	// Parallel start limiter (from --parallel-start-limit)
 	startLimiter *StartLimiter
 
@@ -143,6 +180,17 @@ type ServiceSet struct {
 	// Ready notification fd (from --ready-fd/-F), -1 if unset
 	readyFD int
 
+	// Daemon version, reported by PrintDiagnostics
+	version string
+
+	// Global restart jitter (from --restart-jitter-factor): every
+	// restart delay is scaled by a random factor in [1, 1+factor].
+	restartJitterFactor float64
+
+	// skipPreExecChecks (--skip-preexec-checks) turns off the
+	// process.PreExecCheck validation in BringUp.
+	skipPreExecChecks bool
+
 	// Notification channel: signaled when a service becomes inactive
 	inactiveCh chan struct{}
 
@@ -167,14 +215,20 @@ type ServiceSet struct {
 
 // NewServiceSet creates a new ServiceSet.
 func NewServiceSet(logger ServiceLogger) *ServiceSet {
-	return &ServiceSet{
+	ss := &ServiceSet{
 		records:        make(map[string]Service),
 		aliases:        make(map[string]Service),
 		sharedLogMuxes: make(map[string]*SharedLogMux),
-		restartEnabled: true,
 		logger:         logger,
 		readyFD:        -1,
+		recentEvents:   &recentEventLog{},
+		eventBus:       newEventBus(),
+		jobs:           &jobTable{jobs: make(map[uint32]*Job)},
 	}
+	ss.restartEnabled.Store(true)
+	ss.AddGlobalEventListener(ss.recentEvents)
+	ss.AddGlobalEventListener(ss.jobs)
+	return ss
 }
 
 // SetLoader sets the service loader for this set.
@@ -216,29 +270,44 @@ func (ss *ServiceSet) LoadService(name string) (Service, error) {
 func (ss *ServiceSet) GetLoader() ServiceLoader { return ss.loader }
 
 // ReplaceService atomically replaces an old service with a new one in the set.
+// Event bus subscribers see the old service removed and the new one added.
 func (ss *ServiceSet) ReplaceService(oldSvc, newSvc Service) {
 	ss.mu.Lock()
-	defer ss.mu.Unlock()
-	// Remove old alias
-	if alias := oldSvc.Record().Provides(); alias != "" {
-		delete(ss.aliases, alias)
-	}
+	ss.removeAliasesLocked(oldSvc)
 	ss.records[oldSvc.Name()] = newSvc
-	// Register new alias
-	if alias := newSvc.Record().Provides(); alias != "" {
-		ss.aliases[alias] = newSvc
+	gen := ss.currentGeneration[oldSvc.Name()] + 1
+	if ss.currentGeneration == nil {
+		ss.currentGeneration = make(map[string]uint64)
 	}
+	ss.currentGeneration[oldSvc.Name()] = gen
+	newSvc.Record().generation.Store(gen)
+	ss.addAliasesLocked(newSvc)
+	ss.mu.Unlock()
+	if ps, ok := oldSvc.(*ProcessService); ok {
+		ps.DisarmSocketActivation()
+	}
+	ss.publishMembership(oldSvc, EventRemoved)
+	ss.publishMembership(newSvc, EventAdded)
 }
 
-// AddService adds a service to the set. If the service has a provides
-// alias, it is also registered for lookup by alias name.
+// AddService adds a service to the set. Its provides alias and aliases,
+// if any, are also registered for lookup by alias name.
 func (ss *ServiceSet) AddService(svc Service) {
 	ss.mu.Lock()
-	defer ss.mu.Unlock()
 	ss.records[svc.Name()] = svc
-	if alias := svc.Record().Provides(); alias != "" {
-		ss.aliases[alias] = svc
-	}
+	ss.addAliasesLocked(svc)
+	svc.Record().generation.Store(ss.currentGeneration[svc.Name()])
+	ss.mu.Unlock()
+	ss.publishMembership(svc, EventAdded)
+}
+
+// CurrentGeneration returns the generation of the record the set holds
+// under name. A record whose Generation differs has been replaced by a
+// reload that changed the service type.
+func (ss *ServiceSet) CurrentGeneration(name string) uint64 {
+	ss.mu.RLock()
+	defer ss.mu.RUnlock()
+	return ss.currentGeneration[name]
 }
 
 // RegisterAlias registers a provides alias for a service.
@@ -248,13 +317,59 @@ func (ss *ServiceSet) RegisterAlias(alias string, svc Service) {
 	ss.aliases[alias] = svc
 }
 
+// SyncAliases makes the alias mappings for svc match its record's
+// provides and aliases: names no longer listed stop resolving to svc,
+// while svc itself stays loaded.
+func (ss *ServiceSet) SyncAliases(svc Service) {
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+	ss.removeAliasesLocked(svc)
+	ss.addAliasesLocked(svc)
+}
+
+// ListAliases returns every alias mapping as alias → service name.
+func (ss *ServiceSet) ListAliases() map[string]string {
+	ss.mu.RLock()
+	defer ss.mu.RUnlock()
+	result := make(map[string]string, len(ss.aliases))
+	for alias, svc := range ss.aliases {
+		result[alias] = svc.Name()
+	}
+	return result
+}
+
+func (ss *ServiceSet) addAliasesLocked(svc Service) {
+	rec := svc.Record()
+	if alias := rec.Provides(); alias != "" {
+		ss.aliases[alias] = svc
+	}
+	for _, alias := range rec.Aliases() {
+		ss.aliases[alias] = svc
+	}
+}
+
+// removeAliasesLocked drops every alias mapping to svc, leaving an
+// alias that has since been claimed by another service alone.
+func (ss *ServiceSet) removeAliasesLocked(svc Service) {
+	for alias, target := range ss.aliases {
+		if target == svc {
+			delete(ss.aliases, alias)
+		}
+	}
+}
+
 // RemoveService removes a service from the set.
 func (ss *ServiceSet) RemoveService(svc Service) {
 	ss.mu.Lock()
-	defer ss.mu.Unlock()
 	delete(ss.records, svc.Name())
-	if alias := svc.Record().Provides(); alias != "" {
-		delete(ss.aliases, alias)
+	ss.removeAliasesLocked(svc)
+	ss.mu.Unlock()
+	if ps, ok := svc.(*ProcessService); ok {
+		ps.DisarmSocketActivation()
+	}
+	ss.publishMembership(svc, EventRemoved)
+	if ss.OnServiceRemoved != nil {
+		ss.OnServiceRemoved(svc)
 	}
 }
 
@@ -268,6 +383,106 @@ func (ss *ServiceSet) UnloadService(svc Service) {
 	}
 }
 
+// ReloadOutcome says what ReloadAll did with one service.
+type ReloadOutcome uint8
+
+const (
+	ReloadUnchanged     ReloadOutcome = iota // description file not modified
+	ReloadApplied                            // changed; the new description is in effect
+	ReloadRestartNeeded                      // changed while started; some settings apply from the next start
+	ReloadFailed                             // the description could not be reloaded
+	ReloadSkipped                            // service in transition, not reloaded
+)
+
+func (o ReloadOutcome) String() string {
+	switch o {
+	case ReloadUnchanged:
+		return "unchanged"
+	case ReloadApplied:
+		return "reloaded"
+	case ReloadRestartNeeded:
+		return "restart needed"
+	case ReloadFailed:
+		return "failed"
+	case ReloadSkipped:
+		return "skipped"
+	default:
+		return fmt.Sprintf("outcome(%d)", o)
+	}
+}
+
+// ReloadResult is ReloadAll's report for one service.
+type ReloadResult struct {
+	Name    string
+	Outcome ReloadOutcome
+	Detail  string // error for ReloadFailed, state for ReloadSkipped
+}
+
+// ReloadCounts returns how many of results were reloaded (whether or
+// not their description changed) and how many failed.
+func ReloadCounts(results []ReloadResult) (reloaded, failed int) {
+	for _, r := range results {
+		switch r.Outcome {
+		case ReloadUnchanged, ReloadApplied, ReloadRestartNeeded:
+			reloaded++
+		case ReloadFailed:
+			failed++
+		}
+	}
+	return reloaded, failed
+}
+
+// ReloadAll re-reads the description of every loaded service that is
+// stopped or started through the loader, skipping services in
+// transition (their description may be fine, the timing is not).
+// onReplace, if non-nil, is called for each service replaced by a new
+// object (a type change). Returns one result per service, sorted by
+// name. A description counts as changed when its file or modification
+// time differs from the one last loaded; a started service with a
+// changed description keeps running with its old process settings and
+// is reported as needing a restart.
+func (ss *ServiceSet) ReloadAll(onReplace func(oldSvc, newSvc Service)) []ReloadResult {
+	if ss.loader == nil {
+		return nil
+	}
+	svcs := ss.ListServices()
+	sort.Slice(svcs, func(i, j int) bool { return svcs[i].Name() < svcs[j].Name() })
+	results := make([]ReloadResult, 0, len(svcs))
+	for _, svc := range svcs {
+		res := ReloadResult{Name: svc.Name()}
+		state := svc.State()
+		if state != StateStopped && state != StateStarted {
+			res.Outcome = ReloadSkipped
+			res.Detail = state.String()
+			results = append(results, res)
+			continue
+		}
+		oldDir := svc.Record().ServiceDir()
+		oldMod := svc.Record().LoadModTime()
+		newSvc, err := ss.loader.ReloadService(svc)
+		if err != nil {
+			res.Outcome = ReloadFailed
+			res.Detail = err.Error()
+			results = append(results, res)
+			continue
+		}
+		switch {
+		case newSvc.Record().ServiceDir() == oldDir && newSvc.Record().LoadModTime().Equal(oldMod):
+			res.Outcome = ReloadUnchanged
+		case state == StateStarted:
+			res.Outcome = ReloadRestartNeeded
+		default:
+			res.Outcome = ReloadApplied
+		}
+		results = append(results, res)
+		if newSvc != svc && onReplace != nil {
+			onReplace(svc, newSvc)
+		}
+	}
+	ss.ProcessQueues()
+	return results
+}
+
 // ListServices returns all loaded services.
 func (ss *ServiceSet) ListServices() []Service {
 	ss.mu.RLock()
@@ -279,6 +494,29 @@ func (ss *ServiceSet) ListServices() []Service {
 	return result
 }
 
+// FindByPID returns the loaded service whose current process (for a
+// bgprocess service, its daemon) has the given pid, or nil.
+func (ss *ServiceSet) FindByPID(pid int) Service {
+	if pid <= 0 {
+		return nil
+	}
+	ss.mu.RLock()
+	defer ss.mu.RUnlock()
+	for _, svc := range ss.records {
+		if svc.PID() == pid {
+			return svc
+		}
+	}
+	return nil
+}
+
+// ChildExitReceiver is implemented by services that monitor a process
+// slinit did not fork directly (a bgprocess daemon), so that a wait
+// status reaped elsewhere can be handed to the monitoring goroutine.
+type ChildExitReceiver interface {
+	DeliverChildExit(pid int, status syscall.WaitStatus) bool
+}
+
 // StartService starts a service and processes queues.
 func (ss *ServiceSet) StartService(svc Service) {
 	ss.queueMu.Lock()
@@ -313,6 +551,39 @@ func (ss *ServiceSet) ForceStopService(svc Service) {
 	ss.processQueuesLocked()
 }
 
+// KillService stops svc without restarting it (see
+// ServiceRecord.KillService) and sends SIGKILL to its process rather
+// than waiting for it to exit after the stop signal.
+func (ss *ServiceSet) KillService(svc Service) {
+	ss.queueMu.Lock()
+	defer ss.queueMu.Unlock()
+	svc.Record().KillService()
+	ss.processQueuesLocked()
+	if pid := svc.PID(); pid > 0 && svc.State() == StateStopping {
+		process.SignalProcess(pid, syscall.SIGKILL, false)
+	}
+}
+
+// Apply runs fn under queueMu and then processes the queues. It is the
+// entry point for state changes that have no dedicated one above
+// (pinning, dependency edits, triggers, restart settings, environment),
+// so they are serialized with the scheduler and the monitor goroutines.
+// fn must not call StartService or anything else that takes queueMu.
+func (ss *ServiceSet) Apply(fn func()) {
+	ss.queueMu.Lock()
+	defer ss.queueMu.Unlock()
+	fn()
+	ss.processQueuesLocked()
+}
+
+// View runs fn under queueMu held for reading, for callers that read
+// several fields of a service and need them to be consistent.
+func (ss *ServiceSet) View(fn func()) {
+	ss.queueMu.RLock()
+	defer ss.queueMu.RUnlock()
+	fn()
+}
+
 // StopAllServices stops all services (for shutdown).
 func (ss *ServiceSet) StopAllServices(shutdownType ShutdownType) {
 	// Snapshot services under read lock to avoid racing with concurrent
@@ -326,7 +597,7 @@ func (ss *ServiceSet) StopAllServices(shutdownType ShutdownType) {
 
 	ss.queueMu.Lock()
 	defer ss.queueMu.Unlock()
-	ss.restartEnabled = false
+	ss.restartEnabled.Store(false)
 	ss.shutdownType = shutdownType
 	for _, svc := range snapshot {
 		svc.Stop(false)
@@ -342,7 +613,7 @@ func (ss *ServiceSet) AddPropQueue(svc Service) {
 	rec := svc.Record()
 	if !rec.InPropQueue {
 		rec.InPropQueue = true
-		ss.propQueue = append(ss.propQueue, svc)
+		ss.pushProp(svc)
 	}
 }
 
@@ -367,12 +638,10 @@ func (ss *ServiceSet) ProcessQueues() {
 // processQueuesLocked is the core scheduling loop. Caller must hold queueMu.
 func (ss *ServiceSet) processQueuesLocked() {
 	for len(ss.propQueue) > 0 || len(ss.stopQueue) > 0 {
-		// Drain propagation queue using index to avoid reslicing overhead
-		pq := ss.propQueue
-		ss.propQueue = nil
-		for i := range pq {
-			svc := pq[i]
-			pq[i] = nil // allow GC
+		// Drain the propagation queue, highest priority first. Services
+		// queued by a propagation join the heap and are drained too.
+		for len(ss.propQueue) > 0 {
+			svc := ss.popProp()
 			svc.Record().InPropQueue = false
 			svc.Record().DoPropagation()
 		}
@@ -395,27 +664,46 @@ func (ss *ServiceSet) AppendConsoleQueue(svc Service) {
 	ss.consoleQueue = append(ss.consoleQueue, svc)
 }
 
-// PullConsoleQueue dispatches the next service waiting for the console.
+// AppendHighPriorityConsoleQueue adds a service to the high-priority
+// console wait queue, which PullConsoleQueue drains before the normal one.
+func (ss *ServiceSet) AppendHighPriorityConsoleQueue(svc Service) {
+	ss.highPriorityConsoleQueue = append(ss.highPriorityConsoleQueue, svc)
+}
+
+// PullConsoleQueue dispatches the next service waiting for the console,
+// taking high-priority waiters first.
 func (ss *ServiceSet) PullConsoleQueue() {
-	if len(ss.consoleQueue) == 0 {
+	q := &ss.highPriorityConsoleQueue
+	if len(*q) == 0 {
+		q = &ss.consoleQueue
+	}
+	if len(*q) == 0 {
 		return
 	}
-	front := ss.consoleQueue[0]
-	ss.consoleQueue = ss.consoleQueue[1:]
+	front := (*q)[0]
+	(*q)[0] = nil
+	*q = (*q)[1:]
 	front.Record().AcquiredConsole()
 }
 
-// UnqueueConsole removes a service from the console queue.
+// UnqueueConsole removes a service from the console queues.
 func (ss *ServiceSet) UnqueueConsole(svc Service) {
-	for i, s := range ss.consoleQueue {
+	if !unqueueConsole(&ss.highPriorityConsoleQueue, svc) {
+		unqueueConsole(&ss.consoleQueue, svc)
+	}
+}
+
+func unqueueConsole(q *[]Service, svc Service) bool {
+	for i, s := range *q {
 		if s == svc {
-			last := len(ss.consoleQueue) - 1
-			ss.consoleQueue[i] = ss.consoleQueue[last]
-			ss.consoleQueue[last] = nil
-			ss.consoleQueue = ss.consoleQueue[:last]
-			return
+			last := len(*q) - 1
+			(*q)[i] = (*q)[last]
+			(*q)[last] = nil
+			*q = (*q)[:last]
+			return true
 		}
 	}
+	return false
 }
 
 // --- Active service tracking ---
@@ -455,7 +743,7 @@ func (ss *ServiceSet) InactiveCh() <-chan struct{} {
 
 // IsShuttingDown returns true if automatic restart is disabled (shutdown in progress).
 func (ss *ServiceSet) IsShuttingDown() bool {
-	return !ss.restartEnabled
+	return !ss.restartEnabled.Load()
 }
 
 // ActiveServiceInfo holds info about a non-stopped service (for shutdown reporting).
@@ -519,6 +807,7 @@ func (ss *ServiceSet) KernelUptime() time.Duration { return ss.kernelUptime }
 func (ss *ServiceSet) ResetBootTiming() {
 	ss.bootStartTime = time.Now()
 	ss.bootReadyTime = time.Time{}
+	ss.resetBootEvents()
 }
 
 // --- Global daemon settings ---
@@ -692,6 +981,14 @@ func (ss *ServiceSet) RunnerPath() string                { return ss.runnerPath
 func (ss *ServiceSet) SetReadyFD(fd int)                 { ss.readyFD = fd }
 func (ss *ServiceSet) ReadyFD() int                      { return ss.readyFD }
 
+// SetRestartJitterFactor sets the global restart jitter factor. 0
+// disables it; negative values are treated as 0.
+func (ss *ServiceSet) SetRestartJitterFactor(f float64) { ss.restartJitterFactor = f }
+
+// SetSkipPreExecChecks disables the pre-exec validation done before
+// each service start, trading precise error messages for fewer syscalls.
+func (ss *ServiceSet) SetSkipPreExecChecks(skip bool) { ss.skipPreExecChecks = skip }
+
 // RWReady returns true when a service with starts-rwfs has reached STARTED.
 func (ss *ServiceSet) RWReady() bool { return ss.rwReady }
 
@@ -793,3 +1090,186 @@ func (ss *ServiceSet) CountByState() StateCounts {
 	}
 	return out
 }
+
+// ServiceTier is a set of services with no dependencies among each other,
+// which can therefore be started simultaneously.
+type ServiceTier []Service
+
+// DependencyCycleError is returned by FlattenDependencyGraph when the
+// dependency graph is not acyclic. Cycle lists the names along the loop,
+// with the first name repeated at the end.
+type DependencyCycleError struct {
+	Cycle []string
+}
+
+func (e *DependencyCycleError) Error() string {
+	return "dependency cycle: " + strings.Join(e.Cycle, " -> ")
+}
+
+// FlattenDependencyGraph assigns every service root pulls in (through
+// dependencies other than before/after) to a start tier using Kahn's algorithm. Tier 0 holds services without
+// dependencies; every service in tier N depends only on services in
+// tiers < N. A before edge is read in reverse: "a before b" puts a in
+// an earlier tier than b. Services within a tier are ordered by name.
+func (ss *ServiceSet) FlattenDependencyGraph(root Service) ([]ServiceTier, error) {
+	byName := make(map[string]Service)
+	var walk func(svc Service)
+	walk = func(svc Service) {
+		if _, seen := byName[svc.Name()]; seen {
+			return
+		}
+		byName[svc.Name()] = svc
+		for _, dep := range svc.Record().Dependencies() {
+			if !dep.IsOnlyOrdering() {
+				walk(dep.To)
+			}
+		}
+	}
+	walk(root)
+
+	// Ordering edges only rank services already pulled in; edges to
+	// services outside the set are dropped by TierNames. A before edge
+	// (A before X) means X waits for A, so it counts as a dependency of
+	// its target rather than of its source.
+	names := make([]string, 0, len(byName))
+	deps := make(map[string][]string)
+	for name, svc := range byName {
+		names = append(names, name)
+		for _, dep := range svc.Record().Dependencies() {
+			if dep.DepType == DepBefore {
+				deps[dep.To.Name()] = append(deps[dep.To.Name()], name)
+			} else {
+				deps[name] = append(deps[name], dep.To.Name())
+			}
+		}
+	}
+	tiers, err := TierNames(names, func(name string) []string { return deps[name] })
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]ServiceTier, len(tiers))
+	for i, tier := range tiers {
+		result[i] = make(ServiceTier, len(tier))
+		for j, name := range tier {
+			result[i][j] = byName[name]
+		}
+	}
+	return result, nil
+}
+
+// FlattenDependencyGraph returns the start tiers of this service and
+// everything it depends on. See ServiceSet.FlattenDependencyGraph.
+func (sr *ServiceRecord) FlattenDependencyGraph() ([]ServiceTier, error) {
+	return sr.services.FlattenDependencyGraph(sr.self)
+}
+
+// TierNames layers the graph given by names and deps (name -> names it
+// depends on) with Kahn's algorithm; see FlattenDependencyGraph. Edges to
+// names outside the set are ignored. It operates on plain names so that
+// clients holding only a wire-level view of the graph can share it.
+func TierNames(names []string, deps func(name string) []string) ([][]string, error) {
+	inSet := make(map[string]bool, len(names))
+	for _, n := range names {
+		inSet[n] = true
+	}
+
+	// pending[n] counts n's unresolved dependencies; dependents is the
+	// reverse adjacency used to release them.
+	pending := make(map[string]int, len(names))
+	dependents := make(map[string][]string)
+	for _, n := range names {
+		seen := make(map[string]bool)
+		for _, d := range deps(n) {
+			if !inSet[d] || seen[d] {
+				continue
+			}
+			seen[d] = true
+			pending[n]++
+			dependents[d] = append(dependents[d], n)
+		}
+	}
+
+	var ready []string
+	for _, n := range names {
+		if pending[n] == 0 {
+			ready = append(ready, n)
+		}
+	}
+
+	var tiers [][]string
+	placed := 0
+	for len(ready) > 0 {
+		sort.Strings(ready)
+		tiers = append(tiers, ready)
+		placed += len(ready)
+		var next []string
+		for _, n := range ready {
+			for _, d := range dependents[n] {
+				pending[d]--
+				if pending[d] == 0 {
+					next = append(next, d)
+				}
+			}
+		}
+		ready = next
+	}
+
+	if placed < len(names) {
+		return nil, &DependencyCycleError{Cycle: findCycle(names, deps, pending)}
+	}
+	return tiers, nil
+}
+
+// findCycle walks dependency edges among the names Kahn's algorithm could
+// not place (pending > 0) until a name repeats. Every such name has at
+// least one unplaced dependency, so the walk is guaranteed to loop.
+func findCycle(names []string, deps func(name string) []string, pending map[string]int) []string {
+	start := ""
+	for _, n := range names {
+		if pending[n] > 0 && (start == "" || n < start) {
+			start = n
+		}
+	}
+	index := make(map[string]int)
+	var path []string
+	for n := start; ; {
+		if i, ok := index[n]; ok {
+			return append(path[i:], n)
+		}
+		index[n] = len(path)
+		path = append(path, n)
+		next := ""
+		for _, d := range deps(n) {
+			if pending[d] > 0 && (next == "" || d < next) {
+				next = d
+			}
+		}
+		if next == "" {
+			return path
+		}
+		n = next
+	}
+}
+
+// debugf logs at debug level when the set's logger supports it
+// (*logging.Logger does; ServiceLogger itself only requires Info/Error).
+func (ss *ServiceSet) debugf(format string, args ...interface{}) {
+	if dl, ok := ss.logger.(interface {
+		Debug(format string, args ...interface{})
+	}); ok {
+		dl.Debug(format, args...)
+	}
+}
+
+// warnf logs at warning level, or as an error when the set's logger has
+// no warning level.
+func (ss *ServiceSet) warnf(format string, args ...interface{}) {
+	if wl, ok := ss.logger.(interface {
+		Warn(format string, args ...interface{})
+	}); ok {
+		wl.Warn(format, args...)
+		return
+	}
+	ss.logger.Error(format, args...)
+}
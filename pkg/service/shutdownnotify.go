@@ -0,0 +1,75 @@
+package service
+
+import (
+	"time"
+
+	"github.com/sunlightlinux/slinit/pkg/process"
+)
+
+// Shutdown-pending notification (notify-shutdown-signal /
+// notify-shutdown-delay). A service that drains in-flight work before
+// exiting is told the shutdown is coming and given time to do so
+// before it receives its term-signal.
+
+// NotifyShutdownPending tells the service a shutdown is about to stop
+// it. The default sends notify-shutdown-signal, if one is set, to the
+// service process; it does nothing otherwise. Service types with their
+// own drain protocol override it.
+func (sr *ServiceRecord) NotifyShutdownPending() {
+	if sr.notifyShutdownSignal == 0 {
+		return
+	}
+	pid := sr.self.PID()
+	if pid <= 0 {
+		return
+	}
+	if err := process.SignalProcess(pid, sr.notifyShutdownSignal, false); err != nil {
+		sr.services.logger.Error("Service '%s': failed to send shutdown notification: %v",
+			sr.serviceName, err)
+	}
+}
+
+// GracefulShutdownWithCallback stops every service like StopAllServices,
+// but first calls NotifyShutdownPending on each active service and
+// waits for the longer of notifyDelay and the notified services' own
+// notify-shutdown-delay. Automatic restarts are off from the moment the
+// notifications go out. done, if not nil, is called once
+// StopAllServices has run. With nothing to wait for the services are
+// stopped before it returns; otherwise the stop happens on a timer
+// goroutine.
+func (ss *ServiceSet) GracefulShutdownWithCallback(shutdownType ShutdownType, notifyDelay time.Duration, done func()) {
+	ss.mu.RLock()
+	snapshot := make([]Service, 0, len(ss.records))
+	for _, svc := range ss.records {
+		snapshot = append(snapshot, svc)
+	}
+	ss.mu.RUnlock()
+
+	wait := notifyDelay
+	ss.queueMu.Lock()
+	ss.restartEnabled.Store(false)
+	ss.shutdownType = shutdownType
+	for _, svc := range snapshot {
+		if svc.State() == StateStopped {
+			continue
+		}
+		svc.NotifyShutdownPending()
+		if d := svc.Record().notifyShutdownDelay; d > wait {
+			wait = d
+		}
+	}
+	ss.queueMu.Unlock()
+
+	stop := func() {
+		ss.StopAllServices(shutdownType)
+		if done != nil {
+			done()
+		}
+	}
+	if wait <= 0 {
+		stop()
+		return
+	}
+	ss.logger.Info("Shutdown pending, stopping services in %s", wait)
+	time.AfterFunc(wait, stop)
+}
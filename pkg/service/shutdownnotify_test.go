@@ -0,0 +1,67 @@
+package service
+
+import (
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestGracefulShutdownNotifiesFirst(t *testing.T) {
+	set, _ := newTestSet()
+	dir := t.TempDir()
+	ready := filepath.Join(dir, "ready")
+	notified := filepath.Join(dir, "notified")
+
+	svc := NewProcessService(set, "drain")
+	svc.SetCommand([]string{"/bin/sh", "-c",
+		"trap 'touch " + notified + "' USR1; touch " + ready + "; while :; do sleep 0.05; done"})
+	svc.SetNotifyShutdown(syscall.SIGUSR1, 300*time.Millisecond)
+	set.AddService(svc)
+	set.StartService(svc)
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		if _, err := os.Stat(ready); err == nil && svc.State() == StateStarted {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("service did not start")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	done := make(chan struct{})
+	set.GracefulShutdownWithCallback(ShutdownPoweroff, 0, func() { close(done) })
+	if !set.IsShuttingDown() {
+		t.Error("restarts still enabled while the shutdown is pending")
+	}
+	if svc.State() != StateStarted {
+		t.Fatalf("service %v before the notify delay passed", svc.State())
+	}
+
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("done was not called")
+	}
+	if _, err := os.Stat(notified); err != nil {
+		t.Error("service did not receive notify-shutdown-signal")
+	}
+	if !waitStopped(svc, 3*time.Second) {
+		t.Fatalf("service still %v after shutdown", svc.State())
+	}
+}
+
+func TestGracefulShutdownWithoutDelayStopsNow(t *testing.T) {
+	set, _ := newTestSet()
+	svc := NewInternalService(set, "svc")
+	set.AddService(svc)
+	set.StartService(svc)
+
+	called := false
+	set.GracefulShutdownWithCallback(ShutdownHalt, 0, func() { called = true })
+	if !called || svc.State() != StateStopped {
+		t.Errorf("called = %v, state = %v; want a synchronous stop", called, svc.State())
+	}
+}
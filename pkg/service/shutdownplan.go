@@ -0,0 +1,116 @@
+package service
+
+import (
+	"sort"
+	"time"
+)
+
+// stopHistorySize bounds the per-service stop-time history kept for
+// shutdown estimates.
+const stopHistorySize = 20
+
+// ShutdownStep is one batch of a shutdown plan: services that stop in
+// parallel once every earlier step has finished.
+type ShutdownStep struct {
+	Services            []string
+	EstimatedDurationMs int // slowest service's estimate in the batch
+}
+
+// noteStopDuration records how long the service took from BringDown to
+// STOPPED. Called from Stopped().
+func (sr *ServiceRecord) noteStopDuration() {
+	if sr.bringDownTime.IsZero() {
+		return
+	}
+	d := sr.stoppedTime.Sub(sr.bringDownTime)
+	sr.bringDownTime = time.Time{}
+	if len(sr.stopDurations) >= stopHistorySize {
+		copy(sr.stopDurations, sr.stopDurations[1:])
+		sr.stopDurations = sr.stopDurations[:stopHistorySize-1]
+	}
+	sr.stopDurations = append(sr.stopDurations, d)
+}
+
+// estimatedStopTime returns the 95th percentile of the service's recent
+// stop times, or its stop timeout (the worst case) when it has never
+// been stopped. For a process service following a stop-signal-sequence
+// the worst case is the sum of the step timeouts instead. Services
+// without a stop timeout and without history are assumed to stop
+// immediately.
+func (sr *ServiceRecord) estimatedStopTime() time.Duration {
+	if n := len(sr.stopDurations); n > 0 {
+		sorted := make([]time.Duration, n)
+		copy(sorted, sr.stopDurations)
+		sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+		// Nearest-rank percentile.
+		idx := (95*n+99)/100 - 1
+		return sorted[idx]
+	}
+	if _, ok := sr.self.(*ProcessService); ok && len(sr.stopSignalSeq) > 0 {
+		var total time.Duration
+		for _, step := range sr.stopSignalSeq {
+			total += step.Timeout()
+		}
+		return total
+	}
+	if st, ok := sr.self.(interface{ StopTimeout() time.Duration }); ok {
+		return st.StopTimeout()
+	}
+	return 0
+}
+
+// ShutdownPlan reports, without stopping anything, the order in which a
+// shutdown would stop the currently active services. A service stops
+// only after every service holding a hard dependency on it, so step 0
+// holds services nothing active depends on, step 1 their dependencies,
+// and so on. Services within a step are ordered by name.
+func (ss *ServiceSet) ShutdownPlan() []ShutdownStep {
+	ss.queueMu.RLock()
+	defer ss.queueMu.RUnlock()
+
+	active := make(map[string]Service)
+	for _, svc := range ss.ListServices() {
+		if svc.State() != StateStopped {
+			active[svc.Name()] = svc
+		}
+	}
+	if len(active) == 0 {
+		return nil
+	}
+	names := make([]string, 0, len(active))
+	for name := range active {
+		names = append(names, name)
+	}
+
+	// Layer on the reversed graph: a service "depends on" its hard
+	// dependents stopping first.
+	tiers, err := TierNames(names, func(name string) []string {
+		var out []string
+		for _, dept := range active[name].Record().Dependents() {
+			if dept.IsHard() {
+				out = append(out, dept.From.Name())
+			}
+		}
+		return out
+	})
+	if err != nil {
+		// A cycle cannot be ordered; report everything as one batch.
+		sort.Strings(names)
+		tiers = [][]string{names}
+	}
+
+	plan := make([]ShutdownStep, len(tiers))
+	for i, tier := range tiers {
+		var longest time.Duration
+		for _, name := range tier {
+			if d := active[name].Record().estimatedStopTime(); d > longest {
+				longest = d
+			}
+		}
+		plan[i] = ShutdownStep{
+			Services:            tier,
+			EstimatedDurationMs: int(longest / time.Millisecond),
+		}
+	}
+	return plan
+}
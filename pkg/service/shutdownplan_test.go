@@ -0,0 +1,88 @@
+package service
+
+import (
+	"reflect"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestShutdownPlanOrder(t *testing.T) {
+	set, _ := newTestSet()
+	db := NewInternalService(set, "db")
+	cache := NewInternalService(set, "cache")
+	app := NewInternalService(set, "app")
+	logger := NewInternalService(set, "logger")
+	idle := NewInternalService(set, "idle")
+	app.Record().AddDep(db, DepRegular)
+	app.Record().AddDep(cache, DepRegular)
+	app.Record().AddDep(logger, DepSoft)
+	for _, svc := range []Service{db, cache, app, logger, idle} {
+		set.AddService(svc)
+	}
+	set.StartService(app)
+
+	plan := set.ShutdownPlan()
+	var got [][]string
+	for _, step := range plan {
+		got = append(got, step.Services)
+	}
+	// logger is only a soft dependency, so it does not wait for app;
+	// idle was never started and is not part of the plan.
+	want := [][]string{{"app", "logger"}, {"cache", "db"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("plan = %v, want %v", got, want)
+	}
+	if app.State() != StateStarted {
+		t.Errorf("ShutdownPlan must not stop anything, app is %v", app.State())
+	}
+}
+
+func TestShutdownPlanEstimates(t *testing.T) {
+	set, _ := newTestSet()
+	proc := NewProcessService(set, "proc")
+	proc.SetStopTimeout(7 * time.Second)
+	if d := proc.Record().estimatedStopTime(); d != 7*time.Second {
+		t.Errorf("no history: estimate = %v, want the stop-timeout", d)
+	}
+
+	for i := 1; i <= 20; i++ {
+		proc.Record().stopDurations = append(proc.Record().stopDurations, time.Duration(i)*time.Millisecond)
+	}
+	if d := proc.Record().estimatedStopTime(); d != 19*time.Millisecond {
+		t.Errorf("p95 of 1..20ms = %v, want 19ms", d)
+	}
+
+	seq := NewProcessService(set, "seq")
+	seq.SetStopTimeout(7 * time.Second)
+	seq.Record().SetStopSignalSequence([]StopSignalStep{
+		{Signal: syscall.SIGTERM, TimeoutSeconds: 10},
+		{Signal: syscall.SIGHUP, TimeoutSeconds: 5},
+		{Signal: syscall.SIGKILL},
+	})
+	if d := seq.Record().estimatedStopTime(); d != 15*time.Second {
+		t.Errorf("stop-signal-sequence estimate = %v, want the 15s of step timeouts", d)
+	}
+
+	internal := NewInternalService(set, "internal")
+	if d := internal.Record().estimatedStopTime(); d != 0 {
+		t.Errorf("internal service estimate = %v, want 0", d)
+	}
+}
+
+func TestStopDurationRecorded(t *testing.T) {
+	set, _ := newTestSet()
+	svc := NewInternalService(set, "svc")
+	set.AddService(svc)
+
+	for i := 0; i < stopHistorySize+3; i++ {
+		set.StartService(svc)
+		set.StopService(svc)
+	}
+	if n := len(svc.Record().stopDurations); n != stopHistorySize {
+		t.Errorf("kept %d stop durations, want %d", n, stopHistorySize)
+	}
+	if !svc.Record().bringDownTime.IsZero() {
+		t.Error("bringDownTime should be cleared once STOPPED")
+	}
+}
@@ -0,0 +1,572 @@
+package service
+
+import (
+	"maps"
+	"time"
+)
+
+// ServiceSnapshot is a copy of the type-specific configuration of a
+// service, taken before a reload changes it. Its contents depend on the
+// service type; only RestoreSnapshot of the same type can use it.
+type ServiceSnapshot interface{}
+
+// Snapshotter is implemented by service types whose type-specific
+// configuration a reload can roll back. The loader saves a snapshot
+// before applying a new description in place and restores it if a later
+// step of the reload fails, so the service is left as it was.
+type Snapshotter interface {
+	SaveSnapshot() ServiceSnapshot
+	RestoreSnapshot(snap ServiceSnapshot)
+}
+
+// processSnapshot holds the ProcessService fields a reload may change.
+type processSnapshot struct {
+	command            []string
+	argv0              string
+	memfdExec          bool
+	stopCommand        []string
+	finishCommand      []string
+	preStartCommand    []string
+	postStartCommand   []string
+	readyCheckCommand  []string
+	readyCheckInterval time.Duration
+	preStopHook        []string
+	controlCommands    map[string][]string
+	workingDir         string
+	envFile            string
+	envDir             string
+	encryptedEnvFile   string
+	envEncryptionKey   string
+	envGenerator       string
+	chroot             string
+	lockFile           string
+	newSession         bool
+	closeStdin         bool
+	closeStdout        bool
+	closeStderr        bool
+
+	runAsUID          uint32
+	runAsGID          uint32
+	supplementaryGIDs []uint32
+
+	startTimeout            time.Duration
+	stopTimeout             time.Duration
+	restartDelay            time.Duration
+	timeoutStartFailureMode TimeoutFailureMode
+	timeoutAbortSec         time.Duration
+	exitType                ExitType
+	restartDelayStep        time.Duration
+	restartDelayCap         time.Duration
+	restartRandomizedDelay  time.Duration
+	restartMaxDelay         time.Duration
+	restartInterval         time.Duration
+	maxRestartCount         int
+	restartTokens           tokenBucket
+
+	readyNotifyFD     int
+	readyNotifyVar    string
+	readyNotifySocket bool
+	watchdogTimeout   time.Duration
+	socketOnDemand    bool
+
+	logType              LogType
+	logBufMax            int
+	logFile              string
+	logFilePerms         int
+	logFileUID           int
+	logFileGID           int
+	logMaxSize           int64
+	logMaxFiles          int
+	logMinFiles          int
+	logRotateTime        time.Duration
+	logProcessor         []string
+	logIncludes          []string
+	logExcludes          []string
+	logSelect            []string
+	logRateLimitInterval time.Duration
+	logRateLimitBurst    int
+	logLevelMax          int
+	logSanitizeChar      byte
+	logSanitizeExtra     []byte
+	logMaxLineLength     int
+	logTimestampMode     string
+	logLinePrefix        string
+	logReadBufferSize    int
+	logForwardUDP        string
+	logForwardFormat     string
+	logForwardFacility   int
+	logForwardTag        string
+	alertFile            string
+	alertLevel           int
+	outputLogger         []string
+	errorLogger          []string
+
+	cronRunner    *CronRunner
+	healthChecker *HealthChecker
+
+	vttyEnabled    bool
+	vttyScrollback int
+	vttySockDir    string
+}
+
+// SaveSnapshot copies the configuration a reload may change.
+func (s *ProcessService) SaveSnapshot() ServiceSnapshot {
+	return &processSnapshot{
+		command:            s.command,
+		argv0:              s.argv0,
+		memfdExec:          s.memfdExec,
+		stopCommand:        s.stopCommand,
+		finishCommand:      s.finishCommand,
+		preStartCommand:    s.preStartCommand,
+		postStartCommand:   s.postStartCommand,
+		readyCheckCommand:  s.readyCheckCommand,
+		readyCheckInterval: s.readyCheckInterval,
+		preStopHook:        s.preStopHook,
+		controlCommands:    s.controlCommands,
+		workingDir:         s.workingDir,
+		envFile:            s.envFile,
+		envDir:             s.envDir,
+		encryptedEnvFile:   s.encryptedEnvFile,
+		envEncryptionKey:   s.envEncryptionKey,
+		envGenerator:       s.envGenerator,
+		chroot:             s.chroot,
+		lockFile:           s.lockFile,
+		newSession:         s.newSession,
+		closeStdin:         s.closeStdin,
+		closeStdout:        s.closeStdout,
+		closeStderr:        s.closeStderr,
+
+		runAsUID:          s.runAsUID,
+		runAsGID:          s.runAsGID,
+		supplementaryGIDs: s.supplementaryGIDs,
+
+		startTimeout:            s.startTimeout,
+		stopTimeout:             s.stopTimeout,
+		restartDelay:            s.restartDelay,
+		timeoutStartFailureMode: s.timeoutStartFailureMode,
+		timeoutAbortSec:         s.timeoutAbortSec,
+		exitType:                s.exitType,
+		restartDelayStep:        s.restartDelayStep,
+		restartDelayCap:         s.restartDelayCap,
+		restartRandomizedDelay:  s.restartRandomizedDelay,
+		restartMaxDelay:         s.restartMaxDelay,
+		restartInterval:         s.restartInterval,
+		maxRestartCount:         s.maxRestartCount,
+		restartTokens:           s.restartTokens,
+
+		readyNotifyFD:     s.readyNotifyFD,
+		readyNotifyVar:    s.readyNotifyVar,
+		readyNotifySocket: s.readyNotifySocket,
+		watchdogTimeout:   s.watchdogTimeout,
+		socketOnDemand:    s.socketOnDemand,
+
+		logType:              s.logType,
+		logBufMax:            s.logBufMax,
+		logFile:              s.logFile,
+		logFilePerms:         s.logFilePerms,
+		logFileUID:           s.logFileUID,
+		logFileGID:           s.logFileGID,
+		logMaxSize:           s.logMaxSize,
+		logMaxFiles:          s.logMaxFiles,
+		logMinFiles:          s.logMinFiles,
+		logRotateTime:        s.logRotateTime,
+		logProcessor:         s.logProcessor,
+		logIncludes:          s.logIncludes,
+		logExcludes:          s.logExcludes,
+		logSelect:            s.logSelect,
+		logRateLimitInterval: s.logRateLimitInterval,
+		logRateLimitBurst:    s.logRateLimitBurst,
+		logLevelMax:          s.logLevelMax,
+		logSanitizeChar:      s.logSanitizeChar,
+		logSanitizeExtra:     s.logSanitizeExtra,
+		logMaxLineLength:     s.logMaxLineLength,
+		logTimestampMode:     s.logTimestampMode,
+		logLinePrefix:        s.logLinePrefix,
+		logReadBufferSize:    s.logReadBufferSize,
+		logForwardUDP:        s.logForwardUDP,
+		logForwardFormat:     s.logForwardFormat,
+		logForwardFacility:   s.logForwardFacility,
+		logForwardTag:        s.logForwardTag,
+		alertFile:            s.alertFile,
+		alertLevel:           s.alertLevel,
+		outputLogger:         s.outputLogger,
+		errorLogger:          s.errorLogger,
+
+		cronRunner:    s.cronRunner,
+		healthChecker: s.healthChecker,
+
+		vttyEnabled:    s.vttyEnabled,
+		vttyScrollback: s.vttyScrollback,
+		vttySockDir:    s.vttySockDir,
+	}
+}
+
+// RestoreSnapshot puts back configuration saved by SaveSnapshot.
+// Snapshots of another service type are ignored.
+func (s *ProcessService) RestoreSnapshot(snap ServiceSnapshot) {
+	c, ok := snap.(*processSnapshot)
+	if !ok {
+		return
+	}
+	s.command = c.command
+	s.argv0 = c.argv0
+	s.memfdExec = c.memfdExec
+	s.stopCommand = c.stopCommand
+	s.finishCommand = c.finishCommand
+	s.preStartCommand = c.preStartCommand
+	s.postStartCommand = c.postStartCommand
+	s.readyCheckCommand = c.readyCheckCommand
+	s.readyCheckInterval = c.readyCheckInterval
+	s.preStopHook = c.preStopHook
+	s.controlCommands = c.controlCommands
+	s.workingDir = c.workingDir
+	s.envFile = c.envFile
+	s.envDir = c.envDir
+	s.encryptedEnvFile = c.encryptedEnvFile
+	s.envEncryptionKey = c.envEncryptionKey
+	s.envGenerator = c.envGenerator
+	s.chroot = c.chroot
+	s.lockFile = c.lockFile
+	s.newSession = c.newSession
+	s.closeStdin = c.closeStdin
+	s.closeStdout = c.closeStdout
+	s.closeStderr = c.closeStderr
+
+	s.runAsUID = c.runAsUID
+	s.runAsGID = c.runAsGID
+	s.supplementaryGIDs = c.supplementaryGIDs
+
+	s.startTimeout = c.startTimeout
+	s.stopTimeout = c.stopTimeout
+	s.restartDelay = c.restartDelay
+	s.timeoutStartFailureMode = c.timeoutStartFailureMode
+	s.timeoutAbortSec = c.timeoutAbortSec
+	s.exitType = c.exitType
+	s.restartDelayStep = c.restartDelayStep
+	s.restartDelayCap = c.restartDelayCap
+	s.restartRandomizedDelay = c.restartRandomizedDelay
+	s.restartMaxDelay = c.restartMaxDelay
+	s.restartInterval = c.restartInterval
+	s.maxRestartCount = c.maxRestartCount
+	s.restartTokens = c.restartTokens
+
+	s.readyNotifyFD = c.readyNotifyFD
+	s.readyNotifyVar = c.readyNotifyVar
+	s.readyNotifySocket = c.readyNotifySocket
+	s.watchdogTimeout = c.watchdogTimeout
+	s.socketOnDemand = c.socketOnDemand
+
+	s.logType = c.logType
+	s.logBufMax = c.logBufMax
+	s.logFile = c.logFile
+	s.logFilePerms = c.logFilePerms
+	s.logFileUID = c.logFileUID
+	s.logFileGID = c.logFileGID
+	s.logMaxSize = c.logMaxSize
+	s.logMaxFiles = c.logMaxFiles
+	s.logMinFiles = c.logMinFiles
+	s.logRotateTime = c.logRotateTime
+	s.logProcessor = c.logProcessor
+	s.logIncludes = c.logIncludes
+	s.logExcludes = c.logExcludes
+	s.logSelect = c.logSelect
+	s.logRateLimitInterval = c.logRateLimitInterval
+	s.logRateLimitBurst = c.logRateLimitBurst
+	s.logLevelMax = c.logLevelMax
+	s.logSanitizeChar = c.logSanitizeChar
+	s.logSanitizeExtra = c.logSanitizeExtra
+	s.logMaxLineLength = c.logMaxLineLength
+	s.logTimestampMode = c.logTimestampMode
+	s.logLinePrefix = c.logLinePrefix
+	s.logReadBufferSize = c.logReadBufferSize
+	s.logForwardUDP = c.logForwardUDP
+	s.logForwardFormat = c.logForwardFormat
+	s.logForwardFacility = c.logForwardFacility
+	s.logForwardTag = c.logForwardTag
+	s.alertFile = c.alertFile
+	s.alertLevel = c.alertLevel
+	s.outputLogger = c.outputLogger
+	s.errorLogger = c.errorLogger
+
+	s.cronRunner = c.cronRunner
+	s.healthChecker = c.healthChecker
+
+	s.vttyEnabled = c.vttyEnabled
+	s.vttyScrollback = c.vttyScrollback
+	s.vttySockDir = c.vttySockDir
+}
+
+// bgProcessSnapshot holds the BGProcessService fields a reload may change.
+type bgProcessSnapshot struct {
+	command        []string
+	argv0          string
+	memfdExec      bool
+	stopCommand    []string
+	workingDir     string
+	envFile        string
+	pidFile        string
+	pidFileLocking bool
+
+	runAsUID          uint32
+	runAsGID          uint32
+	supplementaryGIDs []uint32
+
+	startTimeout            time.Duration
+	stopTimeout             time.Duration
+	restartDelay            time.Duration
+	timeoutAbortSec         time.Duration
+	timeoutStartFailureMode TimeoutFailureMode
+	exitType                ExitType
+	restartDelayStep        time.Duration
+	restartDelayCap         time.Duration
+	restartRandomizedDelay  time.Duration
+	restartMaxDelay         time.Duration
+	restartInterval         time.Duration
+	maxRestartCount         int
+	restartTokens           tokenBucket
+
+	logType      LogType
+	logBufMax    int
+	logFile      string
+	logFilePerms int
+	logFileUID   int
+	logFileGID   int
+}
+
+// SaveSnapshot copies the configuration a reload may change.
+func (s *BGProcessService) SaveSnapshot() ServiceSnapshot {
+	return &bgProcessSnapshot{
+		command:        s.command,
+		argv0:          s.argv0,
+		memfdExec:      s.memfdExec,
+		stopCommand:    s.stopCommand,
+		workingDir:     s.workingDir,
+		envFile:        s.envFile,
+		pidFile:        s.pidFile,
+		pidFileLocking: s.pidFileLocking,
+
+		runAsUID:          s.runAsUID,
+		runAsGID:          s.runAsGID,
+		supplementaryGIDs: s.supplementaryGIDs,
+
+		startTimeout:            s.startTimeout,
+		stopTimeout:             s.stopTimeout,
+		restartDelay:            s.restartDelay,
+		timeoutAbortSec:         s.timeoutAbortSec,
+		timeoutStartFailureMode: s.timeoutStartFailureMode,
+		exitType:                s.exitType,
+		restartDelayStep:        s.restartDelayStep,
+		restartDelayCap:         s.restartDelayCap,
+		restartRandomizedDelay:  s.restartRandomizedDelay,
+		restartMaxDelay:         s.restartMaxDelay,
+		restartInterval:         s.restartInterval,
+		maxRestartCount:         s.maxRestartCount,
+		restartTokens:           s.restartTokens,
+
+		logType:      s.logType,
+		logBufMax:    s.logBufMax,
+		logFile:      s.logFile,
+		logFilePerms: s.logFilePerms,
+		logFileUID:   s.logFileUID,
+		logFileGID:   s.logFileGID,
+	}
+}
+
+// RestoreSnapshot puts back configuration saved by SaveSnapshot.
+// Snapshots of another service type are ignored.
+func (s *BGProcessService) RestoreSnapshot(snap ServiceSnapshot) {
+	c, ok := snap.(*bgProcessSnapshot)
+	if !ok {
+		return
+	}
+	s.command = c.command
+	s.argv0 = c.argv0
+	s.memfdExec = c.memfdExec
+	s.stopCommand = c.stopCommand
+	s.workingDir = c.workingDir
+	s.envFile = c.envFile
+	s.pidFile = c.pidFile
+	s.pidFileLocking = c.pidFileLocking
+
+	s.runAsUID = c.runAsUID
+	s.runAsGID = c.runAsGID
+	s.supplementaryGIDs = c.supplementaryGIDs
+
+	s.startTimeout = c.startTimeout
+	s.stopTimeout = c.stopTimeout
+	s.restartDelay = c.restartDelay
+	s.timeoutAbortSec = c.timeoutAbortSec
+	s.timeoutStartFailureMode = c.timeoutStartFailureMode
+	s.exitType = c.exitType
+	s.restartDelayStep = c.restartDelayStep
+	s.restartDelayCap = c.restartDelayCap
+	s.restartRandomizedDelay = c.restartRandomizedDelay
+	s.restartMaxDelay = c.restartMaxDelay
+	s.restartInterval = c.restartInterval
+	s.maxRestartCount = c.maxRestartCount
+	s.restartTokens = c.restartTokens
+
+	s.logType = c.logType
+	s.logBufMax = c.logBufMax
+	s.logFile = c.logFile
+	s.logFilePerms = c.logFilePerms
+	s.logFileUID = c.logFileUID
+	s.logFileGID = c.logFileGID
+}
+
+// scriptedSnapshot holds the ScriptedService fields a reload may change.
+type scriptedSnapshot struct {
+	startCommand      []string
+	stopCommand       []string
+	workingDir        string
+	captureStartError bool
+
+	runAsUID          uint32
+	runAsGID          uint32
+	supplementaryGIDs []uint32
+
+	startTimeout time.Duration
+	stopTimeout  time.Duration
+
+	logType      LogType
+	logBufMax    int
+	logFile      string
+	logFilePerms int
+	logFileUID   int
+	logFileGID   int
+
+	healthChecker *HealthChecker
+}
+
+// SaveSnapshot copies the configuration a reload may change.
+func (s *ScriptedService) SaveSnapshot() ServiceSnapshot {
+	return &scriptedSnapshot{
+		startCommand:      s.startCommand,
+		stopCommand:       s.stopCommand,
+		workingDir:        s.workingDir,
+		captureStartError: s.captureStartError,
+
+		runAsUID:          s.runAsUID,
+		runAsGID:          s.runAsGID,
+		supplementaryGIDs: s.supplementaryGIDs,
+
+		startTimeout: s.startTimeout,
+		stopTimeout:  s.stopTimeout,
+
+		logType:      s.logType,
+		logBufMax:    s.logBufMax,
+		logFile:      s.logFile,
+		logFilePerms: s.logFilePerms,
+		logFileUID:   s.logFileUID,
+		logFileGID:   s.logFileGID,
+
+		healthChecker: s.healthChecker,
+	}
+}
+
+// RestoreSnapshot puts back configuration saved by SaveSnapshot.
+// Snapshots of another service type are ignored. SetHealthCheck stops
+// the old checker of a started service, so the saved one is started
+// again.
+func (s *ScriptedService) RestoreSnapshot(snap ServiceSnapshot) {
+	c, ok := snap.(*scriptedSnapshot)
+	if !ok {
+		return
+	}
+	s.startCommand = c.startCommand
+	s.stopCommand = c.stopCommand
+	s.workingDir = c.workingDir
+	s.captureStartError = c.captureStartError
+
+	s.runAsUID = c.runAsUID
+	s.runAsGID = c.runAsGID
+	s.supplementaryGIDs = c.supplementaryGIDs
+
+	s.startTimeout = c.startTimeout
+	s.stopTimeout = c.stopTimeout
+
+	s.logType = c.logType
+	s.logBufMax = c.logBufMax
+	s.logFile = c.logFile
+	s.logFilePerms = c.logFilePerms
+	s.logFileUID = c.logFileUID
+	s.logFileGID = c.logFileGID
+
+	if s.healthChecker != c.healthChecker {
+		if s.healthChecker != nil {
+			s.healthChecker.Stop()
+		}
+		s.healthChecker = c.healthChecker
+		if s.State() == StateStarted {
+			s.startHealthCheck()
+		}
+	}
+}
+
+// socketActivatedSnapshot holds the SocketActivatedService fields a
+// reload may change.
+type socketActivatedSnapshot struct {
+	command    []string
+	workingDir string
+	envFile    string
+	runAsUID   uint32
+	runAsGID   uint32
+	mode       SocketActivationMode
+}
+
+// SaveSnapshot copies the configuration a reload may change.
+func (s *SocketActivatedService) SaveSnapshot() ServiceSnapshot {
+	return &socketActivatedSnapshot{
+		command:    s.command,
+		workingDir: s.workingDir,
+		envFile:    s.envFile,
+		runAsUID:   s.runAsUID,
+		runAsGID:   s.runAsGID,
+		mode:       s.mode,
+	}
+}
+
+// RestoreSnapshot puts back configuration saved by SaveSnapshot.
+// Snapshots of another service type are ignored.
+func (s *SocketActivatedService) RestoreSnapshot(snap ServiceSnapshot) {
+	c, ok := snap.(*socketActivatedSnapshot)
+	if !ok {
+		return
+	}
+	s.command = c.command
+	s.workingDir = c.workingDir
+	s.envFile = c.envFile
+	s.runAsUID = c.runAsUID
+	s.runAsGID = c.runAsGID
+	s.mode = c.mode
+}
+
+// triggeredSnapshot holds the TriggeredService fields a reload may
+// change. SetTriggerNames drops named triggers that are no longer
+// declared, so their state is saved too.
+type triggeredSnapshot struct {
+	triggerNames  []string
+	triggerAll    bool
+	namedTriggers map[string]bool
+}
+
+// SaveSnapshot copies the configuration a reload may change.
+func (s *TriggeredService) SaveSnapshot() ServiceSnapshot {
+	return &triggeredSnapshot{
+		triggerNames:  s.triggerNames,
+		triggerAll:    s.triggerAll,
+		namedTriggers: maps.Clone(s.namedTriggers),
+	}
+}
+
+// RestoreSnapshot puts back configuration saved by SaveSnapshot.
+// Snapshots of another service type are ignored.
+func (s *TriggeredService) RestoreSnapshot(snap ServiceSnapshot) {
+	c, ok := snap.(*triggeredSnapshot)
+	if !ok {
+		return
+	}
+	s.triggerNames = c.triggerNames
+	s.triggerAll = c.triggerAll
+	s.namedTriggers = c.namedTriggers
+}
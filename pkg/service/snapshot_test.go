@@ -0,0 +1,62 @@
+package service
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestProcessSnapshotRestore(t *testing.T) {
+	set, _ := newTestSet()
+	svc := NewProcessService(set, "snap")
+	svc.SetCommand([]string{"/bin/old"})
+	svc.SetStopTimeout(5 * time.Second)
+	svc.SetRestartLimits(10*time.Second, 3)
+
+	snap := svc.SaveSnapshot()
+	svc.SetCommand([]string{"/bin/new"})
+	svc.SetStopTimeout(20 * time.Second)
+	svc.SetRestartLimits(time.Minute, 9)
+	svc.RestoreSnapshot(snap)
+
+	if !slices.Equal(svc.command, []string{"/bin/old"}) {
+		t.Errorf("command = %v, want [/bin/old]", svc.command)
+	}
+	if svc.StopTimeout() != 5*time.Second {
+		t.Errorf("stop timeout = %v, want 5s", svc.StopTimeout())
+	}
+	if svc.restartInterval != 10*time.Second || svc.maxRestartCount != 3 {
+		t.Errorf("restart limits = %v/%d, want 10s/3", svc.restartInterval, svc.maxRestartCount)
+	}
+}
+
+func TestTriggeredSnapshotRestoresNamedTriggers(t *testing.T) {
+	set, _ := newTestSet()
+	svc := NewTriggeredService(set, "trig")
+	svc.SetTriggerNames([]string{"a", "b"}, true)
+	svc.SetNamedTrigger("a", true)
+
+	snap := svc.SaveSnapshot()
+	svc.SetTriggerNames([]string{"b"}, false)
+	svc.RestoreSnapshot(snap)
+
+	names, all := svc.TriggerNames()
+	if !slices.Equal(names, []string{"a", "b"}) || !all {
+		t.Errorf("trigger names = %v all=%v, want [a b] all=true", names, all)
+	}
+	if !svc.namedTriggers["a"] {
+		t.Error("named trigger a lost after restore")
+	}
+}
+
+func TestRestoreSnapshotIgnoresOtherType(t *testing.T) {
+	set, _ := newTestSet()
+	proc := NewProcessService(set, "proc")
+	proc.SetCommand([]string{"/bin/proc"})
+	scripted := NewScriptedService(set, "scripted")
+
+	proc.RestoreSnapshot(scripted.SaveSnapshot())
+	if !slices.Equal(proc.command, []string{"/bin/proc"}) {
+		t.Errorf("command = %v after foreign snapshot, want [/bin/proc]", proc.command)
+	}
+}
@@ -321,3 +321,68 @@ func TestOnDemandWatcherStartStop(t *testing.T) {
 	// Stop watcher — should not panic
 	svc.stopOnDemandWatcher()
 }
+
+func TestOnDemandActivation(t *testing.T) {
+	tmpDir := t.TempDir()
+	sockPath := filepath.Join(tmpDir, "demand.sock")
+
+	set, _ := newTestSet()
+	svc := NewProcessService(set, "demand-svc")
+	svc.SetCommand([]string{"/bin/sleep", "60"})
+	svc.Record().SetSocketDetails(sockPath, 0600, -1, -1)
+	svc.SetSocketOnDemand(true)
+	set.AddService(svc)
+
+	svc.ArmSocketActivation()
+	defer svc.DisarmSocketActivation()
+
+	if _, err := os.Stat(sockPath); err != nil {
+		t.Fatalf("socket not bound after arming: %v", err)
+	}
+	time.Sleep(100 * time.Millisecond)
+	if svc.State() != StateStopped {
+		t.Fatalf("expected STOPPED before any connection, got %v", svc.State())
+	}
+
+	conn, err := net.Dial("unix", sockPath)
+	if err != nil {
+		t.Fatalf("dial failed: %v", err)
+	}
+	defer conn.Close()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for svc.State() != StateStarted && time.Now().Before(deadline) {
+		time.Sleep(20 * time.Millisecond)
+	}
+	if svc.State() != StateStarted {
+		t.Fatalf("expected STARTED after connection, got %v", svc.State())
+	}
+	if svc.PID() <= 0 {
+		t.Fatal("expected a running process")
+	}
+
+	// Drain the pending connection as the service would, otherwise the
+	// re-armed watcher starts it again right after the stop.
+	ln, err := net.FileListener(svc.socketFD)
+	if err != nil {
+		t.Fatalf("FileListener: %v", err)
+	}
+	if c, err := ln.Accept(); err == nil {
+		c.Close()
+	}
+	ln.Close()
+
+	set.StopService(svc)
+	deadline = time.Now().Add(2 * time.Second)
+	for svc.State() != StateStopped && time.Now().Before(deadline) {
+		time.Sleep(20 * time.Millisecond)
+	}
+	if svc.State() != StateStopped {
+		t.Fatalf("expected STOPPED, got %v", svc.State())
+	}
+
+	// The socket stays bound so the next connection starts it again.
+	if _, err := os.Stat(sockPath); err != nil {
+		t.Errorf("socket removed after stop: %v", err)
+	}
+}
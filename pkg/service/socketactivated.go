@@ -0,0 +1,347 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"golang.org/x/sys/unix"
+
+	"github.com/sunlightlinux/slinit/pkg/process"
+)
+
+// SocketActivationMode selects how a socket-activated service hands
+// connections to its command.
+type SocketActivationMode uint8
+
+const (
+	// SocketModeInetd accepts each connection and spawns one process
+	// per connection with the connected socket as stdin/stdout/stderr
+	// (inetd "nowait").
+	SocketModeInetd SocketActivationMode = iota
+	// SocketModeSystemd spawns one process when the socket becomes
+	// readable and passes the listening socket as fd 3 (LISTEN_FDS=1).
+	// The process accepts connections itself; the socket is watched
+	// again once it exits.
+	SocketModeSystemd
+)
+
+func (m SocketActivationMode) String() string {
+	if m == SocketModeSystemd {
+		return "systemd"
+	}
+	return "inetd"
+}
+
+// ParseSocketActivationMode decodes the socket-activation-mode value.
+func ParseSocketActivationMode(s string) (SocketActivationMode, error) {
+	switch s {
+	case "", "inetd":
+		return SocketModeInetd, nil
+	case "systemd":
+		return SocketModeSystemd, nil
+	}
+	return 0, fmt.Errorf("unknown socket-activation-mode %q (use inetd|systemd)", s)
+}
+
+// socketPollTimeoutMs bounds how long the connection watcher blocks in
+// poll before re-checking for a stop request.
+const socketPollTimeoutMs = 200
+
+// socketSpawnRetryDelay throttles respawning in systemd mode when the
+// command cannot be started, so a pending connection does not turn the
+// watcher into a busy loop.
+const socketSpawnRetryDelay = time.Second
+
+// SocketActivatedService owns a listening socket (the first socket-listen
+// path) and runs its command on demand when connections arrive. It is
+// STARTED for as long as the socket is bound, whether or not a process
+// is currently running.
+type SocketActivatedService struct {
+	ServiceRecord
+
+	command    []string
+	workingDir string
+	envFile    string
+	runAsUID   uint32
+	runAsGID   uint32
+	mode       SocketActivationMode
+
+	listenFD    *os.File
+	watchStop   chan struct{}
+	watchDone   chan struct{}
+	childrenMu  sync.Mutex
+	children    map[int]struct{}
+	connections uint64 // processes spawned since BringUp
+}
+
+// NewSocketActivatedService creates a new socket-activated service.
+func NewSocketActivatedService(set *ServiceSet, name string) *SocketActivatedService {
+	svc := &SocketActivatedService{
+		children: make(map[int]struct{}),
+	}
+	svc.ServiceRecord = *NewServiceRecord(svc, set, name, TypeSocketActivated)
+	return svc
+}
+
+// SetCommand sets the command spawned for connections.
+func (s *SocketActivatedService) SetCommand(cmd []string) { s.command = cmd }
+
+// SetWorkingDir sets the working directory for spawned processes.
+func (s *SocketActivatedService) SetWorkingDir(dir string) { s.workingDir = dir }
+
+// SetEnvFile sets the environment file for spawned processes.
+func (s *SocketActivatedService) SetEnvFile(path string) { s.envFile = path }
+
+// SetRunAs sets the UID/GID spawned processes run as.
+func (s *SocketActivatedService) SetRunAs(uid, gid uint32) {
+	s.runAsUID = uid
+	s.runAsGID = gid
+}
+
+// SetSocketActivationMode selects inetd or systemd connection handling.
+func (s *SocketActivatedService) SetSocketActivationMode(m SocketActivationMode) { s.mode = m }
+
+// SocketActivationMode returns the configured connection handling mode.
+func (s *SocketActivatedService) SocketActivationMode() SocketActivationMode { return s.mode }
+
+// ActiveProcesses returns the number of spawned processes still running.
+func (s *SocketActivatedService) ActiveProcesses() int {
+	s.childrenMu.Lock()
+	defer s.childrenMu.Unlock()
+	return len(s.children)
+}
+
+// Connections returns how many processes were spawned since the socket
+// was last bound.
+func (s *SocketActivatedService) Connections() uint64 {
+	s.childrenMu.Lock()
+	defer s.childrenMu.Unlock()
+	return s.connections
+}
+
+// BringUp binds the socket, starts watching it for connections and marks
+// the service STARTED. No process is spawned until a client connects.
+func (s *SocketActivatedService) BringUp() bool {
+	if len(s.command) == 0 {
+		s.services.logger.Error("Service '%s': no command specified", s.serviceName)
+		return false
+	}
+	if s.socketPath == "" {
+		s.services.logger.Error("Service '%s': no socket-listen specified", s.serviceName)
+		return false
+	}
+	if !s.preExecCheck(process.ExecParams{
+		Command:     s.command,
+		WorkingDir:  s.workingDir,
+		EnvFile:     s.envFile,
+		SocketPaths: []string{s.socketPath},
+		RunAsUID:    s.runAsUID,
+		RunAsGID:    s.runAsGID,
+	}) {
+		return false
+	}
+	if s.mode == SocketModeInetd && isDatagramSocketPath(s.socketPath) {
+		s.services.logger.Error("Service '%s': socket-activation-mode = inetd needs a stream socket, not %q",
+			s.serviceName, s.socketPath)
+		return false
+	}
+
+	fd, err := s.openOneSocket(s.socketPath)
+	if err != nil {
+		s.services.logger.Error("Service '%s': socket-listen %q: %v", s.serviceName, s.socketPath, err)
+		return false
+	}
+	if s.mode == SocketModeInetd {
+		// Only slinit accepts in inetd mode, so a connection reset
+		// between poll and accept must not block the watcher.
+		if err := unix.SetNonblock(int(fd.Fd()), true); err != nil {
+			fd.Close()
+			s.removeSocketFile()
+			s.services.logger.Error("Service '%s': socket-listen %q: %v", s.serviceName, s.socketPath, err)
+			return false
+		}
+	}
+
+	s.listenFD = fd
+	s.childrenMu.Lock()
+	s.connections = 0
+	s.childrenMu.Unlock()
+	s.watchStop = make(chan struct{})
+	s.watchDone = make(chan struct{})
+	go s.watch(fd, s.mode, s.watchStop, s.watchDone)
+
+	s.services.logger.Info("Service '%s': listening on %s (%s mode)", s.serviceName, s.socketPath, s.mode)
+	s.Started()
+	return true
+}
+
+// BringDown stops watching the socket, closes it and signals any
+// processes still serving connections. The service is STOPPED as soon as
+// the socket is closed; connection handlers are not waited for.
+func (s *SocketActivatedService) BringDown() {
+	if s.watchStop != nil {
+		close(s.watchStop)
+		<-s.watchDone
+		s.watchStop = nil
+		s.watchDone = nil
+	}
+	if s.listenFD != nil {
+		s.listenFD.Close()
+		s.listenFD = nil
+		s.removeSocketFile()
+	}
+
+	sig := s.termSignal
+	if sig == 0 {
+		sig = syscall.SIGTERM
+	}
+	s.childrenMu.Lock()
+	for pid := range s.children {
+		process.SignalProcess(pid, sig, s.Flags.SignalProcessOnly)
+	}
+	s.childrenMu.Unlock()
+
+	s.Stopped()
+}
+
+// CanInterruptStart returns true since BringUp completes synchronously.
+func (s *SocketActivatedService) CanInterruptStart() bool {
+	return true
+}
+
+// InterruptStart cancels the start immediately.
+func (s *SocketActivatedService) InterruptStart() bool {
+	return true
+}
+
+// watch polls the listening socket until stop is closed, spawning the
+// command for each connection (inetd) or each time the socket becomes
+// readable with no process running (systemd).
+func (s *SocketActivatedService) watch(ln *os.File, mode SocketActivationMode, stop, done chan struct{}) {
+	defer close(done)
+	lfd := int(ln.Fd())
+	fds := []unix.PollFd{{Fd: int32(lfd), Events: unix.POLLIN}}
+	for {
+		select {
+		case <-stop:
+			return
+		default:
+		}
+		fds[0].Revents = 0
+		n, err := unix.Poll(fds, socketPollTimeoutMs)
+		if err != nil {
+			if errors.Is(err, syscall.EINTR) {
+				continue
+			}
+			s.services.logger.Error("Service '%s': socket poll failed: %v", s.serviceName, err)
+			return
+		}
+		if n == 0 || fds[0].Revents&unix.POLLIN == 0 {
+			continue
+		}
+
+		if mode == SocketModeSystemd {
+			exited := s.spawn(nil, ln)
+			if exited == nil {
+				select {
+				case <-time.After(socketSpawnRetryDelay):
+				case <-stop:
+					return
+				}
+				continue
+			}
+			// The process owns the socket while it runs; resume
+			// watching once it exits so the next connection starts a
+			// fresh one.
+			select {
+			case <-exited:
+			case <-stop:
+				return
+			}
+			continue
+		}
+
+		nfd, _, err := unix.Accept4(lfd, unix.SOCK_CLOEXEC)
+		if err != nil {
+			if !errors.Is(err, syscall.EAGAIN) && !errors.Is(err, syscall.EINTR) &&
+				!errors.Is(err, syscall.ECONNABORTED) {
+				s.services.logger.Error("Service '%s': accept failed: %v", s.serviceName, err)
+			}
+			continue
+		}
+		conn := os.NewFile(uintptr(nfd), "socket-activated-conn")
+		s.spawn(conn, nil)
+		// The child holds its own copy; the connection lives on
+		// until it closes it.
+		conn.Close()
+	}
+}
+
+// spawn starts the command for one activation: conn becomes the child's
+// stdin/stdout/stderr (inetd), or ln is passed as fd 3 (systemd).
+// Returns a channel that is closed when the process exits, or nil if it
+// could not be started.
+func (s *SocketActivatedService) spawn(conn, ln *os.File) <-chan struct{} {
+	params := process.ExecParams{
+		Command:           s.command,
+		WorkingDir:        s.workingDir,
+		Env:               s.Record().BuildEnvWithFile(s.envFile),
+		TermSignal:        s.termSignal,
+		SignalProcessOnly: s.Flags.SignalProcessOnly,
+		RunAsUID:          s.runAsUID,
+		RunAsGID:          s.runAsGID,
+	}
+	if conn != nil {
+		params.InputPipe = conn
+		params.OutputPipe = conn
+	} else {
+		params.SocketFD = ln
+	}
+	s.Record().ApplyProcessAttrs(&params)
+
+	pid, exitCh, err := process.StartProcess(params)
+	if err != nil {
+		s.services.logger.Error("Service '%s': failed to start: %v", s.serviceName, err)
+		return nil
+	}
+	s.childrenMu.Lock()
+	s.children[pid] = struct{}{}
+	s.connections++
+	s.childrenMu.Unlock()
+
+	exited := make(chan struct{})
+	go func() {
+		defer close(exited)
+		exit := <-exitCh
+		s.childrenMu.Lock()
+		delete(s.children, pid)
+		s.childrenMu.Unlock()
+		if exit.ExecErr != nil {
+			s.services.logger.Error("Service '%s': pid %d: %v", s.serviceName, pid, exit.ExecErr)
+		} else if !exit.Exited() || exit.Status.ExitStatus() != 0 {
+			s.services.logger.Info("Service '%s': pid %d exited with status %v",
+				s.serviceName, pid, exit.Status)
+		}
+	}()
+	return exited
+}
+
+// removeSocketFile unlinks a Unix socket path; tcp:/udp: addresses have
+// nothing on disk.
+func (s *SocketActivatedService) removeSocketFile() {
+	if !strings.Contains(s.socketPath, ":") {
+		os.Remove(s.socketPath)
+	}
+}
+
+// isDatagramSocketPath reports whether a socket-listen address names a
+// UDP socket, which has no connections to accept.
+func isDatagramSocketPath(path string) bool {
+	return strings.HasPrefix(path, "udp:") || strings.HasPrefix(path, "udp4:") ||
+		strings.HasPrefix(path, "udp6:")
+}
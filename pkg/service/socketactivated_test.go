@@ -0,0 +1,143 @@
+package service
+
+import (
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSocketActivatedInetd(t *testing.T) {
+	sockPath := filepath.Join(t.TempDir(), "inetd.sock")
+
+	set, _ := newTestSet()
+	svc := NewSocketActivatedService(set, "echo-svc")
+	svc.SetCommand([]string{"/bin/sh", "-c", "read line; echo \"got $line\""})
+	svc.Record().SetSocketDetails(sockPath, 0600, -1, -1)
+	set.AddService(svc)
+
+	set.StartService(svc)
+	if svc.State() != StateStarted {
+		t.Fatalf("expected STARTED with socket bound, got %v", svc.State())
+	}
+	if svc.ActiveProcesses() != 0 {
+		t.Fatalf("no process should run before a connection, got %d", svc.ActiveProcesses())
+	}
+
+	// Two connections get two independent processes.
+	for _, msg := range []string{"one", "two"} {
+		conn, err := net.Dial("unix", sockPath)
+		if err != nil {
+			t.Fatalf("dial: %v", err)
+		}
+		conn.SetDeadline(time.Now().Add(5 * time.Second))
+		if _, err := conn.Write([]byte(msg + "\n")); err != nil {
+			t.Fatalf("write: %v", err)
+		}
+		reply, err := io.ReadAll(conn)
+		conn.Close()
+		if err != nil {
+			t.Fatalf("read: %v", err)
+		}
+		if got := strings.TrimSpace(string(reply)); got != "got "+msg {
+			t.Errorf("reply = %q, want %q", got, "got "+msg)
+		}
+	}
+	if n := svc.Connections(); n != 2 {
+		t.Errorf("Connections() = %d, want 2", n)
+	}
+
+	set.StopService(svc)
+	if svc.State() != StateStopped {
+		t.Fatalf("expected STOPPED, got %v", svc.State())
+	}
+	if _, err := os.Stat(sockPath); !os.IsNotExist(err) {
+		t.Errorf("socket file should be removed on stop, stat err = %v", err)
+	}
+}
+
+func TestSocketActivatedSystemd(t *testing.T) {
+	dir := t.TempDir()
+	sockPath := filepath.Join(dir, "systemd.sock")
+	outFile := filepath.Join(dir, "env")
+
+	set, _ := newTestSet()
+	svc := NewSocketActivatedService(set, "sd-svc")
+	svc.SetSocketActivationMode(SocketModeSystemd)
+	// Record the activation environment, then hold the socket until
+	// stopped without accepting.
+	svc.SetCommand([]string{"/bin/sh", "-c",
+		"echo \"$LISTEN_FDS\" > " + outFile + "; exec sleep 60"})
+	svc.Record().SetSocketDetails(sockPath, 0600, -1, -1)
+	set.AddService(svc)
+
+	set.StartService(svc)
+	if svc.State() != StateStarted {
+		t.Fatalf("expected STARTED, got %v", svc.State())
+	}
+
+	conn, err := net.Dial("unix", sockPath)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer conn.Close()
+
+	deadline := time.Now().Add(5 * time.Second)
+	var data []byte
+	for time.Now().Before(deadline) {
+		data, _ = os.ReadFile(outFile)
+		if len(data) > 0 {
+			break
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	if got := strings.TrimSpace(string(data)); got != "1" {
+		t.Fatalf("LISTEN_FDS = %q, want 1", got)
+	}
+
+	// One process owns the socket; a pending connection must not
+	// spawn a second one while it runs.
+	time.Sleep(100 * time.Millisecond)
+	if n := svc.Connections(); n != 1 {
+		t.Errorf("Connections() = %d, want 1", n)
+	}
+
+	set.StopService(svc)
+	deadline = time.Now().Add(5 * time.Second)
+	for svc.ActiveProcesses() > 0 && time.Now().Before(deadline) {
+		time.Sleep(20 * time.Millisecond)
+	}
+	if n := svc.ActiveProcesses(); n != 0 {
+		t.Errorf("process still running after stop: %d", n)
+	}
+}
+
+func TestSocketActivatedRejectsUDPInInetdMode(t *testing.T) {
+	set, _ := newTestSet()
+	svc := NewSocketActivatedService(set, "udp-svc")
+	svc.SetCommand([]string{"/bin/true"})
+	svc.Record().SetSocketDetails("udp:127.0.0.1:0", 0, -1, -1)
+	set.AddService(svc)
+
+	set.StartService(svc)
+	if svc.State() != StateStopped {
+		t.Errorf("expected start to fail, got %v", svc.State())
+	}
+}
+
+func TestParseSocketActivationMode(t *testing.T) {
+	for in, want := range map[string]SocketActivationMode{
+		"": SocketModeInetd, "inetd": SocketModeInetd, "systemd": SocketModeSystemd,
+	} {
+		got, err := ParseSocketActivationMode(in)
+		if err != nil || got != want {
+			t.Errorf("ParseSocketActivationMode(%q) = %v, %v; want %v", in, got, err, want)
+		}
+	}
+	if _, err := ParseSocketActivationMode("xinetd"); err == nil {
+		t.Error("expected error for unknown mode")
+	}
+}
@@ -0,0 +1,93 @@
+package service
+
+import (
+	"context"
+	"syscall"
+	"time"
+
+	"github.com/sunlightlinux/slinit/pkg/process"
+)
+
+// Per-service stop deadlines for shutdown (slinit
+// --service-stop-timeout). A service's own stop-timeout already
+// escalates to SIGKILL, but only while the service is in charge of its
+// stop; a deadline is a hard cap set by the operator for this shutdown,
+// so one hung service cannot hold the whole shutdown until the
+// event loop's emergency timeout.
+
+// StopAllServicesWithDeadlines stops every service like StopAllServices
+// and arms the deadlines in deadlines (see ArmStopDeadlines).
+func (ss *ServiceSet) StopAllServicesWithDeadlines(ctx context.Context, shutdownType ShutdownType, deadlines map[string]time.Duration) {
+	ss.StopAllServices(shutdownType)
+	ss.ArmStopDeadlines(ctx, deadlines)
+}
+
+// ArmStopDeadlines arms, for each service named in deadlines that is
+// still stopping, a timer that SIGKILLs its process when the deadline
+// passes. The timers are disarmed as each service stops, and all of
+// them when ctx is done.
+func (ss *ServiceSet) ArmStopDeadlines(ctx context.Context, deadlines map[string]time.Duration) {
+	var armed []Service
+	ss.queueMu.Lock()
+	for name, d := range deadlines {
+		svc := ss.FindService(name, false)
+		if svc == nil || svc.State() == StateStopped {
+			continue
+		}
+		ss.armStopDeadlineLocked(svc, d)
+		armed = append(armed, svc)
+	}
+	ss.queueMu.Unlock()
+
+	if len(armed) > 0 {
+		context.AfterFunc(ctx, func() {
+			ss.queueMu.Lock()
+			defer ss.queueMu.Unlock()
+			for _, svc := range armed {
+				svc.Record().cancelStopDeadline()
+			}
+		})
+	}
+}
+
+// StopDeadlinedService arms a timer that sends SIGKILL to svc's process
+// group if the service has not stopped within deadline. The timer is
+// disarmed when the service reaches STOPPED. It does not itself stop
+// the service.
+func (ss *ServiceSet) StopDeadlinedService(svc Service, deadline time.Duration) {
+	ss.queueMu.Lock()
+	defer ss.queueMu.Unlock()
+	ss.armStopDeadlineLocked(svc, deadline)
+}
+
+func (ss *ServiceSet) armStopDeadlineLocked(svc Service, d time.Duration) {
+	rec := svc.Record()
+	rec.cancelStopDeadline()
+	if d <= 0 {
+		return
+	}
+	name := rec.serviceName
+	rec.stopDeadlineTimer = time.AfterFunc(d, func() {
+		ss.queueMu.Lock()
+		defer ss.queueMu.Unlock()
+		if svc.State() == StateStopped {
+			return
+		}
+		pid := svc.PID()
+		if pid <= 0 {
+			return
+		}
+		ss.logger.Error("Service '%s': did not stop within its %s shutdown deadline, sending SIGKILL",
+			name, d)
+		process.SignalProcess(pid, syscall.SIGKILL, false)
+	})
+}
+
+// cancelStopDeadline disarms the stop deadline timer if armed. Safe to
+// call when no timer is active.
+func (sr *ServiceRecord) cancelStopDeadline() {
+	if sr.stopDeadlineTimer != nil {
+		sr.stopDeadlineTimer.Stop()
+		sr.stopDeadlineTimer = nil
+	}
+}
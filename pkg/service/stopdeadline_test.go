@@ -0,0 +1,75 @@
+package service
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// startStubborn starts a process service that ignores SIGTERM and has
+// a stop-timeout far longer than the test, so only a deadline stops it.
+func startStubborn(t *testing.T, set *ServiceSet, name string) *ProcessService {
+	t.Helper()
+	marker := filepath.Join(t.TempDir(), "ready")
+	svc := NewProcessService(set, name)
+	svc.SetCommand([]string{"/bin/sh", "-c", "trap '' TERM; touch " + marker + "; sleep 60"})
+	svc.SetStopTimeout(time.Minute)
+	set.AddService(svc)
+	set.StartService(svc)
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		if _, err := os.Stat(marker); err == nil && svc.State() == StateStarted {
+			return svc
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("%s did not start", name)
+	return nil
+}
+
+func waitStopped(svc Service, within time.Duration) bool {
+	deadline := time.Now().Add(within)
+	for time.Now().Before(deadline) {
+		if svc.State() == StateStopped {
+			return true
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	return false
+}
+
+func TestStopAllServicesWithDeadlines(t *testing.T) {
+	set, _ := newTestSet()
+	hung := startStubborn(t, set, "hung")
+	other := startStubborn(t, set, "other")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	set.StopAllServicesWithDeadlines(ctx, ShutdownPoweroff,
+		map[string]time.Duration{"hung": 100 * time.Millisecond, "missing": time.Second})
+
+	if !waitStopped(hung, 3*time.Second) {
+		t.Fatalf("hung still %v after its deadline", hung.State())
+	}
+	if other.State() != StateStopping {
+		t.Errorf("service without a deadline is %v, want STOPPING", other.State())
+	}
+	set.KillService(other)
+}
+
+func TestStopDeadlineCancelledOnContextDone(t *testing.T) {
+	set, _ := newTestSet()
+	svc := startStubborn(t, set, "hung")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	set.StopAllServicesWithDeadlines(ctx, ShutdownPoweroff,
+		map[string]time.Duration{"hung": 200 * time.Millisecond})
+	cancel()
+
+	if waitStopped(svc, 500*time.Millisecond) {
+		t.Fatal("deadline fired after its context was cancelled")
+	}
+	set.KillService(svc)
+}
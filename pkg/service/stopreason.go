@@ -0,0 +1,64 @@
+package service
+
+import (
+	"fmt"
+)
+
+// SetCustomStopReason records an application-defined reason for the
+// service stopping, e.g. from a health-check script that found its
+// filesystem missing. code must be ReasonCustom or above. The reason is
+// reported by StopReason and CustomStopReason until the service next
+// starts; set it before stopping the service, or after it has stopped.
+func (sr *ServiceRecord) SetCustomStopReason(code StoppedReason, msg string) error {
+	if code < ReasonCustom {
+		return fmt.Errorf("stop reason %d is reserved (custom reasons start at %d)", code, ReasonCustom)
+	}
+	sr.customStopMu.Lock()
+	sr.customStopCode = code
+	sr.customStopReason = msg
+	sr.customStopMu.Unlock()
+	return nil
+}
+
+// CustomStopReason returns the message set by SetCustomStopReason, or
+// "" if none is set.
+func (sr *ServiceRecord) CustomStopReason() string {
+	sr.customStopMu.Lock()
+	defer sr.customStopMu.Unlock()
+	return sr.customStopReason
+}
+
+// SetLastErrorMessage records the error output of a failed start.
+func (sr *ServiceRecord) SetLastErrorMessage(msg string) {
+	sr.customStopMu.Lock()
+	sr.lastErrorMessage = msg
+	sr.customStopMu.Unlock()
+}
+
+// LastErrorMessage returns the error output captured from the last
+// failed start, or "" if none was captured.
+func (sr *ServiceRecord) LastErrorMessage() string {
+	sr.customStopMu.Lock()
+	defer sr.customStopMu.Unlock()
+	return sr.lastErrorMessage
+}
+
+// StopReason returns why the service last stopped: the custom reason
+// when one is set, otherwise the reason slinit recorded.
+func (sr *ServiceRecord) StopReason() StoppedReason {
+	sr.customStopMu.Lock()
+	defer sr.customStopMu.Unlock()
+	if sr.customStopCode != 0 {
+		return sr.customStopCode
+	}
+	return sr.stopReason
+}
+
+// clearCustomStopReason drops the custom reason when the service starts
+// again; it explained the previous stop, not the next one.
+func (sr *ServiceRecord) clearCustomStopReason() {
+	sr.customStopMu.Lock()
+	sr.customStopCode = 0
+	sr.customStopReason = ""
+	sr.customStopMu.Unlock()
+}
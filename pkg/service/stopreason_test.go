@@ -0,0 +1,39 @@
+package service
+
+import "testing"
+
+func TestCustomStopReason(t *testing.T) {
+	set, _ := newTestSet()
+	svc := NewInternalService(set, "checked")
+	set.AddService(svc)
+	rec := svc.Record()
+
+	if err := rec.SetCustomStopReason(ReasonTimedOut, "x"); err == nil {
+		t.Error("expected an error for a built-in reason code")
+	}
+
+	set.StartService(svc)
+	if err := rec.SetCustomStopReason(ReasonCustom+1, "filesystem not mounted"); err != nil {
+		t.Fatal(err)
+	}
+	set.StopService(svc)
+
+	if svc.State() != StateStopped {
+		t.Fatalf("state = %v, want STOPPED", svc.State())
+	}
+	if r := rec.StopReason(); r != ReasonCustom+1 || r.String() != "custom" {
+		t.Errorf("StopReason = %v (%d), want custom %d", r, r, ReasonCustom+1)
+	}
+	if msg := rec.CustomStopReason(); msg != "filesystem not mounted" {
+		t.Errorf("CustomStopReason = %q", msg)
+	}
+
+	// The reason explained the last stop; starting again clears it.
+	set.StartService(svc)
+	if r := rec.StopReason(); r >= ReasonCustom {
+		t.Errorf("StopReason after restart = %v, want a built-in reason", r)
+	}
+	if msg := rec.CustomStopReason(); msg != "" {
+		t.Errorf("CustomStopReason after restart = %q, want empty", msg)
+	}
+}
@@ -0,0 +1,72 @@
+package service
+
+import (
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/sunlightlinux/slinit/pkg/process"
+)
+
+// StopSignalStep is one step of a stop-signal-sequence: deliver Signal,
+// then give the process TimeoutSeconds to exit before the next step.
+// Only the last step may have no timeout, meaning it is waited on
+// indefinitely. The plain term-signal + stop-timeout behaviour is the
+// sequence SIGTERM:<stop-timeout>,SIGKILL.
+type StopSignalStep struct {
+	Signal         syscall.Signal
+	TimeoutSeconds float64
+}
+
+// Timeout returns the step's timeout as a duration.
+func (st StopSignalStep) Timeout() time.Duration {
+	return time.Duration(st.TimeoutSeconds * float64(time.Second))
+}
+
+// String renders the step in stop-signal-sequence syntax, e.g. "TERM:5".
+func (st StopSignalStep) String() string {
+	s := signalName(st.Signal)
+	if st.TimeoutSeconds > 0 {
+		s += ":" + strconv.FormatFloat(st.TimeoutSeconds, 'f', -1, 64)
+	}
+	return s
+}
+
+// SetStopSignalSequence sets the stop-signal-sequence; nil restores the
+// term-signal + stop-timeout escalation.
+func (sr *ServiceRecord) SetStopSignalSequence(seq []StopSignalStep) { sr.stopSignalSeq = seq }
+
+// StopSignalSequence returns the configured stop-signal-sequence.
+func (sr *ServiceRecord) StopSignalSequence() []StopSignalStep { return sr.stopSignalSeq }
+
+// armStopSequenceStep arms the timeout of the current stop-signal-
+// sequence step, if it has one.
+func (s *ProcessService) armStopSequenceStep() {
+	if d := s.Record().StopSignalSequence()[s.stopSeqStep].Timeout(); d > 0 {
+		s.armTimer(d, timerStopSequence)
+	}
+}
+
+// advanceStopSequence moves a stop-signal-sequence on to its next step
+// once the current step's timeout expired: delivers that step's signal
+// and arms its timeout. Returns false when the sequence is exhausted
+// (its last step had a timeout), leaving the caller to apply the
+// regular stop-timeout escalation. Called with queueMu held.
+func (s *ProcessService) advanceStopSequence() bool {
+	seq := s.Record().StopSignalSequence()
+	s.stopSeqStep++
+	if s.stopSeqStep >= len(seq) {
+		return false
+	}
+	sig := seq[s.stopSeqStep].Signal
+	if s.pid > 0 {
+		s.services.logger.Error("Service '%s': still running after stop-signal-sequence step %d, sending %v",
+			s.serviceName, s.stopSeqStep, sig)
+		process.SignalProcess(s.pid, sig, s.Flags.SignalProcessOnly)
+	}
+	if s.killsToGroup() {
+		s.killCgroupTree(sig)
+	}
+	s.armStopSequenceStep()
+	return true
+}
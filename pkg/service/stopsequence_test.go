@@ -0,0 +1,74 @@
+package service
+
+import (
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// startTrapping starts a process service running script after it has
+// touched a readiness marker; stop-timeout is far longer than the test.
+func startTrapping(t *testing.T, set *ServiceSet, name, script string, seq []StopSignalStep) *ProcessService {
+	t.Helper()
+	marker := filepath.Join(t.TempDir(), "ready")
+	svc := NewProcessService(set, name)
+	svc.SetCommand([]string{"/bin/sh", "-c", script + "; touch " + marker + "; while :; do sleep 0.05; done"})
+	svc.SetStopTimeout(time.Minute)
+	svc.SetStopSignalSequence(seq)
+	set.AddService(svc)
+	set.StartService(svc)
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		if _, err := os.Stat(marker); err == nil && svc.State() == StateStarted {
+			return svc
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("%s did not start", name)
+	return nil
+}
+
+func TestStopSignalSequenceEscalates(t *testing.T) {
+	set, _ := newTestSet()
+	gotInt := filepath.Join(t.TempDir(), "int")
+	svc := startTrapping(t, set, "seq",
+		"trap '' TERM; trap 'touch "+gotInt+"; exit 0' INT",
+		[]StopSignalStep{
+			{Signal: syscall.SIGTERM, TimeoutSeconds: 0.2},
+			{Signal: syscall.SIGINT, TimeoutSeconds: 0.2},
+			{Signal: syscall.SIGKILL},
+		})
+
+	set.StopService(svc)
+	if !waitStopped(svc, 3*time.Second) {
+		t.Fatal("service ignored the sequence")
+	}
+	if _, err := os.Stat(gotInt); err != nil {
+		t.Error("SIGINT step was not delivered before the process exited")
+	}
+}
+
+func TestStopSignalSequenceExhaustedFallsBackToFinalKill(t *testing.T) {
+	set, _ := newTestSet()
+	svc := startTrapping(t, set, "stubborn", "trap '' TERM INT",
+		[]StopSignalStep{
+			{Signal: syscall.SIGTERM, TimeoutSeconds: 0.1},
+			{Signal: syscall.SIGINT, TimeoutSeconds: 0.1},
+		})
+
+	set.StopService(svc)
+	if !waitStopped(svc, 3*time.Second) {
+		t.Fatal("final-kill-signal did not follow the exhausted sequence")
+	}
+}
+
+func TestStopSignalStepString(t *testing.T) {
+	if got := (StopSignalStep{Signal: syscall.SIGTERM, TimeoutSeconds: 2.5}).String(); got != "TERM:2.5" {
+		t.Errorf("got %q", got)
+	}
+	if got := (StopSignalStep{Signal: syscall.SIGKILL}).String(); got != "KILL" {
+		t.Errorf("got %q", got)
+	}
+}
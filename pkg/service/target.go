@@ -0,0 +1,101 @@
+package service
+
+import (
+	"fmt"
+	"sort"
+)
+
+// TargetSwitchResult reports what SwitchTarget did.
+type TargetSwitchResult struct {
+	// Target is the service that is now the active top-level service.
+	Target string
+	// Released lists the services that were explicitly active and lost
+	// that mark because the new target does not need them.
+	Released []string
+	// Stopping lists the services on their way down as a result: the
+	// released ones nothing else requires, and their dependencies.
+	Stopping []string
+}
+
+// SwitchTarget makes target the active top-level service, the way a
+// runlevel change does (multi-user to rescue, say). target is started
+// and marked active; then every other explicitly activated service that
+// neither target nor a service pinned started depends on, directly or
+// not, is released. Reference counting does the rest: a released
+// service, and each dependency only it needed, stops once nothing
+// requires it any more, while services the new target shares with the
+// old one stay up.
+//
+// The start is processed before anything is released, so a shared
+// dependency is acquired by the new target before the old one lets go
+// and never bounces. Services pinned started keep running. Only
+// dependencies count toward what is kept; before/after ordering does
+// not.
+func (ss *ServiceSet) SwitchTarget(target Service) (*TargetSwitchResult, error) {
+	snapshot := ss.ListServices()
+
+	ss.queueMu.Lock()
+	defer ss.queueMu.Unlock()
+	if ss.IsShuttingDown() {
+		return nil, fmt.Errorf("system is shutting down")
+	}
+	if target.Record().IsStopPinned() {
+		return nil, fmt.Errorf("service '%s' is pinned stopped", target.Name())
+	}
+
+	target.Start()
+	ss.processQueuesLocked()
+
+	roots := []Service{target}
+	for _, svc := range snapshot {
+		if svc.Record().IsStartPinned() {
+			roots = append(roots, svc)
+		}
+	}
+	keep := dependencyClosure(roots)
+
+	wasUp := make(map[Service]bool, len(snapshot))
+	for _, svc := range snapshot {
+		wasUp[svc] = svc.State() != StateStopped
+	}
+
+	result := &TargetSwitchResult{Target: target.Name()}
+	for _, svc := range snapshot {
+		if keep[svc] || !svc.Record().IsMarkedActive() {
+			continue
+		}
+		svc.Stop(false)
+		result.Released = append(result.Released, svc.Name())
+	}
+	ss.processQueuesLocked()
+
+	for _, svc := range snapshot {
+		if wasUp[svc] && svc.Record().TargetState() == StateStopped {
+			result.Stopping = append(result.Stopping, svc.Name())
+		}
+	}
+	sort.Strings(result.Released)
+	sort.Strings(result.Stopping)
+	return result, nil
+}
+
+// dependencyClosure returns roots and every service they depend on,
+// directly or transitively, ignoring ordering-only links.
+func dependencyClosure(roots []Service) map[Service]bool {
+	seen := make(map[Service]bool)
+	stack := append([]Service(nil), roots...)
+	for len(stack) > 0 {
+		svc := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if seen[svc] {
+			continue
+		}
+		seen[svc] = true
+		for _, dep := range svc.Record().Dependencies() {
+			if !dep.IsOnlyOrdering() && !seen[dep.To] {
+				stack = append(stack, dep.To)
+			}
+		}
+	}
+	return seen
+}
@@ -0,0 +1,67 @@
+package service
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestSwitchTarget(t *testing.T) {
+	set, _ := newTestSet()
+	svcs := make(map[string]*InternalService)
+	for _, name := range []string{"multi-user", "rescue", "shared", "net", "getty", "logger"} {
+		svcs[name] = NewInternalService(set, name)
+		set.AddService(svcs[name])
+	}
+	svcs["multi-user"].Record().AddDep(svcs["shared"], DepRegular)
+	svcs["multi-user"].Record().AddDep(svcs["net"], DepWaitsFor)
+	svcs["rescue"].Record().AddDep(svcs["shared"], DepRegular)
+	svcs["rescue"].Record().AddDep(svcs["getty"], DepRegular)
+	svcs["multi-user"].Record().AddDep(svcs["rescue"], DepAfter) // ordering only
+
+	set.StartService(svcs["multi-user"])
+	set.StartService(svcs["getty"]) // explicitly active, but rescue needs it
+	set.StartService(svcs["logger"])
+	set.Apply(svcs["logger"].PinStart)
+
+	res, err := set.SwitchTarget(svcs["rescue"])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Target != "rescue" || !slices.Equal(res.Released, []string{"multi-user"}) {
+		t.Errorf("result = %+v, want target rescue with multi-user released", res)
+	}
+	if !slices.Equal(res.Stopping, []string{"multi-user", "net"}) {
+		t.Errorf("Stopping = %v, want [multi-user net]", res.Stopping)
+	}
+
+	want := map[string]ServiceState{
+		"rescue":     StateStarted,
+		"shared":     StateStarted,
+		"getty":      StateStarted,
+		"logger":     StateStarted,
+		"multi-user": StateStopped,
+		"net":        StateStopped,
+	}
+	for name, state := range want {
+		if got := svcs[name].State(); got != state {
+			t.Errorf("%s: state %v, want %v", name, got, state)
+		}
+	}
+	if !svcs["rescue"].Record().IsMarkedActive() {
+		t.Error("new target is not marked active")
+	}
+}
+
+func TestSwitchTargetPinnedStopped(t *testing.T) {
+	set, _ := newTestSet()
+	svc := NewInternalService(set, "rescue")
+	set.AddService(svc)
+	svc.PinStop()
+
+	if _, err := set.SwitchTarget(svc); err == nil {
+		t.Error("switch to a service pinned stopped succeeded")
+	}
+	if svc.State() != StateStopped {
+		t.Errorf("state %v, want STOPPED", svc.State())
+	}
+}
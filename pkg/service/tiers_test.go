@@ -0,0 +1,174 @@
+package service
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func tierNamesOf(tiers []ServiceTier) [][]string {
+	out := make([][]string, len(tiers))
+	for i, tier := range tiers {
+		for _, svc := range tier {
+			out[i] = append(out[i], svc.Name())
+		}
+	}
+	return out
+}
+
+func TestFlattenDependencyGraphDiamond(t *testing.T) {
+	set := newDepTestSet()
+	a := NewInternalService(set, "a")
+	b := NewInternalService(set, "b")
+	c := NewInternalService(set, "c")
+	d := NewInternalService(set, "d")
+
+	a.Record().AddDep(b, DepRegular)
+	a.Record().AddDep(c, DepRegular)
+	b.Record().AddDep(d, DepRegular)
+	c.Record().AddDep(d, DepWaitsFor)
+
+	tiers, err := set.FlattenDependencyGraph(a)
+	if err != nil {
+		t.Fatalf("flatten: %v", err)
+	}
+	want := [][]string{{"d"}, {"b", "c"}, {"a"}}
+	if got := tierNamesOf(tiers); !reflect.DeepEqual(got, want) {
+		t.Fatalf("tiers: got %v, want %v", got, want)
+	}
+
+	// The record method is a shorthand for the same computation.
+	tiers, err = a.Record().FlattenDependencyGraph()
+	if err != nil {
+		t.Fatalf("record flatten: %v", err)
+	}
+	if got := tierNamesOf(tiers); !reflect.DeepEqual(got, want) {
+		t.Fatalf("record tiers: got %v, want %v", got, want)
+	}
+}
+
+func TestFlattenDependencyGraphUnevenChains(t *testing.T) {
+	set := newDepTestSet()
+	root := NewInternalService(set, "root")
+	long1 := NewInternalService(set, "long1")
+	long2 := NewInternalService(set, "long2")
+	short := NewInternalService(set, "short")
+
+	// root → long1 → long2, root → short. A service must sit in the tier
+	// after its deepest dependency, not its shallowest.
+	root.Record().AddDep(long1, DepRegular)
+	root.Record().AddDep(short, DepRegular)
+	long1.Record().AddDep(long2, DepRegular)
+	// unrelated is loaded but not reachable from root, so it is left out.
+	NewInternalService(set, "unrelated")
+
+	tiers, err := set.FlattenDependencyGraph(root)
+	if err != nil {
+		t.Fatalf("flatten: %v", err)
+	}
+	want := [][]string{{"long2", "short"}, {"long1"}, {"root"}}
+	if got := tierNamesOf(tiers); !reflect.DeepEqual(got, want) {
+		t.Fatalf("tiers: got %v, want %v", got, want)
+	}
+}
+
+func TestFlattenDependencyGraphBeforeAfter(t *testing.T) {
+	set := newDepTestSet()
+	root := NewInternalService(set, "root")
+	a := NewInternalService(set, "a")
+	b := NewInternalService(set, "b")
+	c := NewInternalService(set, "c")
+
+	// "a before b" and "c after a" both put a ahead of the other two.
+	root.Record().AddDep(a, DepRegular)
+	root.Record().AddDep(b, DepRegular)
+	root.Record().AddDep(c, DepRegular)
+	a.Record().AddDep(b, DepBefore)
+	c.Record().AddDep(a, DepAfter)
+
+	tiers, err := set.FlattenDependencyGraph(root)
+	if err != nil {
+		t.Fatalf("flatten: %v", err)
+	}
+	want := [][]string{{"a"}, {"b", "c"}, {"root"}}
+	if got := tierNamesOf(tiers); !reflect.DeepEqual(got, want) {
+		t.Fatalf("tiers: got %v, want %v", got, want)
+	}
+}
+
+func TestFlattenDependencyGraphOrderingOnlyNotPulledIn(t *testing.T) {
+	set := newDepTestSet()
+	root := NewInternalService(set, "root")
+	a := NewInternalService(set, "a")
+	x := NewInternalService(set, "x")
+	y := NewInternalService(set, "y")
+
+	// Nothing requires x or y; the ordering edges to them must not drag
+	// them into root's tiers.
+	root.Record().AddDep(a, DepRegular)
+	root.Record().AddDep(x, DepAfter)
+	a.Record().AddDep(y, DepBefore)
+
+	tiers, err := set.FlattenDependencyGraph(root)
+	if err != nil {
+		t.Fatalf("flatten: %v", err)
+	}
+	want := [][]string{{"a"}, {"root"}}
+	if got := tierNamesOf(tiers); !reflect.DeepEqual(got, want) {
+		t.Fatalf("tiers: got %v, want %v", got, want)
+	}
+}
+
+func TestFlattenDependencyGraphSingle(t *testing.T) {
+	set := newDepTestSet()
+	a := NewInternalService(set, "a")
+
+	tiers, err := set.FlattenDependencyGraph(a)
+	if err != nil {
+		t.Fatalf("flatten: %v", err)
+	}
+	if got := tierNamesOf(tiers); !reflect.DeepEqual(got, [][]string{{"a"}}) {
+		t.Fatalf("tiers: got %v", got)
+	}
+}
+
+func TestFlattenDependencyGraphCycle(t *testing.T) {
+	set := newDepTestSet()
+	a := NewInternalService(set, "a")
+	b := NewInternalService(set, "b")
+	c := NewInternalService(set, "c")
+	d := NewInternalService(set, "d")
+
+	// a → b → c → b, plus an acyclic leaf d.
+	a.Record().AddDep(b, DepRegular)
+	a.Record().AddDep(d, DepRegular)
+	b.Record().AddDep(c, DepRegular)
+	c.Record().AddDep(b, DepRegular)
+
+	_, err := set.FlattenDependencyGraph(a)
+	var cycleErr *DependencyCycleError
+	if !errors.As(err, &cycleErr) {
+		t.Fatalf("expected DependencyCycleError, got %v", err)
+	}
+	if want := []string{"b", "c", "b"}; !reflect.DeepEqual(cycleErr.Cycle, want) {
+		t.Fatalf("cycle: got %v, want %v", cycleErr.Cycle, want)
+	}
+	if err.Error() != "dependency cycle: b -> c -> b" {
+		t.Errorf("message: got %q", err.Error())
+	}
+}
+
+func TestTierNamesIgnoresExternalEdges(t *testing.T) {
+	deps := map[string][]string{
+		"x": {"y", "outside"},
+		"y": {"y-dup", "y-dup"},
+	}
+	tiers, err := TierNames([]string{"x", "y", "y-dup"}, func(n string) []string { return deps[n] })
+	if err != nil {
+		t.Fatalf("tiers: %v", err)
+	}
+	want := [][]string{{"y-dup"}, {"y"}, {"x"}}
+	if !reflect.DeepEqual(tiers, want) {
+		t.Fatalf("got %v, want %v", tiers, want)
+	}
+}
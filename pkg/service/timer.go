@@ -0,0 +1,204 @@
+package service
+
+import (
+	"sort"
+	"time"
+)
+
+// TimerService activates a target service on a schedule: every
+// interval, or at the times an on-calendar spec matches, with an
+// optional first delay after the timer starts (on-boot-delay). A
+// target that is already started is restarted; otherwise it is
+// started. The timer itself is STARTED while it is armed, so stopping
+// it cancels the schedule without touching the target.
+type TimerService struct {
+	ServiceRecord
+	target    Service
+	interval  time.Duration
+	bootDelay time.Duration
+	calendar  *CalendarSpec
+
+	timer    *time.Timer
+	nextFire time.Time
+	lastFire time.Time
+}
+
+// NewTimerService creates a new timer service.
+func NewTimerService(set *ServiceSet, name string) *TimerService {
+	svc := &TimerService{}
+	svc.ServiceRecord = *NewServiceRecord(svc, set, name, TypeTimer)
+	return svc
+}
+
+// SetTarget sets the service the timer activates.
+func (s *TimerService) SetTarget(target Service) { s.target = target }
+
+// Target returns the service the timer activates.
+func (s *TimerService) Target() Service { return s.target }
+
+// SetSchedule sets when the timer fires. A calendar spec takes the
+// place of the interval; bootDelay, when set, is the delay to the
+// first firing after the timer starts. A running timer keeps its
+// current deadline and picks the new schedule up from the next one.
+func (s *TimerService) SetSchedule(interval, bootDelay time.Duration, calendar *CalendarSpec) {
+	s.interval = interval
+	s.bootDelay = bootDelay
+	s.calendar = calendar
+}
+
+// NextFire returns when the timer fires next, or the zero time if it
+// is not armed.
+func (s *TimerService) NextFire() time.Time { return s.nextFire }
+
+// LastFire returns when the timer last fired, or the zero time if it
+// has not fired yet.
+func (s *TimerService) LastFire() time.Time { return s.lastFire }
+
+// BringUp arms the timer and marks it started.
+func (s *TimerService) BringUp() bool {
+	s.arm(s.nextAfter(time.Now(), true))
+	s.Started()
+	return true
+}
+
+// BringDown disarms the timer and stops it immediately.
+func (s *TimerService) BringDown() {
+	s.disarm()
+	s.Stopped()
+}
+
+// CanInterruptStart returns true since a timer starts instantly.
+func (s *TimerService) CanInterruptStart() bool {
+	return true
+}
+
+// InterruptStart cancels the start immediately.
+func (s *TimerService) InterruptStart() bool {
+	return true
+}
+
+// nextAfter returns the next firing time after now. The first firing
+// after a start honours the boot delay. The zero time means the
+// schedule never fires again.
+func (s *TimerService) nextAfter(now time.Time, first bool) time.Time {
+	if first && s.bootDelay > 0 {
+		return now.Add(s.bootDelay)
+	}
+	if s.calendar != nil {
+		return s.calendar.NextAfter(now)
+	}
+	if s.interval > 0 {
+		return now.Add(s.interval)
+	}
+	return time.Time{}
+}
+
+// arm schedules the next firing at when. A zero when leaves the timer
+// started but idle.
+func (s *TimerService) arm(when time.Time) {
+	s.disarm()
+	if when.IsZero() {
+		return
+	}
+	s.nextFire = when
+	set := s.services
+	var t *time.Timer
+	t = time.AfterFunc(time.Until(when), func() {
+		set.queueMu.Lock()
+		defer set.queueMu.Unlock()
+		// A stop or re-arm between the timer expiring and us taking
+		// the lock has replaced or cleared s.timer.
+		if s.timer != t || s.State() != StateStarted {
+			return
+		}
+		s.timer = nil
+		s.fire()
+		set.processQueuesLocked()
+		s.arm(s.nextAfter(time.Now(), false))
+	})
+	s.timer = t
+}
+
+// disarm cancels a pending firing. Safe to call when none is armed.
+func (s *TimerService) disarm() {
+	if s.timer != nil {
+		s.timer.Stop()
+		s.timer = nil
+	}
+	s.nextFire = time.Time{}
+}
+
+// fire activates the target: a restart if it is running, else a start.
+func (s *TimerService) fire() {
+	s.lastFire = time.Now()
+	if s.target == nil {
+		return
+	}
+	s.services.logger.Info("Timer '%s': activating '%s'", s.serviceName, s.target.Name())
+	if !s.target.Record().Restart() {
+		s.target.Record().Start()
+	}
+}
+
+// timerSnapshot holds the TimerService fields a reload may change.
+type timerSnapshot struct {
+	target    Service
+	interval  time.Duration
+	bootDelay time.Duration
+	calendar  *CalendarSpec
+}
+
+// SaveSnapshot copies the configuration a reload may change.
+func (s *TimerService) SaveSnapshot() ServiceSnapshot {
+	return &timerSnapshot{
+		target:    s.target,
+		interval:  s.interval,
+		bootDelay: s.bootDelay,
+		calendar:  s.calendar,
+	}
+}
+
+// RestoreSnapshot puts back configuration saved by SaveSnapshot.
+// Snapshots of another service type are ignored.
+func (s *TimerService) RestoreSnapshot(snap ServiceSnapshot) {
+	if c, ok := snap.(*timerSnapshot); ok {
+		s.target = c.target
+		s.interval = c.interval
+		s.bootDelay = c.bootDelay
+		s.calendar = c.calendar
+	}
+}
+
+// TimerInfo describes one timer service for list-timers.
+type TimerInfo struct {
+	Name     string
+	Target   string
+	State    ServiceState
+	NextFire time.Time // zero when not armed
+	LastFire time.Time // zero when never fired
+}
+
+// ListTimers returns the timer services, sorted by name.
+func (ss *ServiceSet) ListTimers() []TimerInfo {
+	ss.queueMu.RLock()
+	defer ss.queueMu.RUnlock()
+	var timers []TimerInfo
+	for _, svc := range ss.ListServices() {
+		t, ok := svc.(*TimerService)
+		if !ok {
+			continue
+		}
+		info := TimerInfo{
+			Name:     t.Name(),
+			State:    t.State(),
+			NextFire: t.nextFire,
+			LastFire: t.lastFire,
+		}
+		if t.target != nil {
+			info.Target = t.target.Name()
+		}
+		timers = append(timers, info)
+	}
+	sort.Slice(timers, func(i, j int) bool { return timers[i].Name < timers[j].Name })
+	return timers
+}
@@ -0,0 +1,104 @@
+package service
+
+import (
+	"testing"
+	"time"
+)
+
+// waitForTimer polls cond under the queue lock until it holds or a second
+// has passed.
+func waitForTimer(t *testing.T, set *ServiceSet, cond func() bool) bool {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		set.queueMu.RLock()
+		ok := cond()
+		set.queueMu.RUnlock()
+		if ok {
+			return true
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	return false
+}
+
+func TestTimerActivatesTarget(t *testing.T) {
+	set, _ := newTestSet()
+
+	backup := NewInternalService(set, "backup")
+	timer := NewTimerService(set, "backup-timer")
+	set.AddService(backup)
+	set.AddService(timer)
+	timer.SetTarget(backup)
+	timer.SetSchedule(20*time.Millisecond, 0, nil)
+
+	set.StartService(timer)
+	if timer.State() != StateStarted {
+		t.Fatalf("expected timer STARTED, got %v", timer.State())
+	}
+	if backup.State() != StateStopped {
+		t.Fatalf("expected backup STOPPED before the first firing, got %v", backup.State())
+	}
+	if timer.NextFire().IsZero() {
+		t.Fatal("expected the timer to be armed")
+	}
+
+	if !waitForTimer(t, set, func() bool { return backup.State() == StateStarted }) {
+		t.Fatalf("target not started by the timer, state %v", backup.State())
+	}
+	first := timer.LastFire()
+	if first.IsZero() {
+		t.Fatal("expected LastFire to be set")
+	}
+	// A started target is restarted on the next firing.
+	if !waitForTimer(t, set, func() bool { return timer.LastFire().After(first) }) {
+		t.Fatal("timer did not fire a second time")
+	}
+
+	timers := set.ListTimers()
+	if len(timers) != 1 || timers[0].Name != "backup-timer" || timers[0].Target != "backup" {
+		t.Fatalf("ListTimers = %+v", timers)
+	}
+
+	set.StopService(timer)
+	if timer.State() != StateStopped || !timer.NextFire().IsZero() {
+		t.Fatalf("expected stopped and disarmed timer, got %v next %v", timer.State(), timer.NextFire())
+	}
+	last := timer.LastFire()
+	time.Sleep(60 * time.Millisecond)
+	set.queueMu.RLock()
+	fired := timer.LastFire().After(last)
+	set.queueMu.RUnlock()
+	if fired {
+		t.Error("stopped timer fired")
+	}
+}
+
+func TestTimerNextAfter(t *testing.T) {
+	set, _ := newTestSet()
+	timer := NewTimerService(set, "t")
+	now := time.Date(2026, 3, 1, 10, 30, 0, 0, time.Local)
+
+	timer.SetSchedule(5*time.Minute, time.Minute, nil)
+	if got := timer.nextAfter(now, true); !got.Equal(now.Add(time.Minute)) {
+		t.Errorf("first firing = %v, want boot delay", got)
+	}
+	if got := timer.nextAfter(now, false); !got.Equal(now.Add(5 * time.Minute)) {
+		t.Errorf("next firing = %v, want interval", got)
+	}
+
+	cal, err := ParseCalendar("daily")
+	if err != nil {
+		t.Fatal(err)
+	}
+	timer.SetSchedule(5*time.Minute, 0, cal)
+	want := time.Date(2026, 3, 2, 0, 0, 0, 0, time.Local)
+	if got := timer.nextAfter(now, true); !got.Equal(want) {
+		t.Errorf("calendar firing = %v, want %v", got, want)
+	}
+
+	timer.SetSchedule(0, 0, nil)
+	if got := timer.nextAfter(now, false); !got.IsZero() {
+		t.Errorf("no schedule: got %v, want zero", got)
+	}
+}
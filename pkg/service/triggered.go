@@ -1,12 +1,27 @@
 package service
 
+import (
+	"fmt"
+	"slices"
+	"sort"
+)
+
 // TriggeredService is a service that waits for an external trigger before
 // completing startup. Like InternalService, it has no external process.
 // The trigger is set via SetTrigger(true), typically from the control
 // socket (Phase 4) or programmatically.
+//
+// A service may also declare named triggers (trigger-names), one per
+// event it waits for, set with SetNamedTrigger. With trigger-mode = any
+// the first named trigger completes startup; with all, every declared
+// one must be set. The plain trigger completes startup either way.
 type TriggeredService struct {
 	ServiceRecord
 	isTriggered bool
+
+	triggerNames  []string // declared named triggers; empty = any name accepted
+	triggerAll    bool     // trigger-mode = all
+	namedTriggers map[string]bool
 }
 
 // NewTriggeredService creates a new triggered service.
@@ -19,7 +34,7 @@ func NewTriggeredService(set *ServiceSet, name string) *TriggeredService {
 // BringUp starts the triggered service. If already triggered, transitions to
 // STARTED immediately. Otherwise, stays in STARTING state until triggered.
 func (s *TriggeredService) BringUp() bool {
-	if s.isTriggered {
+	if s.triggerSatisfied() {
 		s.Started()
 	}
 	// If not triggered, we stay in STARTING state until SetTrigger(true)
@@ -45,12 +60,98 @@ func (s *TriggeredService) InterruptStart() bool {
 // is in STARTING state with deps satisfied, the service transitions to STARTED.
 func (s *TriggeredService) SetTrigger(triggered bool) {
 	s.isTriggered = triggered
-	if s.isTriggered && s.state.Load() == StateStarting && !s.waitingForDeps {
-		s.Started()
-	}
+	s.checkTrigger()
 }
 
 // IsTriggered returns the current trigger state.
 func (s *TriggeredService) IsTriggered() bool {
 	return s.isTriggered
 }
+
+// SetTriggerNames declares the named triggers (trigger-names) and whether
+// all of them must be set (trigger-mode = all) rather than any one.
+// Triggers that remain declared keep their state across a reload.
+func (s *TriggeredService) SetTriggerNames(names []string, all bool) {
+	s.triggerNames = slices.Clone(names)
+	s.triggerAll = all
+	for name := range s.namedTriggers {
+		if len(names) > 0 && !slices.Contains(names, name) {
+			delete(s.namedTriggers, name)
+		}
+	}
+}
+
+// TriggerNames returns the declared named triggers and whether all of
+// them are required.
+func (s *TriggeredService) TriggerNames() ([]string, bool) {
+	return s.triggerNames, s.triggerAll
+}
+
+// SetNamedTrigger sets or clears one named trigger. When the service
+// declares trigger-names, name must be one of them.
+func (s *TriggeredService) SetNamedTrigger(name string, value bool) error {
+	if name == "" {
+		return fmt.Errorf("empty trigger name")
+	}
+	if len(s.triggerNames) > 0 && !slices.Contains(s.triggerNames, name) {
+		return fmt.Errorf("service '%s' has no trigger named '%s'", s.serviceName, name)
+	}
+	if value {
+		if s.namedTriggers == nil {
+			s.namedTriggers = make(map[string]bool)
+		}
+		s.namedTriggers[name] = true
+	} else {
+		delete(s.namedTriggers, name)
+	}
+	s.checkTrigger()
+	return nil
+}
+
+// NamedTriggers reports the state of every named trigger: the declared
+// ones, plus any set under an undeclared name. Sorted by name.
+func (s *TriggeredService) NamedTriggers() []NamedTrigger {
+	names := slices.Clone(s.triggerNames)
+	for name := range s.namedTriggers {
+		if !slices.Contains(names, name) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	out := make([]NamedTrigger, len(names))
+	for i, name := range names {
+		out[i] = NamedTrigger{Name: name, Set: s.namedTriggers[name]}
+	}
+	return out
+}
+
+// NamedTrigger is the state of one named trigger.
+type NamedTrigger struct {
+	Name string
+	Set  bool
+}
+
+// triggerSatisfied reports whether startup may complete: the plain
+// trigger is set, or the named triggers are, per trigger-mode.
+func (s *TriggeredService) triggerSatisfied() bool {
+	if s.isTriggered {
+		return true
+	}
+	if !s.triggerAll || len(s.triggerNames) == 0 {
+		return len(s.namedTriggers) > 0
+	}
+	for _, name := range s.triggerNames {
+		if !s.namedTriggers[name] {
+			return false
+		}
+	}
+	return true
+}
+
+// checkTrigger completes a pending start once the trigger condition
+// holds and the dependencies are up.
+func (s *TriggeredService) checkTrigger() {
+	if s.triggerSatisfied() && s.state.Load() == StateStarting && !s.waitingForDeps {
+		s.Started()
+	}
+}
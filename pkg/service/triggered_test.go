@@ -161,3 +161,58 @@ func TestTriggeredServiceCancelStart(t *testing.T) {
 		t.Errorf("expected STOPPED after cancel, got %v", svc.State())
 	}
 }
+
+func TestTriggeredServiceNamedTriggersAll(t *testing.T) {
+	set, _ := newTestSet()
+
+	svc := NewTriggeredService(set, "app")
+	svc.SetTriggerNames([]string{"network", "database"}, true)
+	set.AddService(svc)
+	set.StartService(svc)
+
+	if err := svc.SetNamedTrigger("disk", true); err == nil {
+		t.Error("expected an error for an undeclared trigger")
+	}
+	if err := svc.SetNamedTrigger("network", true); err != nil {
+		t.Fatal(err)
+	}
+	if svc.State() != StateStarting {
+		t.Fatalf("expected STARTING with one of two triggers, got %v", svc.State())
+	}
+	if err := svc.SetNamedTrigger("database", true); err != nil {
+		t.Fatal(err)
+	}
+	if svc.State() != StateStarted {
+		t.Errorf("expected STARTED with both triggers, got %v", svc.State())
+	}
+
+	got := svc.NamedTriggers()
+	if len(got) != 2 || got[0] != (NamedTrigger{"database", true}) || got[1] != (NamedTrigger{"network", true}) {
+		t.Errorf("NamedTriggers = %+v", got)
+	}
+}
+
+func TestTriggeredServiceNamedTriggersAny(t *testing.T) {
+	set, _ := newTestSet()
+
+	svc := NewTriggeredService(set, "app")
+	svc.SetTriggerNames([]string{"network", "database"}, false)
+	set.AddService(svc)
+	set.StartService(svc)
+
+	if err := svc.SetNamedTrigger("database", true); err != nil {
+		t.Fatal(err)
+	}
+	if svc.State() != StateStarted {
+		t.Errorf("expected STARTED after any trigger, got %v", svc.State())
+	}
+
+	// Clearing a trigger does not stop a started service, but the
+	// next start waits again.
+	svc.SetNamedTrigger("database", false)
+	set.StopService(svc)
+	set.StartService(svc)
+	if svc.State() != StateStarting {
+		t.Errorf("expected STARTING with the trigger cleared, got %v", svc.State())
+	}
+}
@@ -64,6 +64,22 @@ const (
 	TypeScripted                       // Start/stop via external commands
 	TypeInternal                       // No external process
 	TypeTriggered                      // Externally triggered service
+
+	// TypeSocketActivated owns a listening socket and spawns its command
+	// when connections arrive (inetd-style).
+	TypeSocketActivated ServiceType = 7
+
+	// TypeBarrier stays STARTING until all of its dependencies are
+	// STARTED, then starts (milestone fan-in).
+	TypeBarrier ServiceType = 8
+
+	// TypeFanout starts its members and is STARTED at once, without
+	// waiting for them.
+	TypeFanout ServiceType = 9
+
+	// TypeTimer activates a target service on an interval or calendar
+	// schedule.
+	TypeTimer ServiceType = 10
 )
 
 func (t ServiceType) String() string {
@@ -80,6 +96,14 @@ func (t ServiceType) String() string {
 		return "internal"
 	case TypeTriggered:
 		return "triggered"
+	case TypeSocketActivated:
+		return "socket-activated"
+	case TypeBarrier:
+		return "barrier"
+	case TypeFanout:
+		return "fanout"
+	case TypeTimer:
+		return "timer"
 	default:
 		return fmt.Sprintf("ServiceType(%d)", t)
 	}
@@ -131,6 +155,9 @@ const (
 	EventPressureMemory                     // cgroup v2 memory.pressure crossed threshold
 	EventPressureCPU                        // cgroup v2 cpu.pressure crossed threshold
 	EventPressureIO                         // cgroup v2 io.pressure crossed threshold
+	EventAnnotation                         // operator note (slinitctl annotate); event history only
+	EventAdded                              // service added to the set; EventBus only
+	EventRemoved                            // service removed from the set; EventBus only
 )
 
 func (e ServiceEvent) String() string {
@@ -151,6 +178,12 @@ func (e ServiceEvent) String() string {
 		return "PRESSURE-CPU"
 	case EventPressureIO:
 		return "PRESSURE-IO"
+	case EventAnnotation:
+		return "ANNOTATION"
+	case EventAdded:
+		return "ADDED"
+	case EventRemoved:
+		return "REMOVED"
 	default:
 		return fmt.Sprintf("ServiceEvent(%d)", e)
 	}
@@ -203,6 +236,11 @@ const (
 	ReasonTerminated                      // Process terminated after starting
 )
 
+// ReasonCustom is the first application-defined stop reason. Codes from
+// here up are set with SetCustomStopReason and carry a message
+// explaining the stop (e.g. "filesystem not mounted").
+const ReasonCustom StoppedReason = 100
+
 func (r StoppedReason) String() string {
 	switch r {
 	case ReasonNormal:
@@ -219,9 +257,11 @@ func (r StoppedReason) String() string {
 		return "timed-out"
 	case ReasonTerminated:
 		return "terminated"
-	default:
-		return fmt.Sprintf("StoppedReason(%d)", r)
 	}
+	if r >= ReasonCustom {
+		return "custom"
+	}
+	return fmt.Sprintf("StoppedReason(%d)", r)
 }
 
 // DidFinish returns true if the reason indicates the service ran and then terminated.
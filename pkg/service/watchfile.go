@@ -0,0 +1,127 @@
+package service
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// fileWatcher watches a service's watch-file for removal. One per
+// service while STARTED; cancelled in Stopped().
+type fileWatcher struct {
+	inotify *os.File
+}
+
+// SetWatchFile sets the file whose removal fails the started service.
+// Takes effect at the next start.
+func (sr *ServiceRecord) SetWatchFile(path string) { sr.watchFile = path }
+
+// WatchFilePath returns the configured watch-file, or "".
+func (sr *ServiceRecord) WatchFilePath() string { return sr.watchFile }
+
+// WatchFile starts watching path: once it is deleted or renamed away
+// while the service is STARTED, the service is failed as if its
+// process had died, and restarted if its restart policy allows. The
+// watch is on the parent directory, so the file need not exist yet.
+// Replaces any earlier watch; cancelled when the service stops.
+func (sr *ServiceRecord) WatchFile(path string) error {
+	sr.cancelFileWatch()
+	dir, name := filepath.Split(filepath.Clean(path))
+	fd, err := unix.InotifyInit1(unix.IN_CLOEXEC | unix.IN_NONBLOCK)
+	if err != nil {
+		return fmt.Errorf("inotify_init1: %w", err)
+	}
+	if _, err := unix.InotifyAddWatch(fd, dir, unix.IN_DELETE|unix.IN_MOVED_FROM); err != nil {
+		unix.Close(fd)
+		return fmt.Errorf("watch %s: %w", dir, err)
+	}
+	// A non-blocking inotify fd goes through the runtime poller, so
+	// closing the file in cancelFileWatch ends the pending Read.
+	f := os.NewFile(uintptr(fd), "inotify:"+path)
+	sr.fileWatch = &fileWatcher{inotify: f}
+
+	go func() {
+		buf := make([]byte, 4096)
+		for {
+			n, err := f.Read(buf)
+			if err != nil {
+				return
+			}
+			if inotifyNamed(buf[:n], name) {
+				sr.watchFileGone(f, path)
+				return
+			}
+		}
+	}()
+	return nil
+}
+
+// armFileWatch starts the watch-file watch, if one is configured, when
+// the service reaches STARTED.
+func (sr *ServiceRecord) armFileWatch() {
+	if sr.watchFile == "" {
+		return
+	}
+	if _, err := os.Stat(sr.watchFile); err != nil {
+		sr.services.logger.Error("Service '%s': watch-file '%s' does not exist at start",
+			sr.serviceName, sr.watchFile)
+	}
+	if err := sr.WatchFile(sr.watchFile); err != nil {
+		sr.services.logger.Error("Service '%s': cannot watch '%s': %v",
+			sr.serviceName, sr.watchFile, err)
+	}
+}
+
+// cancelFileWatch stops the watcher if armed.
+func (sr *ServiceRecord) cancelFileWatch() {
+	if sr.fileWatch != nil {
+		sr.fileWatch.inotify.Close()
+		sr.fileWatch = nil
+	}
+}
+
+// watchFileGone fails the service after its watch-file went away. f
+// identifies the watch that fired, which a restart may have replaced
+// by the time queueMu is acquired.
+func (sr *ServiceRecord) watchFileGone(f *os.File, path string) {
+	sr.services.queueMu.Lock()
+	defer sr.services.queueMu.Unlock()
+	if sr.fileWatch == nil || sr.fileWatch.inotify != f || sr.state.Load() != StateStarted {
+		return
+	}
+	sr.services.logger.Error("Service '%s': watch-file '%s' removed, treating service as failed",
+		sr.serviceName, path)
+	sr.SetLastErrorMessage("watch-file " + path + " removed")
+	// Same handling as a failed health check: restart per policy,
+	// otherwise stop as failed.
+	sr.failHealthCheck()
+	sr.services.processQueuesLocked()
+}
+
+// inotifyNamed reports whether the inotify events in buf include one
+// for name.
+func inotifyNamed(buf []byte, name string) bool {
+	const evtSize = int(unsafe.Sizeof(unix.InotifyEvent{}))
+	for off := 0; off+evtSize <= len(buf); {
+		evt := (*unix.InotifyEvent)(unsafe.Pointer(&buf[off]))
+		nameEnd := off + evtSize + int(evt.Len)
+		if nameEnd > len(buf) {
+			return false
+		}
+		if evt.Mask&unix.IN_ISDIR == 0 {
+			got := buf[off+evtSize : nameEnd]
+			if i := bytes.IndexByte(got, 0); i >= 0 {
+				got = got[:i]
+			}
+			if string(got) == name {
+				return true
+			}
+		}
+		off = nameEnd
+	}
+	return false
+}
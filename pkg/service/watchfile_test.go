@@ -0,0 +1,77 @@
+package service
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func waitForState(t *testing.T, svc Service, want ServiceState) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for svc.State() != want {
+		if time.Now().After(deadline) {
+			t.Fatalf("state = %v, want %v", svc.State(), want)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestWatchFileRemovalFailsService(t *testing.T) {
+	set, _ := newTestSet()
+	path := filepath.Join(t.TempDir(), "svc.sock")
+	if err := os.WriteFile(path, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	svc := NewInternalService(set, "svc")
+	svc.Record().SetAutoRestart(RestartNever)
+	svc.Record().SetWatchFile(path)
+	set.AddService(svc)
+	set.StartService(svc)
+	if svc.State() != StateStarted {
+		t.Fatalf("state = %v, want STARTED", svc.State())
+	}
+
+	// Another file in the directory going away is not the watch-file.
+	other := filepath.Join(filepath.Dir(path), "other")
+	os.WriteFile(other, nil, 0644)
+	os.Remove(other)
+	time.Sleep(50 * time.Millisecond)
+	if svc.State() != StateStarted {
+		t.Fatalf("removing another file stopped the service")
+	}
+
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+	waitForState(t, svc, StateStopped)
+	if r := svc.Record().StopReason(); r != ReasonFailed {
+		t.Errorf("stop reason = %v, want %v", r, ReasonFailed)
+	}
+}
+
+func TestWatchFileCancelledOnStop(t *testing.T) {
+	set, _ := newTestSet()
+	path := filepath.Join(t.TempDir(), "svc.pid")
+	if err := os.WriteFile(path, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	svc := NewInternalService(set, "svc")
+	svc.Record().SetWatchFile(path)
+	set.AddService(svc)
+	set.StartService(svc)
+	set.StopService(svc)
+	if svc.Record().fileWatch != nil {
+		t.Fatal("watch still armed after stop")
+	}
+
+	// Starting again and removing the file only then fails the service.
+	os.Remove(path)
+	set.StartService(svc)
+	if svc.State() != StateStarted {
+		t.Fatalf("state = %v, want STARTED", svc.State())
+	}
+}
@@ -0,0 +1,51 @@
+package shutdown
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ParseDelay parses a shutdown(8) time argument into a delay.
+//
+//	"now"      → 0 (immediate)
+//	"+N"       → N minutes
+//	"HH:MM"    → delay until that time today (or tomorrow if past)
+func ParseDelay(s string) (time.Duration, error) {
+	if s == "now" || s == "" {
+		return 0, nil
+	}
+
+	// +N minutes
+	if strings.HasPrefix(s, "+") {
+		mins, err := strconv.Atoi(s[1:])
+		if err != nil || mins < 0 {
+			return 0, fmt.Errorf("invalid time: %s (use +N for minutes)", s)
+		}
+		return time.Duration(mins) * time.Minute, nil
+	}
+
+	// Plain number = minutes
+	if mins, err := strconv.Atoi(s); err == nil && mins >= 0 {
+		return time.Duration(mins) * time.Minute, nil
+	}
+
+	// HH:MM absolute time
+	parts := strings.SplitN(s, ":", 2)
+	if len(parts) == 2 {
+		h, err1 := strconv.Atoi(parts[0])
+		m, err2 := strconv.Atoi(parts[1])
+		if err1 == nil && err2 == nil && h >= 0 && h <= 23 && m >= 0 && m <= 59 {
+			now := time.Now()
+			target := time.Date(now.Year(), now.Month(), now.Day(), h, m, 0, 0, now.Location())
+			if target.Before(now) {
+				// Target time already passed today — schedule for tomorrow.
+				target = target.Add(24 * time.Hour)
+			}
+			return target.Sub(now), nil
+		}
+	}
+
+	return 0, fmt.Errorf("invalid time: %s (use 'now', '+N' for minutes, or 'HH:MM')", s)
+}
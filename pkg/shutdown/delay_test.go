@@ -0,0 +1,88 @@
+package shutdown
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseDelayNow(t *testing.T) {
+	d, err := ParseDelay("now")
+	if err != nil {
+		t.Fatalf("ParseDelay(now): %v", err)
+	}
+	if d != 0 {
+		t.Errorf("d = %v, want 0", d)
+	}
+}
+
+func TestParseDelayEmpty(t *testing.T) {
+	d, err := ParseDelay("")
+	if err != nil {
+		t.Fatalf("ParseDelay(): %v", err)
+	}
+	if d != 0 {
+		t.Errorf("d = %v, want 0", d)
+	}
+}
+
+func TestParseDelayPlusMinutes(t *testing.T) {
+	d, err := ParseDelay("+5")
+	if err != nil {
+		t.Fatalf("ParseDelay(+5): %v", err)
+	}
+	if d != 5*time.Minute {
+		t.Errorf("d = %v, want 5m", d)
+	}
+}
+
+func TestParseDelayPlainMinutes(t *testing.T) {
+	d, err := ParseDelay("10")
+	if err != nil {
+		t.Fatalf("ParseDelay(10): %v", err)
+	}
+	if d != 10*time.Minute {
+		t.Errorf("d = %v, want 10m", d)
+	}
+}
+
+func TestParseDelayAbsolute(t *testing.T) {
+	// Use a time in the future.
+	now := time.Now()
+	target := now.Add(30 * time.Minute)
+	timeStr := target.Format("15:04")
+
+	d, err := ParseDelay(timeStr)
+	if err != nil {
+		t.Fatalf("ParseDelay(%s): %v", timeStr, err)
+	}
+	// Should be approximately 30 minutes (allow 2 minute tolerance).
+	if d < 28*time.Minute || d > 32*time.Minute {
+		t.Errorf("d = %v, want ~30m for %s", d, timeStr)
+	}
+}
+
+func TestParseDelayAbsolutePast(t *testing.T) {
+	// Use a time in the past — should wrap to tomorrow.
+	now := time.Now()
+	target := now.Add(-30 * time.Minute)
+	timeStr := target.Format("15:04")
+
+	d, err := ParseDelay(timeStr)
+	if err != nil {
+		t.Fatalf("ParseDelay(%s): %v", timeStr, err)
+	}
+	// Should be approximately 23h30m.
+	if d < 23*time.Hour || d > 24*time.Hour {
+		t.Errorf("d = %v, want ~23h30m for past time %s", d, timeStr)
+	}
+}
+
+func TestParseDelayInvalid(t *testing.T) {
+	bad := []string{"foo", "+abc", "25:00", "12:61", "-5"}
+	for _, s := range bad {
+		_, err := ParseDelay(s)
+		if err == nil {
+			t.Errorf("ParseDelay(%q) should fail", s)
+		}
+	}
+}
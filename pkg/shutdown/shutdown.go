@@ -12,6 +12,7 @@ import (
 	"time"
 
 	"github.com/sunlightlinux/slinit/pkg/logging"
+	"github.com/sunlightlinux/slinit/pkg/process"
 	"github.com/sunlightlinux/slinit/pkg/service"
 	"github.com/sunlightlinux/slinit/pkg/utmp"
 )
@@ -201,10 +202,12 @@ func Execute(shutdownType service.ShutdownType, logger *logging.Logger) {
 	// forever — we detect that specific EINVAL and fall back to a
 	// normal reboot. This mirrors systemctl kexec's behavior.
 	rebootType := shutdownType
+	logger.Flush()
 	if err := rebootSystem(rebootType); err != nil {
 		if rebootType == service.ShutdownKexec && errors.Is(err, syscall.EINVAL) {
 			logger.Error("kexec reboot: no kernel pre-loaded (use `kexec -l <kernel>` before `slinitctl shutdown kexec`); falling back to normal reboot")
 			rebootType = service.ShutdownReboot
+			logger.Flush()
 			if err := rebootSystem(rebootType); err != nil {
 				logger.Error("Fallback reboot syscall failed: %v", err)
 			}
@@ -216,6 +219,7 @@ func Execute(shutdownType service.ShutdownType, logger *logging.Logger) {
 	// If we get here, the reboot syscall failed.
 	// PID 1 must never exit, so hold indefinitely.
 	logger.Error("Shutdown failed, holding indefinitely")
+	logger.Flush()
 	InfiniteHold()
 }
 
@@ -275,10 +279,12 @@ func ExecuteForce(shutdownType service.ShutdownType, logger *logging.Logger) {
 
 	// See Execute() for the same fallback rationale on kexec EINVAL.
 	rebootType := shutdownType
+	logger.Flush()
 	if err := rebootSystem(rebootType); err != nil {
 		if rebootType == service.ShutdownKexec && errors.Is(err, syscall.EINVAL) {
 			logger.Error("kexec reboot: no kernel pre-loaded; falling back to normal reboot")
 			rebootType = service.ShutdownReboot
+			logger.Flush()
 			if err := rebootSystem(rebootType); err != nil {
 				logger.Error("Fallback reboot syscall failed: %v", err)
 			}
@@ -287,6 +293,7 @@ func ExecuteForce(shutdownType service.ShutdownType, logger *logging.Logger) {
 		}
 	}
 	logger.Error("Forced shutdown syscall returned unexpectedly")
+	logger.Flush()
 	InfiniteHold()
 }
 
@@ -402,7 +409,7 @@ func runShutdownHook(shutdownType service.ShutdownType, logger *logging.Logger)
 	cmd.Stdout = &output
 	cmd.Stderr = &output
 
-	err := cmd.Run()
+	err := process.Run(cmd)
 
 	// Log any output from the hook
 	if output.Len() > 0 {
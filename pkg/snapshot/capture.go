@@ -38,9 +38,10 @@ func Capture(set *service.ServiceSet) *Snapshot {
 }
 
 // captureOne returns a ServiceSnapshot for svc, or nil if svc has no
-// state worth preserving. Services that are not activated, pinned, or
-// triggered are skipped — the dependency graph will pull them up
-// transitively when their activator is re-started.
+// state worth preserving. Services that are not activated, pinned,
+// triggered, under an auto-restart override or annotated are skipped —
+// the dependency graph will pull them up transitively when their
+// activator is re-started.
 func captureOne(svc service.Service) *ServiceSnapshot {
 	rec := svc.Record()
 
@@ -49,19 +50,39 @@ func captureOne(svc service.Service) *ServiceSnapshot {
 	pinStop := rec.IsStopPinned()
 
 	triggered := false
+	var namedTriggers []string
 	if ts, ok := svc.(*service.TriggeredService); ok {
 		triggered = ts.IsTriggered()
+		for _, nt := range ts.NamedTriggers() {
+			if nt.Set {
+				namedTriggers = append(namedTriggers, nt.Name)
+			}
+		}
+	}
+
+	var autoRestart *bool
+	if o := rec.AutoRestartOverride(); o != nil {
+		v := *o
+		autoRestart = &v
+	}
+
+	var notes []AnnotationSnapshot
+	for _, a := range rec.Annotations() {
+		notes = append(notes, AnnotationSnapshot{Time: a.Time, User: a.User, Message: a.Message})
 	}
 
-	if !activated && !pinStart && !pinStop && !triggered {
+	if !activated && !pinStart && !pinStop && !triggered && len(namedTriggers) == 0 && autoRestart == nil && len(notes) == 0 {
 		return nil
 	}
 
 	return &ServiceSnapshot{
-		Name:        rec.Name(),
-		Activated:   activated,
-		PinnedStart: pinStart,
-		PinnedStop:  pinStop,
-		Triggered:   triggered,
+		Name:          rec.Name(),
+		Activated:     activated,
+		PinnedStart:   pinStart,
+		PinnedStop:    pinStop,
+		Triggered:     triggered,
+		NamedTriggers: namedTriggers,
+		AutoRestart:   autoRestart,
+		Annotations:   notes,
 	}
 }
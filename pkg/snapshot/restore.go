@@ -99,10 +99,28 @@ func applyOne(set *service.ServiceSet, entry ServiceSnapshot, logger RestoreLogg
 
 	// Trigger: a TriggeredService remembers the latch even when
 	// stopped, so SetTrigger before Start works in either order.
-	if entry.Triggered {
-		if ts, ok := svc.(*service.TriggeredService); ok {
+	if ts, ok := svc.(*service.TriggeredService); ok {
+		if entry.Triggered {
 			ts.SetTrigger(true)
 		}
+		// A trigger the new description no longer declares is dropped.
+		for _, name := range entry.NamedTriggers {
+			_ = ts.SetNamedTrigger(name, true)
+		}
+	}
+
+	// Restore the override before Start so a service the operator set
+	// to no-restart cannot respawn in between.
+	if entry.AutoRestart != nil {
+		svc.Record().SetAutoRestartEnabled(*entry.AutoRestart)
+	}
+
+	if len(entry.Annotations) > 0 {
+		notes := make([]service.Annotation, len(entry.Annotations))
+		for i, a := range entry.Annotations {
+			notes[i] = service.Annotation{Time: a.Time, User: a.User, Message: a.Message}
+		}
+		svc.Record().SetAnnotations(notes)
 	}
 
 	// Activation: skip if the operator pinned the service down — they
@@ -26,6 +26,8 @@
 // changes.
 package snapshot
 
+import "time"
+
 // CurrentVersion is the schema version written by this build.
 //
 // Bump only for changes that an older reader cannot ignore (renamed
@@ -85,9 +87,29 @@ type ServiceSnapshot struct {
 	// is a meaningful state the operator may have configured.
 	Triggered bool `json:"triggered,omitempty"`
 
+	// NamedTriggers lists the named triggers that were set.
+	NamedTriggers []string `json:"named_triggers,omitempty"`
+
+	// AutoRestart captures the runtime auto-restart override set by
+	// `slinitctl no-restart` / `enable-restart`. Nil when the service
+	// uses its configured restart mode.
+	AutoRestart *bool `json:"auto_restart,omitempty"`
+
+	// Annotations carries the operator notes added with
+	// `slinitctl annotate`, oldest first, so the audit trail survives
+	// the restart.
+	Annotations []AnnotationSnapshot `json:"annotations,omitempty"`
+
 	// --- Reserved for Phase B (PID re-attach). Do not populate from
 	// Phase A capture; readers ignore them when zero. ---
 	//
 	// PID       int `json:"pid,omitempty"`
 	// ExecStage int `json:"exec_stage,omitempty"`
 }
+
+// AnnotationSnapshot is one operator note on a service.
+type AnnotationSnapshot struct {
+	Time    time.Time `json:"time"`
+	User    string    `json:"user,omitempty"`
+	Message string    `json:"message"`
+}
@@ -95,6 +95,28 @@ func TestCaptureTriggered(t *testing.T) {
 	}
 }
 
+func TestCaptureRestoreAutoRestartOverride(t *testing.T) {
+	set := newSet()
+	svc := service.NewInternalService(set, "crashy")
+	set.AddService(svc)
+	svc.Record().SetAutoRestartEnabled(false)
+
+	snap := snapshot.Capture(set)
+	if len(snap.Services) != 1 || snap.Services[0].AutoRestart == nil || *snap.Services[0].AutoRestart {
+		t.Fatalf("expected one AutoRestart=false entry, got %+v", snap.Services)
+	}
+
+	set2 := newSet()
+	svc2 := service.NewInternalService(set2, "crashy")
+	set2.AddService(svc2)
+	if _, err := snapshot.Restore(set2, snap, nil); err != nil {
+		t.Fatalf("Restore: %v", err)
+	}
+	if o := svc2.Record().AutoRestartOverride(); o == nil || *o {
+		t.Errorf("override after restore = %v, want false", o)
+	}
+}
+
 func TestCaptureGlobalEnv(t *testing.T) {
 	set := newSet()
 	set.GlobalSetEnv("FOO", "bar")
@@ -309,3 +331,36 @@ func equalStrings(a, b []string) bool {
 	}
 	return true
 }
+
+func TestCaptureRestoreAnnotations(t *testing.T) {
+	set := newSet()
+	svc := service.NewInternalService(set, "web")
+	set.AddService(svc)
+	svc.Record().AnnotateEvent("alice", "planned maintenance")
+
+	snap := snapshot.Capture(set)
+	if len(snap.Services) != 1 || len(snap.Services[0].Annotations) != 1 {
+		t.Fatalf("expected one annotated entry, got %+v", snap.Services)
+	}
+
+	path := filepath.Join(t.TempDir(), "snap.json")
+	if err := snapshot.Write(path, snap); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	snap, err := snapshot.Read(path)
+	if err != nil {
+		t.Fatalf("Read: %v", err)
+	}
+
+	set2 := newSet()
+	svc2 := service.NewInternalService(set2, "web")
+	set2.AddService(svc2)
+	if _, err := snapshot.Restore(set2, snap, nil); err != nil {
+		t.Fatalf("Restore: %v", err)
+	}
+	notes := svc2.Record().Annotations()
+	if len(notes) != 1 || notes[0].User != "alice" || notes[0].Message != "planned maintenance" ||
+		!notes[0].Time.Equal(svc.Record().Annotations()[0].Time) {
+		t.Errorf("annotations after restore = %+v", notes)
+	}
+}
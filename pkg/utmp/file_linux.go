@@ -0,0 +1,150 @@
+//go:build linux
+
+package utmp
+
+import (
+	"io"
+	"os"
+	"syscall"
+	"time"
+)
+
+// Access to utmp-format files without libc, for the pure-Go backend.
+// Every operation holds an fcntl write lock on the file, the same lock
+// glibc takes, so libc programs updating the files concurrently (login,
+// sshd, ...) do not interleave with us. Like glibc, nothing here
+// creates a missing utmp or wtmp file: their existence is the
+// administrator's switch for the accounting.
+
+// lockFile takes a write lock on all of f, waiting for it.
+func lockFile(f *os.File) error {
+	lk := syscall.Flock_t{Type: syscall.F_WRLCK, Whence: io.SeekStart}
+	for {
+		err := syscall.FcntlFlock(f.Fd(), syscall.F_SETLKW, &lk)
+		if err != syscall.EINTR {
+			return err
+		}
+	}
+}
+
+// openLocked opens the file at path and locks it.
+func openLocked(path string, flag int) (*os.File, error) {
+	f, err := os.OpenFile(path, flag, 0)
+	if err != nil {
+		return nil, err
+	}
+	if err := lockFile(f); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return f, nil
+}
+
+// readAll reads every whole record from f, from the start.
+func readAll(f *os.File) ([]record, error) {
+	data, err := io.ReadAll(io.NewSectionReader(f, 0, 1<<62))
+	if err != nil {
+		return nil, err
+	}
+	recs := make([]record, 0, len(data)/recordSize)
+	for len(data) >= recordSize {
+		recs = append(recs, decodeRecord(data))
+		data = data[recordSize:]
+	}
+	return recs, nil
+}
+
+// readRecords returns the records in the utmp-format file at path. A
+// missing file has none.
+func readRecords(path string) ([]record, error) {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	lk := syscall.Flock_t{Type: syscall.F_RDLCK, Whence: io.SeekStart}
+	if err := syscall.FcntlFlock(f.Fd(), syscall.F_SETLKW, &lk); err != nil {
+		return nil, err
+	}
+	return readAll(f)
+}
+
+// putRecord writes r to the utmp file at path as pututxline(3) does:
+// over the slot getutxid(3) finds for it, or else at the end. prep, if
+// not nil, sees the records read under the lock and may adjust r first.
+func putRecord(path string, r record, prep func(recs []record, r *record)) error {
+	f, err := openLocked(path, os.O_RDWR)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	recs, err := readAll(f)
+	if err != nil {
+		return err
+	}
+	if prep != nil {
+		prep(recs, &r)
+	}
+	slot := len(recs)
+	for i := range recs {
+		if matchesID(&recs[i], &r) {
+			slot = i
+			break
+		}
+	}
+	_, err = f.WriteAt(r.encode(), int64(slot)*recordSize)
+	return err
+}
+
+// appendRecord appends r to the wtmp file at path as updwtmp(3) does,
+// first cutting off a partial record left by an interrupted writer.
+func appendRecord(path string, r record) error {
+	f, err := openLocked(path, os.O_WRONLY)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	fi, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	end := fi.Size() - fi.Size()%recordSize
+	if end != fi.Size() {
+		if err := f.Truncate(end); err != nil {
+			return err
+		}
+	}
+	_, err = f.WriteAt(r.encode(), end)
+	return err
+}
+
+// logoutUsers rewrites every user-process record in the utmp file at
+// utmpPath as a dead process and appends the same record to the wtmp
+// file at wtmpPath. Returns the number of sessions logged out.
+func logoutUsers(utmpPath, wtmpPath string, now time.Time) (int, error) {
+	f, err := openLocked(utmpPath, os.O_RDWR)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	recs, err := readAll(f)
+	if err != nil {
+		return 0, err
+	}
+	n := 0
+	for i, e := range recs {
+		if e.Type != typeUserProcess || e.Line == "" {
+			continue
+		}
+		dead := record{Type: typeDeadProcess, PID: e.PID, ID: e.ID, Line: e.Line, Time: now}
+		if _, err := f.WriteAt(dead.encode(), int64(i)*recordSize); err != nil {
+			return n, err
+		}
+		appendRecord(wtmpPath, dead) //nolint: errcheck // wtmp is best effort
+		n++
+	}
+	return n, nil
+}
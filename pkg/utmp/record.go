@@ -0,0 +1,128 @@
+package utmp
+
+import (
+	"bytes"
+	"encoding/binary"
+	"time"
+)
+
+// Record types (ut_type), as in <utmp.h>.
+const (
+	typeEmpty        = 0
+	typeRunLevel     = 1
+	typeBootTime     = 2
+	typeNewTime      = 3
+	typeOldTime      = 4
+	typeInitProcess  = 5
+	typeLoginProcess = 6
+	typeUserProcess  = 7
+	typeDeadProcess  = 8
+)
+
+// Field sizes and offsets of glibc's struct utmp on platforms where
+// ut_session and ut_tv are 32-bit (x86_64 with its 32-bit-compatible
+// layout, and the 32-bit ABIs): 384 bytes in all.
+const (
+	recordSize = 384
+
+	lineSize = 32
+	idSize   = 4
+	userSize = 32
+	hostSize = 256
+
+	offType    = 0
+	offPID     = 4
+	offLine    = 8
+	offID      = offLine + lineSize
+	offUser    = offID + idSize
+	offHost    = offUser + userSize
+	offExit    = offHost + hostSize
+	offSession = offExit + 4
+	offTvSec   = offSession + 4
+	offTvUsec  = offTvSec + 4
+)
+
+// record is one utmp/wtmp entry. Strings longer than their field are
+// truncated; a field filled to its size has no NUL terminator, as in C.
+type record struct {
+	Type int16
+	PID  int32
+	Line string
+	ID   string
+	User string
+	Host string
+	Time time.Time
+}
+
+// encode returns r in the on-disk layout, in native byte order.
+func (r *record) encode() []byte {
+	b := make([]byte, recordSize)
+	binary.NativeEndian.PutUint16(b[offType:], uint16(r.Type))
+	binary.NativeEndian.PutUint32(b[offPID:], uint32(r.PID))
+	copy(b[offLine:offLine+lineSize], r.Line)
+	copy(b[offID:offID+idSize], r.ID)
+	copy(b[offUser:offUser+userSize], r.User)
+	copy(b[offHost:offHost+hostSize], r.Host)
+	if !r.Time.IsZero() {
+		binary.NativeEndian.PutUint32(b[offTvSec:], uint32(r.Time.Unix()))
+		binary.NativeEndian.PutUint32(b[offTvUsec:], uint32(r.Time.Nanosecond()/1000))
+	}
+	return b
+}
+
+// decodeRecord parses a record from b, which holds at least recordSize
+// bytes.
+func decodeRecord(b []byte) record {
+	r := record{
+		Type: int16(binary.NativeEndian.Uint16(b[offType:])),
+		PID:  int32(binary.NativeEndian.Uint32(b[offPID:])),
+		Line: cField(b[offLine : offLine+lineSize]),
+		ID:   cField(b[offID : offID+idSize]),
+		User: cField(b[offUser : offUser+userSize]),
+		Host: cField(b[offHost : offHost+hostSize]),
+	}
+	sec := int32(binary.NativeEndian.Uint32(b[offTvSec:]))
+	usec := int32(binary.NativeEndian.Uint32(b[offTvUsec:]))
+	if sec != 0 || usec != 0 {
+		r.Time = time.Unix(int64(sec), int64(usec)*1000)
+	}
+	return r
+}
+
+// cField returns a fixed-size C string field up to its first NUL.
+func cField(b []byte) string {
+	if i := bytes.IndexByte(b, 0); i >= 0 {
+		b = b[:i]
+	}
+	return string(b)
+}
+
+// matchesID reports whether entry is the slot getutxid(3) would pick
+// for want: the same type for the run-level and clock records, the
+// same ut_id among the process records.
+func matchesID(entry, want *record) bool {
+	switch want.Type {
+	case typeRunLevel, typeBootTime, typeNewTime, typeOldTime:
+		return entry.Type == want.Type
+	case typeInitProcess, typeLoginProcess, typeUserProcess, typeDeadProcess:
+		switch entry.Type {
+		case typeInitProcess, typeLoginProcess, typeUserProcess, typeDeadProcess:
+			return truncate(entry.ID, idSize) == truncate(want.ID, idSize)
+		}
+	}
+	return false
+}
+
+// matchesLine reports whether entry is the slot getutxline(3) would
+// pick for line: a login or user process on that terminal.
+func matchesLine(entry *record, line string) bool {
+	return (entry.Type == typeLoginProcess || entry.Type == typeUserProcess) &&
+		entry.Line == truncate(line, lineSize)
+}
+
+func truncate(s string, n int) string {
+	if len(s) > n {
+		return s[:n]
+	}
+	return s
+}
@@ -0,0 +1,144 @@
+//go:build linux
+
+package utmp
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestRecordLayout(t *testing.T) {
+	// glibc struct utmp: ut_exit at 332, ut_session at 336, ut_tv at
+	// 340, then ut_addr_v6[4] and 20 unused bytes.
+	if offExit != 332 || offSession != 336 || offTvSec != 340 || offTvUsec+4+16+20 != recordSize {
+		t.Errorf("layout: exit %d, session %d, tv %d, size %d", offExit, offSession, offTvSec, recordSize)
+	}
+
+	now := time.Unix(1700000000, 123456000)
+	in := record{Type: typeUserProcess, PID: 4242, Line: "pts/7", ID: "ts/7", User: "alice", Host: "example.org", Time: now}
+	b := in.encode()
+	if len(b) != recordSize {
+		t.Fatalf("encoded %d bytes, want %d", len(b), recordSize)
+	}
+	if out := decodeRecord(b); out != in {
+		t.Errorf("round trip: got %+v, want %+v", out, in)
+	}
+
+	long := record{Type: typeInitProcess, ID: "toolong", Line: "x"}
+	if got := decodeRecord(long.encode()).ID; got != "tool" {
+		t.Errorf("ut_id truncated to %q, want %q", got, "tool")
+	}
+}
+
+// emptyFile creates an empty utmp-format file in a temporary directory.
+func emptyFile(t *testing.T, name string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestPutRecordReplacesSlot(t *testing.T) {
+	path := emptyFile(t, "utmp")
+	put := func(r record) {
+		t.Helper()
+		if err := putRecord(path, r, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	put(record{Type: typeBootTime, Time: time.Unix(100, 0)})
+	put(record{Type: typeInitProcess, PID: 10, ID: "1", Line: "tty1"})
+	put(record{Type: typeInitProcess, PID: 20, ID: "2", Line: "tty2"})
+	// Same id: overwrites the tty1 slot. Same type: overwrites boot time.
+	put(record{Type: typeDeadProcess, PID: 10, ID: "1", Line: "tty1"})
+	put(record{Type: typeBootTime, Time: time.Unix(200, 0)})
+
+	recs, err := readRecords(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(recs) != 3 {
+		t.Fatalf("got %d records, want 3: %+v", len(recs), recs)
+	}
+	if recs[0].Type != typeBootTime || recs[0].Time.Unix() != 200 {
+		t.Errorf("slot 0 = %+v, want boot time 200", recs[0])
+	}
+	if recs[1].Type != typeDeadProcess || recs[1].ID != "1" {
+		t.Errorf("slot 1 = %+v, want dead process id 1", recs[1])
+	}
+	if recs[2].Type != typeInitProcess || recs[2].PID != 20 {
+		t.Errorf("slot 2 = %+v, want init process pid 20", recs[2])
+	}
+}
+
+func TestAppendRecordTrimsPartial(t *testing.T) {
+	path := emptyFile(t, "wtmp")
+	if err := appendRecord(path, record{Type: typeBootTime}); err != nil {
+		t.Fatal(err)
+	}
+	// An interrupted writer left half a record behind.
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Write(make([]byte, recordSize/2))
+	f.Close()
+
+	if err := appendRecord(path, record{Type: typeRunLevel, User: "shutdown"}); err != nil {
+		t.Fatal(err)
+	}
+	fi, _ := os.Stat(path)
+	if fi.Size() != 2*recordSize {
+		t.Fatalf("wtmp is %d bytes, want %d", fi.Size(), 2*recordSize)
+	}
+	recs, _ := readRecords(path)
+	if recs[1].User != "shutdown" {
+		t.Errorf("second record = %+v, want the shutdown record", recs[1])
+	}
+}
+
+func TestMissingFileNotCreated(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "wtmp")
+	if err := appendRecord(path, record{Type: typeBootTime}); err == nil {
+		t.Error("append to a missing wtmp succeeded")
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("wtmp was created: %v", err)
+	}
+}
+
+func TestLogoutUsers(t *testing.T) {
+	utmpPath := emptyFile(t, "utmp")
+	wtmpPath := emptyFile(t, "wtmp")
+	for _, r := range []record{
+		{Type: typeInitProcess, PID: 1, ID: "si"},
+		{Type: typeUserProcess, PID: 100, ID: "1", Line: "tty1", User: "root"},
+		{Type: typeUserProcess, PID: 200, ID: "ts/0", Line: "pts/0", User: "alice"},
+	} {
+		if err := putRecord(utmpPath, r, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	n, err := logoutUsers(utmpPath, wtmpPath, time.Unix(300, 0))
+	if err != nil || n != 2 {
+		t.Fatalf("logoutUsers = %d, %v; want 2", n, err)
+	}
+	recs, _ := readRecords(utmpPath)
+	for _, r := range recs[1:] {
+		if r.Type != typeDeadProcess || r.User != "" {
+			t.Errorf("utmp entry %+v not logged out", r)
+		}
+	}
+	if recs[0].Type != typeInitProcess {
+		t.Errorf("init entry changed: %+v", recs[0])
+	}
+	wrecs, _ := readRecords(wtmpPath)
+	if len(wrecs) != 2 || wrecs[0].Line != "tty1" || wrecs[1].PID != 200 {
+		t.Errorf("wtmp = %+v, want logout records for tty1 and pts/0", wrecs)
+	}
+}
@@ -0,0 +1,131 @@
+//go:build linux && !cgo && (amd64 || 386 || arm)
+
+// Package utmp provides utmpx database functions for logging boot time
+// and service process entries to /var/run/utmp and /var/log/wtmp.
+// This mirrors dinit's USE_UTMPX functionality.
+//
+// Without cgo (a static build, as slinit is usually built to run as PID
+// 1) the records are encoded and written directly, on the platforms
+// whose glibc record layout is implemented in record.go.
+package utmp
+
+import (
+	"os"
+	"time"
+)
+
+const (
+	utmpPath = "/var/run/utmp"
+	wtmpPath = "/var/log/wtmp"
+)
+
+// MaxIDLen is the maximum length of an inittab-id value.
+var MaxIDLen = idSize
+
+// MaxLineLen is the maximum length of an inittab-line value.
+var MaxLineLen = lineSize
+
+// MaxUserLen is the maximum length of an ut_user value.
+var MaxUserLen = userSize
+
+// LogBoot writes a BOOT_TIME record to utmp and wtmp.
+// It clears the utmp file first (same as dinit's CLEAR_UTMP_ON_BOOT).
+// Should be called once when the root filesystem becomes read-write.
+func LogBoot() bool {
+	r := record{Type: typeBootTime, Time: time.Now()}
+	// Best-effort: the file may not exist yet.
+	os.Truncate(utmpPath, 0)    //nolint: errcheck
+	appendRecord(wtmpPath, r)   //nolint: errcheck
+	putRecord(utmpPath, r, nil) //nolint: errcheck
+	return true
+}
+
+// CreateEntry writes an INIT_PROCESS record to utmp for a started service.
+// id and line correspond to the service's inittab-id and inittab-line
+// settings. pid is the process ID of the started service.
+func CreateEntry(id, line string, pid int) bool {
+	return CreateEntryMode(id, line, pid, "")
+}
+
+// CreateEntryMode writes a utmp record of the mode-selected ut_type
+// (init | login | user). Empty mode defaults to init.
+func CreateEntryMode(id, line string, pid int, mode string) bool {
+	var utType int16 = typeInitProcess
+	switch mode {
+	case "login":
+		utType = typeLoginProcess
+	case "user":
+		utType = typeUserProcess
+	}
+	r := record{Type: utType, PID: int32(pid), ID: id, Line: line, Time: time.Now()}
+	return putRecord(utmpPath, r, nil) == nil
+}
+
+// ClearEntry writes a DEAD_PROCESS record to utmp for a stopped service.
+// It looks up the existing entry by id or line to preserve the PID field.
+func ClearEntry(id, line string) {
+	r := record{Type: typeDeadProcess, ID: id, Line: line, Time: time.Now()}
+	keepPID := func(recs []record, r *record) {
+		for i := range recs {
+			if (id != "" && matchesID(&recs[i], r)) || (id == "" && line != "" && matchesLine(&recs[i], line)) {
+				r.PID = recs[i].PID
+				return
+			}
+		}
+	}
+	putRecord(utmpPath, r, keepPID) //nolint: errcheck
+}
+
+// ListUserTTYs returns the ut_line values (TTY device names, e.g. "tty1",
+// "pts/0") of all active USER_PROCESS entries in the utmp database.
+// Used by the shutdown wall broadcast to find logged-in users.
+func ListUserTTYs() []string {
+	recs, _ := readRecords(utmpPath)
+	var lines []string
+	for _, r := range recs {
+		if r.Type == typeUserProcess && r.Line != "" {
+			lines = append(lines, r.Line)
+		}
+	}
+	return lines
+}
+
+// Session represents a single logged-in user session from the utmpx
+// database: the user name and the TTY/pseudoterminal device they are
+// attached to.
+type Session struct {
+	User string
+	Line string
+}
+
+// ListUserSessions returns one Session per active USER_PROCESS entry in
+// the utmp database. Used by shutdown.allow access control to verify
+// that at least one authorised user is currently logged in before
+// honouring a signal-driven shutdown.
+func ListUserSessions() []Session {
+	recs, _ := readRecords(utmpPath)
+	var sessions []Session
+	for _, r := range recs {
+		if r.Type == typeUserProcess && r.User != "" {
+			sessions = append(sessions, Session{User: r.User, Line: r.Line})
+		}
+	}
+	return sessions
+}
+
+// LogoutAllUsers marks every active USER_PROCESS entry in utmp as
+// DEAD_PROCESS and appends a matching logout record to wtmp, so `last`
+// shows a clean session boundary across the reboot. Returns the number
+// of sessions logged out.
+func LogoutAllUsers() int {
+	n, _ := logoutUsers(utmpPath, wtmpPath, time.Now())
+	return n
+}
+
+// LogShutdown appends a RUN_LVL "shutdown" record to wtmp so that
+// `last -x` renders a system-shutdown boundary, with sysvinit's
+// ut_user="shutdown", ut_line="~", ut_id="~~".
+func LogShutdown() bool {
+	r := record{Type: typeRunLevel, User: "shutdown", Line: "~", ID: "~~", Time: time.Now()}
+	return appendRecord(wtmpPath, r) == nil
+}
@@ -1,7 +1,8 @@
-//go:build !linux || !cgo
+//go:build !linux || (!cgo && !amd64 && !386 && !arm)
 
 // Package utmp provides utmpx database functions.
-// This is a no-op stub for non-Linux platforms or when cgo is disabled.
+// This is a no-op stub for non-Linux platforms, and for cgo-less builds
+// on platforms whose utmp record layout utmp_nocgo.go does not encode.
 package utmp
 
 // MaxIDLen is the maximum length of an inittab-id value.